@@ -662,7 +662,9 @@ class BusinessManager {
 
             let response;
             if (this.editingConfigId) {
-                // 更新配置
+                // 更新配置（携带编辑前读取的版本号，配置已被他人修改时服务端返回409）
+                const original = this.currentConfigs.find(c => c.id === this.editingConfigId);
+                config.version = original ? original.version : undefined;
                 response = await proxyAPI.updateConfig(this.editingConfigId, config);
                 this.showSuccess('配置更新成功');
             } else {
@@ -5221,9 +5223,10 @@ func main() {
                 });
                 this.addTestResult(testResults, '配置状态更新', !!updateResult, '应该能更新配置状态');
 
-                // 恢复原始状态
+                // 恢复原始状态（使用更新后的版本号）
                 await proxyAPI.updateConfig(testConfig.id, {
                     ...testConfig,
+                    version: updateResult.version,
                     enabled: originalEnabled
                 });
             }
@@ -5924,6 +5927,7 @@ func main() {
             steps.push('更新配置状态');
             const updateResult = await proxyAPI.updateConfig(newConfigId, {
                 ...testConfigData,
+                version: createResult.version,
                 enabled: false
             });
             if (!updateResult) {
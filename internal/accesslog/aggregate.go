@@ -0,0 +1,226 @@
+package accesslog
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// 聚合统计参数
+const (
+	DefaultAggregateInterval = 5 * time.Minute // 默认时间分桶间隔
+	MinAggregateInterval     = time.Minute     // 最小时间分桶间隔
+	MaxAggregateInterval     = 24 * time.Hour  // 最大时间分桶间隔
+	maxAggregateHosts        = 50              // 返回的目标主机数上限
+)
+
+// AggregateStats 筛选结果的聚合统计
+type AggregateStats struct {
+	Total           int                `json:"total"`            // 匹配的日志条数
+	Hosts           []HostCount        `json:"hosts"`            // 按请求数降序的目标主机（最多50个）
+	StatusClasses   map[string]int     `json:"status_classes"`   // 按状态码类别（2xx、3xx、4xx、5xx、other）的请求数
+	IntervalSeconds int64              `json:"interval_seconds"` // 时间分桶间隔（秒）
+	Timeline        []TimeBucket       `json:"timeline"`         // 按时间分桶的请求数（升序）
+	Latency         LatencyPercentiles `json:"latency_ms"`       // 处理时长分位数（毫秒）
+}
+
+// HostCount 目标主机请求数
+type HostCount struct {
+	Host  string `json:"host"`
+	Count int    `json:"count"`
+}
+
+// TimeBucket 时间分桶
+type TimeBucket struct {
+	Start      time.Time `json:"start"`       // 分桶开始时间
+	Count      int       `json:"count"`       // 请求数
+	ErrorCount int       `json:"error_count"` // 状态码>=400的请求数
+}
+
+// LatencyPercentiles 处理时长分位数（毫秒）
+type LatencyPercentiles struct {
+	P50  int64   `json:"p50"`
+	P90  int64   `json:"p90"`
+	P95  int64   `json:"p95"`
+	P99  int64   `json:"p99"`
+	Max  int64   `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// Aggregator 逐条累加日志的聚合器，内存占用与日志条数无关
+type Aggregator struct {
+	interval time.Duration
+	total    int
+	hosts    map[string]int
+	statuses map[string]int
+	buckets  map[int64]*TimeBucket
+	latency  latencyHistogram
+}
+
+// NewAggregator 创建聚合器，interval为时间分桶间隔
+func NewAggregator(interval time.Duration) *Aggregator {
+	return &Aggregator{
+		interval: interval,
+		hosts:    make(map[string]int),
+		statuses: map[string]int{"2xx": 0, "3xx": 0, "4xx": 0, "5xx": 0, "other": 0},
+		buckets:  make(map[int64]*TimeBucket),
+	}
+}
+
+// Add 累加一条日志
+func (a *Aggregator) Add(log *AccessLog) {
+	a.total++
+	a.hosts[log.TargetHost]++
+	a.statuses[statusClass(log.StatusCode)]++
+
+	start := log.Timestamp.Truncate(a.interval)
+	bucket, ok := a.buckets[start.Unix()]
+	if !ok {
+		bucket = &TimeBucket{Start: start}
+		a.buckets[start.Unix()] = bucket
+	}
+	bucket.Count++
+	if log.StatusCode >= 400 {
+		bucket.ErrorCount++
+	}
+
+	a.latency.record(log.Duration)
+}
+
+// Result 获取聚合结果
+func (a *Aggregator) Result() *AggregateStats {
+	stats := &AggregateStats{
+		Total:           a.total,
+		Hosts:           make([]HostCount, 0, len(a.hosts)),
+		StatusClasses:   a.statuses,
+		IntervalSeconds: int64(a.interval / time.Second),
+		Timeline:        make([]TimeBucket, 0, len(a.buckets)),
+		Latency: LatencyPercentiles{
+			P50:  a.latency.percentile(50),
+			P90:  a.latency.percentile(90),
+			P95:  a.latency.percentile(95),
+			P99:  a.latency.percentile(99),
+			Max:  a.latency.max,
+			Mean: a.latency.mean(),
+		},
+	}
+
+	for host, count := range a.hosts {
+		stats.Hosts = append(stats.Hosts, HostCount{Host: host, Count: count})
+	}
+	sort.Slice(stats.Hosts, func(i, j int) bool {
+		if stats.Hosts[i].Count != stats.Hosts[j].Count {
+			return stats.Hosts[i].Count > stats.Hosts[j].Count
+		}
+		return stats.Hosts[i].Host < stats.Hosts[j].Host
+	})
+	if len(stats.Hosts) > maxAggregateHosts {
+		stats.Hosts = stats.Hosts[:maxAggregateHosts]
+	}
+
+	for _, bucket := range a.buckets {
+		stats.Timeline = append(stats.Timeline, *bucket)
+	}
+	sort.Slice(stats.Timeline, func(i, j int) bool {
+		return stats.Timeline[i].Start.Before(stats.Timeline[j].Start)
+	})
+
+	return stats
+}
+
+// statusClass 获取状态码类别
+func statusClass(statusCode int) string {
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return "2xx"
+	case statusCode >= 300 && statusCode < 400:
+		return "3xx"
+	case statusCode >= 400 && statusCode < 500:
+		return "4xx"
+	case statusCode >= 500 && statusCode < 600:
+		return "5xx"
+	default:
+		return "other"
+	}
+}
+
+// 延迟直方图参数：小于128ms的值精确记录，更大的值按2的幂分段、每段64个子桶（相对误差<2%）
+const (
+	histogramExactLimit = 128
+	histogramSubBuckets = 64
+	histogramSize       = histogramExactLimit + 57*histogramSubBuckets
+)
+
+// latencyHistogram 固定大小的对数直方图，用于流式计算分位数而无需保存和排序所有样本
+type latencyHistogram struct {
+	counts [histogramSize]int64
+	count  int64
+	sum    float64
+	max    int64
+}
+
+// record 记录一个样本（毫秒）
+func (h *latencyHistogram) record(value int64) {
+	if value < 0 {
+		value = 0
+	}
+	h.counts[histogramIndex(value)]++
+	h.count++
+	h.sum += float64(value)
+	if value > h.max {
+		h.max = value
+	}
+}
+
+// percentile 按最近秩法计算分位数，返回所在子桶的上界（不超过最大样本值）
+func (h *latencyHistogram) percentile(p float64) int64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var cumulative int64
+	for idx, count := range h.counts {
+		cumulative += count
+		if cumulative >= rank {
+			if upper := histogramUpperBound(idx); upper < h.max {
+				return upper
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// mean 计算平均值
+func (h *latencyHistogram) mean() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / float64(h.count)
+}
+
+// histogramIndex 计算样本所在的子桶
+func histogramIndex(value int64) int {
+	if value < histogramExactLimit {
+		return int(value)
+	}
+	shift := bits.Len64(uint64(value)) - 7 // value >> shift 落在[64, 128)
+	sub := int(value >> uint(shift))
+	return histogramExactLimit + (shift-1)*histogramSubBuckets + (sub - histogramSubBuckets)
+}
+
+// histogramUpperBound 获取子桶内的最大值
+func histogramUpperBound(idx int) int64 {
+	if idx < histogramExactLimit {
+		return int64(idx)
+	}
+	offset := idx - histogramExactLimit
+	shift := offset/histogramSubBuckets + 1
+	sub := int64(offset%histogramSubBuckets + histogramSubBuckets)
+	return (sub+1)<<uint(shift) - 1
+}
@@ -0,0 +1,80 @@
+package accesslog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// compressBodies 对超过阈值的请求体和响应体做gzip压缩，压缩后没有变小时保留原文
+//
+// 只在存储内部的日志副本上调用，压缩后对应的字符串字段被清空。
+func (l *AccessLog) compressBodies(threshold int) {
+	if threshold <= 0 {
+		return
+	}
+
+	if len(l.ResponseBody) > threshold {
+		if data := gzipString(l.ResponseBody); data != nil {
+			l.responseBodyGz = data
+			l.ResponseBody = ""
+		}
+	}
+
+	if len(l.RequestBody) > threshold {
+		if data := gzipString(l.RequestBody); data != nil {
+			l.requestBodyGz = data
+			l.RequestBody = ""
+		}
+	}
+}
+
+// isCompressed 检查日志是否有压缩存储的内容
+func (l *AccessLog) isCompressed() bool {
+	return l.responseBodyGz != nil || l.requestBodyGz != nil
+}
+
+// decompressed 返回解压后的日志副本，原日志保持不变
+func (l *AccessLog) decompressed() AccessLog {
+	out := *l
+	if l.responseBodyGz != nil {
+		out.ResponseBody = gunzipString(l.responseBodyGz)
+		out.responseBodyGz = nil
+	}
+	if l.requestBodyGz != nil {
+		out.RequestBody = gunzipString(l.requestBodyGz)
+		out.requestBodyGz = nil
+	}
+	return out
+}
+
+// gzipString 压缩字符串，压缩失败或结果不小于原文时返回nil
+func gzipString(s string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(writer, s); err != nil {
+		return nil
+	}
+	if err := writer.Close(); err != nil {
+		return nil
+	}
+	if buf.Len() >= len(s) {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// gunzipString 解压gzipString的结果，数据损坏时返回空字符串
+func gunzipString(data []byte) string {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	defer reader.Close()
+
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
@@ -11,6 +11,8 @@ var (
 	ErrInvalidTargetHost = errors.New("invalid target host")
 	ErrInvalidStatusCode = errors.New("invalid status code")
 	ErrInvalidTimeRange  = errors.New("invalid time range: from time must be before to time")
+	ErrInvalidClientIP   = errors.New("invalid client IP filter: expected an IP address or CIDR")
+	ErrInvalidDuration   = errors.New("invalid duration range: min duration must not exceed max duration")
 
 	// 存储相关错误
 	ErrStorageFull       = errors.New("storage is full")
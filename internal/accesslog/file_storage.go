@@ -0,0 +1,233 @@
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStorageOptions 磁盘持久化选项
+type FileStorageOptions struct {
+	Path         string        // 日志文件路径
+	MaxSizeBytes int64         // 单个文件达到该大小后轮转（0表示不按大小轮转）
+	MaxAge       time.Duration // 文件打开超过该时长后轮转（0表示不按时间轮转）
+	MaxBackups   int           // 保留的历史文件数（path.1 ~ path.N）
+	LoadExisting bool          // 启动时是否从当前文件恢复日志
+}
+
+// FileStorage 磁盘持久化存储
+//
+// 日志以JSON Lines格式追加写入文件，同时保存在内存存储中用于快速查询最近的日志。
+// 文件按大小或时间轮转：当前文件重命名为path.1，已有的历史文件依次后移，超过
+// MaxBackups的文件被删除。
+type FileStorage struct {
+	*MemoryStorage
+
+	options  FileStorageOptions
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	mutex    sync.Mutex
+}
+
+// NewFileStorage 创建磁盘持久化存储，返回存储及启动时恢复的日志条数
+func NewFileStorage(memory *MemoryStorage, options FileStorageOptions) (*FileStorage, int, error) {
+	if options.Path == "" {
+		return nil, 0, fmt.Errorf("log file path is required")
+	}
+	if options.MaxBackups < 1 {
+		options.MaxBackups = 1
+	}
+
+	s := &FileStorage{
+		MemoryStorage: memory,
+		options:       options,
+	}
+
+	loaded := 0
+	if options.LoadExisting {
+		var err error
+		if loaded, err = s.load(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if dir := filepath.Dir(options.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, 0, fmt.Errorf("failed to create log directory: %w", err)
+		}
+	}
+	if err := s.open(); err != nil {
+		return nil, 0, err
+	}
+
+	return s, loaded, nil
+}
+
+// Add 添加日志记录并追加写入文件
+func (s *FileStorage) Add(log *AccessLog) error {
+	// 先写入内存存储（校验并截断响应体），保证文件内容与内存一致
+	if err := s.MemoryStorage.Add(log); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		return fmt.Errorf("failed to marshal access log: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("log file is closed")
+	}
+	if s.shouldRotate(int64(len(data))) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write access log: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭日志文件
+func (s *FileStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return s.MemoryStorage.Close()
+	}
+	err := s.file.Close()
+	s.file = nil
+	if closeErr := s.MemoryStorage.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// load 从当前日志文件恢复日志（跳过无法解析的行，如异常退出时写了一半的记录）
+func (s *FileStorage) load() (int, error) {
+	file, err := os.Open(s.options.Path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	loaded := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var log AccessLog
+		if err := json.Unmarshal([]byte(line), &log); err != nil {
+			continue
+		}
+		if err := s.MemoryStorage.Add(&log); err == nil {
+			loaded++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return loaded, fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	return loaded, nil
+}
+
+// open 以追加方式打开当前日志文件（调用方需持有锁或尚未并发访问）
+func (s *FileStorage) open() error {
+	file, err := os.OpenFile(s.options.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+	s.openedAt = time.Now()
+
+	// 上次异常退出时最后一行可能不完整，补上换行避免与新记录拼接
+	if s.size > 0 && !endsWithNewline(s.options.Path, s.size) {
+		n, _ := file.Write([]byte{'\n'})
+		s.size += int64(n)
+	}
+	return nil
+}
+
+// endsWithNewline 检查文件最后一个字节是否为换行符
+func endsWithNewline(path string, size int64) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return true
+	}
+	return last[0] == '\n'
+}
+
+// shouldRotate 检查写入下一条记录前是否需要轮转（空文件不轮转）
+func (s *FileStorage) shouldRotate(next int64) bool {
+	if s.size == 0 {
+		return false
+	}
+	if s.options.MaxSizeBytes > 0 && s.size+next > s.options.MaxSizeBytes {
+		return true
+	}
+	if s.options.MaxAge > 0 && time.Since(s.openedAt) >= s.options.MaxAge {
+		return true
+	}
+	return false
+}
+
+// rotate 轮转日志文件（调用方需持有锁）
+func (s *FileStorage) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	s.file = nil
+
+	path := s.options.Path
+	os.Remove(backupPath(path, s.options.MaxBackups))
+	for i := s.options.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(path, i), backupPath(path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	if err := os.Rename(path, backupPath(path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	return s.open()
+}
+
+// backupPath 获取第n个历史文件的路径
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,122 @@
+package accesslog
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countLines 统计文件行数
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestFileStorage_RotatesAtSizeThreshold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "access.log")
+
+	// 先计算单条日志写入后的大小，阈值设置为每个文件正好容纳3条
+	probe, _, err := NewFileStorage(NewMemoryStorage(10, 0, 24, 1024), FileStorageOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Failed to create file storage: %v", err)
+	}
+	probe.Add(newTestLog(0, time.Now(), ""))
+	lineSize := probe.size
+	probe.Close()
+	os.Remove(path)
+
+	storage, _, err := NewFileStorage(NewMemoryStorage(100, 0, 24, 1024), FileStorageOptions{
+		Path:         path,
+		MaxSizeBytes: lineSize * 3,
+		MaxBackups:   2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create file storage: %v", err)
+	}
+	defer storage.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := storage.Add(newTestLog(i, time.Now(), "")); err != nil {
+			t.Fatalf("Failed to add log: %v", err)
+		}
+	}
+
+	// 10条日志：access.log.2(log-3~5)被保留，log-0~2所在文件超出保留数被删除
+	if lines := countLines(t, path); lines != 1 {
+		t.Errorf("Expected 1 entry in current file, got %d", lines)
+	}
+	for _, backup := range []string{path + ".1", path + ".2"} {
+		if lines := countLines(t, backup); lines != 3 {
+			t.Errorf("Expected 3 entries in %s, got %d", backup, lines)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected backups beyond MaxBackups to be removed")
+	}
+
+	// 查询仍然可以从内存中获取所有日志
+	response, err := storage.Query(&LogFilter{Page: 1, Limit: 100})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if response.Total != 10 {
+		t.Errorf("Expected 10 logs from query, got %d", response.Total)
+	}
+	if log, err := storage.GetByID("log-9"); err != nil || log.TargetPath != "/items/9" {
+		t.Errorf("Expected to find log-9, got %v, %v", log, err)
+	}
+}
+
+func TestFileStorage_LoadExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	storage, _, err := NewFileStorage(NewMemoryStorage(10, 0, 24, 1024), FileStorageOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Failed to create file storage: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		storage.Add(newTestLog(i, time.Now(), "error body"))
+	}
+	storage.Close()
+
+	// 模拟异常退出时写了一半的记录
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	file.WriteString(`{"id":"log-broken","timest`)
+	file.Close()
+
+	restored, loaded, err := NewFileStorage(NewMemoryStorage(10, 0, 24, 1024), FileStorageOptions{
+		Path:         path,
+		LoadExisting: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to reopen file storage: %v", err)
+	}
+	defer restored.Close()
+
+	if loaded != 5 {
+		t.Errorf("Expected 5 logs to be restored, got %d", loaded)
+	}
+	response, _ := restored.Query(&LogFilter{Page: 1, Limit: 10, StatusCode: []int{500}})
+	if response.Total != 5 || response.Logs[0].ResponseBody != "error body" {
+		t.Errorf("Expected restored logs to be queryable, got %d logs", response.Total)
+	}
+
+	// 新记录写在新的一行
+	restored.Add(newTestLog(5, time.Now(), ""))
+	if lines := countLines(t, path); lines != 7 {
+		t.Errorf("Expected new entry on its own line (7 lines), got %d", lines)
+	}
+}
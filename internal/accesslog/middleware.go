@@ -20,9 +20,13 @@ type ResponseCapture struct {
 	startTime       time.Time
 	actualUserAgent string            // 实际发送给目标服务器的User-Agent
 	proxyInfo       string            // 代理服务器信息
+	requestID       string            // 请求ID
 	requestHeaders  map[string]string // 请求头信息
 	requestBody     string            // 请求体内容
 	responseHeaders map[string]string // 响应头信息
+	configID        string            // 路由使用的代理配置ID
+	configName      string            // 路由使用的代理配置名称
+	errorCode       string            // 网关错误代码（网关返回错误或上游返回5xx时）
 	record200       bool              // 是否记录200状态码的详细信息
 }
 
@@ -52,9 +56,26 @@ func (rc *ResponseCapture) WriteHeader(statusCode int) {
 		}
 	}
 
+	// SSE事件流是长连接，不捕获响应体
+	if IsEventStream(rc.ResponseWriter.Header().Get("Content-Type")) {
+		rc.captureBody = false
+	}
+
 	rc.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Flush 将缓冲的数据立即发送给客户端（支持SSE等流式响应）
+func (rc *ResponseCapture) Flush() {
+	if flusher, ok := rc.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 返回被包装的ResponseWriter（供http.ResponseController使用）
+func (rc *ResponseCapture) Unwrap() http.ResponseWriter {
+	return rc.ResponseWriter
+}
+
 // Write 捕获响应体内容
 func (rc *ResponseCapture) Write(data []byte) (int, error) {
 	// 写入原始响应
@@ -63,17 +84,14 @@ func (rc *ResponseCapture) Write(data []byte) (int, error) {
 	// 更新响应大小
 	rc.bodySize += int64(n)
 
-	// 根据状态码决定是否捕获响应体
+	// 根据状态码决定是否捕获响应体（最多保留maxBodySize字节，不影响写给客户端的数据）
 	if rc.captureBody && rc.shouldCaptureBody() {
-		// 检查是否超过最大大小限制
-		if rc.body.Len()+len(data) <= rc.maxBodySize {
-			rc.body.Write(data[:n])
-		} else {
-			// 只写入剩余可用空间
-			remaining := rc.maxBodySize - rc.body.Len()
-			if remaining > 0 {
-				rc.body.Write(data[:remaining])
+		if remaining := rc.maxBodySize - rc.body.Len(); remaining > 0 {
+			chunk := data[:n]
+			if len(chunk) > remaining {
+				chunk = chunk[:remaining]
 			}
+			rc.body.Write(chunk)
 		}
 	}
 
@@ -102,8 +120,8 @@ func (rc *ResponseCapture) GetBody() string {
 	// 处理不同类型的响应内容
 	body := rc.formatResponseBody(bodyBytes, contentType)
 
-	// 如果响应体被截断，添加截断标记
-	if rc.bodySize > int64(rc.maxBodySize) {
+	// 如果响应体被截断，添加截断标记（二进制和压缩内容只记录描述，不需要标记）
+	if rc.bodySize > int64(rc.maxBodySize) && contentType != "binary" && contentType != "gzip" {
 		if len(body) > 13 { // len("...[truncated]") = 13
 			body = body[:len(body)-13] + "...[truncated]"
 		} else {
@@ -144,6 +162,16 @@ func (rc *ResponseCapture) GetProxyInfo() string {
 	return rc.proxyInfo
 }
 
+// SetRequestID 设置请求ID
+func (rc *ResponseCapture) SetRequestID(requestID string) {
+	rc.requestID = requestID
+}
+
+// GetRequestID 获取请求ID
+func (rc *ResponseCapture) GetRequestID() string {
+	return rc.requestID
+}
+
 // SetRequestHeaders 设置请求头信息
 func (rc *ResponseCapture) SetRequestHeaders(headers map[string]string) {
 	rc.requestHeaders = headers
@@ -164,6 +192,32 @@ func (rc *ResponseCapture) GetRequestBody() string {
 	return rc.requestBody
 }
 
+// SetConfig 设置路由使用的代理配置
+func (rc *ResponseCapture) SetConfig(configID, configName string) {
+	rc.configID = configID
+	rc.configName = configName
+}
+
+// GetConfigID 获取路由使用的代理配置ID
+func (rc *ResponseCapture) GetConfigID() string {
+	return rc.configID
+}
+
+// GetConfigName 获取路由使用的代理配置名称
+func (rc *ResponseCapture) GetConfigName() string {
+	return rc.configName
+}
+
+// SetErrorCode 设置网关错误代码
+func (rc *ResponseCapture) SetErrorCode(code string) {
+	rc.errorCode = code
+}
+
+// GetErrorCode 获取网关错误代码
+func (rc *ResponseCapture) GetErrorCode() string {
+	return rc.errorCode
+}
+
 // GetResponseHeaders 获取响应头信息
 func (rc *ResponseCapture) GetResponseHeaders() map[string]string {
 	return rc.responseHeaders
@@ -184,6 +238,7 @@ type LoggingMiddleware struct {
 	storage     Storage
 	maxBodySize int
 	record200   bool
+	redactor    *Redactor // 存储前脱敏（可选）
 }
 
 // NewLoggingMiddleware 创建新的日志记录中间件
@@ -216,6 +271,7 @@ func (lm *LoggingMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
 		// 创建日志记录
 		log := &AccessLog{
 			ID:             GenerateLogID(),
+			RequestID:      capture.GetRequestID(),
 			Timestamp:      startTime,
 			Method:         r.Method,
 			TargetHost:     extractTargetHost(r),
@@ -232,6 +288,9 @@ func (lm *LoggingMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
 			RequestBody:    capture.GetRequestBody(),
 		}
 
+		// 存储前脱敏
+		lm.redactor.Apply(log)
+
 		// 异步记录日志，避免影响响应性能
 		go func() {
 			if err := lm.storage.Add(log); err != nil {
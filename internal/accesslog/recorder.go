@@ -14,9 +14,10 @@ import (
 
 // Recorder 日志记录器
 type Recorder struct {
-	storage Storage
-	config  *config.Config
-	logger  *logger.Logger
+	storage  Storage
+	config   *config.Config
+	logger   *logger.Logger
+	redactor *Redactor // 存储前脱敏
 
 	// 异步处理
 	logChan chan *AccessLog
@@ -34,19 +35,46 @@ type Recorder struct {
 
 // NewRecorder 创建新的日志记录器
 func NewRecorder(cfg *config.Config, log *logger.Logger) (*Recorder, error) {
+	// 创建脱敏器（规则无效时不启动记录器，避免记录未脱敏的内容）
+	redactor, err := NewRedactor(cfg.LogRedactHeaders, cfg.LogRedactFields, cfg.LogRedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建存储
-	storage := NewMemoryStorage(
+	memory := NewMemoryStorage(
 		cfg.LogMaxEntries,
 		cfg.LogMaxMemoryMB,
 		cfg.LogRetentionHours,
 		cfg.LogMaxBodySize,
 	)
+	memory.SetCompressThreshold(cfg.LogCompressThreshold)
+
+	// 配置了日志文件时同时持久化到磁盘
+	var storage Storage = memory
+	if cfg.LogFile != "" {
+		fileStorage, loaded, err := NewFileStorage(memory, FileStorageOptions{
+			Path:         cfg.LogFile,
+			MaxSizeBytes: int64(cfg.LogRotateMB) * 1024 * 1024,
+			MaxAge:       time.Duration(cfg.LogRotateHours) * time.Hour,
+			MaxBackups:   cfg.LogRotateKeep,
+			LoadExisting: cfg.LogFileLoad,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if loaded > 0 {
+			log.Info("access logs restored from file", "file", cfg.LogFile, "entries", loaded)
+		}
+		storage = fileStorage
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	recorder := &Recorder{
 		storage:    storage,
 		config:     cfg,
+		redactor:   redactor,
 		logger:     log,
 		logChan:    make(chan *AccessLog, 1000), // 缓冲1000条日志
 		ctx:        ctx,
@@ -58,13 +86,31 @@ func NewRecorder(cfg *config.Config, log *logger.Logger) (*Recorder, error) {
 	// 启动异步处理协程
 	recorder.startWorkers()
 
+	// 启动保留策略清理协程
+	cleanupInterval := time.Duration(cfg.LogCleanupIntervalSeconds) * time.Second
+	if cleanupInterval <= 0 {
+		cleanupInterval = 5 * time.Minute
+	}
+	recorder.startCleanup(cleanupInterval)
+
 	return recorder, nil
 }
 
 // RecordRequest 记录HTTP请求
 func (r *Recorder) RecordRequest(req *http.Request, statusCode int, responseBody string, duration time.Duration, responseSize int64, endpoint string) {
-	// 创建日志记录
-	log := &AccessLog{
+	r.enqueue(r.newRequestLog(req, statusCode, responseBody, duration, responseSize, endpoint))
+}
+
+// RecordWebSocket 记录WebSocket会话，frames为nil时不记录帧
+func (r *Recorder) RecordWebSocket(req *http.Request, statusCode int, duration time.Duration, frames *FrameLog) {
+	log := r.newRequestLog(req, statusCode, "", duration, 0, "/ws")
+	log.WebSocketFrames, log.WebSocketFramesDropped = frames.Frames()
+	r.enqueue(log)
+}
+
+// newRequestLog 根据请求信息创建日志记录
+func (r *Recorder) newRequestLog(req *http.Request, statusCode int, responseBody string, duration time.Duration, responseSize int64, endpoint string) *AccessLog {
+	return &AccessLog{
 		ID:           GenerateLogID(),
 		Timestamp:    time.Now(),
 		Method:       req.Method,
@@ -79,8 +125,10 @@ func (r *Recorder) RecordRequest(req *http.Request, statusCode int, responseBody
 		RequestSize:  req.ContentLength,
 		ResponseSize: responseSize,
 	}
+}
 
-	// 异步发送到处理队列
+// enqueue 将日志异步发送到处理队列，队列满时丢弃
+func (r *Recorder) enqueue(log *AccessLog) {
 	select {
 	case r.logChan <- log:
 		// 成功发送
@@ -100,6 +148,7 @@ func (r *Recorder) RecordFromCapture(req *http.Request, capture *ResponseCapture
 
 	log := &AccessLog{
 		ID:             GenerateLogID(),
+		RequestID:      capture.GetRequestID(),
 		Timestamp:      capture.startTime,
 		Method:         req.Method,
 		RequestType:    DetermineRequestTypeWithResponse(req, endpoint, capture.GetResponseHeaders()),
@@ -115,16 +164,12 @@ func (r *Recorder) RecordFromCapture(req *http.Request, capture *ResponseCapture
 		ResponseSize:   capture.GetBodySize(),
 		RequestHeaders: capture.GetRequestHeaders(),
 		RequestBody:    capture.GetRequestBody(),
+		ConfigID:       capture.GetConfigID(),
+		ConfigName:     capture.GetConfigName(),
+		ErrorCode:      capture.GetErrorCode(),
 	}
 
-	// 异步发送到处理队列
-	select {
-	case r.logChan <- log:
-		// 成功发送
-	default:
-		// 队列满了，丢弃日志并记录错误
-		r.recordError(fmt.Errorf("log queue is full, dropping log"))
-	}
+	r.enqueue(log)
 }
 
 // Query 查询日志
@@ -132,6 +177,11 @@ func (r *Recorder) Query(filter *LogFilter) (*LogResponse, error) {
 	return r.storage.Query(filter)
 }
 
+// Aggregate 对匹配筛选条件的日志做聚合统计
+func (r *Recorder) Aggregate(filter *LogFilter, interval time.Duration) (*AggregateStats, error) {
+	return r.storage.Aggregate(filter, interval)
+}
+
 // GetStats 获取统计信息
 func (r *Recorder) GetStats() *RecorderStats {
 	r.mutex.RLock()
@@ -150,6 +200,17 @@ func (r *Recorder) GetStats() *RecorderStats {
 	}
 }
 
+// Writable 检查记录器是否可以接收新日志（未关闭且队列未满）
+func (r *Recorder) Writable() error {
+	if err := r.ctx.Err(); err != nil {
+		return fmt.Errorf("recorder closed: %w", err)
+	}
+	if len(r.logChan) >= cap(r.logChan) {
+		return fmt.Errorf("log queue is full")
+	}
+	return nil
+}
+
 // Close 关闭记录器
 func (r *Recorder) Close() error {
 	// 停止接收新日志
@@ -172,6 +233,38 @@ func (r *Recorder) startWorkers() {
 	}
 }
 
+// startCleanup 启动定期执行保留策略的协程
+func (r *Recorder) startCleanup(interval time.Duration) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.cleanup()
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// cleanup 执行一次保留策略清理
+func (r *Recorder) cleanup() {
+	if removed := r.storage.Cleanup(); removed > 0 {
+		stats := r.storage.GetStats()
+		r.logger.Debug("access logs cleaned up",
+			"removed", removed,
+			"remaining", stats.CurrentEntries,
+			"memory_mb", stats.MemoryUsageMB,
+		)
+	}
+}
+
 // worker 工作协程
 func (r *Recorder) worker() {
 	defer r.wg.Done()
@@ -200,11 +293,29 @@ func (r *Recorder) worker() {
 
 // processLog 处理单条日志
 func (r *Recorder) processLog(log *AccessLog) error {
+	// 存储前脱敏
+	r.redactor.Apply(log)
+
 	// 验证日志
 	if err := log.Validate(); err != nil {
 		return fmt.Errorf("invalid log: %w", err)
 	}
 
+	// 标记慢请求（WebSocket会话时长不代表请求延迟，不标记）
+	threshold := int64(r.config.SlowRequestMs)
+	if threshold > 0 && log.Duration > threshold && log.RequestType != RequestTypeWebSocket {
+		log.Slow = true
+		r.logger.Warn("slow request recorded",
+			"request_id", log.RequestID,
+			"method", log.Method,
+			"target", log.TargetHost+log.TargetPath,
+			"status", log.StatusCode,
+			"duration", log.Duration,
+			"threshold", threshold,
+			"config_id", log.ConfigID,
+		)
+	}
+
 	// 存储日志
 	if err := r.storage.Add(log); err != nil {
 		return fmt.Errorf("failed to store log: %w", err)
@@ -332,7 +443,9 @@ type RecorderStats struct {
 
 // CreateMiddleware 创建中间件
 func (r *Recorder) CreateMiddleware() *LoggingMiddleware {
-	return NewLoggingMiddleware(r.storage, r.config.LogMaxBodySize, r.config.LogRecord200)
+	middleware := NewLoggingMiddleware(r.storage, r.config.LogMaxBodySize, r.config.LogRecord200)
+	middleware.redactor = r.redactor
+	return middleware
 }
 
 // WrapHandler 包装HTTP处理器
@@ -0,0 +1,196 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue 脱敏后的替换值
+const RedactedValue = "[REDACTED]"
+
+// Redactor 访问日志脱敏器，在日志存储前替换请求头和请求/响应体中的敏感内容
+type Redactor struct {
+	headers  map[string]bool  // 需要脱敏的请求头（小写）
+	fields   map[string]bool  // 需要脱敏的JSON字段名或点分路径（小写）
+	fieldsRe *regexp.Regexp   // 无法解析的JSON（如被截断）按字段名匹配的回退正则
+	patterns []*regexp.Regexp // 需要脱敏的正则表达式
+}
+
+// truncatedMarker 记录器截断请求/响应体时追加的标记
+const truncatedMarker = "...[truncated]"
+
+// NewRedactor 创建脱敏器，正则表达式无效时返回错误
+func NewRedactor(headers, fields, patterns []string) (*Redactor, error) {
+	r := &Redactor{
+		headers: make(map[string]bool),
+		fields:  make(map[string]bool),
+	}
+	for _, header := range headers {
+		if header = strings.ToLower(strings.TrimSpace(header)); header != "" {
+			r.headers[header] = true
+		}
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			r.fields[field] = true
+			// 回退正则只能按字段名匹配，点分路径取最后一段
+			name := field[strings.LastIndex(field, ".")+1:]
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, regexp.QuoteMeta(name))
+			}
+		}
+	}
+	if len(names) > 0 {
+		// "字段名": 后的字符串（允许未闭合，即被截断）或数字、布尔等标量值
+		r.fieldsRe = regexp.MustCompile(`(?i)("(?:` + strings.Join(names, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*(?:"|$)|[^\s,}\]]+)`)
+	}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, compiled)
+	}
+	return r, nil
+}
+
+// Apply 对日志的请求头、请求体、响应体和WebSocket文本帧脱敏（nil接收者不做处理）
+func (r *Redactor) Apply(log *AccessLog) {
+	if r == nil || log == nil {
+		return
+	}
+
+	if len(log.RequestHeaders) > 0 {
+		headers := make(map[string]string, len(log.RequestHeaders))
+		for key, value := range log.RequestHeaders {
+			if r.headers[strings.ToLower(key)] {
+				headers[key] = RedactedValue
+			} else {
+				headers[key] = r.redactPatterns(value)
+			}
+		}
+		log.RequestHeaders = headers
+	}
+
+	log.RequestBody = r.RedactBody(log.RequestBody)
+	log.ResponseBody = r.RedactBody(log.ResponseBody)
+	for i := range log.WebSocketFrames {
+		log.WebSocketFrames[i].Payload = r.RedactBody(log.WebSocketFrames[i].Payload)
+	}
+}
+
+// RedactBody 对请求/响应体脱敏：先替换JSON中匹配的字段，再应用正则表达式
+func (r *Redactor) RedactBody(body string) string {
+	if r == nil || body == "" {
+		return body
+	}
+	return r.redactPatterns(r.redactJSONBody(body))
+}
+
+// redactJSONBody 替换JSON中匹配字段的值，无匹配字段时原样返回
+//
+// 无法解析的内容（被截断的请求/响应体、WebSocket帧等）按字段名用正则回退替换，避免敏感字段以明文记录。
+func (r *Redactor) redactJSONBody(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if len(r.fields) == 0 {
+		return body
+	}
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return r.redactFieldsFallback(body)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return r.redactFieldsFallback(body)
+	}
+	if !r.redactJSON(value, "") {
+		return body
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return body
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactFieldsFallback 按字段名替换无法解析的JSON中的值，保留末尾的截断标记
+func (r *Redactor) redactFieldsFallback(body string) string {
+	if r.fieldsRe == nil {
+		return body
+	}
+	content, truncated := strings.CutSuffix(body, truncatedMarker)
+	content = r.fieldsRe.ReplaceAllString(content, `${1}"`+RedactedValue+`"`)
+	if truncated {
+		content += truncatedMarker
+	}
+	return content
+}
+
+// redactJSON 递归替换匹配字段名或点分路径的值，返回是否有替换（数组元素沿用父路径）
+func (r *Redactor) redactJSON(value interface{}, path string) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			name := strings.ToLower(key)
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			if r.fields[name] || r.fields[childPath] {
+				v[key] = RedactedValue
+				changed = true
+				continue
+			}
+			if r.redactJSON(child, childPath) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if r.redactJSON(item, path) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// redactPatterns 替换正则匹配的内容，正则包含捕获组时只替换第一个捕获组
+func (r *Redactor) redactPatterns(value string) string {
+	for _, pattern := range r.patterns {
+		if pattern.NumSubexp() == 0 {
+			value = pattern.ReplaceAllLiteralString(value, RedactedValue)
+			continue
+		}
+
+		matches := pattern.FindAllStringSubmatchIndex(value, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var builder strings.Builder
+		last := 0
+		for _, match := range matches {
+			if match[2] < 0 {
+				continue
+			}
+			builder.WriteString(value[last:match[2]])
+			builder.WriteString(RedactedValue)
+			last = match[3]
+		}
+		builder.WriteString(value[last:])
+		value = builder.String()
+	}
+	return value
+}
@@ -0,0 +1,86 @@
+package accesslog
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestRedactor(t *testing.T) *Redactor {
+	t.Helper()
+	redactor, err := NewRedactor([]string{"authorization"}, []string{"password", "access_token", "user.ssn"}, nil)
+	if err != nil {
+		t.Fatalf("NewRedactor() error = %v", err)
+	}
+	return redactor
+}
+
+func TestRedactor_RedactBodyJSON(t *testing.T) {
+	redactor := newTestRedactor(t)
+
+	body := redactor.RedactBody(`{"username":"alice","password":"hunter2","user":{"ssn":"123-45-6789"}}`)
+	if strings.Contains(body, "hunter2") || strings.Contains(body, "123-45-6789") {
+		t.Errorf("Expected sensitive fields to be redacted, got %s", body)
+	}
+	if !strings.Contains(body, `"username":"alice"`) {
+		t.Errorf("Expected other fields to be kept, got %s", body)
+	}
+
+	// 无匹配字段时原样返回
+	if body := redactor.RedactBody(`{ "name": "alice" }`); body != `{ "name": "alice" }` {
+		t.Errorf("Expected body without sensitive fields to be unchanged, got %s", body)
+	}
+}
+
+func TestRedactor_RedactBodyTruncatedJSON(t *testing.T) {
+	redactor := newTestRedactor(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		secret string
+	}{
+		{"truncated after value", `{"password":"hunter2","items":[1,2,3...[truncated]`, "hunter2"},
+		{"truncated inside value", `{"name":"alice","access_token":"eyJhbGciOiJIUzI1NiJ9.eyJzdWIi...[truncated]`, "eyJhbGci"},
+		{"non-string value", `{"PASSWORD": 123456, "data": "...[truncated]`, "123456"},
+		{"escaped quote", `{"password":"a\"b\"secret","next":1,...[truncated]`, "secret"},
+		{"websocket frame", `{"type":"auth","access_token":"tok_live_abc"} trailing`, "tok_live_abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := redactor.RedactBody(tt.body)
+			if strings.Contains(body, tt.secret) {
+				t.Errorf("Expected %q to be redacted, got %s", tt.secret, body)
+			}
+			if !strings.Contains(body, RedactedValue) {
+				t.Errorf("Expected redacted marker in %s", body)
+			}
+			if strings.HasSuffix(tt.body, truncatedMarker) && !strings.HasSuffix(body, truncatedMarker) {
+				t.Errorf("Expected truncation marker to be kept, got %s", body)
+			}
+		})
+	}
+
+	// 回退替换只影响匹配的字段
+	if body := redactor.RedactBody(`{"name":"alice","note":"password reset...[truncated]`); body != `{"name":"alice","note":"password reset...[truncated]` {
+		t.Errorf("Expected unrelated content to be unchanged, got %s", body)
+	}
+}
+
+func TestRedactor_ApplyTruncatedBodies(t *testing.T) {
+	redactor := newTestRedactor(t)
+
+	log := &AccessLog{
+		RequestHeaders:  map[string]string{"Authorization": "Bearer secret"},
+		RequestBody:     `{"password":"hunter2","padding":"` + strings.Repeat("x", 64) + truncatedMarker,
+		ResponseBody:    `{"access_token":"tok_live_abc","expires_in":3600,` + truncatedMarker,
+		WebSocketFrames: []WebSocketFrame{{Payload: `{"access_token":"tok_ws_abc"` + truncatedMarker}},
+	}
+	redactor.Apply(log)
+
+	for _, value := range []string{log.RequestHeaders["Authorization"], log.RequestBody, log.ResponseBody, log.WebSocketFrames[0].Payload} {
+		if strings.Contains(value, "secret") || strings.Contains(value, "hunter2") || strings.Contains(value, "tok_") {
+			t.Errorf("Expected sensitive value to be redacted, got %s", value)
+		}
+	}
+}
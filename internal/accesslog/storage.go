@@ -16,9 +16,15 @@ type Storage interface {
 	// GetByID 根据ID获取单个日志记录
 	GetByID(id string) (*AccessLog, error)
 
+	// Aggregate 对匹配筛选条件的日志做聚合统计
+	Aggregate(filter *LogFilter, interval time.Duration) (*AggregateStats, error)
+
 	// GetStats 获取存储统计信息
 	GetStats() *StorageStats
 
+	// Cleanup 按保留策略清理日志，返回清理的条数
+	Cleanup() int
+
 	// Clear 清空所有日志
 	Clear()
 
@@ -36,13 +42,11 @@ type MemoryStorage struct {
 	retentionHours int         // 保留时间（小时）
 	maxBodySize    int         // 响应体最大大小
 
+	compressThreshold int // 请求体/响应体超过该大小（字节）时压缩存储（0表示不压缩）
+
 	mutex        sync.RWMutex // 读写锁
 	cleanupCount int64        // 清理次数
 	lastCleanup  time.Time    // 最后清理时间
-
-	// 清理相关
-	cleanupTicker *time.Ticker
-	stopCleanup   chan struct{}
 }
 
 // NewMemoryStorage 创建新的内存存储
@@ -57,15 +61,18 @@ func NewMemoryStorage(maxEntries int, maxMemoryMB float64, retentionHours int, m
 		maxBodySize:    maxBodySize,
 		cleanupCount:   0,
 		lastCleanup:    time.Now(),
-		stopCleanup:    make(chan struct{}),
 	}
 
-	// 启动定期清理
-	storage.startCleanup()
-
 	return storage
 }
 
+// SetCompressThreshold 设置请求体/响应体压缩存储的阈值（字节），0表示不压缩
+func (s *MemoryStorage) SetCompressThreshold(threshold int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.compressThreshold = threshold
+}
+
 // Add 添加日志记录
 func (s *MemoryStorage) Add(log *AccessLog) error {
 	if log == nil {
@@ -90,8 +97,10 @@ func (s *MemoryStorage) Add(log *AccessLog) error {
 		s.forceCleanup()
 	}
 
-	// 添加到环形缓冲区
-	s.logs[s.head] = *log
+	// 添加到环形缓冲区（压缩只作用于存储的副本，调用方的日志保持原样）
+	entry := *log
+	entry.compressBodies(s.compressThreshold)
+	s.logs[s.head] = entry
 	s.head = (s.head + 1) % s.maxEntries
 
 	if s.size < s.maxEntries {
@@ -121,12 +130,7 @@ func (s *MemoryStorage) Query(filter *LogFilter) (*LogResponse, error) {
 
 	for i := 0; i < s.size; i++ {
 		// 计算实际索引（从最老的开始）
-		var idx int
-		if s.size < s.maxEntries {
-			idx = i
-		} else {
-			idx = (s.head + i) % s.maxEntries
-		}
+		idx := s.index(i)
 
 		log := s.logs[idx]
 
@@ -157,6 +161,13 @@ func (s *MemoryStorage) Query(filter *LogFilter) (*LogResponse, error) {
 		matchedLogs = matchedLogs[start:end]
 	}
 
+	// 只解压当前页的日志
+	for i := range matchedLogs {
+		if matchedLogs[i].isCompressed() {
+			matchedLogs[i] = matchedLogs[i].decompressed()
+		}
+	}
+
 	return &LogResponse{
 		Logs:       matchedLogs,
 		Total:      total,
@@ -166,6 +177,31 @@ func (s *MemoryStorage) Query(filter *LogFilter) (*LogResponse, error) {
 	}, nil
 }
 
+// Aggregate 对匹配筛选条件的日志做聚合统计（忽略分页参数）
+func (s *MemoryStorage) Aggregate(filter *LogFilter, interval time.Duration) (*AggregateStats, error) {
+	if filter == nil {
+		filter = &LogFilter{}
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	aggregator := NewAggregator(interval)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for i := 0; i < s.size; i++ {
+		idx := s.index(i)
+
+		if s.matchesFilter(&s.logs[idx], filter) {
+			aggregator.Add(&s.logs[idx])
+		}
+	}
+
+	return aggregator.Result(), nil
+}
+
 // GetByID 根据ID获取单个日志记录
 func (s *MemoryStorage) GetByID(id string) (*AccessLog, error) {
 	if id == "" {
@@ -178,17 +214,12 @@ func (s *MemoryStorage) GetByID(id string) (*AccessLog, error) {
 	// 遍历所有日志查找匹配的ID
 	for i := 0; i < s.size; i++ {
 		// 计算实际索引
-		var idx int
-		if s.size < s.maxEntries {
-			idx = i
-		} else {
-			idx = (s.head + i) % s.maxEntries
-		}
+		idx := s.index(i)
 
 		log := s.logs[idx]
 		if log.ID == id {
-			// 返回日志的副本
-			logCopy := log
+			// 返回日志的副本（压缩存储的内容解压后返回）
+			logCopy := log.decompressed()
 			return &logCopy, nil
 		}
 	}
@@ -211,17 +242,8 @@ func (s *MemoryStorage) GetStats() *StorageStats {
 
 	// 获取最老和最新的日志时间
 	if s.size > 0 {
-		var oldestIdx, newestIdx int
-		if s.size < s.maxEntries {
-			oldestIdx = 0
-			newestIdx = s.size - 1
-		} else {
-			oldestIdx = s.head
-			newestIdx = (s.head - 1 + s.maxEntries) % s.maxEntries
-		}
-
-		stats.OldestEntry = s.logs[oldestIdx].Timestamp.Format(time.RFC3339)
-		stats.NewestEntry = s.logs[newestIdx].Timestamp.Format(time.RFC3339)
+		stats.OldestEntry = s.logs[s.index(0)].Timestamp.Format(time.RFC3339)
+		stats.NewestEntry = s.logs[s.index(s.size-1)].Timestamp.Format(time.RFC3339)
 	}
 
 	return stats
@@ -240,10 +262,6 @@ func (s *MemoryStorage) Clear() {
 
 // Close 关闭存储
 func (s *MemoryStorage) Close() error {
-	close(s.stopCleanup)
-	if s.cleanupTicker != nil {
-		s.cleanupTicker.Stop()
-	}
 	return nil
 }
 
@@ -259,12 +277,41 @@ func (s *MemoryStorage) matchesFilter(log *AccessLog, filter *LogFilter) bool {
 		return false
 	}
 
+	// HTTP方法筛选
+	if !ContainsMethod(filter.Method, log.Method) {
+		return false
+	}
+
+	// 代理配置筛选
+	if filter.ConfigID != "" && log.ConfigID != filter.ConfigID {
+		return false
+	}
+
+	// 客户端IP筛选
+	if !MatchesClientIP(log.ClientIP, filter.clientIPNet) {
+		return false
+	}
+
 	// 时间范围筛选
 	if !IsWithinTimeRange(log.Timestamp, filter.FromTime, filter.ToTime) {
 		return false
 	}
 
-	// 搜索关键词筛选
+	// 处理时长筛选
+	if !IsWithinDuration(log.Duration, filter.MinDuration, filter.MaxDuration) {
+		return false
+	}
+
+	// 慢请求筛选
+	if filter.Slow && !log.Slow {
+		return false
+	}
+
+	// 搜索关键词筛选（压缩存储的内容需要解压后再匹配）
+	if filter.Search != "" && log.isCompressed() {
+		plain := log.decompressed()
+		log = &plain
+	}
 	if !MatchesSearch(log, filter.Search) {
 		return false
 	}
@@ -277,12 +324,7 @@ func (s *MemoryStorage) calculateMemoryUsage() float64 {
 	var totalBytes int64
 
 	for i := 0; i < s.size; i++ {
-		var idx int
-		if s.size < s.maxEntries {
-			idx = i
-		} else {
-			idx = (s.head + i) % s.maxEntries
-		}
+		idx := s.index(i)
 		totalBytes += EstimateMemoryUsage(&s.logs[idx])
 	}
 
@@ -297,59 +339,58 @@ func (s *MemoryStorage) isMemoryLimitExceeded() bool {
 	return s.calculateMemoryUsage() > s.maxMemoryMB
 }
 
-// startCleanup 启动定期清理
-func (s *MemoryStorage) startCleanup() {
-	// 每5分钟清理一次
-	s.cleanupTicker = time.NewTicker(5 * time.Minute)
-
-	go func() {
-		for {
-			select {
-			case <-s.cleanupTicker.C:
-				s.performCleanup()
-			case <-s.stopCleanup:
-				return
-			}
-		}
-	}()
-}
-
-// performCleanup 执行清理操作
-func (s *MemoryStorage) performCleanup() {
+// Cleanup 执行保留策略：依次清理超过保留时间、超过条数上限和超过内存上限的最老日志，返回清理的条数
+func (s *MemoryStorage) Cleanup() int {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	if s.size == 0 {
-		return
+		return 0
 	}
 
-	cutoff := time.Now().Add(-time.Duration(s.retentionHours) * time.Hour)
-	cleaned := 0
+	removed := 0
 
-	// 从最老的日志开始检查
-	for i := 0; i < s.size; i++ {
-		var idx int
-		if s.size < s.maxEntries {
-			idx = i
-		} else {
-			idx = (s.head + i) % s.maxEntries
+	// 按保留时间清理
+	if s.retentionHours > 0 {
+		cutoff := time.Now().Add(-time.Duration(s.retentionHours) * time.Hour)
+		expired := 0
+		for expired < s.size && s.logs[s.index(expired)].Timestamp.Before(cutoff) {
+			expired++
 		}
+		s.removeOldest(expired)
+		removed += expired
+	}
+
+	// 按条数清理（环形缓冲区写入时已保证不超过上限，这里作为兜底）
+	if s.size > s.maxEntries {
+		excess := s.size - s.maxEntries
+		s.removeOldest(excess)
+		removed += excess
+	}
 
-		if s.logs[idx].Timestamp.Before(cutoff) {
-			// 清理过期日志
-			s.logs[idx] = AccessLog{} // 清空内存
-			cleaned++
-		} else {
-			break // 后面的日志都是更新的
+	// 按内存上限清理
+	if s.maxMemoryMB > 0 {
+		limit := int64(s.maxMemoryMB * 1024 * 1024)
+		var usage int64
+		for i := 0; i < s.size; i++ {
+			usage += EstimateMemoryUsage(&s.logs[s.index(i)])
 		}
+
+		excess := 0
+		for excess < s.size && usage > limit {
+			usage -= EstimateMemoryUsage(&s.logs[s.index(excess)])
+			excess++
+		}
+		s.removeOldest(excess)
+		removed += excess
 	}
 
-	if cleaned > 0 {
-		// 重新整理数组
-		s.compactArray(cleaned)
+	if removed > 0 {
 		s.cleanupCount++
 		s.lastCleanup = time.Now()
 	}
+
+	return removed
 }
 
 // forceCleanup 强制清理以释放内存
@@ -364,28 +405,24 @@ func (s *MemoryStorage) forceCleanup() {
 		cleanCount = 1
 	}
 
-	s.compactArray(cleanCount)
+	s.removeOldest(cleanCount)
 	s.cleanupCount++
 	s.lastCleanup = time.Now()
 }
 
-// compactArray 压缩数组，移除已清理的元素
-func (s *MemoryStorage) compactArray(removeCount int) {
-	if removeCount >= s.size {
-		s.head = 0
-		s.size = 0
-		return
+// index 将从最老日志开始的序号转换为数组下标
+func (s *MemoryStorage) index(i int) int {
+	return (s.head - s.size + i + s.maxEntries) % s.maxEntries
+}
+
+// removeOldest 移除最老的count条日志并释放其内存
+func (s *MemoryStorage) removeOldest(count int) {
+	if count > s.size {
+		count = s.size
 	}
 
-	// 移动数据
-	if s.size < s.maxEntries {
-		// 简单情况：数组未满
-		copy(s.logs[0:], s.logs[removeCount:s.size])
-		s.size -= removeCount
-	} else {
-		// 复杂情况：环形缓冲区
-		newHead := (s.head + removeCount) % s.maxEntries
-		s.head = newHead
-		s.size -= removeCount
+	for i := 0; i < count; i++ {
+		s.logs[s.index(i)] = AccessLog{}
 	}
+	s.size -= count
 }
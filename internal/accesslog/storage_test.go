@@ -0,0 +1,160 @@
+package accesslog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestLog 创建指定时间和响应体的日志
+func newTestLog(i int, timestamp time.Time, body string) *AccessLog {
+	return &AccessLog{
+		ID:           fmt.Sprintf("log-%d", i),
+		Timestamp:    timestamp,
+		Method:       "GET",
+		TargetHost:   "example.com",
+		TargetPath:   fmt.Sprintf("/items/%d", i),
+		StatusCode:   500,
+		ResponseBody: body,
+	}
+}
+
+// oldestID 获取最老日志的ID
+func oldestID(t *testing.T, storage *MemoryStorage) string {
+	t.Helper()
+	response, err := storage.Query(&LogFilter{Page: 1, Limit: storage.maxEntries})
+	if err != nil || len(response.Logs) == 0 {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	// Query按时间倒序返回
+	return response.Logs[len(response.Logs)-1].ID
+}
+
+func TestMemoryStorage_CleanupByAge(t *testing.T) {
+	storage := NewMemoryStorage(10, 0, 1, 1024)
+	defer storage.Close()
+
+	// 环形缓冲区写满后继续写入，最老的日志已过期
+	now := time.Now()
+	for i := 0; i < 14; i++ {
+		timestamp := now
+		if i < 8 {
+			timestamp = now.Add(-2 * time.Hour)
+		}
+		if err := storage.Add(newTestLog(i, timestamp, "")); err != nil {
+			t.Fatalf("Failed to add log: %v", err)
+		}
+	}
+
+	if removed := storage.Cleanup(); removed != 4 {
+		t.Errorf("Expected 4 expired logs to be removed, got %d", removed)
+	}
+
+	stats := storage.GetStats()
+	if stats.CurrentEntries != 6 || stats.CleanupCount != 1 {
+		t.Errorf("Expected 6 entries after 1 cleanup, got %d entries and %d cleanups", stats.CurrentEntries, stats.CleanupCount)
+	}
+	if id := oldestID(t, storage); id != "log-8" {
+		t.Errorf("Expected oldest remaining log to be log-8, got %s", id)
+	}
+
+	// 清理后继续写入，顺序保持不变
+	for i := 14; i < 20; i++ {
+		storage.Add(newTestLog(i, now, ""))
+	}
+	if stats := storage.GetStats(); stats.CurrentEntries != 10 {
+		t.Errorf("Expected storage to be capped at 10 entries, got %d", stats.CurrentEntries)
+	}
+	if id := oldestID(t, storage); id != "log-10" {
+		t.Errorf("Expected oldest log to be log-10, got %s", id)
+	}
+
+	// 没有需要清理的日志时不增加清理次数
+	if removed := storage.Cleanup(); removed != 0 {
+		t.Errorf("Expected nothing to be removed, got %d", removed)
+	}
+	if stats := storage.GetStats(); stats.CleanupCount != 1 {
+		t.Errorf("Expected cleanup count to stay at 1, got %d", stats.CleanupCount)
+	}
+}
+
+func TestMemoryStorage_CleanupByMemory(t *testing.T) {
+	storage := NewMemoryStorage(100, 0, 24, 200*1024)
+	defer storage.Close()
+
+	body := strings.Repeat("x", 100*1024)
+	for i := 0; i < 20; i++ {
+		storage.Add(newTestLog(i, time.Now(), body))
+	}
+
+	// 降低内存上限后执行清理，保留最新的日志
+	storage.maxMemoryMB = 1
+	removed := storage.Cleanup()
+
+	stats := storage.GetStats()
+	if stats.MemoryUsageMB > 1 {
+		t.Errorf("Expected memory usage to be trimmed below 1MB, got %.2fMB", stats.MemoryUsageMB)
+	}
+	if removed == 0 || stats.CurrentEntries != 20-removed || stats.CurrentEntries < 9 {
+		t.Errorf("Expected only the oldest logs to be removed, got %d removed and %d remaining", removed, stats.CurrentEntries)
+	}
+	if id := oldestID(t, storage); id != fmt.Sprintf("log-%d", removed) {
+		t.Errorf("Expected oldest remaining log to be log-%d, got %s", removed, id)
+	}
+}
+
+func TestMemoryStorage_CompressesLargeBodies(t *testing.T) {
+	plain := NewMemoryStorage(10, 0, 24, 64*1024)
+	compressed := NewMemoryStorage(10, 0, 24, 64*1024)
+	compressed.SetCompressThreshold(1024)
+
+	body := strings.Repeat(`{"error":"upstream timeout","retry":true}`, 500)
+	requestBody := strings.Repeat("field=value&", 200)
+	for _, storage := range []*MemoryStorage{plain, compressed} {
+		log := newTestLog(1, time.Now(), body)
+		log.RequestBody = requestBody
+		if err := storage.Add(log); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		// 小于阈值的内容不压缩
+		storage.Add(newTestLog(2, time.Now(), "small body"))
+	}
+
+	// 存储内部保存的是压缩数据
+	stored := compressed.logs[0]
+	if stored.ResponseBody != "" || len(stored.responseBodyGz) == 0 || len(stored.requestBodyGz) == 0 {
+		t.Fatal("Expected large bodies to be stored compressed")
+	}
+	if compressed.logs[1].isCompressed() {
+		t.Error("Expected small body to be stored as-is")
+	}
+
+	// 内存统计按压缩后大小计算
+	if compressed.GetStats().MemoryUsageMB >= plain.GetStats().MemoryUsageMB/10 {
+		t.Errorf("Expected compressed memory usage to be much smaller, got %.4f vs %.4f MB",
+			compressed.GetStats().MemoryUsageMB, plain.GetStats().MemoryUsageMB)
+	}
+
+	// 读取时返回原始内容
+	log, err := compressed.GetByID("log-1")
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if log.ResponseBody != body || log.RequestBody != requestBody {
+		t.Error("Expected GetByID to return the original bodies")
+	}
+
+	response, err := compressed.Query(&LogFilter{Search: "upstream timeout"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(response.Logs) != 1 || response.Logs[0].ResponseBody != body {
+		t.Error("Expected search to match compressed body and return it decompressed")
+	}
+
+	// 读取不会改变存储中的压缩数据
+	if !compressed.logs[0].isCompressed() {
+		t.Error("Expected stored entry to stay compressed after reads")
+	}
+}
@@ -1,6 +1,7 @@
 package accesslog
 
 import (
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -10,6 +11,7 @@ import (
 // AccessLog 访问日志记录结构
 type AccessLog struct {
 	ID             string            `json:"id"`                        // 唯一标识符
+	RequestID      string            `json:"request_id,omitempty"`      // 请求ID（与响应头及上游请求头一致）
 	Timestamp      time.Time         `json:"timestamp"`                 // 请求时间戳
 	Method         string            `json:"method"`                    // HTTP 方法
 	RequestType    string            `json:"request_type"`              // 请求类型 (HTTP, HTTPS, WebSocket, SSE)
@@ -25,17 +27,45 @@ type AccessLog struct {
 	ResponseSize   int64             `json:"response_size,omitempty"`   // 响应大小（字节）
 	RequestHeaders map[string]string `json:"request_headers,omitempty"` // 请求头信息
 	RequestBody    string            `json:"request_body,omitempty"`    // 请求体内容
+	ConfigID       string            `json:"config_id,omitempty"`       // 路由使用的代理配置ID（按配置转发时）
+	ConfigName     string            `json:"config_name,omitempty"`     // 路由使用的代理配置名称
+	Slow           bool              `json:"slow,omitempty"`            // 处理时长是否超过慢请求阈值（SLOW_REQUEST_MS）
+	ErrorCode      string            `json:"error_code,omitempty"`      // 网关错误代码（如UPSTREAM_DNS_ERROR、UPSTREAM_TLS_ERROR、UPSTREAM_5XX）
+
+	WebSocketFrames        []WebSocketFrame `json:"websocket_frames,omitempty"`         // WebSocket帧记录（配置启用log_websocket_frames时）
+	WebSocketFramesDropped int              `json:"websocket_frames_dropped,omitempty"` // 超出记录上限未记录的帧数
+
+	responseBodyGz []byte // gzip压缩存储的响应体（仅存储内部使用，读取时解压）
+	requestBodyGz  []byte // gzip压缩存储的请求体（仅存储内部使用，读取时解压）
+}
+
+// WebSocketFrame WebSocket帧记录
+type WebSocketFrame struct {
+	Timestamp time.Time `json:"timestamp"`           // 帧转发时间
+	Direction string    `json:"direction"`           // 方向（client_to_target、target_to_client）
+	Opcode    int       `json:"opcode"`              // 帧类型（1文本、2二进制）
+	Size      int       `json:"size"`                // 负载大小（字节）
+	Payload   string    `json:"payload,omitempty"`   // 文本帧内容（截断），二进制帧不记录内容
+	Truncated bool      `json:"truncated,omitempty"` // 内容是否被截断
 }
 
 // LogFilter 日志筛选条件
 type LogFilter struct {
-	Domain     string    `json:"domain,omitempty"`      // 域名筛选
-	StatusCode []int     `json:"status_code,omitempty"` // 状态码筛选
-	FromTime   time.Time `json:"from_time,omitempty"`   // 开始时间
-	ToTime     time.Time `json:"to_time,omitempty"`     // 结束时间
-	Page       int       `json:"page"`                  // 页码（从1开始）
-	Limit      int       `json:"limit"`                 // 每页条数
-	Search     string    `json:"search,omitempty"`      // 搜索关键词
+	Domain      string    `json:"domain,omitempty"`          // 域名筛选
+	StatusCode  []int     `json:"status_code,omitempty"`     // 状态码筛选
+	Method      []string  `json:"method,omitempty"`          // HTTP方法筛选（大写）
+	ClientIP    string    `json:"client_ip,omitempty"`       // 客户端IP筛选（单个IP或CIDR，支持IPv6）
+	MinDuration int64     `json:"min_duration_ms,omitempty"` // 最短处理时长（毫秒，0表示不限制）
+	MaxDuration int64     `json:"max_duration_ms,omitempty"` // 最长处理时长（毫秒，0表示不限制）
+	FromTime    time.Time `json:"from_time,omitempty"`       // 开始时间
+	ToTime      time.Time `json:"to_time,omitempty"`         // 结束时间
+	Page        int       `json:"page"`                      // 页码（从1开始）
+	Limit       int       `json:"limit"`                     // 每页条数
+	Search      string    `json:"search,omitempty"`          // 搜索关键词
+	ConfigID    string    `json:"config_id,omitempty"`       // 代理配置ID筛选（精确匹配）
+	Slow        bool      `json:"slow,omitempty"`            // 只返回标记为慢请求的日志
+
+	clientIPNet *net.IPNet // 解析后的客户端IP筛选网段（Validate时设置）
 }
 
 // LogResponse 日志查询响应
@@ -96,6 +126,11 @@ func DetermineRequestType(r *http.Request, endpoint string) string {
 	return RequestTypeHTTP
 }
 
+// IsEventStream 检查Content-Type是否为SSE事件流
+func IsEventStream(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "text/event-stream")
+}
+
 // DetermineRequestTypeWithResponse 根据请求信息和响应头确定请求类型
 func DetermineRequestTypeWithResponse(r *http.Request, endpoint string, responseHeaders map[string]string) string {
 	// 如果是WebSocket端点
@@ -104,10 +139,8 @@ func DetermineRequestTypeWithResponse(r *http.Request, endpoint string, response
 	}
 
 	// 检查响应的Content-Type是否是text/event-stream
-	if contentType, exists := responseHeaders["Content-Type"]; exists {
-		if strings.Contains(strings.ToLower(contentType), "text/event-stream") {
-			return RequestTypeSSE
-		}
+	if contentType, exists := responseHeaders["Content-Type"]; exists && IsEventStream(contentType) {
+		return RequestTypeSSE
 	}
 
 	// 根据目标URL确定HTTP/HTTPS
@@ -204,6 +237,18 @@ func (filter *LogFilter) Validate() error {
 	if !filter.FromTime.IsZero() && !filter.ToTime.IsZero() && filter.FromTime.After(filter.ToTime) {
 		return ErrInvalidTimeRange
 	}
+	if filter.MinDuration < 0 || filter.MaxDuration < 0 ||
+		(filter.MaxDuration > 0 && filter.MinDuration > filter.MaxDuration) {
+		return ErrInvalidDuration
+	}
+	filter.clientIPNet = nil
+	if filter.ClientIP != "" {
+		network, err := ParseClientIPFilter(filter.ClientIP)
+		if err != nil {
+			return err
+		}
+		filter.clientIPNet = network
+	}
 	return nil
 }
 
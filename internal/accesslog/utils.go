@@ -168,6 +168,65 @@ func ContainsStatusCode(codes []int, target int) bool {
 	return false
 }
 
+// ContainsMethod 检查HTTP方法是否在指定的列表中（不区分大小写）
+func ContainsMethod(methods []string, target string) bool {
+	if len(methods) == 0 {
+		return true // 空列表表示不筛选
+	}
+	for _, method := range methods {
+		if strings.EqualFold(method, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClientIPFilter 解析客户端IP筛选条件，单个IP视为只包含该地址的网段
+func ParseClientIPFilter(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, ErrInvalidClientIP
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, ErrInvalidClientIP
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return &net.IPNet{IP: ipv4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// MatchesClientIP 检查客户端IP是否在筛选网段内（network为nil表示不筛选）
+func MatchesClientIP(clientIP string, network *net.IPNet) bool {
+	if network == nil {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		if host, _, err := net.SplitHostPort(clientIP); err == nil {
+			ip = net.ParseIP(host)
+		}
+	}
+	return ip != nil && network.Contains(ip)
+}
+
+// IsWithinDuration 检查处理时长是否在指定范围内（边界为0表示不限制）
+func IsWithinDuration(duration, min, max int64) bool {
+	if min > 0 && duration < min {
+		return false
+	}
+	if max > 0 && duration > max {
+		return false
+	}
+	return true
+}
+
 // IsWithinTimeRange 检查时间是否在指定范围内
 func IsWithinTimeRange(t, from, to time.Time) bool {
 	if from.IsZero() && to.IsZero() {
@@ -223,6 +282,10 @@ func EstimateMemoryUsage(log *AccessLog) int64 {
 	size += 8 // StatusCode (int)
 	size += 8 // ResponseBody (string header)
 	size += int64(len(log.ResponseBody))
+	size += 8 // RequestBody (string header)
+	size += int64(len(log.RequestBody))
+	// 压缩存储的内容按压缩后大小计算
+	size += int64(len(log.responseBodyGz) + len(log.requestBodyGz))
 	size += 8 // UserAgent (string header)
 	size += int64(len(log.UserAgent))
 	size += 8 // ClientIP (string header)
@@ -231,6 +294,11 @@ func EstimateMemoryUsage(log *AccessLog) int64 {
 	size += 8 // RequestSize (int64)
 	size += 8 // ResponseSize (int64)
 
+	// WebSocket帧记录
+	for _, frame := range log.WebSocketFrames {
+		size += 64 + int64(len(frame.Direction)+len(frame.Payload))
+	}
+
 	return size
 }
 
@@ -243,6 +311,11 @@ func MatchesSearch(log *AccessLog, search string) bool {
 	// 转换为小写进行不区分大小写的搜索
 	search = strings.ToLower(search)
 
+	// 搜索请求ID
+	if strings.Contains(strings.ToLower(log.RequestID), search) {
+		return true
+	}
+
 	// 搜索目标主机
 	if strings.Contains(strings.ToLower(log.TargetHost), search) {
 		return true
@@ -278,6 +351,11 @@ func MatchesSearch(log *AccessLog, search string) bool {
 		return true
 	}
 
+	// 搜索代理配置名称
+	if strings.Contains(strings.ToLower(log.ConfigName), search) {
+		return true
+	}
+
 	// 搜索响应体内容（仅非200状态码）
 	if log.StatusCode != 200 && strings.Contains(strings.ToLower(log.ResponseBody), search) {
 		return true
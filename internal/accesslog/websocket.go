@@ -0,0 +1,73 @@
+package accesslog
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket帧方向
+const (
+	FrameDirectionClientToTarget = "client_to_target"
+	FrameDirectionTargetToClient = "target_to_client"
+)
+
+const (
+	textFrameOpcode    = 1   // 文本帧
+	maxWebSocketFrames = 100 // 单个会话最多记录的帧数
+)
+
+// FrameLog 收集单个WebSocket会话的帧记录（并发安全，nil接收者不做处理）
+//
+// 记录只在内存中追加截断后的内容，不会阻塞消息转发；超过上限的帧只计数。
+type FrameLog struct {
+	mutex      sync.Mutex
+	maxPayload int
+	frames     []WebSocketFrame
+	dropped    int
+}
+
+// NewFrameLog 创建帧记录器，maxPayload为文本帧内容的最大记录字节数
+func NewFrameLog(maxPayload int) *FrameLog {
+	return &FrameLog{maxPayload: maxPayload}
+}
+
+// Add 记录一帧，二进制帧只记录类型和大小
+func (f *FrameLog) Add(direction string, opcode int, payload []byte) {
+	if f == nil {
+		return
+	}
+
+	frame := WebSocketFrame{
+		Timestamp: time.Now(),
+		Direction: direction,
+		Opcode:    opcode,
+		Size:      len(payload),
+	}
+	if opcode == textFrameOpcode {
+		content := payload
+		if len(content) > f.maxPayload {
+			content = content[:f.maxPayload]
+			frame.Truncated = true
+		}
+		frame.Payload = strings.ToValidUTF8(string(content), "")
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(f.frames) >= maxWebSocketFrames {
+		f.dropped++
+		return
+	}
+	f.frames = append(f.frames, frame)
+}
+
+// Frames 获取已记录的帧及因超出上限未记录的帧数
+func (f *FrameLog) Frames() ([]WebSocketFrame, int) {
+	if f == nil {
+		return nil, 0
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]WebSocketFrame(nil), f.frames...), f.dropped
+}
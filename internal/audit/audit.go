@@ -0,0 +1,317 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 操作者类型
+const (
+	ActorAdmin = "admin" // 管理员密钥
+	ActorToken = "token" // 访问令牌
+	ActorOwner = "owner" // 配置的租户管理密钥
+)
+
+// 审计动作
+const (
+	ActionConfigCreate     = "config.create"
+	ActionConfigUpdate     = "config.update"
+	ActionConfigDelete     = "config.delete"
+	ActionConfigRestore    = "config.restore"
+	ActionConfigImport     = "config.import"
+	ActionConfigBatch      = "config.batch"
+	ActionConfigStatsReset = "config.stats_reset"
+	ActionTokenCreate      = "token.create"
+	ActionTokenUpdate      = "token.update"
+	ActionTokenDelete      = "token.delete"
+	ActionTokenStatsReset  = "token.stats_reset"
+	ActionMetricsReset     = "metrics.reset"
+)
+
+// ErrChainBroken 审计记录哈希链校验失败
+var ErrChainBroken = errors.New("audit chain verification failed")
+
+// Entry 审计记录
+//
+// 每条记录包含上一条记录的哈希（PrevHash）以及自身内容的哈希（Hash），
+// 构成哈希链：任何记录被修改、删除或重排都会导致校验失败。
+type Entry struct {
+	ID        string                 `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Actor     string                 `json:"actor"`               // admin / token / owner
+	Action    string                 `json:"action"`              // 如 config.create、token.delete
+	ConfigID  string                 `json:"config_id,omitempty"` // 目标配置ID
+	TokenID   string                 `json:"token_id,omitempty"`  // 目标令牌ID
+	ClientIP  string                 `json:"client_ip"`
+	Details   map[string]interface{} `json:"details,omitempty"` // 附加信息
+	PrevHash  string                 `json:"prev_hash"`
+	Hash      string                 `json:"hash"`
+}
+
+// computeHash 计算记录哈希（不包含Hash字段本身）
+func (e *Entry) computeHash() string {
+	copyEntry := *e
+	copyEntry.Hash = ""
+	data, _ := json.Marshal(copyEntry)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Filter 审计记录筛选条件
+type Filter struct {
+	Actor    string    `json:"actor,omitempty"`
+	Action   string    `json:"action,omitempty"`
+	ConfigID string    `json:"config_id,omitempty"`
+	FromTime time.Time `json:"from_time,omitempty"`
+	ToTime   time.Time `json:"to_time,omitempty"`
+	Search   string    `json:"search,omitempty"`
+	Page     int       `json:"page"`
+	Limit    int       `json:"limit"`
+}
+
+// Response 审计记录查询响应
+type Response struct {
+	Entries    []Entry `json:"entries"`
+	Total      int     `json:"total"`
+	Page       int     `json:"page"`
+	Limit      int     `json:"limit"`
+	TotalPages int     `json:"total_pages"`
+}
+
+// Recorder 审计记录器
+//
+// 记录保存在内存中（超过上限时丢弃最旧的记录），配置了文件路径时同时以
+// JSON Lines格式追加写入文件，重启后从文件恢复并继续哈希链。
+type Recorder struct {
+	entries    []Entry
+	maxEntries int
+	lastHash   string
+	file       *os.File
+	mutex      sync.RWMutex
+}
+
+// NewRecorder 创建审计记录器，filePath为空时仅保存在内存中
+func NewRecorder(maxEntries int, filePath string) (*Recorder, error) {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+
+	r := &Recorder{
+		entries:    make([]Entry, 0),
+		maxEntries: maxEntries,
+	}
+
+	if filePath == "" {
+		return r, nil
+	}
+
+	if err := r.loadFromFile(filePath); err != nil {
+		return nil, err
+	}
+
+	if dir := filepath.Dir(filePath); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %w", err)
+	}
+	r.file = file
+
+	return r, nil
+}
+
+// loadFromFile 从审计文件恢复记录
+func (r *Recorder) loadFromFile(filePath string) error {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open audit log file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return fmt.Errorf("failed to parse audit log file: %w", err)
+		}
+		r.appendLocked(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log file: %w", err)
+	}
+
+	return nil
+}
+
+// Record 追加一条审计记录
+func (r *Recorder) Record(entry Entry) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.PrevHash = r.lastHash
+	entry.Hash = entry.computeHash()
+
+	if r.file != nil {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+		if _, err := r.file.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write audit entry: %w", err)
+		}
+	}
+
+	r.appendLocked(entry)
+	return nil
+}
+
+// appendLocked 将记录加入内存并更新链尾哈希（调用方需持有锁）
+func (r *Recorder) appendLocked(entry Entry) {
+	r.entries = append(r.entries, entry)
+	if len(r.entries) > r.maxEntries {
+		r.entries = r.entries[len(r.entries)-r.maxEntries:]
+	}
+	r.lastHash = entry.Hash
+}
+
+// Query 查询审计记录（按时间倒序）
+func (r *Recorder) Query(filter *Filter) *Response {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	page, limit := filter.Page, filter.Limit
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var matched []Entry
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if matchesFilter(&r.entries[i], filter) {
+			matched = append(matched, r.entries[i])
+		}
+	}
+
+	total := len(matched)
+	start := (page - 1) * limit
+	end := start + limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	entries := matched[start:end]
+	if entries == nil {
+		entries = []Entry{}
+	}
+
+	return &Response{
+		Entries:    entries,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+	}
+}
+
+// matchesFilter 检查记录是否满足筛选条件
+func matchesFilter(entry *Entry, filter *Filter) bool {
+	if filter.Actor != "" && entry.Actor != filter.Actor {
+		return false
+	}
+	if filter.Action != "" && entry.Action != filter.Action && !strings.HasPrefix(entry.Action, filter.Action+".") {
+		return false
+	}
+	if filter.ConfigID != "" && entry.ConfigID != filter.ConfigID {
+		return false
+	}
+	if !filter.FromTime.IsZero() && entry.Timestamp.Before(filter.FromTime) {
+		return false
+	}
+	if !filter.ToTime.IsZero() && entry.Timestamp.After(filter.ToTime) {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(entry.Action), search) &&
+			!strings.Contains(strings.ToLower(entry.ConfigID), search) &&
+			!strings.Contains(strings.ToLower(entry.TokenID), search) &&
+			!strings.Contains(entry.ClientIP, search) {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify 校验内存中记录的哈希链是否完整
+func (r *Recorder) Verify() error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for i := range r.entries {
+		entry := &r.entries[i]
+		if entry.Hash != entry.computeHash() {
+			return fmt.Errorf("%w: entry %s has been modified", ErrChainBroken, entry.ID)
+		}
+		if i > 0 && entry.PrevHash != r.entries[i-1].Hash {
+			return fmt.Errorf("%w: entry %s does not follow %s", ErrChainBroken, entry.ID, r.entries[i-1].ID)
+		}
+	}
+	return nil
+}
+
+// Count 返回内存中的记录数
+func (r *Recorder) Count() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return len(r.entries)
+}
+
+// Close 关闭审计文件
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
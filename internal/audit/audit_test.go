@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_RecordBuildsHashChain(t *testing.T) {
+	r, err := NewRecorder(100, "")
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	r.Record(Entry{Actor: ActorAdmin, Action: ActionConfigCreate, ConfigID: "c1", ClientIP: "10.0.0.1"})
+	r.Record(Entry{Actor: ActorAdmin, Action: ActionTokenDelete, ConfigID: "c1", TokenID: "t1", ClientIP: "10.0.0.1"})
+
+	if err := r.Verify(); err != nil {
+		t.Fatalf("Expected valid chain, got %v", err)
+	}
+
+	if r.entries[1].PrevHash != r.entries[0].Hash {
+		t.Error("Expected second entry to reference first entry hash")
+	}
+
+	// 篡改记录后校验应失败
+	r.entries[0].ConfigID = "tampered"
+	if err := r.Verify(); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("Expected ErrChainBroken after tampering, got %v", err)
+	}
+}
+
+func TestRecorder_QueryFiltersAndPaginates(t *testing.T) {
+	r, _ := NewRecorder(100, "")
+
+	for i := 0; i < 5; i++ {
+		r.Record(Entry{Actor: ActorAdmin, Action: ActionConfigUpdate, ConfigID: "c1"})
+	}
+	r.Record(Entry{Actor: ActorAdmin, Action: ActionTokenDelete, ConfigID: "c2", TokenID: "t1"})
+
+	response := r.Query(&Filter{Action: "config", Page: 1, Limit: 2})
+	if response.Total != 5 || len(response.Entries) != 2 || response.TotalPages != 3 {
+		t.Errorf("Unexpected pagination: total=%d entries=%d pages=%d", response.Total, len(response.Entries), response.TotalPages)
+	}
+
+	response = r.Query(&Filter{ConfigID: "c2"})
+	if response.Total != 1 || response.Entries[0].Action != ActionTokenDelete {
+		t.Errorf("Expected single token.delete entry for c2, got %+v", response.Entries)
+	}
+
+	// 最新记录在前
+	response = r.Query(&Filter{})
+	if response.Entries[0].Action != ActionTokenDelete {
+		t.Errorf("Expected newest entry first, got %s", response.Entries[0].Action)
+	}
+}
+
+func TestRecorder_FilePersistenceContinuesChain(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "audit", "audit.log")
+
+	r, err := NewRecorder(100, filePath)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	r.Record(Entry{Actor: ActorAdmin, Action: ActionConfigCreate, ConfigID: "c1"})
+	r.Close()
+
+	reopened, err := NewRecorder(100, filePath)
+	if err != nil {
+		t.Fatalf("Failed to reopen recorder: %v", err)
+	}
+	defer reopened.Close()
+
+	if reopened.Count() != 1 {
+		t.Fatalf("Expected 1 restored entry, got %d", reopened.Count())
+	}
+
+	reopened.Record(Entry{Actor: ActorAdmin, Action: ActionConfigDelete, ConfigID: "c1"})
+	if err := reopened.Verify(); err != nil {
+		t.Errorf("Expected chain to continue across restarts, got %v", err)
+	}
+}
+
+func TestRecorder_VerifyDetectsTamperedFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "audit.log")
+
+	r, _ := NewRecorder(100, filePath)
+	r.Record(Entry{Actor: ActorAdmin, Action: ActionTokenCreate, ConfigID: "c1", TokenID: "t1"})
+	r.Record(Entry{Actor: ActorAdmin, Action: ActionTokenDelete, ConfigID: "c1", TokenID: "t1"})
+	r.Close()
+
+	// 直接修改文件中的记录，重启后恢复的链应校验失败
+	data, _ := os.ReadFile(filePath)
+	tampered := strings.Replace(string(data), `"action":"token.delete"`, `"action":"token.update"`, 1)
+	if tampered == string(data) {
+		t.Fatal("Expected audit file to contain token.delete entry")
+	}
+	os.WriteFile(filePath, []byte(tampered), 0600)
+
+	reopened, err := NewRecorder(100, filePath)
+	if err != nil {
+		t.Fatalf("Failed to reopen recorder: %v", err)
+	}
+	defer reopened.Close()
+
+	if err := reopened.Verify(); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("Expected ErrChainBroken for tampered file, got %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry 缓存的响应
+type Entry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	ExpiresAt  time.Time
+	Vary       map[string]string // 响应Vary中列出的请求头及存储时请求中的取值
+}
+
+// size 估算条目占用的内存（字节）
+func (e *Entry) size() int64 {
+	size := int64(len(e.Body))
+	for key, values := range e.Header {
+		size += int64(len(key))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	for name, value := range e.Vary {
+		size += int64(len(name) + len(value))
+	}
+	return size
+}
+
+// cacheItem LRU链表中的元素
+type cacheItem struct {
+	key   string
+	entry *Entry
+	size  int64
+}
+
+// ResponseCache 基于LRU的内存响应缓存，按总字节数限制容量
+type ResponseCache struct {
+	maxBytes  int64                    // 最大内存使用（字节）
+	usedBytes int64                    // 当前内存使用（字节）
+	lru       *list.List               // 最近使用在前
+	items     map[string]*list.Element // 键到链表元素的索引
+	mutex     sync.Mutex
+
+	now func() time.Time // 时间源（便于测试）
+}
+
+// NewResponseCache 创建响应缓存
+func NewResponseCache(maxBytes int64) *ResponseCache {
+	return &ResponseCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get 获取未过期的缓存条目，过期条目会被直接移除
+func (c *ResponseCache) Get(key string) (*Entry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+
+	item := elem.Value.(*cacheItem)
+	if !c.now().Before(item.entry.ExpiresAt) {
+		c.removeElementLocked(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return item.entry, true
+}
+
+// Set 写入缓存条目，超过容量时淘汰最久未使用的条目
+//
+// 单个条目超过缓存总容量时不会被缓存，返回false。
+func (c *ResponseCache) Set(key string, entry *Entry) bool {
+	size := entry.size() + int64(len(key))
+	if c.maxBytes <= 0 || size > c.maxBytes {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, exists := c.items[key]; exists {
+		c.removeElementLocked(elem)
+	}
+
+	for c.usedBytes+size > c.maxBytes {
+		oldest := c.lru.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeElementLocked(oldest)
+	}
+
+	c.items[key] = c.lru.PushFront(&cacheItem{key: key, entry: entry, size: size})
+	c.usedBytes += size
+	return true
+}
+
+// Delete 删除缓存条目
+func (c *ResponseCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, exists := c.items[key]; exists {
+		c.removeElementLocked(elem)
+	}
+}
+
+// Len 返回缓存条目数量
+func (c *ResponseCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.items)
+}
+
+// UsedBytes 返回当前内存使用（字节）
+func (c *ResponseCache) UsedBytes() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.usedBytes
+}
+
+// MaxBytes 返回最大内存使用（字节）
+func (c *ResponseCache) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// removeElementLocked 移除链表元素（调用方需持有锁）
+func (c *ResponseCache) removeElementLocked(elem *list.Element) {
+	item := elem.Value.(*cacheItem)
+	c.lru.Remove(elem)
+	delete(c.items, item.key)
+	c.usedBytes -= item.size
+}
+
+// hasDirective 判断Cache-Control中是否包含指定指令（忽略大小写和指令参数）
+func hasDirective(header http.Header, names ...string) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if i := strings.IndexByte(directive, '='); i >= 0 {
+				directive = strings.TrimSpace(directive[:i])
+			}
+			for _, name := range names {
+				if strings.EqualFold(directive, name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// allowsAuthorized 判断响应是否允许共享缓存用于带Authorization的请求（RFC 9111 3.5）
+func allowsAuthorized(header http.Header) bool {
+	return hasDirective(header, "public", "s-maxage", "must-revalidate")
+}
+
+// varyHeaders 返回响应Vary中列出的请求头（规范化名称），Vary为*时返回false
+func varyHeaders(header http.Header) ([]string, bool) {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names, true
+}
+
+// IsStorable 按共享缓存规则（RFC 9111）判断上游响应是否允许缓存
+//
+// 以下情况不缓存：请求或响应的Cache-Control含no-store，响应含private、Set-Cookie或Vary: *，
+// 请求带有Authorization而响应未通过public、s-maxage或must-revalidate显式允许。
+func IsStorable(req *http.Request, header http.Header) bool {
+	if hasDirective(req.Header, "no-store") || hasDirective(header, "no-store", "private") {
+		return false
+	}
+	if len(header.Values("Set-Cookie")) > 0 {
+		return false
+	}
+	if req.Header.Get("Authorization") != "" && !allowsAuthorized(header) {
+		return false
+	}
+	_, ok := varyHeaders(header)
+	return ok
+}
+
+// VaryValues 记录响应Vary中列出的请求头在请求中的取值，存入Entry.Vary
+func VaryValues(req *http.Request, header http.Header) map[string]string {
+	names, _ := varyHeaders(header)
+	if len(names) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		values[name] = strings.Join(req.Header.Values(name), ",")
+	}
+	return values
+}
+
+// Matches 判断缓存条目能否用于响应该请求
+//
+// Vary中列出的请求头取值必须与存储时一致；带Authorization的请求只能使用显式允许的响应。
+func (e *Entry) Matches(req *http.Request) bool {
+	if req.Header.Get("Authorization") != "" && !allowsAuthorized(e.Header) {
+		return false
+	}
+	for name, value := range e.Vary {
+		if strings.Join(req.Header.Values(name), ",") != value {
+			return false
+		}
+	}
+	return true
+}
+
+// IsNotModified 判断条件请求是否可以直接以304响应（If-None-Match优先于If-Modified-Since）
+func IsNotModified(req *http.Request, header http.Header) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		etag := header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" {
+		lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+		if err != nil {
+			return false
+		}
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !lastModified.After(since)
+	}
+
+	return false
+}
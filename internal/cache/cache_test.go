@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 辅助函数：创建指定大小和过期时间的条目
+func newTestEntry(body string, expiresAt time.Time) *Entry {
+	return &Entry{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       []byte(body),
+		ExpiresAt:  expiresAt,
+	}
+}
+
+func TestResponseCache_HitAndMiss(t *testing.T) {
+	c := NewResponseCache(1024)
+
+	if _, ok := c.Get("missing"); ok {
+		t.Error("Expected miss for unknown key")
+	}
+
+	if !c.Set("key", newTestEntry("hello", time.Now().Add(time.Minute))) {
+		t.Fatal("Expected entry to be stored")
+	}
+
+	entry, ok := c.Get("key")
+	if !ok {
+		t.Fatal("Expected cache hit")
+	}
+	if string(entry.Body) != "hello" {
+		t.Errorf("Expected body 'hello', got %q", entry.Body)
+	}
+}
+
+func TestResponseCache_TTLExpiry(t *testing.T) {
+	c := NewResponseCache(1024)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set("key", newTestEntry("hello", now.Add(10*time.Second)))
+
+	if _, ok := c.Get("key"); !ok {
+		t.Fatal("Expected hit before TTL expiry")
+	}
+
+	// 时间推进到过期之后
+	now = now.Add(11 * time.Second)
+	if _, ok := c.Get("key"); ok {
+		t.Error("Expected miss after TTL expiry")
+	}
+	if c.Len() != 0 || c.UsedBytes() != 0 {
+		t.Errorf("Expected expired entry to be removed, len=%d used=%d", c.Len(), c.UsedBytes())
+	}
+}
+
+func TestResponseCache_EvictsLRUWhenFull(t *testing.T) {
+	// 每个条目约 1 + 10 字节
+	c := NewResponseCache(25)
+	expiresAt := time.Now().Add(time.Minute)
+
+	c.Set("a", newTestEntry("0123456789", expiresAt))
+	c.Set("b", newTestEntry("0123456789", expiresAt))
+
+	// 访问a，使b成为最久未使用的条目
+	c.Get("a")
+	c.Set("c", newTestEntry("0123456789", expiresAt))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected recently used entry to be kept")
+	}
+	if c.UsedBytes() > c.MaxBytes() {
+		t.Errorf("Expected used bytes %d to stay within %d", c.UsedBytes(), c.MaxBytes())
+	}
+}
+
+func TestResponseCache_RejectsOversizedEntry(t *testing.T) {
+	c := NewResponseCache(8)
+
+	if c.Set("key", newTestEntry("this body is too large", time.Now().Add(time.Minute))) {
+		t.Error("Expected oversized entry to be rejected")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected empty cache, got %d entries", c.Len())
+	}
+}
+
+func TestIsStorable(t *testing.T) {
+	testCases := []struct {
+		name          string
+		requestHeader http.Header
+		header        http.Header
+		expected      bool
+	}{
+		{"no directives", nil, http.Header{}, true},
+		{"max-age", nil, http.Header{"Cache-Control": {"max-age=60"}}, true},
+		{"no-store", nil, http.Header{"Cache-Control": {"no-store"}}, false},
+		{"private", nil, http.Header{"Cache-Control": {"Private"}}, false},
+		{"private with fields", nil, http.Header{"Cache-Control": {`max-age=60, private="X-User"`}}, false},
+		{"request no-store", http.Header{"Cache-Control": {"no-store"}}, http.Header{}, false},
+		{"set-cookie", nil, http.Header{"Set-Cookie": {"session=abc"}}, false},
+		{"vary star", nil, http.Header{"Vary": {"Accept, *"}}, false},
+		{"vary headers", nil, http.Header{"Vary": {"Accept-Encoding"}}, true},
+		{"authorized request", http.Header{"Authorization": {"Bearer x"}}, http.Header{"Cache-Control": {"max-age=60"}}, false},
+		{"authorized request public", http.Header{"Authorization": {"Bearer x"}}, http.Header{"Cache-Control": {"public, max-age=60"}}, true},
+		{"authorized request s-maxage", http.Header{"Authorization": {"Bearer x"}}, http.Header{"Cache-Control": {"s-maxage=60"}}, true},
+	}
+
+	for _, tc := range testCases {
+		req := &http.Request{Header: tc.requestHeader}
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+		if got := IsStorable(req, tc.header); got != tc.expected {
+			t.Errorf("%s: IsStorable() = %v, expected %v", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestEntry_Matches(t *testing.T) {
+	stored := &http.Request{Header: http.Header{"Accept-Encoding": {"gzip"}}}
+	header := http.Header{"Vary": {"accept-encoding, Accept-Language"}}
+	entry := newTestEntry("hello", time.Now().Add(time.Minute))
+	entry.Vary = VaryValues(stored, header)
+
+	// Vary列出的请求头取值一致时命中，缺失的请求头按空值比较
+	if !entry.Matches(&http.Request{Header: http.Header{"Accept-Encoding": {"gzip"}, "Accept": {"text/html"}}}) {
+		t.Error("Expected request with same varying headers to match")
+	}
+	if entry.Matches(&http.Request{Header: http.Header{"Accept-Encoding": {"br"}}}) {
+		t.Error("Expected request with different Accept-Encoding not to match")
+	}
+	if entry.Matches(&http.Request{Header: http.Header{"Accept-Encoding": {"gzip"}, "Accept-Language": {"de"}}}) {
+		t.Error("Expected request with extra Accept-Language not to match")
+	}
+
+	// 带Authorization的请求不使用未显式允许的响应
+	if entry.Matches(&http.Request{Header: http.Header{"Accept-Encoding": {"gzip"}, "Authorization": {"Bearer x"}}}) {
+		t.Error("Expected authorized request not to match a response without public")
+	}
+	entry.Header.Set("Cache-Control", "public")
+	if !entry.Matches(&http.Request{Header: http.Header{"Accept-Encoding": {"gzip"}, "Authorization": {"Bearer x"}}}) {
+		t.Error("Expected authorized request to match a public response")
+	}
+}
+
+func TestIsNotModified(t *testing.T) {
+	header := http.Header{}
+	header.Set("ETag", `W/"abc"`)
+	header.Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		expected bool
+	}{
+		{"no conditional headers", nil, false},
+		{"matching etag", map[string]string{"If-None-Match": `"abc"`}, true},
+		{"etag in list", map[string]string{"If-None-Match": `"x", W/"abc"`}, true},
+		{"different etag", map[string]string{"If-None-Match": `"other"`}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": "Tue, 03 Jan 2006 15:04:05 GMT"}, true},
+		{"modified since", map[string]string{"If-Modified-Since": "Sun, 01 Jan 2006 15:04:05 GMT"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			if got := IsNotModified(req, header); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net"
 	"net/url"
 	"os"
 	"strconv"
@@ -14,15 +15,34 @@ func Load() *Config {
 		port = "10805"
 	}
 
+	// 监听地址（IPv6地址可带方括号）
+	bindAddress := strings.TrimSpace(os.Getenv("BIND_ADDRESS"))
+	bindAddress = strings.TrimSuffix(strings.TrimPrefix(bindAddress, "["), "]")
+
 	sensitiveHeadersStr := os.Getenv("SENSITIVE_HEADERS")
 	if sensitiveHeadersStr == "" {
-		sensitiveHeadersStr = "cf-,x-forwarded,proxy,via,x-request-id,x-trace,x-correlation-id,x-country,x-region,x-city,x-proxy-token,x-log-secret,x-config-id,referer,if-none-match,if-modified-since,if-match,if-unmodified-since,if-range"
+		sensitiveHeadersStr = "cf-,x-forwarded,proxy,via,x-request-id,x-trace,x-correlation-id,x-country,x-region,x-city,x-proxy-token,x-log-secret,x-config-id,referer,if-match,if-unmodified-since,if-range"
+	}
+
+	// 出站代理不代理的目标（未设置时沿用标准NO_PROXY环境变量）
+	noProxyStr := os.Getenv("UPSTREAM_NO_PROXY")
+	if noProxyStr == "" {
+		noProxyStr = os.Getenv("NO_PROXY")
 	}
+	if noProxyStr == "" {
+		noProxyStr = os.Getenv("no_proxy")
+	}
+	upstreamNoProxy := splitList(noProxyStr)
 
-	// 加载默认代理配置
+	// 加载默认出站代理配置（UPSTREAM_PROXY，兼容旧的DEFAULT_PROXY）
+	defaultProxyURL := os.Getenv("UPSTREAM_PROXY")
+	if defaultProxyURL == "" {
+		defaultProxyURL = os.Getenv("DEFAULT_PROXY")
+	}
 	var defaultProxy *ProxyConfig
-	if defaultProxyURL := os.Getenv("DEFAULT_PROXY"); defaultProxyURL != "" {
+	if defaultProxyURL != "" {
 		if proxy, err := parseSimpleProxy(defaultProxyURL); err == nil {
+			proxy.NoProxy = upstreamNoProxy
 			defaultProxy = proxy
 		}
 	}
@@ -40,8 +60,29 @@ func Load() *Config {
 	// 是否允许私有IP代理（用于开发测试）
 	allowPrivateIP := os.Getenv("ALLOW_PRIVATE_PROXY") == "true"
 
-	// 加载管理相关配置
-	adminSecret := os.Getenv("ADMIN_SECRET")
+	// TLS证书（SNI证书格式：host=cert.pem:key.pem;*.example.com=wildcard.pem:wildcard.key）
+	tlsCertFile := os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile := os.Getenv("TLS_KEY_FILE")
+	tlsSNICerts := parseSNICerts(os.Getenv("TLS_SNI_CERTS"))
+	tlsHTTPRedirectPort := os.Getenv("TLS_HTTP_REDIRECT_PORT")
+
+	// ACME自动证书
+	acmeEnabled := os.Getenv("ACME_ENABLED") == "true"
+	acmeCacheDir := os.Getenv("ACME_CACHE_DIR")
+	if acmeCacheDir == "" {
+		acmeCacheDir = "data/acme"
+	}
+
+	// 加载管理相关配置（管理密钥可从文件或其他环境变量读取，避免出现在进程环境列表中）
+	var loadIssues []ValidationIssue
+	adminSecret, issue := loadAdminSecret()
+	if issue != nil {
+		loadIssues = append(loadIssues, *issue)
+	}
+	adminSecretPrevious, issue := loadPreviousAdminSecret()
+	if issue != nil {
+		loadIssues = append(loadIssues, *issue)
+	}
 
 	logMaxEntries := 1000
 	if val := os.Getenv("LOG_MAX_ENTRIES"); val != "" {
@@ -71,24 +112,469 @@ func Load() *Config {
 		}
 	}
 
+	// 日志保留策略执行间隔（秒）
+	logCleanupIntervalSeconds := 300
+	if val := os.Getenv("LOG_CLEANUP_INTERVAL"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			logCleanupIntervalSeconds = parsed
+		}
+	}
+
+	// 请求体/响应体超过该大小（字节）时压缩存储（0表示不压缩）
+	logCompressThreshold := 0
+	if val := os.Getenv("LOG_COMPRESS_THRESHOLD"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			logCompressThreshold = parsed
+		}
+	}
+
+	// 慢请求阈值（毫秒，0表示不标记慢请求）
+	slowRequestMs := 0
+	if val := os.Getenv("SLOW_REQUEST_MS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			slowRequestMs = parsed
+		}
+	}
+
+	// 访问日志持久化（JSON Lines文件，按大小或时间轮转）
+	logFile := strings.TrimSpace(os.Getenv("LOG_FILE"))
+
+	logRotateMB := 100
+	if val := os.Getenv("LOG_ROTATE_MB"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			logRotateMB = parsed
+		}
+	}
+
+	logRotateHours := 0
+	if val := os.Getenv("LOG_ROTATE_HOURS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			logRotateHours = parsed
+		}
+	}
+
+	logRotateKeep := 5
+	if val := os.Getenv("LOG_ROTATE_KEEP"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			logRotateKeep = parsed
+		}
+	}
+
+	logFileLoad := os.Getenv("LOG_FILE_LOAD") != "false"
+
 	// 是否记录200状态码的详细信息（默认false，只记录非200状态码）
 	logRecord200 := os.Getenv("LOG_RECORD_200") == "true"
 
+	// 访问日志脱敏规则（请求头、JSON字段、正则表达式）
+	logRedactHeadersStr := os.Getenv("LOG_REDACT_HEADERS")
+	if logRedactHeadersStr == "" {
+		logRedactHeadersStr = "authorization,proxy-authorization,cookie,set-cookie,x-api-key"
+	}
+	logRedactFieldsStr := os.Getenv("LOG_REDACT_FIELDS")
+	if logRedactFieldsStr == "" {
+		logRedactFieldsStr = "password,passwd,secret,client_secret,token,access_token,refresh_token,api_key"
+	}
+	var logRedactPatterns []string
+	for _, pattern := range strings.Split(os.Getenv("LOG_REDACT_PATTERNS"), ";") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			logRedactPatterns = append(logRedactPatterns, pattern)
+		}
+	}
+
+	// 响应缓存总内存上限（仅对启用了cache_ttl_seconds的配置生效）
+	responseCacheMaxMB := 64.0
+	if val := os.Getenv("RESPONSE_CACHE_MAX_MB"); val != "" {
+		if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed > 0 {
+			responseCacheMaxMB = parsed
+		}
+	}
+
+	// 网关侧gzip压缩代理响应（默认关闭）
+	responseCompression := os.Getenv("RESPONSE_COMPRESSION") == "true"
+
+	// CORS允许的来源（逗号分隔，默认*）
+	corsAllowedOrigins := []string{"*"}
+	if val := os.Getenv("CORS_ALLOWED_ORIGINS"); val != "" {
+		corsAllowedOrigins = nil
+		for _, origin := range strings.Split(val, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				corsAllowedOrigins = append(corsAllowedOrigins, strings.TrimSuffix(origin, "/"))
+			}
+		}
+	}
+	corsAllowCredentials := os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+
+	// 单IP速率限制（请求/分钟，0表示不限制）
+	ipRateLimit := 0
+	if val := os.Getenv("IP_RATE_LIMIT"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			ipRateLimit = parsed
+		}
+	}
+
+	ipRateLimitBurst := ipRateLimit
+	if val := os.Getenv("IP_RATE_LIMIT_BURST"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			ipRateLimitBurst = parsed
+		}
+	}
+
+	// 代理目标访问策略（SSRF防护）
+	targetAllowedSchemes := splitList(os.Getenv("TARGET_ALLOWED_SCHEMES"))
+	if len(targetAllowedSchemes) == 0 {
+		targetAllowedSchemes = []string{"http", "https"}
+	}
+	targetAllowedHosts := splitList(os.Getenv("TARGET_ALLOWED_HOSTS"))
+	targetDeniedHosts := splitList(os.Getenv("TARGET_DENIED_HOSTS"))
+	targetAllowPrivate := os.Getenv("TARGET_ALLOW_PRIVATE") == "true"
+	targetAllowLoopback := os.Getenv("TARGET_ALLOW_LOOPBACK") == "true"
+
+	// 入站代理请求的请求头限制
+	maxRequestHeaders := 100
+	if val := os.Getenv("MAX_REQUEST_HEADERS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			maxRequestHeaders = parsed
+		}
+	}
+
+	maxRequestHeaderBytes := 32 << 10
+	if val := os.Getenv("MAX_REQUEST_HEADER_BYTES"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			maxRequestHeaderBytes = parsed
+		}
+	}
+
+	// 上游连接池（默认值与Go标准库一致）
+	upstreamMaxIdleConns := 100
+	if val := os.Getenv("UPSTREAM_MAX_IDLE_CONNS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			upstreamMaxIdleConns = parsed
+		}
+	}
+
+	upstreamMaxIdleConnsPerHost := 2
+	if val := os.Getenv("UPSTREAM_MAX_IDLE_CONNS_PER_HOST"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			upstreamMaxIdleConnsPerHost = parsed
+		}
+	}
+
+	upstreamIdleConnTimeoutSeconds := 90
+	if val := os.Getenv("UPSTREAM_IDLE_CONN_TIMEOUT"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			upstreamIdleConnTimeoutSeconds = parsed
+		}
+	}
+
+	upstreamDisableKeepAlives := os.Getenv("UPSTREAM_DISABLE_KEEPALIVES") == "true"
+
+	// 上游主机DNS缓存（默认启用）
+	dnsCacheEnabled := os.Getenv("DNS_CACHE_ENABLED") != "false"
+
+	dnsCacheTTLSeconds := 30
+	if val := os.Getenv("DNS_CACHE_TTL"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			dnsCacheTTLSeconds = parsed
+		}
+	}
+
+	dnsCacheNegativeTTLSeconds := 5
+	if val := os.Getenv("DNS_CACHE_NEGATIVE_TTL"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			dnsCacheNegativeTTLSeconds = parsed
+		}
+	}
+
+	// 静态文件根目录及单页应用回退
+	staticDir := strings.TrimSpace(os.Getenv("STATIC_DIR"))
+	staticSPAFallback := os.Getenv("STATIC_SPA_FALLBACK") == "true"
+	errorPagesDir := strings.TrimSpace(os.Getenv("ERROR_PAGES_DIR"))
+
+	// 请求ID头（用于关联客户端、网关日志与上游服务）
+	requestIDHeader := strings.TrimSpace(os.Getenv("REQUEST_ID_HEADER"))
+	if requestIDHeader == "" {
+		requestIDHeader = "X-Request-ID"
+	}
+
+	// 链路追踪（遵循OpenTelemetry标准环境变量）
+	otelEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if otelEndpoint == "" {
+		otelEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	otelServiceName := os.Getenv("OTEL_SERVICE_NAME")
+	if otelServiceName == "" {
+		otelServiceName = "privacy-gateway"
+	}
+	otelHeaders := parseKeyValueList(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+
+	// 指标历史数据（默认60个1分钟数据点，如需24小时可设置288个300秒数据点）
+	metricsHistoryLength := 60
+	if val := os.Getenv("METRICS_HISTORY_LENGTH"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			metricsHistoryLength = parsed
+		}
+	}
+
+	metricsHistoryIntervalSeconds := 60
+	if val := os.Getenv("METRICS_HISTORY_INTERVAL"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			metricsHistoryIntervalSeconds = parsed
+		}
+	}
+
+	// 阈值告警（成功率、平均响应时间或内存持续超过阈值时发送Webhook通知）
+	alertWebhookURL := strings.TrimSpace(os.Getenv("ALERT_WEBHOOK_URL"))
+
+	alertMinSuccessRate := 95.0
+	if val := os.Getenv("ALERT_MIN_SUCCESS_RATE"); val != "" {
+		if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed >= 0 && parsed <= 100 {
+			alertMinSuccessRate = parsed
+		}
+	}
+
+	alertMinRequests := int64(100)
+	if val := os.Getenv("ALERT_MIN_REQUESTS"); val != "" {
+		if parsed, err := strconv.ParseInt(val, 10, 64); err == nil && parsed >= 0 {
+			alertMinRequests = parsed
+		}
+	}
+
+	alertMaxAvgResponseMs := int64(1000)
+	if val := os.Getenv("ALERT_MAX_AVG_RESPONSE_MS"); val != "" {
+		if parsed, err := strconv.ParseInt(val, 10, 64); err == nil && parsed >= 0 {
+			alertMaxAvgResponseMs = parsed
+		}
+	}
+
+	alertMaxMemoryMB := 500.0
+	if val := os.Getenv("ALERT_MAX_MEMORY_MB"); val != "" {
+		if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed >= 0 {
+			alertMaxMemoryMB = parsed
+		}
+	}
+
+	alertWindowSeconds := 300
+	if val := os.Getenv("ALERT_WINDOW"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			alertWindowSeconds = parsed
+		}
+	}
+
+	alertCooldownSeconds := 900
+	if val := os.Getenv("ALERT_COOLDOWN"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			alertCooldownSeconds = parsed
+		}
+	}
+
+	// 审计日志（管理操作记录，与访问日志分开保存）
+	auditLogFile := os.Getenv("AUDIT_LOG_FILE")
+
+	auditMaxEntries := 10000
+	if val := os.Getenv("AUDIT_MAX_ENTRIES"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			auditMaxEntries = parsed
+		}
+	}
+
+	// 代理配置持久化文件（PROXY_CONFIG_PERSIST=false时只保存在内存中）
+	proxyConfigFile := ""
+	if os.Getenv("PROXY_CONFIG_PERSIST") != "false" {
+		proxyConfigFile = os.Getenv("PROXY_CONFIG_FILE")
+		if proxyConfigFile == "" {
+			proxyConfigFile = "data/proxy-configs.json"
+		}
+	}
+
+	// 代理配置存储后端（memory|redis，默认memory）
+	storageBackend := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_BACKEND")))
+	if storageBackend == "" {
+		storageBackend = StorageBackendMemory
+	}
+	redisKeyPrefix := os.Getenv("REDIS_KEY_PREFIX")
+	if redisKeyPrefix == "" {
+		redisKeyPrefix = "privacygateway:"
+	}
+
+	// 已删除配置的保留时长（小时，默认7天），期间可从回收站恢复
+	deletedConfigRetentionHours := 168
+	if val := os.Getenv("DELETED_CONFIG_RETENTION_HOURS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			deletedConfigRetentionHours = parsed
+		}
+	}
+
+	// 启动检查：严格模式下关键配置有误时拒绝启动
+	strictConfig := os.Getenv("STRICT_CONFIG") == "true"
+
 	return &Config{
 		Port:             port,
+		BindAddress:      bindAddress,
 		SensitiveHeaders: strings.Split(strings.ToLower(sensitiveHeadersStr), ","),
 		DefaultProxy:     defaultProxy,
+		UpstreamNoProxy:  upstreamNoProxy,
 		ProxyWhitelist:   proxyWhitelist,
 		AllowPrivateIP:   allowPrivateIP,
+		TrustedProxies:   splitList(os.Getenv("TRUSTED_PROXIES")),
+
+		// TLS配置
+		TLSCertFile:         tlsCertFile,
+		TLSKeyFile:          tlsKeyFile,
+		TLSSNICerts:         tlsSNICerts,
+		TLSHTTPRedirectPort: tlsHTTPRedirectPort,
+
+		// ACME自动证书配置
+		ACMEEnabled:  acmeEnabled,
+		ACMEDomains:  splitList(os.Getenv("ACME_DOMAINS")),
+		ACMECacheDir: acmeCacheDir,
+		ACMEEmail:    os.Getenv("ACME_EMAIL"),
 
 		// 管理配置
-		AdminSecret:       adminSecret,
-		LogMaxEntries:     logMaxEntries,
-		LogMaxBodySize:    logMaxBodySize,
-		LogRetentionHours: logRetentionHours,
-		LogMaxMemoryMB:    logMaxMemoryMB,
-		LogRecord200:      logRecord200,
+		AdminSecret:         adminSecret,
+		AdminSecretPrevious: adminSecretPrevious,
+		LogMaxEntries:       logMaxEntries,
+		LogMaxBodySize:      logMaxBodySize,
+		LogRetentionHours:   logRetentionHours,
+		LogMaxMemoryMB:      logMaxMemoryMB,
+		LogRecord200:        logRecord200,
+		RequestIDHeader:     requestIDHeader,
+
+		LogCleanupIntervalSeconds: logCleanupIntervalSeconds,
+		LogCompressThreshold:      logCompressThreshold,
+		SlowRequestMs:             slowRequestMs,
+
+		// 访问日志持久化配置
+		LogFile:        logFile,
+		LogRotateMB:    logRotateMB,
+		LogRotateHours: logRotateHours,
+		LogRotateKeep:  logRotateKeep,
+		LogFileLoad:    logFileLoad,
+
+		// 日志脱敏配置
+		LogRedactHeaders:  splitList(logRedactHeadersStr),
+		LogRedactFields:   splitList(logRedactFieldsStr),
+		LogRedactPatterns: logRedactPatterns,
+
+		// 静态文件配置
+		StaticDir:         staticDir,
+		StaticSPAFallback: staticSPAFallback,
+		ErrorPagesDir:     errorPagesDir,
+
+		// 响应缓存配置
+		ResponseCacheMaxMB: responseCacheMaxMB,
+
+		// 响应压缩配置
+		ResponseCompression: responseCompression,
+
+		// CORS配置
+		CORSAllowedOrigins:   corsAllowedOrigins,
+		CORSAllowCredentials: corsAllowCredentials,
+
+		// 限流配置
+		IPRateLimit:      ipRateLimit,
+		IPRateLimitBurst: ipRateLimitBurst,
+
+		// 入站请求头限制
+		MaxRequestHeaders:     maxRequestHeaders,
+		MaxRequestHeaderBytes: maxRequestHeaderBytes,
+
+		// 代理目标访问策略
+		TargetAllowedSchemes: targetAllowedSchemes,
+		TargetAllowedHosts:   targetAllowedHosts,
+		TargetDeniedHosts:    targetDeniedHosts,
+		TargetAllowPrivate:   targetAllowPrivate,
+		TargetAllowLoopback:  targetAllowLoopback,
+
+		// 上游连接池配置
+		UpstreamMaxIdleConns:           upstreamMaxIdleConns,
+		UpstreamMaxIdleConnsPerHost:    upstreamMaxIdleConnsPerHost,
+		UpstreamIdleConnTimeoutSeconds: upstreamIdleConnTimeoutSeconds,
+		UpstreamDisableKeepAlives:      upstreamDisableKeepAlives,
+
+		// DNS缓存配置
+		DNSCacheEnabled:            dnsCacheEnabled,
+		DNSCacheTTLSeconds:         dnsCacheTTLSeconds,
+		DNSCacheNegativeTTLSeconds: dnsCacheNegativeTTLSeconds,
+
+		// 链路追踪配置
+		OTelEndpoint:    otelEndpoint,
+		OTelServiceName: otelServiceName,
+		OTelHeaders:     otelHeaders,
+
+		// 指标历史数据配置
+		MetricsHistoryLength:          metricsHistoryLength,
+		MetricsHistoryIntervalSeconds: metricsHistoryIntervalSeconds,
+
+		// 阈值告警配置
+		AlertWebhookURL:       alertWebhookURL,
+		AlertMinSuccessRate:   alertMinSuccessRate,
+		AlertMinRequests:      alertMinRequests,
+		AlertMaxAvgResponseMs: alertMaxAvgResponseMs,
+		AlertMaxMemoryMB:      alertMaxMemoryMB,
+		AlertWindowSeconds:    alertWindowSeconds,
+		AlertCooldownSeconds:  alertCooldownSeconds,
+
+		// 审计日志配置
+		AuditLogFile:    auditLogFile,
+		AuditMaxEntries: auditMaxEntries,
+
+		// 代理配置持久化
+		ProxyConfigFile: proxyConfigFile,
+
+		// 代理配置存储后端
+		StorageBackend: storageBackend,
+		RedisURL:       os.Getenv("REDIS_URL"),
+		RedisKeyPrefix: redisKeyPrefix,
+
+		DeletedConfigRetentionHours: deletedConfigRetentionHours,
+
+		// 启动检查配置
+		StrictConfig: strictConfig,
+		loadIssues:   loadIssues,
+	}
+}
+
+// splitList 解析逗号分隔的列表，去除空白和空项（统一转为小写）
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
+}
+
+// parseKeyValueList 解析 key1=value1,key2=value2 格式的列表（保留键值大小写）
+func parseKeyValueList(value string) map[string]string {
+	items := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(item, "=")
+		if key = strings.TrimSpace(key); found && key != "" {
+			if unescaped, err := url.QueryUnescape(strings.TrimSpace(val)); err == nil {
+				items[key] = unescaped
+			}
+		}
+	}
+	return items
+}
+
+// parseSNICerts 解析 host=cert:key;host2=cert2:key2 格式的SNI证书列表（主机名转为小写）
+func parseSNICerts(value string) map[string]TLSCertPair {
+	certs := make(map[string]TLSCertPair)
+	for _, item := range strings.Split(value, ";") {
+		host, files, found := strings.Cut(item, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !found || host == "" {
+			continue
+		}
+		certFile, keyFile, found := strings.Cut(strings.TrimSpace(files), ":")
+		if !found || certFile == "" || keyFile == "" {
+			continue
+		}
+		certs[host] = TLSCertPair{CertFile: certFile, KeyFile: keyFile}
+	}
+	return certs
 }
 
 // parseSimpleProxy 解析简单的代理URL（内部辅助函数）
@@ -120,3 +606,18 @@ func parseSimpleProxy(proxyURL string) (*ProxyConfig, error) {
 
 	return config, nil
 }
+
+// ListenAddr 返回HTTP服务器的监听地址（host:port，IPv6地址带方括号，未设置BindAddress时监听所有接口）
+func (c *Config) ListenAddr() string {
+	return net.JoinHostPort(c.BindAddress, c.Port)
+}
+
+// TLSEnabled 检查网关是否直接提供HTTPS（启用了ACME或配置了默认证书）
+func (c *Config) TLSEnabled() bool {
+	return c.ACMEEnabled || c.HasStaticCert()
+}
+
+// HasStaticCert 检查是否配置了默认证书和私钥文件
+func (c *Config) HasStaticCert() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
@@ -86,6 +86,48 @@ func TestLoad(t *testing.T) {
 	})
 }
 
+func TestLoad_UpstreamProxy(t *testing.T) {
+	t.Setenv("DEFAULT_PROXY", "http://legacy.example.com:8080")
+	t.Setenv("UPSTREAM_PROXY", "socks5://proxy.corp.example.com:1080")
+	t.Setenv("UPSTREAM_NO_PROXY", "internal.example.com, 10.0.0.0/8")
+
+	config := Load()
+
+	// UPSTREAM_PROXY优先于旧的DEFAULT_PROXY
+	if config.DefaultProxy == nil || config.DefaultProxy.URL != "socks5://proxy.corp.example.com:1080" {
+		t.Fatalf("Expected UPSTREAM_PROXY to take precedence, got %+v", config.DefaultProxy)
+	}
+	if config.DefaultProxy.Type != "socks5" {
+		t.Errorf("Expected proxy type socks5, got %s", config.DefaultProxy.Type)
+	}
+	if len(config.DefaultProxy.NoProxy) != 2 || config.DefaultProxy.NoProxy[1] != "10.0.0.0/8" {
+		t.Errorf("Expected no-proxy list to be applied, got %v", config.DefaultProxy.NoProxy)
+	}
+}
+
+func TestLoad_UpstreamTransportPool(t *testing.T) {
+	config := Load()
+	if config.UpstreamMaxIdleConns != 100 || config.UpstreamMaxIdleConnsPerHost != 2 ||
+		config.UpstreamIdleConnTimeoutSeconds != 90 || config.UpstreamDisableKeepAlives {
+		t.Errorf("Expected standard library defaults, got %d %d %d %v",
+			config.UpstreamMaxIdleConns, config.UpstreamMaxIdleConnsPerHost,
+			config.UpstreamIdleConnTimeoutSeconds, config.UpstreamDisableKeepAlives)
+	}
+
+	t.Setenv("UPSTREAM_MAX_IDLE_CONNS", "500")
+	t.Setenv("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", "50")
+	t.Setenv("UPSTREAM_IDLE_CONN_TIMEOUT", "30")
+	t.Setenv("UPSTREAM_DISABLE_KEEPALIVES", "true")
+
+	config = Load()
+	if config.UpstreamMaxIdleConns != 500 || config.UpstreamMaxIdleConnsPerHost != 50 ||
+		config.UpstreamIdleConnTimeoutSeconds != 30 || !config.UpstreamDisableKeepAlives {
+		t.Errorf("Expected transport pool settings from env, got %d %d %d %v",
+			config.UpstreamMaxIdleConns, config.UpstreamMaxIdleConnsPerHost,
+			config.UpstreamIdleConnTimeoutSeconds, config.UpstreamDisableKeepAlives)
+	}
+}
+
 func TestParseSimpleProxy(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -177,3 +219,35 @@ func TestParseSimpleProxy(t *testing.T) {
 		})
 	}
 }
+
+func TestListenAddr(t *testing.T) {
+	original := os.Getenv("BIND_ADDRESS")
+	defer os.Setenv("BIND_ADDRESS", original)
+
+	tests := []struct {
+		name        string
+		bindAddress string
+		want        string
+	}{
+		{"all interfaces", "", ":10805"},
+		{"ipv4", "127.0.0.1", "127.0.0.1:10805"},
+		{"ipv6", "::1", "[::1]:10805"},
+		{"bracketed ipv6", "[::1]", "[::1]:10805"},
+		{"host name", "localhost", "localhost:10805"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("BIND_ADDRESS", tt.bindAddress)
+			cfg := Load()
+			cfg.Port = "10805"
+
+			if got := cfg.ListenAddr(); got != tt.want {
+				t.Errorf("Expected listen address %s, got %s", tt.want, got)
+			}
+			if !isValidBindAddress(cfg.BindAddress) {
+				t.Errorf("Expected bind address %q to be valid", tt.bindAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 密钥引用前缀
+const (
+	secretFilePrefix = "file:" // file:/run/secrets/admin 从文件读取
+	secretEnvPrefix  = "env:"  // env:OTHER_VAR 从其他环境变量读取
+)
+
+// ResolveSecret 解析密钥值：file:路径 从文件读取，env:变量名 从其他环境变量读取，其余按字面值返回
+//
+// 从文件或环境变量读取的值去除首尾空白和换行（挂载的密钥文件通常以换行结尾）。
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretFilePrefix):
+		return readSecretFile(strings.TrimPrefix(value, secretFilePrefix))
+	case strings.HasPrefix(value, secretEnvPrefix):
+		name := strings.TrimPrefix(value, secretEnvPrefix)
+		if name == "" {
+			return "", fmt.Errorf("env: reference requires a variable name")
+		}
+		return strings.TrimSpace(os.Getenv(name)), nil
+	default:
+		return value, nil
+	}
+}
+
+// readSecretFile 读取密钥文件内容，去除首尾空白
+func readSecretFile(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("file: reference requires a path")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// loadAdminSecret 加载管理密钥：优先使用ADMIN_SECRET（支持file:/env:引用），未设置时读取ADMIN_SECRET_FILE
+//
+// 读取失败或文件为空时返回对应环境变量的问题，管理功能保持关闭。
+func loadAdminSecret() (string, *ValidationIssue) {
+	if value := os.Getenv("ADMIN_SECRET"); value != "" {
+		secret, err := ResolveSecret(value)
+		if err != nil {
+			return "", &ValidationIssue{Env: "ADMIN_SECRET", Message: err.Error()}
+		}
+		if secret == "" {
+			return "", &ValidationIssue{Env: "ADMIN_SECRET", Message: "admin secret reference resolved to an empty value"}
+		}
+		return secret, nil
+	}
+
+	if path := strings.TrimSpace(os.Getenv("ADMIN_SECRET_FILE")); path != "" {
+		secret, err := readSecretFile(path)
+		if err != nil {
+			return "", &ValidationIssue{Env: "ADMIN_SECRET_FILE", Message: err.Error()}
+		}
+		if secret == "" {
+			return "", &ValidationIssue{Env: "ADMIN_SECRET_FILE", Message: "admin secret file is empty"}
+		}
+		return secret, nil
+	}
+
+	return "", nil
+}
+
+// loadPreviousAdminSecret 加载轮换窗口内仍然有效的旧管理密钥ADMIN_SECRET_PREVIOUS（支持file:/env:引用）
+func loadPreviousAdminSecret() (string, *ValidationIssue) {
+	value := os.Getenv("ADMIN_SECRET_PREVIOUS")
+	if value == "" {
+		return "", nil
+	}
+	secret, err := ResolveSecret(value)
+	if err != nil {
+		return "", &ValidationIssue{Env: "ADMIN_SECRET_PREVIOUS", Message: err.Error()}
+	}
+	return secret, nil
+}
+
+// AdminSecrets 返回当前有效的管理密钥：当前密钥及轮换窗口内的旧密钥；未配置当前密钥时返回nil（管理功能关闭）
+func (c *Config) AdminSecrets() []string {
+	if c.AdminSecret == "" {
+		return nil
+	}
+	if c.AdminSecretPrevious == "" || c.AdminSecretPrevious == c.AdminSecret {
+		return []string{c.AdminSecret}
+	}
+	return []string{c.AdminSecret, c.AdminSecretPrevious}
+}
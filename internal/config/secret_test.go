@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSecretFile 在临时目录中写入密钥文件并返回路径
+func writeSecretFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "admin_secret")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_AdminSecretFromFile(t *testing.T) {
+	t.Setenv("ADMIN_SECRET", "")
+	t.Setenv("ADMIN_SECRET_FILE", writeSecretFile(t, "  file-secret-value\n"))
+
+	cfg := Load()
+	if cfg.AdminSecret != "file-secret-value" {
+		t.Errorf("Expected secret from file with whitespace trimmed, got %q", cfg.AdminSecret)
+	}
+	if issues := cfg.Validate(); hasIssue(issues, "ADMIN_SECRET_FILE") {
+		t.Errorf("Expected no admin secret issues, got %v", issues)
+	}
+}
+
+func TestLoad_AdminSecretPrecedence(t *testing.T) {
+	t.Setenv("ADMIN_SECRET_FILE", writeSecretFile(t, "file-secret-value\n"))
+
+	// 显式设置的ADMIN_SECRET优先于ADMIN_SECRET_FILE
+	t.Setenv("ADMIN_SECRET", "explicit-secret")
+	if cfg := Load(); cfg.AdminSecret != "explicit-secret" {
+		t.Errorf("Expected ADMIN_SECRET to take precedence, got %q", cfg.AdminSecret)
+	}
+
+	// ADMIN_SECRET可以引用文件或其他环境变量
+	t.Setenv("ADMIN_SECRET", "file:"+writeSecretFile(t, "referenced-file-secret\n"))
+	if cfg := Load(); cfg.AdminSecret != "referenced-file-secret" {
+		t.Errorf("Expected file: reference to be resolved, got %q", cfg.AdminSecret)
+	}
+
+	t.Setenv("VAULT_ADMIN_SECRET", " env-referenced-secret ")
+	t.Setenv("ADMIN_SECRET", "env:VAULT_ADMIN_SECRET")
+	if cfg := Load(); cfg.AdminSecret != "env-referenced-secret" {
+		t.Errorf("Expected env: reference to be resolved, got %q", cfg.AdminSecret)
+	}
+}
+
+func TestLoad_AdminSecretFileErrors(t *testing.T) {
+	t.Setenv("ADMIN_SECRET", "")
+	t.Setenv("ADMIN_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	cfg := Load()
+	if cfg.AdminSecret != "" {
+		t.Errorf("Expected admin secret to stay empty, got %q", cfg.AdminSecret)
+	}
+	if issues := cfg.Validate(); !hasIssue(issues, "ADMIN_SECRET_FILE") {
+		t.Errorf("Expected missing secret file to be reported, got %v", issues)
+	}
+
+	t.Setenv("ADMIN_SECRET_FILE", writeSecretFile(t, "\n"))
+	if issues := Load().Validate(); !hasIssue(issues, "ADMIN_SECRET_FILE") {
+		t.Errorf("Expected empty secret file to be reported, got %v", issues)
+	}
+
+	t.Setenv("ADMIN_SECRET", "env:UNSET_ADMIN_SECRET_VAR")
+	if issues := Load().Validate(); !hasIssue(issues, "ADMIN_SECRET") {
+		t.Errorf("Expected empty env: reference to be reported, got %v", issues)
+	}
+}
+
+func TestConfig_AdminSecrets(t *testing.T) {
+	t.Setenv("ADMIN_SECRET", "new-admin-secret")
+	t.Setenv("ADMIN_SECRET_PREVIOUS", "file:"+writeSecretFile(t, "old-admin-secret\n"))
+
+	cfg := Load()
+	if secrets := cfg.AdminSecrets(); len(secrets) != 2 || secrets[0] != "new-admin-secret" || secrets[1] != "old-admin-secret" {
+		t.Errorf("Expected current and previous secrets, got %v", secrets)
+	}
+	if issues := cfg.Validate(); hasIssue(issues, "ADMIN_SECRET_PREVIOUS") {
+		t.Errorf("Expected no previous secret issues, got %v", issues)
+	}
+
+	// 旧密钥与当前密钥相同时只返回一个
+	cfg.AdminSecretPrevious = cfg.AdminSecret
+	if secrets := cfg.AdminSecrets(); len(secrets) != 1 {
+		t.Errorf("Expected duplicate previous secret to be ignored, got %v", secrets)
+	}
+
+	// 未配置当前密钥时管理功能关闭，旧密钥单独设置会被报告
+	t.Setenv("ADMIN_SECRET", "")
+	cfg = Load()
+	if secrets := cfg.AdminSecrets(); secrets != nil {
+		t.Errorf("Expected no admin secrets without ADMIN_SECRET, got %v", secrets)
+	}
+	if issues := cfg.Validate(); !hasIssue(issues, "ADMIN_SECRET_PREVIOUS") {
+		t.Errorf("Expected previous secret without current secret to be reported, got %v", issues)
+	}
+}
+
+// hasIssue 检查问题列表中是否包含指定环境变量的问题
+func hasIssue(issues []ValidationIssue, env string) bool {
+	for _, issue := range issues {
+		if issue.Env == env {
+			return true
+		}
+	}
+	return false
+}
@@ -8,25 +8,143 @@ type ProxyAuth struct {
 
 // ProxyConfig 代理配置
 type ProxyConfig struct {
-	URL     string     `json:"url"`               // 代理服务器地址
-	Type    string     `json:"type,omitempty"`    // 代理类型: http, socks5
-	Auth    *ProxyAuth `json:"auth,omitempty"`    // 认证信息
-	Timeout int        `json:"timeout,omitempty"` // 超时时间(秒)
+	URL     string     `json:"url"`                // 代理服务器地址
+	Type    string     `json:"type,omitempty"`     // 代理类型: http, socks5
+	Auth    *ProxyAuth `json:"auth,omitempty"`     // 认证信息
+	Timeout int        `json:"timeout,omitempty"`  // 超时时间(秒)
+	NoProxy []string   `json:"no_proxy,omitempty"` // 不经过代理直连的目标（NO_PROXY格式：域名后缀、IP、CIDR）
+}
+
+// TLSCertPair 证书与私钥文件路径
+type TLSCertPair struct {
+	CertFile string
+	KeyFile  string
 }
 
 // Config 存储应用程序的配置
 type Config struct {
 	Port             string
+	BindAddress      string // 监听的网卡地址（为空时监听所有接口）
 	SensitiveHeaders []string
-	DefaultProxy     *ProxyConfig // 默认代理配置
+	DefaultProxy     *ProxyConfig // 默认出站代理配置（UPSTREAM_PROXY）
+	UpstreamNoProxy  []string     // 不经过出站代理的目标（UPSTREAM_NO_PROXY）
 	ProxyWhitelist   []string     // 代理白名单
 	AllowPrivateIP   bool         // 是否允许私有IP代理
+	TrustedProxies   []string     // 受信任的前置代理（IP或CIDR，TRUSTED_PROXIES），只保留来自这些地址的X-Forwarded-*头
+
+	// TLS配置（设置证书和私钥后网关直接提供HTTPS）
+	TLSCertFile         string                 // 默认证书文件
+	TLSKeyFile          string                 // 默认私钥文件
+	TLSSNICerts         map[string]TLSCertPair // 按SNI主机名选择的证书（支持*.example.com）
+	TLSHTTPRedirectPort string                 // HTTP→HTTPS重定向监听端口（为空时不启用）
+
+	// ACME自动证书配置（启用后取代TLS_CERT_FILE/TLS_KEY_FILE）
+	ACMEEnabled  bool     // 是否通过ACME（Let's Encrypt）自动申请证书
+	ACMEDomains  []string // 允许申请证书的域名（*.example.com表示其下一级子域名）
+	ACMECacheDir string   // 证书缓存目录
+	ACMEEmail    string   // ACME账户联系邮箱（可选）
 
 	// 管理相关配置
-	AdminSecret       string  // 管理功能访问密钥
-	LogMaxEntries     int     // 最大日志条数
-	LogMaxBodySize    int     // 响应体最大记录大小（字节）
-	LogRetentionHours int     // 日志保留时间（小时）
-	LogMaxMemoryMB    float64 // 日志最大内存使用（MB）
-	LogRecord200      bool    // 是否记录200状态码的详细信息
+	AdminSecret         string  // 管理功能访问密钥
+	AdminSecretPrevious string  // 轮换前的旧管理密钥，轮换窗口内与当前密钥同样有效（为空时只接受当前密钥）
+	LogMaxEntries       int     // 最大日志条数
+	LogMaxBodySize      int     // 响应体最大记录大小（字节）
+	LogRetentionHours   int     // 日志保留时间（小时）
+	LogMaxMemoryMB      float64 // 日志最大内存使用（MB）
+	LogRecord200        bool    // 是否记录200状态码的详细信息
+	RequestIDHeader     string  // 请求ID头名称（为空时使用X-Request-ID）
+
+	LogCleanupIntervalSeconds int // 日志保留策略的执行间隔（秒）
+	LogCompressThreshold      int // 请求体/响应体超过该大小（字节）时在内存中gzip压缩存储（0表示不压缩）
+	SlowRequestMs             int // 处理时长超过该值（毫秒）的请求标记为慢请求并输出WARN日志（0表示不标记）
+
+	// 访问日志持久化配置
+	LogFile        string // 访问日志文件路径（为空时仅保存在内存中）
+	LogRotateMB    int    // 单个日志文件达到该大小（MB）后轮转（0表示不按大小轮转）
+	LogRotateHours int    // 日志文件打开超过该时长（小时）后轮转（0表示不按时间轮转）
+	LogRotateKeep  int    // 保留的历史日志文件数
+	LogFileLoad    bool   // 启动时是否从日志文件恢复最近的日志
+
+	// 日志脱敏配置（在访问日志存储前生效）
+	LogRedactHeaders  []string // 需要脱敏的请求头名称（小写）
+	LogRedactFields   []string // 需要脱敏的JSON字段名或点分路径（小写）
+	LogRedactPatterns []string // 需要脱敏的正则表达式（有捕获组时只替换第一个捕获组）
+
+	// 静态文件配置
+	StaticDir         string // 静态文件根目录（为空时使用内置前端目录）
+	StaticSPAFallback bool   // 未知路径是否返回index.html（单页应用）
+	ErrorPagesDir     string // 网关错误的HTML错误页模板目录（为空时浏览器也返回JSON错误）
+
+	// 响应缓存配置
+	ResponseCacheMaxMB float64 // 响应缓存最大内存使用（MB）
+
+	// 响应压缩配置
+	ResponseCompression bool // 客户端接受gzip时由网关压缩未压缩的代理响应（配置可单独覆盖）
+
+	// CORS配置
+	CORSAllowedOrigins   []string // 允许的跨域来源（"*"表示全部）
+	CORSAllowCredentials bool     // 是否允许携带凭据
+
+	// 限流配置
+	IPRateLimit      int // 单IP每分钟请求数（0表示不限制）
+	IPRateLimitBurst int // 单IP突发请求数（默认等于IPRateLimit）
+
+	// 入站代理请求的请求头限制（0表示不限制）
+	MaxRequestHeaders     int // 请求头的最大数量（同名头的多个值分别计数）
+	MaxRequestHeaderBytes int // 请求头名称和值的最大总字节数
+
+	// 代理目标访问策略（SSRF防护）
+	TargetAllowedSchemes []string // 允许的目标协议（为空时默认http、https）
+	TargetAllowedHosts   []string // 目标主机允许列表（为空时不限制，支持*.example.com）
+	TargetDeniedHosts    []string // 目标主机拒绝列表
+	TargetAllowPrivate   bool     // 是否允许访问私有/链路本地地址
+	TargetAllowLoopback  bool     // 是否允许访问回环地址（本地开发使用）
+
+	// 上游连接池配置
+	UpstreamMaxIdleConns           int  // 所有上游主机的最大空闲连接数
+	UpstreamMaxIdleConnsPerHost    int  // 每个上游主机的最大空闲连接数
+	UpstreamIdleConnTimeoutSeconds int  // 上游空闲连接的保留时间（秒）
+	UpstreamDisableKeepAlives      bool // 禁用上游连接复用，每个请求使用新连接
+
+	// DNS缓存配置
+	DNSCacheEnabled            bool // 是否缓存上游主机的DNS解析结果
+	DNSCacheTTLSeconds         int  // 解析成功的缓存时间（秒）
+	DNSCacheNegativeTTLSeconds int  // 域名不存在的缓存时间（秒，0表示不缓存）
+
+	// 链路追踪配置（OpenTelemetry OTLP/HTTP）
+	OTelEndpoint    string            // OTLP Collector地址（为空时不启用追踪）
+	OTelServiceName string            // 上报的服务名
+	OTelHeaders     map[string]string // 导出请求附加的头（如认证信息）
+
+	// 指标历史数据配置
+	MetricsHistoryLength          int // 历史数据点数
+	MetricsHistoryIntervalSeconds int // 历史数据间隔（秒）
+
+	// 阈值告警配置
+	AlertWebhookURL       string  // 告警通知地址（为空时不启用告警）
+	AlertMinSuccessRate   float64 // 成功率下限（百分比）
+	AlertMinRequests      int64   // 计算成功率所需的最少请求数（每个检查间隔内）
+	AlertMaxAvgResponseMs int64   // 平均响应时间上限（毫秒，0表示不检查）
+	AlertMaxMemoryMB      float64 // 内存使用上限（MB，0表示不检查）
+	AlertWindowSeconds    int     // 超过阈值需持续的时长（秒）
+	AlertCooldownSeconds  int     // 两次告警之间的最小间隔（秒）
+
+	// 审计日志配置
+	AuditLogFile    string // 审计日志文件路径（为空时仅保存在内存中）
+	AuditMaxEntries int    // 内存中保留的最大审计记录数
+
+	// 代理配置持久化
+	ProxyConfigFile string // 代理配置持久化文件路径（PROXY_CONFIG_PERSIST=false时为空）
+
+	// 代理配置存储后端（多实例部署时使用redis共享配置和令牌）
+	StorageBackend string // memory（默认，进程内存储，按ProxyConfigFile持久化）或redis
+	RedisURL       string // Redis连接地址（redis://[:密码@]主机:端口/库号，rediss://使用TLS）
+	RedisKeyPrefix string // Redis键前缀，多个网关集群共用同一Redis时用于隔离
+
+	// 已删除配置在回收站中保留的时长（小时），超过后彻底清除
+	DeletedConfigRetentionHours int
+
+	// 启动检查配置
+	StrictConfig bool              // 关键配置检查不通过时拒绝启动（否则只记录警告）
+	loadIssues   []ValidationIssue // 加载时发现的问题（如密钥文件无法读取），由Validate一并返回
 }
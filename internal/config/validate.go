@@ -0,0 +1,184 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 管理密钥长度限制（日志查看器登录时使用相同的规则）
+const (
+	MinAdminSecretLength = 8
+	MaxAdminSecretLength = 256
+)
+
+// 代理配置存储后端
+const (
+	StorageBackendMemory = "memory"
+	StorageBackendRedis  = "redis"
+)
+
+// ValidationIssue 启动检查发现的配置问题
+type ValidationIssue struct {
+	Env     string // 对应的环境变量
+	Message string // 问题描述
+}
+
+// Error 实现 error 接口
+func (i ValidationIssue) Error() string {
+	return i.Env + ": " + i.Message
+}
+
+// Validate 检查启动所需的关键配置，返回发现的全部问题（没有问题时返回nil）
+//
+// 检查项：加载时的问题（如管理密钥文件无法读取）；监听端口是数字且在有效范围内，监听地址是IP或主机名；启用管理功能（日志查看器）时管理密钥足够强；
+// TLS证书、ACME与重定向端口的组合有效；存储后端有效且使用redis时设置了连接地址；代理配置、访问日志和审计日志文件所在目录可写。
+func (c *Config) Validate() []ValidationIssue {
+	issues := append([]ValidationIssue(nil), c.loadIssues...)
+	add := func(env, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Env: env, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		add("GATEWAY_PORT", "port must be a number between 1 and 65535, got %q", c.Port)
+	}
+
+	if !isValidBindAddress(c.BindAddress) {
+		add("BIND_ADDRESS", "bind address must be an IP address or host name, got %q", c.BindAddress)
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				add("TRUSTED_PROXIES", "trusted proxy must be an IP address or CIDR, got %q", proxy)
+			}
+		}
+	}
+
+	if c.AdminSecret != "" {
+		if len(c.AdminSecret) < MinAdminSecretLength {
+			add("ADMIN_SECRET", "admin secret is too weak, at least %d characters are required", MinAdminSecretLength)
+		} else if len(c.AdminSecret) > MaxAdminSecretLength {
+			add("ADMIN_SECRET", "admin secret must not exceed %d characters", MaxAdminSecretLength)
+		}
+	}
+	if c.AdminSecretPrevious != "" {
+		if c.AdminSecret == "" {
+			add("ADMIN_SECRET_PREVIOUS", "previous admin secret has no effect without ADMIN_SECRET")
+		} else if len(c.AdminSecretPrevious) < MinAdminSecretLength || len(c.AdminSecretPrevious) > MaxAdminSecretLength {
+			add("ADMIN_SECRET_PREVIOUS", "previous admin secret must be %d to %d characters", MinAdminSecretLength, MaxAdminSecretLength)
+		}
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		add("TLS_CERT_FILE", "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	} else if !c.HasStaticCert() && len(c.TLSSNICerts) > 0 {
+		add("TLS_SNI_CERTS", "SNI certificates require a default certificate in TLS_CERT_FILE/TLS_KEY_FILE")
+	}
+	if c.TLSHTTPRedirectPort != "" {
+		if port, err := strconv.Atoi(c.TLSHTTPRedirectPort); err != nil || port < 1 || port > 65535 {
+			add("TLS_HTTP_REDIRECT_PORT", "redirect port must be a number between 1 and 65535, got %q", c.TLSHTTPRedirectPort)
+		} else if !c.TLSEnabled() {
+			add("TLS_HTTP_REDIRECT_PORT", "HTTPS redirect requires TLS to be enabled")
+		} else if c.TLSHTTPRedirectPort == c.Port {
+			add("TLS_HTTP_REDIRECT_PORT", "redirect port must differ from GATEWAY_PORT")
+		}
+	}
+
+	if c.ACMEEnabled {
+		if len(c.ACMEDomains) == 0 {
+			add("ACME_DOMAINS", "ACME requires at least one allowed domain")
+		}
+		if err := checkWritableDir(c.ACMECacheDir); err != nil {
+			add("ACME_CACHE_DIR", "certificate cache directory %s is not writable: %v", c.ACMECacheDir, err)
+		}
+	}
+
+	if c.ErrorPagesDir != "" {
+		if info, err := os.Stat(c.ErrorPagesDir); err != nil || !info.IsDir() {
+			add("ERROR_PAGES_DIR", "error pages directory %s does not exist", c.ErrorPagesDir)
+		}
+	}
+
+	switch c.StorageBackend {
+	case "", StorageBackendMemory:
+	case StorageBackendRedis:
+		if c.RedisURL == "" {
+			add("REDIS_URL", "redis storage backend requires REDIS_URL")
+		}
+	default:
+		add("STORAGE_BACKEND", "storage backend must be %s or %s, got %q", StorageBackendMemory, StorageBackendRedis, c.StorageBackend)
+	}
+
+	files := []struct {
+		env  string
+		path string
+	}{
+		{"PROXY_CONFIG_FILE", c.ProxyConfigFile},
+		{"LOG_FILE", c.LogFile},
+		{"AUDIT_LOG_FILE", c.AuditLogFile},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		if err := checkWritableDir(filepath.Dir(file.path)); err != nil {
+			add(file.env, "directory of %s is not writable: %v", file.path, err)
+		}
+	}
+
+	return issues
+}
+
+// checkWritableDir 检查目录可写；目录尚不存在时检查最近的已存在上级目录（启动时会自动创建）
+func checkWritableDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// isValidBindAddress 检查监听地址是IP地址或主机名（为空表示所有接口）
+func isValidBindAddress(host string) bool {
+	if host == "" || net.ParseIP(host) != nil {
+		return true
+	}
+	if len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, ch := range label {
+			if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
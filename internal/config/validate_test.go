@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// validConfig 返回能通过启动检查的配置
+func validConfig(t *testing.T) *Config {
+	return &Config{
+		Port:            "10805",
+		AdminSecret:     "a-strong-admin-secret",
+		ProxyConfigFile: filepath.Join(t.TempDir(), "data", "proxy-configs.json"),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Valid Config", func(t *testing.T) {
+		if issues := validConfig(t).Validate(); len(issues) != 0 {
+			t.Errorf("Expected no issues, got %v", issues)
+		}
+	})
+
+	t.Run("Admin Disabled", func(t *testing.T) {
+		cfg := validConfig(t)
+		cfg.AdminSecret = ""
+		if issues := cfg.Validate(); len(issues) != 0 {
+			t.Errorf("Expected no issues without admin secret, got %v", issues)
+		}
+	})
+
+	t.Run("Weak Secret", func(t *testing.T) {
+		cfg := validConfig(t)
+		cfg.AdminSecret = "123"
+		assertSingleIssue(t, cfg.Validate(), "ADMIN_SECRET")
+	})
+
+	t.Run("Invalid Port", func(t *testing.T) {
+		for _, port := range []string{"abc", "0", "70000", ""} {
+			cfg := validConfig(t)
+			cfg.Port = port
+			assertSingleIssue(t, cfg.Validate(), "GATEWAY_PORT")
+		}
+	})
+
+	t.Run("Invalid Bind Address", func(t *testing.T) {
+		for _, addr := range []string{"0.0.0.0:8080", "bad host", "-host.example.com"} {
+			cfg := validConfig(t)
+			cfg.BindAddress = addr
+			assertSingleIssue(t, cfg.Validate(), "BIND_ADDRESS")
+		}
+	})
+
+	t.Run("Invalid Trusted Proxy", func(t *testing.T) {
+		cfg := validConfig(t)
+		cfg.TrustedProxies = []string{"10.0.0.1", "10.0.0.0/8", "not-an-ip"}
+		assertSingleIssue(t, cfg.Validate(), "TRUSTED_PROXIES")
+	})
+
+	t.Run("Incomplete TLS", func(t *testing.T) {
+		cfg := validConfig(t)
+		cfg.TLSCertFile = "server.crt"
+		assertSingleIssue(t, cfg.Validate(), "TLS_CERT_FILE")
+
+		cfg = validConfig(t)
+		cfg.TLSHTTPRedirectPort = "80"
+		assertSingleIssue(t, cfg.Validate(), "TLS_HTTP_REDIRECT_PORT")
+	})
+
+	t.Run("Storage Backend", func(t *testing.T) {
+		cfg := validConfig(t)
+		cfg.StorageBackend = "etcd"
+		assertSingleIssue(t, cfg.Validate(), "STORAGE_BACKEND")
+
+		cfg = validConfig(t)
+		cfg.StorageBackend = StorageBackendRedis
+		assertSingleIssue(t, cfg.Validate(), "REDIS_URL")
+
+		cfg.RedisURL = "redis://localhost:6379/0"
+		if issues := cfg.Validate(); len(issues) != 0 {
+			t.Errorf("Expected no issues with redis URL, got %v", issues)
+		}
+	})
+
+	t.Run("Unwritable Config Path", func(t *testing.T) {
+		// 上级路径是普通文件，目录无法创建（以root运行时权限位不生效，因此不依赖chmod）
+		blocker := filepath.Join(t.TempDir(), "blocker")
+		if err := os.WriteFile(blocker, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+
+		cfg := validConfig(t)
+		cfg.ProxyConfigFile = filepath.Join(blocker, "data", "proxy-configs.json")
+		assertSingleIssue(t, cfg.Validate(), "PROXY_CONFIG_FILE")
+	})
+
+	t.Run("Multiple Issues", func(t *testing.T) {
+		cfg := validConfig(t)
+		cfg.Port = "http"
+		cfg.AdminSecret = "short"
+		if issues := cfg.Validate(); len(issues) != 2 {
+			t.Errorf("Expected 2 issues, got %v", issues)
+		}
+	})
+}
+
+// assertSingleIssue 检查只有一个问题且对应预期的环境变量
+func assertSingleIssue(t *testing.T, issues []ValidationIssue, wantEnv string) {
+	t.Helper()
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue for %s, got %v", wantEnv, issues)
+	}
+	if issues[0].Env != wantEnv {
+		t.Errorf("Expected issue for %s, got %s", wantEnv, issues[0].Env)
+	}
+}
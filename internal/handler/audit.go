@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"privacygateway/internal/audit"
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+	"privacygateway/internal/logviewer"
+)
+
+// auditActorKey 请求上下文中审计操作者类型的键
+type auditActorKey struct{}
+
+// withAuditActor 标记请求的审计操作者类型（未标记时记录为管理员）
+func withAuditActor(r *http.Request, actor string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), auditActorKey{}, actor))
+}
+
+// recordAudit 记录一条管理操作审计日志（recorder为nil时跳过）
+func recordAudit(recorder *audit.Recorder, log *logger.Logger, r *http.Request, action, configID, tokenID string, details map[string]interface{}) {
+	if recorder == nil {
+		return
+	}
+
+	actor, _ := r.Context().Value(auditActorKey{}).(string)
+	if actor == "" {
+		actor = audit.ActorAdmin
+	}
+
+	entry := audit.Entry{
+		Actor:    actor,
+		Action:   action,
+		ConfigID: configID,
+		TokenID:  tokenID,
+		ClientIP: getClientIP(r),
+		Details:  details,
+	}
+
+	if err := recorder.Record(entry); err != nil {
+		log.Error("failed to record audit entry", "action", action, "config_id", configID, "token_id", tokenID, "error", err)
+	}
+}
+
+// HandleAuditAPI 处理审计日志查询请求（只读，需要管理员密钥）
+func HandleAuditAPI(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, recorder *audit.Recorder) {
+	if !isAuthorizedForConfig(r, cfg.AdminSecrets()) {
+		handleConfigAuthFailure(w, r, cfg.AdminSecret)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if recorder == nil {
+		http.Error(w, "Audit log not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	// 复用日志查看器的分页、时间范围与搜索参数解析
+	params := logviewer.NewFilterBuilder().FromRequest(r).GetParams()
+	query := r.URL.Query()
+
+	filter := &audit.Filter{
+		Actor:    query.Get("actor"),
+		Action:   query.Get("action"),
+		ConfigID: query.Get("config_id"),
+		FromTime: params.FromTime,
+		ToTime:   params.ToTime,
+		Search:   params.Search,
+		Page:     params.Page,
+		Limit:    params.Limit,
+	}
+
+	response := recorder.Query(filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// AuditVerifyResponse 审计记录哈希链校验结果
+type AuditVerifyResponse struct {
+	Valid   bool   `json:"valid"`
+	Entries int    `json:"entries"`
+	Error   string `json:"error,omitempty"`
+}
+
+// HandleAuditVerifyAPI 校验审计记录哈希链（只读，需要管理员密钥）
+//
+// 链完整时返回200，发现记录被修改、删除或重排时返回409
+func HandleAuditVerifyAPI(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, recorder *audit.Recorder) {
+	if !isAuthorizedForConfig(r, cfg.AdminSecrets()) {
+		handleConfigAuthFailure(w, r, cfg.AdminSecret)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if recorder == nil {
+		http.Error(w, "Audit log not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	response := AuditVerifyResponse{Valid: true, Entries: recorder.Count()}
+	status := http.StatusOK
+	if err := recorder.Verify(); err != nil {
+		log.Error("audit log integrity check failed", "error", err)
+		response.Valid = false
+		response.Error = err.Error()
+		status = http.StatusConflict
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
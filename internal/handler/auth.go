@@ -17,25 +17,35 @@ type AuthResult struct {
 	Token            *proxyconfig.AccessToken           `json:"token"`             // 令牌信息
 	ValidationResult *proxyconfig.TokenValidationResult `json:"validation_result"` // 令牌验证结果
 	Error            string                             `json:"error"`
+
+	tokenValue string // 令牌认证时的令牌值（用于ConsumeTokenUsage计数）
 }
 
 // ProxyAuthenticator 代理认证器
 type ProxyAuthenticator struct {
-	adminSecret string
-	storage     proxyconfig.Storage
-	logger      *logger.Logger
+	adminSecrets []string // 有效的管理密钥（当前密钥及轮换窗口内的旧密钥）
+	storage      proxyconfig.Storage
+	logger       *logger.Logger
 }
 
-// NewProxyAuthenticator 创建代理认证器
-func NewProxyAuthenticator(adminSecret string, storage proxyconfig.Storage, logger *logger.Logger) *ProxyAuthenticator {
+// NewProxyAuthenticator 创建代理认证器，previousSecrets为轮换窗口内仍然有效的旧管理密钥
+//
+// adminSecret为空时管理员认证关闭，旧密钥也不再生效。
+func NewProxyAuthenticator(adminSecret string, storage proxyconfig.Storage, logger *logger.Logger, previousSecrets ...string) *ProxyAuthenticator {
+	var adminSecrets []string
+	if adminSecret != "" {
+		adminSecrets = append([]string{adminSecret}, previousSecrets...)
+	}
 	return &ProxyAuthenticator{
-		adminSecret: adminSecret,
-		storage:     storage,
-		logger:      logger,
+		adminSecrets: adminSecrets,
+		storage:      storage,
+		logger:       logger,
 	}
 }
 
 // AuthenticateForProxy 代理请求认证
+//
+// 只校验凭据，不计入令牌使用次数：网关自身的检查全部通过、即将转发时再调用ConsumeTokenUsage。
 func (pa *ProxyAuthenticator) AuthenticateForProxy(r *http.Request, configID string) *AuthResult {
 	startTime := time.Now()
 
@@ -120,14 +130,6 @@ func (pa *ProxyAuthenticator) AuthenticateForProxy(r *http.Request, configID str
 		}
 	}
 
-	// 令牌认证成功，更新使用统计
-	if err := pa.storage.UpdateTokenUsage(configID, tokenValue); err != nil {
-		pa.logger.Error("failed to update token usage",
-			"error", err,
-			"config_id", configID,
-			"token_id", validationResult.Token.ID)
-	}
-
 	pa.logger.Info("token authentication successful",
 		"client_ip", getClientIP(r),
 		"config_id", configID,
@@ -141,11 +143,41 @@ func (pa *ProxyAuthenticator) AuthenticateForProxy(r *http.Request, configID str
 		ConfigID:         configID,
 		Token:            validationResult.Token,
 		ValidationResult: validationResult,
+		tokenValue:       tokenValue,
 	}
 }
 
-// AuthenticateForConfig 配置管理认证（仅支持管理员密钥）
-func (pa *ProxyAuthenticator) AuthenticateForConfig(r *http.Request) *AuthResult {
+// ConsumeTokenUsage 为令牌认证的请求计入一次使用（同时原子地占用一次使用次数配额），管理员认证时不计数
+//
+// 在禁用、方法、Basic认证、请求签名、并发限制、维护模式等网关检查全部通过后调用，
+// 被网关拒绝的请求不消耗配额。配额已被并发请求用完时返回ErrTokenQuotaExceeded。
+func (pa *ProxyAuthenticator) ConsumeTokenUsage(r *http.Request, result *AuthResult) error {
+	if result == nil || result.Method != "token" || result.tokenValue == "" {
+		return nil
+	}
+
+	err := pa.storage.UpdateTokenUsage(result.ConfigID, result.tokenValue)
+	if err == proxyconfig.ErrTokenQuotaExceeded {
+		// 并发请求在验证之后用完了配额
+		pa.logger.Warn("token usage rejected",
+			"client_ip", getClientIP(r),
+			"config_id", result.ConfigID,
+			"token_id", result.Token.ID,
+			"error_code", "TOKEN_QUOTA_EXCEEDED")
+		return err
+	}
+	if err != nil {
+		pa.logger.Error("failed to update token usage",
+			"error", err,
+			"config_id", result.ConfigID,
+			"token_id", result.Token.ID)
+	}
+	return nil
+}
+
+// AuthenticateForConfig 配置管理认证：管理员密钥可管理所有配置；
+// configID非空时，该配置的租户管理密钥（owner secret）也可通过，但只对该配置有效
+func (pa *ProxyAuthenticator) AuthenticateForConfig(r *http.Request, configID string) *AuthResult {
 	if pa.authenticateAdmin(r) {
 		return &AuthResult{
 			Authenticated: true,
@@ -153,6 +185,14 @@ func (pa *ProxyAuthenticator) AuthenticateForConfig(r *http.Request) *AuthResult
 		}
 	}
 
+	if configID != "" && pa.authenticateOwner(r, configID) {
+		return &AuthResult{
+			Authenticated: true,
+			Method:        "owner",
+			ConfigID:      configID,
+		}
+	}
+
 	return &AuthResult{
 		Authenticated: false,
 		Method:        "none",
@@ -162,21 +202,24 @@ func (pa *ProxyAuthenticator) AuthenticateForConfig(r *http.Request) *AuthResult
 
 // authenticateAdmin 管理员密钥认证
 func (pa *ProxyAuthenticator) authenticateAdmin(r *http.Request) bool {
-	if pa.adminSecret == "" {
-		return false
-	}
+	return isAuthorizedForProxy(r, pa.adminSecrets)
+}
 
-	// 检查请求头
-	if secret := r.Header.Get("X-Log-Secret"); secret == pa.adminSecret {
-		return true
+// authenticateOwner 租户管理密钥认证（与管理员密钥使用相同的请求头和查询参数）
+func (pa *ProxyAuthenticator) authenticateOwner(r *http.Request, configID string) bool {
+	secret := r.Header.Get("X-Log-Secret")
+	if secret == "" {
+		secret = r.URL.Query().Get("secret")
 	}
-
-	// 检查查询参数（向后兼容）
-	if secret := r.URL.Query().Get("secret"); secret == pa.adminSecret {
-		return true
+	if secret == "" {
+		return false
 	}
 
-	return false
+	config, err := pa.storage.GetByID(configID)
+	if err != nil {
+		return false
+	}
+	return config.CheckOwnerSecret(secret)
 }
 
 // extractToken 从请求中提取令牌
@@ -241,7 +284,7 @@ func (pa *ProxyAuthenticator) IsTokenAuthenticationEnabled() bool {
 func (pa *ProxyAuthenticator) GetAuthenticationMethods() []string {
 	methods := []string{}
 
-	if pa.adminSecret != "" {
+	if len(pa.adminSecrets) > 0 {
 		methods = append(methods, "admin")
 	}
 
@@ -52,7 +52,6 @@ func TestProxyAuthenticator_AuthenticateForProxy_Token(t *testing.T) {
 	// 添加测试配置
 	config := &proxyconfig.ProxyConfig{
 		Name:      "Test Config",
-		Subdomain: "test",
 		TargetURL: "https://example.com",
 		Enabled:   true,
 	}
@@ -88,13 +87,13 @@ func TestProxyAuthenticator_AuthenticateForProxy_Token(t *testing.T) {
 		t.Errorf("Expected config ID %s, got: %s", config.ID, result.ConfigID)
 	}
 
-	// 测试令牌认证（Authorization Bearer头）
+	// Authorization头原样转发给上游，不作为网关令牌
 	req = httptest.NewRequest("GET", "/proxy?target=https://example.com", nil)
 	req.Header.Set("Authorization", "Bearer "+tokenValue)
 
 	result = authenticator.AuthenticateForProxy(req, config.ID)
-	if !result.Authenticated {
-		t.Errorf("Expected Bearer token authentication to succeed, got: %s", result.Error)
+	if result.Authenticated {
+		t.Error("Expected Bearer token not to be accepted as a gateway token")
 	}
 
 	// 测试令牌认证（查询参数）
@@ -125,7 +124,6 @@ func TestProxyAuthenticator_AuthenticateForProxy_DisabledToken(t *testing.T) {
 	// 添加测试配置
 	config := &proxyconfig.ProxyConfig{
 		Name:      "Test Config",
-		Subdomain: "test",
 		TargetURL: "https://example.com",
 		Enabled:   true,
 	}
@@ -168,7 +166,6 @@ func TestProxyAuthenticator_AuthenticateForProxy_ExpiredToken(t *testing.T) {
 	// 添加测试配置
 	config := &proxyconfig.ProxyConfig{
 		Name:      "Test Config",
-		Subdomain: "test",
 		TargetURL: "https://example.com",
 		Enabled:   true,
 	}
@@ -215,7 +212,7 @@ func TestProxyAuthenticator_AuthenticateForConfig(t *testing.T) {
 	req := httptest.NewRequest("GET", "/config/proxy", nil)
 	req.Header.Set("X-Log-Secret", "test-secret")
 
-	result := authenticator.AuthenticateForConfig(req)
+	result := authenticator.AuthenticateForConfig(req, "")
 	if !result.Authenticated {
 		t.Errorf("Expected config authentication to succeed, got: %s", result.Error)
 	}
@@ -227,12 +224,47 @@ func TestProxyAuthenticator_AuthenticateForConfig(t *testing.T) {
 	req = httptest.NewRequest("GET", "/config/proxy", nil)
 	req.Header.Set("X-Log-Secret", "wrong-secret")
 
-	result = authenticator.AuthenticateForConfig(req)
+	result = authenticator.AuthenticateForConfig(req, "")
 	if result.Authenticated {
 		t.Error("Expected config authentication to fail with wrong secret")
 	}
 }
 
+func TestProxyAuthenticator_PreviousAdminSecret(t *testing.T) {
+	storage := proxyconfig.NewMemoryStorage(100)
+	log := logger.New()
+
+	authenticator := NewProxyAuthenticator("new-secret-value", storage, log, "old-secret-value")
+
+	// 轮换窗口内当前密钥和旧密钥都可以认证
+	for _, secret := range []string{"new-secret-value", "old-secret-value"} {
+		req := httptest.NewRequest("GET", "/proxy?target=https://example.com", nil)
+		req.Header.Set("X-Log-Secret", secret)
+		if result := authenticator.AuthenticateForProxy(req, ""); !result.Authenticated {
+			t.Errorf("Expected %q to authenticate, got: %s", secret, result.Error)
+		}
+
+		req = httptest.NewRequest("GET", "/config/proxy?secret="+secret, nil)
+		if !isAuthorizedForConfig(req, []string{"new-secret-value", "old-secret-value"}) {
+			t.Errorf("Expected %q to authorize config API", secret)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/proxy?target=https://example.com", nil)
+	req.Header.Set("X-Log-Secret", "wrong-secret-value")
+	if result := authenticator.AuthenticateForProxy(req, ""); result.Authenticated {
+		t.Error("Expected authentication to fail with wrong secret")
+	}
+
+	// 未配置当前密钥时旧密钥不生效
+	disabled := NewProxyAuthenticator("", storage, log, "old-secret-value")
+	req = httptest.NewRequest("GET", "/proxy?target=https://example.com", nil)
+	req.Header.Set("X-Log-Secret", "old-secret-value")
+	if result := disabled.AuthenticateForProxy(req, ""); result.Authenticated {
+		t.Error("Expected previous secret to be ignored without a current secret")
+	}
+}
+
 func TestExtractConfigID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -291,7 +323,6 @@ func TestProxyAuthenticator_Performance(t *testing.T) {
 	// 添加测试配置
 	config := &proxyconfig.ProxyConfig{
 		Name:      "Test Config",
-		Subdomain: "test",
 		TargetURL: "https://example.com",
 		Enabled:   true,
 	}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"privacygateway/internal/logger"
+	"privacygateway/internal/proxyconfig"
+)
+
+// isRewritableResponse 检查响应体是否可以改写：未压缩的文本类内容，且有响应体
+func isRewritableResponse(r *http.Request, resp *http.Response) bool {
+	if r.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return false
+	}
+	return isCompressibleType(resp.Header.Get("Content-Type"))
+}
+
+// rewriteResponseBody 读取响应体并应用配置的改写规则，同步更新Content-Length
+//
+// 响应体超过proxyconfig.MaxRewriteBodySize时不改写，已读取的部分与剩余部分拼接后原样转发。
+func rewriteResponseBody(w http.ResponseWriter, resp *http.Response, routeConfig *proxyconfig.ProxyConfig, responseHeader http.Header, log *logger.Logger) io.Reader {
+	original, err := io.ReadAll(io.LimitReader(resp.Body, proxyconfig.MaxRewriteBodySize+1))
+	if err != nil || len(original) > proxyconfig.MaxRewriteBodySize {
+		if err == nil {
+			log.Debug("response body too large to rewrite", "config_id", routeConfig.ID)
+		}
+		return io.MultiReader(bytes.NewReader(original), resp.Body)
+	}
+
+	rewritten, err := routeConfig.RewriteResponseBody(original)
+	if err != nil {
+		log.Warn("failed to rewrite response body", "config_id", routeConfig.ID, "error", err)
+		rewritten = original
+	}
+
+	length := strconv.Itoa(len(rewritten))
+	w.Header().Set("Content-Length", length)
+	responseHeader.Set("Content-Length", length)
+	return bytes.NewReader(rewritten)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/proxyconfig"
+)
+
+func TestHTTPProxyWithTokenAuth_ResponseBodyRewrite(t *testing.T) {
+	binary := []byte{0x89, 'P', 'N', 'G', 'i', 'n', 't', 'e', 'r', 'n', 'a', 'l', '.', 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0x00}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        []byte
+	}{
+		{
+			name:        "html hostname replaced",
+			contentType: "text/html; charset=utf-8",
+			body:        []byte(`<a href="https://internal.example/login">internal.example</a> <img src="http://cdn1.internal.example/a.png">`),
+			want:        []byte(`<a href="https://gateway.example/login">gateway.example</a> <img src="http://cdn.gateway.example/a.png">`),
+		},
+		{
+			name:        "binary body untouched",
+			contentType: "application/octet-stream",
+			body:        binary,
+			want:        binary,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+			cfg.LogMaxEntries = 100
+			cfg.LogMaxBodySize = 1024
+			cfg.LogRetentionHours = 1
+			cfg.LogMaxMemoryMB = 10
+
+			recorder, err := accesslog.NewRecorder(cfg, log)
+			if err != nil {
+				t.Fatalf("Failed to create recorder: %v", err)
+			}
+			defer recorder.Close()
+
+			updated := *proxyConfig
+			updated.ResponseBodyRewrites = []proxyconfig.BodyRewrite{
+				{Find: `cdn\d+\.internal\.example`, Replace: "cdn.gateway.example", Regex: true},
+				{Find: "internal.example", Replace: "gateway.example"},
+			}
+			if err := storage.Update(proxyConfig.ID, &updated); err != nil {
+				t.Fatalf("Failed to update config: %v", err)
+			}
+
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("Content-Length", strconv.Itoa(len(tt.body)))
+				w.Write(tt.body)
+			}))
+			defer upstream.Close()
+
+			req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/page&config_id="+proxyConfig.ID, nil)
+			req.Header.Set("X-Proxy-Token", tokenValue)
+			w := httptest.NewRecorder()
+			HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			got, _ := io.ReadAll(w.Body)
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Expected body %q, got %q", tt.want, got)
+			}
+			// Content-Length与改写后的响应体一致
+			if cl := w.Header().Get("Content-Length"); cl != strconv.Itoa(len(tt.want)) {
+				t.Errorf("Expected Content-Length %d, got %q", len(tt.want), cl)
+			}
+		})
+	}
+}
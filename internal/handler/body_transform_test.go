@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProxyWithTokenAuth_BodyTransform(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	transforming := *proxyConfig
+	transforming.TargetURL = upstream.URL
+	transforming.BodyTransform = json.RawMessage(`{"tenant_id":"acme"}`)
+	if err := storage.Update(proxyConfig.ID, &transforming); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	send := func(contentType, body string) {
+		req := httptest.NewRequest("POST", "/proxy?target="+upstream.URL+"/data&config_id="+proxyConfig.ID, strings.NewReader(body))
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// JSON请求体合并模板字段
+	send("application/json; charset=utf-8", `{"name":"widget","count":12345678901234567890}`)
+	var forwarded map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(received))
+	decoder.UseNumber()
+	if err := decoder.Decode(&forwarded); err != nil {
+		t.Fatalf("Expected JSON body upstream, got %q", received)
+	}
+	if forwarded["tenant_id"] != "acme" || forwarded["name"] != "widget" {
+		t.Errorf("Expected tenant_id merged into body, got %s", received)
+	}
+	if forwarded["count"] != json.Number("12345678901234567890") {
+		t.Errorf("Expected large number to be preserved, got %v", forwarded["count"])
+	}
+
+	// 非JSON请求体原样转发
+	send("text/plain", `{"name":"widget"}`)
+	if received != `{"name":"widget"}` {
+		t.Errorf("Expected non-JSON body unchanged, got %q", received)
+	}
+}
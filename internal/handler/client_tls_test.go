@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"privacygateway/internal/proxyconfig"
+)
+
+// generateClientCert 生成自签名客户端证书，返回证书对象及PEM格式的证书和私钥
+func generateClientCert(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gateway-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return cert, string(certPEM), string(keyPEM)
+}
+
+func TestHTTPProxyWithTokenAuth_ClientCertificate(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	clientCert, certPEM, keyPEM := generateClientCert(t)
+
+	// 上游要求客户端证书
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello " + r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	upstream.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	serverCAPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: upstream.Certificate().Raw}))
+
+	doRequest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/data&config_id="+proxyConfig.ID, nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+
+	// 仅信任上游CA、未配置客户端证书时握手失败
+	withCA := *proxyConfig
+	withCA.CACert = serverCAPEM
+	storage.Update(proxyConfig.ID, &withCA)
+
+	if w := doRequest(); w.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 without client certificate, got %d", w.Code)
+	}
+
+	// 配置更新为携带客户端证书后生效
+	withCert := withCA
+	withCert.ClientCert = certPEM
+	withCert.ClientKey = keyPEM
+	storage.Update(proxyConfig.ID, &withCert)
+
+	w := doRequest()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with client certificate, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "hello gateway-client" {
+		t.Errorf("Expected upstream to see client certificate, got %q", w.Body.String())
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_InsecureSkipVerify(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	// 自签名证书的上游
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("staging"))
+	}))
+	defer upstream.Close()
+
+	doRequest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"&config_id="+proxyConfig.ID, nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+
+	// 默认校验证书
+	if w := doRequest(); w.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 for self-signed upstream by default, got %d", w.Code)
+	}
+
+	insecure := *proxyConfig
+	insecure.InsecureSkipVerify = true
+	storage.Update(proxyConfig.ID, &insecure)
+
+	if w := doRequest(); w.Code != http.StatusOK || w.Body.String() != "staging" {
+		t.Errorf("Expected self-signed upstream to be reachable with insecure_skip_verify, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_TransportIsolatedPerConfig(t *testing.T) {
+	cfg, log, storage, _, _ := setupProxyIntegrationTest()
+
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("staging"))
+	}))
+	defer upstream.Close()
+
+	insecure := &proxyconfig.ProxyConfig{Name: "Insecure Staging", TargetURL: upstream.URL, Protocol: "https", Enabled: true, InsecureSkipVerify: true}
+	strict := &proxyconfig.ProxyConfig{Name: "Strict Staging", TargetURL: upstream.URL, Protocol: "https", Enabled: true}
+	storage.Add(insecure)
+	storage.Add(strict)
+
+	doRequest := func(configID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"&config_id="+configID, nil)
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+
+	if w := doRequest(insecure.ID); w.Code != http.StatusOK {
+		t.Fatalf("Expected insecure config to reach self-signed upstream, got %d", w.Code)
+	}
+	// 另一个配置不能复用前一个配置跳过校验建立的连接
+	if w := doRequest(strict.ID); w.Code != http.StatusBadGateway {
+		t.Errorf("Expected strict config to verify the certificate on its own connection, got %d", w.Code)
+	}
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/config"
+	"privacygateway/internal/proxyconfig"
+)
+
+// minCompressSize 已知长度小于该值的响应不压缩（压缩收益不足以抵消开销）
+const minCompressSize = 1024
+
+// compressionEnabled 确定本次请求是否启用网关侧压缩：配置的设置优先，未设置时沿用全局设置
+func compressionEnabled(cfg *config.Config, routeConfig *proxyconfig.ProxyConfig) bool {
+	if routeConfig != nil && routeConfig.CompressResponses != nil {
+		return *routeConfig.CompressResponses
+	}
+	return cfg.ResponseCompression
+}
+
+// acceptsGzip 检查客户端的Accept-Encoding是否接受gzip（q=0表示拒绝）
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isCompressibleType 检查响应类型是否值得压缩（文本、JSON、XML、JavaScript等）
+func isCompressibleType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "application/x-www-form-urlencoded", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter 在写入响应头时决定是否gzip压缩响应体的ResponseWriter
+//
+// 位于访问日志捕获器之下，捕获器记录的仍是压缩前的响应体。已压缩、SSE事件流、
+// 无响应体以及非文本类型的响应原样透传。
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	method      string
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+// wrapGzip 按配置和请求的Accept-Encoding包装ResponseWriter，返回的close函数需在响应结束后调用
+func wrapGzip(w http.ResponseWriter, r *http.Request, enabled bool) (http.ResponseWriter, func()) {
+	if !enabled || !acceptsGzip(r) {
+		return w, func() {}
+	}
+	gw := &gzipResponseWriter{ResponseWriter: w, method: r.Method}
+	return gw, gw.close
+}
+
+// WriteHeader 根据响应头决定是否压缩，压缩时移除Content-Length并设置Content-Encoding
+func (gw *gzipResponseWriter) WriteHeader(statusCode int) {
+	if gw.wroteHeader {
+		return
+	}
+	gw.wroteHeader = true
+
+	if gw.shouldCompress(statusCode) {
+		header := gw.Header()
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	}
+	gw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// shouldCompress 检查当前响应是否需要压缩
+func (gw *gzipResponseWriter) shouldCompress(statusCode int) bool {
+	if gw.method == http.MethodHead || statusCode < http.StatusOK ||
+		statusCode == http.StatusNoContent || statusCode == http.StatusNotModified ||
+		statusCode == http.StatusPartialContent {
+		return false
+	}
+
+	header := gw.Header()
+	if encoding := header.Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if accesslog.IsEventStream(contentType) || !isCompressibleType(contentType) {
+		return false
+	}
+	if length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil && length < minCompressSize {
+		return false
+	}
+	return true
+}
+
+// Write 写入响应体，压缩时经gzip写入
+func (gw *gzipResponseWriter) Write(data []byte) (int, error) {
+	if !gw.wroteHeader {
+		gw.WriteHeader(http.StatusOK)
+	}
+	if gw.gz != nil {
+		return gw.gz.Write(data)
+	}
+	return gw.ResponseWriter.Write(data)
+}
+
+// Flush 刷新已压缩的数据并转发给客户端
+func (gw *gzipResponseWriter) Flush() {
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	if flusher, ok := gw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 返回被包装的ResponseWriter（供http.ResponseController使用）
+func (gw *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// close 结束gzip流，写入剩余数据
+func (gw *gzipResponseWriter) close() {
+	if gw.gz != nil {
+		gw.gz.Close()
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+)
+
+func TestHTTPProxyWithTokenAuth_ResponseCompression(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	cfg.ResponseCompression = true
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 64 * 1024
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+	cfg.LogRecord200 = true
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	payload := `{"items":"` + strings.Repeat("privacy gateway ", 256) + `"}`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(payload))
+	}))
+	defer upstream.Close()
+
+	doRequest := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?config_id="+proxyConfig.ID+"&target="+url.QueryEscape(upstream.URL), nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+		return w
+	}
+
+	// 接受gzip的客户端收到压缩后的响应
+	w := doRequest("gzip, deflate")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" {
+		t.Fatalf("Expected gzip response without Content-Length, got headers %v", w.Header())
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Expected valid gzip body: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+	if string(body) != payload {
+		t.Errorf("Expected decompressed body to match upstream payload, got %d bytes", len(body))
+	}
+
+	// 访问日志记录压缩前的响应体
+	deadline := time.Now().Add(time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+		if err == nil && len(logs.Logs) > 0 {
+			// JSON响应体在日志中会被格式化，只检查是否为明文
+			if !strings.Contains(logs.Logs[0].ResponseBody, "privacy gateway") {
+				t.Errorf("Expected logged body to be uncompressed, got %q", logs.Logs[0].ResponseBody)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected access log entry to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 不接受gzip（或q=0）的客户端收到原始响应
+	for _, acceptEncoding := range []string{"", "identity", "gzip;q=0"} {
+		w = doRequest(acceptEncoding)
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != payload {
+			t.Errorf("Expected uncompressed response for Accept-Encoding %q, got encoding %q", acceptEncoding, w.Header().Get("Content-Encoding"))
+		}
+	}
+
+	// 配置可关闭全局启用的压缩
+	disabled := false
+	updated := *proxyConfig
+	updated.CompressResponses = &disabled
+	storage.Update(proxyConfig.ID, &updated)
+	if w = doRequest("gzip"); w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected per-config override to disable compression, got %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestGzipResponseWriter_SkipsIneligibleResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		encoding    string
+	}{
+		{"already compressed", "GET", "application/json", "br"},
+		{"event stream", "GET", "text/event-stream", ""},
+		{"binary", "GET", "image/png", ""},
+		{"head request", "HEAD", "text/plain", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/proxy", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+
+			w, closeGzip := wrapGzip(rec, req, true)
+			w.Header().Set("Content-Type", tt.contentType)
+			if tt.encoding != "" {
+				w.Header().Set("Content-Encoding", tt.encoding)
+			}
+			w.WriteHeader(http.StatusOK)
+			if tt.method != "HEAD" {
+				w.Write([]byte(strings.Repeat("x", 2048)))
+			}
+			closeGzip()
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tt.encoding, got)
+			}
+			if tt.method == "HEAD" && rec.Body.Len() != 0 {
+				t.Errorf("Expected empty body for HEAD, got %d bytes", rec.Body.Len())
+			}
+		})
+	}
+}
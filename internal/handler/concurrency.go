@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"privacygateway/internal/proxyconfig"
+)
+
+// concurrencyRetryAfter 配置并发已满时建议客户端重试的等待时间（秒）
+const concurrencyRetryAfter = 1
+
+// configLimiter 按配置ID统计进行中的请求并执行配置的并发上限
+//
+// 使用计数信号量而不是固定容量的channel，配置更新max_concurrency后立即按新上限生效。
+type configLimiter struct {
+	mu       sync.Mutex
+	inFlight map[string]int64
+}
+
+// configConcurrency 代理路径共用的配置并发限制器
+var configConcurrency = newConfigLimiter()
+
+// newConfigLimiter 创建配置并发限制器
+func newConfigLimiter() *configLimiter {
+	return &configLimiter{inFlight: make(map[string]int64)}
+}
+
+// acquire 占用配置的一个并发名额，limit<=0表示不限制；名额已满时返回false
+//
+// 成功时返回的release函数需在请求结束后调用（可重复调用）。
+func (l *configLimiter) acquire(configID string, limit int) (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit > 0 && l.inFlight[configID] >= int64(limit) {
+		return nil, false
+	}
+	l.inFlight[configID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { l.release(configID) })
+	}, true
+}
+
+// release 归还配置的并发名额，计数归零时移除记录
+func (l *configLimiter) release(configID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[configID] <= 1 {
+		delete(l.inFlight, configID)
+		return
+	}
+	l.inFlight[configID]--
+}
+
+// current 返回配置当前进行中的请求数
+func (l *configLimiter) current(configID string) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight[configID]
+}
+
+// writeConcurrencyLimitResponse 返回配置并发已满的错误响应
+func writeConcurrencyLimitResponse(w http.ResponseWriter, routeConfig *proxyconfig.ProxyConfig) {
+	w.Header().Set("Retry-After", strconv.Itoa(concurrencyRetryAfter))
+	writeProxyErrorFields(w, http.StatusServiceUnavailable, errCodeConcurrencyLimit, "Too many concurrent requests for this configuration", map[string]interface{}{
+		"config_id":       routeConfig.ID,
+		"max_concurrency": routeConfig.MaxConcurrency,
+	})
+}
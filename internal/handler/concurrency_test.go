@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"privacygateway/internal/proxyconfig"
+)
+
+func TestHTTPProxyWithTokenAuth_MaxConcurrency(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	const limit = 2
+	limited := *proxyConfig
+	limited.MaxConcurrency = limit
+	if err := storage.Update(proxyConfig.ID, &limited); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	// 另一个不限并发的配置，使用独立的令牌
+	other := *proxyConfig
+	other.ID = ""
+	other.Name = "Other Config"
+	other.AccessTokens = nil
+	other.MaxConcurrency = 0
+	if err := storage.Add(&other); err != nil {
+		t.Fatalf("Failed to add config: %v", err)
+	}
+	token, otherToken, err := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Other Token"}, "admin")
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	storage.AddToken(other.ID, token)
+
+	// 上游在收到unblock前挂起请求
+	entered := make(chan struct{}, limit)
+	unblock := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			entered <- struct{}{}
+			<-unblock
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	doRequest := func(configID, token, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?config_id="+configID+"&target="+url.QueryEscape(upstream.URL+path), nil)
+		req.Header.Set("X-Proxy-Token", token)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+
+	// 占满配置的并发名额
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, limit)
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = doRequest(proxyConfig.ID, tokenValue, "/slow")
+		}(i)
+	}
+	for i := 0; i < limit; i++ {
+		select {
+		case <-entered:
+		case <-time.After(2 * time.Second):
+			close(unblock)
+			t.Fatal("Timed out waiting for in-flight requests")
+		}
+	}
+
+	// 统计信息反映当前并发数
+	statsReq := httptest.NewRequest("GET", "/config/proxy/"+proxyConfig.ID+"/stats", nil)
+	statsReq.Header.Set("X-Log-Secret", "test-secret")
+	statsW := httptest.NewRecorder()
+	HandleConfigStatsAPI(statsW, statsReq, cfg, log, storage, nil)
+	var stats proxyconfig.ConfigStats
+	json.NewDecoder(statsW.Body).Decode(&stats)
+	if stats.CurrentConcurrency != limit {
+		t.Errorf("Expected current_concurrency %d, got %d", limit, stats.CurrentConcurrency)
+	}
+
+	// 第N+1个请求被拒绝
+	w := doRequest(proxyConfig.ID, tokenValue, "/fast")
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on saturated config")
+	}
+	assertProxyError(t, w, http.StatusServiceUnavailable, errCodeConcurrencyLimit)
+
+	// 其他配置不受影响
+	if w := doRequest(other.ID, otherToken, "/fast"); w.Code != http.StatusOK {
+		t.Errorf("Expected other config to be unaffected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	close(unblock)
+	wg.Wait()
+	for i, result := range results {
+		if result.Code != http.StatusOK {
+			t.Errorf("Expected in-flight request %d to succeed, got %d", i, result.Code)
+		}
+	}
+
+	// 名额释放后恢复转发
+	if w := doRequest(proxyConfig.ID, tokenValue, "/fast"); w.Code != http.StatusOK {
+		t.Errorf("Expected request to succeed after slots were released, got %d", w.Code)
+	}
+	if n := configConcurrency.current(proxyConfig.ID); n != 0 {
+		t.Errorf("Expected no in-flight requests, got %d", n)
+	}
+}
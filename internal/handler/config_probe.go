@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+	"privacygateway/internal/proxy"
+	"privacygateway/internal/proxyconfig"
+)
+
+// 配置测试请求的限制
+const (
+	maxConfigTestRequestSize = 1 << 20  // 测试请求（JSON）的最大大小（字节）
+	maxConfigTestBodySize    = 16 << 10 // 测试结果中返回的响应体上限（字节），超出部分截断
+)
+
+// configTestRequest 配置测试请求：发往配置目标地址的示例请求
+type configTestRequest struct {
+	Method  string            `json:"method,omitempty"`  // 请求方法（默认GET）
+	Path    string            `json:"path,omitempty"`    // 拼接在target_url后的路径，可包含查询参数
+	Headers map[string]string `json:"headers,omitempty"` // 请求头（敏感头按SENSITIVE_HEADERS过滤，与代理请求一致）
+	Body    string            `json:"body,omitempty"`    // 请求体
+}
+
+// configTestResult 配置测试结果
+type configTestResult struct {
+	Target        string            `json:"target"`                   // 实际请求的上游地址
+	Method        string            `json:"method"`                   // 请求方法
+	StatusCode    int               `json:"status_code,omitempty"`    // 上游响应状态码（请求失败时为0）
+	DurationMs    int64             `json:"duration_ms"`              // 从发送请求到读取完响应体的耗时（毫秒）
+	Headers       map[string]string `json:"headers,omitempty"`        // 上游响应头（每个头取第一个值）
+	Body          string            `json:"body,omitempty"`           // 上游响应体（超过上限时截断）
+	BodyTruncated bool              `json:"body_truncated,omitempty"` // 响应体是否被截断
+	ErrorCode     string            `json:"error_code,omitempty"`     // 请求失败时的错误代码（与代理请求的error_code相同）
+	Error         string            `json:"error,omitempty"`          // 请求失败时的错误描述
+}
+
+// HandleConfigTestAPI 在服务端向配置的目标地址发送示例请求并返回状态码、耗时和截断的响应体
+// 路径格式: /config/proxy/{configID}/test
+//
+// 请求经过与代理请求相同的传输层：出站代理、按配置隔离的连接（mTLS、连接池）、
+// 超时、重定向策略和目标地址访问策略（SSRF防护）。上游请求失败时仍返回200，错误写在结果中。
+func HandleConfigTestAPI(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, storage proxyconfig.Storage) {
+	// 认证检查
+	if !isAuthorizedForConfig(r, cfg.AdminSecrets()) {
+		handleConfigAuthFailure(w, r, cfg.AdminSecret)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "config" || parts[1] != "proxy" || parts[2] == "" || parts[3] != "test" {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Config ID is required")
+		return
+	}
+	configID := parts[2]
+
+	routeConfig, err := storage.GetByID(configID)
+	if err != nil {
+		if err == proxyconfig.ErrConfigNotFound {
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Config not found")
+		} else {
+			log.Error("failed to get config", "id", configID, "error", err)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
+		}
+		return
+	}
+
+	var testReq configTestRequest
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxConfigTestRequestSize)).Decode(&testReq); err != nil && err != io.EOF {
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid JSON")
+			return
+		}
+	}
+	method := strings.ToUpper(testReq.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	targetURL, err := configTestTarget(routeConfig, testReq.Path)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidTarget, err.Error())
+		return
+	}
+
+	// 出站代理、访问策略和上游连接与代理请求的处理一致
+	proxyConfig, status, err := resolveOutboundProxy(r, cfg, routeConfig)
+	if err != nil {
+		log.Error("failed to resolve proxy config", "error", err)
+		writeOutboundProxyError(w, status)
+		return
+	}
+	targetPolicy := proxy.NewTargetPolicy(cfg, routeConfig.AllowedHosts, routeConfig.DeniedHosts)
+	if !checkProxyTarget(w, r, log, targetPolicy, targetURL) {
+		return
+	}
+
+	result := configTestResult{Target: targetURL.String(), Method: method}
+
+	upstream, err := upstreamOptions(routeConfig)
+	if err != nil {
+		log.Error("failed to load upstream TLS config", "config_id", routeConfig.ID, "error", err)
+		writeProxyError(w, http.StatusBadGateway, errCodeUpstreamTLS, "Invalid upstream TLS configuration")
+		return
+	}
+	client, err := proxy.CreateHTTPClient(proxyConfig, targetPolicy, upstream)
+	if err != nil {
+		log.Error("failed to create HTTP client", "error", err)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Failed to create proxy client")
+		return
+	}
+	maxRedirects, _ := routeConfig.RedirectPolicy.MaxRedirects()
+	proxy.LimitRedirects(client, maxRedirects, targetPolicy)
+
+	proxyReq, err := http.NewRequestWithContext(r.Context(), method, targetURL.String(), bytes.NewReader([]byte(testReq.Body)))
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid test request")
+		return
+	}
+	for key, value := range testReq.Headers {
+		if !IsSensitiveHeader(key, cfg.SensitiveHeaders) {
+			proxyReq.Header.Set(key, value)
+		}
+	}
+	if routeConfig.UserAgentOverride != "" {
+		proxyReq.Header.Set("User-Agent", routeConfig.UserAgentOverride)
+	}
+
+	log.Info("testing proxy config", "id", configID, "method", method, "target", targetURL.String())
+
+	start := time.Now()
+	resp, err := client.Do(proxyReq)
+	if err != nil {
+		result.DurationMs = time.Since(start).Milliseconds()
+		if errors.Is(err, proxy.ErrTargetBlocked) {
+			log.Warn("proxy target blocked at connect time", "target", targetURL.String(), "client_ip", getClientIP(r), "error", err)
+			writeTargetBlockedResponse(w, err)
+			return
+		}
+		failure := classifyUpstreamError(err)
+		result.ErrorCode = failure.code
+		result.Error = failure.message
+		writeConfigTestResult(w, result)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigTestBodySize+1))
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.StatusCode = resp.StatusCode
+	result.Headers = make(map[string]string, len(resp.Header))
+	for key, values := range resp.Header {
+		if len(values) > 0 {
+			result.Headers[key] = values[0]
+		}
+	}
+	if len(body) > maxConfigTestBodySize {
+		body = body[:maxConfigTestBodySize]
+		result.BodyTruncated = true
+	}
+	result.Body = string(body)
+	if err != nil {
+		failure := classifyUpstreamError(err)
+		result.ErrorCode = failure.code
+		result.Error = "Failed to read upstream response body"
+	}
+
+	writeConfigTestResult(w, result)
+}
+
+// configTestTarget 将测试路径拼接到配置的target_url后，并按配置的路径规则、基础路径和查询参数过滤处理
+func configTestTarget(routeConfig *proxyconfig.ProxyConfig, path string) (*url.URL, error) {
+	targetURL, err := url.Parse(routeConfig.TargetURL)
+	if err != nil || targetURL.Host == "" {
+		return nil, errors.New("config target_url is invalid")
+	}
+
+	if path != "" {
+		ref, err := url.Parse(path)
+		if err != nil || ref.Scheme != "" || ref.Host != "" {
+			return nil, errors.New("path must be a relative path")
+		}
+		targetURL.Path = strings.TrimSuffix(targetURL.Path, "/") + "/" + strings.TrimPrefix(ref.Path, "/")
+		targetURL.RawPath = ""
+		if ref.RawQuery != "" {
+			targetURL.RawQuery = ref.RawQuery
+		}
+	}
+
+	if rewritten, ok := routeConfig.RewriteTarget(targetURL); ok {
+		targetURL = rewritten
+	} else if routeConfig.BasePath != "" {
+		targetURL = routeConfig.ApplyBasePath(targetURL)
+	}
+	return filterForwardedQuery(targetURL, routeConfig), nil
+}
+
+// writeConfigTestResult 返回配置测试结果
+func writeConfigTestResult(w http.ResponseWriter, result configTestResult) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+	"privacygateway/internal/proxyconfig"
+)
+
+// runConfigTest 调用配置测试API并解析结果
+func runConfigTest(t *testing.T, cfg *config.Config, storage proxyconfig.Storage, configID, body string) (*httptest.ResponseRecorder, configTestResult) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/config/proxy/"+configID+"/test", strings.NewReader(body))
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w := httptest.NewRecorder()
+	HandleConfigTestAPI(w, req, cfg, logger.New(), storage)
+
+	var result configTestResult
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode test result: %v", err)
+		}
+	}
+	return w, result
+}
+
+func TestHandleConfigTestAPI_ReportsStatusAndTiming(t *testing.T) {
+	cfg, _, storage, _, _ := setupProxyIntegrationTest()
+
+	var gotMethod, gotPath, gotQuery, gotHeader, gotBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath, gotQuery = r.Method, r.URL.Path, r.URL.RawQuery
+		gotHeader = r.Header.Get("X-Test")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("X-Upstream", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+	defer upstream.Close()
+
+	routeConfig := &proxyconfig.ProxyConfig{Name: "Test Target", TargetURL: upstream.URL + "/api", Protocol: "http", Enabled: true}
+	storage.Add(routeConfig)
+
+	w, result := runConfigTest(t, cfg, storage, routeConfig.ID, `{"method":"post","path":"/items?limit=1","headers":{"X-Test":"1"},"body":"hello"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if gotMethod != http.MethodPost || gotPath != "/api/items" || gotQuery != "limit=1" || gotHeader != "1" || gotBody != "hello" {
+		t.Errorf("Unexpected upstream request: %s %s?%s header=%q body=%q", gotMethod, gotPath, gotQuery, gotHeader, gotBody)
+	}
+	if result.StatusCode != http.StatusCreated {
+		t.Errorf("Expected reported status 201, got %d", result.StatusCode)
+	}
+	if result.DurationMs < 50 {
+		t.Errorf("Expected reported duration to include upstream latency, got %dms", result.DurationMs)
+	}
+	if result.Body != "created" || result.BodyTruncated {
+		t.Errorf("Unexpected body %q (truncated=%v)", result.Body, result.BodyTruncated)
+	}
+	if result.Headers["X-Upstream"] != "yes" {
+		t.Errorf("Expected upstream headers in result, got %v", result.Headers)
+	}
+	if result.Target != upstream.URL+"/api/items?limit=1" {
+		t.Errorf("Unexpected target %q", result.Target)
+	}
+}
+
+func TestHandleConfigTestAPI_TruncatesBody(t *testing.T) {
+	cfg, _, storage, _, _ := setupProxyIntegrationTest()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", maxConfigTestBodySize+100)))
+	}))
+	defer upstream.Close()
+
+	routeConfig := &proxyconfig.ProxyConfig{Name: "Large Body", TargetURL: upstream.URL, Protocol: "http", Enabled: true}
+	storage.Add(routeConfig)
+
+	_, result := runConfigTest(t, cfg, storage, routeConfig.ID, "")
+	if result.StatusCode != http.StatusOK || !result.BodyTruncated || len(result.Body) != maxConfigTestBodySize {
+		t.Errorf("Expected truncated body of %d bytes, got status=%d truncated=%v len=%d", maxConfigTestBodySize, result.StatusCode, result.BodyTruncated, len(result.Body))
+	}
+}
+
+func TestHandleConfigTestAPI_Errors(t *testing.T) {
+	cfg, _, storage, _, _ := setupProxyIntegrationTest()
+
+	// 已关闭的上游：连接失败写在结果中
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	unreachable := &proxyconfig.ProxyConfig{Name: "Unreachable", TargetURL: closed.URL, Protocol: "http", Enabled: true}
+	storage.Add(unreachable)
+
+	w, result := runConfigTest(t, cfg, storage, unreachable.ID, "")
+	if w.Code != http.StatusOK || result.StatusCode != 0 || result.ErrorCode != errCodeUpstreamRefused {
+		t.Errorf("Expected connection refused in result, got code=%d result=%+v", w.Code, result)
+	}
+
+	// 未允许回环地址时按SSRF策略拒绝
+	strictCfg := *cfg
+	strictCfg.TargetAllowLoopback = false
+	w, _ = runConfigTest(t, &strictCfg, storage, unreachable.ID, "")
+	assertProxyError(t, w, http.StatusForbidden, errCodeTargetBlocked)
+
+	// 不存在的配置
+	w, _ = runConfigTest(t, cfg, storage, "missing", "")
+	assertProxyError(t, w, http.StatusNotFound, errCodeConfigNotFound)
+
+	// 绝对地址不能作为测试路径
+	w, _ = runConfigTest(t, cfg, storage, unreachable.ID, `{"path":"http://evil.example.com/"}`)
+	assertProxyError(t, w, http.StatusBadRequest, errCodeInvalidTarget)
+
+	// 未认证
+	req := httptest.NewRequest(http.MethodPost, "/config/proxy/"+unreachable.ID+"/test", nil)
+	w = httptest.NewRecorder()
+	HandleConfigTestAPI(w, req, cfg, logger.New(), storage)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without admin secret, got %d", w.Code)
+	}
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultErrorPage 错误页目录中没有error.html时使用的通用模板
+const defaultErrorPage = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head><meta charset="utf-8"><title>{{.Status}} {{.StatusText}}</title></head>
+<body>
+<h1>{{.Status}} {{.StatusText}}</h1>
+<p>{{.Message}}</p>
+<p><code>{{.ErrorCode}}</code></p>
+</body>
+</html>
+`
+
+// ErrorPageData 错误页模板可用的参数
+type ErrorPageData struct {
+	Status     int    // HTTP状态码
+	StatusText string // 状态码的标准文本
+	ErrorCode  string // 网关错误代码（与JSON响应的error_code相同）
+	Message    string // 错误描述
+}
+
+// ErrorPages 网关自身错误的HTML错误页（仅对浏览器请求生效，API请求仍返回JSON）
+type ErrorPages struct {
+	pages    map[int]*template.Template // 按状态码的模板（如404.html）
+	fallback *template.Template         // 其他状态码使用的模板（error.html或内置模板）
+}
+
+// LoadErrorPages 从目录加载错误页模板：{状态码}.html对应单个状态码，error.html用于其余状态码
+func LoadErrorPages(dir string) (*ErrorPages, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read error pages directory: %w", err)
+	}
+
+	pages := &ErrorPages{pages: make(map[int]*template.Template)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".html" {
+			continue
+		}
+		base := strings.TrimSuffix(name, ".html")
+		status, err := strconv.Atoi(base)
+		if base != "error" && (err != nil || status < 400 || status > 599) {
+			continue
+		}
+
+		tmpl, err := template.ParseFiles(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse error page %s: %w", name, err)
+		}
+		if base == "error" {
+			pages.fallback = tmpl
+		} else {
+			pages.pages[status] = tmpl
+		}
+	}
+
+	if pages.fallback == nil {
+		pages.fallback = builtinErrorPages.fallback
+	}
+	return pages, nil
+}
+
+// Wrap 为浏览器请求包装ResponseWriter，使网关错误以HTML错误页返回；pages为nil或非浏览器请求时原样返回
+func (p *ErrorPages) Wrap(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if p == nil || !acceptsHTML(r) {
+		return w
+	}
+	return &errorPageWriter{ResponseWriter: w, pages: p}
+}
+
+// render 渲染错误页，模板执行失败时返回nil（调用方回退为JSON）
+func (p *ErrorPages) render(status int, code, message string) []byte {
+	tmpl, ok := p.pages[status]
+	if !ok {
+		tmpl = p.fallback
+	}
+
+	var buf bytes.Buffer
+	data := ErrorPageData{Status: status, StatusText: http.StatusText(status), ErrorCode: code, Message: message}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// acceptsHTML 检查请求是否来自浏览器（Accept头包含text/html）
+func acceptsHTML(r *http.Request) bool {
+	accept := strings.ToLower(r.Header.Get("Accept"))
+	return strings.Contains(accept, "text/html") || strings.Contains(accept, "application/xhtml+xml")
+}
+
+// errorPageWriter 标记请求应使用HTML错误页的ResponseWriter
+type errorPageWriter struct {
+	http.ResponseWriter
+	pages *ErrorPages
+}
+
+// Flush 支持流式响应
+func (ew *errorPageWriter) Flush() {
+	if flusher, ok := ew.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 支持WebSocket升级
+func (ew *errorPageWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := ew.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap 返回被包装的ResponseWriter（供http.ResponseController使用）
+func (ew *errorPageWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// builtinErrorPages 只包含内置模板的错误页，用于未配置ERROR_PAGES_DIR时也需要HTML响应的场景
+var builtinErrorPages = &ErrorPages{
+	pages:    map[int]*template.Template{},
+	fallback: template.Must(template.New("error").Parse(defaultErrorPage)),
+}
+
+// withBuiltinErrorPages 请求链路中没有启用错误页时使用内置错误页包装（浏览器请求才生效）
+func withBuiltinErrorPages(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if findErrorPages(w) != nil {
+		return w
+	}
+	return builtinErrorPages.Wrap(w, r)
+}
+
+// findErrorPages 沿Unwrap链查找errorPageWriter，未启用错误页时返回nil
+func findErrorPages(w http.ResponseWriter) *ErrorPages {
+	for current := w; current != nil; {
+		if ew, ok := current.(*errorPageWriter); ok {
+			return ew.pages
+		}
+		unwrapper, ok := current.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		current = unwrapper.Unwrap()
+	}
+	return nil
+}
+
+// writeErrorPage 请求链路中启用了错误页时以HTML写入错误响应，返回是否已写入
+//
+// 响应仍通过最外层的w写入，访问日志等包装层能记录到错误页。
+func writeErrorPage(w http.ResponseWriter, status int, code, message string) bool {
+	pages := findErrorPages(w)
+	if pages == nil {
+		return false
+	}
+	body := pages.render(status, code, message)
+	if body == nil {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+	return true
+}
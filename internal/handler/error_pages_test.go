@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorPages_BrowserAndAPIClients(t *testing.T) {
+	cfg, log, storage, _, _ := setupProxyIntegrationTest()
+
+	dir := t.TempDir()
+	page := `<html><body class="brand">{{.Status}} {{.ErrorCode}}: {{.Message}}</body></html>`
+	if err := os.WriteFile(filepath.Join(dir, "404.html"), []byte(page), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	pages, err := LoadErrorPages(dir)
+	if err != nil {
+		t.Fatalf("LoadErrorPages failed: %v", err)
+	}
+
+	request := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/config/proxy/missing/stats", nil)
+		req.Header.Set("X-Log-Secret", "test-secret")
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		HandleConfigStatsAPI(pages.Wrap(w, req), req, cfg, log, storage, nil)
+		return w
+	}
+
+	// 浏览器请求返回自定义错误页
+	w := request("text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected HTML content type, got %s", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `class="brand"`) || !strings.Contains(body, "404 CONFIG_NOT_FOUND: Config not found") {
+		t.Errorf("Expected custom error page, got %s", body)
+	}
+
+	// API请求仍返回JSON
+	assertProxyError(t, request("application/json"), http.StatusNotFound, errCodeConfigNotFound)
+}
+
+func TestErrorPages_FallbackTemplate(t *testing.T) {
+	pages, err := LoadErrorPages(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadErrorPages failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/proxy", nil)
+	req.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	writeProxyError(pages.Wrap(w, req), http.StatusGatewayTimeout, errCodeUpstreamTimeout, "<timeout>")
+
+	body := w.Body.String()
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(body, "UPSTREAM_TIMEOUT") {
+		t.Errorf("Expected built-in error page, got %d %s", w.Code, body)
+	}
+	if strings.Contains(body, "<timeout>") {
+		t.Error("Expected message to be HTML escaped")
+	}
+}
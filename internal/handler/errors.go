@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// 网关错误代码（错误响应的error_code字段），客户端可据此区分失败原因
+const (
+	errCodeUnauthorized     = "UNAUTHORIZED"
+	errCodeInvalidSignature = "INVALID_SIGNATURE"
+	errCodeMissingTarget    = "MISSING_TARGET"
+	errCodeInvalidTarget    = "INVALID_TARGET"
+	errCodeTargetBlocked    = "TARGET_BLOCKED"
+	errCodeConfigDisabled   = "CONFIG_DISABLED"
+	errCodeConfigNotFound   = "CONFIG_NOT_FOUND"
+	errCodeConfigConflict   = "CONFIG_CONFLICT"
+	errCodeVersionRequired  = "VERSION_REQUIRED"
+	errCodeVersionConflict  = "VERSION_CONFLICT"
+	errCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	errCodeConcurrencyLimit = "CONCURRENCY_LIMIT_EXCEEDED"
+	errCodeMaintenance      = "MAINTENANCE_MODE"
+	errCodeInvalidProxy     = "INVALID_PROXY"
+	errCodeProxyNotAllowed  = "PROXY_NOT_ALLOWED"
+	errCodeProxyUnsupported = "PROXY_UNSUPPORTED"
+	errCodeInvalidRequest   = "INVALID_REQUEST"
+	errCodeAmbiguousFraming = "AMBIGUOUS_FRAMING"
+	errCodeHeadersTooLarge  = "HEADERS_TOO_LARGE"
+	errCodeBodyTooLarge     = "REQUEST_BODY_TOO_LARGE"
+	errCodeOverrideDenied   = "OVERRIDE_NOT_ALLOWED"
+	errCodeInvalidJSON      = "INVALID_JSON"
+	errCodeValidation       = "VALIDATION_ERROR"
+	errCodeUpstreamTLS      = "UPSTREAM_TLS_ERROR"
+	errCodeUpstreamDNS      = "UPSTREAM_DNS_ERROR"
+	errCodeUpstreamRefused  = "UPSTREAM_CONNECTION_REFUSED"
+	errCodeUpstreamTimeout  = "UPSTREAM_TIMEOUT"
+	errCodeHeaderTimeout    = "UPSTREAM_HEADER_TIMEOUT"
+	errCodeIdleTimeout      = "UPSTREAM_IDLE_TIMEOUT"
+	errCodeUpstreamError    = "UPSTREAM_ERROR"
+	errCodeUpstream5xx      = "UPSTREAM_5XX" // 仅记录在访问日志中，上游的5xx响应原样返回给客户端
+	errCodeInternal         = "INTERNAL_ERROR"
+)
+
+// writeProxyError 返回统一格式的网关错误响应
+//
+// 响应体固定包含success、error（状态码的标准文本）、error_code、message和status。
+func writeProxyError(w http.ResponseWriter, status int, code, message string) {
+	writeProxyErrorFields(w, status, code, message, nil)
+}
+
+// writeProxyErrorFields 返回统一格式的网关错误响应，fields中的附加字段（如config_id）一并写入
+//
+// 配置了错误页且请求来自浏览器时改为返回HTML错误页（见ErrorPages）。错误代码同时记录在访问日志中。
+func writeProxyErrorFields(w http.ResponseWriter, status int, code, message string, fields map[string]interface{}) {
+	if capture := findResponseCapture(w); capture != nil {
+		capture.SetErrorCode(code)
+	}
+	if writeErrorPage(w, status, code, message) {
+		return
+	}
+
+	body := make(map[string]interface{}, len(fields)+5)
+	for key, value := range fields {
+		body[key] = value
+	}
+	body["success"] = false
+	body["error"] = http.StatusText(status)
+	body["error_code"] = code
+	body["message"] = message
+	body["status"] = status
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeUpstreamError 按上游请求失败的原因返回504（超时）或502，错误代码区分DNS、TLS、连接被拒绝等，并计入指标
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	failure := classifyUpstreamError(err)
+	recordUpstreamError(failure.category)
+	writeProxyError(w, failure.status, failure.code, failure.message)
+}
+
+// isTimeoutError 检查错误是否由超时引起（客户端超时、连接超时或请求上下文到期）
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// writeOutboundProxyError 返回出站代理解析失败的错误响应
+func writeOutboundProxyError(w http.ResponseWriter, status int) {
+	switch status {
+	case http.StatusBadRequest:
+		writeProxyError(w, status, errCodeInvalidProxy, "Invalid proxy configuration")
+	case http.StatusForbidden:
+		writeProxyError(w, status, errCodeProxyNotAllowed, "Proxy not allowed")
+	default:
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+	}
+}
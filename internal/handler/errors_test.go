@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// proxyErrorBody 统一错误响应的固定字段
+type proxyErrorBody struct {
+	Success   *bool  `json:"success"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+	Status    int    `json:"status"`
+}
+
+// assertProxyError 检查响应是统一格式的错误且状态码和错误代码符合预期
+func assertProxyError(t *testing.T, w *httptest.ResponseRecorder, wantStatus int, wantCode string) {
+	t.Helper()
+
+	if w.Code != wantStatus {
+		t.Fatalf("Expected status %d, got %d: %s", wantStatus, w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+
+	var body proxyErrorBody
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Expected JSON error body: %v", err)
+	}
+	if body.Success == nil || *body.Success {
+		t.Errorf("Expected success=false, got %v", body.Success)
+	}
+	if body.ErrorCode != wantCode {
+		t.Errorf("Expected error_code %s, got %s", wantCode, body.ErrorCode)
+	}
+	if body.Status != wantStatus || body.Error != http.StatusText(wantStatus) {
+		t.Errorf("Expected status %d (%s) in body, got %d (%s)", wantStatus, http.StatusText(wantStatus), body.Status, body.Error)
+	}
+	if body.Message == "" {
+		t.Error("Expected non-empty message")
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_ErrorResponses(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := closed.URL
+	closed.Close()
+
+	disabled := *proxyConfig
+	disabled.ID = ""
+	disabled.Name = "Disabled Config"
+	disabled.Enabled = false
+	disabled.AccessTokens = nil
+	storage.Add(&disabled)
+
+	proxyURL := func(configID, target string) string {
+		return "/proxy?config_id=" + configID + "&target=" + url.QueryEscape(target)
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		token      string
+		timeout    time.Duration
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing token", proxyURL(proxyConfig.ID, slow.URL), "", 0, http.StatusUnauthorized, errCodeUnauthorized},
+		{"invalid token", proxyURL(proxyConfig.ID, slow.URL), "invalid-token", 0, http.StatusUnauthorized, "TOKEN_NOT_FOUND"},
+		{"config disabled", proxyURL(disabled.ID, slow.URL), "", 0, http.StatusForbidden, errCodeConfigDisabled},
+		{"missing target", "/proxy?config_id=" + proxyConfig.ID, tokenValue, 0, http.StatusBadRequest, errCodeMissingTarget},
+		{"invalid target", proxyURL(proxyConfig.ID, "not a url"), tokenValue, 0, http.StatusBadRequest, errCodeInvalidTarget},
+		{"target blocked", proxyURL(proxyConfig.ID, "http://10.0.0.1/"), tokenValue, 0, http.StatusForbidden, errCodeTargetBlocked},
+		{"upstream unreachable", proxyURL(proxyConfig.ID, closedURL), tokenValue, 0, http.StatusBadGateway, errCodeUpstreamRefused},
+		{"upstream timeout", proxyURL(proxyConfig.ID, slow.URL), tokenValue, 50 * time.Millisecond, http.StatusGatewayTimeout, errCodeUpstreamTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			switch {
+			case tt.token != "":
+				req.Header.Set("X-Proxy-Token", tt.token)
+			case tt.wantCode == errCodeConfigDisabled:
+				req.Header.Set("X-Log-Secret", "test-secret")
+			}
+			if tt.timeout > 0 {
+				ctx, cancel := context.WithTimeout(req.Context(), tt.timeout)
+				defer cancel()
+				req = req.WithContext(ctx)
+			}
+
+			w := httptest.NewRecorder()
+			HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+			assertProxyError(t, w, tt.wantStatus, tt.wantCode)
+		})
+	}
+}
+
+func TestHandleProxyConfigAPI_ErrorResponses(t *testing.T) {
+	cfg, log, storage, _, _ := setupProxyIntegrationTest()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		secret     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"unauthorized", "GET", "/config/proxy", "", "", http.StatusUnauthorized, errCodeUnauthorized},
+		{"not found", "DELETE", "/config/proxy?id=missing", "", "test-secret", http.StatusNotFound, errCodeConfigNotFound},
+		{"invalid json", "POST", "/config/proxy", "{broken", "test-secret", http.StatusBadRequest, errCodeInvalidJSON},
+		{"validation", "POST", "/config/proxy", `{"protocol":"https"}`, "test-secret", http.StatusBadRequest, errCodeValidation},
+		{"method not allowed", "TRACE", "/config/proxy", "", "test-secret", http.StatusMethodNotAllowed, errCodeMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.secret != "" {
+				req.Header.Set("X-Log-Secret", tt.secret)
+			}
+			w := httptest.NewRecorder()
+			HandleProxyConfigAPI(w, req, cfg, log, storage, nil)
+			assertProxyError(t, w, tt.wantStatus, tt.wantCode)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// setForwardedHeaders 设置发往上游的X-Forwarded-For/Proto/Host和X-Real-IP
+//
+// 直接连接的对端在trustedProxies中时，保留其传入的X-Forwarded-*头并在X-Forwarded-For末尾追加对端地址；
+// 否则传入的值视为可能伪造而丢弃，只使用网关观察到的对端地址、协议和Host。
+// X-Real-IP取X-Forwarded-For链中从右往左第一个非受信任代理的地址。
+func setForwardedHeaders(header http.Header, r *http.Request, trustedProxies []string) {
+	peer := remoteIP(r)
+	trusted := isTrustedProxy(peer, trustedProxies)
+
+	var chain []string
+	if trusted {
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			for _, ip := range strings.Split(value, ",") {
+				if ip = strings.TrimSpace(ip); ip != "" {
+					chain = append(chain, ip)
+				}
+			}
+		}
+	}
+	chain = append(chain, peer)
+	header.Set("X-Forwarded-For", strings.Join(chain, ", "))
+	header.Set("X-Real-IP", clientIPFromChain(chain, trustedProxies))
+
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+	host := r.Host
+	if trusted {
+		if value := r.Header.Get("X-Forwarded-Proto"); value != "" {
+			proto = value
+		}
+		if value := r.Header.Get("X-Forwarded-Host"); value != "" {
+			host = value
+		}
+	}
+	header.Set("X-Forwarded-Proto", proto)
+	header.Set("X-Forwarded-Host", host)
+}
+
+// ClientIP 返回请求的真实客户端IP：只有直接连接的对端是受信任代理时才读取X-Forwarded-For，
+// 否则直接使用对端地址（客户端自行携带的转发头不可信）
+func ClientIP(r *http.Request, trustedProxies []string) string {
+	peer := remoteIP(r)
+	if !isTrustedProxy(peer, trustedProxies) {
+		return peer
+	}
+
+	var chain []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(value, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				chain = append(chain, ip)
+			}
+		}
+	}
+	chain = append(chain, peer)
+	return clientIPFromChain(chain, trustedProxies)
+}
+
+// clientIPFromChain 从右往左跳过受信任代理，返回第一个地址；全部受信任时返回最左侧的地址
+func clientIPFromChain(chain []string, trustedProxies []string) string {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if !isTrustedProxy(chain[i], trustedProxies) {
+			return chain[i]
+		}
+	}
+	return chain[0]
+}
+
+// remoteIP 返回直接连接的对端IP（不读取任何转发头）
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isTrustedProxy 检查地址是否属于受信任的代理（IP或CIDR）
+func isTrustedProxy(address string, trustedProxies []string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range trustedProxies {
+		if strings.Contains(proxy, "/") {
+			if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if trustedIP := net.ParseIP(proxy); trustedIP != nil && trustedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPProxyWithTokenAuth_ForwardedHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		remoteAddr string
+		inbound    map[string]string
+		want       map[string]string
+	}{
+		{
+			name:       "direct request ignores forged headers",
+			enabled:    true,
+			remoteAddr: "203.0.113.5:40000",
+			inbound:    map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example.com"},
+			want: map[string]string{
+				"X-Forwarded-For":   "203.0.113.5",
+				"X-Real-IP":         "203.0.113.5",
+				"X-Forwarded-Proto": "http",
+				"X-Forwarded-Host":  "gateway.example.com",
+			},
+		},
+		{
+			name:       "chained request through trusted proxy appends peer",
+			enabled:    true,
+			remoteAddr: "10.0.0.2:40000",
+			inbound:    map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.9", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"},
+			want: map[string]string{
+				"X-Forwarded-For":   "198.51.100.7, 10.0.0.9, 10.0.0.2",
+				"X-Real-IP":         "198.51.100.7",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "api.example.com",
+			},
+		},
+		{
+			name:       "disabled sends no client headers",
+			enabled:    false,
+			remoteAddr: "203.0.113.5:40000",
+			want:       map[string]string{"X-Forwarded-For": "", "X-Real-IP": "", "X-Forwarded-Proto": "", "X-Forwarded-Host": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+			cfg.TrustedProxies = []string{"10.0.0.0/8"}
+
+			var received http.Header
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Clone()
+				w.Write([]byte("ok"))
+			}))
+			defer upstream.Close()
+
+			updated := *proxyConfig
+			updated.ForwardClientHeaders = tt.enabled
+			storage.Update(proxyConfig.ID, &updated)
+
+			req := httptest.NewRequest("GET", "http://gateway.example.com/proxy?target="+upstream.URL+"/data&config_id="+proxyConfig.ID, nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Proxy-Token", tokenValue)
+			for key, value := range tt.inbound {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			for key, want := range tt.want {
+				if got := received.Get(key); got != want {
+					t.Errorf("Expected %s %q, got %q", key, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8"}
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+	}{
+		{"direct request ignores forged header", "203.0.113.5:40000", "1.2.3.4", "203.0.113.5"},
+		{"trusted proxy uses forwarded client", "10.0.0.2:40000", "198.51.100.7", "198.51.100.7"},
+		{"skips trusted hops from the right", "10.0.0.2:40000", "1.2.3.4, 198.51.100.7, 10.0.0.3", "198.51.100.7"},
+		{"trusted proxy without header", "10.0.0.2:40000", "", "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/proxy", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if got := ClientIP(req, trusted); got != tt.want {
+				t.Errorf("Expected client IP %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// hopByHopHeaders 只对单跳连接有效、代理两个方向都不转发的头（RFC 7230 6.1）
+//
+// WebSocket升级由/ws单独处理，HTTP代理不转发Upgrade。
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// checkRequestFraming 检查请求体长度的表示是否唯一，拒绝常见的请求走私手法
+//
+// 同时带Content-Length和Transfer-Encoding、多个或逗号分隔的Content-Length、非法的长度值，
+// 以及chunked以外的Transfer-Encoding都视为有歧义：前后两跳可能对请求边界得出不同的结论。
+func checkRequestFraming(r *http.Request) error {
+	contentLengths := r.Header.Values("Content-Length")
+	transferEncodings := append(r.Header.Values("Transfer-Encoding"), r.TransferEncoding...)
+
+	if len(contentLengths) > 1 || (len(contentLengths) == 1 && strings.Contains(contentLengths[0], ",")) {
+		return errors.New("multiple Content-Length headers")
+	}
+	if len(contentLengths) == 1 {
+		if length, err := strconv.ParseInt(strings.TrimSpace(contentLengths[0]), 10, 64); err != nil || length < 0 {
+			return errors.New("invalid Content-Length header")
+		}
+		if len(transferEncodings) > 0 {
+			return errors.New("both Content-Length and Transfer-Encoding present")
+		}
+	}
+
+	// 标准库会把Transfer-Encoding从请求头移到r.TransferEncoding，两处分别检查
+	if err := checkTransferEncoding(r.Header.Values("Transfer-Encoding")); err != nil {
+		return err
+	}
+	return checkTransferEncoding(r.TransferEncoding)
+}
+
+// checkTransferEncoding 只接受单个chunked编码
+func checkTransferEncoding(values []string) error {
+	chunked := 0
+	for _, value := range values {
+		for _, coding := range strings.Split(value, ",") {
+			if !strings.EqualFold(strings.TrimSpace(coding), "chunked") {
+				return errors.New("unsupported Transfer-Encoding")
+			}
+			chunked++
+		}
+	}
+	if chunked > 1 {
+		return errors.New("repeated Transfer-Encoding")
+	}
+	return nil
+}
+
+// removeHopByHopHeaders 移除逐跳头以及Connection头中列出的头，转发请求给上游和返回上游响应给客户端前都需调用
+func removeHopByHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+}
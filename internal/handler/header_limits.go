@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+)
+
+// WithRequestHeaderLimits 请求头数量或总大小超过上限时返回431，不再交给next处理
+//
+// 由路由层对所有入站路由统一应用，令牌代理、管理员代理和管理API共用同一限制。
+func WithRequestHeaderLimits(next http.HandlerFunc, cfg *config.Config, log *logger.Logger) http.HandlerFunc {
+	if cfg.MaxRequestHeaders <= 0 && cfg.MaxRequestHeaderBytes <= 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := checkRequestHeaderLimits(r, cfg); err != nil {
+			log.Warn("request rejected: request headers too large", "reason", err.Error(), "client_ip", getClientIP(r), "path", r.URL.Path)
+			writeProxyError(w, http.StatusRequestHeaderFieldsTooLarge, errCodeHeadersTooLarge, err.Error())
+			return
+		}
+		next(w, r)
+	}
+}
+
+// checkRequestHeaderLimits 检查入站请求的请求头数量和总大小是否超过配置的上限（0表示不限制）
+//
+// 同名头的多个值分别计数，大小按名称和值的字节数累计。
+func checkRequestHeaderLimits(r *http.Request, cfg *config.Config) error {
+	count, size := 0, 0
+	for name, values := range r.Header {
+		count += len(values)
+		for _, value := range values {
+			size += len(name) + len(value)
+		}
+	}
+
+	if cfg.MaxRequestHeaders > 0 && count > cfg.MaxRequestHeaders {
+		return fmt.Errorf("request has %d headers, limit is %d", count, cfg.MaxRequestHeaders)
+	}
+	if cfg.MaxRequestHeaderBytes > 0 && size > cfg.MaxRequestHeaderBytes {
+		return fmt.Errorf("request headers total %d bytes, limit is %d", size, cfg.MaxRequestHeaderBytes)
+	}
+	return nil
+}
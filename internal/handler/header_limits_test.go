@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWithRequestHeaderLimits_TokenProxy(t *testing.T) {
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	cfg.MaxRequestHeaders = 10
+	cfg.MaxRequestHeaderBytes = 1024
+	target := "/proxy?target=" + upstream.URL + "/data&config_id=" + proxyConfig.ID
+	proxyHandler := WithRequestHeaderLimits(func(w http.ResponseWriter, r *http.Request) {
+		HTTPProxyWithTokenAuth(w, r, cfg, log, nil, storage, nil)
+	}, cfg, log)
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		return req
+	}
+
+	// 请求头数量超过上限（同名头的多个值分别计数）
+	req := newRequest()
+	for i := 0; i < 10; i++ {
+		req.Header.Add("X-Extra", fmt.Sprintf("value-%d", i))
+	}
+	w := httptest.NewRecorder()
+	proxyHandler(w, req)
+	assertProxyError(t, w, http.StatusRequestHeaderFieldsTooLarge, errCodeHeadersTooLarge)
+
+	// 请求头总大小超过上限
+	req = newRequest()
+	req.Header.Set("X-Large", strings.Repeat("a", 1024))
+	w = httptest.NewRecorder()
+	proxyHandler(w, req)
+	assertProxyError(t, w, http.StatusRequestHeaderFieldsTooLarge, errCodeHeadersTooLarge)
+
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("Expected rejected requests not to reach upstream, got %d hits", n)
+	}
+
+	// 未超过上限的请求正常转发
+	req = newRequest()
+	req.Header.Set("X-Small", "value")
+	w = httptest.NewRecorder()
+	proxyHandler(w, req)
+	if w.Code != http.StatusOK || atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("Expected request within limits to be forwarded, got status %d", w.Code)
+	}
+}
+
+func TestWithRequestHeaderLimits_AdminProxy(t *testing.T) {
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer upstream.Close()
+
+	cfg, log, _, _, _ := setupProxyIntegrationTest()
+	cfg.MaxRequestHeaders = 10
+	cfg.TargetAllowLoopback = true
+
+	// 管理员代理（HTTPProxy）同样受限制
+	proxyHandler := WithRequestHeaderLimits(func(w http.ResponseWriter, r *http.Request) {
+		HTTPProxy(w, r, cfg, log, nil)
+	}, cfg, log)
+
+	req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL, nil)
+	req.Header.Set("X-Log-Secret", cfg.AdminSecret)
+	for i := 0; i < 10; i++ {
+		req.Header.Add("X-Extra", fmt.Sprintf("value-%d", i))
+	}
+	w := httptest.NewRecorder()
+	proxyHandler(w, req)
+	assertProxyError(t, w, http.StatusRequestHeaderFieldsTooLarge, errCodeHeadersTooLarge)
+
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("Expected rejected request not to reach upstream, got %d hits", n)
+	}
+}
+
+func TestWithRequestHeaderLimits_Disabled(t *testing.T) {
+	cfg, log, _, _, _ := setupProxyIntegrationTest()
+	cfg.MaxRequestHeaders = 0
+	cfg.MaxRequestHeaderBytes = 0
+
+	called := false
+	next := func(w http.ResponseWriter, r *http.Request) { called = true }
+	req := httptest.NewRequest("GET", "/config/proxy", nil)
+	for i := 0; i < 200; i++ {
+		req.Header.Add("X-Extra", "value")
+	}
+	WithRequestHeaderLimits(next, cfg, log)(httptest.NewRecorder(), req)
+	if !called {
+		t.Error("Expected requests to pass through when limits are disabled")
+	}
+}
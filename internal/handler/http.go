@@ -2,14 +2,19 @@ package handler
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	"privacygateway/internal/accesslog"
+	"privacygateway/internal/cache"
 	"privacygateway/internal/config"
 	"privacygateway/internal/logger"
+	"privacygateway/internal/metrics"
 	"privacygateway/internal/proxy"
 	"privacygateway/internal/proxyconfig"
 )
@@ -25,12 +30,16 @@ func HTTPProxy(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 	}
 
 	// 认证检查 - 代理服务需要管理员权限
-	if !isAuthorizedForProxy(r, cfg.AdminSecret) {
+	if !isAuthorizedForProxy(r, cfg.AdminSecrets()) {
 		log.Warn("unauthorized proxy request", "client_ip", getClientIP(r), "target", r.URL.Query().Get("target"))
-		http.Error(w, "Unauthorized: Admin secret required", http.StatusUnauthorized)
+		writeProxyError(w, http.StatusUnauthorized, errCodeUnauthorized, "Admin secret required")
 		return
 	}
 
+	// 网关侧gzip压缩位于响应捕获器之下，访问日志记录压缩前的响应体
+	w, closeGzip := wrapGzip(w, r, cfg.ResponseCompression)
+	defer closeGzip()
+
 	// 创建响应捕获器（如果有记录器）
 	var capture *accesslog.ResponseCapture
 
@@ -46,39 +55,47 @@ func HTTPProxy(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 		}
 	}()
 
+	// 分配请求ID（响应头、上游请求头和访问日志使用同一ID）
+	requestID := assignRequestID(w, r, cfg, capture)
+
 	targetStr := r.URL.Query().Get("target")
 	if targetStr == "" {
-		http.Error(w, "'target' query parameter is required", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeMissingTarget, "'target' query parameter is required")
 		return
 	}
 
 	targetURL, err := url.Parse(targetStr)
 	if err != nil || targetURL.Host == "" {
 		log.Error("failed to parse target URL", "input", targetStr, "error", err)
-		http.Error(w, "Invalid target URL", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidTarget, "Invalid target URL")
 		return
 	}
+	// 移除网关自身使用的查询参数
+	targetURL = filterForwardedQuery(targetURL, nil)
+
+	// 链路追踪（未配置OTLP Collector时为空操作）
+	w, span, finishSpan := startProxySpan(w, r, targetURL, "")
+	defer finishSpan()
 
-	// 获取代理配置
-	proxyConfig, err := proxy.GetConfig(r, cfg.DefaultProxy)
+	// 获取出站代理配置（请求指定的代理需通过安全校验）
+	proxyConfig, status, err := resolveOutboundProxy(r, cfg, nil)
 	if err != nil {
-		log.Error("failed to parse proxy config", "error", err)
-		http.Error(w, "Invalid proxy configuration", http.StatusBadRequest)
+		log.Error("failed to resolve proxy config", "error", err)
+		writeOutboundProxyError(w, status)
 		return
 	}
 
-	// 验证代理配置安全性
-	if err := proxy.Validate(proxyConfig, cfg.ProxyWhitelist, cfg.AllowPrivateIP); err != nil {
-		log.Error("proxy validation failed", "error", err)
-		http.Error(w, "Proxy not allowed", http.StatusForbidden)
+	// 检查目标地址访问策略（SSRF防护）
+	targetPolicy := proxy.NewTargetPolicy(cfg, nil, nil)
+	if !checkProxyTarget(w, r, log, targetPolicy, targetURL) {
 		return
 	}
 
 	// 记录请求信息（不泄露敏感代理信息）
 	if proxyConfig != nil && proxyConfig.URL != "" {
-		log.Info("forwarding request via proxy", "method", r.Method, "target", targetURL.String(), "proxy_type", proxyConfig.Type)
+		log.Info("forwarding request via proxy", "method", r.Method, "target", targetURL.String(), "proxy_type", proxyConfig.Type, "request_id", requestID)
 	} else {
-		log.Info("forwarding request", "method", r.Method, "target", targetURL.String())
+		log.Info("forwarding request", "method", r.Method, "target", targetURL.String(), "request_id", requestID)
 	}
 
 	// 读取请求体（如果有）
@@ -87,17 +104,17 @@ func HTTPProxy(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 		requestBody, err = io.ReadAll(r.Body)
 		if err != nil {
 			log.Error("failed to read request body", "error", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
 			return
 		}
 		r.Body.Close()
 	}
 
-	// 创建转发请求
-	proxyReq, err := http.NewRequest(r.Method, targetURL.String(), bytes.NewReader(requestBody))
+	// 创建转发请求（客户端断开时取消上游请求）
+	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL.String(), bytes.NewReader(requestBody))
 	if err != nil {
 		log.Error("failed to create proxy request", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
 		return
 	}
 
@@ -109,6 +126,12 @@ func HTTPProxy(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 			}
 		}
 	}
+	// 逐跳头不转发给上游
+	removeHopByHopHeaders(proxyReq.Header)
+	// 向上游传递请求ID
+	proxyReq.Header.Set(RequestIDHeader(cfg), requestID)
+	// 传播trace上下文（W3C traceparent）
+	span.Inject(proxyReq.Header)
 	// 设置正确的主机头
 	proxyReq.Host = targetURL.Host
 
@@ -144,38 +167,66 @@ func HTTPProxy(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 	}
 
 	// 创建HTTP客户端（支持代理）
-	client, err := proxy.CreateHTTPClient(proxyConfig)
+	client, err := proxy.CreateHTTPClient(proxyConfig, targetPolicy, nil)
 	if err != nil {
 		log.Error("failed to create HTTP client", "error", err)
-		http.Error(w, "Failed to create proxy client", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Failed to create proxy client")
 		return
 	}
+	// 默认不跟随上游重定向，3xx响应原样返回给客户端
+	proxy.LimitRedirects(client, 0, targetPolicy)
+	if isEventStreamRequest(r) {
+		client = streamingClient(client)
+	}
 
 	// 执行请求
 	resp, err := client.Do(proxyReq)
 	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, proxy.ErrTargetBlocked) {
+			log.Warn("proxy target blocked at connect time", "target", targetURL.String(), "client_ip", getClientIP(r), "error", err)
+			writeTargetBlockedResponse(w, err)
+			return
+		}
 		log.Error("failed to execute proxy request", "error", err)
-		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		writeUpstreamError(w, err)
 		return
 	}
 	defer resp.Body.Close()
+	recordUpstreamStatus(capture, resp.StatusCode)
 
-	// 将目标服务器的响应复制回客户端（过滤CORS头避免重复）
+	// 将目标服务器的响应复制回客户端（过滤逐跳头，过滤CORS头避免重复）
+	removeHopByHopHeaders(resp.Header)
 	for key, values := range resp.Header {
-		// 跳过CORS相关的头，因为我们已经设置了
-		if isCORSHeader(key) {
+		// 跳过CORS相关的头，因为我们已经设置了；请求ID以网关分配的为准
+		if isCORSHeader(key) || key == RequestIDHeader(cfg) {
 			continue
 		}
 		for _, value := range values {
 			w.Header().Add(key, value)
 		}
 	}
+
+	// SSE事件流逐块转发并立即刷新
+	if accesslog.IsEventStream(resp.Header.Get("Content-Type")) {
+		prepareEventStreamHeaders(w.Header())
+		w.WriteHeader(resp.StatusCode)
+		if err := writeEventStream(w, resp.Body); err != nil {
+			log.Debug("event stream closed", "target", targetURL.String(), "error", err)
+		}
+		return
+	}
+
 	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+
+	// 流式转发响应体，不在内存中缓冲
+	if _, err := copyAndFlush(w, resp.Body, streamBufferSize); err != nil {
+		log.Error("failed to copy response body", "error", err)
+	}
 }
 
 // HTTPProxyWithTokenAuth 处理HTTP代理请求（支持令牌认证）
-func HTTPProxyWithTokenAuth(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, recorder *accesslog.Recorder, storage proxyconfig.Storage) {
+func HTTPProxyWithTokenAuth(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, recorder *accesslog.Recorder, storage proxyconfig.Storage, responseCache *cache.ResponseCache) {
 	// 注意：CORS头部已在路由层设置，这里不再重复设置
 
 	// 处理预检请求
@@ -188,31 +239,130 @@ func HTTPProxyWithTokenAuth(w http.ResponseWriter, r *http.Request, cfg *config.
 	configID := ExtractConfigID(r)
 
 	// 创建认证器
-	authenticator := NewProxyAuthenticator(cfg.AdminSecret, storage, log)
+	authenticator := NewProxyAuthenticator(cfg.AdminSecret, storage, log, cfg.AdminSecretPrevious)
 
 	// 认证检查
 	authResult := authenticator.AuthenticateForProxy(r, configID)
 	if !authResult.Authenticated {
 		authenticator.LogAuthFailure(r, authResult, "http_proxy")
 
-		// 返回详细的认证错误信息
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
+		// 返回详细的认证错误信息，令牌校验失败时使用具体的错误代码
+		errorCode := errCodeUnauthorized
+		if authResult.ValidationResult != nil && authResult.ValidationResult.ErrorCode != "" {
+			errorCode = authResult.ValidationResult.ErrorCode
+		}
+		writeProxyErrorFields(w, http.StatusUnauthorized, errorCode, authResult.Error, map[string]interface{}{
+			"method": authResult.Method,
+		})
+		return
+	}
+
+	// 管理员调试用的目标覆盖，令牌认证的请求不允许使用
+	var overrideTarget *url.URL
+	if value := r.Header.Get(overrideTargetHeader); value != "" {
+		if authResult.Method != "admin" {
+			log.Warn("proxy request rejected: target override requires admin secret",
+				"method", authResult.Method,
+				"config_id", authResult.ConfigID,
+				"client_ip", getClientIP(r))
 
-		errorResponse := map[string]interface{}{
-			"error":   "Unauthorized",
-			"message": authResult.Error,
-			"method":  authResult.Method,
-			"status":  http.StatusUnauthorized,
-			"success": false,
+			writeProxyError(w, http.StatusForbidden, errCodeOverrideDenied, overrideTargetHeader+" requires admin authentication")
+			return
+		}
+		var err error
+		if overrideTarget, err = parseOverrideTarget(value); err != nil {
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidTarget, "Invalid "+overrideTargetHeader+": "+err.Error())
+			return
 		}
+	}
 
-		if authResult.ValidationResult != nil {
-			errorResponse["error_code"] = authResult.ValidationResult.ErrorCode
+	// 检查配置是否已禁用（令牌有效也不允许转发）
+	var routeConfig *proxyconfig.ProxyConfig
+	if authResult.ConfigID != "" {
+		if proxyConfig, err := storage.GetByID(authResult.ConfigID); err == nil {
+			routeConfig = proxyConfig
+		}
+		if routeConfig != nil && !routeConfig.Enabled {
+			log.Warn("proxy request rejected: config disabled",
+				"method", authResult.Method,
+				"config_id", authResult.ConfigID,
+				"client_ip", getClientIP(r),
+				"target", r.URL.Query().Get("target"))
+
+			writeConfigDisabledResponse(w, authResult.ConfigID)
+			return
+		}
+		if routeConfig != nil && !routeConfig.AllowsMethod(r.Method) {
+			log.Warn("proxy request rejected: method not allowed",
+				"method", r.Method,
+				"config_id", authResult.ConfigID,
+				"client_ip", getClientIP(r),
+				"target", r.URL.Query().Get("target"))
+
+			writeMethodNotAllowedResponse(w, routeConfig)
+			return
 		}
+	}
 
-		json.NewEncoder(w).Encode(errorResponse)
-		return
+	// 配置要求的客户端Basic认证（与访问令牌相互独立）
+	if routeConfig != nil && routeConfig.RequiresBasicAuth() {
+		username, password, ok := r.BasicAuth()
+		if !ok || !routeConfig.CheckBasicAuth(username, password) {
+			log.Warn("proxy request rejected: basic auth failed",
+				"config_id", routeConfig.ID,
+				"credentials_present", ok,
+				"client_ip", getClientIP(r),
+				"target", r.URL.Query().Get("target"))
+
+			writeBasicAuthChallenge(w, routeConfig, ok)
+			return
+		}
+		// 网关消费的凭据不转发给上游
+		r.Header.Del("Authorization")
+	}
+
+	// 配置要求的HMAC请求签名（校验时间窗口和nonce防重放）
+	if routeConfig != nil && routeConfig.RequestSigning != nil {
+		if err := verifyRequestSignature(r, routeConfig.RequestSigning, routeConfig.ID); errors.Is(err, proxyconfig.ErrSignedBodyTooLarge) {
+			log.Warn("proxy request rejected: signed request body too large",
+				"config_id", routeConfig.ID,
+				"max_body_size", proxyconfig.MaxSignedBodySize,
+				"client_ip", getClientIP(r),
+				"target", r.URL.Query().Get("target"))
+
+			writeProxyErrorFields(w, http.StatusRequestEntityTooLarge, errCodeBodyTooLarge, "Request body exceeds the signed body size limit", map[string]interface{}{
+				"config_id":     routeConfig.ID,
+				"max_body_size": proxyconfig.MaxSignedBodySize,
+			})
+			return
+		} else if err != nil {
+			log.Warn("proxy request rejected: invalid request signature",
+				"config_id", routeConfig.ID,
+				"reason", err.Error(),
+				"client_ip", getClientIP(r),
+				"target", r.URL.Query().Get("target"))
+
+			writeProxyErrorFields(w, http.StatusUnauthorized, errCodeInvalidSignature, "Request signature verification failed: "+err.Error(), map[string]interface{}{
+				"config_id": routeConfig.ID,
+			})
+			return
+		}
+	}
+
+	// 配置级并发限制：名额已满时只拒绝该配置的请求
+	if routeConfig != nil {
+		release, ok := configConcurrency.acquire(routeConfig.ID, routeConfig.MaxConcurrency)
+		if !ok {
+			log.Warn("proxy request rejected: config concurrency limit reached",
+				"config_id", routeConfig.ID,
+				"max_concurrency", routeConfig.MaxConcurrency,
+				"client_ip", getClientIP(r),
+				"target", r.URL.Query().Get("target"))
+
+			writeConcurrencyLimitResponse(w, routeConfig)
+			return
+		}
+		defer release()
 	}
 
 	// 记录认证成功信息
@@ -222,17 +372,119 @@ func HTTPProxyWithTokenAuth(w http.ResponseWriter, r *http.Request, cfg *config.
 		"client_ip", getClientIP(r),
 		"target", r.URL.Query().Get("target"))
 
-	// 调用原有的代理逻辑（从认证检查之后开始）
-	handleProxyRequest(w, r, cfg, log, recorder)
+	// 调用原有的代理逻辑（从认证检查之后开始），令牌使用次数在其中的网关检查通过后计入
+	consumeUsage := func() error { return authenticator.ConsumeTokenUsage(r, authResult) }
+	handleProxyRequest(w, r, cfg, log, recorder, routeConfig, responseCache, overrideTarget, consumeUsage)
+}
+
+// writeConfigDisabledResponse 返回配置已禁用的错误响应
+func writeConfigDisabledResponse(w http.ResponseWriter, configID string) {
+	writeProxyErrorFields(w, http.StatusForbidden, errCodeConfigDisabled, "Proxy configuration is disabled", map[string]interface{}{
+		"config_id": configID,
+	})
+}
+
+// maintenanceRetryAfter 维护模式下建议客户端重试的等待时间（秒）
+const maintenanceRetryAfter = 300
+
+// writeMaintenanceResponse 返回维护模式的503响应，浏览器请求返回HTML页面
+func writeMaintenanceResponse(w http.ResponseWriter, r *http.Request, routeConfig *proxyconfig.ProxyConfig) {
+	message := routeConfig.MaintenanceMessage
+	if message == "" {
+		message = "Service is under maintenance, please retry later"
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(maintenanceRetryAfter))
+	writeProxyErrorFields(withBuiltinErrorPages(w, r), http.StatusServiceUnavailable, errCodeMaintenance, message, map[string]interface{}{
+		"config_id": routeConfig.ID,
+	})
+}
+
+// writeBasicAuthChallenge 返回要求Basic认证的401响应（凭据缺失或错误时都带认证质询）
+func writeBasicAuthChallenge(w http.ResponseWriter, routeConfig *proxyconfig.ProxyConfig, credentialsPresent bool) {
+	message := "Basic authentication required"
+	if credentialsPresent {
+		message = "Invalid basic authentication credentials"
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="Privacy Gateway", charset="UTF-8"`)
+	writeProxyErrorFields(w, http.StatusUnauthorized, errCodeUnauthorized, message, map[string]interface{}{
+		"config_id": routeConfig.ID,
+	})
+}
+
+// writeMethodNotAllowedResponse 返回请求方法不在配置允许列表中的错误响应
+func writeMethodNotAllowedResponse(w http.ResponseWriter, routeConfig *proxyconfig.ProxyConfig) {
+	w.Header().Set("Allow", routeConfig.AllowHeader())
+	writeProxyErrorFields(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Request method is not allowed by proxy configuration", map[string]interface{}{
+		"config_id": routeConfig.ID,
+	})
+}
+
+// resolveOutboundProxy 确定本次请求使用的出站代理
+//
+// 优先级：请求头/查询参数 > 配置级upstream_proxy > 全局UPSTREAM_PROXY。
+// 只有请求自行指定的代理需要经过白名单和私有地址校验，管理员配置的代理
+// （可能位于内网）直接信任。出错时返回应响应的状态码。
+func resolveOutboundProxy(r *http.Request, cfg *config.Config, routeConfig *proxyconfig.ProxyConfig) (*config.ProxyConfig, int, error) {
+	if proxy.IsRequestSupplied(r) {
+		proxyConfig, err := proxy.GetConfig(r, nil)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		if err := proxy.Validate(proxyConfig, cfg.ProxyWhitelist, cfg.AllowPrivateIP); err != nil {
+			return nil, http.StatusForbidden, err
+		}
+		return proxyConfig, 0, nil
+	}
+
+	if routeConfig != nil && routeConfig.UpstreamProxy != "" {
+		if routeConfig.UpstreamProxy == proxy.DirectProxy {
+			return nil, 0, nil
+		}
+		proxyConfig, err := proxy.ParseSimple(routeConfig.UpstreamProxy)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		proxyConfig.NoProxy = cfg.UpstreamNoProxy
+		return proxyConfig, 0, nil
+	}
+
+	return cfg.DefaultProxy, 0, nil
+}
+
+// checkProxyTarget 检查目标地址是否允许代理，不允许时写入403响应并返回false
+func checkProxyTarget(w http.ResponseWriter, r *http.Request, log *logger.Logger, policy *proxy.TargetPolicy, targetURL *url.URL) bool {
+	if err := policy.Check(r.Context(), targetURL); err != nil {
+		log.Warn("proxy target blocked", "target", targetURL.String(), "client_ip", getClientIP(r), "error", err)
+		writeTargetBlockedResponse(w, err)
+		return false
+	}
+	return true
+}
+
+// writeTargetBlockedResponse 返回目标地址被拒绝的错误响应
+func writeTargetBlockedResponse(w http.ResponseWriter, err error) {
+	writeProxyError(w, http.StatusForbidden, errCodeTargetBlocked, err.Error())
 }
 
 // handleProxyRequest 处理代理请求的核心逻辑（从认证之后开始）
-func handleProxyRequest(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, recorder *accesslog.Recorder) {
-	// 创建响应捕获器（如果有记录器）
+//
+// routeConfig 为认证时解析出的代理配置（管理员未指定配置时为nil），
+// responseCache 为nil时不启用响应缓存，overrideTarget 为管理员通过X-Override-Target指定的上游（为nil时不覆盖），
+// consumeUsage 在维护模式等网关检查通过后计入令牌使用次数（为nil时不计数）。
+func handleProxyRequest(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, recorder *accesslog.Recorder, routeConfig *proxyconfig.ProxyConfig, responseCache *cache.ResponseCache, overrideTarget *url.URL, consumeUsage func() error) {
+	// 网关侧gzip压缩位于响应捕获器之下，访问日志记录压缩前的响应体
+	w, closeGzip := wrapGzip(w, r, compressionEnabled(cfg, routeConfig))
+	defer closeGzip()
+
+	// 创建响应捕获器（如果有记录器），配置可覆盖全局的请求/响应体记录策略
 	var capture *accesslog.ResponseCapture
+	captureBody, record200 := bodyLoggingPolicy(cfg, routeConfig)
 
 	if recorder != nil {
-		capture = accesslog.NewResponseCapture(w, true, cfg.LogMaxBodySize, cfg.LogRecord200)
+		capture = accesslog.NewResponseCapture(w, captureBody, cfg.LogMaxBodySize, record200)
+		if routeConfig != nil {
+			capture.SetConfig(routeConfig.ID, routeConfig.Name)
+		}
 		w = capture
 	}
 
@@ -243,39 +495,111 @@ func handleProxyRequest(w http.ResponseWriter, r *http.Request, cfg *config.Conf
 		}
 	}()
 
+	// 分配请求ID（响应头、上游请求头和访问日志使用同一ID）
+	requestID := assignRequestID(w, r, cfg, capture)
+
+	// 请求体长度的表示有歧义时拒绝转发（防止请求走私）
+	if err := checkRequestFraming(r); err != nil {
+		log.Warn("proxy request rejected: ambiguous request framing", "reason", err.Error(), "client_ip", getClientIP(r), "request_id", requestID)
+		writeProxyError(w, http.StatusBadRequest, errCodeAmbiguousFraming, "Ambiguous request framing: "+err.Error())
+		return
+	}
+
+	// 维护模式：不转发，直接返回503（请求仍记录到访问日志）
+	if routeConfig != nil && routeConfig.MaintenanceMode {
+		log.Info("proxy request rejected: config in maintenance mode", "config_id", routeConfig.ID, "request_id", requestID)
+		writeMaintenanceResponse(w, r, routeConfig)
+		return
+	}
+
+	// 网关检查全部通过后才计入令牌使用次数，被网关拒绝的请求不消耗配额
+	if consumeUsage != nil {
+		if err := consumeUsage(); err != nil {
+			writeProxyErrorFields(w, http.StatusUnauthorized, "TOKEN_QUOTA_EXCEEDED", err.Error(), map[string]interface{}{
+				"method": "token",
+			})
+			return
+		}
+	}
+
 	targetStr := r.URL.Query().Get("target")
 	if targetStr == "" {
-		http.Error(w, "'target' query parameter is required", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeMissingTarget, "'target' query parameter is required")
 		return
 	}
 
 	targetURL, err := url.Parse(targetStr)
 	if err != nil || targetURL.Host == "" {
 		log.Error("failed to parse target URL", "input", targetStr, "error", err)
-		http.Error(w, "Invalid target URL", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidTarget, "Invalid target URL")
 		return
 	}
 
-	// 获取代理配置
-	proxyConfig, err := proxy.GetConfig(r, cfg.DefaultProxy)
+	// 按配置的路径规则改写目标地址（首个匹配的规则生效），无规则匹配时拼接基础路径
+	if routeConfig != nil {
+		if rewritten, ok := routeConfig.RewriteTarget(targetURL); ok {
+			log.Debug("target rewritten by path rule", "config_id", routeConfig.ID, "from", targetURL.String(), "to", rewritten.String())
+			targetURL = rewritten
+		} else if routeConfig.BasePath != "" {
+			targetURL = routeConfig.ApplyBasePath(targetURL)
+		}
+	}
+	// 管理员指定的目标覆盖（只替换协议和主机，目标访问策略仍然生效）
+	if overrideTarget != nil {
+		overridden := applyOverrideTarget(targetURL, overrideTarget)
+		log.Warn("proxy target overridden by admin header",
+			"from", targetURL.String(),
+			"to", overridden.String(),
+			"client_ip", getClientIP(r),
+			"request_id", requestID)
+		targetURL = overridden
+	}
+	// 移除网关自身使用的查询参数，并按配置过滤转发的查询参数
+	targetURL = filterForwardedQuery(targetURL, routeConfig)
+
+	// 链路追踪（未配置OTLP Collector时为空操作）
+	configID := ""
+	if routeConfig != nil {
+		configID = routeConfig.ID
+	}
+	w, span, finishSpan := startProxySpan(w, r, targetURL, configID)
+	defer finishSpan()
+
+	// 获取出站代理配置（请求指定 > 配置级upstream_proxy > 全局UPSTREAM_PROXY）
+	proxyConfig, status, err := resolveOutboundProxy(r, cfg, routeConfig)
 	if err != nil {
-		log.Error("failed to parse proxy config", "error", err)
-		http.Error(w, "Invalid proxy configuration", http.StatusBadRequest)
+		log.Error("failed to resolve proxy config", "error", err)
+		writeOutboundProxyError(w, status)
 		return
 	}
 
-	// 验证代理配置安全性
-	if err := proxy.Validate(proxyConfig, cfg.ProxyWhitelist, cfg.AllowPrivateIP); err != nil {
-		log.Error("proxy validation failed", "error", err)
-		http.Error(w, "Proxy not allowed", http.StatusForbidden)
+	// 检查目标地址访问策略（SSRF防护），合并配置级的主机允许/拒绝列表
+	var targetPolicy *proxy.TargetPolicy
+	if routeConfig != nil {
+		targetPolicy = proxy.NewTargetPolicy(cfg, routeConfig.AllowedHosts, routeConfig.DeniedHosts)
+	} else {
+		targetPolicy = proxy.NewTargetPolicy(cfg, nil, nil)
+	}
+	if !checkProxyTarget(w, r, log, targetPolicy, targetURL) {
 		return
 	}
 
+	// 响应缓存（仅对启用了缓存的配置的GET请求生效，按共享缓存规则匹配Vary和Authorization）
+	cacheKey := ""
+	if responseCache != nil && routeConfig != nil && routeConfig.CacheTTLSeconds > 0 && r.Method == http.MethodGet {
+		cacheKey = routeConfig.ID + "|" + targetURL.String()
+		if entry, ok := responseCache.Get(cacheKey); ok && entry.Matches(r) {
+			log.Debug("serving response from cache", "config_id", routeConfig.ID, "target", targetURL.String())
+			writeCachedResponse(w, r, entry)
+			return
+		}
+	}
+
 	// 记录请求信息（不泄露敏感代理信息）
 	if proxyConfig != nil && proxyConfig.URL != "" {
-		log.Info("forwarding request via proxy", "method", r.Method, "target", targetURL.String(), "proxy_type", proxyConfig.Type)
+		log.Info("forwarding request via proxy", "method", r.Method, "target", targetURL.String(), "proxy_type", proxyConfig.Type, "request_id", requestID)
 	} else {
-		log.Info("forwarding request", "method", r.Method, "target", targetURL.String())
+		log.Info("forwarding request", "method", r.Method, "target", targetURL.String(), "request_id", requestID)
 	}
 
 	// 读取请求体（如果有）
@@ -284,17 +608,25 @@ func handleProxyRequest(w http.ResponseWriter, r *http.Request, cfg *config.Conf
 		requestBody, err = io.ReadAll(r.Body)
 		if err != nil {
 			log.Error("failed to read request body", "error", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
 			return
 		}
 		r.Body.Close()
 	}
 
-	// 创建转发请求
-	proxyReq, err := http.NewRequest(r.Method, targetURL.String(), bytes.NewReader(requestBody))
+	// 按配置的模板改写JSON请求体（非JSON请求体原样转发）
+	if routeConfig != nil {
+		if transformed, ok := routeConfig.TransformRequestBody(r.Header.Get("Content-Type"), requestBody); ok {
+			log.Debug("request body transformed", "config_id", routeConfig.ID, "request_id", requestID)
+			requestBody = transformed
+		}
+	}
+
+	// 创建转发请求（客户端断开时取消上游请求及重试等待）
+	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL.String(), bytes.NewReader(requestBody))
 	if err != nil {
 		log.Error("failed to create proxy request", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
 		return
 	}
 
@@ -306,11 +638,33 @@ func handleProxyRequest(w http.ResponseWriter, r *http.Request, cfg *config.Conf
 			}
 		}
 	}
+	// 逐跳头和网关消费的目标覆盖头不转发给上游
+	removeHopByHopHeaders(proxyReq.Header)
+	proxyReq.Header.Del(overrideTargetHeader)
+	// 向上游传递请求ID
+	proxyReq.Header.Set(RequestIDHeader(cfg), requestID)
+	// 传播trace上下文（W3C traceparent）
+	span.Inject(proxyReq.Header)
 	// 设置正确的主机头
 	proxyReq.Host = targetURL.Host
+	// 按配置向上游传递客户端信息（X-Forwarded-*、X-Real-IP）
+	if routeConfig != nil && routeConfig.ForwardClientHeaders {
+		setForwardedHeaders(proxyReq.Header, r, cfg.TrustedProxies)
+	}
+	// 按配置替换User-Agent（未配置时透传客户端的User-Agent）
+	if routeConfig != nil && routeConfig.UserAgentOverride != "" {
+		proxyReq.Header.Set("User-Agent", routeConfig.UserAgentOverride)
+	}
+	// 需要改写响应体时由Transport协商压缩并自动解压，保证拿到的是明文
+	if routeConfig != nil && len(routeConfig.ResponseBodyRewrites) > 0 {
+		proxyReq.Header.Del("Accept-Encoding")
+	}
 
 	// 记录请求头信息（用于日志）
 	if recorder != nil && capture != nil {
+		// 访问日志记录实际发送给上游的User-Agent
+		capture.SetActualUserAgent(proxyReq.Header.Get("User-Agent"))
+
 		requestHeaders := make(map[string]string)
 		for key, values := range r.Header {
 			if !IsSensitiveHeader(key, cfg.SensitiveHeaders) && len(values) > 0 {
@@ -320,45 +674,199 @@ func handleProxyRequest(w http.ResponseWriter, r *http.Request, cfg *config.Conf
 		capture.SetRequestHeaders(requestHeaders)
 
 		// 捕获请求体（如果有且不是太大）
-		if len(requestBody) > 0 && len(requestBody) <= cfg.LogMaxBodySize {
+		if captureBody && len(requestBody) > 0 && len(requestBody) <= cfg.LogMaxBodySize {
 			capture.SetRequestBody(string(requestBody))
 		}
 	}
 
+	// 按配置隔离的上游连接：mTLS客户端证书、自定义CA及连接池覆盖
+	var upstream *proxy.UpstreamOptions
+	if routeConfig != nil {
+		upstream, err = upstreamOptions(routeConfig)
+		if err != nil {
+			log.Error("failed to load upstream TLS config", "config_id", routeConfig.ID, "error", err)
+			writeProxyError(w, http.StatusBadGateway, errCodeUpstreamTLS, "Invalid upstream TLS configuration")
+			return
+		}
+	}
+
 	// 创建HTTP客户端（支持代理）
-	client, err := proxy.CreateHTTPClient(proxyConfig)
+	client, err := proxy.CreateHTTPClient(proxyConfig, targetPolicy, upstream)
 	if err != nil {
 		log.Error("failed to create HTTP client", "error", err)
-		http.Error(w, "Failed to create proxy client", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Failed to create proxy client")
 		return
 	}
+	// 按配置的重定向策略跟随上游重定向（默认不跟随），每一跳都重新检查目标访问策略
+	maxRedirects := 0
+	if routeConfig != nil {
+		maxRedirects, _ = routeConfig.RedirectPolicy.MaxRedirects()
+	}
+	proxy.LimitRedirects(client, maxRedirects, targetPolicy)
+	if isEventStreamRequest(r) {
+		client = streamingClient(client)
+	}
+
+	// 镜像请求副本到影子上游（异步，不影响本次响应）
+	if routeConfig != nil && routeConfig.MirrorURL != "" {
+		mirrorRequest(proxyReq, requestBody, routeConfig, proxyConfig, targetPolicy, log)
+	}
+
+	// 执行请求（按配置对临时错误重试），上游超过读空闲超时没有发送数据时中止
+	proxyReq, watchdog := newIdleWatchdog(proxyReq, routeConfig)
+	defer watchdog.Stop()
+	resp, retries, err := doWithRetry(client, proxyReq, requestBody, newRetryPolicy(routeConfig), log)
+	err = watchdog.Err(err)
+
+	// 记录代理信息（包含重试次数）
+	if capture != nil {
+		proxyInfoStr := "Privacy Gateway v1.0"
+		if proxyConfig != nil && proxyConfig.URL != "" {
+			proxyInfoStr += " (via " + proxyConfig.Type + " proxy)"
+		}
+		if retries > 0 {
+			proxyInfoStr += fmt.Sprintf(" (retries: %d)", retries)
+		}
+		capture.SetProxyInfo(proxyInfoStr)
+	}
+	if retries > 0 {
+		span.SetAttribute("http.request.resend_count", retries)
+	}
 
-	// 执行请求
-	resp, err := client.Do(proxyReq)
 	if err != nil {
-		log.Error("failed to execute proxy request", "error", err)
-		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		span.RecordError(err)
+		if errors.Is(err, proxy.ErrTargetBlocked) {
+			log.Warn("proxy target blocked at connect time", "target", targetURL.String(), "client_ip", getClientIP(r), "error", err)
+			writeTargetBlockedResponse(w, err)
+			return
+		}
+		log.Error("failed to execute proxy request", "error", err, "retries", retries)
+		writeUpstreamError(w, err)
 		return
 	}
+	resp.Body = watchdog.Wrap(resp.Body)
 	defer resp.Body.Close()
+	recordUpstreamStatus(capture, resp.StatusCode)
 
-	// 复制响应头（过滤CORS头避免重复）
+	// 复制响应头（过滤逐跳头，过滤CORS头避免重复）
+	removeHopByHopHeaders(resp.Header)
+	responseHeader := make(http.Header)
 	for key, values := range resp.Header {
-		// 跳过CORS相关的头，因为我们已经在路由层设置了
-		if isCORSHeader(key) {
+		// 跳过CORS相关的头，因为我们已经在路由层设置了；请求ID以网关分配的为准
+		if isCORSHeader(key) || key == RequestIDHeader(cfg) {
 			continue
 		}
 		for _, value := range values {
 			w.Header().Add(key, value)
+			responseHeader.Add(key, value)
 		}
 	}
 
+	// SSE事件流逐块转发并立即刷新，不缓存
+	if accesslog.IsEventStream(resp.Header.Get("Content-Type")) {
+		prepareEventStreamHeaders(w.Header())
+		w.WriteHeader(resp.StatusCode)
+		if err := writeEventStream(w, resp.Body); err != nil {
+			log.Debug("event stream closed", "target", targetURL.String(), "error", err)
+		}
+		return
+	}
+
+	// 按配置改写文本响应体（超过大小上限或非文本内容原样转发）
+	var body io.Reader = resp.Body
+	if routeConfig != nil && len(routeConfig.ResponseBodyRewrites) > 0 && isRewritableResponse(r, resp) {
+		body = rewriteResponseBody(w, resp, routeConfig, responseHeader, log)
+	}
+
+	// 判断响应是否可以缓存
+	storable := cacheKey != "" && resp.StatusCode == http.StatusOK && cache.IsStorable(r, resp.Header)
+	if storable {
+		w.Header().Set("X-Cache", "MISS")
+	}
+
 	// 设置状态码
 	w.WriteHeader(resp.StatusCode)
 
-	// 复制响应体
-	_, err = io.Copy(w, resp.Body)
+	// 流式复制响应体（可缓存时同时写入缓冲区，超过缓存容量则放弃缓存）
+	var cacheBuffer *limitedBuffer
+	if storable {
+		cacheBuffer = &limitedBuffer{limit: responseCache.MaxBytes()}
+		body = io.TeeReader(body, cacheBuffer)
+	}
+
+	_, err = copyAndFlush(w, body, streamBufferSize)
 	if err != nil {
+		if errors.Is(err, errUpstreamIdleTimeout) {
+			// 响应头已发出，只能中断响应
+			recordUpstreamError(metrics.UpstreamErrorTimeout)
+			log.Warn("upstream stalled while streaming response", "target", targetURL.String(), "read_idle_timeout", routeConfig.ReadIdleTimeout)
+			return
+		}
 		log.Error("failed to copy response body", "error", err)
+		return
+	}
+
+	if cacheBuffer != nil && !cacheBuffer.overflow {
+		responseCache.Set(cacheKey, &cache.Entry{
+			StatusCode: resp.StatusCode,
+			Header:     responseHeader,
+			Body:       cacheBuffer.Bytes(),
+			ExpiresAt:  time.Now().Add(time.Duration(routeConfig.CacheTTLSeconds) * time.Second),
+			Vary:       cache.VaryValues(r, resp.Header),
+		})
+	}
+}
+
+// bodyLoggingPolicy 确定访问日志是否记录请求/响应体，以及是否记录200响应的响应体
+func bodyLoggingPolicy(cfg *config.Config, routeConfig *proxyconfig.ProxyConfig) (bool, bool) {
+	if routeConfig != nil {
+		switch routeConfig.LogBodies {
+		case proxyconfig.LogBodiesNever:
+			return false, false
+		case proxyconfig.LogBodiesAlways:
+			return true, true
+		}
+	}
+	return true, cfg.LogRecord200
+}
+
+// writeCachedResponse 使用缓存条目响应请求，条件请求命中校验器时返回304
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, entry *cache.Entry) {
+	for key, values := range entry.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.Header().Set("X-Cache", "HIT")
+
+	if cache.IsNotModified(r, entry.Header) {
+		// 304响应不包含实体相关的头和响应体
+		w.Header().Del("Content-Length")
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(entry.StatusCode)
+	w.Write(entry.Body)
+}
+
+// limitedBuffer 有容量上限的缓冲区，超过上限后停止写入并标记溢出
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+// Write 实现io.Writer接口，始终返回成功以免中断响应转发
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+	if int64(b.Len()+len(p)) > b.limit {
+		b.overflow = true
+		b.Reset()
+		return len(p), nil
 	}
+	return b.Buffer.Write(p)
 }
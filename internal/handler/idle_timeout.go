@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"privacygateway/internal/proxyconfig"
+)
+
+// errUpstreamIdleTimeout 上游超过读空闲超时没有发送任何数据
+var errUpstreamIdleTimeout = errors.New("upstream sent no data within the read idle timeout")
+
+// idleWatchdog 上游读空闲超时：从发出请求开始，超过timeout没有收到上游数据时取消请求
+//
+// 与整体请求超时不同，只要上游持续发送数据（如长时间的下载或事件流）就不会触发。
+type idleWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired atomic.Bool
+}
+
+// newIdleWatchdog 按配置的read_idle_timeout为请求设置读空闲超时，返回可被取消的请求和看门狗
+//
+// 未配置时返回原请求和nil（nil看门狗的方法均为空操作）。
+func newIdleWatchdog(req *http.Request, routeConfig *proxyconfig.ProxyConfig) (*http.Request, *idleWatchdog) {
+	if routeConfig == nil || routeConfig.ReadIdleTimeout <= 0 {
+		return req, nil
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	watchdog := &idleWatchdog{timeout: time.Duration(routeConfig.ReadIdleTimeout) * time.Second, cancel: cancel}
+	watchdog.timer = time.AfterFunc(watchdog.timeout, func() {
+		watchdog.expired.Store(true)
+		cancel()
+	})
+	return req.WithContext(ctx), watchdog
+}
+
+// Expired 检查请求是否因读空闲超时被取消
+func (w *idleWatchdog) Expired() bool {
+	return w != nil && w.expired.Load()
+}
+
+// Stop 停止计时并释放请求上下文
+func (w *idleWatchdog) Stop() {
+	if w == nil {
+		return
+	}
+	w.timer.Stop()
+	w.cancel()
+}
+
+// Err 请求出错时，如果由读空闲超时引起则返回errUpstreamIdleTimeout，否则原样返回
+func (w *idleWatchdog) Err(err error) error {
+	if err != nil && w.Expired() {
+		return errUpstreamIdleTimeout
+	}
+	return err
+}
+
+// Wrap 包装上游响应体，每次读到数据时重新计时
+func (w *idleWatchdog) Wrap(body io.ReadCloser) io.ReadCloser {
+	if w == nil {
+		return body
+	}
+	return &idleTimeoutBody{ReadCloser: body, watchdog: w}
+}
+
+// idleTimeoutBody 读到数据时重置读空闲计时的响应体
+type idleTimeoutBody struct {
+	io.ReadCloser
+	watchdog *idleWatchdog
+}
+
+// Read 实现io.Reader接口
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.watchdog.Expired() {
+		b.watchdog.timer.Reset(b.watchdog.timeout)
+	}
+	return n, b.watchdog.Err(err)
+}
+
+// isResponseHeaderTimeout 检查错误是否为等待上游响应头超时（response_header_timeout）
+//
+// 标准库没有导出对应的错误类型，按错误信息判断。
+func isResponseHeaderTimeout(err error) bool {
+	return strings.Contains(err.Error(), "timeout awaiting response headers")
+}
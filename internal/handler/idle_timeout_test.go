@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stalledUpstream 接受连接但从不响应的上游，返回其地址
+func stalledUpstream(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	return "http://" + listener.Addr().String()
+}
+
+func TestHTTPProxyWithTokenAuth_UpstreamTimeouts(t *testing.T) {
+	stalled := stalledUpstream(t)
+
+	tests := []struct {
+		name          string
+		headerTimeout int
+		idleTimeout   int
+		wantCode      string
+	}{
+		{"response header timeout", 1, 0, errCodeHeaderTimeout},
+		{"read idle timeout before headers", 0, 1, errCodeIdleTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+			proxyConfig.ResponseHeaderTimeout = tt.headerTimeout
+			proxyConfig.ReadIdleTimeout = tt.idleTimeout
+			if err := storage.Update(proxyConfig.ID, proxyConfig); err != nil {
+				t.Fatalf("Failed to update config: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/proxy?target="+stalled+"/slow&config_id="+proxyConfig.ID, nil)
+			req.Header.Set("X-Proxy-Token", tokenValue)
+			w := httptest.NewRecorder()
+
+			start := time.Now()
+			HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+			// 在配置的超时后返回504，而不是等待30秒的整体请求超时
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Expected request to be aborted after about 1s, took %v", elapsed)
+			}
+			assertProxyError(t, w, http.StatusGatewayTimeout, tt.wantCode)
+		})
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_ReadIdleTimeoutMidBody(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	proxyConfig.ReadIdleTimeout = 1
+	if err := storage.Update(proxyConfig.ID, proxyConfig); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/stream&config_id="+proxyConfig.ID, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+	// 响应头已发出，停滞后中断响应体的转发
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected stalled body to be aborted after about 1s, took %v", elapsed)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("Expected partial 200 response, got %d %q", w.Code, w.Body.String())
+	}
+}
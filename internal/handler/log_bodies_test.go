@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/proxyconfig"
+)
+
+func TestHTTPProxyWithTokenAuth_LogBodiesOverride(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       proxyconfig.LogBodiesMode
+		status     int
+		wantBodies bool
+	}{
+		{"never skips error body", proxyconfig.LogBodiesNever, http.StatusInternalServerError, false},
+		{"always records 200 body", proxyconfig.LogBodiesAlways, http.StatusOK, true},
+		{"inherit follows global setting", proxyconfig.LogBodiesInherit, http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+			cfg.LogMaxEntries = 100
+			cfg.LogMaxBodySize = 1024
+			cfg.LogRetentionHours = 1
+			cfg.LogMaxMemoryMB = 10
+			cfg.LogRecord200 = false
+
+			recorder, err := accesslog.NewRecorder(cfg, log)
+			if err != nil {
+				t.Fatalf("Failed to create recorder: %v", err)
+			}
+			defer recorder.Close()
+
+			updated := *proxyConfig
+			updated.LogBodies = tt.mode
+			storage.Update(proxyConfig.ID, &updated)
+
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"secret":"response"}`))
+			}))
+			defer upstream.Close()
+
+			req := httptest.NewRequest("POST", "/proxy?target="+upstream.URL+"/data&config_id="+proxyConfig.ID, strings.NewReader(`{"secret":"request"}`))
+			req.Header.Set("X-Proxy-Token", tokenValue)
+			w := httptest.NewRecorder()
+			HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, w.Code)
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for {
+				logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+				if err == nil && len(logs.Logs) > 0 {
+					entry := logs.Logs[0]
+					if gotBody := entry.ResponseBody != ""; gotBody != tt.wantBodies {
+						t.Errorf("Expected response body logged=%v, got %q", tt.wantBodies, entry.ResponseBody)
+					}
+					if tt.mode == proxyconfig.LogBodiesNever && entry.RequestBody != "" {
+						t.Errorf("Expected request body to be omitted, got %q", entry.RequestBody)
+					}
+					if tt.mode == proxyconfig.LogBodiesAlways && entry.RequestBody == "" {
+						t.Error("Expected request body to be logged")
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Expected access log entry to be recorded")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
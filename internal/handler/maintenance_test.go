@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+)
+
+func TestHTTPProxyWithTokenAuth_MaintenanceMode(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 1024
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	var upstreamCalls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upstreamCalls, 1)
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	updated := *proxyConfig
+	updated.MaintenanceMode = true
+	updated.MaintenanceMessage = "Backend upgrade until 02:00 UTC"
+	storage.Update(proxyConfig.ID, &updated)
+
+	send := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/data&config_id="+proxyConfig.ID, nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+		return w
+	}
+
+	// API请求返回JSON
+	w := send("application/json")
+	if !strings.Contains(w.Body.String(), "Backend upgrade until 02:00 UTC") {
+		t.Errorf("Expected maintenance message in body, got %s", w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+	assertProxyError(t, w, http.StatusServiceUnavailable, errCodeMaintenance)
+
+	// 浏览器请求返回HTML
+	w = send("text/html")
+	if w.Code != http.StatusServiceUnavailable || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected HTML 503, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "Backend upgrade until 02:00 UTC") {
+		t.Errorf("Expected maintenance message in page, got %s", w.Body.String())
+	}
+
+	if calls := atomic.LoadInt32(&upstreamCalls); calls != 0 {
+		t.Errorf("Expected no upstream calls in maintenance mode, got %d", calls)
+	}
+
+	// 请求仍记录到访问日志
+	deadline := time.Now().Add(time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+		if err == nil && len(logs.Logs) == 2 {
+			if logs.Logs[0].StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("Expected logged status 503, got %d", logs.Logs[0].StatusCode)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected maintenance requests to be recorded in the access log")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 关闭维护模式后恢复转发
+	updated.MaintenanceMode = false
+	storage.Update(proxyConfig.ID, &updated)
+	if w := send("application/json"); w.Code != http.StatusOK {
+		t.Errorf("Expected forwarding to resume, got %d", w.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"privacygateway/internal/audit"
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+	"privacygateway/internal/metrics"
+)
+
+// HandleMetricsResetAPI 处理指标清零请求（需要管理员密钥），返回清零前的快照便于运维留存
+func HandleMetricsResetAPI(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, m *metrics.Metrics, auditRecorder *audit.Recorder) {
+	if !isAuthorizedForConfig(r, cfg.AdminSecrets()) {
+		handleConfigAuthFailure(w, r, cfg.AdminSecret)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if m == nil {
+		http.Error(w, "Metrics not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	snapshot := m.Reset()
+	log.Info("metrics reset", "total_requests", snapshot.TotalRequests, "client_ip", getClientIP(r))
+	recordAudit(auditRecorder, log, r, audit.ActionMetricsReset, "", "", map[string]interface{}{
+		"total_requests": snapshot.TotalRequests,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(snapshot)
+}
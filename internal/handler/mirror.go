@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+	"privacygateway/internal/proxy"
+	"privacygateway/internal/proxyconfig"
+)
+
+// 镜像请求的限制
+const (
+	maxMirrorBodySize    = 1 << 20 // 请求体超过该大小（字节）时不镜像
+	maxInFlightMirrors   = 64      // 同时进行中的镜像请求上限，超出时丢弃
+	mirrorRequestTimeout = 30 * time.Second
+)
+
+// mirrorSlots 限制同时进行中的镜像请求
+var mirrorSlots = make(chan struct{}, maxInFlightMirrors)
+
+// recordMirrorResult 记录镜像请求结果
+func recordMirrorResult(success bool) {
+	if m := gatewayMetrics.Load(); m != nil {
+		m.RecordMirror(success)
+	}
+}
+
+// mirrorRequest 将已构造好的上游请求复制一份异步发送到配置的镜像上游，响应被丢弃
+//
+// 镜像不影响返回给客户端的响应和耗时：请求体超过上限或进行中的镜像过多时直接跳过，
+// 镜像请求使用独立的超时，不随客户端断开而取消。
+func mirrorRequest(proxyReq *http.Request, body []byte, routeConfig *proxyconfig.ProxyConfig, outboundProxy *config.ProxyConfig, policy *proxy.TargetPolicy, log *logger.Logger) {
+	if len(body) > maxMirrorBodySize {
+		log.Debug("mirror skipped: request body too large", "config_id", routeConfig.ID, "size", len(body))
+		recordMirrorResult(false)
+		return
+	}
+
+	target, err := routeConfig.MirrorTarget(proxyReq.URL)
+	if err != nil {
+		log.Warn("mirror skipped: invalid mirror url", "config_id", routeConfig.ID, "error", err)
+		recordMirrorResult(false)
+		return
+	}
+
+	select {
+	case mirrorSlots <- struct{}{}:
+	default:
+		log.Warn("mirror skipped: too many in-flight mirror requests", "config_id", routeConfig.ID)
+		recordMirrorResult(false)
+		return
+	}
+
+	header := proxyReq.Header.Clone()
+	method := proxyReq.Method
+
+	go func() {
+		defer func() { <-mirrorSlots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), mirrorRequestTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+		if err != nil {
+			recordMirrorResult(false)
+			return
+		}
+		req.Header = header
+
+		client, err := proxy.CreateHTTPClient(outboundProxy, policy, nil)
+		if err != nil {
+			log.Warn("mirror request failed", "config_id", routeConfig.ID, "error", err)
+			recordMirrorResult(false)
+			return
+		}
+		proxy.LimitRedirects(client, 0, policy)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Debug("mirror request failed", "config_id", routeConfig.ID, "target", target.String(), "error", err)
+			recordMirrorResult(false)
+			return
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxMirrorBodySize))
+		resp.Body.Close()
+
+		recordMirrorResult(resp.StatusCode < http.StatusInternalServerError)
+	}()
+}
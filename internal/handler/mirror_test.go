@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/metrics"
+)
+
+func TestHTTPProxyWithTokenAuth_MirrorRequest(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	m := metrics.NewMetrics()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+
+	type mirrored struct {
+		method, path, query, body string
+	}
+	received := make(chan mirrored, 1)
+	release := make(chan struct{})
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- mirrored{r.Method, r.URL.Path, r.URL.RawQuery, string(body)}
+		// 镜像上游较慢也不影响主请求
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mirror.Close()
+	defer close(release)
+
+	updated := *proxyConfig
+	updated.MirrorURL = mirror.URL + "/shadow"
+	storage.Update(proxyConfig.ID, &updated)
+
+	req := httptest.NewRequest("POST", "/proxy?target="+primary.URL+"/orders?page=2&config_id="+proxyConfig.ID, strings.NewReader(`{"id":1}`))
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected primary response not to wait for the mirror, took %v", elapsed)
+	}
+
+	if w.Code != http.StatusOK || w.Body.String() != "primary" {
+		t.Fatalf("Expected primary response, got %d %q", w.Code, w.Body.String())
+	}
+
+	select {
+	case got := <-received:
+		if got.method != "POST" || got.path != "/shadow/orders" || got.query != "page=2" || got.body != `{"id":1}` {
+			t.Errorf("Unexpected mirrored request: %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected mirror to receive the request")
+	}
+	release <- struct{}{}
+
+	// 镜像上游返回5xx，记录为失败
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if snapshot := m.GetSnapshot(); snapshot.MirrorFailures == 1 {
+			if snapshot.MirrorSuccess != 0 {
+				t.Errorf("Expected no mirror successes, got %d", snapshot.MirrorSuccess)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected mirror failure to be recorded in metrics")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
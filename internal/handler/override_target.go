@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"errors"
+	"net/url"
+)
+
+// overrideTargetHeader 管理员调试用的请求头：将本次请求转发到指定的上游（只替换协议和主机，不修改配置）
+const overrideTargetHeader = "X-Override-Target"
+
+// parseOverrideTarget 解析X-Override-Target，只接受不含路径、查询参数的http(s)地址（如http://staging.internal:8080）
+func parseOverrideTarget(value string) (*url.URL, error) {
+	override, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if (override.Scheme != "http" && override.Scheme != "https") || override.Host == "" {
+		return nil, errors.New("override target must be an http or https URL with a host")
+	}
+	if (override.Path != "" && override.Path != "/") || override.RawQuery != "" || override.User != nil {
+		return nil, errors.New("override target must not contain a path, query or credentials")
+	}
+	return override, nil
+}
+
+// applyOverrideTarget 将目标地址的协议和主机替换为覆盖地址，保留路径和查询参数
+func applyOverrideTarget(target, override *url.URL) *url.URL {
+	overridden := *target
+	overridden.Scheme = override.Scheme
+	overridden.Host = override.Host
+	return &overridden
+}
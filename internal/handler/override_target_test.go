@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// countingUpstream 记录收到的请求数和最后一次请求的路径、覆盖头
+func countingUpstream(t *testing.T, hits *int32, path, overrideHeader *atomic.Value) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if path != nil {
+			path.Store(r.URL.Path)
+			overrideHeader.Store(r.Header.Get(overrideTargetHeader))
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPProxyWithTokenAuth_OverrideTarget(t *testing.T) {
+	var configuredHits, overrideHits int32
+	var path, forwardedHeader atomic.Value
+	configured := countingUpstream(t, &configuredHits, nil, nil)
+	alternate := countingUpstream(t, &overrideHits, &path, &forwardedHeader)
+
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	target := "/proxy?target=" + configured.URL + "/api/items&config_id=" + proxyConfig.ID
+
+	// 管理员请求：转发到覆盖的上游，保留路径，覆盖头不转发
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	req.Header.Set(overrideTargetHeader, alternate.URL)
+	w := httptest.NewRecorder()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected admin override to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if overrideHits != 1 || configuredHits != 0 {
+		t.Errorf("Expected request to reach override target only, got override=%d configured=%d", overrideHits, configuredHits)
+	}
+	if got := path.Load(); got != "/api/items" {
+		t.Errorf("Expected path to be preserved, got %v", got)
+	}
+	if got := forwardedHeader.Load(); got != "" {
+		t.Errorf("Expected override header not to be forwarded, got %q", got)
+	}
+
+	// 令牌认证的请求不允许覆盖目标
+	req = httptest.NewRequest("GET", target, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	req.Header.Set(overrideTargetHeader, alternate.URL)
+	w = httptest.NewRecorder()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+	assertProxyError(t, w, http.StatusForbidden, errCodeOverrideDenied)
+	if overrideHits != 1 || configuredHits != 0 {
+		t.Errorf("Expected token request not to be forwarded, got override=%d configured=%d", overrideHits, configuredHits)
+	}
+
+	// 覆盖地址只能包含协议和主机
+	req = httptest.NewRequest("GET", target, nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	req.Header.Set(overrideTargetHeader, alternate.URL+"/other")
+	w = httptest.NewRecorder()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+	assertProxyError(t, w, http.StatusBadRequest, errCodeInvalidTarget)
+}
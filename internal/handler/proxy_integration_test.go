@@ -1,13 +1,18 @@
 package handler
 
 import (
-	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/cache"
 	"privacygateway/internal/config"
 	"privacygateway/internal/logger"
 	"privacygateway/internal/proxyconfig"
@@ -16,8 +21,9 @@ import (
 func setupProxyIntegrationTest() (*config.Config, *logger.Logger, proxyconfig.Storage, *proxyconfig.ProxyConfig, string) {
 	// 创建测试配置
 	cfg := &config.Config{
-		AdminSecret: "test-secret",
-		Port:        "10805",
+		AdminSecret:         "test-secret",
+		Port:                "10805",
+		TargetAllowLoopback: true, // 测试使用本地上游服务器
 	}
 
 	// 创建日志器
@@ -29,7 +35,6 @@ func setupProxyIntegrationTest() (*config.Config, *logger.Logger, proxyconfig.St
 	// 创建测试代理配置
 	proxyConfig := &proxyconfig.ProxyConfig{
 		Name:      "Test Proxy Config",
-		Subdomain: "testproxy",
 		TargetURL: "https://httpbin.org",
 		Protocol:  "https",
 		Enabled:   true,
@@ -59,7 +64,7 @@ func TestHTTPProxyWithTokenAuth_AdminSecret(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	// 执行请求
-	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage)
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
 
 	// 验证响应（由于是真实的HTTP请求，我们主要验证认证部分）
 	if w.Code == http.StatusUnauthorized {
@@ -79,7 +84,7 @@ func TestHTTPProxyWithTokenAuth_ValidToken(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	// 执行请求
-	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage)
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
 
 	// 验证响应
 	if w.Code == http.StatusUnauthorized {
@@ -104,7 +109,7 @@ func TestHTTPProxyWithTokenAuth_InvalidToken(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	// 执行请求
-	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage)
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
 
 	// 验证响应
 	if w.Code != http.StatusUnauthorized {
@@ -134,7 +139,7 @@ func TestHTTPProxyWithTokenAuth_NoAuthentication(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	// 执行请求
-	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage)
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
 
 	// 验证响应
 	if w.Code != http.StatusUnauthorized {
@@ -142,39 +147,439 @@ func TestHTTPProxyWithTokenAuth_NoAuthentication(t *testing.T) {
 	}
 }
 
-func TestSubdomainProxyWithTokenAuth_ValidToken(t *testing.T) {
+func TestHTTPProxyWithTokenAuth_DisabledConfig(t *testing.T) {
 	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
 
-	// 创建子域名请求（使用有效令牌）
-	req := httptest.NewRequest("GET", "/test", nil)
-	req.Host = proxyConfig.Subdomain + ".localhost:10805"
+	// 禁用配置
+	disabled := *proxyConfig
+	disabled.Enabled = false
+	if err := storage.Update(proxyConfig.ID, &disabled); err != nil {
+		t.Fatalf("Failed to disable config: %v", err)
+	}
+
+	// 使用有效令牌，通过config_id指定配置
+	req := httptest.NewRequest("GET", "/proxy?target=https://httpbin.org/get&config_id="+proxyConfig.ID, nil)
 	req.Header.Set("X-Proxy-Token", tokenValue)
 	w := httptest.NewRecorder()
 
-	// 执行请求
-	HandleSubdomainProxyWithTokenAuth(w, req, cfg, log, nil, storage)
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
 
-	// 验证响应
-	if w.Code == http.StatusUnauthorized {
-		t.Error("Expected token authentication to succeed for subdomain proxy")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for disabled config, got %d", w.Code)
+	}
+
+	var errorResponse map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&errorResponse); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if errorResponse["error_code"] != "CONFIG_DISABLED" {
+		t.Errorf("Expected error_code=CONFIG_DISABLED, got %v", errorResponse["error_code"])
 	}
 }
 
-func TestSubdomainProxyWithTokenAuth_InvalidSubdomain(t *testing.T) {
-	cfg, log, storage, _, tokenValue := setupProxyIntegrationTest()
+func TestHTTPProxyWithTokenAuth_DisabledConfigByTokenLookup(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	disabled := *proxyConfig
+	disabled.Enabled = false
+	if err := storage.Update(proxyConfig.ID, &disabled); err != nil {
+		t.Fatalf("Failed to disable config: %v", err)
+	}
 
-	// 创建无效子域名请求
-	req := httptest.NewRequest("GET", "/test", nil)
-	req.Host = "nonexistent.localhost:10805"
+	// 不指定config_id，通过令牌反向查找配置
+	req := httptest.NewRequest("GET", "/proxy?target=https://httpbin.org/get", nil)
 	req.Header.Set("X-Proxy-Token", tokenValue)
 	w := httptest.NewRecorder()
 
-	// 执行请求
-	HandleSubdomainProxyWithTokenAuth(w, req, cfg, log, nil, storage)
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
 
-	// 验证响应
-	if w.Code != http.StatusNotFound {
-		t.Errorf("Expected 404 for invalid subdomain, got %d", w.Code)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for disabled config resolved by token, got %d", w.Code)
+	}
+}
+
+// 辅助函数：创建启用缓存的配置和计数上游服务器
+func setupResponseCacheTest(t *testing.T, cacheControl string) (*config.Config, *logger.Logger, proxyconfig.Storage, *proxyconfig.ProxyConfig, string, *int) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	upstreamCalls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls++
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Write([]byte("upstream response"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	cached := *proxyConfig
+	cached.TargetURL = upstream.URL
+	cached.CacheTTLSeconds = 60
+	if err := storage.Update(proxyConfig.ID, &cached); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	return cfg, log, storage, &cached, tokenValue, &upstreamCalls
+}
+
+// 辅助函数：发送一次经过缓存的代理请求
+func doCachedProxyRequest(cfg *config.Config, log *logger.Logger, storage proxyconfig.Storage, responseCache *cache.ResponseCache, proxyConfig *proxyconfig.ProxyConfig, tokenValue string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/proxy?target="+proxyConfig.TargetURL+"/data&config_id="+proxyConfig.ID, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	w := httptest.NewRecorder()
+
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, responseCache)
+	return w
+}
+
+func TestHTTPProxyWithTokenAuth_ResponseCacheHitAndMiss(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue, upstreamCalls := setupResponseCacheTest(t, "")
+	responseCache := cache.NewResponseCache(1024 * 1024)
+
+	first := doCachedProxyRequest(cfg, log, storage, responseCache, proxyConfig, tokenValue)
+	if first.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("Expected X-Cache: MISS on first request, got %q", first.Header().Get("X-Cache"))
+	}
+
+	second := doCachedProxyRequest(cfg, log, storage, responseCache, proxyConfig, tokenValue)
+	if second.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("Expected X-Cache: HIT on second request, got %q", second.Header().Get("X-Cache"))
+	}
+	if second.Body.String() != "upstream response" {
+		t.Errorf("Expected cached body, got %q", second.Body.String())
+	}
+	if *upstreamCalls != 1 {
+		t.Errorf("Expected upstream to be called once, got %d", *upstreamCalls)
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_ResponseCacheNoStore(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue, upstreamCalls := setupResponseCacheTest(t, "no-store")
+	responseCache := cache.NewResponseCache(1024 * 1024)
+
+	for i := 0; i < 2; i++ {
+		w := doCachedProxyRequest(cfg, log, storage, responseCache, proxyConfig, tokenValue)
+		if w.Header().Get("X-Cache") == "HIT" {
+			t.Error("Expected no-store response not to be served from cache")
+		}
+	}
+
+	if *upstreamCalls != 2 {
+		t.Errorf("Expected upstream to be called twice, got %d", *upstreamCalls)
+	}
+	if responseCache.Len() != 0 {
+		t.Errorf("Expected no cached entries, got %d", responseCache.Len())
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_ResponseCacheSharedRules(t *testing.T) {
+	testCases := []struct {
+		name           string
+		responseHeader http.Header
+		requestHeader  http.Header
+		upstreamCalls  int
+	}{
+		{"private", http.Header{"Cache-Control": {"private, max-age=60"}}, nil, 2},
+		{"set-cookie", http.Header{"Set-Cookie": {"session=abc"}}, nil, 2},
+		{"authorized request", nil, http.Header{"Authorization": {"Bearer upstream-secret"}}, 2},
+		{"authorized request public", http.Header{"Cache-Control": {"public, max-age=60"}}, http.Header{"Authorization": {"Bearer upstream-secret"}}, 1},
+		{"vary star", http.Header{"Vary": {"*"}}, nil, 2},
+		{"vary same value", http.Header{"Vary": {"Accept-Language"}}, http.Header{"Accept-Language": {"en"}}, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+			upstreamCalls := 0
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				upstreamCalls++
+				for key, values := range tc.responseHeader {
+					w.Header()[key] = values
+				}
+				w.Write([]byte("upstream response"))
+			}))
+			defer upstream.Close()
+
+			cached := *proxyConfig
+			cached.TargetURL = upstream.URL
+			cached.CacheTTLSeconds = 60
+			storage.Update(proxyConfig.ID, &cached)
+			responseCache := cache.NewResponseCache(1024 * 1024)
+
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/data&config_id="+cached.ID, nil)
+				req.Header.Set("X-Proxy-Token", tokenValue)
+				for key, values := range tc.requestHeader {
+					req.Header[key] = values
+				}
+				HTTPProxyWithTokenAuth(httptest.NewRecorder(), req, cfg, log, nil, storage, responseCache)
+			}
+
+			if upstreamCalls != tc.upstreamCalls {
+				t.Errorf("Expected %d upstream calls, got %d", tc.upstreamCalls, upstreamCalls)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_ResponseCacheVary(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte("lang=" + r.Header.Get("Accept-Language")))
+	}))
+	defer upstream.Close()
+
+	cached := *proxyConfig
+	cached.TargetURL = upstream.URL
+	cached.CacheTTLSeconds = 60
+	storage.Update(proxyConfig.ID, &cached)
+	responseCache := cache.NewResponseCache(1024 * 1024)
+
+	request := func(language string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/data&config_id="+cached.ID, nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		req.Header.Set("Accept-Language", language)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, responseCache)
+		return w
+	}
+
+	request("en")
+	// 不同的Accept-Language不能命中缓存的英文响应
+	if w := request("de"); w.Header().Get("X-Cache") == "HIT" || w.Body.String() != "lang=de" {
+		t.Errorf("Expected a fresh response for a different variant, got %q (X-Cache %q)", w.Body.String(), w.Header().Get("X-Cache"))
+	}
+	if w := request("de"); w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "lang=de" {
+		t.Errorf("Expected cached variant to be served, got %q (X-Cache %q)", w.Body.String(), w.Header().Get("X-Cache"))
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_RelaysNotModified(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	// 使用默认的敏感头配置，确保条件请求头不会被过滤
+	cfg.SensitiveHeaders = config.Load().SensitiveHeaders
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 1024
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("full body"))
+	}))
+	defer upstream.Close()
+
+	req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"&config_id="+proxyConfig.ID, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	req.Header.Set("If-None-Match", `"v1"`)
+	w := httptest.NewRecorder()
+
+	HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 to be relayed, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body for 304, got %q", w.Body.String())
+	}
+
+	// 访问日志应记录为304
+	deadline := time.Now().Add(time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+		if err == nil && len(logs.Logs) > 0 {
+			if logs.Logs[0].StatusCode != http.StatusNotModified {
+				t.Errorf("Expected access log status 304, got %d", logs.Logs[0].StatusCode)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected access log entry to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_LogsConfig(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 1024
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	// 按配置转发的请求和管理员直接转发的请求各一条
+	req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/configured&config_id="+proxyConfig.ID, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	HTTPProxyWithTokenAuth(httptest.NewRecorder(), req, cfg, log, recorder, storage, nil)
+
+	req = httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/direct", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	HTTPProxyWithTokenAuth(httptest.NewRecorder(), req, cfg, log, recorder, storage, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+		if err == nil && len(logs.Logs) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected two access log entries to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 按配置ID筛选只返回按配置转发的请求，并带有配置名称
+	logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10, ConfigID: proxyConfig.ID})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(logs.Logs) != 1 {
+		t.Fatalf("Expected 1 log for config, got %d", len(logs.Logs))
+	}
+	entry := logs.Logs[0]
+	if entry.TargetPath != "/configured" || entry.ConfigID != proxyConfig.ID || entry.ConfigName != proxyConfig.Name {
+		t.Errorf("Expected log tagged with config %s (%s), got %+v", proxyConfig.ID, proxyConfig.Name, entry)
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_CachedNotModified(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue, upstreamCalls := setupResponseCacheTest(t, "")
+	responseCache := cache.NewResponseCache(1024 * 1024)
+
+	// 首次请求填充缓存
+	doCachedProxyRequest(cfg, log, storage, responseCache, proxyConfig, tokenValue)
+
+	etag := `"cached"`
+	entry, ok := responseCache.Get(proxyConfig.ID + "|" + proxyConfig.TargetURL + "/data")
+	if !ok {
+		t.Fatal("Expected response to be cached")
+	}
+	entry.Header.Set("ETag", etag)
+
+	req := httptest.NewRequest("GET", "/proxy?target="+proxyConfig.TargetURL+"/data&config_id="+proxyConfig.ID, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	req.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, responseCache)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 from cache, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body, got %q", w.Body.String())
+	}
+	if *upstreamCalls != 1 {
+		t.Errorf("Expected upstream to be called once, got %d", *upstreamCalls)
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_TargetBlocked(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	cfg.TargetAllowLoopback = false
+
+	// 配置级允许列表
+	proxyConfig.AllowedHosts = []string{"api.example.com"}
+	storage.Update(proxyConfig.ID, proxyConfig)
+
+	targets := []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://127.0.0.1:8080/admin",
+		"https://other.example.org/",
+	}
+
+	for _, target := range targets {
+		req := httptest.NewRequest("GET", "/proxy?target="+target+"&config_id="+proxyConfig.ID, nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		w := httptest.NewRecorder()
+
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for %s, got %d", target, w.Code)
+			continue
+		}
+
+		var errorResponse map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&errorResponse); err != nil {
+			t.Fatalf("Failed to decode error response: %v", err)
+		}
+		if errorResponse["error_code"] != "TARGET_BLOCKED" {
+			t.Errorf("Expected error_code=TARGET_BLOCKED for %s, got %v", target, errorResponse["error_code"])
+		}
+	}
+}
+
+func TestHTTPProxy_TargetBlocked(t *testing.T) {
+	cfg := &config.Config{AdminSecret: "test-secret"}
+
+	req := httptest.NewRequest("GET", "/proxy?target=http://169.254.169.254/latest/meta-data/", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w := httptest.NewRecorder()
+
+	HTTPProxy(w, req, cfg, logger.New(), nil)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for metadata service target, got %d", w.Code)
+	}
+}
+
+func TestResolveOutboundProxy(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProxy:    &config.ProxyConfig{URL: "http://10.0.0.1:3128", Type: "http"},
+		UpstreamNoProxy: []string{"internal.example.com"},
+	}
+
+	// 全局出站代理（位于内网，由管理员配置，无需校验）
+	req := httptest.NewRequest("GET", "/proxy?target=https://api.example.com", nil)
+	proxyConfig, _, err := resolveOutboundProxy(req, cfg, nil)
+	if err != nil || proxyConfig == nil || proxyConfig.URL != "http://10.0.0.1:3128" {
+		t.Errorf("Expected global upstream proxy, got %+v (err=%v)", proxyConfig, err)
+	}
+
+	// 配置级覆盖
+	routeConfig := &proxyconfig.ProxyConfig{UpstreamProxy: "socks5://10.0.0.2:1080"}
+	proxyConfig, _, err = resolveOutboundProxy(req, cfg, routeConfig)
+	if err != nil || proxyConfig == nil || proxyConfig.Type != "socks5" {
+		t.Errorf("Expected per-config upstream proxy, got %+v (err=%v)", proxyConfig, err)
+	} else if len(proxyConfig.NoProxy) != 1 {
+		t.Errorf("Expected per-config proxy to inherit no-proxy list, got %v", proxyConfig.NoProxy)
+	}
+
+	routeConfig.UpstreamProxy = "direct"
+	if proxyConfig, _, err = resolveOutboundProxy(req, cfg, routeConfig); err != nil || proxyConfig != nil {
+		t.Errorf("Expected direct connection, got %+v (err=%v)", proxyConfig, err)
+	}
+
+	// 请求自行指定的内网代理需要被拒绝
+	req = httptest.NewRequest("GET", "/proxy?target=https://api.example.com&proxy=http://192.168.1.1:8080", nil)
+	if _, status, err := resolveOutboundProxy(req, cfg, routeConfig); err == nil || status != http.StatusForbidden {
+		t.Errorf("Expected request-supplied private proxy to be rejected, got status %d (err=%v)", status, err)
 	}
 }
 
@@ -193,7 +598,7 @@ func TestTokenUsageStatistics(t *testing.T) {
 		req.Header.Set("X-Proxy-Token", tokenValue)
 		w := httptest.NewRecorder()
 
-		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage)
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
 
 		// 短暂等待，确保统计更新
 		time.Sleep(10 * time.Millisecond)
@@ -222,7 +627,7 @@ func TestProxyRequestLogging(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	// 执行请求
-	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage)
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
 
 	// 注意：这里主要验证请求不会因为日志记录而失败
 	// 实际的日志验证需要更复杂的设置
@@ -231,30 +636,6 @@ func TestProxyRequestLogging(t *testing.T) {
 	}
 }
 
-func TestCORSHeaders(t *testing.T) {
-	cfg, log, storage, _, _ := setupProxyIntegrationTest()
-
-	// 测试预检请求
-	req := httptest.NewRequest("OPTIONS", "/proxy", nil)
-	req.Header.Set("Origin", "https://example.com")
-	w := httptest.NewRecorder()
-
-	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage)
-
-	// 验证CORS头
-	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
-		t.Error("Expected Access-Control-Allow-Origin: *")
-	}
-
-	if !contains(w.Header().Get("Access-Control-Allow-Headers"), "X-Proxy-Token") {
-		t.Error("Expected X-Proxy-Token in allowed headers")
-	}
-
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected 200 for OPTIONS request, got %d", w.Code)
-	}
-}
-
 func TestConfigIDExtraction(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -296,10 +677,416 @@ func TestConfigIDExtraction(t *testing.T) {
 	}
 }
 
-// 辅助函数
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr ||
-		(len(s) > len(substr) && (s[:len(substr)+1] == substr+"," ||
-			s[len(s)-len(substr)-1:] == ","+substr ||
-			bytes.Contains([]byte(s), []byte(","+substr+",")))))
+func TestHTTPProxyWithTokenAuth_PathRules(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	var receivedPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.RequestURI()
+	}))
+	defer upstream.Close()
+
+	withRules := *proxyConfig
+	withRules.PathRules = []proxyconfig.PathRule{{MatchPrefix: "/api/v1", RewriteTo: upstream.URL + "/v1", StripPrefix: true}}
+	storage.Update(proxyConfig.ID, &withRules)
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{upstream.URL + "/api/v1/users?page=2", "/v1/users?page=2"},
+		{upstream.URL + "/health", "/health"},
+	}
+
+	for _, tt := range tests {
+		receivedPath = ""
+		req := httptest.NewRequest("GET", "/proxy?config_id="+proxyConfig.ID+"&target="+url.QueryEscape(tt.target), nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for %s, got %d: %s", tt.target, w.Code, w.Body.String())
+		}
+		if receivedPath != tt.want {
+			t.Errorf("Expected upstream path %q for %s, got %q", tt.want, tt.target, receivedPath)
+		}
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_BasePath(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	var receivedPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.RequestURI()
+	}))
+	defer upstream.Close()
+
+	withBase := *proxyConfig
+	withBase.BasePath = "/v1"
+	withBase.PathRules = []proxyconfig.PathRule{{MatchPrefix: "/legacy", RewriteTo: "/old"}}
+	storage.Update(proxyConfig.ID, &withBase)
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{upstream.URL, "/v1"},
+		{upstream.URL + "/", "/v1"},
+		{upstream.URL + "/users/42?expand=true", "/v1/users/42?expand=true"},
+		// 路径规则匹配时不再拼接基础路径
+		{upstream.URL + "/legacy/items", "/old/legacy/items"},
+	}
+
+	for _, tt := range tests {
+		receivedPath = ""
+		req := httptest.NewRequest("GET", "/proxy?config_id="+proxyConfig.ID+"&target="+url.QueryEscape(tt.target), nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for %s, got %d: %s", tt.target, w.Code, w.Body.String())
+		}
+		if receivedPath != tt.want {
+			t.Errorf("Expected upstream path %q for %s, got %q", tt.want, tt.target, receivedPath)
+		}
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_AllowedMethods(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	upstreamHits := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHits++
+	}))
+	defer upstream.Close()
+
+	readOnly := *proxyConfig
+	readOnly.AllowedMethods = []string{"GET", "HEAD"}
+	storage.Update(proxyConfig.ID, &readOnly)
+
+	proxyURL := "/proxy?config_id=" + proxyConfig.ID + "&target=" + url.QueryEscape(upstream.URL)
+
+	// 允许的方法正常转发
+	req := httptest.NewRequest("GET", proxyURL, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	w := httptest.NewRecorder()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+	if w.Code != http.StatusOK || upstreamHits != 1 {
+		t.Fatalf("Expected GET to be forwarded, got %d (upstream hits %d)", w.Code, upstreamHits)
+	}
+
+	// 不允许的方法返回405和Allow头部，不转发到上游
+	req = httptest.NewRequest("POST", proxyURL, strings.NewReader("{}"))
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	w = httptest.NewRecorder()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405 for POST, got %d: %s", w.Code, w.Body.String())
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow header %q, got %q", "GET, HEAD, OPTIONS", allow)
+	}
+	if upstreamHits != 1 {
+		t.Errorf("Expected disallowed request not to reach upstream, got %d hits", upstreamHits)
+	}
+
+	// OPTIONS预检不受限制
+	req = httptest.NewRequest("OPTIONS", proxyURL, nil)
+	w = httptest.NewRecorder()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected preflight to pass, got %d", w.Code)
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_QueryFiltering(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	var received url.Values
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.URL.Query()
+	}))
+	defer upstream.Close()
+
+	filtered := *proxyConfig
+	filtered.DeniedQueryParams = []string{"debug"}
+	storage.Update(proxyConfig.ID, &filtered)
+
+	doRequest := func(target string) {
+		t.Helper()
+		received = nil
+		req := httptest.NewRequest("GET", "/proxy?config_id="+proxyConfig.ID+"&token="+tokenValue+"&target="+url.QueryEscape(target), nil)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// 网关参数和禁止的参数都不会到达上游
+	doRequest(upstream.URL + "/search?q=go&token=" + tokenValue + "&secret=s&config_id=x&debug=1")
+	if got := received.Get("q"); got != "go" {
+		t.Errorf("Expected q to be forwarded, got %q", got)
+	}
+	for _, name := range []string{"token", "secret", "config_id", "debug"} {
+		if _, ok := received[name]; ok {
+			t.Errorf("Expected %s to be stripped, got %v", name, received)
+		}
+	}
+
+	// 配置了允许列表时只转发列出的参数
+	filtered.AllowedQueryParams = []string{"q"}
+	storage.Update(proxyConfig.ID, &filtered)
+	doRequest(upstream.URL + "/search?q=go&page=2")
+	if len(received) != 1 || received.Get("q") != "go" {
+		t.Errorf("Expected only q to be forwarded, got %v", received)
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_RedirectPolicy(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/internal":
+			http.Redirect(w, r, "http://10.0.0.1/admin", http.StatusFound)
+		default:
+			w.Write([]byte("final"))
+		}
+	}))
+	defer upstream.Close()
+
+	doRequest := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?config_id="+proxyConfig.ID+"&target="+url.QueryEscape(upstream.URL+path), nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+
+	// 默认不跟随，302原样返回给客户端
+	w := doRequest("/moved")
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/final" {
+		t.Errorf("Expected 302 to be relayed, got %d (Location %q)", w.Code, w.Header().Get("Location"))
+	}
+
+	following := *proxyConfig
+	following.RedirectPolicy = proxyconfig.RedirectFollow
+	storage.Update(proxyConfig.ID, &following)
+
+	w = doRequest("/moved")
+	if w.Code != http.StatusOK || w.Body.String() != "final" {
+		t.Errorf("Expected redirect to be followed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// 跟随时重定向到私有地址被访问策略拒绝
+	w = doRequest("/internal")
+	assertProxyError(t, w, http.StatusForbidden, errCodeTargetBlocked)
+}
+
+func TestHTTPProxyWithTokenAuth_BasicAuth(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	var forwardedAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedAuth = r.Header.Get("Authorization")
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	protected := *proxyConfig
+	protected.BasicAuthUser = "legacy"
+	protected.BasicAuthPasswordHash = proxyconfig.HashToken("s3cret")
+	storage.Update(proxyConfig.ID, &protected)
+
+	doRequest := func(setAuth func(r *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?config_id="+proxyConfig.ID+"&target="+url.QueryEscape(upstream.URL), nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		if setAuth != nil {
+			setAuth(req)
+		}
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+
+	// 未提供凭据：返回认证质询
+	w := doRequest(nil)
+	if !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Basic ") {
+		t.Errorf("Expected Basic challenge, got %q", w.Header().Get("WWW-Authenticate"))
+	}
+	assertProxyError(t, w, http.StatusUnauthorized, errCodeUnauthorized)
+
+	// 密码错误
+	w = doRequest(func(r *http.Request) { r.SetBasicAuth("legacy", "wrong") })
+	assertProxyError(t, w, http.StatusUnauthorized, errCodeUnauthorized)
+
+	// 用户名错误
+	w = doRequest(func(r *http.Request) { r.SetBasicAuth("other", "s3cret") })
+	assertProxyError(t, w, http.StatusUnauthorized, errCodeUnauthorized)
+
+	// 凭据正确：转发请求，凭据不会传给上游
+	w = doRequest(func(r *http.Request) { r.SetBasicAuth("legacy", "s3cret") })
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("Expected 200 ok, got %d: %s", w.Code, w.Body.String())
+	}
+	if forwardedAuth != "" {
+		t.Errorf("Expected Authorization header to be stripped, got %q", forwardedAuth)
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_RequestSigning(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	signing := &proxyconfig.RequestSigning{Secret: "integration-signing-secret"}
+	signed := *proxyConfig
+	signed.RequestSigning = signing
+	storage.Update(proxyConfig.ID, &signed)
+
+	doRequest := func(signedBody, sentBody, nonce string, timestamp time.Time) *httptest.ResponseRecorder {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req := httptest.NewRequest("POST", "/proxy?config_id="+proxyConfig.ID+"&target="+url.QueryEscape(upstream.URL+"/orders"), strings.NewReader(sentBody))
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		req.Header.Set("X-Signature", signing.Sign("POST", upstream.URL+"/orders", ts, nonce, []byte(signedBody)))
+		req.Header.Set("X-Signature-Timestamp", ts)
+		req.Header.Set("X-Signature-Nonce", nonce)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+
+	// 签名正确：请求体原样转发
+	w := doRequest(`{"amount":100}`, `{"amount":100}`, "nonce-1", time.Now())
+	if w.Code != http.StatusOK || received != `{"amount":100}` {
+		t.Fatalf("Expected signed request to be forwarded, got %d (upstream body %q)", w.Code, received)
+	}
+
+	// 同一nonce重放
+	w = doRequest(`{"amount":100}`, `{"amount":100}`, "nonce-1", time.Now())
+	assertProxyError(t, w, http.StatusUnauthorized, errCodeInvalidSignature)
+
+	// 请求体被篡改
+	w = doRequest(`{"amount":100}`, `{"amount":999}`, "nonce-2", time.Now())
+	assertProxyError(t, w, http.StatusUnauthorized, errCodeInvalidSignature)
+
+	// 时间戳过期
+	w = doRequest(`{"amount":100}`, `{"amount":100}`, "nonce-3", time.Now().Add(-time.Hour))
+	assertProxyError(t, w, http.StatusUnauthorized, errCodeInvalidSignature)
+
+	// 请求体超过签名校验的大小上限：不缓存完整请求体，直接拒绝
+	oversized := strings.Repeat("a", proxyconfig.MaxSignedBodySize+1)
+	received = ""
+	w = doRequest(oversized, oversized, "nonce-4", time.Now())
+	assertProxyError(t, w, http.StatusRequestEntityTooLarge, errCodeBodyTooLarge)
+	if received != "" {
+		t.Errorf("Expected oversized signed request not to be forwarded")
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_TokenQuota(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	token, trialValue, err := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Trial", MaxUsageCount: 2}, "admin")
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	storage.AddToken(proxyConfig.ID, token)
+
+	doRequest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?config_id="+proxyConfig.ID+"&target="+url.QueryEscape(upstream.URL), nil)
+		req.Header.Set("X-Proxy-Token", trialValue)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+
+	// 配额内的请求正常转发
+	for i := 0; i < 2; i++ {
+		if w := doRequest(); w.Code != http.StatusOK {
+			t.Fatalf("Expected request %d within quota to succeed, got %d: %s", i+1, w.Code, w.Body.String())
+		}
+	}
+
+	// 达到上限后被拒绝
+	assertProxyError(t, doRequest(), http.StatusUnauthorized, "TOKEN_QUOTA_EXCEEDED")
+}
+
+func TestHTTPProxyWithTokenAuth_RejectedRequestsKeepQuota(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	token, trialValue, err := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Trial", MaxUsageCount: 1}, "admin")
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	storage.AddToken(proxyConfig.ID, token)
+
+	doRequest := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/proxy?config_id="+proxyConfig.ID+"&target="+url.QueryEscape(upstream.URL), nil)
+		req.Header.Set("X-Proxy-Token", trialValue)
+		w := httptest.NewRecorder()
+		HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+		return w
+	}
+	usageCount := func() int64 {
+		stored, _ := storage.GetTokenByID(proxyConfig.ID, token.ID)
+		return stored.UsageCount
+	}
+
+	// 方法不允许（405）不计入使用次数
+	restricted := *proxyConfig
+	restricted.TargetURL = upstream.URL
+	restricted.AllowedMethods = []string{"GET"}
+	storage.Update(proxyConfig.ID, &restricted)
+	if w := doRequest("POST"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405, got %d", w.Code)
+	}
+	if count := usageCount(); count != 0 {
+		t.Errorf("Expected 405 not to consume usage, got %d", count)
+	}
+
+	// 维护模式（503）不计入使用次数
+	maintenance := restricted
+	maintenance.MaintenanceMode = true
+	storage.Update(proxyConfig.ID, &maintenance)
+	if w := doRequest("GET"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", w.Code)
+	}
+	if count := usageCount(); count != 0 {
+		t.Errorf("Expected maintenance response not to consume usage, got %d", count)
+	}
+
+	// 配额仍可用于真正转发的请求
+	maintenance.MaintenanceMode = false
+	storage.Update(proxyConfig.ID, &maintenance)
+	if w := doRequest("GET"); w.Code != http.StatusOK {
+		t.Fatalf("Expected request within quota to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if count := usageCount(); count != 1 {
+		t.Errorf("Expected forwarded request to consume usage, got %d", count)
+	}
+	assertProxyError(t, doRequest("GET"), http.StatusUnauthorized, "TOKEN_QUOTA_EXCEEDED")
 }
@@ -2,22 +2,25 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"privacygateway/internal/audit"
 	"privacygateway/internal/config"
 	"privacygateway/internal/logger"
 	"privacygateway/internal/proxyconfig"
 )
 
-// HandleProxyConfigAPI 处理代理配置API请求
-func HandleProxyConfigAPI(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, storage proxyconfig.Storage) {
+// HandleProxyConfigAPI 处理代理配置API请求，auditRecorder为nil时不记录审计日志
+func HandleProxyConfigAPI(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, storage proxyconfig.Storage, auditRecorder *audit.Recorder) {
 	// 认证检查
-	if !isAuthorizedForConfig(r, cfg.AdminSecret) {
+	if !isAuthorizedForConfig(r, cfg.AdminSecrets()) {
 		handleConfigAuthFailure(w, r, cfg.AdminSecret)
 		return
 	}
@@ -36,11 +39,31 @@ func HandleProxyConfigAPI(w http.ResponseWriter, r *http.Request, cfg *config.Co
 		return
 	}
 	if path == "/config/proxy/import" {
-		handleImportConfigs(w, r, storage, log)
+		handleImportConfigs(w, r, storage, log, auditRecorder)
+		return
+	}
+	if path == "/config/proxy/import/diff" {
+		handleImportDiff(w, r, storage)
 		return
 	}
 	if path == "/config/proxy/batch" {
-		handleBatchOperation(w, r, storage, log)
+		handleBatchOperation(w, r, storage, log, auditRecorder)
+		return
+	}
+	if path == "/config/proxy/search" {
+		handleSearchConfigs(w, r, storage)
+		return
+	}
+	if path == "/config/proxy/deleted" {
+		handleListDeletedConfigs(w, r, storage, log)
+		return
+	}
+	if strings.HasSuffix(path, "/export") {
+		handleExportConfig(w, r, storage, log)
+		return
+	}
+	if strings.HasSuffix(path, "/restore") {
+		handleRestoreConfig(w, r, storage, log, auditRecorder)
 		return
 	}
 
@@ -48,33 +71,93 @@ func HandleProxyConfigAPI(w http.ResponseWriter, r *http.Request, cfg *config.Co
 	case http.MethodGet:
 		handleGetConfigs(w, r, storage, log)
 	case http.MethodPost:
-		handleCreateConfig(w, r, storage, log)
+		handleCreateConfig(w, r, storage, log, auditRecorder)
 	case http.MethodPut:
-		handleUpdateConfig(w, r, storage, log)
+		handleUpdateConfig(w, r, storage, log, auditRecorder)
+	case http.MethodPatch:
+		handlePatchConfig(w, r, storage, log, auditRecorder)
 	case http.MethodDelete:
-		handleDeleteConfig(w, r, storage, log)
+		handleDeleteConfig(w, r, storage, log, auditRecorder)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleConfigStatsAPI 处理单个配置的统计信息请求（GET查询，DELETE清零）
+// 路径格式: /config/proxy/{configID}/stats
+func HandleConfigStatsAPI(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, storage proxyconfig.Storage, auditRecorder *audit.Recorder) {
+	// 认证检查
+	if !isAuthorizedForConfig(r, cfg.AdminSecrets()) {
+		handleConfigAuthFailure(w, r, cfg.AdminSecret)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "config" || parts[1] != "proxy" || parts[2] == "" || parts[3] != "stats" {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Config ID is required")
+		return
+	}
+	configID := parts[2]
+
+	switch r.Method {
+	case http.MethodGet:
+		handleGetConfigStats(w, configID, storage, log)
+	case http.MethodDelete:
+		handleResetConfigStats(w, r, configID, storage, log, auditRecorder)
+	default:
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleGetConfigStats 获取配置统计信息
+func handleGetConfigStats(w http.ResponseWriter, configID string, storage proxyconfig.Storage, log *logger.Logger) {
+	stats, err := storage.GetConfigStats(configID)
+	if err != nil {
+		if err == proxyconfig.ErrConfigNotFound {
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Config not found")
+		} else {
+			log.Error("failed to get config stats", "id", configID, "error", err)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
+		}
+		return
 	}
+	stats.CurrentConcurrency = configConcurrency.current(configID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleResetConfigStats 将配置统计信息清零
+func handleResetConfigStats(w http.ResponseWriter, r *http.Request, configID string, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder) {
+	if err := storage.ResetConfigStats(configID); err != nil {
+		if err == proxyconfig.ErrConfigNotFound {
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Config not found")
+		} else {
+			log.Error("failed to reset config stats", "id", configID, "error", err)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
+		}
+		return
+	}
+
+	log.Info("config stats reset", "id", configID)
+	recordAudit(auditRecorder, log, r, audit.ActionConfigStatsReset, configID, "", nil)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // isAuthorizedForConfig 检查配置管理权限
-func isAuthorizedForConfig(r *http.Request, adminSecret string) bool {
-	if adminSecret == "" {
+func isAuthorizedForConfig(r *http.Request, adminSecrets []string) bool {
+	if len(adminSecrets) == 0 {
 		return false
 	}
 
 	// 检查请求头
-	if secret := r.Header.Get("X-Log-Secret"); secret == adminSecret {
+	if matchAdminSecret(r.Header.Get("X-Log-Secret"), adminSecrets) {
 		return true
 	}
 
 	// 检查查询参数
-	if secret := r.URL.Query().Get("secret"); secret == adminSecret {
-		return true
-	}
-
-	return false
+	return matchAdminSecret(r.URL.Query().Get("secret"), adminSecrets)
 }
 
 // handleGetConfigs 获取配置列表
@@ -104,11 +187,14 @@ func handleGetConfigs(w http.ResponseWriter, r *http.Request, storage proxyconfi
 		}
 	}
 
+	// 按标签筛选（?tag=env:prod，可重复，需全部满足）
+	filter.Tags = r.URL.Query()["tag"]
+
 	// 获取配置列表
 	response, err := storage.List(filter)
 	if err != nil {
 		log.Error("failed to get config list", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
 		return
 	}
 
@@ -118,77 +204,179 @@ func handleGetConfigs(w http.ResponseWriter, r *http.Request, storage proxyconfi
 }
 
 // handleCreateConfig 创建配置
-func handleCreateConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger) {
+func handleCreateConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder) {
 	var config proxyconfig.ProxyConfig
 	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid JSON")
 		return
 	}
 
 	// 验证配置
 	if err := proxyconfig.ValidateConfig(&config); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeConfigValidationError(w, err)
 		return
 	}
 
 	// 添加配置
 	if err := storage.Add(&config); err != nil {
 		log.Error("failed to add config", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
 		return
 	}
 
 	log.Info("config created", "id", config.ID, "name", config.Name)
+	proxyconfig.WarnInsecureTLS(log, &config)
+	recordAudit(auditRecorder, log, r, audit.ActionConfigCreate, config.ID, "", map[string]interface{}{"name": config.Name})
 
 	// 返回创建的配置
+	w.Header().Set("ETag", configETag(config.Version))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(config)
 }
 
 // handleUpdateConfig 更新配置
-func handleUpdateConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger) {
+func handleUpdateConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder) {
 	configID := r.URL.Query().Get("id")
 	if configID == "" {
-		http.Error(w, "Config ID is required", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Config ID is required")
 		return
 	}
 
 	var config proxyconfig.ProxyConfig
 	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid JSON")
+		return
+	}
+
+	// 乐观并发控制：If-Match头优先于请求体中的version
+	if !applyIfMatch(w, r, &config) {
+		return
+	}
+
+	saveUpdatedConfig(w, r, storage, log, auditRecorder, configID, &config)
+}
+
+// handlePatchConfig 部分更新配置，只修改请求体中出现的字段
+func handlePatchConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder) {
+	configID := r.URL.Query().Get("id")
+	if configID == "" {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Config ID is required")
+		return
+	}
+
+	// 解码为原始字段，以区分未提供的字段和零值
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid JSON")
 		return
 	}
 
+	existing, err := storage.GetByID(configID)
+	if err != nil {
+		if err == proxyconfig.ErrConfigNotFound {
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Config not found")
+		} else {
+			log.Error("failed to get config", "id", configID, "error", err)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
+		}
+		return
+	}
+
+	config, err := proxyconfig.ApplyPatch(existing, patch)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// 与PUT一样必须携带版本号，不能默认使用读取到的当前版本
+	if _, ok := patch["version"]; !ok {
+		config.Version = 0
+	}
+	if !applyIfMatch(w, r, config) {
+		return
+	}
+
+	saveUpdatedConfig(w, r, storage, log, auditRecorder, configID, config)
+}
+
+// applyIfMatch 用If-Match头中的版本号覆盖配置版本，头无效时写入400并返回false
+func applyIfMatch(w http.ResponseWriter, r *http.Request, config *proxyconfig.ProxyConfig) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return true
+	}
+	version, ok := parseConfigETag(ifMatch)
+	if !ok {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid If-Match header")
+		return false
+	}
+	config.Version = version
+	return true
+}
+
+// saveUpdatedConfig 验证并保存更新后的配置，PUT和PATCH共用
+func saveUpdatedConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder, configID string, config *proxyconfig.ProxyConfig) {
 	// 验证配置
-	if err := proxyconfig.ValidateConfig(&config); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := proxyconfig.ValidateConfig(config); err != nil {
+		writeConfigValidationError(w, err)
 		return
 	}
 
 	// 更新配置
-	if err := storage.Update(configID, &config); err != nil {
+	if err := storage.Update(configID, config); err != nil {
+		if err == proxyconfig.ErrVersionRequired {
+			writeProxyError(w, http.StatusPreconditionRequired, errCodeVersionRequired, "Config version is required: send If-Match or version")
+			return
+		}
+		if err == proxyconfig.ErrVersionConflict {
+			log.Warn("config update rejected due to version conflict", "id", configID, "version", config.Version)
+			fields := map[string]interface{}{"config_id": configID}
+			if current, err := storage.GetByID(configID); err == nil {
+				fields["current_version"] = current.Version
+			}
+			writeProxyErrorFields(w, http.StatusConflict, errCodeVersionConflict, "Config has been modified, reload and retry", fields)
+			return
+		}
 		log.Error("failed to update config", "id", configID, "error", err)
 		if err == proxyconfig.ErrConfigNotFound {
-			http.Error(w, "Config not found", http.StatusNotFound)
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Config not found")
 		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
 		}
 		return
 	}
 
 	log.Info("config updated", "id", configID, "name", config.Name)
+	proxyconfig.WarnInsecureTLS(log, config)
+	recordAudit(auditRecorder, log, r, audit.ActionConfigUpdate, configID, "", map[string]interface{}{"name": config.Name})
 
 	// 返回更新的配置
+	w.Header().Set("ETag", configETag(config.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(config)
 }
 
+// configETag 将配置版本号格式化为ETag
+func configETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// parseConfigETag 解析If-Match头中的版本号（接受"3"、W/"3"或3）
+func parseConfigETag(value string) (int64, bool) {
+	value = strings.Trim(strings.TrimPrefix(strings.TrimSpace(value), "W/"), `"`)
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
 // handleDeleteConfig 删除配置
-func handleDeleteConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger) {
+func handleDeleteConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder) {
 	configID := r.URL.Query().Get("id")
 	if configID == "" {
-		http.Error(w, "Config ID is required", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Config ID is required")
 		return
 	}
 
@@ -196,113 +384,397 @@ func handleDeleteConfig(w http.ResponseWriter, r *http.Request, storage proxycon
 	if err := storage.Delete(configID); err != nil {
 		log.Error("failed to delete config", "id", configID, "error", err)
 		if err == proxyconfig.ErrConfigNotFound {
-			http.Error(w, "Config not found", http.StatusNotFound)
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Config not found")
 		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
 		}
 		return
 	}
 
 	log.Info("config deleted", "id", configID)
+	recordAudit(auditRecorder, log, r, audit.ActionConfigDelete, configID, "", nil)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// handleListDeletedConfigs 获取回收站中的配置
+func handleListDeletedConfigs(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger) {
+	if r.Method != http.MethodGet {
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	configs, err := storage.ListDeleted()
+	if err != nil {
+		log.Error("failed to list deleted configs", "error", err)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"configs": configs,
+		"total":   len(configs),
+	})
+}
+
+// handleSearchConfigs 按关键字跨配置和令牌搜索，返回命中的配置及其命中的令牌
+func handleSearchConfigs(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage) {
+	if r.Method != http.MethodGet {
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Search query is required")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(storage.Search(query))
+}
+
+// handleRestoreConfig 从回收站恢复配置
+// 路径格式: /config/proxy/{configID}/restore
+func handleRestoreConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder) {
+	if r.Method != http.MethodPost {
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "config" || parts[1] != "proxy" || parts[2] == "" {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Config ID is required")
+		return
+	}
+	configID := parts[2]
+
+	if err := storage.Restore(configID); err != nil {
+		if err == proxyconfig.ErrConfigNotFound {
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Deleted config not found")
+		} else {
+			log.Error("failed to restore config", "id", configID, "error", err)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
+		}
+		return
+	}
+
+	config, err := storage.GetByID(configID)
+	if err != nil {
+		log.Error("failed to load restored config", "id", configID, "error", err)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Internal server error")
+		return
+	}
+
+	log.Info("config restored", "id", configID, "name", config.Name)
+	recordAudit(auditRecorder, log, r, audit.ActionConfigRestore, configID, "", map[string]interface{}{"name": config.Name})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
 // handleExportConfigs 导出配置
 func handleExportConfigs(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 格式协商：?format= 优先，其次Accept头
+	format, err := negotiateExportFormat(r)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	tokenHashes, err := proxyconfig.NormalizeTokenHashesMode(r.URL.Query().Get("token_hashes"))
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
 	exportData, err := storage.ExportAll()
 	if err != nil {
 		log.Error("failed to export configs", "error", err)
-		http.Error(w, "Export failed", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Export failed")
+		return
+	}
+	if tokenHashes == proxyconfig.TokenHashesExclude {
+		exportData.StripTokenHashes()
+	} else {
+		exportData.StripTokenValues()
+	}
+
+	data, err := proxyconfig.MarshalFormat(exportData, format)
+	if err != nil {
+		log.Error("failed to encode export data", "format", format, "error", err)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Export failed")
 		return
 	}
 
 	// 设置下载文件头
-	filename := fmt.Sprintf("proxy-configs-%s.json", time.Now().Format("20060102-150405"))
-	w.Header().Set("Content-Type", "application/json")
+	filename := fmt.Sprintf("proxy-configs-%s.%s", time.Now().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", proxyconfig.ContentTypeForFormat(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	w.Write(data)
+	log.Info("configs exported", "count", exportData.TotalCount, "token_hashes", tokenHashes, "filename", filename, "format", format)
+}
+
+// handleExportConfig 导出单个配置，?include_tokens=false 时不包含令牌
+// 路径格式: /config/proxy/{configID}/export
+func handleExportConfig(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger) {
+	if r.Method != http.MethodGet {
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "config" || parts[1] != "proxy" || parts[2] == "" {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Config ID is required")
+		return
+	}
+	configID := parts[2]
+
+	includeTokens := true
+	if value := r.URL.Query().Get("include_tokens"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "include_tokens must be true or false")
+			return
+		}
+		includeTokens = parsed
+	}
+
+	tokenHashes, err := proxyconfig.NormalizeTokenHashesMode(r.URL.Query().Get("token_hashes"))
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	format, err := negotiateExportFormat(r)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	config, err := storage.GetByID(configID)
+	if err != nil {
+		if err == proxyconfig.ErrConfigNotFound {
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Config not found")
+		} else {
+			log.Error("failed to get config", "id", configID, "error", err)
+			writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Export failed")
+		}
+		return
+	}
+
+	exportData := proxyconfig.ExportSingle(config, includeTokens)
+	if tokenHashes == proxyconfig.TokenHashesExclude {
+		exportData.StripTokenHashes()
+	} else {
+		exportData.StripTokenValues()
+	}
+
+	data, err := proxyconfig.MarshalFormat(exportData, format)
+	if err != nil {
+		log.Error("failed to encode export data", "format", format, "error", err)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Export failed")
+		return
+	}
+
+	filename := fmt.Sprintf("proxy-config-%s-%s.%s", configID, time.Now().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", proxyconfig.ContentTypeForFormat(format))
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
-	json.NewEncoder(w).Encode(exportData)
-	log.Info("configs exported", "count", exportData.TotalCount, "filename", filename)
+	w.Write(data)
+	log.Info("config exported", "id", configID, "include_tokens", includeTokens, "token_hashes", tokenHashes, "filename", filename, "format", format)
+}
+
+// negotiateExportFormat 根据查询参数或Accept头确定导出格式
+func negotiateExportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return proxyconfig.NormalizeFormat(format)
+	}
+	return proxyconfig.FormatFromContentType(r.Header.Get("Accept")), nil
 }
 
 // handleImportConfigs 导入配置
-func handleImportConfigs(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger) {
+func handleImportConfigs(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var importData struct {
-		Configs []proxyconfig.ProxyConfig `json:"configs"`
-		Mode    string                    `json:"mode"` // skip, replace, error
+		Configs   []proxyconfig.ProxyConfig `json:"configs"`
+		Mode      string                    `json:"mode"`       // skip, replace, error
+		TokenMode string                    `json:"token_mode"` // keep, reissue
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&importData); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	// 根据Content-Type识别导入格式（JSON或YAML）
+	format := proxyconfig.FormatFromContentType(r.Header.Get("Content-Type"))
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Failed to read request body")
+		return
+	}
+
+	if err := proxyconfig.UnmarshalFormat(body, format, &importData); err != nil {
+		if format == proxyconfig.FormatYAML {
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid YAML")
+		} else {
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid JSON")
+		}
 		return
 	}
 
 	// 默认模式为error（遇到冲突时报错）
 	if importData.Mode == "" {
-		importData.Mode = "error"
+		importData.Mode = proxyconfig.ImportModeError
+	}
+	if importData.Mode != proxyconfig.ImportModeSkip && importData.Mode != proxyconfig.ImportModeReplace && importData.Mode != proxyconfig.ImportModeError {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid mode. Must be: skip, replace, or error")
+		return
 	}
 
-	result, err := storage.ImportConfigs(importData.Configs, importData.Mode)
+	if importData.TokenMode == "" {
+		importData.TokenMode = proxyconfig.TokenImportKeep
+	}
+	if importData.TokenMode != proxyconfig.TokenImportKeep && importData.TokenMode != proxyconfig.TokenImportReissue {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid token_mode. Must be: keep or reissue")
+		return
+	}
+
+	result, err := storage.ImportConfigsWithOptions(importData.Configs, proxyconfig.ImportOptions{Mode: importData.Mode, TokenMode: importData.TokenMode})
 	if err != nil {
+		var conflictErr *proxyconfig.ImportConflictError
+		if errors.As(err, &conflictErr) {
+			log.Warn("config import aborted due to conflicts", "conflicts", conflictErr.Names)
+			writeProxyErrorFields(w, http.StatusConflict, errCodeConfigConflict, err.Error(), map[string]interface{}{
+				"conflicts": conflictErr.Names,
+			})
+			return
+		}
 		log.Error("failed to import configs", "error", err)
-		http.Error(w, "Import failed", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Import failed")
 		return
 	}
 
-	log.Info("configs imported", "imported", result.ImportedCount, "skipped", result.SkippedCount, "errors", result.ErrorCount)
+	// 重新签发的令牌值只在响应中返回，日志和审计只记录数量
+	reissued := 0
+	for _, tokens := range result.ReissuedTokens {
+		reissued += len(tokens)
+	}
+	log.Info("configs imported", "imported", result.ImportedCount, "skipped", result.SkippedCount, "errors", result.ErrorCount,
+		"token_mode", importData.TokenMode, "reissued_tokens", reissued, "dropped_tokens", result.DroppedTokens, "format", format)
+	for i := range importData.Configs {
+		proxyconfig.WarnInsecureTLS(log, &importData.Configs[i])
+	}
+	recordAudit(auditRecorder, log, r, audit.ActionConfigImport, "", "", map[string]interface{}{
+		"mode":            importData.Mode,
+		"token_mode":      importData.TokenMode,
+		"imported":        result.ImportedCount,
+		"skipped":         result.SkippedCount,
+		"errors":          result.ErrorCount,
+		"reissued_tokens": reissued,
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
+// handleImportDiff 预览导入文件与当前配置的差异（新增、更新及prune模式下的删除），不修改存储
+func handleImportDiff(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage) {
+	if r.Method != http.MethodPost {
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	prune := false
+	if value := r.URL.Query().Get("prune"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "prune must be true or false")
+			return
+		}
+		prune = parsed
+	}
+
+	// 与导入相同，根据Content-Type识别JSON或YAML
+	format := proxyconfig.FormatFromContentType(r.Header.Get("Content-Type"))
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Failed to read request body")
+		return
+	}
+
+	var importData proxyconfig.ExportData
+	if err := proxyconfig.UnmarshalFormat(body, format, &importData); err != nil {
+		if format == proxyconfig.FormatYAML {
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid YAML")
+		} else {
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid JSON")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(storage.DiffImport(importData.Configs, prune))
+}
+
 // handleBatchOperation 批量操作
-func handleBatchOperation(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger) {
+func handleBatchOperation(w http.ResponseWriter, r *http.Request, storage proxyconfig.Storage, log *logger.Logger, auditRecorder *audit.Recorder) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeProxyError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req proxyconfig.BatchOperationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidJSON, "Invalid JSON")
 		return
 	}
 
 	// 验证操作类型
 	if req.Operation != "enable" && req.Operation != "disable" && req.Operation != "delete" {
-		http.Error(w, "Invalid operation. Must be: enable, disable, or delete", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid operation. Must be: enable, disable, or delete")
 		return
 	}
 
 	if len(req.ConfigIDs) == 0 {
-		http.Error(w, "No config IDs provided", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidRequest, "No config IDs provided")
 		return
 	}
 
 	result, err := storage.BatchOperation(req.Operation, req.ConfigIDs)
 	if err != nil {
 		log.Error("batch operation failed", "operation", req.Operation, "error", err)
-		http.Error(w, "Batch operation failed", http.StatusInternalServerError)
+		writeProxyError(w, http.StatusInternalServerError, errCodeInternal, "Batch operation failed")
 		return
 	}
 
 	log.Info("batch operation completed", "operation", req.Operation, "total", result.TotalCount, "success", len(result.Success), "failed", result.FailedCount)
+	recordAudit(auditRecorder, log, r, audit.ActionConfigBatch+"_"+req.Operation, "", "", map[string]interface{}{
+		"config_ids": result.Success,
+		"failed":     result.FailedCount,
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
+// writeConfigValidationError 返回配置校验错误，errors字段列出所有字段错误
+func writeConfigValidationError(w http.ResponseWriter, err error) {
+	var fields map[string]interface{}
+	var validationErr *proxyconfig.ValidationError
+	if errors.As(err, &validationErr) {
+		fields = map[string]interface{}{"errors": validationErr.Errors}
+	}
+	writeProxyErrorFields(w, http.StatusBadRequest, errCodeValidation, err.Error(), fields)
+}
+
 // handleConfigAuthFailure 处理配置API认证失败
 func handleConfigAuthFailure(w http.ResponseWriter, r *http.Request, adminSecret string) {
 	// 检查是否是浏览器请求（通过Accept头判断）
@@ -333,19 +805,9 @@ func handleConfigAuthFailure(w http.ResponseWriter, r *http.Request, adminSecret
 	}
 
 	// 对于API请求或没有配置管理密钥的情况，返回JSON错误
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-
-	errorResponse := map[string]interface{}{
-		"error":   "Unauthorized",
-		"message": "Authentication required",
-		"status":  http.StatusUnauthorized,
-		"success": false,
-	}
-
+	message := "Authentication required"
 	if adminSecret == "" {
-		errorResponse["message"] = "Admin secret not configured"
+		message = "Admin secret not configured"
 	}
-
-	json.NewEncoder(w).Encode(errorResponse)
+	writeProxyError(w, http.StatusUnauthorized, errCodeUnauthorized, message)
 }
@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"privacygateway/internal/proxyconfig"
+)
+
+func TestHandleProxyConfigAPI_RecycleBin(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		HandleProxyConfigAPI(w, req, cfg, log, storage, nil)
+		return w
+	}
+
+	if w := do("DELETE", "/config/proxy?id="+proxyConfig.ID); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+
+	// 删除后从列表中消失，出现在回收站中
+	var list proxyconfig.ConfigResponse
+	json.NewDecoder(do("GET", "/config/proxy").Body).Decode(&list)
+	if list.Total != 0 {
+		t.Errorf("Expected deleted config to be hidden from list, got %d", list.Total)
+	}
+
+	w := do("GET", "/config/proxy/deleted")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var deleted struct {
+		Configs []proxyconfig.ProxyConfig `json:"configs"`
+		Total   int                       `json:"total"`
+	}
+	json.NewDecoder(w.Body).Decode(&deleted)
+	if deleted.Total != 1 || deleted.Configs[0].ID != proxyConfig.ID || deleted.Configs[0].DeletedAt == nil {
+		t.Fatalf("Expected config in recycle bin, got %+v", deleted)
+	}
+
+	// 恢复后令牌仍然可用
+	if w := do("POST", "/config/proxy/"+proxyConfig.ID+"/restore"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	restored, err := storage.GetByID(proxyConfig.ID)
+	if err != nil || len(restored.AccessTokens) != 1 {
+		t.Fatalf("Expected restored config with its token, got %+v (err %v)", restored, err)
+	}
+
+	assertProxyError(t, do("POST", "/config/proxy/"+proxyConfig.ID+"/restore"), http.StatusNotFound, errCodeConfigNotFound)
+	assertProxyError(t, do("GET", "/config/proxy/"+proxyConfig.ID+"/restore"), http.StatusMethodNotAllowed, errCodeMethodNotAllowed)
+}
+
+func TestHandleProxyConfigAPI_UpdateVersioning(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+
+	update := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/config/proxy?id="+proxyConfig.ID, strings.NewReader(body))
+		req.Header.Set("X-Log-Secret", "test-secret")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		HandleProxyConfigAPI(w, req, cfg, log, storage, nil)
+		return w
+	}
+	body := func(target string) string {
+		return `{"name":"Test Config","target_url":"` + target + `","protocol":"https","enabled":true}`
+	}
+
+	// 携带当前版本（If-Match）时更新成功，返回新版本
+	w := update(body("https://first.example.com"), `"1"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("Expected ETag \"2\", got %s", etag)
+	}
+	var updated proxyconfig.ProxyConfig
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Version != 2 {
+		t.Errorf("Expected version 2 in response, got %d", updated.Version)
+	}
+
+	// 请求体中的version同样有效
+	if w := update(`{"name":"Test Config","target_url":"https://second.example.com","protocol":"https","enabled":true,"version":2}`, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 with body version, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// 旧版本被拒绝
+	w = update(body("https://stale.example.com"), `"1"`)
+	assertProxyError(t, w, http.StatusConflict, errCodeVersionConflict)
+	current, _ := storage.GetByID(proxyConfig.ID)
+	if current.TargetURL != "https://second.example.com" {
+		t.Errorf("Expected stale update to be rejected, got target %s", current.TargetURL)
+	}
+
+	// 未携带版本
+	assertProxyError(t, update(body("https://missing.example.com"), ""), http.StatusPreconditionRequired, errCodeVersionRequired)
+	assertProxyError(t, update(body("https://bad.example.com"), "abc"), http.StatusBadRequest, errCodeInvalidRequest)
+}
+
+func TestHandleProxyConfigAPI_Patch(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+
+	patch := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/config/proxy?id="+proxyConfig.ID, strings.NewReader(body))
+		req.Header.Set("X-Log-Secret", "test-secret")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		HandleProxyConfigAPI(w, req, cfg, log, storage, nil)
+		return w
+	}
+	original, _ := storage.GetByID(proxyConfig.ID)
+
+	// 只修改enabled，false不能被当作未提供
+	w := patch(`{"enabled":false}`, `"1"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("Expected ETag \"2\", got %s", etag)
+	}
+	current, _ := storage.GetByID(proxyConfig.ID)
+	if current.Enabled {
+		t.Error("Expected config to be disabled")
+	}
+	if current.Name != original.Name || current.TargetURL != original.TargetURL || current.Protocol != original.Protocol {
+		t.Errorf("Expected other fields to be preserved, got %+v", current)
+	}
+	if len(current.AccessTokens) != len(original.AccessTokens) {
+		t.Errorf("Expected %d tokens to be preserved, got %d", len(original.AccessTokens), len(current.AccessTokens))
+	}
+
+	// 只修改target_url，版本号放在请求体中
+	if w := patch(`{"target_url":"https://patched.example.com","version":2}`, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	current, _ = storage.GetByID(proxyConfig.ID)
+	if current.TargetURL != "https://patched.example.com" {
+		t.Errorf("Expected target to be patched, got %s", current.TargetURL)
+	}
+	if current.Enabled || current.Name != original.Name {
+		t.Errorf("Expected earlier patch and name to be preserved, got %+v", current)
+	}
+
+	// 合并结果需通过验证
+	assertProxyError(t, patch(`{"target_url":"not a url"}`, `"3"`), http.StatusBadRequest, errCodeValidation)
+
+	// 只读字段、缺少版本和旧版本
+	assertProxyError(t, patch(`{"id":"other"}`, `"3"`), http.StatusBadRequest, errCodeInvalidRequest)
+	assertProxyError(t, patch(`{"enabled":true}`, ""), http.StatusPreconditionRequired, errCodeVersionRequired)
+	assertProxyError(t, patch(`{"enabled":true}`, `"1"`), http.StatusConflict, errCodeVersionConflict)
+	assertProxyError(t, patch(`[1]`, `"3"`), http.StatusBadRequest, errCodeInvalidJSON)
+}
+
+func TestHandleProxyConfigAPI_Search(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+	other := &proxyconfig.ProxyConfig{Name: "Other", TargetURL: "https://other.example.com", Protocol: "https", Enabled: true}
+	storage.Add(other)
+
+	search := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/config/proxy/search?q="+query, nil)
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		HandleProxyConfigAPI(w, req, cfg, log, storage, nil)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) proxyconfig.SearchResult {
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var result proxyconfig.SearchResult
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return result
+	}
+
+	// 目标URL子串命中（不区分大小写）
+	result := decode(search("HTTPBIN"))
+	if result.Total != 1 || result.Results[0].Config.ID != proxyConfig.ID {
+		t.Fatalf("Expected only the httpbin config, got %+v", result)
+	}
+	if fields := result.Results[0].MatchedFields; len(fields) != 1 || fields[0] != proxyconfig.SearchFieldTargetURL {
+		t.Errorf("Expected target_url match, got %v", fields)
+	}
+	if len(result.Results[0].Tokens) != 0 {
+		t.Errorf("Expected no token matches, got %d", len(result.Results[0].Tokens))
+	}
+
+	// 令牌名称命中，配置自身未命中
+	result = decode(search("integration"))
+	if result.Total != 1 || result.Results[0].Config.ID != proxyConfig.ID {
+		t.Fatalf("Expected config owning the token, got %+v", result)
+	}
+	match := result.Results[0]
+	if len(match.MatchedFields) != 0 {
+		t.Errorf("Expected no config field matches, got %v", match.MatchedFields)
+	}
+	if len(match.Tokens) != 1 || match.Tokens[0].MatchedFields[0] != proxyconfig.SearchFieldName {
+		t.Fatalf("Expected one token name match, got %+v", match.Tokens)
+	}
+	if match.Tokens[0].Token.TokenHash != "" || len(match.Config.AccessTokens) != 0 {
+		t.Error("Expected token data to be sanitized")
+	}
+
+	if result := decode(search("nomatch")); result.Total != 0 || result.Results == nil {
+		t.Errorf("Expected empty results array, got %+v", result)
+	}
+	assertProxyError(t, search(""), http.StatusBadRequest, errCodeInvalidRequest)
+}
+
+func TestHandleProxyConfigAPI_ExportSingle(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+	other := &proxyconfig.ProxyConfig{Name: "Other Config", TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+	storage.Add(other)
+
+	do := func(method, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		HandleProxyConfigAPI(w, req, cfg, log, storage, nil)
+		return w
+	}
+	export := func(query string) proxyconfig.ExportData {
+		t.Helper()
+		w := do("GET", "/config/proxy/"+proxyConfig.ID+"/export"+query, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var data proxyconfig.ExportData
+		if err := json.NewDecoder(w.Body).Decode(&data); err != nil {
+			t.Fatalf("Failed to decode export: %v", err)
+		}
+		return data
+	}
+
+	// 默认包含令牌，只导出指定的配置
+	withTokens := export("")
+	if withTokens.TotalCount != 1 || len(withTokens.Configs) != 1 || withTokens.Configs[0].ID != proxyConfig.ID {
+		t.Fatalf("Expected only the requested config, got %+v", withTokens)
+	}
+	if len(withTokens.Configs[0].AccessTokens) != 1 {
+		t.Errorf("Expected tokens to be exported, got %d", len(withTokens.Configs[0].AccessTokens))
+	} else if withTokens.Configs[0].AccessTokens[0].TokenHash != "" {
+		t.Error("Expected token hashes to be excluded by default")
+	}
+	if preserved := export("?token_hashes=preserve"); preserved.Configs[0].AccessTokens[0].TokenHash == "" {
+		t.Error("Expected token hashes to be kept with token_hashes=preserve")
+	}
+
+	withoutTokens := export("?include_tokens=false")
+	if len(withoutTokens.Configs[0].AccessTokens) != 0 || withoutTokens.Configs[0].TokenStats != nil {
+		t.Errorf("Expected tokens to be stripped, got %+v", withoutTokens.Configs[0].AccessTokens)
+	}
+	// 导出不影响存储中的令牌
+	if stored, _ := storage.GetByID(proxyConfig.ID); len(stored.AccessTokens) != 1 {
+		t.Errorf("Expected stored tokens to be untouched, got %d", len(stored.AccessTokens))
+	}
+
+	assertProxyError(t, do("GET", "/config/proxy/missing/export", ""), http.StatusNotFound, errCodeConfigNotFound)
+	assertProxyError(t, do("GET", "/config/proxy/"+proxyConfig.ID+"/export?include_tokens=maybe", ""), http.StatusBadRequest, errCodeInvalidRequest)
+	assertProxyError(t, do("GET", "/config/proxy/"+proxyConfig.ID+"/export?token_hashes=plain", ""), http.StatusBadRequest, errCodeInvalidRequest)
+	assertProxyError(t, do("POST", "/config/proxy/"+proxyConfig.ID+"/export", ""), http.StatusMethodNotAllowed, errCodeMethodNotAllowed)
+
+	// 删除后导入导出数据，重新创建配置
+	if w := do("DELETE", "/config/proxy?id="+proxyConfig.ID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"configs": withoutTokens.Configs, "mode": proxyconfig.ImportModeError})
+	w := do("POST", "/config/proxy/import", string(payload))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected import status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result proxyconfig.ImportResult
+	json.NewDecoder(w.Body).Decode(&result)
+	if result.ImportedCount != 1 {
+		t.Fatalf("Expected 1 imported config, got %+v", result)
+	}
+
+	var list proxyconfig.ConfigResponse
+	json.NewDecoder(do("GET", "/config/proxy?search=Test+Proxy+Config", "").Body).Decode(&list)
+	if list.Total != 1 || list.Configs[0].TargetURL != proxyConfig.TargetURL || len(list.Configs[0].AccessTokens) != 0 {
+		t.Errorf("Expected imported config without tokens, got %+v", list.Configs)
+	}
+}
+
+func TestHandleProxyConfigAPI_ImportReissuesTokens(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	do := func(method, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		HandleProxyConfigAPI(w, req, cfg, log, storage, nil)
+		return w
+	}
+
+	var exported proxyconfig.ExportData
+	json.NewDecoder(do("GET", "/config/proxy/export", "").Body).Decode(&exported)
+	if w := do("DELETE", "/config/proxy?id="+proxyConfig.ID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{"configs": exported.Configs, "token_mode": proxyconfig.TokenImportReissue})
+	w := do("POST", "/config/proxy/import", string(payload))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected import status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result proxyconfig.ImportResult
+	json.NewDecoder(w.Body).Decode(&result)
+	newValue := result.ReissuedTokens[proxyConfig.Name]["Integration Test Token"]
+	if newValue == "" || newValue == tokenValue {
+		t.Fatalf("Expected a reissued token value, got %+v", result.ReissuedTokens)
+	}
+
+	assertProxyError(t, do("POST", "/config/proxy/import", `{"configs":[],"token_mode":"rotate"}`), http.StatusBadRequest, errCodeInvalidRequest)
+}
+
+func TestHandleProxyConfigAPI_ImportDiff(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+
+	do := func(path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		HandleProxyConfigAPI(w, req, cfg, log, storage, nil)
+		return w
+	}
+
+	body := `{"version":"1.0","configs":[{"name":"New Config","target_url":"https://example.com","protocol":"https","enabled":true}]}`
+	w := do("/config/proxy/import/diff?prune=true", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var diff proxyconfig.ImportDiff
+	json.NewDecoder(w.Body).Decode(&diff)
+	if len(diff.Adds) != 1 || len(diff.Deletes) != 1 || diff.Deletes[0].ID != proxyConfig.ID {
+		t.Errorf("Expected one add and the existing config as delete, got %+v", diff)
+	}
+	if stats := storage.GetStats(); stats.TotalConfigs != 1 {
+		t.Errorf("Expected diff not to modify storage, got %d configs", stats.TotalConfigs)
+	}
+
+	assertProxyError(t, do("/config/proxy/import/diff", "{"), http.StatusBadRequest, errCodeInvalidJSON)
+	assertProxyError(t, do("/config/proxy/import/diff?prune=maybe", body), http.StatusBadRequest, errCodeInvalidRequest)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+)
+
+func TestHTTPProxyWithTokenAuth_RedactsLoggedSecrets(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 1024
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+	cfg.LogRecord200 = true
+	cfg.LogRedactHeaders = []string{"authorization"}
+	cfg.LogRedactFields = []string{"password", "session.token"}
+	cfg.LogRedactPatterns = []string{`sk-[A-Za-z0-9]+`}
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	// 上游仍收到原始内容
+	var upstreamAuth, upstreamBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		upstreamBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"session":{"token":"abc123","user":"alice"},"note":"key sk-live42"}`))
+	}))
+	defer upstream.Close()
+
+	requestBody := `{"username":"alice","password":"hunter2"}`
+	req := httptest.NewRequest("POST", "/proxy?target="+upstream.URL+"/login&config_id="+proxyConfig.ID, strings.NewReader(requestBody))
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	req.Header.Set("Authorization", "Bearer super-secret")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if upstreamAuth != "Bearer super-secret" || upstreamBody != requestBody {
+		t.Errorf("Expected upstream to receive original request, got auth %q body %q", upstreamAuth, upstreamBody)
+	}
+	if !strings.Contains(w.Body.String(), "abc123") {
+		t.Errorf("Expected client to receive original response, got %s", w.Body.String())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+		if err == nil && len(logs.Logs) > 0 {
+			entry := logs.Logs[0]
+			if entry.RequestHeaders["Authorization"] != accesslog.RedactedValue {
+				t.Errorf("Expected Authorization header to be redacted, got %q", entry.RequestHeaders["Authorization"])
+			}
+			if entry.RequestBody != `{"password":"[REDACTED]","username":"alice"}` {
+				t.Errorf("Expected password field to be redacted, got %s", entry.RequestBody)
+			}
+			if strings.Contains(entry.ResponseBody, "abc123") || strings.Contains(entry.ResponseBody, "sk-live42") || !strings.Contains(entry.ResponseBody, "alice") {
+				t.Errorf("Expected nested token and pattern match to be redacted, got %s", entry.ResponseBody)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected access log entry to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 无效的正则表达式时不创建记录器
+	cfg.LogRedactPatterns = []string{"("}
+	if _, err := accesslog.NewRecorder(cfg, log); err == nil {
+		t.Error("Expected error for invalid redact pattern")
+	}
+}
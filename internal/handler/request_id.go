@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/config"
+)
+
+// defaultRequestIDHeader 默认的请求ID头名称
+const defaultRequestIDHeader = "X-Request-ID"
+
+// requestIDPattern 客户端提供的请求ID的合法格式（避免日志注入和超长值）
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDHeader 获取请求ID头名称（规范化格式，便于与http.Header的键比较）
+func RequestIDHeader(cfg *config.Config) string {
+	if cfg == nil || cfg.RequestIDHeader == "" {
+		return http.CanonicalHeaderKey(defaultRequestIDHeader)
+	}
+	return http.CanonicalHeaderKey(cfg.RequestIDHeader)
+}
+
+// assignRequestID 确定本次请求的ID并写入响应头和访问日志
+//
+// 客户端提供了格式合法的请求ID时沿用，否则生成新的ID。
+func assignRequestID(w http.ResponseWriter, r *http.Request, cfg *config.Config, capture *accesslog.ResponseCapture) string {
+	header := RequestIDHeader(cfg)
+
+	requestID := r.Header.Get(header)
+	if !requestIDPattern.MatchString(requestID) {
+		requestID = accesslog.GenerateLogID()
+	}
+
+	w.Header().Set(header, requestID)
+	if capture != nil {
+		capture.SetRequestID(requestID)
+	}
+	return requestID
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+)
+
+func TestHTTPProxy_RequestID(t *testing.T) {
+	cfg, log, _, _, _ := setupProxyIntegrationTest()
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 1024
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+	cfg.LogRecord200 = true
+
+	// 上游记录收到的请求ID，并尝试返回自己的请求ID
+	upstreamIDs := make(chan string, 2)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamIDs <- r.Header.Get("X-Request-ID")
+		w.Header().Set("X-Request-ID", "upstream-id")
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	doRequest := func(clientID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL, nil)
+		req.Header.Set("X-Log-Secret", "test-secret")
+		if clientID != "" {
+			req.Header.Set("X-Request-ID", clientID)
+		}
+		w := httptest.NewRecorder()
+		HTTPProxy(w, req, cfg, log, recorder)
+		return w
+	}
+
+	// 未提供请求ID时生成新ID
+	w := doRequest("")
+	requestID := w.Header().Get("X-Request-ID")
+	if requestID == "" || requestID == "upstream-id" {
+		t.Fatalf("Expected gateway-generated request ID, got %q", requestID)
+	}
+	if values := w.Header().Values("X-Request-ID"); len(values) != 1 {
+		t.Errorf("Expected a single request ID header, got %v", values)
+	}
+	if got := <-upstreamIDs; got != requestID {
+		t.Errorf("Expected upstream to receive %q, got %q", requestID, got)
+	}
+
+	// 访问日志记录同一请求ID
+	deadline := time.Now().Add(time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10, Search: requestID})
+		if err == nil && len(logs.Logs) == 1 {
+			if logs.Logs[0].RequestID != requestID {
+				t.Errorf("Expected logged request ID %q, got %q", requestID, logs.Logs[0].RequestID)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected access log entry with request ID to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 格式合法的客户端请求ID被沿用，非法值被替换
+	w = doRequest("client-trace.42")
+	if got := w.Header().Get("X-Request-ID"); got != "client-trace.42" {
+		t.Errorf("Expected client request ID to be reused, got %q", got)
+	}
+	<-upstreamIDs
+
+	w = doRequest("bad id\" with spaces")
+	if got := w.Header().Get("X-Request-ID"); got == "bad id\" with spaces" || got == "" {
+		t.Errorf("Expected malformed request ID to be replaced, got %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"privacygateway/internal/logger"
+	"privacygateway/internal/proxy"
+	"privacygateway/internal/proxyconfig"
+)
+
+// defaultRetryBackoff 未配置retry_backoff_ms时的首次重试等待时间
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// retryPolicy 上游请求重试策略
+type retryPolicy struct {
+	maxRetries         int
+	backoff            time.Duration
+	allowNonIdempotent bool
+}
+
+// newRetryPolicy 根据代理配置创建重试策略，routeConfig为nil时不重试
+func newRetryPolicy(routeConfig *proxyconfig.ProxyConfig) retryPolicy {
+	if routeConfig == nil || routeConfig.RetryCount <= 0 {
+		return retryPolicy{}
+	}
+
+	backoff := defaultRetryBackoff
+	if routeConfig.RetryBackoffMs > 0 {
+		backoff = time.Duration(routeConfig.RetryBackoffMs) * time.Millisecond
+	}
+
+	return retryPolicy{
+		maxRetries:         routeConfig.RetryCount,
+		backoff:            backoff,
+		allowNonIdempotent: routeConfig.RetryNonIdempotent,
+	}
+}
+
+// retriesFor 返回该请求方法允许的最大重试次数
+func (p retryPolicy) retriesFor(method string) int {
+	if p.allowNonIdempotent || isIdempotentMethod(method) {
+		return p.maxRetries
+	}
+	return 0
+}
+
+// delay 返回第attempt次重试（从0开始）前的等待时间，按指数退避
+func (p retryPolicy) delay(attempt int) time.Duration {
+	return p.backoff << uint(attempt)
+}
+
+// isIdempotentMethod 判断请求方法是否幂等（RFC 7231）
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryableStatus 判断上游状态码是否属于可重试的临时错误
+func isRetryableStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// isRetryableError 判断请求错误是否可以重试（被访问策略拒绝或请求已取消时不重试）
+func isRetryableError(err error) bool {
+	return !errors.Is(err, proxy.ErrTargetBlocked) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// doWithRetry 执行上游请求，遇到连接错误或502/503/504时按策略重试
+//
+// 每次尝试使用body重新构造请求体。返回最后一次的响应或错误以及实际重试次数；
+// 最后一次尝试仍返回可重试状态码时，将该响应原样返回给调用方。
+func doWithRetry(client *http.Client, req *http.Request, body []byte, policy retryPolicy, log *logger.Logger) (*http.Response, int, error) {
+	maxRetries := policy.retriesFor(req.Method)
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+		attemptReq.Body = http.NoBody
+		if len(body) > 0 {
+			attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		attemptReq.ContentLength = int64(len(body))
+
+		resp, err := client.Do(attemptReq)
+		if attempt >= maxRetries {
+			return resp, attempt, err
+		}
+
+		if err != nil {
+			if !isRetryableError(err) {
+				return nil, attempt, err
+			}
+			log.Warn("upstream request failed, retrying", "target", req.URL.String(), "attempt", attempt+1, "error", err)
+		} else {
+			if !isRetryableStatus(resp.StatusCode) {
+				return resp, attempt, nil
+			}
+			log.Warn("upstream returned retryable status, retrying", "target", req.URL.String(), "attempt", attempt+1, "status", resp.StatusCode)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, attempt, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
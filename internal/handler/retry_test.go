@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+	"privacygateway/internal/proxyconfig"
+)
+
+// setupFlakyUpstream 创建首次请求失败、之后成功的上游，并将配置指向该上游
+//
+// failure 为 "503" 时首次返回503，为 "reset" 时首次直接断开连接。
+func setupFlakyUpstream(t *testing.T, failure string) (*config.Config, *logger.Logger, proxyconfig.Storage, *proxyconfig.ProxyConfig, string, *int32) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	var upstreamCalls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&upstreamCalls, 1) == 1 {
+			if failure == "reset" {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err == nil {
+					conn.Close()
+				}
+				return
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	retrying := *proxyConfig
+	retrying.TargetURL = upstream.URL
+	retrying.RetryCount = 2
+	retrying.RetryBackoffMs = 1
+	if err := storage.Update(proxyConfig.ID, &retrying); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	return cfg, log, storage, &retrying, tokenValue, &upstreamCalls
+}
+
+func TestHTTPProxyWithTokenAuth_RetriesIdempotentRequests(t *testing.T) {
+	for _, failure := range []string{"503", "reset"} {
+		t.Run(failure, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue, upstreamCalls := setupFlakyUpstream(t, failure)
+
+			cfg.LogMaxEntries = 100
+			cfg.LogMaxBodySize = 1024
+			cfg.LogRetentionHours = 1
+			cfg.LogMaxMemoryMB = 10
+			cfg.LogRecord200 = true
+			recorder, err := accesslog.NewRecorder(cfg, log)
+			if err != nil {
+				t.Fatalf("Failed to create recorder: %v", err)
+			}
+			defer recorder.Close()
+
+			req := httptest.NewRequest("GET", "/proxy?target="+proxyConfig.TargetURL+"/data&config_id="+proxyConfig.ID, nil)
+			req.Header.Set("X-Proxy-Token", tokenValue)
+			w := httptest.NewRecorder()
+
+			HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+
+			if w.Code != http.StatusOK || w.Body.String() != "ok" {
+				t.Fatalf("Expected GET to succeed after retry, got %d %q", w.Code, w.Body.String())
+			}
+			if calls := atomic.LoadInt32(upstreamCalls); calls != 2 {
+				t.Errorf("Expected 2 upstream calls, got %d", calls)
+			}
+
+			// 访问日志的代理信息应记录重试次数
+			deadline := time.Now().Add(time.Second)
+			for {
+				logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+				if err == nil && len(logs.Logs) > 0 {
+					if !strings.Contains(logs.Logs[0].ProxyInfo, "retries: 1") {
+						t.Errorf("Expected proxy info to record retries, got %q", logs.Logs[0].ProxyInfo)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Expected access log entry to be recorded")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_DoesNotRetryPost(t *testing.T) {
+	for _, failure := range []string{"503", "reset"} {
+		t.Run(failure, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue, upstreamCalls := setupFlakyUpstream(t, failure)
+
+			req := httptest.NewRequest("POST", "/proxy?target="+proxyConfig.TargetURL+"/data&config_id="+proxyConfig.ID, strings.NewReader(`{"a":1}`))
+			req.Header.Set("X-Proxy-Token", tokenValue)
+			w := httptest.NewRecorder()
+
+			HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+			if w.Code == http.StatusOK {
+				t.Error("Expected POST failure to be returned without retry")
+			}
+			if calls := atomic.LoadInt32(upstreamCalls); calls != 1 {
+				t.Errorf("Expected 1 upstream call for POST, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestHTTPProxyWithTokenAuth_RetriesPostWhenAllowed(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue, upstreamCalls := setupFlakyUpstream(t, "503")
+
+	proxyConfig.RetryNonIdempotent = true
+	storage.Update(proxyConfig.ID, proxyConfig)
+
+	req := httptest.NewRequest("POST", "/proxy?target="+proxyConfig.TargetURL+"/data&config_id="+proxyConfig.ID, strings.NewReader(`{"a":1}`))
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	w := httptest.NewRecorder()
+
+	HTTPProxyWithTokenAuth(w, req, cfg, log, nil, storage, nil)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected POST to succeed when retries are allowed, got %d", w.Code)
+	}
+	if calls := atomic.LoadInt32(upstreamCalls); calls != 2 {
+		t.Errorf("Expected 2 upstream calls, got %d", calls)
+	}
+}
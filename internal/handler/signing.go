@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"privacygateway/internal/proxyconfig"
+)
+
+// nonceCache 记录签名时间窗口内已使用的nonce（按配置区分），用于拒绝重放的请求
+type nonceCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time // configID + "\n" + nonce -> 过期时间
+	lastGC  time.Time
+	gcEvery time.Duration
+}
+
+// signatureNonces 代理路径共用的nonce缓存
+var signatureNonces = newNonceCache()
+
+// newNonceCache 创建nonce缓存
+func newNonceCache() *nonceCache {
+	return &nonceCache{seen: make(map[string]time.Time), gcEvery: time.Minute}
+}
+
+// use 登记nonce，在有效期内重复出现时返回false
+func (c *nonceCache) use(configID, nonce string, ttl time.Duration, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastGC) >= c.gcEvery {
+		for key, expiry := range c.seen {
+			if now.After(expiry) {
+				delete(c.seen, key)
+			}
+		}
+		c.lastGC = now
+	}
+
+	key := configID + "\n" + nonce
+	if expiry, ok := c.seen[key]; ok && !now.After(expiry) {
+		return false
+	}
+	// 时间戳允许前后偏差，nonce需保留两倍窗口才能覆盖所有可接受的时间戳
+	c.seen[key] = now.Add(2 * ttl)
+	return true
+}
+
+// verifyRequestSignature 校验配置要求的HMAC签名，请求体读取后会被还原供后续转发；
+// 请求体超过MaxSignedBodySize时返回ErrSignedBodyTooLarge
+func verifyRequestSignature(r *http.Request, signing *proxyconfig.RequestSigning, configID string) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, proxyconfig.MaxSignedBodySize+1))
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		if len(body) > proxyconfig.MaxSignedBodySize {
+			return proxyconfig.ErrSignedBodyTooLarge
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	now := time.Now()
+	nonce, err := signing.Verify(r.Method, r.URL.Query().Get("target"), r.Header, body, now)
+	if err != nil {
+		return err
+	}
+	if !signatureNonces.use(configID, nonce, signing.MaxSkew(), now) {
+		return proxyconfig.ErrSignatureReplayed
+	}
+	return nil
+}
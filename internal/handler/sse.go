@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// isEventStreamRequest 检查客户端是否请求SSE事件流（EventSource会发送 Accept: text/event-stream）
+func isEventStreamRequest(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Accept")), "text/event-stream")
+}
+
+// streamingClient 返回不限制总时长的客户端副本
+//
+// SSE是长连接，http.Client.Timeout会在读取响应体期间中断连接；
+// 客户端断开时请求上下文被取消，上游连接随之关闭。
+func streamingClient(client *http.Client) *http.Client {
+	streaming := *client
+	streaming.Timeout = 0
+	return &streaming
+}
+
+// prepareEventStreamHeaders 设置SSE响应头：禁止缓存，并关闭前置反向代理（如Nginx）的缓冲
+func prepareEventStreamHeaders(header http.Header) {
+	header.Del("Content-Length")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+}
+
+// writeEventStream 转发SSE事件流，每读到一个事件立即写出并刷新，不等待完整响应体
+func writeEventStream(w http.ResponseWriter, body io.Reader) error {
+	// 事件流持续时间不定，取消服务器的写超时
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// 使用较小的缓冲区，尽快转发单个事件
+	_, err := copyAndFlush(w, body, 4096)
+	return err
+}
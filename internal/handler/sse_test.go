@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/cache"
+)
+
+func TestHTTPProxyWithTokenAuth_EventStream(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 1024
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+	cfg.LogRecord200 = true
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	// 开启缓存，验证事件流不会被缓存
+	cached := *proxyConfig
+	cached.CacheTTLSeconds = 60
+	storage.Update(proxyConfig.ID, &cached)
+	responseCache := cache.NewResponseCache(1024 * 1024)
+
+	// 上游每发送一个事件后等待测试确认收到，再发送下一个
+	received := make(chan struct{}, 3)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "id: %d\ndata: event %d\n\n", i, i)
+			w.(http.Flusher).Flush()
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HTTPProxyWithTokenAuth(w, r, cfg, log, recorder, storage, responseCache)
+	}))
+	defer gateway.Close()
+
+	req, _ := http.NewRequest("GET", gateway.URL+"/proxy?target="+upstream.URL+"/events&config_id="+proxyConfig.ID, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Cache-Control") != "no-cache" || resp.Header.Get("X-Accel-Buffering") != "no" {
+		t.Errorf("Expected streaming headers, got %v", resp.Header)
+	}
+
+	// 逐个读取事件：若网关缓冲完整响应体，第一个事件永远不会到达
+	reader := bufio.NewReader(resp.Body)
+	for i := 1; i <= 3; i++ {
+		lines := make(chan string, 1)
+		go func() {
+			var event strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				event.WriteString(line)
+				if err != nil || line == "\n" {
+					lines <- event.String()
+					return
+				}
+			}
+		}()
+
+		select {
+		case event := <-lines:
+			if !strings.Contains(event, fmt.Sprintf("data: event %d", i)) {
+				t.Fatalf("Expected event %d, got %q", i, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for event %d, response is not streamed", i)
+		}
+		received <- struct{}{}
+	}
+
+	// 事件流不写入缓存，访问日志标记为SSE且不捕获响应体
+	if responseCache.Len() != 0 {
+		t.Errorf("Expected event stream not to be cached, got %d entries", responseCache.Len())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+		if err == nil && len(logs.Logs) > 0 {
+			if logs.Logs[0].RequestType != accesslog.RequestTypeSSE || logs.Logs[0].ResponseBody != "" {
+				t.Errorf("Expected SSE log without body, got type=%q body=%q", logs.Logs[0].RequestType, logs.Logs[0].ResponseBody)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected access log entry to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
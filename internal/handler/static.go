@@ -4,14 +4,21 @@ import (
 	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
+	"privacygateway/internal/config"
 	"privacygateway/internal/logger"
 )
 
+// spaReservedPrefixes 单页应用回退不适用的路径前缀（API及管理路由）
+var spaReservedPrefixes = []string{"/api", "/proxy", "/config", "/logs"}
+
 // Static 处理静态文件请求（支持新的模块化前端结构）
-func Static(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
+//
+// 配置了STATIC_DIR时从该目录提供文件，否则使用内置的前端目录。
+func Static(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger) {
 	// 注意：CORS头部已在路由层设置，这里不再重复设置
 
 	// 处理预检请求
@@ -20,6 +27,12 @@ func Static(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
 		return
 	}
 
+	// 自定义静态文件目录
+	if cfg != nil && cfg.StaticDir != "" {
+		serveStaticDir(w, r, cfg.StaticDir, cfg.StaticSPAFallback, log)
+		return
+	}
+
 	// 获取不带查询参数的路径
 	path := r.URL.Path
 
@@ -158,3 +171,94 @@ func serveAsset(w http.ResponseWriter, r *http.Request, log *logger.Logger) {
 
 	log.Debug("served asset", "path", filePath, "size", len(fileContent))
 }
+
+// serveStaticDir 从指定根目录提供静态文件，开启SPA回退时未知路径返回index.html
+func serveStaticDir(w http.ResponseWriter, r *http.Request, root string, spaFallback bool, log *logger.Logger) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 安全检查：拒绝包含上级目录的路径，防止访问根目录之外的文件；
+	// 同时拒绝以"."开头的路径段，不提供.env、.git等隐藏文件
+	for _, segment := range strings.Split(r.URL.Path, "/") {
+		if segment == ".." {
+			log.Warn("potential path traversal attempt", "path", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasPrefix(segment, ".") {
+			log.Debug("hidden static path denied", "path", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	urlPath := r.URL.Path
+	if strings.HasSuffix(urlPath, "/") {
+		urlPath += "index.html"
+	}
+
+	if serveStaticFile(w, r, root, urlPath) {
+		return
+	}
+
+	// 单页应用回退：前端路由交给index.html处理
+	if spaFallback && !isSPAReservedPath(r.URL.Path) && serveStaticFile(w, r, root, "/index.html") {
+		return
+	}
+
+	log.Debug("static file not found", "path", r.URL.Path)
+	http.NotFound(w, r)
+}
+
+// serveStaticFile 提供根目录下的单个文件，文件不存在或不是普通文件时返回false
+func serveStaticFile(w http.ResponseWriter, r *http.Request, root, urlPath string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	// 解析符号链接后再比较，防止通过指向外部的链接访问根目录之外的文件
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return false
+	}
+	filePath, err := filepath.EvalSymlinks(filepath.Join(realRoot, filepath.FromSlash(path.Clean("/"+urlPath))))
+	if err != nil {
+		return false
+	}
+
+	// 再次确认最终路径位于根目录内
+	if rel, err := filepath.Rel(realRoot, filePath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	// 页面入口不缓存，确保前端更新及时生效
+	if filepath.Base(filePath) == "index.html" {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	return true
+}
+
+// isSPAReservedPath 检查路径是否属于不进行SPA回退的API及管理路由
+func isSPAReservedPath(urlPath string) bool {
+	for _, prefix := range spaReservedPrefixes {
+		if urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
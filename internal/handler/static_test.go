@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+)
+
+func TestStatic_StaticDir(t *testing.T) {
+	// 静态目录外放置一个敏感文件
+	base := t.TempDir()
+	root := filepath.Join(base, "public")
+	os.MkdirAll(filepath.Join(root, "assets"), 0755)
+	os.WriteFile(filepath.Join(root, "index.html"), []byte("<html>spa</html>"), 0644)
+	os.WriteFile(filepath.Join(root, "assets", "app.js"), []byte("console.log('app')"), 0644)
+	os.WriteFile(filepath.Join(base, "secret.txt"), []byte("secret"), 0644)
+	// 根目录内的隐藏文件和指向外部的符号链接
+	os.WriteFile(filepath.Join(root, ".env"), []byte("secret=1"), 0644)
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+	os.WriteFile(filepath.Join(root, ".git", "config"), []byte("secret"), 0644)
+	os.Symlink(filepath.Join(base, "secret.txt"), filepath.Join(root, "leak.txt"))
+	os.Symlink(base, filepath.Join(root, "outside"))
+	os.Symlink(filepath.Join(root, "assets", "app.js"), filepath.Join(root, "app-link.js"))
+
+	log := logger.New()
+	doRequest := func(cfg *config.Config, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+		Static(w, req, cfg, log)
+		return w
+	}
+
+	spa := &config.Config{StaticDir: root, StaticSPAFallback: true}
+
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"index", spa, "/", http.StatusOK, "<html>spa</html>"},
+		{"asset", spa, "/assets/app.js", http.StatusOK, "console.log('app')"},
+		{"spa fallback", spa, "/dashboard/settings", http.StatusOK, "<html>spa</html>"},
+		{"reserved prefix", spa, "/api/users", http.StatusNotFound, ""},
+		{"traversal", spa, "/../secret.txt", http.StatusNotFound, ""},
+		{"nested traversal", spa, "/assets/../../secret.txt", http.StatusNotFound, ""},
+		{"fallback disabled", &config.Config{StaticDir: root}, "/dashboard", http.StatusNotFound, ""},
+		{"dotfile", spa, "/.env", http.StatusNotFound, ""},
+		{"dot directory", spa, "/.git/config", http.StatusNotFound, ""},
+		{"symlink file outside root", &config.Config{StaticDir: root}, "/leak.txt", http.StatusNotFound, ""},
+		{"symlink dir outside root", &config.Config{StaticDir: root}, "/outside/secret.txt", http.StatusNotFound, ""},
+		{"symlink inside root", spa, "/app-link.js", http.StatusOK, "console.log('app')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(tt.cfg, tt.path)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), "secret") {
+				t.Error("File outside static root must not be served")
+			}
+		})
+	}
+
+	if contentType := doRequest(spa, "/assets/app.js").Header().Get("Content-Type"); !strings.Contains(contentType, "javascript") {
+		t.Errorf("Expected JavaScript content type, got %q", contentType)
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+)
+
+// streamBufferSize 流式转发响应体时的缓冲区大小
+const streamBufferSize = 32 * 1024
+
+// copyAndFlush 将上游响应体流式写给客户端，每写出一块数据后立即刷新
+//
+// 响应体不会在内存中完整缓冲，大文件下载时客户端可以持续收到数据。
+func copyAndFlush(w http.ResponseWriter, body io.Reader, bufferSize int) (int64, error) {
+	return io.CopyBuffer(&flushWriter{w: w, controller: http.NewResponseController(w)}, body, make([]byte, bufferSize))
+}
+
+// flushWriter 每次写入后刷新的Writer
+type flushWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// Write 写入数据并刷新
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if n > 0 {
+		fw.controller.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+)
+
+func TestHTTPProxyWithTokenAuth_StreamsLargeDownload(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 1024
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+	cfg.LogRecord200 = true
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	// 8MB二进制内容，分块写出
+	const size = 8 * 1024 * 1024
+	chunk := bytes.Repeat([]byte{0x00, 0xff, 0x10, 0x7f}, 16*1024)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		for written := 0; written < size; written += len(chunk) {
+			w.Write(chunk)
+		}
+	}))
+	defer upstream.Close()
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HTTPProxyWithTokenAuth(w, r, cfg, log, recorder, storage, nil)
+	}))
+	defer gateway.Close()
+
+	req, _ := http.NewRequest("GET", gateway.URL+"/proxy?target="+upstream.URL+"/download&config_id="+proxyConfig.ID, nil)
+	req.Header.Set("X-Proxy-Token", tokenValue)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to download through gateway: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 客户端收到完整内容
+	received, err := io.Copy(io.Discard, resp.Body)
+	if err != nil || received != size {
+		t.Fatalf("Expected %d bytes, got %d (err=%v)", size, received, err)
+	}
+
+	// 访问日志只保留不超过LogMaxBodySize的响应体，响应大小记录完整长度
+	deadline := time.Now().Add(time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+		if err == nil && len(logs.Logs) > 0 {
+			entry := logs.Logs[0]
+			if entry.ResponseSize != size {
+				t.Errorf("Expected response size %d, got %d", size, entry.ResponseSize)
+			}
+			if entry.ResponseBody != "[二进制内容，大小: 1.0 KB]" {
+				t.Errorf("Expected capture buffer to be capped at 1KB, got %q", entry.ResponseBody)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected access log entry to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"privacygateway/internal/audit"
 	"privacygateway/internal/logger"
 	"privacygateway/internal/proxyconfig"
 )
@@ -14,17 +18,23 @@ type TokenAPIHandler struct {
 	storage       proxyconfig.Storage
 	authenticator *ProxyAuthenticator
 	logger        *logger.Logger
+	auditRecorder *audit.Recorder // 审计日志记录器（可选）
 }
 
-// NewTokenAPIHandler 创建令牌API处理器
-func NewTokenAPIHandler(storage proxyconfig.Storage, adminSecret string, logger *logger.Logger) *TokenAPIHandler {
+// NewTokenAPIHandler 创建令牌API处理器，previousSecrets为轮换窗口内仍然有效的旧管理密钥
+func NewTokenAPIHandler(storage proxyconfig.Storage, adminSecret string, logger *logger.Logger, previousSecrets ...string) *TokenAPIHandler {
 	return &TokenAPIHandler{
 		storage:       storage,
-		authenticator: NewProxyAuthenticator(adminSecret, storage, logger),
+		authenticator: NewProxyAuthenticator(adminSecret, storage, logger, previousSecrets...),
 		logger:        logger,
 	}
 }
 
+// SetAuditRecorder 设置审计日志记录器，令牌的创建、更新、删除操作将被记录
+func (h *TokenAPIHandler) SetAuditRecorder(recorder *audit.Recorder) {
+	h.auditRecorder = recorder
+}
+
 // APIResponse 标准API响应格式
 type APIResponse struct {
 	Success bool        `json:"success"`
@@ -61,8 +71,11 @@ func (h *TokenAPIHandler) HandleTokenAPI(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// 认证检查（仅支持管理员）
-	authResult := h.authenticator.AuthenticateForConfig(r)
+	// 提取配置ID（租户管理密钥只对路径中的配置有效）
+	configID := h.extractConfigIDFromPath(r.URL.Path)
+
+	// 认证检查（管理员密钥或该配置的租户管理密钥）
+	authResult := h.authenticator.AuthenticateForConfig(r, configID)
 	if !authResult.Authenticated {
 		h.logger.Warn("token API access denied",
 			"client_ip", getClientIP(r),
@@ -73,9 +86,10 @@ func (h *TokenAPIHandler) HandleTokenAPI(w http.ResponseWriter, r *http.Request)
 		h.sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if authResult.Method == "owner" {
+		r = withAuditActor(r, audit.ActorOwner)
+	}
 
-	// 提取配置ID
-	configID := h.extractConfigIDFromPath(r.URL.Path)
 	if configID == "" {
 		h.sendErrorResponse(w, "Configuration ID is required", http.StatusBadRequest)
 		return
@@ -92,6 +106,7 @@ func (h *TokenAPIHandler) HandleTokenAPI(w http.ResponseWriter, r *http.Request)
 	// 记录API访问
 	h.logger.Info("token API access",
 		"config_id", configID,
+		"auth_method", authResult.Method,
 		"method", r.Method,
 		"path", r.URL.Path,
 		"client_ip", getClientIP(r))
@@ -101,12 +116,18 @@ func (h *TokenAPIHandler) HandleTokenAPI(w http.ResponseWriter, r *http.Request)
 	case http.MethodGet:
 		if strings.HasSuffix(r.URL.Path, "/tokens") {
 			h.handleListTokens(w, r, configID)
+		} else if strings.HasSuffix(r.URL.Path, "/tokens/idle") {
+			h.handleIdleTokens(w, r, configID)
 		} else {
 			tokenID := h.extractTokenIDFromPath(r.URL.Path)
 			if tokenID == "" {
 				h.sendErrorResponse(w, "Token ID is required", http.StatusBadRequest)
 				return
 			}
+			if strings.HasSuffix(r.URL.Path, "/usage") {
+				h.handleGetTokenUsage(w, r, configID, tokenID)
+				return
+			}
 			h.handleGetToken(w, r, configID, tokenID)
 		}
 	case http.MethodPost:
@@ -124,6 +145,10 @@ func (h *TokenAPIHandler) HandleTokenAPI(w http.ResponseWriter, r *http.Request)
 			h.sendErrorResponse(w, "Token ID is required", http.StatusBadRequest)
 			return
 		}
+		if strings.HasSuffix(r.URL.Path, "/stats") {
+			h.handleResetTokenStats(w, r, configID, tokenID)
+			return
+		}
 		h.handleDeleteToken(w, r, configID, tokenID)
 	default:
 		h.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -147,6 +172,9 @@ func (h *TokenAPIHandler) handleListTokens(w http.ResponseWriter, r *http.Reques
 		stats = nil
 	}
 
+	// 按标签过滤（?tag=env:prod，可重复，需全部满足）
+	tokens = proxyconfig.FilterTokensByTags(tokens, r.URL.Query()["tag"])
+
 	// 清理敏感信息
 	sanitizedTokens := proxyconfig.SanitizeTokensForResponse(tokens)
 
@@ -162,6 +190,39 @@ func (h *TokenAPIHandler) handleListTokens(w http.ResponseWriter, r *http.Reques
 	h.sendJSONResponse(w, response, http.StatusOK)
 }
 
+// handleIdleTokens 处理闲置令牌报告请求，返回超过 days 天未使用或从未使用的令牌
+func (h *TokenAPIHandler) handleIdleTokens(w http.ResponseWriter, r *http.Request, configID string) {
+	days := proxyconfig.DefaultIdleDays
+	if val := r.URL.Query().Get("days"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed <= 0 {
+			h.sendErrorResponse(w, "days must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	tokens, err := h.storage.GetTokens(configID)
+	if err != nil {
+		h.logger.Error("failed to get tokens", "config_id", configID, "error", err)
+		h.sendErrorResponse(w, "Failed to retrieve tokens", http.StatusInternalServerError)
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	response := &APIResponse{
+		Success: true,
+		Data: &proxyconfig.IdleTokenReport{
+			Days:   days,
+			Cutoff: cutoff,
+			Tokens: proxyconfig.SanitizeTokensForResponse(proxyconfig.FilterIdleTokens(tokens, cutoff)),
+		},
+		Status: http.StatusOK,
+	}
+
+	h.sendJSONResponse(w, response, http.StatusOK)
+}
+
 // handleGetToken 处理获取单个令牌请求
 func (h *TokenAPIHandler) handleGetToken(w http.ResponseWriter, r *http.Request, configID, tokenID string) {
 	token, err := h.storage.GetTokenByID(configID, tokenID)
@@ -189,6 +250,43 @@ func (h *TokenAPIHandler) handleGetToken(w http.ResponseWriter, r *http.Request,
 	h.sendJSONResponse(w, response, http.StatusOK)
 }
 
+// handleGetTokenUsage 处理获取令牌使用历史请求，可通过 days 参数指定天数
+func (h *TokenAPIHandler) handleGetTokenUsage(w http.ResponseWriter, r *http.Request, configID, tokenID string) {
+	days := proxyconfig.MaxTokenUsageDays
+	if val := r.URL.Query().Get("days"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed <= 0 || parsed > proxyconfig.MaxTokenUsageDays {
+			h.sendErrorResponse(w, fmt.Sprintf("days must be between 1 and %d", proxyconfig.MaxTokenUsageDays), http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	token, err := h.storage.GetTokenByID(configID, tokenID)
+	if err != nil {
+		if err == proxyconfig.ErrTokenNotFound {
+			h.sendErrorResponse(w, "Token not found", http.StatusNotFound)
+		} else {
+			h.logger.Error("failed to get token usage", "config_id", configID, "token_id", tokenID, "error", err)
+			h.sendErrorResponse(w, "Failed to retrieve token", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := &APIResponse{
+		Success: true,
+		Data: &proxyconfig.TokenUsageHistory{
+			TokenID:    token.ID,
+			UsageCount: token.UsageCount,
+			LastUsed:   token.LastUsed,
+			Days:       token.UsageSeries(time.Now(), days),
+		},
+		Status: http.StatusOK,
+	}
+
+	h.sendJSONResponse(w, response, http.StatusOK)
+}
+
 // handleCreateToken 处理创建令牌请求
 func (h *TokenAPIHandler) handleCreateToken(w http.ResponseWriter, r *http.Request, configID string) {
 	var req proxyconfig.TokenCreateRequest
@@ -229,6 +327,7 @@ func (h *TokenAPIHandler) handleCreateToken(w http.ResponseWriter, r *http.Reque
 		"token_id", token.ID,
 		"token_name", token.Name,
 		"client_ip", getClientIP(r))
+	recordAudit(h.auditRecorder, h.logger, r, audit.ActionTokenCreate, configID, token.ID, map[string]interface{}{"name": token.Name})
 
 	// 返回令牌（包含明文值，仅此一次）
 	response := &TokenAPIResponse{
@@ -292,6 +391,7 @@ func (h *TokenAPIHandler) handleUpdateToken(w http.ResponseWriter, r *http.Reque
 		"token_id", tokenID,
 		"token_name", existingToken.Name,
 		"client_ip", getClientIP(r))
+	recordAudit(h.auditRecorder, h.logger, r, audit.ActionTokenUpdate, configID, tokenID, map[string]interface{}{"name": existingToken.Name})
 
 	// 清理敏感信息并返回
 	sanitizedToken := proxyconfig.SanitizeTokenForResponse(existingToken)
@@ -333,6 +433,7 @@ func (h *TokenAPIHandler) handleDeleteToken(w http.ResponseWriter, r *http.Reque
 		"token_id", tokenID,
 		"token_name", token.Name,
 		"client_ip", getClientIP(r))
+	recordAudit(h.auditRecorder, h.logger, r, audit.ActionTokenDelete, configID, tokenID, map[string]interface{}{"name": token.Name})
 
 	response := &APIResponse{
 		Success: true,
@@ -343,6 +444,33 @@ func (h *TokenAPIHandler) handleDeleteToken(w http.ResponseWriter, r *http.Reque
 	h.sendJSONResponse(w, response, http.StatusOK)
 }
 
+// handleResetTokenStats 处理令牌使用统计清零请求
+func (h *TokenAPIHandler) handleResetTokenStats(w http.ResponseWriter, r *http.Request, configID, tokenID string) {
+	if err := h.storage.ResetTokenStats(configID, tokenID); err != nil {
+		if err == proxyconfig.ErrTokenNotFound {
+			h.sendErrorResponse(w, "Token not found", http.StatusNotFound)
+		} else {
+			h.logger.Error("failed to reset token stats", "config_id", configID, "token_id", tokenID, "error", err)
+			h.sendErrorResponse(w, "Failed to reset token stats", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("token stats reset",
+		"config_id", configID,
+		"token_id", tokenID,
+		"client_ip", getClientIP(r))
+	recordAudit(h.auditRecorder, h.logger, r, audit.ActionTokenStatsReset, configID, tokenID, nil)
+
+	response := &APIResponse{
+		Success: true,
+		Message: "Token stats reset successfully",
+		Status:  http.StatusOK,
+	}
+
+	h.sendJSONResponse(w, response, http.StatusOK)
+}
+
 // 辅助方法
 
 // extractConfigIDFromPath 从URL路径提取配置ID
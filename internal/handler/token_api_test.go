@@ -7,7 +7,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"privacygateway/internal/audit"
 	"privacygateway/internal/logger"
 	"privacygateway/internal/proxyconfig"
 )
@@ -20,7 +22,6 @@ func setupTokenAPITest() (*TokenAPIHandler, *proxyconfig.ProxyConfig) {
 	// 创建测试配置
 	config := &proxyconfig.ProxyConfig{
 		Name:      "Test Config",
-		Subdomain: "test",
 		TargetURL: "https://example.com",
 		Enabled:   true,
 	}
@@ -236,6 +237,308 @@ func TestTokenAPIHandler_HandleDeleteToken(t *testing.T) {
 	}
 }
 
+func TestTokenAPIHandler_DeleteTokenRecordsAudit(t *testing.T) {
+	handler, config := setupTokenAPITest()
+
+	auditRecorder, err := audit.NewRecorder(100, "")
+	if err != nil {
+		t.Fatalf("Failed to create audit recorder: %v", err)
+	}
+	handler.SetAuditRecorder(auditRecorder)
+
+	token, _, err := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Audited Token"}, "admin")
+	if err != nil {
+		t.Fatalf("Failed to create test token: %v", err)
+	}
+	handler.storage.AddToken(config.ID, token)
+
+	req := httptest.NewRequest("DELETE", "/config/proxy/"+config.ID+"/tokens/"+token.ID, nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	req.RemoteAddr = "192.0.2.10:54321"
+	w := httptest.NewRecorder()
+
+	handler.HandleTokenAPI(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	// 删除操作应产生一条审计记录
+	response := auditRecorder.Query(&audit.Filter{Action: audit.ActionTokenDelete})
+	if response.Total != 1 {
+		t.Fatalf("Expected 1 token.delete audit entry, got %d", response.Total)
+	}
+
+	entry := response.Entries[0]
+	if entry.Actor != audit.ActorAdmin {
+		t.Errorf("Expected actor %q, got %q", audit.ActorAdmin, entry.Actor)
+	}
+	if entry.ConfigID != config.ID || entry.TokenID != token.ID {
+		t.Errorf("Expected target %s/%s, got %s/%s", config.ID, token.ID, entry.ConfigID, entry.TokenID)
+	}
+	if entry.ClientIP != "192.0.2.10" {
+		t.Errorf("Expected client IP 192.0.2.10, got %s", entry.ClientIP)
+	}
+	if entry.Timestamp.IsZero() || entry.Hash == "" {
+		t.Error("Expected timestamp and hash to be set")
+	}
+
+	// 删除不存在的令牌不应产生审计记录
+	req = httptest.NewRequest("DELETE", "/config/proxy/"+config.ID+"/tokens/"+token.ID, nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	handler.HandleTokenAPI(httptest.NewRecorder(), req)
+
+	if auditRecorder.Count() != 1 {
+		t.Errorf("Expected failed deletion not to be audited, got %d entries", auditRecorder.Count())
+	}
+}
+
+func TestTokenAPIHandler_OwnerSecret(t *testing.T) {
+	handler, config := setupTokenAPITest()
+
+	auditRecorder, err := audit.NewRecorder(100, "")
+	if err != nil {
+		t.Fatalf("Failed to create audit recorder: %v", err)
+	}
+	handler.SetAuditRecorder(auditRecorder)
+
+	// 为配置设置租户管理密钥，另建一个其他租户的配置
+	config.OwnerSecretHash = proxyconfig.HashToken("tenant-a-secret")
+	if err := handler.storage.Update(config.ID, config); err != nil {
+		t.Fatalf("Failed to set owner secret: %v", err)
+	}
+	other := &proxyconfig.ProxyConfig{
+		Name:            "Other Tenant",
+		TargetURL:       "https://example.org",
+		Enabled:         true,
+		OwnerSecretHash: proxyconfig.HashToken("tenant-b-secret"),
+	}
+	if err := handler.storage.Add(other); err != nil {
+		t.Fatalf("Failed to add other config: %v", err)
+	}
+
+	// 租户密钥可以管理本配置的令牌
+	body, _ := json.Marshal(proxyconfig.TokenCreateRequest{Name: "Tenant Token"})
+	req := httptest.NewRequest("POST", "/config/proxy/"+config.ID+"/tokens", bytes.NewReader(body))
+	req.Header.Set("X-Log-Secret", "tenant-a-secret")
+	w := httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected owner to create token, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/config/proxy/"+config.ID+"/tokens", nil)
+	req.Header.Set("X-Log-Secret", "tenant-a-secret")
+	w = httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected owner to list tokens, got %d", w.Code)
+	}
+
+	// 租户操作在审计日志中记录为owner
+	entries := auditRecorder.Query(&audit.Filter{Action: audit.ActionTokenCreate}).Entries
+	if len(entries) != 1 || entries[0].Actor != audit.ActorOwner {
+		t.Errorf("Expected token creation audited as owner, got %+v", entries)
+	}
+
+	// 租户密钥不能访问其他配置
+	for _, method := range []string{"GET", "POST"} {
+		req = httptest.NewRequest(method, "/config/proxy/"+other.ID+"/tokens", bytes.NewReader(body))
+		req.Header.Set("X-Log-Secret", "tenant-a-secret")
+		w = httptest.NewRecorder()
+		handler.HandleTokenAPI(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %s on other config to be rejected, got %d", method, w.Code)
+		}
+	}
+
+	// 全局管理员密钥仍然可以管理所有配置
+	req = httptest.NewRequest("GET", "/config/proxy/"+other.ID+"/tokens", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected admin to list other config tokens, got %d", w.Code)
+	}
+}
+
+func TestTokenAPIHandler_ResetTokenStats(t *testing.T) {
+	handler, config := setupTokenAPITest()
+
+	// 两个令牌均有使用记录
+	first, firstValue, _ := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "First"}, "admin")
+	second, secondValue, _ := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Second"}, "admin")
+	handler.storage.AddToken(config.ID, first)
+	handler.storage.AddToken(config.ID, second)
+	for i := 0; i < 3; i++ {
+		handler.storage.UpdateTokenUsage(config.ID, firstValue)
+	}
+	handler.storage.UpdateTokenUsage(config.ID, secondValue)
+
+	// 未认证请求被拒绝
+	req := httptest.NewRequest("DELETE", "/config/proxy/"+config.ID+"/tokens/"+first.ID+"/stats", nil)
+	w := httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without secret, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/config/proxy/"+config.ID+"/tokens/"+first.ID+"/stats", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// 令牌本身保留，使用统计清零
+	token, err := handler.storage.GetTokenByID(config.ID, first.ID)
+	if err != nil {
+		t.Fatalf("Expected token to be kept, got %v", err)
+	}
+	if token.UsageCount != 0 || token.LastUsed != nil {
+		t.Errorf("Expected usage to be reset, got count=%d last_used=%v", token.UsageCount, token.LastUsed)
+	}
+
+	// 汇总统计只保留另一个令牌的使用次数
+	stats, _ := handler.storage.GetTokenStats(config.ID)
+	if stats.TotalTokens != 2 || stats.TotalRequests != 1 {
+		t.Errorf("Expected aggregate stats to be recomputed, got %+v", stats)
+	}
+
+	// 未知令牌返回404
+	req = httptest.NewRequest("DELETE", "/config/proxy/"+config.ID+"/tokens/unknown/stats", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown token, got %d", w.Code)
+	}
+}
+
+func TestTokenAPIHandler_IdleTokens(t *testing.T) {
+	handler, config := setupTokenAPITest()
+
+	now := time.Now()
+	recentUse := now.Add(-2 * 24 * time.Hour)
+	staleUse := now.Add(-40 * 24 * time.Hour)
+	olderUse := now.Add(-90 * 24 * time.Hour)
+
+	never, _, _ := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Never"}, "admin")
+	recent, _, _ := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Recent"}, "admin")
+	recent.LastUsed = &recentUse
+	stale, _, _ := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Stale"}, "admin")
+	stale.LastUsed = &staleUse
+	older, _, _ := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Older"}, "admin")
+	older.LastUsed = &olderUse
+	for _, token := range []*proxyconfig.AccessToken{never, recent, stale, older} {
+		handler.storage.AddToken(config.ID, token)
+	}
+
+	req := httptest.NewRequest("GET", "/config/proxy/"+config.ID+"/tokens/idle?days=30", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w := httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data proxyconfig.IdleTokenReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// 从未使用的排在最前，其后按最后使用时间从早到晚，最近使用的被排除
+	expected := []string{"Never", "Older", "Stale"}
+	if len(response.Data.Tokens) != len(expected) {
+		t.Fatalf("Expected %d idle tokens, got %d", len(expected), len(response.Data.Tokens))
+	}
+	for i, name := range expected {
+		if response.Data.Tokens[i].Name != name {
+			t.Errorf("Position %d: expected %s, got %s", i, name, response.Data.Tokens[i].Name)
+		}
+		if response.Data.Tokens[i].TokenHash != "" {
+			t.Error("Token hash should not be returned")
+		}
+	}
+	if response.Data.Days != 30 {
+		t.Errorf("Expected days 30, got %d", response.Data.Days)
+	}
+
+	// 非法天数返回400
+	req = httptest.NewRequest("GET", "/config/proxy/"+config.ID+"/tokens/idle?days=-1", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid days, got %d", w.Code)
+	}
+}
+
+func TestTokenAPIHandler_GetTokenUsage(t *testing.T) {
+	handler, config := setupTokenAPITest()
+
+	token, tokenValue, _ := proxyconfig.CreateAccessToken(&proxyconfig.TokenCreateRequest{Name: "Usage"}, "admin")
+	handler.storage.AddToken(config.ID, token)
+	for i := 0; i < 3; i++ {
+		handler.storage.UpdateTokenUsage(config.ID, tokenValue)
+	}
+
+	req := httptest.NewRequest("GET", "/config/proxy/"+config.ID+"/tokens/"+token.ID+"/usage?days=7", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w := httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Success bool                          `json:"success"`
+		Data    proxyconfig.TokenUsageHistory `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.TokenID != token.ID || response.Data.UsageCount != 3 {
+		t.Errorf("Unexpected usage summary: %+v", response.Data)
+	}
+	if len(response.Data.Days) != 7 {
+		t.Fatalf("Expected 7 days, got %d", len(response.Data.Days))
+	}
+	if today := response.Data.Days[6]; today.Count != 3 {
+		t.Errorf("Expected 3 requests today, got %+v", today)
+	}
+
+	// 清零统计同时清空使用历史
+	req = httptest.NewRequest("DELETE", "/config/proxy/"+config.ID+"/tokens/"+token.ID+"/stats", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	handler.HandleTokenAPI(httptest.NewRecorder(), req)
+	reset, _ := handler.storage.GetTokenByID(config.ID, token.ID)
+	if len(reset.UsageHistory) != 0 {
+		t.Errorf("Expected usage history to be cleared, got %+v", reset.UsageHistory)
+	}
+
+	// 非法天数返回400
+	req = httptest.NewRequest("GET", "/config/proxy/"+config.ID+"/tokens/"+token.ID+"/usage?days=0", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid days, got %d", w.Code)
+	}
+
+	// 未知令牌返回404
+	req = httptest.NewRequest("GET", "/config/proxy/"+config.ID+"/tokens/unknown/usage", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	handler.HandleTokenAPI(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown token, got %d", w.Code)
+	}
+}
+
 func TestTokenAPIHandler_Authentication(t *testing.T) {
 	handler, config := setupTokenAPITest()
 
@@ -389,3 +692,89 @@ func TestTokenAPIHandler_PathExtraction(t *testing.T) {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func TestTokenAPIHandler_TagsAndFilter(t *testing.T) {
+	handler, config := setupTokenAPITest()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/config/proxy/"+config.ID+"/tokens", strings.NewReader(body))
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		handler.HandleTokenAPI(w, req)
+		return w
+	}
+
+	w := create(`{"name":"prod-token","tags":{"env":"prod","team":"payments"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created TokenAPIResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Data.Tags["env"] != "prod" || created.Data.Tags["team"] != "payments" {
+		t.Errorf("Expected tags to be returned, got %v", created.Data.Tags)
+	}
+
+	if w := create(`{"name":"dev-token","tags":{"env":"dev"}}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// 非法标签被拒绝
+	if w := create(`{"name":"bad-token","tags":{"a:b":"c"}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid tag key, got %d", w.Code)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"?tag=env:prod", []string{"prod-token"}},
+		{"?tag=env", []string{"prod-token", "dev-token"}},
+		{"?tag=env:dev&tag=team", nil},
+		{"?tag=team:payments&tag=env:prod", []string{"prod-token"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/config/proxy/"+config.ID+"/tokens"+tt.query, nil)
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		handler.HandleTokenAPI(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.query, w.Code)
+		}
+
+		var response TokenListAPIResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var names []string
+		for _, token := range response.Data.Tokens {
+			names = append(names, token.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: expected tokens %v, got %v", tt.query, tt.want, names)
+		}
+	}
+
+	// 更新时整体替换标签，空对象清除标签
+	update := func(body string) proxyconfig.AccessToken {
+		req := httptest.NewRequest("PUT", "/config/proxy/"+config.ID+"/tokens/"+created.Data.ID, strings.NewReader(body))
+		req.Header.Set("X-Log-Secret", "test-secret")
+		w := httptest.NewRecorder()
+		handler.HandleTokenAPI(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		token, _ := handler.storage.GetTokenByID(config.ID, created.Data.ID)
+		return *token
+	}
+	if token := update(`{"tags":{"env":"staging"}}`); len(token.Tags) != 1 || token.Tags["env"] != "staging" {
+		t.Errorf("Expected tags to be replaced, got %v", token.Tags)
+	}
+	if token := update(`{"description":"no tag change"}`); token.Tags["env"] != "staging" {
+		t.Errorf("Expected tags to be kept when omitted, got %v", token.Tags)
+	}
+	if token := update(`{"tags":{}}`); len(token.Tags) != 0 {
+		t.Errorf("Expected tags to be cleared, got %v", token.Tags)
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"privacygateway/internal/tracing"
+)
+
+// startProxySpan 为代理请求开始链路追踪span，返回的finish需在请求结束时调用
+//
+// 未启用追踪时span为nil，w原样返回，finish为空操作。
+func startProxySpan(w http.ResponseWriter, r *http.Request, targetURL *url.URL, configID string) (http.ResponseWriter, *tracing.Span, func()) {
+	span := tracing.StartSpan(r, "proxy "+targetURL.Host)
+	if span == nil {
+		return w, nil, func() {}
+	}
+
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("server.address", targetURL.Host)
+	span.SetAttribute("url.path", targetURL.Path)
+	if configID != "" {
+		span.SetAttribute("privacygateway.config_id", configID)
+	}
+
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	return sw, span, func() {
+		span.SetAttribute("http.response.status_code", sw.status)
+		span.SetAttribute("duration_ms", time.Since(span.StartTime).Milliseconds())
+		if sw.status >= http.StatusInternalServerError {
+			span.SetFailed(http.StatusText(sw.status))
+		}
+		span.End()
+	}
+}
+
+// statusWriter 记录响应状态码的ResponseWriter
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader 记录状态码
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	if !sw.wroteHeader {
+		sw.status = statusCode
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush 支持流式响应
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 返回被包装的ResponseWriter（供http.ResponseController使用）
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
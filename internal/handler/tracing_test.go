@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"privacygateway/internal/tracing"
+)
+
+func TestHTTPProxyWithTokenAuth_Tracing(t *testing.T) {
+	cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+
+	exporter := tracing.NewInMemoryExporter()
+	tracing.SetTracer(tracing.NewTracer(exporter))
+	defer tracing.SetTracer(nil)
+
+	var upstreamTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	doRequest := func(path string) {
+		req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+path+"&config_id="+proxyConfig.ID, nil)
+		req.Header.Set("X-Proxy-Token", tokenValue)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		HTTPProxyWithTokenAuth(httptest.NewRecorder(), req, cfg, log, nil, storage, nil)
+	}
+
+	doRequest("/ok")
+	doRequest("/fail")
+
+	// 每个请求一个span
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	span := spans[0]
+	host := strings.TrimPrefix(upstream.URL, "http://")
+	if span.Name != "proxy "+host {
+		t.Errorf("Expected span named after target host, got %q", span.Name)
+	}
+	if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("Expected span to continue incoming trace, got %s/%s", span.TraceID, span.ParentSpanID)
+	}
+	if span.Attributes["http.response.status_code"] != http.StatusOK || span.Attributes["privacygateway.config_id"] != proxyConfig.ID {
+		t.Errorf("Unexpected span attributes: %v", span.Attributes)
+	}
+	if span.Failed || span.EndTime.Before(span.StartTime) {
+		t.Errorf("Expected successful finished span, got %+v", span)
+	}
+
+	// 上游收到以网关span为父span的traceparent
+	if want := "00-" + spans[1].TraceID + "-" + spans[1].SpanID + "-01"; upstreamTraceparent != want {
+		t.Errorf("Expected upstream traceparent %q, got %q", want, upstreamTraceparent)
+	}
+
+	// 上游5xx响应标记为失败
+	if !spans[1].Failed || spans[1].Attributes["http.response.status_code"] != http.StatusBadGateway {
+		t.Errorf("Expected failed span for 502 response, got %+v", spans[1])
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"time"
+
+	"privacygateway/internal/proxy"
+	"privacygateway/internal/proxyconfig"
+)
+
+// transportPoolOverride 返回代理配置覆盖的连接池和响应头超时设置，未覆盖时返回nil（使用全局设置）
+func transportPoolOverride(routeConfig *proxyconfig.ProxyConfig) *proxy.TransportPool {
+	if routeConfig == nil || (routeConfig.MaxIdleConnsPerHost == 0 && routeConfig.IdleConnTimeout == 0 &&
+		!routeConfig.DisableKeepAlives && routeConfig.ResponseHeaderTimeout == 0) {
+		return nil
+	}
+	return &proxy.TransportPool{
+		MaxIdleConnsPerHost:   routeConfig.MaxIdleConnsPerHost,
+		IdleConnTimeout:       time.Duration(routeConfig.IdleConnTimeout) * time.Second,
+		DisableKeepAlives:     routeConfig.DisableKeepAlives,
+		ResponseHeaderTimeout: time.Duration(routeConfig.ResponseHeaderTimeout) * time.Second,
+	}
+}
+
+// upstreamOptions 返回按配置隔离的上游连接选项：mTLS客户端证书、自定义CA（配置更新后自动重新加载）及连接池、超时覆盖
+func upstreamOptions(routeConfig *proxyconfig.ProxyConfig) (*proxy.UpstreamOptions, error) {
+	tlsConfig, err := routeConfig.ClientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &proxy.UpstreamOptions{Key: routeConfig.ID, TLS: tlsConfig, Pool: transportPoolOverride(routeConfig)}, nil
+}
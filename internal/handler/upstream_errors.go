@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/metrics"
+)
+
+// gatewayMetrics 代理处理过程中记录指标的收集器（为nil时不记录）
+var gatewayMetrics atomic.Pointer[metrics.Metrics]
+
+// SetMetrics 设置代理处理过程中使用的指标收集器（镜像请求结果、上游错误分类）
+func SetMetrics(m *metrics.Metrics) {
+	gatewayMetrics.Store(m)
+}
+
+// recordUpstreamError 按分类记录上游错误
+func recordUpstreamError(category metrics.UpstreamErrorCategory) {
+	if m := gatewayMetrics.Load(); m != nil {
+		m.RecordUpstreamError(category)
+	}
+}
+
+// upstreamFailure 上游请求失败的分类及返回给客户端的错误
+type upstreamFailure struct {
+	category metrics.UpstreamErrorCategory
+	status   int
+	code     string
+	message  string
+}
+
+// classifyUpstreamError 区分上游请求失败的原因：DNS解析、TLS握手、超时、连接被拒绝或其他连接错误
+//
+// 超时按类型区分错误代码：等待响应头超时（response_header_timeout）、读空闲超时（read_idle_timeout）
+// 和整体请求超时，三者都返回504并计入timeout分类。
+func classifyUpstreamError(err error) upstreamFailure {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr):
+		return upstreamFailure{metrics.UpstreamErrorDNS, http.StatusBadGateway, errCodeUpstreamDNS, "Upstream host could not be resolved"}
+	case errors.Is(err, errUpstreamIdleTimeout):
+		return upstreamFailure{metrics.UpstreamErrorTimeout, http.StatusGatewayTimeout, errCodeIdleTimeout, "Upstream sent no data within the read idle timeout"}
+	case isResponseHeaderTimeout(err):
+		return upstreamFailure{metrics.UpstreamErrorTimeout, http.StatusGatewayTimeout, errCodeHeaderTimeout, "Upstream did not send response headers in time"}
+	case isTimeoutError(err):
+		return upstreamFailure{metrics.UpstreamErrorTimeout, http.StatusGatewayTimeout, errCodeUpstreamTimeout, "Upstream request timed out"}
+	case isTLSError(err):
+		return upstreamFailure{metrics.UpstreamErrorTLS, http.StatusBadGateway, errCodeUpstreamTLS, "Upstream TLS handshake failed"}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return upstreamFailure{metrics.UpstreamErrorConnectionRefused, http.StatusBadGateway, errCodeUpstreamRefused, "Upstream connection refused"}
+	default:
+		return upstreamFailure{metrics.UpstreamErrorConnection, http.StatusBadGateway, errCodeUpstreamError, "Upstream request failed"}
+	}
+}
+
+// isTLSError 检查错误是否由TLS握手或证书校验失败引起
+//
+// 对端发送的TLS告警（如handshake failure）没有导出的错误类型，按错误信息判断。
+func isTLSError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "tls: ") || strings.Contains(message, "x509: ") ||
+		strings.Contains(message, "HTTP response to HTTPS client")
+}
+
+// recordUpstreamStatus 上游返回5xx时记录错误分类，并在访问日志中标记error_code
+func recordUpstreamStatus(capture *accesslog.ResponseCapture, status int) {
+	if status < http.StatusInternalServerError {
+		return
+	}
+	recordUpstreamError(metrics.UpstreamError5xx)
+	if capture != nil {
+		capture.SetErrorCode(errCodeUpstream5xx)
+	}
+}
+
+// findResponseCapture 沿Unwrap链查找访问日志捕获器，未记录访问日志时返回nil
+func findResponseCapture(w http.ResponseWriter) *accesslog.ResponseCapture {
+	for current := w; current != nil; {
+		if capture, ok := current.(*accesslog.ResponseCapture); ok {
+			return capture
+		}
+		unwrapper, ok := current.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		current = unwrapper.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/metrics"
+)
+
+func TestHTTPProxyWithTokenAuth_UpstreamErrorCategories(t *testing.T) {
+	// 已关闭的监听地址：连接被拒绝
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	refusedURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	// 自签名证书的上游：TLS证书校验失败
+	tlsUpstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer tlsUpstream.Close()
+
+	// 上游返回503：响应原样返回，访问日志中标记UPSTREAM_5XX
+	failingUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failingUpstream.Close()
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantCode   string
+		category   string
+	}{
+		{"connection refused", refusedURL, http.StatusBadGateway, errCodeUpstreamRefused, "connection_refused"},
+		{"tls failure", tlsUpstream.URL, http.StatusBadGateway, errCodeUpstreamTLS, "tls"},
+		{"upstream 5xx", failingUpstream.URL, http.StatusServiceUnavailable, errCodeUpstream5xx, "upstream_5xx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+			cfg.LogMaxEntries = 100
+			cfg.LogMaxBodySize = 1024
+			cfg.LogRetentionHours = 1
+			cfg.LogMaxMemoryMB = 10
+
+			recorder, err := accesslog.NewRecorder(cfg, log)
+			if err != nil {
+				t.Fatalf("Failed to create recorder: %v", err)
+			}
+			defer recorder.Close()
+
+			m := metrics.NewMetrics()
+			SetMetrics(m)
+			defer SetMetrics(nil)
+
+			req := httptest.NewRequest("GET", "/proxy?target="+tt.target+"/data&config_id="+proxyConfig.ID, nil)
+			req.Header.Set("X-Proxy-Token", tokenValue)
+			w := httptest.NewRecorder()
+			HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+
+			if tt.wantCode == errCodeUpstream5xx {
+				if w.Code != tt.wantStatus {
+					t.Fatalf("Expected upstream status %d to be passed through, got %d", tt.wantStatus, w.Code)
+				}
+			} else {
+				assertProxyError(t, w, tt.wantStatus, tt.wantCode)
+			}
+
+			// 每个分类单独计数
+			for category, count := range m.GetSnapshot().UpstreamErrors {
+				want := int64(0)
+				if category == tt.category {
+					want = 1
+				}
+				if count != want {
+					t.Errorf("Expected upstream_errors[%s]=%d, got %d", category, want, count)
+				}
+			}
+
+			// 访问日志记录错误代码
+			deadline := time.Now().Add(time.Second)
+			for {
+				logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+				if err == nil && len(logs.Logs) > 0 {
+					if code := logs.Logs[0].ErrorCode; code != tt.wantCode {
+						t.Errorf("Expected logged error_code %s, got %q", tt.wantCode, code)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Expected access log entry to be recorded")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestClassifyUpstreamError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		status   int
+		code     string
+		category metrics.UpstreamErrorCategory
+	}{
+		{"dns", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "missing.invalid", IsNotFound: true}}, http.StatusBadGateway, errCodeUpstreamDNS, metrics.UpstreamErrorDNS},
+		{"timeout", &net.OpError{Op: "dial", Err: timeoutError{}}, http.StatusGatewayTimeout, errCodeUpstreamTimeout, metrics.UpstreamErrorTimeout},
+		{"tls alert", fmt.Errorf("remote error: tls: handshake failure"), http.StatusBadGateway, errCodeUpstreamTLS, metrics.UpstreamErrorTLS},
+		{"other", fmt.Errorf("connection reset by peer"), http.StatusBadGateway, errCodeUpstreamError, metrics.UpstreamErrorConnection},
+	}
+
+	for _, tt := range tests {
+		failure := classifyUpstreamError(tt.err)
+		if failure.status != tt.status || failure.code != tt.code || failure.category != tt.category {
+			t.Errorf("%s: expected %d %s %s, got %d %s %s", tt.name, tt.status, tt.code, tt.category, failure.status, failure.code, failure.category)
+		}
+	}
+}
+
+// timeoutError 模拟超时的网络错误
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
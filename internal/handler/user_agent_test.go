@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+)
+
+func TestHTTPProxyWithTokenAuth_UserAgentOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		want     string
+	}{
+		{"override replaces client UA", "PrivacyGateway/1.0", "PrivacyGateway/1.0"},
+		{"pass-through keeps client UA", "", "client-agent/2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, log, storage, proxyConfig, tokenValue := setupProxyIntegrationTest()
+			cfg.LogMaxEntries = 100
+			cfg.LogMaxBodySize = 1024
+			cfg.LogRetentionHours = 1
+			cfg.LogMaxMemoryMB = 10
+
+			recorder, err := accesslog.NewRecorder(cfg, log)
+			if err != nil {
+				t.Fatalf("Failed to create recorder: %v", err)
+			}
+			defer recorder.Close()
+
+			updated := *proxyConfig
+			updated.UserAgentOverride = tt.override
+			storage.Update(proxyConfig.ID, &updated)
+
+			var received string
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.UserAgent()
+				w.Write([]byte("ok"))
+			}))
+			defer upstream.Close()
+
+			req := httptest.NewRequest("GET", "/proxy?target="+upstream.URL+"/data&config_id="+proxyConfig.ID, nil)
+			req.Header.Set("X-Proxy-Token", tokenValue)
+			req.Header.Set("User-Agent", "client-agent/2.0")
+			w := httptest.NewRecorder()
+			HTTPProxyWithTokenAuth(w, req, cfg, log, recorder, storage, nil)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if received != tt.want {
+				t.Errorf("Expected upstream User-Agent %q, got %q", tt.want, received)
+			}
+
+			// 访问日志记录实际发送给上游的User-Agent
+			deadline := time.Now().Add(time.Second)
+			for {
+				logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+				if err == nil && len(logs.Logs) > 0 {
+					if ua := logs.Logs[0].UserAgent; ua != tt.want {
+						t.Errorf("Expected logged User-Agent %q, got %q", tt.want, ua)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Expected access log entry to be recorded")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
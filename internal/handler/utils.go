@@ -1,10 +1,17 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"net/url"
 	"strings"
+
+	"privacygateway/internal/proxyconfig"
 )
 
+// gatewayQueryParams 网关自身使用的查询参数（认证和路由），永远不转发给上游
+var gatewayQueryParams = []string{"secret", "token", "config_id"}
+
 // IsSensitiveHeader 检查一个头信息是否是敏感的（不区分大小写）
 func IsSensitiveHeader(headerKey string, sensitiveList []string) bool {
 	lowerHeaderKey := strings.ToLower(headerKey)
@@ -38,29 +45,41 @@ func isCORSHeader(headerKey string) bool {
 
 // isAuthorizedForProxy 检查代理访问权限（保留向后兼容）
 // 注意：这个函数保留用于向后兼容，新代码应该使用 ProxyAuthenticator
-func isAuthorizedForProxy(r *http.Request, adminSecret string) bool {
-	if adminSecret == "" {
+func isAuthorizedForProxy(r *http.Request, adminSecrets []string) bool {
+	if len(adminSecrets) == 0 {
 		return false
 	}
 
 	// 检查请求头
-	if secret := r.Header.Get("X-Log-Secret"); secret == adminSecret {
+	if matchAdminSecret(r.Header.Get("X-Log-Secret"), adminSecrets) {
 		return true
 	}
 
 	// 检查查询参数（向后兼容）
-	if secret := r.URL.Query().Get("secret"); secret == adminSecret {
-		return true
-	}
+	return matchAdminSecret(r.URL.Query().Get("secret"), adminSecrets)
+}
 
-	return false
+// matchAdminSecret 以常量时间比较请求提供的密钥与全部有效的管理密钥（当前密钥及轮换窗口内的旧密钥）
+//
+// 不在首次匹配时提前返回，比较耗时不泄露命中的是哪一个密钥。
+func matchAdminSecret(candidate string, adminSecrets []string) bool {
+	if candidate == "" {
+		return false
+	}
+	matched := 0
+	for _, secret := range adminSecrets {
+		if secret != "" {
+			matched |= subtle.ConstantTimeCompare([]byte(candidate), []byte(secret))
+		}
+	}
+	return matched == 1
 }
 
 // isAuthorizedForProxyWithToken 检查代理访问权限（支持令牌认证）
-func isAuthorizedForProxyWithToken(r *http.Request, configID string, authenticator *ProxyAuthenticator, adminSecret string) *AuthResult {
+func isAuthorizedForProxyWithToken(r *http.Request, configID string, authenticator *ProxyAuthenticator, adminSecrets []string) *AuthResult {
 	if authenticator == nil {
 		// 回退到旧的认证方式
-		if isAuthorizedForProxy(r, adminSecret) {
+		if isAuthorizedForProxy(r, adminSecrets) {
 			return &AuthResult{
 				Authenticated: true,
 				Method:        "admin",
@@ -99,3 +118,34 @@ func getClientIP(r *http.Request) string {
 	}
 	return r.RemoteAddr
 }
+
+// filterForwardedQuery 过滤转发给上游的查询参数
+//
+// 始终移除网关自身使用的参数，routeConfig非nil时再按配置的允许/禁止列表过滤。
+// 没有参数被移除时原样返回target，保留原始的参数顺序和编码。
+func filterForwardedQuery(target *url.URL, routeConfig *proxyconfig.ProxyConfig) *url.URL {
+	if target.RawQuery == "" {
+		return target
+	}
+
+	query := target.Query()
+	removed := false
+	for _, name := range gatewayQueryParams {
+		if _, ok := query[name]; ok {
+			query.Del(name)
+			removed = true
+		}
+	}
+	if routeConfig != nil {
+		var filtered bool
+		query, filtered = routeConfig.FilterQuery(query)
+		removed = removed || filtered
+	}
+	if !removed {
+		return target
+	}
+
+	filteredURL := *target
+	filteredURL.RawQuery = query.Encode()
+	return &filteredURL
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"privacygateway/internal/config"
 	"privacygateway/internal/logger"
 	"privacygateway/internal/proxy"
+	"privacygateway/internal/proxyconfig"
 
 	"github.com/gorilla/websocket"
 )
@@ -22,38 +24,77 @@ var upgrader = websocket.Upgrader{
 }
 
 // WebSocket handles WebSocket proxying with optional upstream proxy support.
-func WebSocket(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, recorder *accesslog.Recorder) {
+//
+// 请求携带config_id且该配置启用了log_websocket_frames时，转发的帧会记录在会话的访问日志中。
+func WebSocket(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *logger.Logger, recorder *accesslog.Recorder, storage proxyconfig.Storage) {
 	startTime := time.Now()
 	var statusCode int = 101 // WebSocket upgrade status code
+	var frames *accesslog.FrameLog
 
 	// 记录WebSocket连接日志
 	defer func() {
 		if recorder != nil {
 			duration := time.Since(startTime)
-			recorder.RecordRequest(r, statusCode, "", duration, 0, "/ws")
+			recorder.RecordWebSocket(r, statusCode, duration, frames)
 		}
 	}()
+
+	// 按配置检查允许的方法并启用帧记录
+	var routeConfig *proxyconfig.ProxyConfig
+	if configID := r.URL.Query().Get("config_id"); configID != "" && storage != nil {
+		var err error
+		routeConfig, err = storage.GetByID(configID)
+		if err != nil {
+			statusCode = http.StatusNotFound
+			writeProxyError(w, http.StatusNotFound, errCodeConfigNotFound, "Config not found")
+			return
+		}
+		if !routeConfig.AllowsMethod(r.Method) {
+			statusCode = http.StatusMethodNotAllowed
+			writeMethodNotAllowedResponse(w, routeConfig)
+			return
+		}
+		if routeConfig.LogWebSocketFrames && recorder != nil {
+			frames = accesslog.NewFrameLog(cfg.LogMaxBodySize)
+		}
+	}
 	targetURLStr := r.URL.Query().Get("target")
 	if targetURLStr == "" {
 		statusCode = http.StatusBadRequest
-		http.Error(w, "'target' query parameter is required for WebSocket proxy", http.StatusBadRequest)
+		writeProxyError(w, http.StatusBadRequest, errCodeMissingTarget, "'target' query parameter is required for WebSocket proxy")
 		return
 	}
 
-	// 获取代理配置
-	proxyConfig, err := proxy.GetConfig(r, cfg.DefaultProxy)
+	// 获取出站代理配置（请求指定的代理需通过安全校验）
+	proxyConfig, status, err := resolveOutboundProxy(r, cfg, nil)
 	if err != nil {
-		statusCode = http.StatusBadRequest
-		log.Error("failed to parse proxy config", "error", err)
-		http.Error(w, "Invalid proxy configuration", http.StatusBadRequest)
+		statusCode = status
+		log.Error("failed to resolve proxy config", "error", err)
+		writeOutboundProxyError(w, status)
 		return
 	}
 
-	// 验证代理配置安全性
-	if err := proxy.Validate(proxyConfig, cfg.ProxyWhitelist, cfg.AllowPrivateIP); err != nil {
+	// 检查目标地址访问策略（SSRF防护），ws/wss按http/https协议检查
+	targetURL, err := url.Parse(targetURLStr)
+	if err != nil || targetURL.Host == "" {
+		statusCode = http.StatusBadRequest
+		writeProxyError(w, http.StatusBadRequest, errCodeInvalidTarget, "Invalid target URL")
+		return
+	}
+	// 移除网关自身使用的查询参数，并按配置过滤转发的查询参数
+	targetURL = filterForwardedQuery(targetURL, routeConfig)
+	targetURLStr = targetURL.String()
+
+	checkURL := *targetURL
+	switch checkURL.Scheme {
+	case "ws":
+		checkURL.Scheme = "http"
+	case "wss":
+		checkURL.Scheme = "https"
+	}
+	targetPolicy := proxy.NewTargetPolicy(cfg, nil, nil)
+	if !checkProxyTarget(w, r, log, targetPolicy, &checkURL) {
 		statusCode = http.StatusForbidden
-		log.Error("proxy validation failed", "error", err)
-		http.Error(w, "Proxy not allowed", http.StatusForbidden)
 		return
 	}
 
@@ -77,30 +118,33 @@ func WebSocket(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 		requestHeader.Add("Sec-WebSocket-Extensions", extensions)
 	}
 
-	// 创建WebSocket拨号器，支持代理
-	dialer := &websocket.Dialer{}
+	// 创建WebSocket拨号器，支持代理；直连时在建立连接前检查实际IP
+	dialer := &websocket.Dialer{
+		NetDialContext: targetPolicy.DialContext,
+	}
 
 	// 如果有代理配置，设置代理
 	if proxyConfig != nil && proxyConfig.URL != "" {
 		proxyURL, err := url.Parse(proxyConfig.URL)
 		if err != nil {
 			log.Error("failed to parse proxy URL", "error", err)
-			http.Error(w, "Invalid proxy URL", http.StatusBadRequest)
+			writeProxyError(w, http.StatusBadRequest, errCodeInvalidProxy, "Invalid proxy URL")
 			return
 		}
 
 		switch proxyConfig.Type {
 		case "http", "https":
-			// HTTP代理
-			dialer.Proxy = http.ProxyURL(proxyURL)
+			// HTTP代理（NO_PROXY命中的目标直连，仍需通过目标访问策略）
+			dialer.Proxy = proxy.ProxyFunc(proxyURL, proxyConfig.NoProxy)
+			dialer.NetDialContext = proxy.DirectDialContext(targetPolicy, proxyURL)
 		case "socks5":
 			// SOCKS5代理 - 暂时不支持，因为WebSocket的SOCKS5代理实现比较复杂
 			log.Error("SOCKS5 proxy not yet supported for WebSocket", "proxy_url", proxyConfig.URL)
-			http.Error(w, "SOCKS5 proxy not yet supported for WebSocket", http.StatusNotImplemented)
+			writeProxyError(w, http.StatusNotImplemented, errCodeProxyUnsupported, "SOCKS5 proxy not yet supported for WebSocket")
 			return
 		default:
 			log.Error("unsupported proxy type for WebSocket", "type", proxyConfig.Type)
-			http.Error(w, "Unsupported proxy type for WebSocket", http.StatusBadRequest)
+			writeProxyError(w, http.StatusBadRequest, errCodeProxyUnsupported, "Unsupported proxy type for WebSocket")
 			return
 		}
 	}
@@ -108,8 +152,14 @@ func WebSocket(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 	// Connect to the target WebSocket server with the prepared headers.
 	targetConn, _, err := dialer.Dial(targetURLStr, requestHeader)
 	if err != nil {
+		if errors.Is(err, proxy.ErrTargetBlocked) {
+			statusCode = http.StatusForbidden
+			log.Warn("WebSocket target blocked at connect time", "target", targetURLStr, "error", err)
+			writeTargetBlockedResponse(w, err)
+			return
+		}
 		log.Error("failed to dial target WebSocket server", "error", err)
-		http.Error(w, "could not connect to target WebSocket server", http.StatusBadGateway)
+		writeUpstreamError(w, err)
 		return
 	}
 	defer targetConn.Close()
@@ -138,6 +188,7 @@ func WebSocket(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 				}
 				return
 			}
+			frames.Add(accesslog.FrameDirectionTargetToClient, messageType, p)
 			if err := clientConn.WriteMessage(messageType, p); err != nil {
 				log.Error("error writing to client", "error", err)
 				return
@@ -158,6 +209,7 @@ func WebSocket(w http.ResponseWriter, r *http.Request, cfg *config.Config, log *
 				}
 				return
 			}
+			frames.Add(accesslog.FrameDirectionClientToTarget, messageType, p)
 			if err := targetConn.WriteMessage(messageType, p); err != nil {
 				log.Error("error writing to target", "error", err)
 				return
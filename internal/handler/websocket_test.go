@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocket_LogFrames(t *testing.T) {
+	cfg, log, storage, proxyConfig, _ := setupProxyIntegrationTest()
+	cfg.LogMaxEntries = 100
+	cfg.LogMaxBodySize = 16
+	cfg.LogRetentionHours = 1
+	cfg.LogMaxMemoryMB = 10
+
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	framesConfig := *proxyConfig
+	framesConfig.LogWebSocketFrames = true
+	storage.Update(proxyConfig.ID, &framesConfig)
+
+	// 回显上游
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, p, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, p)
+		}
+	}))
+	defer upstream.Close()
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WebSocket(w, r, cfg, log, recorder, storage)
+	}))
+	defer gateway.Close()
+
+	target := "ws" + strings.TrimPrefix(upstream.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(gateway.URL, "http")+"/ws?target="+target+"&config_id="+proxyConfig.ID, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect through gateway: %v", err)
+	}
+
+	messages := []struct {
+		messageType int
+		payload     []byte
+	}{
+		{websocket.TextMessage, []byte("hello")},
+		{websocket.BinaryMessage, []byte{0x00, 0x01, 0x02, 0xff}},
+		{websocket.TextMessage, []byte("a long text message over the cap")},
+	}
+	for _, message := range messages {
+		if err := conn.WriteMessage(message.messageType, message.payload); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+		if _, p, err := conn.ReadMessage(); err != nil || string(p) != string(message.payload) {
+			t.Fatalf("Expected echo %q, got %q (err=%v)", message.payload, p, err)
+		}
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		logs, err := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10})
+		if err == nil && len(logs.Logs) > 0 {
+			frames := logs.Logs[0].WebSocketFrames
+			if len(frames) != 6 {
+				t.Fatalf("Expected 6 frames (3 each direction), got %+v", frames)
+			}
+
+			clientFrames := make([]accesslog.WebSocketFrame, 0, 3)
+			for _, frame := range frames {
+				if frame.Direction == accesslog.FrameDirectionClientToTarget {
+					clientFrames = append(clientFrames, frame)
+				}
+			}
+			if len(clientFrames) != 3 {
+				t.Fatalf("Expected 3 client frames, got %+v", clientFrames)
+			}
+
+			// 文本帧记录内容
+			if clientFrames[0].Payload != "hello" || clientFrames[0].Opcode != websocket.TextMessage {
+				t.Errorf("Expected text frame to be captured, got %+v", clientFrames[0])
+			}
+			// 二进制帧只记录类型和大小
+			if clientFrames[1].Payload != "" || clientFrames[1].Size != 4 || clientFrames[1].Opcode != websocket.BinaryMessage {
+				t.Errorf("Expected binary frame to be summarized, got %+v", clientFrames[1])
+			}
+			// 超长文本帧按LogMaxBodySize截断
+			if clientFrames[2].Payload != "a long text mess" || !clientFrames[2].Truncated || clientFrames[2].Size != 32 {
+				t.Errorf("Expected long text frame to be truncated, got %+v", clientFrames[2])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected WebSocket session to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,146 @@
+package logviewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+)
+
+// newAggregateTestHandler 记录100条请求：处理时长1..100ms，每10条一个500、其余每5条一个404，目标主机交替
+func newAggregateTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	cfg := &config.Config{
+		LogMaxEntries:     200,
+		LogMaxMemoryMB:    10,
+		LogRetentionHours: 24,
+		LogMaxBodySize:    1024,
+	}
+	log := logger.New()
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	t.Cleanup(func() { recorder.Close() })
+
+	handler, err := NewHandler(recorder, "correctsecret", log)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	for i := 1; i <= 100; i++ {
+		status := http.StatusOK
+		switch {
+		case i%10 == 0:
+			status = http.StatusInternalServerError
+		case i%5 == 0:
+			status = http.StatusNotFound
+		}
+		host := "a.example.com"
+		if i%4 == 0 {
+			host = "b.example.com"
+		}
+		req := httptest.NewRequest("GET", fmt.Sprintf("/proxy?target=https://%s/items/%d", host, i), nil)
+		recorder.RecordRequest(req, status, "", time.Duration(i)*time.Millisecond, 0, "/proxy")
+	}
+
+	// 等待异步写入
+	deadline := time.Now().Add(time.Second)
+	for {
+		if logs, _ := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10}); logs != nil && logs.Total == 100 {
+			return handler
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 100 log entries to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// queryAggregateAPI 调用聚合统计API并解析响应
+func queryAggregateAPI(t *testing.T, handler *Handler, query string) (int, accesslog.AggregateStats) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/logs/api/aggregate?"+query, nil)
+	req.Header.Set("X-Log-Secret", "correctsecret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var stats accesslog.AggregateStats
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return w.Code, stats
+}
+
+func TestHandler_APIAggregate(t *testing.T) {
+	handler := newAggregateTestHandler(t)
+
+	code, stats := queryAggregateAPI(t, handler, "")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if stats.Total != 100 {
+		t.Errorf("Expected total 100, got %d", stats.Total)
+	}
+
+	// 状态码分布
+	expected := map[string]int{"2xx": 80, "3xx": 0, "4xx": 10, "5xx": 10, "other": 0}
+	for class, count := range expected {
+		if stats.StatusClasses[class] != count {
+			t.Errorf("Expected %d requests in %s, got %d", count, class, stats.StatusClasses[class])
+		}
+	}
+
+	// 延迟分位数（最近秩法）
+	if stats.Latency.P50 != 50 || stats.Latency.P95 != 95 || stats.Latency.P99 != 99 || stats.Latency.Max != 100 {
+		t.Errorf("Expected p50=50 p95=95 p99=99 max=100, got %+v", stats.Latency)
+	}
+	if stats.Latency.Mean != 50.5 {
+		t.Errorf("Expected mean 50.5, got %v", stats.Latency.Mean)
+	}
+
+	// 目标主机按请求数降序
+	if len(stats.Hosts) != 2 || stats.Hosts[0].Host != "a.example.com" || stats.Hosts[0].Count != 75 || stats.Hosts[1].Count != 25 {
+		t.Errorf("Expected hosts a.example.com=75 b.example.com=25, got %+v", stats.Hosts)
+	}
+
+	// 时间分桶
+	bucketTotal := 0
+	for _, bucket := range stats.Timeline {
+		bucketTotal += bucket.Count
+	}
+	if bucketTotal != 100 || stats.IntervalSeconds != 300 {
+		t.Errorf("Expected 100 requests in 5m buckets, got %d in %ds buckets", bucketTotal, stats.IntervalSeconds)
+	}
+}
+
+func TestHandler_APIAggregateWithFilter(t *testing.T) {
+	handler := newAggregateTestHandler(t)
+
+	code, stats := queryAggregateAPI(t, handler, "status=5xx&interval=1m")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if stats.Total != 10 || stats.StatusClasses["5xx"] != 10 {
+		t.Errorf("Expected only 10 server errors, got total=%d classes=%v", stats.Total, stats.StatusClasses)
+	}
+	if stats.Latency.Max != 100 || stats.Latency.P50 != 50 {
+		t.Errorf("Expected p50=50 max=100 for server errors, got %+v", stats.Latency)
+	}
+
+	for _, query := range []string{"interval=abc", "interval=1s", "interval=48h", "min_duration_ms=-1"} {
+		if code, _ := queryAggregateAPI(t, handler, query); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, code)
+		}
+	}
+}
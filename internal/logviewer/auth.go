@@ -13,7 +13,8 @@ import (
 
 // AuthConfig 认证配置
 type AuthConfig struct {
-	Secret string // 查看密钥
+	Secret          string   // 查看密钥
+	PreviousSecrets []string // 轮换窗口内仍然有效的旧密钥
 }
 
 // AuthResult 认证结果
@@ -40,11 +41,18 @@ type SecretAuthenticator struct {
 	config *AuthConfig
 }
 
-// NewSecretAuthenticator 创建新的密钥认证器
-func NewSecretAuthenticator(secret string) *SecretAuthenticator {
+// NewSecretAuthenticator 创建新的密钥认证器，previous为轮换窗口内仍然有效的旧密钥
+func NewSecretAuthenticator(secret string, previous ...string) *SecretAuthenticator {
+	var previousSecrets []string
+	for _, p := range previous {
+		if p != "" && p != secret {
+			previousSecrets = append(previousSecrets, p)
+		}
+	}
 	return &SecretAuthenticator{
 		config: &AuthConfig{
-			Secret: secret,
+			Secret:          secret,
+			PreviousSecrets: previousSecrets,
 		},
 	}
 }
@@ -70,7 +78,7 @@ func (sa *SecretAuthenticator) Authenticate(r *http.Request) *AuthResult {
 	}
 
 	// 使用常量时间比较防止时序攻击
-	if subtle.ConstantTimeCompare([]byte(secret), []byte(sa.config.Secret)) == 1 {
+	if sa.matchSecret(secret) {
 		return &AuthResult{
 			Authenticated: true,
 			Error:         "",
@@ -85,6 +93,15 @@ func (sa *SecretAuthenticator) Authenticate(r *http.Request) *AuthResult {
 	}
 }
 
+// matchSecret 以常量时间比较候选值与当前密钥及旧密钥，不在首次匹配时提前返回
+func (sa *SecretAuthenticator) matchSecret(candidate string) bool {
+	matched := subtle.ConstantTimeCompare([]byte(candidate), []byte(sa.config.Secret))
+	for _, previous := range sa.config.PreviousSecrets {
+		matched |= subtle.ConstantTimeCompare([]byte(candidate), []byte(previous))
+	}
+	return matched == 1
+}
+
 // RequireAuth 认证中间件
 func (sa *SecretAuthenticator) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -97,7 +114,7 @@ func (sa *SecretAuthenticator) RequireAuth(next http.HandlerFunc) http.HandlerFu
 
 		// 认证成功，设置安全Cookie（如果是通过表单登录）
 		if r.FormValue("secret") != "" {
-			sa.SetSecureCookie(w, r.FormValue("secret"))
+			sa.SetSecureCookie(w, r, r.FormValue("secret"))
 		}
 
 		// 认证成功，继续处理
@@ -548,7 +565,7 @@ func (e *AuthError) Error() string {
 // encryptSecret 加密密钥用于存储在Cookie中
 func (sa *SecretAuthenticator) encryptSecret(plaintext string) string {
 	// 使用配置的密钥作为加密密钥的基础
-	key := sha256.Sum256([]byte(sa.config.Secret + "cookie-encryption"))
+	key := cookieKey(sa.config.Secret)
 
 	block, err := aes.NewCipher(key[:])
 	if err != nil {
@@ -568,35 +585,51 @@ func (sa *SecretAuthenticator) encryptSecret(plaintext string) string {
 	return base64.URLEncoding.EncodeToString(ciphertext)
 }
 
+// cookieKey 由访问密钥派生Cookie加密密钥
+func cookieKey(secret string) [32]byte {
+	return sha256.Sum256([]byte(secret + "cookie-encryption"))
+}
+
 // decryptSecret 解密Cookie中的密钥
+//
+// 密钥轮换后，轮换前登录的Cookie由旧密钥派生的密钥加密：当前密钥解密结果不是有效密钥时依次尝试旧密钥，
+// 使已登录的会话在轮换窗口内保持有效。
 func (sa *SecretAuthenticator) decryptSecret(ciphertext string) string {
 	data, err := base64.URLEncoding.DecodeString(ciphertext)
-	if err != nil {
+	if err != nil || len(data) < aes.BlockSize {
 		return "" // 解密失败返回空
 	}
 
-	key := sha256.Sum256([]byte(sa.config.Secret + "cookie-encryption"))
+	plaintext := decryptWithKey(data, cookieKey(sa.config.Secret))
+	if plaintext == "" || sa.matchSecret(plaintext) {
+		return plaintext
+	}
+	for _, previous := range sa.config.PreviousSecrets {
+		if candidate := decryptWithKey(data, cookieKey(previous)); sa.matchSecret(candidate) {
+			return candidate
+		}
+	}
+	return plaintext
+}
 
+// decryptWithKey 使用指定密钥解密Cookie数据（IV在前），不修改data
+func decryptWithKey(data []byte, key [32]byte) string {
 	block, err := aes.NewCipher(key[:])
 	if err != nil {
 		return ""
 	}
 
-	if len(data) < aes.BlockSize {
-		return ""
-	}
-
 	iv := data[:aes.BlockSize]
-	data = data[aes.BlockSize:]
+	plaintext := make([]byte, len(data)-aes.BlockSize)
 
 	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(data, data)
+	stream.XORKeyStream(plaintext, data[aes.BlockSize:])
 
-	return string(data)
+	return string(plaintext)
 }
 
-// SetSecureCookie 设置安全的Cookie
-func (sa *SecretAuthenticator) SetSecureCookie(w http.ResponseWriter, secret string) {
+// SetSecureCookie 设置安全的Cookie（请求经TLS到达时带Secure标记）
+func (sa *SecretAuthenticator) SetSecureCookie(w http.ResponseWriter, r *http.Request, secret string) {
 	encryptedSecret := sa.encryptSecret(secret)
 
 	cookie := &http.Cookie{
@@ -604,7 +637,7 @@ func (sa *SecretAuthenticator) SetSecureCookie(w http.ResponseWriter, secret str
 		Value:    encryptedSecret,
 		Path:     "/logs",
 		HttpOnly: true,
-		Secure:   false, // 在生产环境中应该设置为true (HTTPS)
+		Secure:   r.TLS != nil,
 		SameSite: http.SameSiteStrictMode,
 		MaxAge:   86400, // 24小时
 	}
@@ -626,11 +659,19 @@ func (sa *SecretAuthenticator) clearServerCookie(w http.ResponseWriter) {
 	http.SetCookie(w, cookie)
 }
 
-// CreateAuthenticator 创建认证器的工厂函数
-func CreateAuthenticator(secret string) (Authenticator, error) {
+// CreateAuthenticator 创建认证器的工厂函数，previous为轮换窗口内仍然有效的旧密钥（为空时忽略）
+func CreateAuthenticator(secret string, previous ...string) (Authenticator, error) {
 	if err := ValidateSecret(secret); err != nil {
 		return nil, err
 	}
+	for _, p := range previous {
+		if p == "" {
+			continue
+		}
+		if err := ValidateSecret(p); err != nil {
+			return nil, err
+		}
+	}
 
-	return NewSecretAuthenticator(secret), nil
+	return NewSecretAuthenticator(secret, previous...), nil
 }
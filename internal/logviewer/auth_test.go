@@ -226,3 +226,57 @@ func TestCookieEncryption(t *testing.T) {
 		t.Error("Expected empty string for invalid encrypted data")
 	}
 }
+
+// TestPreviousSecretRotation 测试密钥轮换窗口内旧密钥和旧Cookie仍然有效
+func TestPreviousSecretRotation(t *testing.T) {
+	before := NewSecretAuthenticator("old-secret-123")
+	oldCookie := before.encryptSecret("old-secret-123")
+
+	auth := NewSecretAuthenticator("new-secret-123", "old-secret-123")
+	for _, secret := range []string{"new-secret-123", "old-secret-123"} {
+		req := httptest.NewRequest("GET", "/logs", nil)
+		req.Header.Set("X-Log-Secret", secret)
+		if result := auth.Authenticate(req); !result.Authenticated {
+			t.Errorf("Expected %q to authenticate, got: %s", secret, result.Error)
+		}
+	}
+
+	// 轮换前登录的Cookie由旧密钥加密，轮换后仍然可以解密并认证
+	req := httptest.NewRequest("GET", "/logs", nil)
+	req.AddCookie(&http.Cookie{Name: "log_secret", Value: oldCookie})
+	if result := auth.Authenticate(req); !result.Authenticated {
+		t.Errorf("Expected cookie from before rotation to authenticate, got: %s", result.Error)
+	}
+
+	// 移除旧密钥后旧Cookie失效
+	req = httptest.NewRequest("GET", "/logs", nil)
+	req.AddCookie(&http.Cookie{Name: "log_secret", Value: oldCookie})
+	if result := NewSecretAuthenticator("new-secret-123").Authenticate(req); result.Authenticated {
+		t.Error("Expected cookie from before rotation to fail once the previous secret is removed")
+	}
+}
+
+// TestSecureCookieFollowsTLS 测试登录Cookie在HTTPS请求下带Secure标记
+func TestSecureCookieFollowsTLS(t *testing.T) {
+	auth := NewSecretAuthenticator("test-secret-123")
+
+	for _, useTLS := range []bool{false, true} {
+		req := httptest.NewRequest("POST", "/logs", nil)
+		if useTLS {
+			req = httptest.NewRequest("POST", "https://gateway.example.com/logs", nil)
+		}
+		w := httptest.NewRecorder()
+		auth.SetSecureCookie(w, req, "test-secret-123")
+
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("Expected 1 cookie, got %d", len(cookies))
+		}
+		if cookies[0].Secure != useTLS {
+			t.Errorf("Expected Secure=%v for TLS=%v, got %v", useTLS, useTLS, cookies[0].Secure)
+		}
+		if !cookies[0].HttpOnly {
+			t.Error("Expected HttpOnly cookie")
+		}
+	}
+}
@@ -13,15 +13,21 @@ import (
 
 // FilterParams 筛选参数
 type FilterParams struct {
-	Domain     string    `json:"domain,omitempty"`      // 域名筛选
-	StatusCode []int     `json:"status_code,omitempty"` // 状态码筛选
-	FromTime   time.Time `json:"from_time,omitempty"`   // 开始时间
-	ToTime     time.Time `json:"to_time,omitempty"`     // 结束时间
-	Page       int       `json:"page"`                  // 页码
-	Limit      int       `json:"limit"`                 // 每页条数
-	SortBy     string    `json:"sort_by,omitempty"`     // 排序字段
-	SortOrder  string    `json:"sort_order,omitempty"`  // 排序方向
-	Search     string    `json:"search,omitempty"`      // 搜索关键词
+	Domain      string    `json:"domain,omitempty"`          // 域名筛选
+	StatusCode  []int     `json:"status_code,omitempty"`     // 状态码筛选
+	Method      []string  `json:"method,omitempty"`          // HTTP方法筛选
+	ClientIP    string    `json:"client_ip,omitempty"`       // 客户端IP筛选（单个IP或CIDR）
+	MinDuration int64     `json:"min_duration_ms,omitempty"` // 最短处理时长（毫秒）
+	MaxDuration int64     `json:"max_duration_ms,omitempty"` // 最长处理时长（毫秒）
+	FromTime    time.Time `json:"from_time,omitempty"`       // 开始时间
+	ToTime      time.Time `json:"to_time,omitempty"`         // 结束时间
+	Page        int       `json:"page"`                      // 页码
+	Limit       int       `json:"limit"`                     // 每页条数
+	SortBy      string    `json:"sort_by,omitempty"`         // 排序字段
+	SortOrder   string    `json:"sort_order,omitempty"`      // 排序方向
+	Search      string    `json:"search,omitempty"`          // 搜索关键词
+	ConfigID    string    `json:"config_id,omitempty"`       // 代理配置ID筛选
+	Slow        bool      `json:"slow,omitempty"`            // 只显示慢请求
 }
 
 // FilterBuilder 筛选器构建器
@@ -55,6 +61,41 @@ func (fb *FilterBuilder) FromRequest(r *http.Request) *FilterBuilder {
 		fb.params.StatusCode = parseStatusCodes(statusStr)
 	}
 
+	// HTTP方法筛选（支持逗号分隔或重复参数）
+	if methods := query["method"]; len(methods) > 0 {
+		fb.params.Method = parseMethods(strings.Join(methods, ","))
+	}
+
+	// 客户端IP筛选
+	if clientIP := query.Get("client_ip"); clientIP != "" {
+		fb.params.ClientIP = strings.TrimSpace(clientIP)
+	}
+
+	// 代理配置筛选
+	if configID := query.Get("config_id"); configID != "" {
+		fb.params.ConfigID = strings.TrimSpace(configID)
+	}
+
+	// 处理时长筛选（毫秒）
+	if minStr := query.Get("min_duration_ms"); minStr != "" {
+		if minDuration, err := strconv.ParseInt(minStr, 10, 64); err == nil {
+			fb.params.MinDuration = minDuration
+		}
+	}
+
+	if maxStr := query.Get("max_duration_ms"); maxStr != "" {
+		if maxDuration, err := strconv.ParseInt(maxStr, 10, 64); err == nil {
+			fb.params.MaxDuration = maxDuration
+		}
+	}
+
+	// 慢请求筛选
+	if slowStr := query.Get("slow"); slowStr != "" {
+		if slow, err := strconv.ParseBool(slowStr); err == nil {
+			fb.params.Slow = slow
+		}
+	}
+
 	// 时间范围筛选
 	if fromStr := query.Get("from"); fromStr != "" {
 		if fromTime, err := parseTime(fromStr); err == nil {
@@ -114,6 +155,37 @@ func (fb *FilterBuilder) StatusCode(codes ...int) *FilterBuilder {
 	return fb
 }
 
+// Method 设置HTTP方法筛选
+func (fb *FilterBuilder) Method(methods ...string) *FilterBuilder {
+	fb.params.Method = parseMethods(strings.Join(methods, ","))
+	return fb
+}
+
+// ClientIP 设置客户端IP筛选（单个IP或CIDR）
+func (fb *FilterBuilder) ClientIP(clientIP string) *FilterBuilder {
+	fb.params.ClientIP = clientIP
+	return fb
+}
+
+// ConfigID 设置代理配置ID筛选
+func (fb *FilterBuilder) ConfigID(configID string) *FilterBuilder {
+	fb.params.ConfigID = configID
+	return fb
+}
+
+// DurationRange 设置处理时长范围（毫秒，0表示不限制）
+func (fb *FilterBuilder) DurationRange(min, max int64) *FilterBuilder {
+	fb.params.MinDuration = min
+	fb.params.MaxDuration = max
+	return fb
+}
+
+// Slow 设置是否只筛选慢请求
+func (fb *FilterBuilder) Slow(slow bool) *FilterBuilder {
+	fb.params.Slow = slow
+	return fb
+}
+
 // TimeRange 设置时间范围
 func (fb *FilterBuilder) TimeRange(from, to time.Time) *FilterBuilder {
 	fb.params.FromTime = from
@@ -157,13 +229,19 @@ func (fb *FilterBuilder) Search(keyword string) *FilterBuilder {
 // Build 构建筛选器
 func (fb *FilterBuilder) Build() *accesslog.LogFilter {
 	return &accesslog.LogFilter{
-		Domain:     fb.params.Domain,
-		StatusCode: fb.params.StatusCode,
-		FromTime:   fb.params.FromTime,
-		ToTime:     fb.params.ToTime,
-		Page:       fb.params.Page,
-		Limit:      fb.params.Limit,
-		Search:     fb.params.Search,
+		Domain:      fb.params.Domain,
+		StatusCode:  fb.params.StatusCode,
+		Method:      fb.params.Method,
+		ClientIP:    fb.params.ClientIP,
+		MinDuration: fb.params.MinDuration,
+		MaxDuration: fb.params.MaxDuration,
+		FromTime:    fb.params.FromTime,
+		ToTime:      fb.params.ToTime,
+		Page:        fb.params.Page,
+		Limit:       fb.params.Limit,
+		Search:      fb.params.Search,
+		ConfigID:    fb.params.ConfigID,
+		Slow:        fb.params.Slow,
 	}
 }
 
@@ -188,6 +266,30 @@ func (fb *FilterBuilder) ToQueryString() string {
 		values.Set("status", strings.Join(statusStrs, ","))
 	}
 
+	if len(fb.params.Method) > 0 {
+		values.Set("method", fb.params.MethodValue())
+	}
+
+	if fb.params.ClientIP != "" {
+		values.Set("client_ip", fb.params.ClientIP)
+	}
+
+	if fb.params.ConfigID != "" {
+		values.Set("config_id", fb.params.ConfigID)
+	}
+
+	if fb.params.MinDuration > 0 {
+		values.Set("min_duration_ms", strconv.FormatInt(fb.params.MinDuration, 10))
+	}
+
+	if fb.params.MaxDuration > 0 {
+		values.Set("max_duration_ms", strconv.FormatInt(fb.params.MaxDuration, 10))
+	}
+
+	if fb.params.Slow {
+		values.Set("slow", "true")
+	}
+
 	if !fb.params.FromTime.IsZero() {
 		values.Set("from", fb.params.FromTime.Format(time.RFC3339))
 	}
@@ -265,6 +367,36 @@ func parseStatusCodes(statusStr string) []int {
 	return codes
 }
 
+// MethodValue 获取逗号分隔的HTTP方法筛选值
+func (p *FilterParams) MethodValue() string {
+	return strings.Join(p.Method, ",")
+}
+
+// parseMethods 解析逗号分隔的HTTP方法（转为大写并去重）
+func parseMethods(methodStr string) []string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(methodStr, ",") {
+		method := strings.ToUpper(strings.TrimSpace(part))
+		if method == "" || seen[method] {
+			continue
+		}
+		seen[method] = true
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// isValidMethod 检查HTTP方法是否有效
+func isValidMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
 // parseTime 解析时间字符串
 func parseTime(timeStr string) (time.Time, error) {
 	// 支持多种时间格式
@@ -344,6 +476,26 @@ func ValidateFilter(params *FilterParams) error {
 		}
 	}
 
+	for _, method := range params.Method {
+		if !isValidMethod(method) {
+			return fmt.Errorf("invalid method: %s", method)
+		}
+	}
+
+	if params.MinDuration < 0 || params.MaxDuration < 0 {
+		return fmt.Errorf("duration filters must not be negative")
+	}
+
+	if params.MaxDuration > 0 && params.MinDuration > params.MaxDuration {
+		return fmt.Errorf("min_duration_ms must not be greater than max_duration_ms")
+	}
+
+	if params.ClientIP != "" {
+		if _, err := accesslog.ParseClientIPFilter(params.ClientIP); err != nil {
+			return fmt.Errorf("invalid client_ip: %s", params.ClientIP)
+		}
+	}
+
 	if params.SortBy != "" && !isValidSortField(params.SortBy) {
 		return fmt.Errorf("invalid sort field: %s", params.SortBy)
 	}
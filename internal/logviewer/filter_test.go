@@ -0,0 +1,265 @@
+package logviewer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/config"
+	"privacygateway/internal/logger"
+)
+
+func TestFilterBuilder_Method(t *testing.T) {
+	req := httptest.NewRequest("GET", "/logs/api?method=post,%20PUT&method=post", nil)
+	builder := NewFilterBuilder().FromRequest(req)
+
+	params := builder.GetParams()
+	if params.MethodValue() != "POST,PUT" {
+		t.Errorf("Expected methods POST,PUT, got %v", params.Method)
+	}
+	if filter := builder.Build(); len(filter.Method) != 2 {
+		t.Errorf("Expected method filter to be passed to LogFilter, got %v", filter.Method)
+	}
+	if query := builder.ToQueryString(); query != "method=POST%2CPUT" {
+		t.Errorf("Expected method in query string, got %s", query)
+	}
+	if err := ValidateFilter(params); err != nil {
+		t.Errorf("Expected valid methods, got %v", err)
+	}
+
+	invalid := NewFilterBuilder().Method("FETCH").GetParams()
+	if err := ValidateFilter(invalid); err == nil || !strings.Contains(err.Error(), "FETCH") {
+		t.Errorf("Expected invalid method error, got %v", err)
+	}
+}
+
+// newFilterTestHandler 创建日志查看处理器，并按顺序记录给定的请求（durations为对应的处理时长，可省略）
+func newFilterTestHandler(t *testing.T, requests []*http.Request, durations ...time.Duration) *Handler {
+	t.Helper()
+
+	cfg := &config.Config{
+		LogMaxEntries:     100,
+		LogMaxMemoryMB:    10,
+		LogRetentionHours: 24,
+		LogMaxBodySize:    1024,
+	}
+	return newFilterTestHandlerWithConfig(t, cfg, requests, durations...)
+}
+
+// newFilterTestHandlerWithConfig 使用指定配置创建日志查看处理器并记录请求
+func newFilterTestHandlerWithConfig(t *testing.T, cfg *config.Config, requests []*http.Request, durations ...time.Duration) *Handler {
+	t.Helper()
+
+	log := logger.New()
+	recorder, err := accesslog.NewRecorder(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	t.Cleanup(func() { recorder.Close() })
+
+	handler, err := NewHandler(recorder, "correctsecret", log)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	for i, req := range requests {
+		duration := time.Millisecond
+		if i < len(durations) {
+			duration = durations[i]
+		}
+		recorder.RecordRequest(req, http.StatusOK, "", duration, 0, "/proxy")
+	}
+
+	// 等待异步写入
+	deadline := time.Now().Add(time.Second)
+	for {
+		if logs, _ := recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10}); logs != nil && logs.Total == len(requests) {
+			return handler
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d log entries to be recorded", len(requests))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// queryLogsAPI 调用日志API并解析响应
+func queryLogsAPI(t *testing.T, handler *Handler, query string) (int, accesslog.LogResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/logs/api?"+query, nil)
+	req.Header.Set("X-Log-Secret", "correctsecret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var response accesslog.LogResponse
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return w.Code, response
+}
+
+func TestFilterBuilder_ConfigID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/logs/api?config_id=%20cfg-1%20", nil)
+	builder := NewFilterBuilder().FromRequest(req)
+
+	if filter := builder.Build(); filter.ConfigID != "cfg-1" {
+		t.Errorf("Expected config_id cfg-1, got %q", filter.ConfigID)
+	}
+	if query := builder.ToQueryString(); !strings.Contains(query, "config_id=cfg-1") {
+		t.Errorf("Expected query string to keep config_id, got %q", query)
+	}
+}
+
+func TestHandler_APIFilterByMethod(t *testing.T) {
+	var requests []*http.Request
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE", "POST"} {
+		requests = append(requests, httptest.NewRequest(method, "/proxy?target=https://api.example.com/items", nil))
+	}
+	handler := newFilterTestHandler(t, requests)
+
+	code, response := queryLogsAPI(t, handler, "method=POST,PUT")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if response.Total != 3 {
+		t.Errorf("Expected 3 POST/PUT entries, got %d", response.Total)
+	}
+	for _, entry := range response.Logs {
+		if entry.Method != "POST" && entry.Method != "PUT" {
+			t.Errorf("Expected only POST or PUT entries, got %s", entry.Method)
+		}
+	}
+
+	// 无效方法返回400
+	if code, _ := queryLogsAPI(t, handler, "method=FETCH"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid method, got %d", code)
+	}
+}
+
+func TestHandler_APIFilterByClientIP(t *testing.T) {
+	var requests []*http.Request
+	for _, remoteAddr := range []string{"203.0.113.7:5000", "203.0.113.200:5000", "198.51.100.1:5000", "[2001:db8::1]:5000", "[2001:db8:1::5]:5000"} {
+		req := httptest.NewRequest("GET", "/proxy?target=https://api.example.com/items", nil)
+		req.RemoteAddr = remoteAddr
+		requests = append(requests, req)
+	}
+	handler := newFilterTestHandler(t, requests)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"client_ip=203.0.113.7", 1},
+		{"client_ip=203.0.113.0/24", 2},
+		{"client_ip=2001:db8::1", 1},
+		{"client_ip=2001:0db8:0000::0001", 1},
+		{"client_ip=2001:db8::/32", 2},
+		{"client_ip=192.0.2.1", 0},
+	}
+
+	for _, tt := range tests {
+		code, response := queryLogsAPI(t, handler, tt.query)
+		if code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d", tt.query, code)
+		}
+		if response.Total != tt.want {
+			t.Errorf("Expected %d entries for %s, got %d", tt.want, tt.query, response.Total)
+		}
+	}
+
+	// 格式错误返回400
+	for _, query := range []string{"client_ip=203.0.113", "client_ip=10.0.0.0/33", "client_ip=not-an-ip"} {
+		if code, _ := queryLogsAPI(t, handler, query); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, code)
+		}
+	}
+}
+
+func TestHandler_APIFilterByDuration(t *testing.T) {
+	var requests []*http.Request
+	durations := []time.Duration{50 * time.Millisecond, 800 * time.Millisecond, 1500 * time.Millisecond, 3 * time.Second}
+	for range durations {
+		requests = append(requests, httptest.NewRequest("GET", "/proxy?target=https://api.example.com/slow", nil))
+	}
+	handler := newFilterTestHandler(t, requests, durations...)
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"min_duration_ms=1000", []int64{1500, 3000}},
+		{"max_duration_ms=800", []int64{50, 800}},
+		{"min_duration_ms=500&max_duration_ms=2000", []int64{800, 1500}},
+	}
+
+	for _, tt := range tests {
+		code, response := queryLogsAPI(t, handler, tt.query)
+		if code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d", tt.query, code)
+		}
+		got := make(map[int64]bool)
+		for _, entry := range response.Logs {
+			got[entry.Duration] = true
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Expected durations %v for %s, got %+v", tt.want, tt.query, got)
+			continue
+		}
+		for _, duration := range tt.want {
+			if !got[duration] {
+				t.Errorf("Expected duration %d for %s, got %+v", duration, tt.query, got)
+			}
+		}
+	}
+
+	// 最短时长大于最长时长返回400
+	if code, _ := queryLogsAPI(t, handler, "min_duration_ms=2000&max_duration_ms=1000"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for min > max, got %d", code)
+	}
+}
+
+func TestHandler_APIFilterBySlow(t *testing.T) {
+	cfg := &config.Config{
+		LogMaxEntries:     100,
+		LogMaxMemoryMB:    10,
+		LogRetentionHours: 24,
+		LogMaxBodySize:    1024,
+		SlowRequestMs:     1000,
+	}
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/proxy?target=https://api.example.com/fast", nil),
+		httptest.NewRequest("GET", "/proxy?target=https://api.example.com/slow", nil),
+	}
+	handler := newFilterTestHandlerWithConfig(t, cfg, requests, 200*time.Millisecond, 2500*time.Millisecond)
+
+	// 超过阈值的请求被标记为慢请求
+	code, response := queryLogsAPI(t, handler, "")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	for _, entry := range response.Logs {
+		if want := entry.TargetPath == "/slow"; entry.Slow != want {
+			t.Errorf("Expected slow=%v for %s (%dms), got %v", want, entry.TargetPath, entry.Duration, entry.Slow)
+		}
+	}
+
+	// slow=true只返回慢请求
+	code, response = queryLogsAPI(t, handler, "slow=true")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(response.Logs) != 1 || response.Logs[0].TargetPath != "/slow" {
+		t.Errorf("Expected only the slow request, got %+v", response.Logs)
+	}
+
+	if query := NewFilterBuilder().Slow(true).ToQueryString(); query != "slow=true" {
+		t.Errorf("Expected slow in query string, got %q", query)
+	}
+}
@@ -2,9 +2,11 @@ package logviewer
 
 import (
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"net/http"
 	"strings"
+	"time"
 
 	"privacygateway/internal/accesslog"
 	"privacygateway/internal/logger"
@@ -16,12 +18,15 @@ type Handler struct {
 	authenticator Authenticator
 	logger        *logger.Logger
 	template      *template.Template
+
+	statusTemplate *template.Template
+	statusSource   StatusSource // 状态页数据来源（未设置时状态页不可用）
 }
 
-// NewHandler 创建新的日志查看处理器
-func NewHandler(recorder *accesslog.Recorder, secret string, log *logger.Logger) (*Handler, error) {
+// NewHandler 创建新的日志查看处理器，previous为轮换窗口内仍然有效的旧密钥
+func NewHandler(recorder *accesslog.Recorder, secret string, log *logger.Logger, previous ...string) (*Handler, error) {
 	// 创建认证器
-	auth, err := CreateAuthenticator(secret)
+	auth, err := CreateAuthenticator(secret, previous...)
 	if err != nil {
 		return nil, err
 	}
@@ -31,6 +36,8 @@ func NewHandler(recorder *accesslog.Recorder, secret string, log *logger.Logger)
 		authenticator: auth,
 		logger:        log,
 		template:      GetTemplate(),
+
+		statusTemplate: GetStatusTemplate(),
 	}, nil
 }
 
@@ -65,11 +72,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// 认证成功，设置安全Cookie（如果是通过表单或URL参数登录）
 	if secret := r.FormValue("secret"); secret != "" {
 		if secretAuth, ok := h.authenticator.(*SecretAuthenticator); ok {
-			secretAuth.SetSecureCookie(w, secret)
+			secretAuth.SetSecureCookie(w, r, secret)
 		}
 	} else if secret := r.URL.Query().Get("secret"); secret != "" {
 		if secretAuth, ok := h.authenticator.(*SecretAuthenticator); ok {
-			secretAuth.SetSecureCookie(w, secret)
+			secretAuth.SetSecureCookie(w, r, secret)
 		}
 	}
 
@@ -81,6 +88,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.handleAPI(w, r)
 	case path == "/stats":
 		h.handleStats(w, r)
+	case path == "/status":
+		h.handleStatus(w, r)
 	default:
 		h.handleError(w, r, "Not found", http.StatusNotFound)
 	}
@@ -160,6 +169,8 @@ func (h *Handler) handleAPI(w http.ResponseWriter, r *http.Request) {
 		h.handleAPILogs(w, r)
 	case path == "/stats":
 		h.handleAPIStats(w, r)
+	case path == "/aggregate":
+		h.handleAPIAggregate(w, r)
 	default:
 		h.handleAPIError(w, "Not found", http.StatusNotFound)
 	}
@@ -258,6 +269,50 @@ func (h *Handler) handleAPIStats(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleAPIAggregate 处理聚合统计查询（主机分布、状态码分布、时间分桶和延迟分位数）
+func (h *Handler) handleAPIAggregate(w http.ResponseWriter, r *http.Request) {
+	filterBuilder := NewFilterBuilder().FromRequest(r)
+	if err := ValidateFilter(filterBuilder.GetParams()); err != nil {
+		h.handleAPIError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interval, err := parseAggregateInterval(r.URL.Query().Get("interval"))
+	if err != nil {
+		h.handleAPIError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.recorder.Aggregate(filterBuilder.Build(), interval)
+	if err != nil {
+		h.logger.Error("failed to aggregate logs", "error", err)
+		h.handleAPIError(w, "Aggregate failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("failed to encode aggregate response", "error", err)
+		h.handleAPIError(w, "Encoding failed", http.StatusInternalServerError)
+	}
+}
+
+// parseAggregateInterval 解析时间分桶间隔，为空时使用默认值
+func parseAggregateInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return accesslog.DefaultAggregateInterval, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval: %s", value)
+	}
+	if interval < accesslog.MinAggregateInterval || interval > accesslog.MaxAggregateInterval {
+		return 0, fmt.Errorf("interval must be between %s and %s", accesslog.MinAggregateInterval, accesslog.MaxAggregateInterval)
+	}
+	return interval, nil
+}
+
 // handleStats 处理统计页面
 func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
 	stats := h.recorder.GetStats()
@@ -352,9 +407,9 @@ func (h *Handler) IsAuthEnabled() bool {
 	return h.authenticator.IsEnabled()
 }
 
-// CreateLogViewHandler 创建日志查看处理器的便捷函数
-func CreateLogViewHandler(recorder *accesslog.Recorder, secret string, log *logger.Logger) http.HandlerFunc {
-	handler, err := NewHandler(recorder, secret, log)
+// CreateLogViewHandler 创建日志查看处理器的便捷函数（status为状态页数据来源，可为nil）
+func CreateLogViewHandler(recorder *accesslog.Recorder, secret string, log *logger.Logger, status StatusSource, previous ...string) http.HandlerFunc {
+	handler, err := NewHandler(recorder, secret, log, previous...)
 	if err != nil {
 		log.Error("failed to create log view handler", "error", err)
 		return func(w http.ResponseWriter, r *http.Request) {
@@ -382,6 +437,7 @@ func CreateLogViewHandler(recorder *accesslog.Recorder, secret string, log *logg
 		}
 	}
 
+	handler.SetStatusSource(status)
 	return handler.ServeHTTP
 }
 
@@ -518,7 +574,7 @@ func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
 		Value:    "",
 		Path:     "/logs",
 		HttpOnly: true,
-		Secure:   false,
+		Secure:   r.TLS != nil,
 		SameSite: http.SameSiteStrictMode,
 		MaxAge:   -1, // 立即过期
 	}
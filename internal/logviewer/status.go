@@ -0,0 +1,283 @@
+package logviewer
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/metrics"
+	"privacygateway/internal/proxyconfig"
+)
+
+// recentErrorLimit 状态页展示的最近错误条数
+const recentErrorLimit = 10
+
+// StatusData 状态页数据
+type StatusData struct {
+	Title        string                    `json:"-"`
+	Timestamp    time.Time                 `json:"timestamp"`
+	Health       *metrics.HealthStatus     `json:"health,omitempty"`
+	Metrics      *metrics.Snapshot         `json:"metrics,omitempty"`
+	Configs      *proxyconfig.StorageStats `json:"configs,omitempty"`
+	AccessLog    *accesslog.RecorderStats  `json:"access_log,omitempty"`
+	RecentErrors []accesslog.AccessLog     `json:"recent_errors"`
+}
+
+// StatusSource 提供状态页中日志查看器之外的数据（指标、健康状态、配置统计）
+type StatusSource func() *StatusData
+
+// SetStatusSource 设置状态页数据来源，未设置时状态页不可用
+func (h *Handler) SetStatusSource(source StatusSource) {
+	h.statusSource = source
+}
+
+// handleStatus 处理状态页（?format=json 或 Accept: application/json 时返回JSON）
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if h.statusSource == nil {
+		h.handleError(w, r, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		h.handleError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data := h.statusSource()
+	data.Title = "系统状态"
+	data.Timestamp = time.Now()
+	data.AccessLog = h.recorder.GetStats()
+	data.RecentErrors = h.recentErrors()
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			h.logger.Error("failed to encode status response", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.statusTemplate.Execute(w, data); err != nil {
+		h.logger.Error("failed to render status template", "error", err)
+		h.handleError(w, r, "Template rendering failed", http.StatusInternalServerError)
+	}
+}
+
+// recentErrors 获取最近的错误日志（状态码>=400，按时间倒序）
+func (h *Handler) recentErrors() []accesslog.AccessLog {
+	recent := make([]accesslog.AccessLog, 0, recentErrorLimit)
+
+	response, err := h.recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 1000})
+	if err != nil {
+		h.logger.Error("failed to query recent errors", "error", err)
+		return recent
+	}
+
+	for _, log := range response.Logs {
+		if log.IsErrorStatus() {
+			recent = append(recent, log)
+			if len(recent) == recentErrorLimit {
+				break
+			}
+		}
+	}
+	return recent
+}
+
+// GetStatusTemplate 获取状态页模板
+func GetStatusTemplate() *template.Template {
+	funcMap := templateFuncMap()
+	funcMap["bytesToMB"] = func(b uint64) float64 {
+		return float64(b) / 1024 / 1024
+	}
+	return template.Must(template.New("status").Funcs(funcMap).Parse(StatusTemplate))
+}
+
+// StatusTemplate 状态页模板
+const StatusTemplate = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}} - Privacy Gateway</title>
+    <style>
+        * { margin: 0; padding: 0; box-sizing: border-box; }
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #f5f5f5; }
+        .container { max-width: 1280px; margin: 0 auto; padding: 20px; }
+        .header, .section { background: white; padding: 20px; border-radius: 8px; margin-bottom: 20px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
+        .header h1 { color: #333; margin-bottom: 10px; }
+        .section h2 { color: #333; font-size: 16px; margin-bottom: 15px; }
+        .stats { display: flex; gap: 20px; flex-wrap: wrap; }
+        .stat-item { background: #f8f9fa; padding: 10px 15px; border-radius: 6px; min-width: 140px; }
+        .stat-label { font-size: 12px; color: #666; text-transform: uppercase; }
+        .stat-value { font-size: 18px; font-weight: bold; color: #333; }
+        .btn { padding: 8px 16px; border: none; border-radius: 4px; cursor: pointer; text-decoration: none; display: inline-block; }
+        .btn-secondary { background: #6c757d; color: white; }
+        .health { padding: 2px 8px; border-radius: 3px; font-size: 14px; font-weight: bold; }
+        .health-healthy, .health-ok { background: #d4edda; color: #155724; }
+        .health-degraded, .health-warning { background: #fff3cd; color: #856404; }
+        .logs-table { width: 100%; border-collapse: collapse; }
+        .logs-table th, .logs-table td { padding: 12px; text-align: left; border-bottom: 1px solid #eee; }
+        .logs-table th { background: #f8f9fa; font-weight: 600; color: #333; }
+        .status-badge { padding: 2px 6px; border-radius: 3px; font-size: 12px; font-weight: bold; }
+        .status-4xx { background: #f8d7da; color: #721c24; }
+        .status-5xx { background: #f5c6cb; color: #721c24; }
+        .method-badge { padding: 2px 6px; border-radius: 3px; font-size: 11px; font-weight: bold; background: #e9ecef; color: #495057; }
+        .empty { text-align: center; padding: 20px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <div style="display: flex; justify-content: space-between; align-items: center; margin-bottom: 20px;">
+                <h1>{{.Title}}</h1>
+                <a href="/logs" class="btn btn-secondary">访问日志</a>
+            </div>
+            <div class="stats">
+                {{if .Health}}
+                <div class="stat-item">
+                    <div class="stat-label">健康状态</div>
+                    <div class="stat-value"><span class="health health-{{.Health.Status}}">{{.Health.Status}}</span></div>
+                </div>
+                {{end}}
+                <div class="stat-item">
+                    <div class="stat-label">更新时间</div>
+                    <div class="stat-value">{{formatLogTime .Timestamp}}</div>
+                </div>
+            </div>
+        </div>
+
+        {{if .Metrics}}
+        <div class="section">
+            <h2>代理请求</h2>
+            <div class="stats">
+                <div class="stat-item">
+                    <div class="stat-label">总请求数</div>
+                    <div class="stat-value">{{.Metrics.TotalRequests}}</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">成功率</div>
+                    <div class="stat-value">{{printf "%.2f%%" .Metrics.SuccessRate}}</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">错误请求</div>
+                    <div class="stat-value">{{.Metrics.ErrorRequests}}</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">平均响应时间</div>
+                    <div class="stat-value">{{.Metrics.AvgResponseTime}} ms</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">最大响应时间</div>
+                    <div class="stat-value">{{.Metrics.MaxResponseTime}} ms</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">内存使用</div>
+                    <div class="stat-value">{{printf "%.2f MB" (bytesToMB .Metrics.MemoryUsage)}}</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">Goroutines</div>
+                    <div class="stat-value">{{.Metrics.Goroutines}}</div>
+                </div>
+            </div>
+        </div>
+        {{end}}
+
+        {{if .Health}}
+        <div class="section">
+            <h2>健康检查</h2>
+            <div class="stats">
+                {{range $name, $check := .Health.Checks}}
+                <div class="stat-item">
+                    <div class="stat-label">{{$name}}</div>
+                    <div class="stat-value"><span class="health health-{{$check.Status}}">{{$check.Status}}</span></div>
+                    <div class="stat-label">{{$check.Message}}</div>
+                </div>
+                {{end}}
+            </div>
+        </div>
+        {{end}}
+
+        <div class="section">
+            <h2>配置与日志</h2>
+            <div class="stats">
+                {{if .Configs}}
+                <div class="stat-item">
+                    <div class="stat-label">代理配置</div>
+                    <div class="stat-value">{{.Configs.TotalConfigs}}</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">已启用配置</div>
+                    <div class="stat-value">{{.Configs.EnabledConfigs}}</div>
+                </div>
+                {{with .Configs.Persistence}}
+                <div class="stat-item">
+                    <div class="stat-label">配置保存</div>
+                    <div class="stat-value">{{if .Degraded}}<span class="health health-degraded">degraded</span>{{else}}<span class="health health-ok">ok</span>{{end}}</div>
+                    {{if .Degraded}}<div class="stat-label">{{.LastError}}</div>{{end}}
+                </div>
+                {{end}}
+                {{end}}
+                {{if .AccessLog}}
+                <div class="stat-item">
+                    <div class="stat-label">日志条数</div>
+                    <div class="stat-value">{{.AccessLog.StorageStats.CurrentEntries}}</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">日志内存</div>
+                    <div class="stat-value">{{printf "%.2f MB" .AccessLog.StorageStats.MemoryUsageMB}}</div>
+                </div>
+                <div class="stat-item">
+                    <div class="stat-label">日志队列</div>
+                    <div class="stat-value">{{.AccessLog.QueueSize}} / {{.AccessLog.QueueCapacity}}</div>
+                </div>
+                {{end}}
+            </div>
+        </div>
+
+        <div class="section">
+            <h2>最近错误</h2>
+            {{if .RecentErrors}}
+            <table class="logs-table">
+                <thead>
+                    <tr>
+                        <th>时间</th>
+                        <th>方法</th>
+                        <th>目标</th>
+                        <th>状态码</th>
+                        <th>耗时</th>
+                        <th>客户端IP</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {{range .RecentErrors}}
+                    <tr>
+                        <td>{{formatLogTime .Timestamp}}</td>
+                        <td><span class="method-badge">{{.Method}}</span></td>
+                        <td>{{.TargetHost}}{{.TargetPath}}</td>
+                        <td><span class="status-badge status-{{getStatusClass .StatusCode}}">{{.StatusCode}}</span></td>
+                        <td>{{.Duration}} ms</td>
+                        <td>{{.ClientIP}}</td>
+                    </tr>
+                    {{end}}
+                </tbody>
+            </table>
+            {{else}}
+            <div class="empty">暂无错误</div>
+            {{end}}
+        </div>
+    </div>
+
+    <script>
+        // 自动刷新功能
+        setTimeout(() => {
+            window.location.reload();
+        }, 30000); // 30秒
+    </script>
+</body>
+</html>`
@@ -0,0 +1,130 @@
+package logviewer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/accesslog"
+	"privacygateway/internal/metrics"
+	"privacygateway/internal/proxyconfig"
+)
+
+// newStatusTestHandler 创建带状态页数据来源的处理器，并记录一条成功请求和一条502错误
+func newStatusTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	handler := newFilterTestHandler(t, []*http.Request{
+		httptest.NewRequest("GET", "/proxy?target=https://api.example.com/ok", nil),
+	})
+	handler.recorder.RecordRequest(httptest.NewRequest("POST", "/proxy?target=https://api.example.com/broken", nil), http.StatusBadGateway, "", 120*time.Millisecond, 0, "/proxy")
+
+	// 等待异步写入
+	deadline := time.Now().Add(time.Second)
+	for {
+		if logs, _ := handler.recorder.Query(&accesslog.LogFilter{Page: 1, Limit: 10}); logs != nil && logs.Total == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected error log to be recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	m := metrics.NewMetrics()
+	for i := 0; i < 3; i++ {
+		m.RecordRequest(40*time.Millisecond, true)
+	}
+	m.RecordRequest(120*time.Millisecond, false)
+
+	handler.SetStatusSource(func() *StatusData {
+		return &StatusData{
+			Health:  m.GetHealthStatus(),
+			Metrics: m.GetSnapshot(),
+			Configs: &proxyconfig.StorageStats{TotalConfigs: 7, EnabledConfigs: 5},
+		}
+	})
+	return handler
+}
+
+func TestHandler_StatusPage(t *testing.T) {
+	handler := newStatusTestHandler(t)
+
+	req := httptest.NewRequest("GET", "/logs/status", nil)
+	req.Header.Set("X-Log-Secret", "correctsecret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+		t.Errorf("Expected HTML response, got %s", contentType)
+	}
+
+	body := w.Body.String()
+	for _, expected := range []string{"系统状态", "75.00%", "已启用配置", ">5<", "api.example.com/broken", "502", "window.location.reload"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected status page to contain %q", expected)
+		}
+	}
+	if strings.Contains(body, "api.example.com/ok") {
+		t.Error("Expected successful requests not to be listed as recent errors")
+	}
+
+	// 未认证时拒绝访问
+	req = httptest.NewRequest("GET", "/logs/status?format=json", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without secret, got %d", w.Code)
+	}
+}
+
+func TestHandler_StatusJSON(t *testing.T) {
+	handler := newStatusTestHandler(t)
+
+	req := httptest.NewRequest("GET", "/logs/status?format=json", nil)
+	req.Header.Set("X-Log-Secret", "correctsecret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var data StatusData
+	if err := json.NewDecoder(w.Body).Decode(&data); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if data.Metrics == nil || data.Metrics.TotalRequests != 4 || data.Metrics.ErrorRequests != 1 {
+		t.Errorf("Expected metrics snapshot with 4 requests and 1 error, got %+v", data.Metrics)
+	}
+	if data.Health == nil || data.Health.Status == "" {
+		t.Error("Expected health status in JSON response")
+	}
+	if data.Configs == nil || data.Configs.TotalConfigs != 7 {
+		t.Errorf("Expected config stats in JSON response, got %+v", data.Configs)
+	}
+	if data.AccessLog == nil || data.AccessLog.StorageStats.CurrentEntries != 2 {
+		t.Errorf("Expected access log stats in JSON response, got %+v", data.AccessLog)
+	}
+	if len(data.RecentErrors) != 1 || data.RecentErrors[0].StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected 1 recent error, got %+v", data.RecentErrors)
+	}
+}
+
+func TestHandler_StatusWithoutSource(t *testing.T) {
+	handler := newFilterTestHandler(t, nil)
+
+	req := httptest.NewRequest("GET", "/logs/status?format=json", nil)
+	req.Header.Set("X-Log-Secret", "correctsecret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without status source, got %d", w.Code)
+	}
+}
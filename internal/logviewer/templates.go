@@ -73,6 +73,7 @@ const LogViewTemplate = `<!DOCTYPE html>
         .status-3xx { background: #d1ecf1; color: #0c5460; }
         .status-4xx { background: #f8d7da; color: #721c24; }
         .status-5xx { background: #f5c6cb; color: #721c24; }
+        .slow-badge { padding: 2px 6px; border-radius: 3px; font-size: 11px; font-weight: bold; background: #fff3cd; color: #856404; }
         .method-badge { padding: 2px 6px; border-radius: 3px; font-size: 11px; font-weight: bold; background: #e9ecef; color: #495057; }
         .type-badge { padding: 2px 6px; border-radius: 3px; font-size: 11px; font-weight: bold; }
         .type-HTTP { background: #d1ecf1; color: #0c5460; }
@@ -173,6 +174,18 @@ const LogViewTemplate = `<!DOCTYPE html>
                             <option value="500">500</option>
                         </select>
                     </div>
+                    <div class="filter-group">
+                        <label for="client_ip">客户端IP</label>
+                        <input type="text" id="client_ip" name="client_ip" value="{{.Filter.ClientIP}}" placeholder="例如: 203.0.113.7 或 10.0.0.0/8">
+                    </div>
+                    <div class="filter-group">
+                        <label for="method">请求方法</label>
+                        <select id="method" name="method">
+                            <option value="">全部方法</option>
+                            {{range $value := methodOptions}}<option value="{{$value}}"{{if eq $.Filter.MethodValue $value}} selected{{end}}>{{$value}}</option>
+                            {{end}}<option value="POST,PUT,PATCH,DELETE"{{if eq $.Filter.MethodValue "POST,PUT,PATCH,DELETE"}} selected{{end}}>写操作 (POST/PUT/PATCH/DELETE)</option>
+                        </select>
+                    </div>
                     <div class="filter-group">
                         <label for="from">开始时间</label>
                         <input type="datetime-local" id="from" name="from" value="{{formatDateTime .Filter.FromTime}}">
@@ -187,6 +200,21 @@ const LogViewTemplate = `<!DOCTYPE html>
                         <label for="search">搜索</label>
                         <input type="text" id="search" name="search" value="{{.Filter.Search}}" placeholder="搜索路径、IP等">
                     </div>
+                    <div class="filter-group">
+                        <label for="min_duration_ms">最短耗时(ms)</label>
+                        <input type="number" id="min_duration_ms" name="min_duration_ms" min="0" value="{{if .Filter.MinDuration}}{{.Filter.MinDuration}}{{end}}" placeholder="例如: 1000">
+                    </div>
+                    <div class="filter-group">
+                        <label for="max_duration_ms">最长耗时(ms)</label>
+                        <input type="number" id="max_duration_ms" name="max_duration_ms" min="0" value="{{if .Filter.MaxDuration}}{{.Filter.MaxDuration}}{{end}}">
+                    </div>
+                    <div class="filter-group">
+                        <label for="slow">慢请求</label>
+                        <select id="slow" name="slow">
+                            <option value="">全部</option>
+                            <option value="true"{{if .Filter.Slow}} selected{{end}}>仅慢请求</option>
+                        </select>
+                    </div>
                     <div class="filter-group">
                         <label for="limit">每页条数</label>
                         <select id="limit" name="limit">
@@ -232,7 +260,7 @@ const LogViewTemplate = `<!DOCTYPE html>
                             {{end}}
                         </td>
                         <td><span class="status-badge status-{{getStatusClass .StatusCode}}">{{.StatusCode}}</span></td>
-                        <td>{{.Duration}}ms</td>
+                        <td>{{.Duration}}ms{{if .Slow}} <span class="slow-badge">慢</span>{{end}}</td>
                         <td>{{.ClientIP}}</td>
                         <td>{{formatLogTime .Timestamp}}</td>
                     </tr>
@@ -314,6 +342,10 @@ const LogViewTemplate = `<!DOCTYPE html>
                     <div class="detail-label">代理服务器</div>
                     <div class="detail-value" id="detail-proxy"></div>
                 </div>
+                <div class="detail-row">
+                    <div class="detail-label">代理配置</div>
+                    <div class="detail-value" id="detail-config"></div>
+                </div>
                 <div class="detail-row">
                     <div class="detail-label">响应内容</div>
                     <div class="detail-value" id="detail-response"></div>
@@ -491,10 +523,11 @@ const LogViewTemplate = `<!DOCTYPE html>
             statusElement.innerHTML = '<span class="status-badge status-' + getStatusClass(log.status_code) + '">' + log.status_code + '</span>';
 
             document.getElementById('detail-time').textContent = formatLogTime(log.timestamp);
-            document.getElementById('detail-duration').textContent = log.duration_ms + 'ms';
+            document.getElementById('detail-duration').textContent = log.duration_ms + 'ms' + (log.slow ? '（慢请求）' : '');
             document.getElementById('detail-ip').textContent = log.client_ip || '未知';
             document.getElementById('detail-useragent').textContent = log.user_agent || '未设置';
             document.getElementById('detail-proxy').textContent = log.proxy_info || 'Privacy Gateway';
+            document.getElementById('detail-config').textContent = log.config_id ? (log.config_name + ' (' + log.config_id + ')') : '未使用';
             document.getElementById('detail-response').textContent = log.response_body || '无响应内容';
 
             // 生成等效的curl命令
@@ -661,6 +694,10 @@ const LogViewTemplate = `<!DOCTYPE html>
         // 如果没有筛选条件，启用自动刷新
         if (window.location.search.indexOf('domain=') === -1 &&
             window.location.search.indexOf('status=') === -1 &&
+            window.location.search.indexOf('method=') === -1 &&
+            window.location.search.indexOf('client_ip=') === -1 &&
+            window.location.search.indexOf('config_id=') === -1 &&
+            window.location.search.indexOf('duration_ms=') === -1 &&
             window.location.search.indexOf('from=') === -1) {
             autoRefresh();
         }
@@ -670,7 +707,12 @@ const LogViewTemplate = `<!DOCTYPE html>
 
 // GetTemplate 获取模板
 func GetTemplate() *template.Template {
-	funcMap := template.FuncMap{
+	return template.Must(template.New("logview").Funcs(templateFuncMap()).Parse(LogViewTemplate))
+}
+
+// templateFuncMap 模板函数
+func templateFuncMap() template.FuncMap {
+	return template.FuncMap{
 		"formatTime": func(timeStr string) string {
 			if timeStr == "" {
 				return "-"
@@ -723,10 +765,10 @@ func GetTemplate() *template.Template {
 		"lower": func(s string) string {
 			return strings.ToLower(s)
 		},
+		"methodOptions": func() []string {
+			return []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+		},
 	}
-
-	tmpl := template.Must(template.New("logview").Funcs(funcMap).Parse(LogViewTemplate))
-	return tmpl
 }
 
 // CreateTemplateData 创建模板数据
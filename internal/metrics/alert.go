@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"privacygateway/internal/logger"
+)
+
+// AlertConfig 告警配置
+type AlertConfig struct {
+	WebhookURL       string        // 告警通知地址（POST JSON）
+	MinSuccessRate   float64       // 成功率下限（百分比）
+	MinRequests      int64         // 计算成功率所需的最少请求数（检查间隔内）
+	MaxAvgResponseMs int64         // 平均响应时间上限（毫秒，0表示不检查）
+	MaxMemoryMB      float64       // 内存使用上限（MB，0表示不检查）
+	Window           time.Duration // 超过阈值需持续的时长
+	Cooldown         time.Duration // 两次告警之间的最小间隔
+	CheckInterval    time.Duration // 检查间隔
+	RequestTimeout   time.Duration // 发送通知的超时时间
+}
+
+// AlertBreach 超过阈值的检查项
+type AlertBreach struct {
+	Check     string  `json:"check"` // success_rate / response_time / memory
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+// AlertPayload 告警通知内容
+type AlertPayload struct {
+	Status        string        `json:"status"` // firing
+	Service       string        `json:"service"`
+	Message       string        `json:"message"`
+	Breaches      []AlertBreach `json:"breaches"`
+	BreachedSince time.Time     `json:"breached_since"`
+	Timestamp     time.Time     `json:"timestamp"`
+}
+
+// Alerter 阈值告警器
+//
+// 定期检查指标快照，任一检查项持续超过阈值Window后向WebhookURL发送一次告警；
+// 之后直到指标恢复正常并再次持续超过阈值才会重新告警，且两次告警间隔不少于Cooldown。
+// 成功率和平均响应时间按相邻两次检查之间的请求计算，不受长时间运行累计的历史数据稀释。
+type Alerter struct {
+	config  AlertConfig
+	metrics *Metrics
+	client  *http.Client
+	log     *logger.Logger
+
+	mutex         sync.Mutex
+	breachedSince time.Time // 本轮超过阈值的开始时间（为零表示当前正常）
+	alerted       bool      // 本轮是否已告警
+	lastAlert     time.Time // 上次告警时间
+	previous      *Snapshot // 上次检查的快照（计算区间指标）
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewAlerter 创建阈值告警器
+func NewAlerter(m *Metrics, config AlertConfig, log *logger.Logger) *Alerter {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = 30 * time.Second
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = 10 * time.Second
+	}
+
+	return &Alerter{
+		config:  config,
+		metrics: m,
+		client:  &http.Client{Timeout: config.RequestTimeout},
+		log:     log,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start 启动定期检查
+func (a *Alerter) Start() {
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(a.config.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				a.evaluate(a.metrics.GetSnapshot(), time.Now())
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定期检查
+func (a *Alerter) Stop() {
+	close(a.stop)
+	<-a.done
+}
+
+// evaluate 根据指标快照更新告警状态，需要告警时发送通知，返回是否发送了告警
+func (a *Alerter) evaluate(snapshot *Snapshot, now time.Time) bool {
+	a.mutex.Lock()
+	breaches := a.checkThresholds(intervalSnapshot(a.previous, snapshot))
+	a.previous = snapshot
+
+	if len(breaches) == 0 {
+		// 恢复正常后允许下一轮告警
+		a.breachedSince = time.Time{}
+		a.alerted = false
+		a.mutex.Unlock()
+		return false
+	}
+
+	if a.breachedSince.IsZero() {
+		a.breachedSince = now
+	}
+	if a.alerted || now.Sub(a.breachedSince) < a.config.Window {
+		a.mutex.Unlock()
+		return false
+	}
+	if !a.lastAlert.IsZero() && now.Sub(a.lastAlert) < a.config.Cooldown {
+		a.mutex.Unlock()
+		return false
+	}
+	a.alerted = true
+	a.lastAlert = now
+	breachedSince := a.breachedSince
+	a.mutex.Unlock()
+
+	payload := &AlertPayload{
+		Status:        "firing",
+		Service:       "privacy-gateway",
+		Message:       fmt.Sprintf("%d health check(s) breached thresholds", len(breaches)),
+		Breaches:      breaches,
+		BreachedSince: breachedSince,
+		Timestamp:     now,
+	}
+	if err := a.send(payload); err != nil {
+		a.log.Error("failed to send alert webhook", "error", err, "url", a.config.WebhookURL)
+	} else {
+		a.log.Warn("alert webhook sent", "breaches", len(breaches))
+	}
+	return true
+}
+
+// intervalSnapshot 返回previous到current之间的请求统计（成功率、平均响应时间按区间内的请求计算）
+//
+// 没有上次快照或计数器被重置（当前值小于上次）时，以当前累计值作为区间。
+func intervalSnapshot(previous, current *Snapshot) *Snapshot {
+	interval := *current
+	if previous != nil && current.TotalRequests >= previous.TotalRequests && current.SuccessRequests >= previous.SuccessRequests {
+		interval.TotalRequests = current.TotalRequests - previous.TotalRequests
+		interval.SuccessRequests = current.SuccessRequests - previous.SuccessRequests
+		interval.ErrorRequests = current.ErrorRequests - previous.ErrorRequests
+		interval.TotalResponseTime = current.TotalResponseTime - previous.TotalResponseTime
+	}
+
+	interval.SuccessRate = 0
+	interval.AvgResponseTime = 0
+	if interval.TotalRequests > 0 {
+		interval.SuccessRate = float64(interval.SuccessRequests) / float64(interval.TotalRequests) * 100
+		interval.AvgResponseTime = interval.TotalResponseTime / interval.TotalRequests
+	}
+	return &interval
+}
+
+// checkThresholds 检查超过阈值的指标
+func (a *Alerter) checkThresholds(snapshot *Snapshot) []AlertBreach {
+	var breaches []AlertBreach
+
+	if snapshot.TotalRequests >= a.config.MinRequests && snapshot.TotalRequests > 0 && snapshot.SuccessRate < a.config.MinSuccessRate {
+		breaches = append(breaches, AlertBreach{Check: "success_rate", Value: snapshot.SuccessRate, Threshold: a.config.MinSuccessRate})
+	}
+	if a.config.MaxAvgResponseMs > 0 && snapshot.AvgResponseTime > a.config.MaxAvgResponseMs {
+		breaches = append(breaches, AlertBreach{Check: "response_time", Value: float64(snapshot.AvgResponseTime), Threshold: float64(a.config.MaxAvgResponseMs)})
+	}
+	memUsageMB := float64(snapshot.MemoryUsage) / 1024 / 1024
+	if a.config.MaxMemoryMB > 0 && memUsageMB > a.config.MaxMemoryMB {
+		breaches = append(breaches, AlertBreach{Check: "memory", Value: memUsageMB, Threshold: a.config.MaxMemoryMB})
+	}
+
+	return breaches
+}
+
+// send 发送告警通知
+func (a *Alerter) send(payload *AlertPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert payload: %w", err)
+	}
+
+	resp, err := a.client.Post(a.config.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
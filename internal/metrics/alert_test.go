@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"privacygateway/internal/logger"
+)
+
+// alertTestTraffic 按检查间隔累加请求，生成与Metrics一致的累计快照
+type alertTestTraffic struct {
+	snapshot Snapshot
+}
+
+// add 累加一个间隔内的请求（requests个请求，其中failed个失败，平均响应时间avgMs毫秒）
+func (tr *alertTestTraffic) add(requests, failed, avgMs int64) *Snapshot {
+	tr.snapshot.TotalRequests += requests
+	tr.snapshot.SuccessRequests += requests - failed
+	tr.snapshot.ErrorRequests += failed
+	tr.snapshot.TotalResponseTime += requests * avgMs
+	snapshot := tr.snapshot
+	return &snapshot
+}
+
+func TestAlerter_FiresOncePerBreach(t *testing.T) {
+	var mutex sync.Mutex
+	var payloads []AlertPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload AlertPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode alert payload: %v", err)
+		}
+		mutex.Lock()
+		payloads = append(payloads, payload)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	alerter := NewAlerter(NewMetrics(), AlertConfig{
+		WebhookURL:       server.URL,
+		MinSuccessRate:   95,
+		MinRequests:      100,
+		MaxAvgResponseMs: 1000,
+		Window:           time.Minute,
+		Cooldown:         5 * time.Minute,
+	}, logger.New())
+
+	traffic := &alertTestTraffic{}
+	degraded := func() *Snapshot { return traffic.add(200, 40, 50) }
+	healthy := func() *Snapshot { return traffic.add(200, 2, 50) }
+	start := time.Now()
+
+	// 未持续超过窗口时不告警
+	if alerter.evaluate(degraded(), start) || alerter.evaluate(degraded(), start.Add(30*time.Second)) {
+		t.Error("Expected no alert before the breach window elapsed")
+	}
+
+	// 持续超过窗口后告警一次
+	if !alerter.evaluate(degraded(), start.Add(time.Minute)) {
+		t.Error("Expected alert after sustained breach")
+	}
+	if alerter.evaluate(degraded(), start.Add(10*time.Minute)) {
+		t.Error("Expected no duplicate alert while still breached")
+	}
+
+	// 恢复后再次超过阈值，重新告警
+	alerter.evaluate(healthy(), start.Add(11*time.Minute))
+	alerter.evaluate(degraded(), start.Add(12*time.Minute))
+	if !alerter.evaluate(degraded(), start.Add(13*time.Minute)) {
+		t.Error("Expected alert after recovery and re-breach")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(payloads) != 2 {
+		t.Fatalf("Expected webhook to be called twice, got %d", len(payloads))
+	}
+	if payloads[0].Status != "firing" || len(payloads[0].Breaches) != 1 || payloads[0].Breaches[0].Check != "success_rate" {
+		t.Errorf("Unexpected alert payload: %+v", payloads[0])
+	}
+}
+
+func TestAlerter_Cooldown(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	alerter := NewAlerter(NewMetrics(), AlertConfig{
+		WebhookURL:       server.URL,
+		MaxAvgResponseMs: 1000,
+		Cooldown:         10 * time.Minute,
+	}, logger.New())
+
+	traffic := &alertTestTraffic{}
+	slow := func() *Snapshot { return traffic.add(10, 0, 1500) }
+	fast := func() *Snapshot { return traffic.add(10, 0, 100) }
+	start := time.Now()
+
+	alerter.evaluate(slow(), start)
+	alerter.evaluate(fast(), start.Add(time.Minute))
+
+	// 冷却时间内恢复后再次超过阈值不告警，冷却结束后告警
+	if alerter.evaluate(slow(), start.Add(2*time.Minute)) {
+		t.Error("Expected alert to be suppressed during cooldown")
+	}
+	if !alerter.evaluate(slow(), start.Add(11*time.Minute)) {
+		t.Error("Expected alert after cooldown")
+	}
+	if calls != 2 {
+		t.Errorf("Expected webhook to be called twice, got %d", calls)
+	}
+}
+
+func TestAlerter_UsesIntervalRates(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	alerter := NewAlerter(NewMetrics(), AlertConfig{
+		WebhookURL:       server.URL,
+		MinSuccessRate:   95,
+		MinRequests:      100,
+		MaxAvgResponseMs: 1000,
+	}, logger.New())
+
+	// 长时间正常运行后的持续故障：累计成功率仍高于99%，区间成功率为0
+	traffic := &alertTestTraffic{}
+	start := time.Now()
+	if alerter.evaluate(traffic.add(1000000, 0, 20), start) {
+		t.Fatal("Expected no alert for healthy traffic")
+	}
+	outage := traffic.add(500, 500, 20)
+	if outage.TotalRequests == 0 || float64(outage.SuccessRequests)/float64(outage.TotalRequests) < 0.99 {
+		t.Fatalf("Expected lifetime success rate to stay above 99%%, got %d/%d", outage.SuccessRequests, outage.TotalRequests)
+	}
+	if !alerter.evaluate(outage, start.Add(30*time.Second)) {
+		t.Error("Expected outage to alert despite a high lifetime success rate")
+	}
+
+	// 区间内请求数不足时不检查成功率
+	alerter = NewAlerter(NewMetrics(), AlertConfig{WebhookURL: server.URL, MinSuccessRate: 95, MinRequests: 100}, logger.New())
+	traffic = &alertTestTraffic{}
+	alerter.evaluate(traffic.add(1000, 0, 20), start)
+	if alerter.evaluate(traffic.add(10, 10, 20), start.Add(30*time.Second)) {
+		t.Error("Expected success rate not to be checked below MinRequests in the interval")
+	}
+
+	// 计数器重置后以当前累计值作为区间
+	if !alerter.evaluate(&Snapshot{TotalRequests: 200, SuccessRequests: 100, ErrorRequests: 100}, start.Add(time.Minute)) {
+		t.Error("Expected counters reset to be evaluated from the new totals")
+	}
+	if calls != 2 {
+		t.Errorf("Expected webhook to be called twice, got %d", calls)
+	}
+}
@@ -29,24 +29,91 @@ type Metrics struct {
 	totalConfigs     int64
 	activeConfigs    int64
 	
+	// 镜像请求统计
+	mirrorSuccess    int64
+	mirrorFailures   int64
+	
+	// 上游错误分类统计
+	upstreamErrors   [upstreamErrorCategoryCount]int64
+	
 	// 系统资源
 	mutex            sync.RWMutex
 	lastUpdate       time.Time
 	memStats         runtime.MemStats
 	
-	// 历史数据（最近1小时，每分钟一个数据点）
-	requestHistory   [60]int64
-	responseHistory  [60]int64
-	errorHistory     [60]int64
-	historyIndex     int
+	// 历史数据（环形缓冲区，默认最近1小时，每分钟一个数据点）
+	requestHistory    []int64
+	responseHistory   []int64
+	errorHistory      []int64
+	historyIndex      int
+	historyInterval   time.Duration
 	lastHistoryUpdate time.Time
+	
+	// 上一个数据点时的累计值（用于计算每个间隔的增量）
+	lastHistoryRequests     int64
+	lastHistoryErrors       int64
+	lastHistoryResponseTime int64
 }
 
-// NewMetrics 创建新的指标收集器
+// UpstreamErrorCategory 上游请求失败的分类，用于区分网络问题和上游应用问题
+type UpstreamErrorCategory int
+
+// 上游错误分类
+const (
+	UpstreamErrorDNS               UpstreamErrorCategory = iota // 域名解析失败
+	UpstreamErrorConnectionRefused                              // 连接被拒绝
+	UpstreamErrorConnection                                     // 其他连接错误（连接重置、提前关闭等）
+	UpstreamErrorTimeout                                        // 连接或响应超时
+	UpstreamErrorTLS                                            // TLS握手或证书校验失败
+	UpstreamError5xx                                            // 上游返回5xx状态码
+	upstreamErrorCategoryCount
+)
+
+// upstreamErrorCategoryNames 分类在指标快照中的名称
+var upstreamErrorCategoryNames = [upstreamErrorCategoryCount]string{
+	UpstreamErrorDNS:               "dns",
+	UpstreamErrorConnectionRefused: "connection_refused",
+	UpstreamErrorConnection:        "connection",
+	UpstreamErrorTimeout:           "timeout",
+	UpstreamErrorTLS:               "tls",
+	UpstreamError5xx:               "upstream_5xx",
+}
+
+// String 返回分类名称
+func (c UpstreamErrorCategory) String() string {
+	if c < 0 || c >= upstreamErrorCategoryCount {
+		return "unknown"
+	}
+	return upstreamErrorCategoryNames[c]
+}
+
+// 默认历史数据配置
+const (
+	DefaultHistoryLength   = 60          // 默认历史数据点数
+	DefaultHistoryInterval = time.Minute // 默认历史数据间隔
+)
+
+// NewMetrics 创建新的指标收集器（保留最近60分钟、每分钟一个数据点的历史数据）
 func NewMetrics() *Metrics {
+	return NewMetricsWithHistory(DefaultHistoryLength, DefaultHistoryInterval)
+}
+
+// NewMetricsWithHistory 创建指定历史数据点数和间隔的指标收集器（如288个5分钟数据点覆盖24小时）
+func NewMetricsWithHistory(length int, interval time.Duration) *Metrics {
+	if length <= 0 {
+		length = DefaultHistoryLength
+	}
+	if interval <= 0 {
+		interval = DefaultHistoryInterval
+	}
+	
 	m := &Metrics{
 		minResponseTime:   int64(^uint64(0) >> 1), // 设置为最大值
 		lastUpdate:        time.Now(),
+		requestHistory:    make([]int64, length),
+		responseHistory:   make([]int64, length),
+		errorHistory:      make([]int64, length),
+		historyInterval:   interval,
 		lastHistoryUpdate: time.Now(),
 	}
 	
@@ -85,6 +152,23 @@ func (m *Metrics) RecordRequest(duration time.Duration, success bool) {
 	}
 }
 
+// RecordMirror 记录一次镜像请求的结果（连接失败、5xx或被丢弃视为失败）
+func (m *Metrics) RecordMirror(success bool) {
+	if success {
+		atomic.AddInt64(&m.mirrorSuccess, 1)
+	} else {
+		atomic.AddInt64(&m.mirrorFailures, 1)
+	}
+}
+
+// RecordUpstreamError 记录一次上游错误（按分类计数）
+func (m *Metrics) RecordUpstreamError(category UpstreamErrorCategory) {
+	if category < 0 || category >= upstreamErrorCategoryCount {
+		return
+	}
+	atomic.AddInt64(&m.upstreamErrors[category], 1)
+}
+
 // RecordTokenValidation 记录令牌验证
 func (m *Metrics) RecordTokenValidation() {
 	atomic.AddInt64(&m.tokenValidations, 1)
@@ -107,21 +191,64 @@ func (m *Metrics) GetSnapshot() *Snapshot {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 	
-	totalReq := atomic.LoadInt64(&m.totalRequests)
-	successReq := atomic.LoadInt64(&m.successRequests)
-	errorReq := atomic.LoadInt64(&m.errorRequests)
-	totalRespTime := atomic.LoadInt64(&m.totalResponseTime)
-	minRespTime := atomic.LoadInt64(&m.minResponseTime)
-	maxRespTime := atomic.LoadInt64(&m.maxResponseTime)
+	return m.buildSnapshot(counterValues{
+		totalRequests:     atomic.LoadInt64(&m.totalRequests),
+		successRequests:   atomic.LoadInt64(&m.successRequests),
+		errorRequests:     atomic.LoadInt64(&m.errorRequests),
+		totalResponseTime: atomic.LoadInt64(&m.totalResponseTime),
+		minResponseTime:   atomic.LoadInt64(&m.minResponseTime),
+		maxResponseTime:   atomic.LoadInt64(&m.maxResponseTime),
+		tokenValidations:  atomic.LoadInt64(&m.tokenValidations),
+		mirrorSuccess:     atomic.LoadInt64(&m.mirrorSuccess),
+		mirrorFailures:    atomic.LoadInt64(&m.mirrorFailures),
+		upstreamErrors:    m.loadUpstreamErrors(false),
+	})
+}
+
+// loadUpstreamErrors 读取上游错误分类计数，reset为true时同时清零
+func (m *Metrics) loadUpstreamErrors(reset bool) [upstreamErrorCategoryCount]int64 {
+	var values [upstreamErrorCategoryCount]int64
+	for i := range m.upstreamErrors {
+		if reset {
+			values[i] = atomic.SwapInt64(&m.upstreamErrors[i], 0)
+		} else {
+			values[i] = atomic.LoadInt64(&m.upstreamErrors[i])
+		}
+	}
+	return values
+}
+
+// counterValues 累计计数器的取值
+type counterValues struct {
+	totalRequests     int64
+	successRequests   int64
+	errorRequests     int64
+	totalResponseTime int64
+	minResponseTime   int64
+	maxResponseTime   int64
+	tokenValidations  int64
+	mirrorSuccess     int64
+	mirrorFailures    int64
+	upstreamErrors    [upstreamErrorCategoryCount]int64
+}
+
+// buildSnapshot 根据计数器取值构建快照（调用方需持有锁）
+func (m *Metrics) buildSnapshot(values counterValues) *Snapshot {
+	totalReq := values.totalRequests
 	
 	var avgResponseTime int64
 	if totalReq > 0 {
-		avgResponseTime = totalRespTime / totalReq
+		avgResponseTime = values.totalResponseTime / totalReq
+	}
+	
+	upstreamErrors := make(map[string]int64, len(values.upstreamErrors))
+	for i, count := range values.upstreamErrors {
+		upstreamErrors[UpstreamErrorCategory(i).String()] = count
 	}
 	
 	var successRate float64
 	if totalReq > 0 {
-		successRate = float64(successReq) / float64(totalReq) * 100
+		successRate = float64(values.successRequests) / float64(totalReq) * 100
 	}
 	
 	return &Snapshot{
@@ -129,24 +256,32 @@ func (m *Metrics) GetSnapshot() *Snapshot {
 		
 		// 请求统计
 		TotalRequests:   totalReq,
-		SuccessRequests: successReq,
-		ErrorRequests:   errorReq,
+		SuccessRequests: values.successRequests,
+		ErrorRequests:   values.errorRequests,
 		SuccessRate:     successRate,
 		
 		// 响应时间统计
-		AvgResponseTime: avgResponseTime,
-		MinResponseTime: minRespTime,
-		MaxResponseTime: maxRespTime,
+		AvgResponseTime:   avgResponseTime,
+		TotalResponseTime: values.totalResponseTime,
+		MinResponseTime:   values.minResponseTime,
+		MaxResponseTime: values.maxResponseTime,
 		
 		// 令牌统计
 		TotalTokens:      atomic.LoadInt64(&m.totalTokens),
 		ActiveTokens:     atomic.LoadInt64(&m.activeTokens),
-		TokenValidations: atomic.LoadInt64(&m.tokenValidations),
+		TokenValidations: values.tokenValidations,
 		
 		// 配置统计
 		TotalConfigs:  atomic.LoadInt64(&m.totalConfigs),
 		ActiveConfigs: atomic.LoadInt64(&m.activeConfigs),
 		
+		// 镜像请求统计
+		MirrorSuccess:  values.mirrorSuccess,
+		MirrorFailures: values.mirrorFailures,
+		
+		// 上游错误分类统计
+		UpstreamErrors: upstreamErrors,
+		
 		// 系统资源
 		MemoryUsage:    m.memStats.Alloc,
 		MemoryTotal:    m.memStats.TotalAlloc,
@@ -154,9 +289,10 @@ func (m *Metrics) GetSnapshot() *Snapshot {
 		Goroutines:     int64(runtime.NumGoroutine()),
 		
 		// 历史数据
-		RequestHistory:  m.requestHistory,
-		ResponseHistory: m.responseHistory,
-		ErrorHistory:    m.errorHistory,
+		RequestHistory:         append([]int64(nil), m.requestHistory...),
+		ResponseHistory:        append([]int64(nil), m.responseHistory...),
+		ErrorHistory:           append([]int64(nil), m.errorHistory...),
+		HistoryIntervalSeconds: int64(m.historyInterval / time.Second),
 	}
 }
 
@@ -171,8 +307,9 @@ type Snapshot struct {
 	SuccessRate     float64 `json:"success_rate"`
 	
 	// 响应时间统计 (毫秒)
-	AvgResponseTime int64 `json:"avg_response_time"`
-	MinResponseTime int64 `json:"min_response_time"`
+	AvgResponseTime   int64 `json:"avg_response_time"`
+	TotalResponseTime int64 `json:"total_response_time"` // 累计响应时间，用于计算区间平均值
+	MinResponseTime   int64 `json:"min_response_time"`
 	MaxResponseTime int64 `json:"max_response_time"`
 	
 	// 令牌统计
@@ -184,26 +321,39 @@ type Snapshot struct {
 	TotalConfigs  int64 `json:"total_configs"`
 	ActiveConfigs int64 `json:"active_configs"`
 	
+	// 镜像请求统计
+	MirrorSuccess  int64 `json:"mirror_success"`
+	MirrorFailures int64 `json:"mirror_failures"`
+	
+	// 上游错误分类统计（dns、connection_refused、connection、timeout、tls、upstream_5xx）
+	UpstreamErrors map[string]int64 `json:"upstream_errors"`
+	
 	// 系统资源
 	MemoryUsage uint64 `json:"memory_usage"`
 	MemoryTotal uint64 `json:"memory_total"`
 	GCCount     uint32 `json:"gc_count"`
 	Goroutines  int64  `json:"goroutines"`
 	
-	// 历史数据（最近60分钟）
-	RequestHistory  [60]int64 `json:"request_history"`
-	ResponseHistory [60]int64 `json:"response_history"`
-	ErrorHistory    [60]int64 `json:"error_history"`
+	// 历史数据（数据点数和间隔由NewMetricsWithHistory指定，默认最近60分钟）
+	RequestHistory         []int64 `json:"request_history"`
+	ResponseHistory        []int64 `json:"response_history"`
+	ErrorHistory           []int64 `json:"error_history"`
+	HistoryIntervalSeconds int64   `json:"history_interval_seconds"`
 }
 
 // updateLoop 定期更新系统指标
 func (m *Metrics) updateLoop() {
-	ticker := time.NewTicker(30 * time.Second)
+	// 历史数据间隔小于30秒时按间隔检查，避免漏掉数据点
+	tick := 30 * time.Second
+	if m.historyInterval < tick {
+		tick = m.historyInterval
+	}
+	ticker := time.NewTicker(tick)
 	defer ticker.Stop()
 	
-	for range ticker.C {
+	for now := range ticker.C {
 		m.updateSystemMetrics()
-		m.updateHistory()
+		m.updateHistory(now)
 	}
 }
 
@@ -217,47 +367,57 @@ func (m *Metrics) updateSystemMetrics() {
 }
 
 // updateHistory 更新历史数据
-func (m *Metrics) updateHistory() {
-	now := time.Now()
-	
+func (m *Metrics) updateHistory(now time.Time) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	
-	// 每分钟更新一次历史数据
-	if now.Sub(m.lastHistoryUpdate) >= time.Minute {
-		// 计算当前分钟的请求数
+	// 每个间隔更新一次历史数据
+	if now.Sub(m.lastHistoryUpdate) >= m.historyInterval {
 		currentRequests := atomic.LoadInt64(&m.totalRequests)
 		currentErrors := atomic.LoadInt64(&m.errorRequests)
 		currentResponseTime := atomic.LoadInt64(&m.totalResponseTime)
 		
-		// 更新历史数组
-		m.requestHistory[m.historyIndex] = currentRequests
-		m.errorHistory[m.historyIndex] = currentErrors
+		// 记录本间隔内的请求数和错误数（与上一个数据点的差值）
+		intervalRequests := currentRequests - m.lastHistoryRequests
+		m.requestHistory[m.historyIndex] = intervalRequests
+		m.errorHistory[m.historyIndex] = currentErrors - m.lastHistoryErrors
 		
-		// 计算平均响应时间
-		if currentRequests > 0 {
-			m.responseHistory[m.historyIndex] = currentResponseTime / currentRequests
+		// 计算本间隔内的平均响应时间（没有请求时为0）
+		m.responseHistory[m.historyIndex] = 0
+		if intervalRequests > 0 {
+			m.responseHistory[m.historyIndex] = (currentResponseTime - m.lastHistoryResponseTime) / intervalRequests
 		}
 		
+		m.lastHistoryRequests = currentRequests
+		m.lastHistoryErrors = currentErrors
+		m.lastHistoryResponseTime = currentResponseTime
+		
 		// 移动到下一个位置
-		m.historyIndex = (m.historyIndex + 1) % 60
+		m.historyIndex = (m.historyIndex + 1) % len(m.requestHistory)
 		m.lastHistoryUpdate = now
 	}
 }
 
-// Reset 重置所有计数器
-func (m *Metrics) Reset() {
-	atomic.StoreInt64(&m.totalRequests, 0)
-	atomic.StoreInt64(&m.successRequests, 0)
-	atomic.StoreInt64(&m.errorRequests, 0)
-	atomic.StoreInt64(&m.totalResponseTime, 0)
-	atomic.StoreInt64(&m.minResponseTime, int64(^uint64(0)>>1))
-	atomic.StoreInt64(&m.maxResponseTime, 0)
-	atomic.StoreInt64(&m.tokenValidations, 0)
-	
+// Reset 重置所有计数器，返回重置前的快照
+//
+// 计数器通过原子交换清零，重置期间并发记录的请求不会丢失（同一请求的各项计数可能分别落在重置前后）。
+func (m *Metrics) Reset() *Snapshot {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	
+	snapshot := m.buildSnapshot(counterValues{
+		totalRequests:     atomic.SwapInt64(&m.totalRequests, 0),
+		successRequests:   atomic.SwapInt64(&m.successRequests, 0),
+		errorRequests:     atomic.SwapInt64(&m.errorRequests, 0),
+		totalResponseTime: atomic.SwapInt64(&m.totalResponseTime, 0),
+		minResponseTime:   atomic.SwapInt64(&m.minResponseTime, int64(^uint64(0)>>1)),
+		maxResponseTime:   atomic.SwapInt64(&m.maxResponseTime, 0),
+		tokenValidations:  atomic.SwapInt64(&m.tokenValidations, 0),
+		mirrorSuccess:     atomic.SwapInt64(&m.mirrorSuccess, 0),
+		mirrorFailures:    atomic.SwapInt64(&m.mirrorFailures, 0),
+		upstreamErrors:    m.loadUpstreamErrors(true),
+	})
+	
 	// 清空历史数据
 	for i := range m.requestHistory {
 		m.requestHistory[i] = 0
@@ -266,6 +426,11 @@ func (m *Metrics) Reset() {
 	}
 	m.historyIndex = 0
 	m.lastHistoryUpdate = time.Now()
+	m.lastHistoryRequests = 0
+	m.lastHistoryErrors = 0
+	m.lastHistoryResponseTime = 0
+	
+	return snapshot
 }
 
 // GetHealthStatus 获取健康状态
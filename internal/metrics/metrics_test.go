@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"sync"
 	"testing"
 	"time"
 )
@@ -142,6 +143,106 @@ func TestReset(t *testing.T) {
 	// 注意：令牌和配置计数不会被重置，因为它们是当前状态而不是累计值
 }
 
+func TestResetReturnsSnapshotUnderConcurrency(t *testing.T) {
+	m := NewMetrics()
+	
+	// 并发记录请求的同时多次清零，所有请求都应计入某次快照或最终计数
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.RecordRequest(time.Millisecond, true)
+			}
+		}()
+	}
+	
+	var counted int64
+	for i := 0; i < 5; i++ {
+		counted += m.Reset().TotalRequests
+	}
+	wg.Wait()
+	counted += m.Reset().TotalRequests
+	
+	if counted != 1000 {
+		t.Errorf("Expected 1000 requests across snapshots, got %d", counted)
+	}
+	if snapshot := m.GetSnapshot(); snapshot.TotalRequests != 0 || snapshot.RequestHistory[0] != 0 {
+		t.Errorf("Expected counters and history to be zero after reset, got %d", snapshot.TotalRequests)
+	}
+}
+
+func TestCustomHistoryRollover(t *testing.T) {
+	m := NewMetricsWithHistory(3, 5*time.Minute)
+	start := m.lastHistoryUpdate
+	
+	snapshot := m.GetSnapshot()
+	if len(snapshot.RequestHistory) != 3 || snapshot.HistoryIntervalSeconds != 300 {
+		t.Fatalf("Expected 3 buckets of 300s, got %d buckets of %ds", len(snapshot.RequestHistory), snapshot.HistoryIntervalSeconds)
+	}
+	
+	// 未到间隔时不写入数据点
+	m.RecordRequest(10*time.Millisecond, true)
+	m.updateHistory(start.Add(4 * time.Minute))
+	if snapshot := m.GetSnapshot(); snapshot.RequestHistory[0] != 0 {
+		t.Errorf("Expected no bucket before the interval elapsed, got %v", snapshot.RequestHistory)
+	}
+	
+	// 每满一个间隔写入一个数据点
+	m.updateHistory(start.Add(5 * time.Minute))
+	m.RecordRequest(10*time.Millisecond, false)
+	m.updateHistory(start.Add(10 * time.Minute))
+	m.updateHistory(start.Add(15 * time.Minute))
+	snapshot = m.GetSnapshot()
+	if snapshot.RequestHistory[0] != 1 || snapshot.RequestHistory[1] != 1 || snapshot.RequestHistory[2] != 0 || snapshot.ErrorHistory[1] != 1 {
+		t.Errorf("Expected buckets [1 1 0] with 1 error in bucket 1, got %v errors %v", snapshot.RequestHistory, snapshot.ErrorHistory)
+	}
+	
+	// 写满后回到第一个数据点覆盖
+	m.RecordRequest(10*time.Millisecond, true)
+	m.RecordRequest(10*time.Millisecond, true)
+	m.updateHistory(start.Add(20 * time.Minute))
+	if snapshot := m.GetSnapshot(); snapshot.RequestHistory[0] != 2 || m.historyIndex != 1 {
+		t.Errorf("Expected rollover to overwrite bucket 0 with 2, got %v (index %d)", snapshot.RequestHistory, m.historyIndex)
+	}
+	
+	// 快照中的历史数据是副本
+	snapshot.RequestHistory[2] = 100
+	if m.GetSnapshot().RequestHistory[2] == 100 {
+		t.Error("Expected snapshot history to be a copy")
+	}
+}
+
+func TestHistoryStoresPerIntervalDeltas(t *testing.T) {
+	m := NewMetricsWithHistory(10, time.Minute)
+	start := m.lastHistoryUpdate
+	
+	// 第一个间隔：3个请求（1个错误），平均100ms
+	m.RecordRequest(50*time.Millisecond, true)
+	m.RecordRequest(100*time.Millisecond, true)
+	m.RecordRequest(150*time.Millisecond, false)
+	m.updateHistory(start.Add(time.Minute))
+	
+	// 第二个间隔：1个请求，400ms
+	m.RecordRequest(400*time.Millisecond, true)
+	m.updateHistory(start.Add(2 * time.Minute))
+	
+	// 第三个间隔：没有请求
+	m.updateHistory(start.Add(3 * time.Minute))
+	
+	snapshot := m.GetSnapshot()
+	if snapshot.RequestHistory[0] != 3 || snapshot.RequestHistory[1] != 1 || snapshot.RequestHistory[2] != 0 {
+		t.Errorf("Expected per-interval request counts [3 1 0], got %v", snapshot.RequestHistory[:3])
+	}
+	if snapshot.ErrorHistory[0] != 1 || snapshot.ErrorHistory[1] != 0 {
+		t.Errorf("Expected per-interval error counts [1 0], got %v", snapshot.ErrorHistory[:2])
+	}
+	if snapshot.ResponseHistory[0] != 100 || snapshot.ResponseHistory[1] != 400 || snapshot.ResponseHistory[2] != 0 {
+		t.Errorf("Expected per-interval average response times [100 400 0], got %v", snapshot.ResponseHistory[:3])
+	}
+}
+
 func TestGetHealthStatus(t *testing.T) {
 	m := NewMetrics()
 	
@@ -1,25 +1,129 @@
 package proxy
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"privacygateway/internal/config"
 
+	"golang.org/x/net/http/httpproxy"
 	"golang.org/x/net/proxy"
 )
 
+// upstreamTransports 缓存的上游传输层（保留连接池以复用连接）
+var upstreamTransports sync.Map
+
+// transportKey 上游传输层的缓存键
+//
+// 带Key（配置ID）的请求按配置隔离，不同配置之间不共享连接和TLS会话；
+// 不属于任何配置的请求按出站代理区分，同一代理共用传输层。
+type transportKey struct {
+	guarded       bool // 直连时是否在建立连接前检查目标IP
+	allowPrivate  bool
+	allowLoopback bool
+	upstreamKey   string
+	proxy         string // 出站代理标识（仅upstreamKey为空时参与区分）
+}
+
+// transportSettings 构建传输层时使用的设置，任一项变化时替换传输层
+type transportSettings struct {
+	tlsConfig *tls.Config
+	pool      TransportPool
+	proxy     string
+}
+
+// transportEntry 传输层缓存条目
+type transportEntry struct {
+	settings  transportSettings
+	transport *http.Transport
+}
+
+// UpstreamOptions 按配置定制的上游连接设置（mTLS客户端证书、自定义CA、连接池及响应头超时）
+//
+// Key（通常为配置ID）相同的请求复用同一传输层；TLS（证书更新）、Pool或出站代理
+// 变化时替换传输层并关闭旧的空闲连接。
+type UpstreamOptions struct {
+	Key  string
+	TLS  *tls.Config    // 为nil时使用默认TLS设置
+	Pool *TransportPool // 覆盖全局连接池设置的非零字段，为nil时使用全局设置
+}
+
+// tlsConfig 返回上游TLS配置（未定制时为nil）
+func (o *UpstreamOptions) tlsConfig() *tls.Config {
+	if o == nil {
+		return nil
+	}
+	return o.TLS
+}
+
+// pool 返回全局连接池设置与配置覆盖合并后的结果
+func (o *UpstreamOptions) pool() TransportPool {
+	pool := CurrentTransportPool()
+	if o != nil && o.Pool != nil {
+		pool = pool.Merge(*o.Pool)
+	}
+	return pool
+}
+
+// LimitRedirects 设置客户端跟随重定向的方式
+//
+// maxRedirects为0时不跟随重定向，3xx响应原样返回给调用方；否则最多跟随
+// maxRedirects次，policy不为nil时每一跳的目标都需要通过策略检查。
+func LimitRedirects(client *http.Client, maxRedirects int, policy *TargetPolicy) {
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if maxRedirects <= 0 {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if policy != nil {
+			return policy.CheckURL(req.URL)
+		}
+		return nil
+	}
+}
+
 // CreateHTTPClient 根据代理配置创建HTTP客户端
-func CreateHTTPClient(proxyConfig *config.ProxyConfig) (*http.Client, error) {
+//
+// policy不为nil时，重定向目标同样需要通过策略检查；直连时还会在建立连接前
+// 检查实际连接的IP地址。经上游代理转发时由代理负责解析，仅依赖请求前的检查。
+// upstream不为nil时使用其中的TLS配置和连接池设置连接上游。传输层按upstream.Key
+// 缓存复用（不同配置互不共享连接），设置变化时重建；连接池设置见SetTransportPool。
+func CreateHTTPClient(proxyConfig *config.ProxyConfig, policy *TargetPolicy, upstream *UpstreamOptions) (*http.Client, error) {
 	// 默认客户端配置
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
 
-	// 如果没有代理配置，返回默认客户端
+	if policy != nil {
+		LimitRedirects(client, 10, policy)
+	}
+
+	key := transportKey{}
+	if policy != nil {
+		key = transportKey{guarded: true, allowPrivate: policy.AllowPrivate, allowLoopback: policy.AllowLoopback}
+	}
+	if upstream != nil {
+		key.upstreamKey = upstream.Key
+	}
+	settings := transportSettings{tlsConfig: upstream.tlsConfig(), pool: upstream.pool()}
+
+	// 如果没有代理配置，直连目标
 	if proxyConfig == nil || proxyConfig.URL == "" {
+		if policy == nil && upstream == nil {
+			return client, nil
+		}
+		client.Transport, _ = cachedTransport(key, settings, func() (*http.Transport, error) {
+			return directTransport(key, settings), nil
+		})
 		return client, nil
 	}
 
@@ -28,20 +132,80 @@ func CreateHTTPClient(proxyConfig *config.ProxyConfig) (*http.Client, error) {
 		client.Timeout = time.Duration(proxyConfig.Timeout) * time.Second
 	}
 
+	settings.proxy = proxyIdentity(proxyConfig)
+	if key.upstreamKey == "" {
+		key.proxy = settings.proxy
+	}
+	transport, err := cachedTransport(key, settings, func() (*http.Transport, error) {
+		return proxiedTransport(proxyConfig, key, settings)
+	})
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = transport
+	return client, nil
+}
+
+// cachedTransport 获取缓存的传输层，不存在或设置变化时调用build重建，并关闭旧传输层的空闲连接
+func cachedTransport(key transportKey, settings transportSettings, build func() (*http.Transport, error)) (*http.Transport, error) {
+	if cached, ok := upstreamTransports.Load(key); ok {
+		entry := cached.(*transportEntry)
+		if entry.settings == settings {
+			return entry.transport, nil
+		}
+	}
+
+	transport, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &transportEntry{settings: settings, transport: transport}
+	if previous, loaded := upstreamTransports.Swap(key, entry); loaded {
+		previous.(*transportEntry).transport.CloseIdleConnections()
+	}
+	return transport, nil
+}
+
+// ipPolicy 返回建立连接前检查目标IP的策略（IP检查只依赖两个开关），未启用检查时返回nil
+func (k transportKey) ipPolicy() *TargetPolicy {
+	if !k.guarded {
+		return nil
+	}
+	return &TargetPolicy{AllowPrivate: k.allowPrivate, AllowLoopback: k.allowLoopback}
+}
+
+// directTransport 创建直连传输层
+func directTransport(key transportKey, settings transportSettings) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	if policy := key.ipPolicy(); policy != nil {
+		transport.DialContext = policy.DialContext
+	}
+	if settings.tlsConfig != nil {
+		transport.TLSClientConfig = settings.tlsConfig
+	}
+	settings.pool.apply(transport)
+	return transport
+}
+
+// proxiedTransport 创建经出站代理转发的传输层（NO_PROXY列表中的目标直连）
+func proxiedTransport(proxyConfig *config.ProxyConfig, key transportKey, settings transportSettings) (*http.Transport, error) {
 	// 解析代理URL
 	proxyURL, err := url.Parse(proxyConfig.URL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid proxy URL: %v", err)
 	}
 
-	// 根据代理类型创建传输层
+	var transport *http.Transport
 	switch proxyConfig.Type {
 	case "http", "https":
 		// HTTP代理
-		transport := &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
+		transport = &http.Transport{
+			Proxy:           ProxyFunc(proxyURL, proxyConfig.NoProxy),
+			DialContext:     DirectDialContext(key.ipPolicy(), proxyURL),
+			TLSClientConfig: settings.tlsConfig,
 		}
-		client.Transport = transport
 
 	case "socks5":
 		// SOCKS5代理
@@ -58,14 +222,95 @@ func CreateHTTPClient(proxyConfig *config.ProxyConfig) (*http.Client, error) {
 			return nil, fmt.Errorf("failed to create SOCKS5 proxy: %v", err)
 		}
 
-		transport := &http.Transport{
-			Dial: dialer.Dial,
+		perHost := proxy.NewPerHost(dialer, directDialer(DirectDialContext(key.ipPolicy(), proxyURL)))
+		perHost.AddFromString(strings.Join(proxyConfig.NoProxy, ","))
+
+		transport = &http.Transport{
+			DialContext:     perHost.DialContext,
+			TLSClientConfig: settings.tlsConfig,
 		}
-		client.Transport = transport
 
 	default:
 		return nil, fmt.Errorf("unsupported proxy type: %s", proxyConfig.Type)
 	}
 
-	return client, nil
+	settings.pool.apply(transport)
+	return transport, nil
+}
+
+// proxyIdentity 返回区分出站代理设置的标识（类型、地址、认证和NO_PROXY列表）
+func proxyIdentity(proxyConfig *config.ProxyConfig) string {
+	identity := proxyConfig.Type + "|" + proxyConfig.URL + "|" + strings.Join(proxyConfig.NoProxy, ",")
+	if proxyConfig.Auth != nil {
+		identity += "|" + proxyConfig.Auth.Username + ":" + proxyConfig.Auth.Password
+	}
+	return identity
+}
+
+// ProxyFunc 返回HTTP代理选择函数，匹配noProxy的目标不走代理
+//
+// noProxy使用NO_PROXY格式（域名后缀、IP、CIDR、*），
+// 与标准库一致，localhost和回环地址始终直连。
+func ProxyFunc(proxyURL *url.URL, noProxy []string) func(*http.Request) (*url.URL, error) {
+	if len(noProxy) == 0 {
+		return http.ProxyURL(proxyURL)
+	}
+
+	selector := (&httpproxy.Config{
+		HTTPProxy:  proxyURL.String(),
+		HTTPSProxy: proxyURL.String(),
+		NoProxy:    strings.Join(noProxy, ","),
+	}).ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return selector(req.URL)
+	}
+}
+
+// DirectDialContext 返回直连拨号函数：连接代理服务器本身时不受目标访问策略限制，
+// 其余直连目标（如NO_PROXY命中的主机）仍需通过策略检查
+func DirectDialContext(policy *TargetPolicy, proxyURL *url.URL) func(ctx context.Context, network, address string) (net.Conn, error) {
+	plain := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if policy == nil {
+		return plain.DialContext
+	}
+
+	proxyAddr := proxyHostPort(proxyURL)
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address == proxyAddr {
+			return plain.DialContext(ctx, network, address)
+		}
+		return policy.DialContext(ctx, network, address)
+	}
+}
+
+// proxyHostPort 返回代理服务器的host:port（补全默认端口）
+func proxyHostPort(proxyURL *url.URL) string {
+	if proxyURL.Port() != "" {
+		return proxyURL.Host
+	}
+	port := "80"
+	switch proxyURL.Scheme {
+	case "https":
+		port = "443"
+	case "socks5":
+		port = "1080"
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// directDialer 将拨号函数适配为proxy.Dialer，用于SOCKS5的NO_PROXY直连
+type directDialer func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Dial 实现proxy.Dialer接口
+func (d directDialer) Dial(network, address string) (net.Conn, error) {
+	return d(context.Background(), network, address)
+}
+
+// DialContext 实现proxy.ContextDialer接口
+func (d directDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d(ctx, network, address)
 }
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"privacygateway/internal/config"
+)
+
+func TestCreateHTTPClient_UpstreamProxy(t *testing.T) {
+	proxyConfig := &config.ProxyConfig{
+		URL:     "http://proxy.corp.example.com:3128",
+		Type:    "http",
+		NoProxy: []string{"internal.example.com", "10.0.0.0/8"},
+	}
+
+	client, err := CreateHTTPClient(proxyConfig, &TargetPolicy{}, nil)
+	if err != nil {
+		t.Fatalf("CreateHTTPClient() error = %v", err)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		t.Fatalf("Expected transport with proxy function, got %T", client.Transport)
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"https://api.example.com/v1", "http://proxy.corp.example.com:3128"},
+		{"http://api.example.com/v1", "http://proxy.corp.example.com:3128"},
+		{"https://internal.example.com/", ""},
+		{"https://svc.internal.example.com/", ""},
+		{"http://10.1.2.3/", ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		proxyURL, err := transport.Proxy(req)
+		if err != nil {
+			t.Errorf("Proxy(%s) error = %v", tt.target, err)
+			continue
+		}
+
+		got := ""
+		if proxyURL != nil {
+			got = proxyURL.String()
+		}
+		if got != tt.want {
+			t.Errorf("Proxy(%s) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCreateHTTPClient_SOCKS5Proxy(t *testing.T) {
+	proxyConfig := &config.ProxyConfig{
+		URL:     "socks5://proxy.corp.example.com:1080",
+		Type:    "socks5",
+		NoProxy: []string{"internal.example.com"},
+	}
+
+	client, err := CreateHTTPClient(proxyConfig, &TargetPolicy{}, nil)
+	if err != nil {
+		t.Fatalf("CreateHTTPClient() error = %v", err)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || transport.DialContext == nil {
+		t.Fatalf("Expected transport with SOCKS5 dialer, got %T", client.Transport)
+	}
+	if transport.Proxy != nil {
+		t.Error("Expected no HTTP proxy function for SOCKS5")
+	}
+}
+
+func TestCreateHTTPClient_TransportIsolation(t *testing.T) {
+	policy := &TargetPolicy{}
+	transportOf := func(upstream *UpstreamOptions, proxyConfig *config.ProxyConfig) *http.Transport {
+		t.Helper()
+		client, err := CreateHTTPClient(proxyConfig, policy, upstream)
+		if err != nil {
+			t.Fatalf("CreateHTTPClient() error = %v", err)
+		}
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("Expected *http.Transport, got %T", client.Transport)
+		}
+		return transport
+	}
+
+	tlsA := &tls.Config{ServerName: "a.internal"}
+	tlsB := &tls.Config{InsecureSkipVerify: true}
+	a := transportOf(&UpstreamOptions{Key: "config-a", TLS: tlsA}, nil)
+	b := transportOf(&UpstreamOptions{Key: "config-b", TLS: tlsB}, nil)
+
+	// 不同配置使用不同的传输层和各自的TLS设置
+	if a == b {
+		t.Fatal("Expected configs with different TLS settings to get distinct transports")
+	}
+	if a.TLSClientConfig != tlsA || b.TLSClientConfig != tlsB {
+		t.Error("Expected each transport to use its config's TLS settings")
+	}
+
+	// 设置未变化时复用传输层
+	if again := transportOf(&UpstreamOptions{Key: "config-a", TLS: tlsA}, nil); again != a {
+		t.Error("Expected unchanged config to reuse its transport")
+	}
+
+	// 即使设置相同，不同配置也不共享传输层
+	c := transportOf(&UpstreamOptions{Key: "config-c", TLS: tlsA}, nil)
+	if c == a {
+		t.Error("Expected different configs not to share a transport")
+	}
+
+	// 配置更新后重建传输层，其他配置不受影响
+	updatedTLS := &tls.Config{ServerName: "a-rotated.internal"}
+	rebuilt := transportOf(&UpstreamOptions{Key: "config-a", TLS: updatedTLS}, nil)
+	if rebuilt == a || rebuilt.TLSClientConfig != updatedTLS {
+		t.Error("Expected updated config to get a rebuilt transport")
+	}
+	if transportOf(&UpstreamOptions{Key: "config-b", TLS: tlsB}, nil) != b {
+		t.Error("Expected other configs to keep their transport")
+	}
+
+	// 出站代理变化同样触发重建
+	viaProxy := transportOf(&UpstreamOptions{Key: "config-b", TLS: tlsB}, &config.ProxyConfig{URL: "http://proxy.corp.example.com:3128", Type: "http"})
+	if viaProxy == b || viaProxy.Proxy == nil {
+		t.Error("Expected switching to an outbound proxy to rebuild the transport")
+	}
+	if transportOf(&UpstreamOptions{Key: "config-b", TLS: tlsB}, &config.ProxyConfig{URL: "http://proxy.corp.example.com:3128", Type: "http"}) != viaProxy {
+		t.Error("Expected proxied transport to be reused")
+	}
+}
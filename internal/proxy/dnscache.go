@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCacheMaxEntries DNS缓存最多保存的主机数，超出时先清理过期条目
+const dnsCacheMaxEntries = 10000
+
+// defaultDNSCache 全局DNS缓存（为nil时不缓存，每次连接都重新解析）
+var (
+	defaultDNSCache   *DNSCache
+	defaultDNSCacheMu sync.RWMutex
+)
+
+// SetDNSCache 设置直连拨号和目标检查使用的全局DNS缓存，传入nil关闭缓存
+func SetDNSCache(cache *DNSCache) {
+	defaultDNSCacheMu.Lock()
+	defer defaultDNSCacheMu.Unlock()
+	defaultDNSCache = cache
+}
+
+// currentDNSCache 获取全局DNS缓存
+func currentDNSCache() *DNSCache {
+	defaultDNSCacheMu.RLock()
+	defer defaultDNSCacheMu.RUnlock()
+	return defaultDNSCache
+}
+
+// dnsCacheEntry DNS缓存条目
+type dnsCacheEntry struct {
+	addrs     []net.IPAddr
+	err       error // 域名不存在时缓存的错误（负缓存）
+	expiresAt time.Time
+}
+
+// DNSCache 带TTL的域名解析缓存（实现Resolver接口）
+//
+// 成功的解析结果缓存ttl，域名不存在（NXDOMAIN）缓存negativeTTL；
+// 超时等临时错误不缓存。缓存只保存解析结果，连接前仍会逐个检查IP。
+type DNSCache struct {
+	resolver    Resolver
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]dnsCacheEntry
+	now     func() time.Time
+}
+
+// NewDNSCache 创建DNS缓存，resolver为nil时使用net.DefaultResolver
+func NewDNSCache(resolver Resolver, ttl, negativeTTL time.Duration) *DNSCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &DNSCache{
+		resolver:    resolver,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]dnsCacheEntry),
+		now:         time.Now,
+	}
+}
+
+// LookupIPAddr 解析主机名，缓存未过期时直接返回缓存结果
+func (c *DNSCache) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	now := c.now()
+
+	c.mutex.Lock()
+	entry, ok := c.entries[host]
+	c.mutex.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		return copyIPAddrs(entry.addrs), nil
+	}
+
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound && c.negativeTTL > 0 {
+			c.store(host, dnsCacheEntry{err: err, expiresAt: now.Add(c.negativeTTL)})
+		}
+		return nil, err
+	}
+
+	if c.ttl > 0 && len(addrs) > 0 {
+		c.store(host, dnsCacheEntry{addrs: copyIPAddrs(addrs), expiresAt: now.Add(c.ttl)})
+	}
+	return addrs, nil
+}
+
+// Len 返回缓存条目数（包含尚未清理的过期条目）
+func (c *DNSCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.entries)
+}
+
+// store 保存缓存条目，达到上限时先清理过期条目，仍然已满则清空
+func (c *DNSCache) store(host string, entry dnsCacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.entries[host]; !exists && len(c.entries) >= dnsCacheMaxEntries {
+		now := c.now()
+		for key, cached := range c.entries {
+			if !now.Before(cached.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= dnsCacheMaxEntries {
+			c.entries = make(map[string]dnsCacheEntry)
+		}
+	}
+
+	c.entries[host] = entry
+}
+
+// copyIPAddrs 复制解析结果，避免调用方修改缓存内容
+func copyIPAddrs(addrs []net.IPAddr) []net.IPAddr {
+	result := make([]net.IPAddr, len(addrs))
+	copy(result, addrs)
+	return result
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingResolver 记录解析次数的解析器
+type countingResolver struct {
+	addrs map[string][]net.IPAddr
+	err   error
+	calls int32
+}
+
+func (r *countingResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	atomic.AddInt32(&r.calls, 1)
+	if r.err != nil {
+		return nil, r.err
+	}
+	addrs, ok := r.addrs[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func (r *countingResolver) count() int {
+	return int(atomic.LoadInt32(&r.calls))
+}
+
+func TestDNSCache_CachesWithinTTL(t *testing.T) {
+	resolver := &countingResolver{addrs: map[string][]net.IPAddr{
+		"api.example.com": {{IP: net.ParseIP("93.184.216.34")}},
+	}}
+	cache := NewDNSCache(resolver, time.Minute, 0)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		addrs, err := cache.LookupIPAddr(context.Background(), "api.example.com")
+		if err != nil || len(addrs) != 1 {
+			t.Fatalf("LookupIPAddr() = %v, %v", addrs, err)
+		}
+	}
+	if resolver.count() != 1 {
+		t.Errorf("Expected 1 lookup within TTL, got %d", resolver.count())
+	}
+
+	// 过期后重新解析
+	now = now.Add(2 * time.Minute)
+	cache.LookupIPAddr(context.Background(), "api.example.com")
+	if resolver.count() != 2 {
+		t.Errorf("Expected re-resolve after TTL, got %d lookups", resolver.count())
+	}
+}
+
+func TestDNSCache_NegativeCaching(t *testing.T) {
+	resolver := &countingResolver{}
+	cache := NewDNSCache(resolver, time.Minute, 10*time.Second)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.LookupIPAddr(context.Background(), "missing.example.com"); err == nil {
+			t.Fatal("Expected NXDOMAIN error")
+		}
+	}
+	if resolver.count() != 1 {
+		t.Errorf("Expected NXDOMAIN to be cached, got %d lookups", resolver.count())
+	}
+
+	// 临时错误不缓存
+	resolver.err = &net.DNSError{Err: "i/o timeout", Name: "flaky.example.com", IsTimeout: true}
+	cache.LookupIPAddr(context.Background(), "flaky.example.com")
+	cache.LookupIPAddr(context.Background(), "flaky.example.com")
+	if resolver.count() != 3 {
+		t.Errorf("Expected temporary errors not to be cached, got %d lookups", resolver.count())
+	}
+}
+
+func TestTargetPolicy_DialContextUsesCacheAndChecksIP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	resolver := &countingResolver{addrs: map[string][]net.IPAddr{
+		"upstream.test": {{IP: net.ParseIP("127.0.0.1")}},
+	}}
+	cache := NewDNSCache(resolver, time.Minute, 0)
+	address := net.JoinHostPort("upstream.test", port)
+
+	allowed := &TargetPolicy{AllowLoopback: true, Resolver: cache}
+	for i := 0; i < 2; i++ {
+		conn, err := allowed.DialContext(context.Background(), "tcp", address)
+		if err != nil {
+			t.Fatalf("DialContext() error = %v", err)
+		}
+		conn.Close()
+	}
+	if resolver.count() != 1 {
+		t.Errorf("Expected second dial to use cached address, got %d lookups", resolver.count())
+	}
+
+	// 缓存的IP在每次连接前仍需通过检查
+	blocked := &TargetPolicy{Resolver: cache}
+	if _, err := blocked.DialContext(context.Background(), "tcp", address); !errors.Is(err, ErrTargetBlocked) {
+		t.Errorf("Expected cached loopback address to be blocked, got %v", err)
+	}
+	if resolver.count() != 1 {
+		t.Errorf("Expected no additional lookups, got %d", resolver.count())
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"privacygateway/internal/config"
 )
 
+// DirectProxy 配置级upstream_proxy取该值时不使用全局出站代理，直接连接目标
+const DirectProxy = "direct"
+
 // ParseSimple 解析简单的代理URL（来自查询参数）
 func ParseSimple(proxyURL string) (*config.ProxyConfig, error) {
 	if proxyURL == "" {
@@ -85,3 +88,8 @@ func GetConfig(r *http.Request, defaultProxy *config.ProxyConfig) (*config.Proxy
 	// 返回默认配置
 	return defaultProxy, nil
 }
+
+// IsRequestSupplied 判断请求是否通过请求头或查询参数自行指定了代理
+func IsRequestSupplied(r *http.Request) bool {
+	return r.Header.Get("X-Proxy-Config") != "" || r.URL.Query().Get("proxy") != ""
+}
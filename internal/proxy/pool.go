@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TransportPool 上游传输层的连接池和超时设置，字段为0时使用http.DefaultTransport的默认值
+type TransportPool struct {
+	MaxIdleConns          int           // 所有主机的最大空闲连接数
+	MaxIdleConnsPerHost   int           // 每个主机的最大空闲连接数（默认2）
+	IdleConnTimeout       time.Duration // 空闲连接的保留时间
+	DisableKeepAlives     bool          // 禁用连接复用，每个请求使用新连接
+	ResponseHeaderTimeout time.Duration // 发送请求后等待上游响应头的最长时间（默认不限制，只受整体请求超时约束）
+}
+
+// defaultTransportPool 全局连接池设置（为nil时使用http.DefaultTransport的默认值）
+var (
+	defaultTransportPool   *TransportPool
+	defaultTransportPoolMu sync.RWMutex
+)
+
+// SetTransportPool 设置上游传输层使用的全局连接池设置，传入nil恢复默认值
+//
+// 已缓存的传输层会被丢弃并关闭空闲连接，后续请求按新设置重建。
+func SetTransportPool(pool *TransportPool) {
+	defaultTransportPoolMu.Lock()
+	defaultTransportPool = pool
+	defaultTransportPoolMu.Unlock()
+
+	upstreamTransports.Range(func(key, value interface{}) bool {
+		upstreamTransports.Delete(key)
+		value.(*transportEntry).transport.CloseIdleConnections()
+		return true
+	})
+}
+
+// CurrentTransportPool 获取全局连接池设置（未设置时返回零值，即使用默认值）
+func CurrentTransportPool() TransportPool {
+	defaultTransportPoolMu.RLock()
+	defer defaultTransportPoolMu.RUnlock()
+	if defaultTransportPool == nil {
+		return TransportPool{}
+	}
+	return *defaultTransportPool
+}
+
+// Merge 用override中的非零字段覆盖当前设置，返回合并后的设置
+func (p TransportPool) Merge(override TransportPool) TransportPool {
+	if override.MaxIdleConns > 0 {
+		p.MaxIdleConns = override.MaxIdleConns
+	}
+	if override.MaxIdleConnsPerHost > 0 {
+		p.MaxIdleConnsPerHost = override.MaxIdleConnsPerHost
+	}
+	if override.IdleConnTimeout > 0 {
+		p.IdleConnTimeout = override.IdleConnTimeout
+	}
+	if override.DisableKeepAlives {
+		p.DisableKeepAlives = true
+	}
+	if override.ResponseHeaderTimeout > 0 {
+		p.ResponseHeaderTimeout = override.ResponseHeaderTimeout
+	}
+	return p
+}
+
+// apply 将连接池设置应用到传输层（零值字段保持传输层原有的值）
+func (p TransportPool) apply(transport *http.Transport) {
+	if p.MaxIdleConns > 0 {
+		transport.MaxIdleConns = p.MaxIdleConns
+	}
+	if p.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = p.MaxIdleConnsPerHost
+	}
+	if p.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = p.IdleConnTimeout
+	}
+	if p.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = p.ResponseHeaderTimeout
+	}
+	transport.DisableKeepAlives = p.DisableKeepAlives
+}
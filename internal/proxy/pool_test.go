@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"privacygateway/internal/config"
+)
+
+func TestCreateHTTPClient_TransportPool(t *testing.T) {
+	SetTransportPool(&TransportPool{
+		MaxIdleConns:        500,
+		MaxIdleConnsPerHost: 50,
+		IdleConnTimeout:     45 * time.Second,
+	})
+	defer SetTransportPool(nil)
+
+	transportOf := func(client *http.Client) *http.Transport {
+		t.Helper()
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("Expected *http.Transport, got %T", client.Transport)
+		}
+		return transport
+	}
+	assertPool := func(name string, transport *http.Transport, maxIdle, perHost int, idle time.Duration, disableKeepAlives bool) {
+		t.Helper()
+		if transport.MaxIdleConns != maxIdle || transport.MaxIdleConnsPerHost != perHost ||
+			transport.IdleConnTimeout != idle || transport.DisableKeepAlives != disableKeepAlives {
+			t.Errorf("%s: expected pool (%d, %d, %v, %v), got (%d, %d, %v, %v)", name,
+				maxIdle, perHost, idle, disableKeepAlives,
+				transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout, transport.DisableKeepAlives)
+		}
+	}
+
+	// 直连传输层使用全局设置
+	client, err := CreateHTTPClient(nil, &TargetPolicy{}, nil)
+	if err != nil {
+		t.Fatalf("CreateHTTPClient() error = %v", err)
+	}
+	assertPool("global", transportOf(client), 500, 50, 45*time.Second, false)
+
+	// 经出站代理的传输层同样使用全局设置
+	proxied, err := CreateHTTPClient(&config.ProxyConfig{URL: "http://proxy.corp.example.com:3128", Type: "http"}, &TargetPolicy{}, nil)
+	if err != nil {
+		t.Fatalf("CreateHTTPClient() error = %v", err)
+	}
+	assertPool("proxied", transportOf(proxied), 500, 50, 45*time.Second, false)
+
+	// 配置级覆盖只替换非零字段
+	override := &UpstreamOptions{Key: "cfg-pool", Pool: &TransportPool{MaxIdleConnsPerHost: 8, DisableKeepAlives: true}}
+	overridden, err := CreateHTTPClient(nil, &TargetPolicy{}, override)
+	if err != nil {
+		t.Fatalf("CreateHTTPClient() error = %v", err)
+	}
+	assertPool("override", transportOf(overridden), 500, 8, 45*time.Second, true)
+
+	// 相同设置复用传输层（保留连接池）
+	again, _ := CreateHTTPClient(nil, &TargetPolicy{}, override)
+	if transportOf(again) != transportOf(overridden) {
+		t.Error("Expected transport to be reused for the same upstream options")
+	}
+
+	// 修改全局设置后重建传输层
+	SetTransportPool(&TransportPool{MaxIdleConnsPerHost: 20})
+	rebuilt, _ := CreateHTTPClient(nil, &TargetPolicy{}, nil)
+	if transport := transportOf(rebuilt); transport == transportOf(client) || transport.MaxIdleConnsPerHost != 20 {
+		t.Errorf("Expected transport to be rebuilt with new pool settings, got %d", transport.MaxIdleConnsPerHost)
+	}
+}
@@ -0,0 +1,231 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"privacygateway/internal/config"
+)
+
+// ErrTargetBlocked 目标地址被访问策略拒绝
+var ErrTargetBlocked = errors.New("target blocked")
+
+// Resolver 域名解析器（net.DefaultResolver满足该接口）
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// TargetPolicy 代理目标访问策略（SSRF防护）
+//
+// 依次检查协议、主机名的允许/拒绝列表，以及解析后的IP地址：
+// 默认禁止访问回环、私有、链路本地（如169.254.169.254元数据服务）等地址。
+type TargetPolicy struct {
+	AllowedSchemes []string // 允许的协议（为空时默认http、https）
+	AllowedHosts   []string // 全局主机允许列表（为空时不限制）
+	DeniedHosts    []string // 全局主机拒绝列表
+	ConfigAllowed  []string // 配置级主机允许列表（为空时不限制）
+	ConfigDenied   []string // 配置级主机拒绝列表
+	AllowPrivate   bool     // 是否允许私有及链路本地地址
+	AllowLoopback  bool     // 是否允许回环地址（本地开发使用）
+	Resolver       Resolver // 域名解析器（为nil时使用全局DNS缓存或net.DefaultResolver）
+}
+
+// NewTargetPolicy 根据全局配置创建目标访问策略，allowedHosts/deniedHosts为配置级列表
+func NewTargetPolicy(cfg *config.Config, allowedHosts, deniedHosts []string) *TargetPolicy {
+	return &TargetPolicy{
+		AllowedSchemes: cfg.TargetAllowedSchemes,
+		AllowedHosts:   cfg.TargetAllowedHosts,
+		DeniedHosts:    cfg.TargetDeniedHosts,
+		ConfigAllowed:  allowedHosts,
+		ConfigDenied:   deniedHosts,
+		AllowPrivate:   cfg.TargetAllowPrivate,
+		AllowLoopback:  cfg.TargetAllowLoopback,
+	}
+}
+
+// Check 检查目标URL：先检查协议和主机名规则，再解析域名并检查全部IP
+func (p *TargetPolicy) Check(ctx context.Context, target *url.URL) error {
+	if err := p.CheckURL(target); err != nil {
+		return err
+	}
+
+	host := target.Hostname()
+	if net.ParseIP(host) != nil {
+		return nil // IP字面量已在CheckURL中检查
+	}
+
+	addrs, err := p.resolver().LookupIPAddr(ctx, host)
+	if err != nil {
+		// 解析失败时交由后续连接报告错误
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := p.CheckIP(addr.IP); err != nil {
+			return fmt.Errorf("%w: %s resolves to %v", ErrTargetBlocked, host, addr.IP)
+		}
+	}
+
+	return nil
+}
+
+// CheckURL 检查目标URL的协议与主机名（不进行域名解析）
+func (p *TargetPolicy) CheckURL(target *url.URL) error {
+	scheme := strings.ToLower(target.Scheme)
+	if !containsFold(p.schemes(), scheme) {
+		return fmt.Errorf("%w: scheme %q not allowed", ErrTargetBlocked, target.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: empty host", ErrTargetBlocked)
+	}
+
+	if matchHostList(p.DeniedHosts, host) || matchHostList(p.ConfigDenied, host) {
+		return fmt.Errorf("%w: host %s is denied", ErrTargetBlocked, host)
+	}
+	if len(p.AllowedHosts) > 0 && !matchHostList(p.AllowedHosts, host) {
+		return fmt.Errorf("%w: host %s not in allowlist", ErrTargetBlocked, host)
+	}
+	if len(p.ConfigAllowed) > 0 && !matchHostList(p.ConfigAllowed, host) {
+		return fmt.Errorf("%w: host %s not in config allowlist", ErrTargetBlocked, host)
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		if !p.AllowLoopback {
+			return fmt.Errorf("%w: loopback host %s", ErrTargetBlocked, host)
+		}
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if err := p.CheckIP(ip); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckIP 检查IP地址是否允许访问
+func (p *TargetPolicy) CheckIP(ip net.IP) error {
+	switch {
+	case ip.IsLoopback() || ip.IsUnspecified():
+		if !p.AllowLoopback {
+			return fmt.Errorf("%w: loopback address %v", ErrTargetBlocked, ip)
+		}
+	case ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast():
+		if !p.AllowPrivate {
+			return fmt.Errorf("%w: private address %v", ErrTargetBlocked, ip)
+		}
+	}
+	return nil
+}
+
+// DialControl 用于net.Dialer.Control，在建立连接前检查实际连接的IP，
+// 防止DNS重绑定绕过Check中的解析结果
+func (p *TargetPolicy) DialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: invalid address %s", ErrTargetBlocked, address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: unresolved address %s", ErrTargetBlocked, address)
+	}
+	return p.CheckIP(ip)
+}
+
+// DialContext 直连拨号：主机名经解析器解析后，只连接通过CheckIP检查的IP
+//
+// 启用DNS缓存时复用缓存的解析结果，但每次连接前仍会重新检查IP，
+// 并保留DialControl作为最终检查。
+func (p *TargetPolicy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   p.DialControl,
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := p.resolver().LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		if err := p.CheckIP(addr.IP); err != nil {
+			lastErr = err
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no addresses found for %s", host)
+	}
+	return nil, lastErr
+}
+
+// resolver 返回使用的域名解析器
+func (p *TargetPolicy) resolver() Resolver {
+	if p.Resolver != nil {
+		return p.Resolver
+	}
+	if cache := currentDNSCache(); cache != nil {
+		return cache
+	}
+	return net.DefaultResolver
+}
+
+// schemes 返回允许的协议列表
+func (p *TargetPolicy) schemes() []string {
+	if len(p.AllowedSchemes) == 0 {
+		return []string{"http", "https"}
+	}
+	return p.AllowedSchemes
+}
+
+// matchHostList 检查主机名是否匹配列表，支持 *.example.com 形式的子域名通配
+func matchHostList(list []string, host string) bool {
+	for _, entry := range list {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if strings.HasPrefix(entry, "*.") {
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+			continue
+		}
+		if host == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold 不区分大小写检查字符串是否在列表中
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
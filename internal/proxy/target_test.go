@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+)
+
+// fakeResolver 返回固定解析结果的解析器
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse URL %s: %v", raw, err)
+	}
+	return u
+}
+
+func TestTargetPolicy_Check(t *testing.T) {
+	resolver := fakeResolver{
+		"api.example.com":      {"93.184.216.34"},
+		"internal.example.com": {"10.0.0.5"},
+		"mixed.example.com":    {"93.184.216.34", "127.0.0.1"},
+	}
+
+	tests := []struct {
+		name    string
+		policy  TargetPolicy
+		target  string
+		blocked bool
+	}{
+		{"metadata service", TargetPolicy{}, "http://169.254.169.254/latest/meta-data", true},
+		{"loopback IP", TargetPolicy{}, "http://127.0.0.1:8080/", true},
+		{"localhost", TargetPolicy{}, "http://localhost/", true},
+		{"IPv6 loopback", TargetPolicy{}, "http://[::1]/", true},
+		{"private IP", TargetPolicy{}, "http://192.168.1.1/", true},
+		{"private IP allowed", TargetPolicy{AllowPrivate: true}, "http://192.168.1.1/", false},
+		{"loopback allowed for dev", TargetPolicy{AllowLoopback: true}, "http://localhost:3000/", false},
+		{"public host", TargetPolicy{}, "https://api.example.com/v1", false},
+		{"hostname resolving to private IP", TargetPolicy{}, "https://internal.example.com/", true},
+		{"any resolved IP blocked", TargetPolicy{}, "https://mixed.example.com/", true},
+		{"scheme not allowed", TargetPolicy{}, "ftp://api.example.com/", true},
+		{"custom scheme allowed", TargetPolicy{AllowedSchemes: []string{"https"}}, "http://api.example.com/", true},
+		{"allowlisted host", TargetPolicy{AllowedHosts: []string{"api.example.com"}}, "https://api.example.com/", false},
+		{"host outside allowlist", TargetPolicy{AllowedHosts: []string{"api.example.com"}}, "https://other.example.org/", true},
+		{"wildcard allowlist", TargetPolicy{AllowedHosts: []string{"*.example.com"}}, "https://API.Example.com/", false},
+		{"config allowlist", TargetPolicy{ConfigAllowed: []string{"other.example.org"}}, "https://api.example.com/", true},
+		{"denied host", TargetPolicy{DeniedHosts: []string{"api.example.com"}}, "https://api.example.com/", true},
+		{"config denied host", TargetPolicy{ConfigDenied: []string{"*.example.com"}}, "https://api.example.com/", true},
+		{"unresolvable host", TargetPolicy{}, "https://unknown.example.net/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			policy.Resolver = resolver
+
+			err := policy.Check(context.Background(), mustParseURL(t, tt.target))
+			if tt.blocked {
+				if !errors.Is(err, ErrTargetBlocked) {
+					t.Errorf("Expected %s to be blocked, got %v", tt.target, err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected %s to be allowed, got %v", tt.target, err)
+			}
+		})
+	}
+}
+
+func TestTargetPolicy_DialControl(t *testing.T) {
+	policy := &TargetPolicy{}
+
+	// 连接阶段检查实际IP，防止DNS重绑定
+	if err := policy.DialControl("tcp4", "169.254.169.254:80", nil); !errors.Is(err, ErrTargetBlocked) {
+		t.Errorf("Expected metadata address to be blocked at dial time, got %v", err)
+	}
+	if err := policy.DialControl("tcp4", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("Expected public address to be allowed at dial time, got %v", err)
+	}
+
+	policy.AllowLoopback = true
+	if err := policy.DialControl("tcp6", "[::1]:8080", nil); err != nil {
+		t.Errorf("Expected loopback to be allowed when enabled, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package proxyconfig
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RequiresBasicAuth 检查配置是否要求客户端提供HTTP Basic认证
+func (c *ProxyConfig) RequiresBasicAuth() bool {
+	return c.BasicAuthUser != ""
+}
+
+// CheckBasicAuth 校验客户端的Basic认证凭据
+//
+// 用户名使用恒定时间比较，密码按存储的哈希方案（SHA-256或Argon2id）验证，
+// 两项都会计算，避免通过响应时间区分用户名是否正确。
+func (c *ProxyConfig) CheckBasicAuth(username, password string) bool {
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(c.BasicAuthUser)) == 1
+	passwordOK := VerifyToken(password, c.BasicAuthPasswordHash)
+	return userOK && passwordOK
+}
+
+// ValidateBasicAuth 验证Basic认证设置：用户名与密码哈希需同时设置，哈希为SHA-256十六进制或Argon2id PHC格式
+func ValidateBasicAuth(user, passwordHash string) error {
+	if user == "" && passwordHash == "" {
+		return nil
+	}
+	if user == "" || passwordHash == "" {
+		return errors.New("basic_auth_user and basic_auth_password_hash must be set together")
+	}
+	if strings.Contains(user, ":") {
+		return errors.New("basic_auth_user must not contain ':'")
+	}
+
+	return validateSecretHash("basic_auth_password_hash", passwordHash)
+}
+
+// validateSecretHash 验证密钥哈希为SHA-256十六进制或Argon2id PHC格式（拒绝误填的明文）
+func validateSecretHash(field, hash string) error {
+	if DetectHashScheme(hash) == HashSchemeArgon2id {
+		if len(strings.Split(hash, "$")) != 6 {
+			return fmt.Errorf("%s is not a valid argon2id hash", field)
+		}
+		return nil
+	}
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != 32 {
+		return fmt.Errorf("%s must be a hex SHA-256 or argon2id hash, not a plain password", field)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package proxyconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateBasicAuth(t *testing.T) {
+	argonHash, err := HashTokenWithScheme("s3cret", HashSchemeArgon2id)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		user    string
+		hash    string
+		wantErr bool
+	}{
+		{"not configured", "", "", false},
+		{"sha256 hash", "legacy", HashToken("s3cret"), false},
+		{"argon2id hash", "legacy", argonHash, false},
+		{"missing hash", "legacy", "", true},
+		{"missing user", "", HashToken("s3cret"), true},
+		{"plain password", "legacy", "s3cret", true},
+		{"colon in user", "leg:acy", HashToken("s3cret"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBasicAuth(tt.user, tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckBasicAuth(t *testing.T) {
+	argonHash, _ := HashTokenWithScheme("s3cret", HashSchemeArgon2id)
+
+	for _, hash := range []string{HashToken("s3cret"), argonHash} {
+		config := &ProxyConfig{BasicAuthUser: "legacy", BasicAuthPasswordHash: hash}
+		scheme := DetectHashScheme(hash)
+
+		if !config.CheckBasicAuth("legacy", "s3cret") {
+			t.Errorf("%s: expected correct credentials to pass", scheme)
+		}
+		if config.CheckBasicAuth("legacy", "wrong") || config.CheckBasicAuth("Legacy", "s3cret") {
+			t.Errorf("%s: expected wrong credentials to fail", scheme)
+		}
+		if config.CheckBasicAuth("legacy", strings.ToUpper("s3cret")) {
+			t.Errorf("%s: expected password comparison to be case-sensitive", scheme)
+		}
+	}
+}
+
+func TestCheckOwnerSecret(t *testing.T) {
+	config := &ProxyConfig{Name: "tenant", TargetURL: "https://example.com", OwnerSecretHash: HashToken("tenant-secret")}
+
+	if !config.CheckOwnerSecret("tenant-secret") {
+		t.Error("Expected owner secret to pass")
+	}
+	if config.CheckOwnerSecret("wrong") || (&ProxyConfig{}).CheckOwnerSecret("") {
+		t.Error("Expected wrong or unset owner secret to fail")
+	}
+
+	// 明文密钥在验证时被拒绝
+	config.OwnerSecretHash = "tenant-secret"
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "owner_secret_hash") {
+		t.Errorf("Expected plain owner secret to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package proxyconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// 响应体改写的限制
+const (
+	MaxBodyRewrites        = 20
+	MaxRewriteBodySize     = 5 << 20 // 响应体超过该大小（字节）时不改写，原样转发
+	maxRewritePatternBytes = 1024
+)
+
+// BodyRewrite 响应体查找替换规则
+type BodyRewrite struct {
+	Find    string `json:"find"`            // 查找的字符串或正则表达式
+	Replace string `json:"replace"`         // 替换内容（正则模式下可使用$1等捕获组）
+	Regex   bool   `json:"regex,omitempty"` // Find是否为正则表达式（RE2语法）
+}
+
+// rewriteRegexps 已编译的改写正则缓存（配置按请求复制，避免每次请求重新编译）
+var rewriteRegexps sync.Map // pattern -> *regexp.Regexp
+
+// compileRewrite 编译并缓存改写规则的正则表达式
+func compileRewrite(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := rewriteRegexps.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	rewriteRegexps.Store(pattern, re)
+	return re, nil
+}
+
+// ValidateBodyRewrites 验证响应体改写规则
+func ValidateBodyRewrites(rules []BodyRewrite) error {
+	if len(rules) > MaxBodyRewrites {
+		return fmt.Errorf("at most %d response_body_rewrites are allowed", MaxBodyRewrites)
+	}
+	for i, rule := range rules {
+		if rule.Find == "" {
+			return fmt.Errorf("response_body_rewrites[%d].find is required", i)
+		}
+		if len(rule.Find) > maxRewritePatternBytes {
+			return fmt.Errorf("response_body_rewrites[%d].find must be at most %d characters", i, maxRewritePatternBytes)
+		}
+		if rule.Regex {
+			if _, err := regexp.Compile(rule.Find); err != nil {
+				return fmt.Errorf("response_body_rewrites[%d].find is not a valid regex: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RewriteResponseBody 按顺序应用响应体改写规则
+func (c *ProxyConfig) RewriteResponseBody(body []byte) ([]byte, error) {
+	for _, rule := range c.ResponseBodyRewrites {
+		if !rule.Regex {
+			body = bytes.ReplaceAll(body, []byte(rule.Find), []byte(rule.Replace))
+			continue
+		}
+		re, err := compileRewrite(rule.Find)
+		if err != nil {
+			return nil, errors.New("invalid response body rewrite regex")
+		}
+		body = re.ReplaceAll(body, []byte(rule.Replace))
+	}
+	return body, nil
+}
@@ -0,0 +1,81 @@
+package proxyconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ValidateBodyTransform 验证请求体转换模板，必须是JSON对象
+func ValidateBodyTransform(template json.RawMessage) error {
+	if len(template) == 0 {
+		return nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(template, &object); err != nil || object == nil {
+		return errors.New("body_transform must be a JSON object")
+	}
+	return nil
+}
+
+// TransformRequestBody 将配置的模板以JSON Merge Patch（RFC 7386）方式合并到请求体
+//
+// 只处理Content-Type为application/json（或+json）且请求体为JSON对象的请求：
+// 模板中的字段覆盖请求体中的同名字段，嵌套对象递归合并，值为null的字段从请求体中移除。
+// 其他请求体原样返回，第二个返回值表示请求体是否被修改。
+func (c *ProxyConfig) TransformRequestBody(contentType string, body []byte) ([]byte, bool) {
+	if len(c.BodyTransform) == 0 || len(body) == 0 || !isJSONContentType(contentType) {
+		return body, false
+	}
+
+	var template map[string]interface{}
+	if err := decodeJSONNumbers(c.BodyTransform, &template); err != nil || template == nil {
+		return body, false
+	}
+	var target map[string]interface{}
+	if err := decodeJSONNumbers(body, &target); err != nil || target == nil {
+		return body, false
+	}
+
+	merged, err := json.Marshal(mergeJSONPatch(target, template))
+	if err != nil {
+		return body, false
+	}
+	return merged, true
+}
+
+// mergeJSONPatch 递归合并patch到target（RFC 7386语义）
+func mergeJSONPatch(target, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		patchObject, ok := value.(map[string]interface{})
+		if !ok {
+			target[key] = value
+			continue
+		}
+		targetObject, ok := target[key].(map[string]interface{})
+		if !ok {
+			targetObject = map[string]interface{}{}
+		}
+		target[key] = mergeJSONPatch(targetObject, patchObject)
+	}
+	return target
+}
+
+// decodeJSONNumbers 解码JSON并保留数字原文，避免大整数被转换为float64后丢失精度
+func decodeJSONNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// isJSONContentType 检查Content-Type是否为JSON（application/json或+json后缀）
+func isJSONContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,55 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTransformRequestBody(t *testing.T) {
+	config := &ProxyConfig{BodyTransform: json.RawMessage(`{"tenant_id":"acme","meta":{"source":"gateway"},"debug":null}`)}
+
+	body, ok := config.TransformRequestBody("application/json", []byte(`{"tenant_id":"other","meta":{"trace":"1"},"debug":true,"name":"x"}`))
+	if !ok {
+		t.Fatal("Expected JSON body to be transformed")
+	}
+	expected := `{"meta":{"source":"gateway","trace":"1"},"name":"x","tenant_id":"acme"}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+
+	// 非JSON类型、非对象请求体和空请求体原样返回
+	cases := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain", `{"name":"x"}`},
+		{"application/json", `[1,2]`},
+		{"application/json", `not json`},
+		{"application/json", ``},
+	}
+	for _, c := range cases {
+		if body, ok := config.TransformRequestBody(c.contentType, []byte(c.body)); ok || string(body) != c.body {
+			t.Errorf("Expected %q (%s) to be unchanged, got %q", c.body, c.contentType, body)
+		}
+	}
+
+	// +json类型同样处理
+	if _, ok := config.TransformRequestBody("application/vnd.api+json", []byte(`{}`)); !ok {
+		t.Error("Expected +json content type to be transformed")
+	}
+}
+
+func TestValidateConfig_BodyTransform(t *testing.T) {
+	config := newEvictionTestConfig("transform")
+	config.BodyTransform = json.RawMessage(`{"tenant_id":"acme"}`)
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected object template to be valid, got %v", err)
+	}
+
+	for _, template := range []string{`[1]`, `"text"`, `null`, `{"a":`} {
+		config.BodyTransform = json.RawMessage(template)
+		if err := ValidateConfig(config); err == nil {
+			t.Errorf("Expected template %s to be rejected", template)
+		}
+	}
+}
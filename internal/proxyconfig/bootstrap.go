@@ -0,0 +1,90 @@
+package proxyconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ParseBootstrapConfigs 解析预置配置数据
+//
+// 支持导出文件格式（{"configs": [...]}）和直接的配置数组两种写法，
+// format为空时按内容识别：以 { 或 [ 开头视为JSON，否则视为YAML。
+func ParseBootstrapConfigs(data []byte, format string) ([]ProxyConfig, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" {
+		return nil, nil
+	}
+
+	if format == "" {
+		format = FormatYAML
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			format = FormatJSON
+		}
+	}
+
+	// 先按配置数组解析，失败时再按导出文件格式解析
+	var configs []ProxyConfig
+	if err := UnmarshalFormat([]byte(trimmed), format, &configs); err == nil {
+		return configs, nil
+	}
+
+	var wrapper struct {
+		Configs []ProxyConfig `json:"configs"`
+	}
+	if err := UnmarshalFormat([]byte(trimmed), format, &wrapper); err != nil {
+		return nil, err
+	}
+	return wrapper.Configs, nil
+}
+
+// LoadBootstrapFile 读取预置配置文件，按扩展名（.yaml/.yml/.json）确定格式
+func LoadBootstrapFile(path string) ([]ProxyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bootstrap file: %w", err)
+	}
+
+	format := ""
+	if ext := strings.TrimPrefix(filepath.Ext(path), "."); ext != "" {
+		if normalized, err := NormalizeFormat(ext); err == nil {
+			format = normalized
+		}
+	}
+
+	configs, err := ParseBootstrapConfigs(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bootstrap file %s: %w", path, err)
+	}
+	return configs, nil
+}
+
+// Bootstrap 启动时导入预置配置，名称已存在的配置（例如已持久化的）会被跳过
+//
+// filePath 和 inline 可同时设置，两者的配置合并后一次性导入。
+func Bootstrap(storage Storage, filePath, inline string) (*ImportResult, error) {
+	var configs []ProxyConfig
+
+	if filePath != "" {
+		fileConfigs, err := LoadBootstrapFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, fileConfigs...)
+	}
+
+	if inline != "" {
+		inlineConfigs, err := ParseBootstrapConfigs([]byte(inline), "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse inline bootstrap configs: %w", err)
+		}
+		configs = append(configs, inlineConfigs...)
+	}
+
+	if len(configs) == 0 {
+		return &ImportResult{Errors: make([]string, 0)}, nil
+	}
+
+	return storage.ImportConfigs(configs, ImportModeSkip)
+}
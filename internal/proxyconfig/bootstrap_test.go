@@ -0,0 +1,89 @@
+package proxyconfig
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"privacygateway/internal/logger"
+)
+
+func TestBootstrap_SeedsStorage(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "bootstrap.yaml")
+	yamlData := `configs:
+  - name: api
+    target_url: https://api.example.com
+    protocol: https
+    enabled: true
+  - name: static
+    target_url: https://static.example.com
+    protocol: https
+    enabled: false
+`
+	if err := os.WriteFile(yamlFile, []byte(yamlData), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	inline := `[{"name":"inline","target_url":"https://inline.example.com","protocol":"https","enabled":true}]`
+
+	var buf bytes.Buffer
+	log := logger.New()
+	log.SetOutput(&buf)
+	storage := NewPersistentStorage(filepath.Join(dir, "configs.json"), 10, EvictionReject, false, log)
+	defer storage.Shutdown()
+
+	result, err := Bootstrap(storage, yamlFile, inline)
+	if err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	if result.ImportedCount != 3 || result.SkippedCount != 0 {
+		t.Errorf("Expected 3 configs seeded, got %+v", result)
+	}
+
+	configs, _ := storage.List(&ConfigFilter{Page: 1, Limit: 10})
+	byName := make(map[string]ProxyConfig)
+	for _, config := range configs.Configs {
+		byName[config.Name] = config
+	}
+	if len(byName) != 3 {
+		t.Fatalf("Expected 3 configs in storage, got %d", len(byName))
+	}
+	if byName["api"].TargetURL != "https://api.example.com" || !byName["api"].Enabled {
+		t.Errorf("Unexpected api config: %+v", byName["api"])
+	}
+	if byName["static"].Enabled {
+		t.Error("Expected static config to stay disabled")
+	}
+	if _, ok := byName["inline"]; !ok {
+		t.Error("Expected inline config to be seeded")
+	}
+
+	// 再次启动时已存在的配置被跳过，不会重复
+	result, err = Bootstrap(storage, yamlFile, inline)
+	if err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	if result.ImportedCount != 0 || result.SkippedCount != 3 {
+		t.Errorf("Expected duplicates to be skipped, got %+v", result)
+	}
+	if stats := storage.GetStats(); stats.TotalConfigs != 3 {
+		t.Errorf("Expected 3 configs after second bootstrap, got %d", stats.TotalConfigs)
+	}
+}
+
+func TestBootstrap_InvalidInput(t *testing.T) {
+	storage := NewMemoryStorage(10)
+
+	if _, err := Bootstrap(storage, filepath.Join(t.TempDir(), "missing.json"), ""); err == nil {
+		t.Error("Expected error for missing bootstrap file")
+	}
+	if _, err := Bootstrap(storage, "", "{not json"); err == nil {
+		t.Error("Expected error for malformed inline configs")
+	}
+
+	result, err := Bootstrap(storage, "", "")
+	if err != nil || result.ImportedCount != 0 {
+		t.Errorf("Expected empty bootstrap to be a no-op, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,147 @@
+package proxyconfig
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"privacygateway/internal/logger"
+)
+
+// clientTLSCache 按配置ID缓存已构建的上游TLS配置
+var clientTLSCache sync.Map
+
+// clientTLSEntry 上游TLS配置缓存条目
+type clientTLSEntry struct {
+	fingerprint string
+	config      *tls.Config
+}
+
+// HasClientTLS 检查配置是否设置了上游mTLS证书、自定义CA或跳过证书校验
+func (c *ProxyConfig) HasClientTLS() bool {
+	return c.ClientCert != "" || c.ClientKey != "" || c.CACert != "" || c.InsecureSkipVerify
+}
+
+// WarnInsecureTLS 对跳过上游证书校验的配置输出警告日志
+func WarnInsecureTLS(log *logger.Logger, config *ProxyConfig) {
+	if config != nil && config.InsecureSkipVerify {
+		log.Warn("upstream TLS certificate verification is DISABLED for config, connections are vulnerable to MITM",
+			"config_id", config.ID, "name", config.Name, "target_url", config.TargetURL)
+	}
+}
+
+// ClientTLSConfig 获取访问上游时使用的TLS配置（未设置证书时返回nil）
+//
+// 证书、私钥和CA均可填写PEM内容或文件路径。内容或文件修改时间变化时重新加载，
+// 否则复用缓存的tls.Config，便于传输层保持连接复用。
+func (c *ProxyConfig) ClientTLSConfig() (*tls.Config, error) {
+	if !c.HasClientTLS() {
+		clientTLSCache.Delete(c.ID)
+		return nil, nil
+	}
+
+	fingerprint := c.clientTLSFingerprint()
+	if cached, ok := clientTLSCache.Load(c.ID); ok {
+		entry := cached.(*clientTLSEntry)
+		if entry.fingerprint == fingerprint {
+			return entry.config, nil
+		}
+	}
+
+	tlsConfig, err := c.buildClientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	clientTLSCache.Store(c.ID, &clientTLSEntry{fingerprint: fingerprint, config: tlsConfig})
+	return tlsConfig, nil
+}
+
+// ValidateClientTLS 验证上游mTLS配置能否正确解析
+func ValidateClientTLS(config *ProxyConfig) error {
+	if !config.HasClientTLS() {
+		return nil
+	}
+	_, err := config.buildClientTLSConfig()
+	return err
+}
+
+// buildClientTLSConfig 解析证书、私钥和CA并构建tls.Config
+func (c *ProxyConfig) buildClientTLSConfig() (*tls.Config, error) {
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return nil, errors.New("client_cert and client_key must be set together")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.ClientCert != "" {
+		certPEM, err := loadPEM(c.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client_cert: %w", err)
+		}
+		keyPEM, err := loadPEM(c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client_key: %w", err)
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.CACert != "" {
+		caPEM, err := loadPEM(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_cert: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("ca_cert contains no valid PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// clientTLSFingerprint 计算证书设置的指纹（文件路径会包含修改时间）
+func (c *ProxyConfig) clientTLSFingerprint() string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "insecure=%t", c.InsecureSkipVerify)
+	for _, value := range []string{c.ClientCert, c.ClientKey, c.CACert} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+		if value != "" && !isInlinePEM(value) {
+			if info, err := os.Stat(value); err == nil {
+				fmt.Fprintf(hash, "%d:%d", info.ModTime().UnixNano(), info.Size())
+			}
+		}
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// loadPEM 读取PEM内容：包含PEM头时直接使用，否则视为文件路径
+func loadPEM(value string) ([]byte, error) {
+	if isInlinePEM(value) {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
+
+// isInlinePEM 判断值是否为PEM内容
+func isInlinePEM(value string) bool {
+	return strings.Contains(value, "-----BEGIN")
+}
@@ -0,0 +1,198 @@
+package proxyconfig
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+)
+
+// 配置和令牌修改的公共逻辑：只修改传入的配置，加锁、令牌索引和持久化由各存储实现负责
+
+// applyConfigUpdate 校验版本号后为config填入existing的ID、创建时间和令牌数据，版本号加1
+func applyConfigUpdate(existing, config *ProxyConfig) error {
+	if config.Version == 0 {
+		return ErrVersionRequired
+	}
+	if config.Version != existing.Version {
+		return ErrVersionConflict
+	}
+
+	config.ID = existing.ID
+	config.Version = existing.Version + 1
+	config.CreatedAt = existing.CreatedAt
+	config.UpdatedAt = time.Now()
+
+	// 保留原有的令牌数据和统计信息
+	config.AccessTokens = existing.AccessTokens
+	config.TokenStats = existing.TokenStats
+	return nil
+}
+
+// recordRequestStats 将一次代理请求计入配置统计
+func recordRequestStats(config *ProxyConfig, responseTime time.Duration, success bool, bytes int64) {
+	// 初始化统计信息
+	if config.Stats == nil {
+		config.Stats = &ConfigStats{}
+	}
+
+	// 更新统计数据
+	config.Stats.RequestCount++
+	if !success {
+		config.Stats.ErrorCount++
+	}
+
+	// 更新平均响应时间（使用移动平均）
+	responseTimeMs := float64(responseTime.Nanoseconds()) / 1e6
+	if config.Stats.RequestCount == 1 {
+		config.Stats.AvgResponseTime = responseTimeMs
+	} else {
+		// 使用指数移动平均，权重为0.1
+		config.Stats.AvgResponseTime = config.Stats.AvgResponseTime*0.9 + responseTimeMs*0.1
+	}
+
+	config.Stats.LastAccessed = time.Now()
+	config.Stats.TotalBytes += bytes
+}
+
+// appendToken 校验令牌数据、数量上限和名称后将令牌加入配置
+func appendToken(config *ProxyConfig, token *AccessToken) error {
+	// 验证令牌数据
+	if err := token.Validate(); err != nil {
+		return err
+	}
+
+	// 检查令牌数量限制
+	if err := ValidateTokenLimit(len(config.AccessTokens)); err != nil {
+		return err
+	}
+
+	// 检查令牌名称是否重复
+	for _, existingToken := range config.AccessTokens {
+		if existingToken.Name == token.Name {
+			return errors.New("token name already exists")
+		}
+	}
+
+	config.AccessTokens = append(config.AccessTokens, *token)
+	config.UpdatedAt = time.Now()
+	config.TokenStats = CalculateTokenStats(config.AccessTokens)
+	return nil
+}
+
+// replaceToken 用token替换配置中ID为tokenID的令牌
+func replaceToken(config *ProxyConfig, tokenID string, token *AccessToken) error {
+	tokenIndex := findTokenByID(config, tokenID)
+	if tokenIndex == -1 {
+		return ErrTokenNotFound
+	}
+
+	// 验证令牌数据
+	if err := token.Validate(); err != nil {
+		return err
+	}
+
+	// 检查名称冲突（排除自己）
+	for i, existingToken := range config.AccessTokens {
+		if i != tokenIndex && existingToken.Name == token.Name {
+			return errors.New("token name already exists")
+		}
+	}
+
+	config.AccessTokens[tokenIndex] = *token
+	config.UpdatedAt = time.Now()
+	config.TokenStats = CalculateTokenStats(config.AccessTokens)
+	return nil
+}
+
+// removeToken 从配置中删除ID为tokenID的令牌
+func removeToken(config *ProxyConfig, tokenID string) error {
+	tokenIndex := findTokenByID(config, tokenID)
+	if tokenIndex == -1 {
+		return ErrTokenNotFound
+	}
+
+	config.AccessTokens = append(config.AccessTokens[:tokenIndex], config.AccessTokens[tokenIndex+1:]...)
+	config.UpdatedAt = time.Now()
+	config.TokenStats = CalculateTokenStats(config.AccessTokens)
+	return nil
+}
+
+// consumeTokenUsage 为配置中下标为tokenIndex的令牌计数一次使用，已达使用次数上限时返回ErrTokenQuotaExceeded且不计数
+func consumeTokenUsage(config *ProxyConfig, tokenIndex int) error {
+	token := &config.AccessTokens[tokenIndex]
+	if token.IsQuotaExceeded() {
+		return ErrTokenQuotaExceeded
+	}
+	token.UpdateUsage()
+	config.UpdatedAt = time.Now()
+	config.TokenStats = CalculateTokenStats(config.AccessTokens)
+	return nil
+}
+
+// resetTokenUsage 将令牌的使用次数、最后使用时间和使用历史清零
+func resetTokenUsage(config *ProxyConfig, tokenID string) error {
+	tokenIndex := findTokenByID(config, tokenID)
+	if tokenIndex == -1 {
+		return ErrTokenNotFound
+	}
+
+	config.AccessTokens[tokenIndex].UsageCount = 0
+	config.AccessTokens[tokenIndex].LastUsed = nil
+	config.AccessTokens[tokenIndex].UsageHistory = nil
+	config.UpdatedAt = time.Now()
+	config.TokenStats = CalculateTokenStats(config.AccessTokens)
+	return nil
+}
+
+// findTokenByID 返回配置中ID为tokenID的令牌下标，未找到时返回-1
+func findTokenByID(config *ProxyConfig, tokenID string) int {
+	for i, token := range config.AccessTokens {
+		if token.ID == tokenID {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchTokenInConfig 返回配置中与令牌值匹配的令牌下标，未找到时返回-1
+//
+// 与lookupTokenLocked一致：SHA-256令牌使用常量时间比较，加盐哈希令牌只验证查找标识相同的，
+// 全部令牌都比较一遍，不在首次匹配时提前退出。
+func matchTokenInConfig(config *ProxyConfig, tokenValue string) int {
+	tokenHash := HashToken(tokenValue)
+	lookupID := tokenHash[:lookupIDLength]
+	matched := -1
+	for i := range config.AccessTokens {
+		token := &config.AccessTokens[i]
+		var ok bool
+		if DetectHashScheme(token.TokenHash) == HashSchemeSHA256 {
+			ok = subtle.ConstantTimeCompare([]byte(token.TokenHash), []byte(tokenHash)) == 1
+		} else if matched == -1 && token.lookupKey() == lookupID {
+			ok = VerifyToken(tokenValue, token.TokenHash)
+		}
+		if ok && matched == -1 {
+			matched = i
+		}
+	}
+	return matched
+}
+
+// tokenValidationResult 检查令牌的访问权限（禁用、过期等）并生成验证结果
+func tokenValidationResult(configID string, token AccessToken) *TokenValidationResult {
+	if err := ValidateTokenAccess(&token); err != nil {
+		return &TokenValidationResult{
+			Valid:     false,
+			Token:     &token,
+			ConfigID:  configID,
+			ErrorCode: getErrorCode(err),
+			ErrorMsg:  err.Error(),
+		}
+	}
+
+	// 令牌有效
+	return &TokenValidationResult{
+		Valid:    true,
+		Token:    &token,
+		ConfigID: configID,
+	}
+}
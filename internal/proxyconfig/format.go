@@ -0,0 +1,88 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 导入导出格式常量
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// NormalizeFormat 规范化格式名称（yml视为yaml，空值视为json）
+func NormalizeFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatYAML, "yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// FormatFromContentType 根据Content-Type/Accept头判断格式，无法识别时返回json
+func FormatFromContentType(contentType string) string {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "yaml") || strings.Contains(contentType, "yml") {
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+// ContentTypeForFormat 返回格式对应的Content-Type
+func ContentTypeForFormat(format string) string {
+	if format == FormatYAML {
+		return "application/yaml"
+	}
+	return "application/json"
+}
+
+// MarshalFormat 将数据按指定格式序列化
+//
+// YAML输出先经过JSON编码，保证字段名与JSON格式完全一致（沿用json标签），
+// 这样同一份导出文件在两种格式之间可以无损转换。
+func MarshalFormat(v interface{}, format string) ([]byte, error) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json: %w", err)
+	}
+
+	if format != FormatYAML {
+		return jsonData, nil
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(jsonData, &generic); err != nil {
+		return nil, fmt.Errorf("failed to convert json: %w", err)
+	}
+
+	yamlData, err := yaml.Marshal(generic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal yaml: %w", err)
+	}
+	return yamlData, nil
+}
+
+// UnmarshalFormat 将指定格式的数据反序列化到v
+func UnmarshalFormat(data []byte, format string, v interface{}) error {
+	if format != FormatYAML {
+		return json.Unmarshal(data, v)
+	}
+
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+
+	jsonData, err := json.Marshal(generic)
+	if err != nil {
+		return fmt.Errorf("failed to convert yaml: %w", err)
+	}
+	return json.Unmarshal(jsonData, v)
+}
@@ -0,0 +1,130 @@
+package proxyconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 辅助函数：创建用于导入导出测试的配置集合
+func createExportTestData(t *testing.T) *ExportData {
+	storage := NewMemoryStorage(10)
+
+	configs := []*ProxyConfig{
+		{Name: "GitHub API", TargetURL: "https://api.github.com", Protocol: "https", Enabled: true},
+		{Name: "Internal", TargetURL: "http://internal.example.com:8080/base", Protocol: "http", Enabled: false},
+	}
+	for _, config := range configs {
+		if err := storage.Add(config); err != nil {
+			t.Fatalf("Failed to add config: %v", err)
+		}
+	}
+
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	token := &AccessToken{
+		ID:        "token-1",
+		Name:      "CI Token",
+		TokenHash: HashToken("secret-token"),
+		ExpiresAt: &expiresAt,
+		Enabled:   true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.AddToken(configs[0].ID, token); err != nil {
+		t.Fatalf("Failed to add token: %v", err)
+	}
+	if err := storage.UpdateStats(configs[0].ID, 25*time.Millisecond, true, 512); err != nil {
+		t.Fatalf("Failed to update stats: %v", err)
+	}
+
+	exportData, err := storage.ExportAll()
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	return exportData
+}
+
+func TestExportData_YAMLRoundTripMatchesJSON(t *testing.T) {
+	exportData := createExportTestData(t)
+
+	jsonData, err := MarshalFormat(exportData, FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	yamlData, err := MarshalFormat(exportData, FormatYAML)
+	if err != nil {
+		t.Fatalf("Failed to marshal YAML: %v", err)
+	}
+
+	// YAML应沿用JSON字段名，保持相同的信封结构
+	for _, key := range []string{"version:", "export_at:", "configs:", "total_count:", "target_url:", "access_tokens:"} {
+		if !strings.Contains(string(yamlData), key) {
+			t.Errorf("Expected YAML output to contain %q", key)
+		}
+	}
+
+	var fromJSON, fromYAML ExportData
+	if err := UnmarshalFormat(jsonData, FormatJSON, &fromJSON); err != nil {
+		t.Fatalf("Failed to unmarshal JSON: %v", err)
+	}
+	if err := UnmarshalFormat(yamlData, FormatYAML, &fromYAML); err != nil {
+		t.Fatalf("Failed to unmarshal YAML: %v", err)
+	}
+
+	if !fromJSON.ExportAt.Equal(fromYAML.ExportAt) {
+		t.Errorf("ExportAt mismatch: json=%v yaml=%v", fromJSON.ExportAt, fromYAML.ExportAt)
+	}
+
+	// 重新编码为JSON后比较，避免time.Time内部单调时钟等差异
+	jsonFromJSON, _ := MarshalFormat(&fromJSON, FormatJSON)
+	jsonFromYAML, _ := MarshalFormat(&fromYAML, FormatJSON)
+	if string(jsonFromJSON) != string(jsonFromYAML) {
+		t.Errorf("YAML round trip differs from JSON path\njson: %s\nyaml: %s", jsonFromJSON, jsonFromYAML)
+	}
+
+	if fromYAML.Version != exportData.Version || fromYAML.TotalCount != exportData.TotalCount {
+		t.Errorf("Envelope mismatch: got version=%s total=%d", fromYAML.Version, fromYAML.TotalCount)
+	}
+	if len(fromYAML.Configs) != len(exportData.Configs) {
+		t.Fatalf("Expected %d configs, got %d", len(exportData.Configs), len(fromYAML.Configs))
+	}
+}
+
+func TestUnmarshalFormat_YAMLImportPayload(t *testing.T) {
+	payload := `
+mode: skip
+configs:
+  - name: Example
+    target_url: https://example.com
+    protocol: https
+    enabled: true
+`
+	var importData struct {
+		Configs []ProxyConfig `json:"configs"`
+		Mode    string        `json:"mode"`
+	}
+	if err := UnmarshalFormat([]byte(payload), FormatYAML, &importData); err != nil {
+		t.Fatalf("Failed to unmarshal YAML: %v", err)
+	}
+
+	expected := []ProxyConfig{{Name: "Example", TargetURL: "https://example.com", Protocol: "https", Enabled: true}}
+	if importData.Mode != "skip" || !reflect.DeepEqual(importData.Configs, expected) {
+		t.Errorf("Unexpected import data: %+v", importData)
+	}
+}
+
+func TestFormatNegotiationHelpers(t *testing.T) {
+	if format, err := NormalizeFormat("YML"); err != nil || format != FormatYAML {
+		t.Errorf("Expected yml to normalize to yaml, got %q (%v)", format, err)
+	}
+	if _, err := NormalizeFormat("xml"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+	if FormatFromContentType("application/x-yaml; charset=utf-8") != FormatYAML {
+		t.Error("Expected application/x-yaml to be detected as yaml")
+	}
+	if FormatFromContentType("application/json") != FormatJSON {
+		t.Error("Expected application/json to be detected as json")
+	}
+}
@@ -0,0 +1,142 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// diffIgnoredFields 由服务端维护、导入时不会覆盖的字段，差异中不比较
+var diffIgnoredFields = map[string]bool{
+	"id":            true,
+	"version":       true,
+	"created_at":    true,
+	"updated_at":    true,
+	"deleted_at":    true,
+	"stats":         true,
+	"access_tokens": true,
+	"token_stats":   true,
+}
+
+// diffSecretFields 包含密钥的字段，差异中只标记变化，不返回值
+var diffSecretFields = map[string]bool{
+	"basic_auth_password_hash": true,
+	"client_key":               true,
+	"owner_secret_hash":        true,
+	"request_signing":          true,
+}
+
+// ImportDiff 导入文件与当前配置之间的差异（按名称匹配，与导入冲突的判断一致）
+type ImportDiff struct {
+	Adds      []ConfigDiff `json:"adds"`             // 导入后新建的配置
+	Updates   []ConfigDiff `json:"updates"`          // 名称相同但字段不同的配置（replace模式下会被覆盖）
+	Deletes   []ConfigDiff `json:"deletes"`          // 导入文件中不存在的已有配置（仅prune模式报告）
+	Unchanged int          `json:"unchanged"`        // 名称相同且字段一致的配置数量
+	Errors    []string     `json:"errors,omitempty"` // 校验失败或名称重复、导入时会被拒绝的配置
+}
+
+// ConfigDiff 单个配置的差异
+type ConfigDiff struct {
+	Name    string        `json:"name"`              // 配置名称
+	ID      string        `json:"id,omitempty"`      // 已有配置的ID（新建的配置为空）
+	Changes []FieldChange `json:"changes,omitempty"` // 字段级变化（仅更新）
+}
+
+// FieldChange 单个字段的变化，Old/New为JSON值（密钥字段不返回值）
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// DiffImport 计算导入配置与当前配置的差异，不修改存储
+//
+// prune为true时，当前存在但导入文件中没有的配置报告为删除；否则视为不变（导入不会删除配置）。
+func (s *MemoryStorage) DiffImport(configs []ProxyConfig, prune bool) *ImportDiff {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	diff := &ImportDiff{Adds: []ConfigDiff{}, Updates: []ConfigDiff{}, Deletes: []ConfigDiff{}}
+	byName := importNameIndex(s.configs)
+	seen := make(map[string]bool, len(configs))
+
+	for i := range configs {
+		config := configs[i]
+		if seen[config.Name] {
+			diff.Errors = append(diff.Errors, fmt.Sprintf("配置 %s 在导入文件中重复", config.Name))
+			continue
+		}
+		seen[config.Name] = true
+
+		if err := ValidateConfig(&config); err != nil {
+			diff.Errors = append(diff.Errors, fmt.Sprintf("配置 %s 验证失败: %v", config.Name, err))
+			continue
+		}
+
+		existing, conflict := byName[config.Name]
+		if !conflict {
+			diff.Adds = append(diff.Adds, ConfigDiff{Name: config.Name})
+			continue
+		}
+
+		changes := diffConfigFields(existing, &config)
+		if len(changes) == 0 {
+			diff.Unchanged++
+			continue
+		}
+		diff.Updates = append(diff.Updates, ConfigDiff{Name: config.Name, ID: existing.ID, Changes: changes})
+	}
+
+	if prune {
+		for name, existing := range byName {
+			if !seen[name] {
+				diff.Deletes = append(diff.Deletes, ConfigDiff{Name: name, ID: existing.ID})
+			}
+		}
+		sort.Slice(diff.Deletes, func(i, j int) bool { return diff.Deletes[i].Name < diff.Deletes[j].Name })
+	}
+
+	return diff
+}
+
+// diffConfigFields 按JSON字段比较两个配置，返回按字段名排序的变化
+func diffConfigFields(current, incoming *ProxyConfig) []FieldChange {
+	oldFields := configFields(current)
+	newFields := configFields(incoming)
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		if diffIgnoredFields[name] || reflect.DeepEqual(oldFields[name], newFields[name]) {
+			continue
+		}
+		change := FieldChange{Field: name}
+		if !diffSecretFields[name] {
+			change.Old, change.New = oldFields[name], newFields[name]
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// configFields 将配置转换为JSON字段表（省略的空字段不出现）
+func configFields(config *ProxyConfig) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
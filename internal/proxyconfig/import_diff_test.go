@@ -0,0 +1,77 @@
+package proxyconfig
+
+import (
+	"testing"
+)
+
+// newDiffTestStorage 创建包含stable、changed和absent三个配置的存储
+func newDiffTestStorage(t *testing.T) *MemoryStorage {
+	t.Helper()
+	storage := NewMemoryStorage(10)
+	for _, name := range []string{"stable", "changed", "absent"} {
+		if err := storage.Add(newEvictionTestConfig(name)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	return storage
+}
+
+func TestMemoryStorage_DiffImport(t *testing.T) {
+	storage := newDiffTestStorage(t)
+
+	changed := newEvictionTestConfig("changed")
+	changed.TargetURL = "https://api.example.com"
+	incoming := []ProxyConfig{*newEvictionTestConfig("stable"), *changed, *newEvictionTestConfig("new")}
+
+	diff := storage.DiffImport(incoming, false)
+
+	if len(diff.Adds) != 1 || diff.Adds[0].Name != "new" {
+		t.Errorf("Expected new config as add, got %+v", diff.Adds)
+	}
+	if len(diff.Updates) != 1 || diff.Updates[0].Name != "changed" || diff.Updates[0].ID == "" {
+		t.Fatalf("Expected changed config as update, got %+v", diff.Updates)
+	}
+	changes := diff.Updates[0].Changes
+	if len(changes) != 1 || changes[0].Field != "target_url" || changes[0].Old != "https://example.com" || changes[0].New != "https://api.example.com" {
+		t.Errorf("Expected target_url change only, got %+v", changes)
+	}
+	// 导入文件中没有的配置不报告删除（导入不会删除配置）
+	if len(diff.Deletes) != 0 || diff.Unchanged != 1 {
+		t.Errorf("Expected absent config to be a no-op, got deletes=%+v unchanged=%d", diff.Deletes, diff.Unchanged)
+	}
+
+	// 计算差异不修改存储
+	if stats := storage.GetStats(); stats.TotalConfigs != 3 {
+		t.Errorf("Expected storage to be unchanged, got %d configs", stats.TotalConfigs)
+	}
+}
+
+func TestMemoryStorage_DiffImportPrune(t *testing.T) {
+	storage := newDiffTestStorage(t)
+
+	diff := storage.DiffImport([]ProxyConfig{*newEvictionTestConfig("stable"), *newEvictionTestConfig("changed")}, true)
+	if len(diff.Deletes) != 1 || diff.Deletes[0].Name != "absent" {
+		t.Errorf("Expected absent config as delete in prune mode, got %+v", diff.Deletes)
+	}
+}
+
+func TestMemoryStorage_DiffImportSecretsAndErrors(t *testing.T) {
+	storage := newDiffTestStorage(t)
+
+	withSecret := newEvictionTestConfig("changed")
+	withSecret.RequestSigning = &RequestSigning{Secret: "0123456789abcdef0123456789abcdef"}
+	invalid := newEvictionTestConfig("invalid")
+	invalid.TargetURL = ""
+
+	diff := storage.DiffImport([]ProxyConfig{*withSecret, *invalid, *newEvictionTestConfig("invalid")}, false)
+
+	if len(diff.Updates) != 1 || len(diff.Updates[0].Changes) != 1 {
+		t.Fatalf("Expected one changed field, got %+v", diff.Updates)
+	}
+	if change := diff.Updates[0].Changes[0]; change.Field != "request_signing" || change.Old != nil || change.New != nil {
+		t.Errorf("Expected secret field change without values, got %+v", change)
+	}
+	if len(diff.Errors) != 2 || len(diff.Adds) != 0 {
+		t.Errorf("Expected invalid and duplicate configs as errors, got adds=%+v errors=%v", diff.Adds, diff.Errors)
+	}
+}
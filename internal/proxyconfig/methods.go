@@ -0,0 +1,64 @@
+package proxyconfig
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// allowedMethodNames 可以在allowed_methods中使用的HTTP方法
+var allowedMethodNames = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodConnect: true,
+	http.MethodTrace:   true,
+}
+
+// AllowsMethod 检查请求方法是否允许转发；未配置allowed_methods时不限制，OPTIONS预检始终放行
+func (c *ProxyConfig) AllowsMethod(method string) bool {
+	if len(c.AllowedMethods) == 0 || method == http.MethodOptions {
+		return true
+	}
+	for _, allowed := range c.AllowedMethods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowHeader 返回405响应的Allow头部值（配置的方法加上始终放行的OPTIONS）
+func (c *ProxyConfig) AllowHeader() string {
+	methods := make([]string, 0, len(c.AllowedMethods)+1)
+	hasOptions := false
+	for _, method := range c.AllowedMethods {
+		if method == http.MethodOptions {
+			hasOptions = true
+		}
+		methods = append(methods, method)
+	}
+	if !hasOptions {
+		methods = append(methods, http.MethodOptions)
+	}
+	return strings.Join(methods, ", ")
+}
+
+// ValidateAllowedMethods 验证允许的方法列表：必须是大写的标准HTTP方法且不重复
+func ValidateAllowedMethods(methods []string) error {
+	seen := make(map[string]bool, len(methods))
+	for i, method := range methods {
+		if !allowedMethodNames[method] {
+			return fmt.Errorf("allowed_methods[%d] %q is not a valid HTTP method", i, method)
+		}
+		if seen[method] {
+			return fmt.Errorf("allowed_methods[%d] %q is duplicated", i, method)
+		}
+		seen[method] = true
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package proxyconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAllowedMethods(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods []string
+		wantErr string
+	}{
+		{"empty", nil, ""},
+		{"valid", []string{"GET", "HEAD"}, ""},
+		{"lowercase", []string{"get"}, "not a valid HTTP method"},
+		{"unknown", []string{"GET", "FETCH"}, "allowed_methods[1]"},
+		{"duplicate", []string{"GET", "GET"}, "duplicated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAllowedMethods(tt.methods)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid methods, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// 保存配置时同样校验
+	config := &ProxyConfig{Name: "api", TargetURL: "https://api.example.com", Protocol: "https", AllowedMethods: []string{"get"}}
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "allowed_methods") {
+		t.Errorf("Expected allowed_methods validation error, got %v", err)
+	}
+}
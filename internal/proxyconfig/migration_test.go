@@ -98,7 +98,6 @@ func TestMigrateConfigData_AlreadyNewVersion(t *testing.T) {
 		"config1": {
 			ID:           "config1",
 			Name:         "Test Config 1",
-			Subdomain:    "test1",
 			TargetURL:    "https://example1.com",
 			Protocol:     "https",
 			Enabled:      true,
@@ -197,7 +196,6 @@ func TestValidateConfigIntegrity(t *testing.T) {
 		"valid-config": {
 			ID:           "valid-config",
 			Name:         "Valid Config",
-			Subdomain:    "valid",
 			TargetURL:    "https://example.com",
 			AccessTokens: []AccessToken{},
 			TokenStats:   &TokenStats{},
@@ -205,10 +203,9 @@ func TestValidateConfigIntegrity(t *testing.T) {
 		"invalid-config": {
 			ID:           "wrong-id", // ID不匹配
 			Name:         "",         // 空名称
-			Subdomain:    "invalid",
-			TargetURL:    "",  // 空URL
-			AccessTokens: nil, // nil AccessTokens
-			TokenStats:   nil, // nil TokenStats
+			TargetURL:    "",         // 空URL
+			AccessTokens: nil,        // nil AccessTokens
+			TokenStats:   nil,        // nil TokenStats
 		},
 		"nil-config": nil, // nil配置
 	}
@@ -0,0 +1,45 @@
+package proxyconfig
+
+import (
+	"errors"
+	"net/url"
+)
+
+// MirrorTarget 将转发目标映射到镜像上游：使用镜像地址的协议和主机，镜像路径作为基础路径，保留目标的路径和查询参数
+func (c *ProxyConfig) MirrorTarget(target *url.URL) (*url.URL, error) {
+	base, err := url.Parse(c.MirrorURL)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrored := JoinBasePath(base.Path, target)
+	if mirrored == target {
+		copied := *target
+		mirrored = &copied
+	}
+	mirrored.Scheme = base.Scheme
+	mirrored.Host = base.Host
+	mirrored.User = nil
+	return mirrored, nil
+}
+
+// ValidateMirrorURL 验证镜像上游地址（为空表示不镜像）
+func ValidateMirrorURL(mirrorURL string) error {
+	if mirrorURL == "" {
+		return nil
+	}
+	u, err := url.Parse(mirrorURL)
+	if err != nil {
+		return errors.New("invalid mirror_url format")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("mirror_url must use http or https")
+	}
+	if u.Host == "" {
+		return errors.New("mirror_url must have a host")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("mirror_url must not contain a query or fragment")
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package proxyconfig
+
+// HasOwnerSecret 检查配置是否设置了租户管理密钥
+func (c *ProxyConfig) HasOwnerSecret() bool {
+	return c.OwnerSecretHash != ""
+}
+
+// CheckOwnerSecret 校验租户管理密钥（按存储的哈希方案验证），未设置租户密钥的配置始终不通过
+func (c *ProxyConfig) CheckOwnerSecret(secret string) bool {
+	if !c.HasOwnerSecret() || secret == "" {
+		return false
+	}
+	return VerifyToken(secret, c.OwnerSecretHash)
+}
@@ -0,0 +1,57 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// patchReadOnlyFields 不能通过PATCH修改的字段（由服务端维护）
+var patchReadOnlyFields = map[string]bool{
+	"id":            true,
+	"created_at":    true,
+	"updated_at":    true,
+	"deleted_at":    true,
+	"stats":         true,
+	"access_tokens": true,
+	"token_stats":   true,
+}
+
+// ApplyPatch 将部分更新合并到配置副本上，返回合并后的配置（不修改existing）
+//
+// 只覆盖patch中出现的顶层字段，未出现的字段保持原值，因此false、0、空字符串也能被正确设置；
+// 值为null时恢复为零值。嵌套对象（如request_signing）整体替换，不做深度合并。
+// version字段会被保留在结果中，由调用方用于版本检查。
+func ApplyPatch(existing *ProxyConfig, patch map[string]json.RawMessage) (*ProxyConfig, error) {
+	for field := range patch {
+		if patchReadOnlyFields[field] {
+			return nil, fmt.Errorf("field %q cannot be modified", field)
+		}
+	}
+
+	data, err := json.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+
+	for field, value := range patch {
+		if string(value) == "null" {
+			delete(merged, field)
+			continue
+		}
+		merged[field] = value
+	}
+
+	data, err = json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var result ProxyConfig
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,52 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	existing := newEvictionTestConfig("patch")
+	existing.Enabled = true
+	existing.Tags = map[string]string{"env": "prod"}
+
+	decode := func(body string) map[string]json.RawMessage {
+		var patch map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(body), &patch); err != nil {
+			t.Fatalf("Invalid patch %s: %v", body, err)
+		}
+		return patch
+	}
+
+	// 零值字段被显式设置，未提供的字段保持不变
+	merged, err := ApplyPatch(existing, decode(`{"enabled":false}`))
+	if err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+	if merged.Enabled {
+		t.Error("Expected enabled to be false")
+	}
+	if merged.Name != existing.Name || merged.TargetURL != existing.TargetURL || merged.Tags["env"] != "prod" {
+		t.Errorf("Expected untouched fields to be preserved, got %+v", merged)
+	}
+	if !existing.Enabled {
+		t.Error("Expected existing config to be unchanged")
+	}
+
+	// null恢复为零值
+	merged, err = ApplyPatch(existing, decode(`{"tags":null}`))
+	if err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+	if merged.Tags != nil {
+		t.Errorf("Expected tags to be cleared, got %v", merged.Tags)
+	}
+
+	// 只读字段和类型错误
+	if _, err := ApplyPatch(existing, decode(`{"created_at":"2020-01-01T00:00:00Z"}`)); err == nil {
+		t.Error("Expected error for read-only field")
+	}
+	if _, err := ApplyPatch(existing, decode(`{"enabled":"yes"}`)); err == nil {
+		t.Error("Expected error for mistyped field")
+	}
+}
@@ -0,0 +1,144 @@
+package proxyconfig
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PathRule 路径前缀路由规则：将匹配前缀的目标路径映射到另一个上游基础路径
+type PathRule struct {
+	MatchPrefix string `json:"match_prefix"`           // 匹配的路径前缀（按路径段匹配，如/api匹配/api和/api/x，不匹配/apix）
+	RewriteTo   string `json:"rewrite_to,omitempty"`   // 改写后的基础路径（/v1）或基础URL（https://backend/v1）
+	StripPrefix bool   `json:"strip_prefix,omitempty"` // 是否去掉匹配的前缀后再拼接到RewriteTo
+}
+
+// RewriteTarget 按路径规则改写目标地址，首个匹配的规则生效；无规则匹配时原样返回target和false
+func (c *ProxyConfig) RewriteTarget(target *url.URL) (*url.URL, bool) {
+	for _, rule := range c.PathRules {
+		prefix := normalizePathPrefix(rule.MatchPrefix)
+		if !matchPathPrefix(target.Path, prefix) {
+			continue
+		}
+
+		rest := target.Path
+		if rule.StripPrefix && prefix != "/" {
+			rest = strings.TrimPrefix(target.Path, prefix)
+		}
+
+		rewritten := *target
+		rewritten.RawPath = ""
+		basePath := ""
+		if rule.RewriteTo != "" {
+			base, err := url.Parse(rule.RewriteTo)
+			if err != nil {
+				continue
+			}
+			if base.Host != "" {
+				rewritten.Scheme = base.Scheme
+				rewritten.Host = base.Host
+				rewritten.User = nil
+			}
+			basePath = strings.TrimSuffix(base.Path, "/")
+		}
+
+		rewritten.Path = basePath + rest
+		if rewritten.Path == "" {
+			rewritten.Path = "/"
+		}
+		return &rewritten, true
+	}
+	return target, false
+}
+
+// ApplyBasePath 将配置的基础路径拼接到目标路径前，自动处理两端的/；未配置基础路径时原样返回target
+func (c *ProxyConfig) ApplyBasePath(target *url.URL) *url.URL {
+	return JoinBasePath(c.BasePath, target)
+}
+
+// JoinBasePath 将基础路径拼接到目标路径前："/v1" + "/users" 为 "/v1/users"，"/v1/" + "" 为 "/v1"
+func JoinBasePath(basePath string, target *url.URL) *url.URL {
+	base := strings.Trim(basePath, "/")
+	if base == "" {
+		return target
+	}
+
+	joined := *target
+	joined.RawPath = ""
+	rest := strings.TrimPrefix(target.Path, "/")
+	joined.Path = "/" + base
+	if rest != "" {
+		joined.Path += "/" + rest
+	}
+	return &joined
+}
+
+// ValidateBasePath 验证基础路径：为空或以/开头的普通路径
+func ValidateBasePath(basePath string) error {
+	if basePath == "" {
+		return nil
+	}
+	if !strings.HasPrefix(basePath, "/") {
+		return fmt.Errorf("base_path must start with /")
+	}
+	if strings.Contains(basePath, "..") || strings.ContainsAny(basePath, "?#") {
+		return fmt.Errorf("base_path is not a valid path")
+	}
+	return nil
+}
+
+// ValidatePathRules 验证路径规则：前缀格式、改写目标，以及规则之间不存在被前面规则完全遮蔽的情况
+func ValidatePathRules(rules []PathRule) error {
+	for i, rule := range rules {
+		if !strings.HasPrefix(rule.MatchPrefix, "/") {
+			return fmt.Errorf("path_rules[%d].match_prefix must start with /", i)
+		}
+		if strings.Contains(rule.MatchPrefix, "..") || strings.ContainsAny(rule.MatchPrefix, "?#") {
+			return fmt.Errorf("path_rules[%d].match_prefix is not a valid path", i)
+		}
+
+		if rule.RewriteTo == "" {
+			if !rule.StripPrefix {
+				return fmt.Errorf("path_rules[%d] must set rewrite_to or strip_prefix", i)
+			}
+		} else if !strings.HasPrefix(rule.RewriteTo, "/") {
+			base, err := url.Parse(rule.RewriteTo)
+			if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+				return fmt.Errorf("path_rules[%d].rewrite_to must be a path or an http(s) URL", i)
+			}
+			if base.RawQuery != "" || base.Fragment != "" {
+				return fmt.Errorf("path_rules[%d].rewrite_to must not contain a query or fragment", i)
+			}
+		} else if strings.ContainsAny(rule.RewriteTo, "?#") {
+			return fmt.Errorf("path_rules[%d].rewrite_to must not contain a query or fragment", i)
+		}
+
+		// 首个匹配生效：前面规则的前缀覆盖了当前规则时，当前规则永远不会生效
+		prefix := normalizePathPrefix(rule.MatchPrefix)
+		for j := 0; j < i; j++ {
+			if matchPathPrefix(prefix, normalizePathPrefix(rules[j].MatchPrefix)) {
+				return fmt.Errorf("path_rules[%d] (%s) is unreachable: shadowed by path_rules[%d] (%s)", i, rule.MatchPrefix, j, rules[j].MatchPrefix)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizePathPrefix 去掉前缀末尾的/（根路径除外）
+func normalizePathPrefix(prefix string) string {
+	if prefix != "/" {
+		prefix = strings.TrimSuffix(prefix, "/")
+	}
+	if prefix == "" {
+		return "/"
+	}
+	return prefix
+}
+
+// matchPathPrefix 按路径段检查path是否以prefix开头
+func matchPathPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,109 @@
+package proxyconfig
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProxyConfig_RewriteTarget(t *testing.T) {
+	config := &ProxyConfig{
+		PathRules: []PathRule{
+			{MatchPrefix: "/api/v1", RewriteTo: "https://backend.example.com/v1", StripPrefix: true},
+			{MatchPrefix: "/static/", RewriteTo: "/assets"},
+			{MatchPrefix: "/internal", StripPrefix: true},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		want    string
+		matched bool
+	}{
+		{"prefix match with strip", "https://api.example.com/api/v1/users?page=2", "https://backend.example.com/v1/users?page=2", true},
+		{"exact prefix", "https://api.example.com/api/v1", "https://backend.example.com/v1", true},
+		{"rewrite keeps full path", "https://api.example.com/static/app.js", "https://api.example.com/assets/static/app.js", true},
+		{"strip only", "https://api.example.com/internal/health", "https://api.example.com/health", true},
+		{"strip to root", "https://api.example.com/internal", "https://api.example.com/", true},
+		{"segment boundary", "https://api.example.com/api/v10/users", "https://api.example.com/api/v10/users", false},
+		{"pass-through", "https://api.example.com/other", "https://api.example.com/other", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, _ := url.Parse(tt.target)
+			got, matched := config.RewriteTarget(target)
+			if matched != tt.matched || got.String() != tt.want {
+				t.Errorf("RewriteTarget(%s) = %s (matched=%v), want %s (matched=%v)", tt.target, got, matched, tt.want, tt.matched)
+			}
+		})
+	}
+
+	// 原始地址不被修改
+	target, _ := url.Parse("https://api.example.com/api/v1/users")
+	config.RewriteTarget(target)
+	if target.String() != "https://api.example.com/api/v1/users" {
+		t.Errorf("Expected original target to be unchanged, got %s", target)
+	}
+}
+
+func TestValidatePathRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []PathRule
+		wantErr string
+	}{
+		{"valid", []PathRule{{MatchPrefix: "/api/v1", RewriteTo: "/v1"}, {MatchPrefix: "/api", RewriteTo: "https://legacy.example.com"}}, ""},
+		{"relative prefix", []PathRule{{MatchPrefix: "api", RewriteTo: "/v1"}}, "must start with /"},
+		{"no action", []PathRule{{MatchPrefix: "/api"}}, "rewrite_to or strip_prefix"},
+		{"invalid rewrite", []PathRule{{MatchPrefix: "/api", RewriteTo: "ftp://backend/v1"}}, "rewrite_to"},
+		{"duplicate prefix", []PathRule{{MatchPrefix: "/api", RewriteTo: "/a"}, {MatchPrefix: "/api/", RewriteTo: "/b"}}, "unreachable"},
+		{"shadowed by shorter prefix", []PathRule{{MatchPrefix: "/api", RewriteTo: "/a"}, {MatchPrefix: "/api/v1", RewriteTo: "/b"}}, "shadowed by path_rules[0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePathRules(tt.rules)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid rules, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProxyConfig_ApplyBasePath(t *testing.T) {
+	tests := []struct {
+		basePath string
+		target   string
+		want     string
+	}{
+		{"/v1", "https://api.example.com", "https://api.example.com/v1"},
+		{"/v1", "https://api.example.com/", "https://api.example.com/v1"},
+		{"/v1", "https://api.example.com/users", "https://api.example.com/v1/users"},
+		{"/v1/", "https://api.example.com/users/42/orders?page=2", "https://api.example.com/v1/users/42/orders?page=2"},
+		{"/api/v2", "https://api.example.com/users/", "https://api.example.com/api/v2/users/"},
+		{"", "https://api.example.com/users", "https://api.example.com/users"},
+		{"/", "https://api.example.com/users", "https://api.example.com/users"},
+	}
+
+	for _, tt := range tests {
+		target, _ := url.Parse(tt.target)
+		config := &ProxyConfig{BasePath: tt.basePath}
+		if got := config.ApplyBasePath(target).String(); got != tt.want {
+			t.Errorf("ApplyBasePath(%q, %q) = %q, want %q", tt.basePath, tt.target, got, tt.want)
+		}
+	}
+
+	for _, basePath := range []string{"v1", "/v1/../admin", "/v1?x=1"} {
+		if err := ValidateBasePath(basePath); err == nil {
+			t.Errorf("Expected ValidateBasePath(%q) to fail", basePath)
+		}
+	}
+}
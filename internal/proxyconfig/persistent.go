@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"privacygateway/internal/logger"
@@ -21,12 +22,29 @@ type PersistentStorage struct {
 	logger       *logger.Logger
 	saveMutex    sync.Mutex
 	stopChan     chan struct{}
+	stopOnce     sync.Once
+	loadErr      error     // 启动时加载配置文件的错误
+	fileModTime  time.Time // 上次加载或写入后配置文件的修改时间（saveMutex保护），用于发现外部修改
+
+	// 保存合并：窗口内的多次修改只写一次文件（saveDelay为0时每次修改立即保存）
+	saveDelay  time.Duration
+	dirtyMutex sync.Mutex
+	dirty      bool        // 有尚未写入文件的修改（包括只在自动保存时写入的令牌使用和请求统计）
+	edited     bool        // 有尚未写入文件的配置或令牌修改（热加载会丢弃这些修改，因此拒绝热加载）
+	saveTimer  *time.Timer // 等待中的合并保存
+	saveCount  atomic.Int64
+
+	statusMutex sync.Mutex
+	saveStatus  PersistenceStatus // 最近的保存结果
 }
 
+// saveFailureThreshold 连续保存失败达到该次数后存储视为降级（就绪检查返回degraded）
+const saveFailureThreshold = 3
+
 // NewPersistentStorage 创建持久化存储实例
-func NewPersistentStorage(filePath string, maxEntries int, autoSave bool, log *logger.Logger) *PersistentStorage {
+func NewPersistentStorage(filePath string, maxEntries int, evictionMode EvictionMode, autoSave bool, log *logger.Logger) *PersistentStorage {
 	ps := &PersistentStorage{
-		MemoryStorage: NewMemoryStorage(maxEntries),
+		MemoryStorage: NewMemoryStorageWithEviction(maxEntries, evictionMode),
 		filePath:      filePath,
 		saveInterval:  30 * time.Second,
 		autoSave:      autoSave,
@@ -36,6 +54,7 @@ func NewPersistentStorage(filePath string, maxEntries int, autoSave bool, log *l
 
 	// 启动时加载配置
 	if err := ps.LoadFromFile(); err != nil {
+		ps.loadErr = err
 		log.Error("failed to load configs from file", "error", err, "file", filePath)
 	} else {
 		log.Info("configs loaded from file", "file", filePath, "count", len(ps.configs))
@@ -50,43 +69,180 @@ func NewPersistentStorage(filePath string, maxEntries int, autoSave bool, log *l
 	return ps
 }
 
-// SaveToFile 保存配置到文件
+// SaveToFile 保存配置到文件，并记录保存结果供健康检查使用
 func (ps *PersistentStorage) SaveToFile() error {
 	ps.saveMutex.Lock()
 	defer ps.saveMutex.Unlock()
 
+	return ps.saveLocked()
+}
+
+// saveLocked 写入文件并清除待保存标记（调用方持有saveMutex）
+//
+// 标记在序列化之前清除：序列化之后发生的修改会重新设置标记，不会被遗漏。
+func (ps *PersistentStorage) saveLocked() error {
+	ps.dirtyMutex.Lock()
+	edited := ps.edited
+	ps.dirty = false
+	ps.edited = false
+	ps.dirtyMutex.Unlock()
+
+	count, err := ps.writeFile()
+	ps.recordSaveResult(err)
+	if err != nil {
+		// 保存失败时保留待保存标记，之后的保存会再次尝试
+		ps.dirtyMutex.Lock()
+		ps.dirty = true
+		ps.edited = ps.edited || edited
+		ps.dirtyMutex.Unlock()
+		return err
+	}
+
+	ps.fileModTime = ps.currentFileModTime()
+	ps.saveCount.Add(1)
+	ps.logger.Debug("configs saved to file", "file", ps.filePath, "count", count)
+	return nil
+}
+
+// currentFileModTime 返回配置文件当前的修改时间，文件不存在时返回零值
+func (ps *PersistentStorage) currentFileModTime() time.Time {
+	info, err := os.Stat(ps.filePath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// saveIfDirty 自动保存：只在有未保存的修改时写文件；
+// 文件在上次加载或保存之后被外部修改时跳过，避免覆盖尚未通过SIGHUP热加载的手工修改
+func (ps *PersistentStorage) saveIfDirty() error {
+	ps.saveMutex.Lock()
+	defer ps.saveMutex.Unlock()
+
+	if !ps.isDirty() {
+		return nil
+	}
+	if modTime := ps.currentFileModTime(); !modTime.Equal(ps.fileModTime) {
+		ps.logger.Warn("config file modified externally, skipping auto save until it is reloaded", "file", ps.filePath)
+		return nil
+	}
+	return ps.saveLocked()
+}
+
+// hasPendingEdits 是否有尚未写入文件的配置或令牌修改（合并窗口内或保存失败）
+func (ps *PersistentStorage) hasPendingEdits() bool {
+	ps.dirtyMutex.Lock()
+	defer ps.dirtyMutex.Unlock()
+	return ps.edited
+}
+
+// isDirty 是否有尚未写入文件的修改
+func (ps *PersistentStorage) isDirty() bool {
+	ps.dirtyMutex.Lock()
+	defer ps.dirtyMutex.Unlock()
+	return ps.dirty
+}
+
+// writeFile 将全部配置写入文件，返回写入的配置数量（调用方持有saveMutex）
+func (ps *PersistentStorage) writeFile() (int, error) {
+	// 在读锁内序列化：配置对象会被并发的令牌使用、统计更新原地修改
 	ps.mutex.RLock()
 	configsCopy := make(map[string]*ProxyConfig)
 	for k, v := range ps.configs {
 		configsCopy[k] = v
 	}
-	ps.mutex.RUnlock()
-
+	// 回收站中的配置带有deleted_at，一并保存，重启后仍可恢复
+	for k, v := range ps.deleted {
+		configsCopy[k] = v
+	}
 	data, err := json.MarshalIndent(configsCopy, "", "  ")
+	ps.mutex.RUnlock()
 	if err != nil {
-		return fmt.Errorf("failed to marshal configs: %w", err)
+		return 0, fmt.Errorf("failed to marshal configs: %w", err)
 	}
 
 	// 创建目录（如果不存在）
 	if dir := filepath.Dir(ps.filePath); dir != "." {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory: %w", err)
+			return 0, fmt.Errorf("failed to create directory: %w", err)
 		}
 	}
 
 	// 写入临时文件，然后原子性重命名
 	tempFile := ps.filePath + ".tmp"
 	if err := ioutil.WriteFile(tempFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
+		return 0, fmt.Errorf("failed to write temp file: %w", err)
 	}
 
 	if err := os.Rename(tempFile, ps.filePath); err != nil {
 		os.Remove(tempFile) // 清理临时文件
-		return fmt.Errorf("failed to rename temp file: %w", err)
+		return 0, fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
-	ps.logger.Debug("configs saved to file", "file", ps.filePath, "count", len(configsCopy))
-	return nil
+	return len(configsCopy), nil
+}
+
+// SetSaveDelay 设置保存合并窗口：修改后等待delay再保存，期间的其他修改合并为一次写入（0表示每次修改立即保存）
+func (ps *PersistentStorage) SetSaveDelay(delay time.Duration) {
+	ps.dirtyMutex.Lock()
+	defer ps.dirtyMutex.Unlock()
+	ps.saveDelay = delay
+}
+
+// requestSave 在修改后保存：未设置合并窗口时立即保存，否则在窗口结束时统一保存
+func (ps *PersistentStorage) requestSave() error {
+	ps.dirtyMutex.Lock()
+	ps.edited = true
+	ps.dirtyMutex.Unlock()
+
+	if ps.markDirty() {
+		return nil
+	}
+	return ps.SaveToFile()
+}
+
+// markDirty 标记有待保存的修改；设置了合并窗口时启动窗口计时并返回true，否则返回false
+func (ps *PersistentStorage) markDirty() bool {
+	ps.dirtyMutex.Lock()
+	defer ps.dirtyMutex.Unlock()
+
+	ps.dirty = true
+	if ps.saveDelay <= 0 {
+		return false
+	}
+	if ps.saveTimer == nil {
+		ps.saveTimer = time.AfterFunc(ps.saveDelay, ps.flushDelayedSave)
+	}
+	return true
+}
+
+// flushDelayedSave 合并窗口结束时保存待保存的修改
+func (ps *PersistentStorage) flushDelayedSave() {
+	ps.dirtyMutex.Lock()
+	ps.saveTimer = nil
+	ps.dirtyMutex.Unlock()
+
+	if err := ps.Flush(); err != nil {
+		ps.logger.Error("delayed save failed", "error", err, "file", ps.filePath)
+	}
+}
+
+// Flush 立即保存合并窗口内尚未写入的修改，没有待保存的修改时不写文件
+func (ps *PersistentStorage) Flush() error {
+	ps.dirtyMutex.Lock()
+	if ps.saveTimer != nil {
+		ps.saveTimer.Stop()
+		ps.saveTimer = nil
+	}
+	ps.dirtyMutex.Unlock()
+
+	ps.saveMutex.Lock()
+	defer ps.saveMutex.Unlock()
+
+	if !ps.isDirty() {
+		return nil
+	}
+	return ps.saveLocked()
 }
 
 // LoadFromFile 从文件加载配置
@@ -106,15 +262,84 @@ func (ps *PersistentStorage) LoadFromFile() error {
 		return fmt.Errorf("failed to unmarshal config file: %w", err)
 	}
 
+	cleared := clearSaltedTokenValues(configs)
+
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 
-	ps.configs = configs
+	ps.configs, ps.deleted = splitDeleted(configs)
+	ps.rebuildTokenIndexLocked()
+	ps.fileModTime = ps.currentFileModTime()
+
+	for _, config := range ps.configs {
+		WarnInsecureTLS(ps.logger, config)
+	}
+
+	// 清除后的内容在下次保存时写回文件
+	if cleared > 0 {
+		ps.logger.Info("cleared stored values of salted tokens", "count", cleared)
+		ps.markDirty()
+	}
 
 	return nil
 }
 
-// StartAutoSave 启动自动保存
+// recordSaveResult 记录一次保存的结果：成功时清零连续失败次数，失败达到阈值时输出降级日志
+func (ps *PersistentStorage) recordSaveResult(err error) {
+	ps.statusMutex.Lock()
+	defer ps.statusMutex.Unlock()
+
+	now := time.Now()
+	if err == nil {
+		if ps.saveStatus.ConsecutiveFailures >= saveFailureThreshold {
+			ps.logger.Info("persistent storage recovered", "file", ps.filePath, "failures", ps.saveStatus.ConsecutiveFailures)
+		}
+		ps.saveStatus.LastSaveAt = &now
+		ps.saveStatus.ConsecutiveFailures = 0
+		ps.saveStatus.Degraded = false
+		return
+	}
+
+	ps.saveStatus.LastError = err.Error()
+	ps.saveStatus.LastErrorAt = &now
+	ps.saveStatus.ConsecutiveFailures++
+	if ps.saveStatus.ConsecutiveFailures == saveFailureThreshold {
+		ps.saveStatus.Degraded = true
+		ps.logger.Error("persistent storage degraded, configs are not being saved", "file", ps.filePath, "failures", ps.saveStatus.ConsecutiveFailures, "error", err)
+	}
+}
+
+// SaveStatus 返回最近的保存状态（副本）
+func (ps *PersistentStorage) SaveStatus() *PersistenceStatus {
+	ps.statusMutex.Lock()
+	defer ps.statusMutex.Unlock()
+
+	status := ps.saveStatus
+	return &status
+}
+
+// SaveError 连续保存失败达到阈值时返回最近一次的错误（健康检查使用），否则返回nil
+func (ps *PersistentStorage) SaveError() error {
+	status := ps.SaveStatus()
+	if !status.Degraded {
+		return nil
+	}
+	return fmt.Errorf("%d consecutive save failures: %s", status.ConsecutiveFailures, status.LastError)
+}
+
+// GetStats 获取统计信息（附带持久化状态）
+func (ps *PersistentStorage) GetStats() *StorageStats {
+	stats := ps.MemoryStorage.GetStats()
+	stats.Persistence = ps.SaveStatus()
+	return stats
+}
+
+// LoadError 返回启动时加载配置文件的错误（成功时为nil）
+func (ps *PersistentStorage) LoadError() error {
+	return ps.loadErr
+}
+
+// StartAutoSave 启动自动保存（每个周期只在有未保存的修改时写文件，见saveIfDirty）
 func (ps *PersistentStorage) StartAutoSave() {
 	go func() {
 		ticker := time.NewTicker(ps.saveInterval)
@@ -123,7 +348,7 @@ func (ps *PersistentStorage) StartAutoSave() {
 		for {
 			select {
 			case <-ticker.C:
-				if err := ps.SaveToFile(); err != nil {
+				if err := ps.saveIfDirty(); err != nil {
 					ps.logger.Error("auto save failed", "error", err)
 				}
 			case <-ps.stopChan:
@@ -134,9 +359,9 @@ func (ps *PersistentStorage) StartAutoSave() {
 	}()
 }
 
-// StopAutoSave 停止自动保存
+// StopAutoSave 停止自动保存（可重复调用）
 func (ps *PersistentStorage) StopAutoSave() {
-	close(ps.stopChan)
+	ps.stopOnce.Do(func() { close(ps.stopChan) })
 }
 
 // Add 添加配置（重写以支持持久化）
@@ -145,8 +370,8 @@ func (ps *PersistentStorage) Add(config *ProxyConfig) error {
 		return err
 	}
 
-	// 立即保存到文件
-	if err := ps.SaveToFile(); err != nil {
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
 		ps.logger.Error("failed to save after add", "error", err)
 		// 不返回错误，因为内存操作已经成功
 	}
@@ -160,8 +385,8 @@ func (ps *PersistentStorage) Update(id string, config *ProxyConfig) error {
 		return err
 	}
 
-	// 立即保存到文件
-	if err := ps.SaveToFile(); err != nil {
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
 		ps.logger.Error("failed to save after update", "error", err)
 		// 不返回错误，因为内存操作已经成功
 	}
@@ -175,8 +400,8 @@ func (ps *PersistentStorage) Delete(id string) error {
 		return err
 	}
 
-	// 立即保存到文件
-	if err := ps.SaveToFile(); err != nil {
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
 		ps.logger.Error("failed to save after delete", "error", err)
 		// 不返回错误，因为内存操作已经成功
 	}
@@ -184,22 +409,73 @@ func (ps *PersistentStorage) Delete(id string) error {
 	return nil
 }
 
+// Restore 从回收站恢复配置（重写以支持持久化）
+func (ps *PersistentStorage) Restore(id string) error {
+	if err := ps.MemoryStorage.Restore(id); err != nil {
+		return err
+	}
+
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
+		ps.logger.Error("failed to save after restore", "error", err)
+		// 不返回错误，因为内存操作已经成功
+	}
+
+	return nil
+}
+
+// PurgeDeleted 彻底清除回收站中过期的配置（重写以支持持久化）
+func (ps *PersistentStorage) PurgeDeleted(deletedBefore time.Time) int {
+	purged := ps.MemoryStorage.PurgeDeleted(deletedBefore)
+	if purged == 0 {
+		return 0
+	}
+
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
+		ps.logger.Error("failed to save after purge", "error", err)
+	}
+
+	return purged
+}
+
+// ImportConfigs 导入配置（重写以支持持久化）
+func (ps *PersistentStorage) ImportConfigs(configs []ProxyConfig, mode string) (*ImportResult, error) {
+	return ps.ImportConfigsWithOptions(configs, ImportOptions{Mode: mode})
+}
+
+// ImportConfigsWithOptions 按导入选项导入配置（重写以支持持久化）
+func (ps *PersistentStorage) ImportConfigsWithOptions(configs []ProxyConfig, options ImportOptions) (*ImportResult, error) {
+	result, err := ps.MemoryStorage.ImportConfigsWithOptions(configs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
+		ps.logger.Error("failed to save after import", "error", err)
+		// 不返回错误，因为内存操作已经成功
+	}
+
+	return result, nil
+}
+
 // Clear 清空所有配置（重写以支持持久化）
 func (ps *PersistentStorage) Clear() {
 	ps.MemoryStorage.Clear()
 
-	// 立即保存到文件
-	if err := ps.SaveToFile(); err != nil {
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
 		ps.logger.Error("failed to save after clear", "error", err)
 	}
 }
 
-// Shutdown 优雅关闭
+// Shutdown 优雅关闭：停止自动保存和等待中的合并保存，保存尚未写入的修改
 func (ps *PersistentStorage) Shutdown() error {
 	ps.StopAutoSave()
 
-	// 最后保存一次
-	if err := ps.SaveToFile(); err != nil {
+	// 保存尚未写入的修改（没有修改时不改写文件）
+	if err := ps.Flush(); err != nil {
 		return fmt.Errorf("failed to save on shutdown: %w", err)
 	}
 
@@ -207,6 +483,11 @@ func (ps *PersistentStorage) Shutdown() error {
 	return nil
 }
 
+// Close 关闭存储（同Shutdown，进程退出时保存尚未写入的修改）
+func (ps *PersistentStorage) Close() error {
+	return ps.Shutdown()
+}
+
 // ==================== 令牌管理持久化方法 ====================
 
 // AddToken 添加令牌（重写以支持持久化）
@@ -215,8 +496,8 @@ func (ps *PersistentStorage) AddToken(configID string, token *AccessToken) error
 		return err
 	}
 
-	// 立即保存到文件
-	if err := ps.SaveToFile(); err != nil {
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
 		ps.logger.Error("failed to save after add token", "error", err, "config_id", configID, "token_id", token.ID)
 		// 不返回错误，因为内存操作已经成功
 	}
@@ -230,8 +511,8 @@ func (ps *PersistentStorage) UpdateToken(configID, tokenID string, token *Access
 		return err
 	}
 
-	// 立即保存到文件
-	if err := ps.SaveToFile(); err != nil {
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
 		ps.logger.Error("failed to save after update token", "error", err, "config_id", configID, "token_id", tokenID)
 		// 不返回错误，因为内存操作已经成功
 	}
@@ -245,8 +526,8 @@ func (ps *PersistentStorage) DeleteToken(configID, tokenID string) error {
 		return err
 	}
 
-	// 立即保存到文件
-	if err := ps.SaveToFile(); err != nil {
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
 		ps.logger.Error("failed to save after delete token", "error", err, "config_id", configID, "token_id", tokenID)
 		// 不返回错误，因为内存操作已经成功
 	}
@@ -254,14 +535,72 @@ func (ps *PersistentStorage) DeleteToken(configID, tokenID string) error {
 	return nil
 }
 
+// ResetTokenStats 清零令牌使用统计（重写以支持持久化）
+func (ps *PersistentStorage) ResetTokenStats(configID, tokenID string) error {
+	if err := ps.MemoryStorage.ResetTokenStats(configID, tokenID); err != nil {
+		return err
+	}
+
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
+		ps.logger.Error("failed to save after reset token stats", "error", err, "config_id", configID, "token_id", tokenID)
+		// 不返回错误，因为内存操作已经成功
+	}
+
+	return nil
+}
+
+// ResetConfigStats 清零配置统计（重写以支持持久化）
+func (ps *PersistentStorage) ResetConfigStats(configID string) error {
+	if err := ps.MemoryStorage.ResetConfigStats(configID); err != nil {
+		return err
+	}
+
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
+		ps.logger.Error("failed to save after reset config stats", "error", err, "config_id", configID)
+		// 不返回错误，因为内存操作已经成功
+	}
+
+	return nil
+}
+
+// BatchOperation 批量操作（重写以支持持久化）
+func (ps *PersistentStorage) BatchOperation(operation string, configIDs []string) (*BatchOperationResult, error) {
+	result, err := ps.MemoryStorage.BatchOperation(operation, configIDs)
+	if err != nil || len(result.Success) == 0 {
+		return result, err
+	}
+
+	// 保存到文件（设置了合并窗口时延迟到窗口结束统一保存）
+	if err := ps.requestSave(); err != nil {
+		ps.logger.Error("failed to save after batch operation", "error", err, "operation", operation)
+		// 不返回错误，因为内存操作已经成功
+	}
+
+	return result, nil
+}
+
+// UpdateStats 更新配置的请求统计（重写以支持持久化）
+func (ps *PersistentStorage) UpdateStats(configID string, responseTime time.Duration, success bool, bytes int64) error {
+	if err := ps.MemoryStorage.UpdateStats(configID, responseTime, success, bytes); err != nil {
+		return err
+	}
+
+	// 请求统计每个请求都会更新，与令牌使用统计一样不立即保存
+	ps.markDirty()
+	return nil
+}
+
 // UpdateTokenUsage 更新令牌使用统计（重写以支持持久化）
 func (ps *PersistentStorage) UpdateTokenUsage(configID, tokenValue string) error {
 	if err := ps.MemoryStorage.UpdateTokenUsage(configID, tokenValue); err != nil {
 		return err
 	}
 
-	// 令牌使用统计更新频繁，不立即保存，依赖自动保存
+	// 令牌使用统计更新频繁，不立即保存：设置了合并窗口时随窗口统一保存，否则依赖自动保存
 	// 这样可以避免过多的磁盘I/O操作
+	ps.markDirty()
 	ps.logger.Debug("token usage updated", "config_id", configID)
 
 	return nil
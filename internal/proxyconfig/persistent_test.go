@@ -0,0 +1,239 @@
+package proxyconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"privacygateway/internal/logger"
+)
+
+func TestPersistentStorage_SaveDelayCoalescesWrites(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	defer storage.Shutdown()
+	storage.SetSaveDelay(100 * time.Millisecond)
+
+	config := newEvictionTestConfig("debounce")
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// 窗口内的多次令牌修改不立即写文件
+	var lastValue string
+	for i := 0; i < 20; i++ {
+		token, value, _ := CreateAccessToken(&TokenCreateRequest{Name: string(rune('a' + i))}, "admin")
+		if err := storage.AddToken(config.ID, token); err != nil {
+			t.Fatalf("AddToken() error = %v", err)
+		}
+		storage.UpdateTokenUsage(config.ID, value)
+		lastValue = value
+	}
+	if count := storage.saveCount.Load(); count != 0 {
+		t.Errorf("Expected no writes inside the save window, got %d", count)
+	}
+
+	// 窗口结束后合并为一次写入，文件包含最新状态
+	deadline := time.Now().Add(2 * time.Second)
+	for storage.saveCount.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := storage.saveCount.Load(); count < 1 || count > 2 {
+		t.Errorf("Expected 40 mutations to be coalesced into 1-2 writes, got %d", count)
+	}
+
+	reloaded := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	if tokens, _ := reloaded.GetTokens(config.ID); len(tokens) != 20 {
+		t.Errorf("Expected 20 tokens in saved file, got %d", len(tokens))
+	}
+	if found, err := reloaded.FindConfigByToken(lastValue); err != nil || found != config.ID {
+		t.Errorf("Expected last token to be saved, got %q (err %v)", found, err)
+	}
+}
+
+func TestPersistentStorage_CloseFlushesPendingSave(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	storage.SetSaveDelay(time.Hour)
+
+	config := newEvictionTestConfig("pending")
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	token, value, _ := CreateAccessToken(&TokenCreateRequest{Name: "ci"}, "admin")
+	storage.AddToken(config.ID, token)
+	storage.UpdateTokenUsage(config.ID, value)
+	if count := storage.saveCount.Load(); count != 0 {
+		t.Fatalf("Expected save to be pending, got %d writes", count)
+	}
+
+	// 关闭时立即写入尚未保存的修改
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Errorf("Expected second Close to succeed, got %v", err)
+	}
+
+	reloaded := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	stored, err := reloaded.GetTokenByID(config.ID, token.ID)
+	if err != nil {
+		t.Fatalf("Expected pending token to be saved on close, got %v", err)
+	}
+	if stored.UsageCount != 1 {
+		t.Errorf("Expected pending usage to be saved on close, got %d", stored.UsageCount)
+	}
+}
+
+func TestPersistentStorage_Flush(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	defer storage.Shutdown()
+
+	// 没有待保存的修改时不写文件
+	if err := storage.Flush(); err != nil || storage.saveCount.Load() != 0 {
+		t.Errorf("Expected Flush without changes to be a no-op, got %d writes (err %v)", storage.saveCount.Load(), err)
+	}
+
+	storage.SetSaveDelay(time.Hour)
+	storage.Add(newEvictionTestConfig("flushed"))
+	if err := storage.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if count := storage.saveCount.Load(); count != 1 {
+		t.Errorf("Expected Flush to write once, got %d", count)
+	}
+	storage.Flush()
+	if count := storage.saveCount.Load(); count != 1 {
+		t.Errorf("Expected repeated Flush not to write again, got %d", count)
+	}
+}
+
+func TestPersistentStorage_SaveFailureStatus(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	defer storage.StopAutoSave()
+
+	config := newEvictionTestConfig("status")
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if status := storage.GetStats().Persistence; status == nil || status.LastSaveAt == nil || status.Degraded {
+		t.Fatalf("Expected successful save in stats, got %+v", status)
+	}
+
+	// 临时文件路径被目录占用，模拟磁盘不可写
+	if err := os.Mkdir(filePath+".tmp", 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	for i := 1; i < saveFailureThreshold; i++ {
+		if err := storage.SaveToFile(); err == nil {
+			t.Fatal("Expected save to fail")
+		}
+	}
+	if status := storage.SaveStatus(); status.ConsecutiveFailures != saveFailureThreshold-1 || status.Degraded || status.LastError == "" {
+		t.Errorf("Expected failures below threshold not to degrade, got %+v", status)
+	}
+	if err := storage.SaveError(); err != nil {
+		t.Errorf("Expected no save error below threshold, got %v", err)
+	}
+
+	storage.SaveToFile()
+	status := storage.GetStats().Persistence
+	if !status.Degraded || status.ConsecutiveFailures != saveFailureThreshold || status.LastErrorAt == nil {
+		t.Errorf("Expected storage to be degraded after repeated failures, got %+v", status)
+	}
+	if err := storage.SaveError(); err == nil {
+		t.Error("Expected SaveError to report repeated failures")
+	}
+
+	// 恢复写入后清除降级状态，保留最近的错误信息
+	os.Remove(filePath + ".tmp")
+	if err := storage.SaveToFile(); err != nil {
+		t.Fatalf("SaveToFile() error = %v", err)
+	}
+	if status := storage.SaveStatus(); status.Degraded || status.ConsecutiveFailures != 0 || status.LastError == "" {
+		t.Errorf("Expected storage to recover after a successful save, got %+v", status)
+	}
+	if err := storage.SaveError(); err != nil {
+		t.Errorf("Expected no save error after recovery, got %v", err)
+	}
+}
+
+func TestPersistentStorage_AutoSaveOnlyWhenDirty(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	defer storage.StopAutoSave()
+
+	config := newEvictionTestConfig("autosave")
+	storage.Add(config)
+	saved := storage.saveCount.Load()
+
+	// 没有修改时自动保存不写文件
+	if err := storage.saveIfDirty(); err != nil || storage.saveCount.Load() != saved {
+		t.Errorf("Expected clean storage not to be saved, got %d writes (err %v)", storage.saveCount.Load()-saved, err)
+	}
+
+	// 请求统计只在自动保存时写入
+	storage.UpdateStats(config.ID, time.Millisecond, true, 10)
+	if storage.saveCount.Load() != saved {
+		t.Error("Expected stats update not to save immediately")
+	}
+	storage.saveIfDirty()
+	storage.saveIfDirty()
+	if count := storage.saveCount.Load() - saved; count != 1 {
+		t.Errorf("Expected dirty storage to be saved once, got %d writes", count)
+	}
+
+	// 批量操作立即保存
+	storage.BatchOperation("disable", []string{config.ID})
+	reloaded := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	if stored, _ := reloaded.GetByID(config.ID); stored == nil || stored.Enabled || stored.Stats == nil || stored.Stats.RequestCount != 1 {
+		t.Errorf("Expected batch operation and stats to be saved, got %+v", stored)
+	}
+}
+
+func TestPersistentStorage_AutoSaveSkipsExternalEdits(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	defer storage.StopAutoSave()
+
+	config := newEvictionTestConfig("external")
+	storage.Add(config)
+
+	// 运维人员手工修改文件，尚未发送SIGHUP
+	data, _ := os.ReadFile(filePath)
+	edited := []byte(strings.Replace(string(data), "https://example.com", "https://edited.example.com", 1))
+	if err := os.WriteFile(filePath, edited, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filePath, future, future)
+
+	// 自动保存不覆盖手工修改
+	storage.UpdateStats(config.ID, time.Millisecond, true, 10)
+	saved := storage.saveCount.Load()
+	if err := storage.saveIfDirty(); err != nil {
+		t.Fatalf("saveIfDirty() error = %v", err)
+	}
+	if storage.saveCount.Load() != saved {
+		t.Error("Expected auto save to skip an externally modified file")
+	}
+	if current, _ := os.ReadFile(filePath); string(current) != string(edited) {
+		t.Error("Expected manual edit to be preserved")
+	}
+
+	// 热加载之后恢复自动保存，且保存的是手工修改后的内容
+	if _, err := storage.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	storage.saveIfDirty()
+	if storage.saveCount.Load() != saved+1 {
+		t.Error("Expected auto save to resume after reload")
+	}
+	if current, _ := os.ReadFile(filePath); !strings.Contains(string(current), "https://edited.example.com") {
+		t.Error("Expected reloaded edit to be kept in the saved file")
+	}
+}
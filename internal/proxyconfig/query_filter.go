@@ -0,0 +1,48 @@
+package proxyconfig
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// FilterQuery 按配置的查询参数允许/禁止列表过滤转发的查询参数，返回过滤后的参数和是否有参数被移除
+//
+// 允许列表非空时只保留列出的参数，之后再移除禁止列表中的参数；参数名区分大小写。
+func (c *ProxyConfig) FilterQuery(query url.Values) (url.Values, bool) {
+	if len(c.AllowedQueryParams) == 0 && len(c.DeniedQueryParams) == 0 {
+		return query, false
+	}
+
+	allowed := toSet(c.AllowedQueryParams)
+	denied := toSet(c.DeniedQueryParams)
+
+	filtered := make(url.Values, len(query))
+	removed := false
+	for name, values := range query {
+		if (len(allowed) > 0 && !allowed[name]) || denied[name] {
+			removed = true
+			continue
+		}
+		filtered[name] = values
+	}
+	return filtered, removed
+}
+
+// ValidateQueryParams 验证查询参数名列表（field为错误信息中使用的字段名）
+func ValidateQueryParams(field string, names []string) error {
+	for i, name := range names {
+		if name == "" {
+			return fmt.Errorf("%s[%d] must not be empty", field, i)
+		}
+	}
+	return nil
+}
+
+// toSet 将字符串列表转换为集合
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
@@ -0,0 +1,835 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"privacygateway/internal/logger"
+)
+
+// redisMaxTxRetries 乐观事务因并发修改失败后的最大重试次数
+const redisMaxTxRetries = 32
+
+// errRedisTxConflict 被WATCH的键在事务提交前被其他实例修改
+var errRedisTxConflict = errors.New("redis transaction conflict")
+
+// RedisStorage Redis存储实现，多个网关实例共享配置和令牌
+//
+// 键布局（均带keyPrefix前缀）：
+//   - config:<id>  活跃配置的JSON（包含令牌和统计信息）
+//   - configs      活跃配置ID集合
+//   - deleted      回收站，哈希：配置ID -> JSON
+//   - tokens       令牌索引，哈希：SHA-256令牌的哈希或加盐哈希令牌的查找标识 -> 配置ID
+//
+// 单个配置的修改通过WATCH/MULTI乐观事务完成，多个实例同时修改同一配置时自动重试；
+// 列表、搜索、导出等需要全部配置的操作基于一次加载的快照，复用MemoryStorage的实现。
+type RedisStorage struct {
+	client       *redisClient
+	prefix       string
+	maxEntries   int
+	evictionMode EvictionMode
+	logger       *logger.Logger
+}
+
+// NewRedisStorage 创建Redis存储实例，连接失败时返回错误
+func NewRedisStorage(redisURL, keyPrefix string, maxEntries int, evictionMode EvictionMode, log *logger.Logger) (*RedisStorage, error) {
+	client, err := newRedisClient(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	if evictionMode == "" {
+		evictionMode = EvictionReject
+	}
+
+	rs := &RedisStorage{
+		client:       client,
+		prefix:       keyPrefix,
+		maxEntries:   maxEntries,
+		evictionMode: evictionMode,
+		logger:       log,
+	}
+	if err := rs.LoadError(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return rs, nil
+}
+
+// LoadError 检查Redis是否可用（健康检查使用）
+func (rs *RedisStorage) LoadError() error {
+	_, err := rs.client.do("PING")
+	return err
+}
+
+// Close 关闭Redis连接
+func (rs *RedisStorage) Close() error {
+	return rs.client.Close()
+}
+
+// EvictionMode 获取当前淘汰策略
+func (rs *RedisStorage) EvictionMode() EvictionMode {
+	return rs.evictionMode
+}
+
+// ==================== 键和编码 ====================
+
+func (rs *RedisStorage) configKey(id string) string { return rs.prefix + "config:" + id }
+func (rs *RedisStorage) idsKey() string             { return rs.prefix + "configs" }
+func (rs *RedisStorage) deletedKey() string         { return rs.prefix + "deleted" }
+func (rs *RedisStorage) tokensKey() string          { return rs.prefix + "tokens" }
+
+// decodeConfig 解析存储的配置JSON
+func decodeConfig(data string) (*ProxyConfig, error) {
+	var config ProxyConfig
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return &config, nil
+}
+
+// indexedTokenKeys 返回配置中令牌的索引键：SHA-256令牌的哈希，加盐哈希令牌的查找标识
+//
+// 查找标识只有16位十六进制，不会与64位的SHA-256哈希冲突；没有查找标识的加盐哈希令牌无法索引。
+func indexedTokenKeys(config *ProxyConfig) []string {
+	var keys []string
+	for i := range config.AccessTokens {
+		token := &config.AccessTokens[i]
+		if DetectHashScheme(token.TokenHash) == HashSchemeSHA256 {
+			keys = append(keys, token.TokenHash)
+		} else if lookupID := token.lookupKey(); lookupID != "" {
+			keys = append(keys, lookupID)
+		}
+	}
+	return keys
+}
+
+// storeCommands 保存活跃配置并写入其令牌索引的命令
+func (rs *RedisStorage) storeCommands(config *ProxyConfig) ([][]string, error) {
+	clearSaltedTokenValues(map[string]*ProxyConfig{config.ID: config})
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	cmds := [][]string{
+		{"SET", rs.configKey(config.ID), string(data)},
+		{"SADD", rs.idsKey(), config.ID},
+	}
+
+	if keys := indexedTokenKeys(config); len(keys) > 0 {
+		cmd := []string{"HSET", rs.tokensKey()}
+		for _, key := range keys {
+			cmd = append(cmd, key, config.ID)
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+// unindexCommands 移除配置令牌索引的命令（需在令牌列表变化之前生成）
+func (rs *RedisStorage) unindexCommands(config *ProxyConfig) [][]string {
+	var cmds [][]string
+	if keys := indexedTokenKeys(config); len(keys) > 0 {
+		cmds = append(cmds, append([]string{"HDEL", rs.tokensKey()}, keys...))
+	}
+	return cmds
+}
+
+// removeCommands 移除活跃配置及其令牌索引的命令
+func (rs *RedisStorage) removeCommands(config *ProxyConfig) [][]string {
+	return append(rs.unindexCommands(config),
+		[]string{"DEL", rs.configKey(config.ID)},
+		[]string{"SREM", rs.idsKey(), config.ID},
+	)
+}
+
+// ==================== 读取和事务 ====================
+
+// getConfig 读取活跃配置
+func (rs *RedisStorage) getConfig(id string) (*ProxyConfig, error) {
+	reply, err := rs.client.do("GET", rs.configKey(id))
+	if err != nil {
+		return nil, err
+	}
+	data, ok := redisString(reply)
+	if !ok {
+		return nil, ErrConfigNotFound
+	}
+	return decodeConfig(data)
+}
+
+// loadActive 读取全部活跃配置
+func (rs *RedisStorage) loadActive() (map[string]*ProxyConfig, error) {
+	reply, err := rs.client.do("SMEMBERS", rs.idsKey())
+	if err != nil {
+		return nil, err
+	}
+	ids := redisStrings(reply)
+	configs := make(map[string]*ProxyConfig, len(ids))
+	if len(ids) == 0 {
+		return configs, nil
+	}
+
+	args := []string{"MGET"}
+	for _, id := range ids {
+		args = append(args, rs.configKey(id))
+	}
+	reply, err = rs.client.do(args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range reply.([]interface{}) {
+		data, ok := redisString(item)
+		if !ok {
+			continue // 读取期间被其他实例删除
+		}
+		config, err := decodeConfig(data)
+		if err != nil {
+			return nil, err
+		}
+		configs[config.ID] = config
+	}
+	return configs, nil
+}
+
+// loadDeleted 读取回收站中的全部配置
+func (rs *RedisStorage) loadDeleted() (map[string]*ProxyConfig, error) {
+	reply, err := rs.client.do("HGETALL", rs.deletedKey())
+	if err != nil {
+		return nil, err
+	}
+	fields := redisStrings(reply)
+	configs := make(map[string]*ProxyConfig, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		config, err := decodeConfig(fields[i+1])
+		if err != nil {
+			return nil, err
+		}
+		configs[fields[i]] = config
+	}
+	return configs, nil
+}
+
+// snapshot 将全部配置加载到一个临时的内存存储中，供只读查询和导入计算使用
+func (rs *RedisStorage) snapshot() (*MemoryStorage, error) {
+	active, err := rs.loadActive()
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := rs.loadDeleted()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := NewMemoryStorageWithEviction(rs.maxEntries, rs.evictionMode)
+	snap.configs = active
+	snap.deleted = deleted
+	snap.rebuildTokenIndexLocked()
+	return snap, nil
+}
+
+// transaction 执行乐观事务：WATCH指定的键后由build读取数据并生成命令，再以MULTI/EXEC提交
+//
+// 被监视的键在提交前被修改时重新执行build；build返回错误或没有命令时不提交。keys为空时不监视。
+func (rs *RedisStorage) transaction(keys []string, build func(rc *redisConn) ([][]string, error)) error {
+	for attempt := 0; attempt < redisMaxTxRetries; attempt++ {
+		rc, err := rs.client.conn()
+		if err != nil {
+			return err
+		}
+		err = rs.tryTransaction(rc, keys, build)
+		rs.client.release(rc)
+		if err != errRedisTxConflict {
+			return err
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", redisMaxTxRetries, errRedisTxConflict)
+}
+
+// tryTransaction 在独占连接上执行一次事务
+func (rs *RedisStorage) tryTransaction(rc *redisConn, keys []string, build func(rc *redisConn) ([][]string, error)) error {
+	if len(keys) > 0 {
+		if _, err := rc.do(append([]string{"WATCH"}, keys...)...); err != nil {
+			return err
+		}
+	}
+
+	cmds, err := build(rc)
+	if err != nil || len(cmds) == 0 {
+		if len(keys) > 0 {
+			if _, unwatchErr := rc.do("UNWATCH"); unwatchErr != nil {
+				rc.broken = true
+			}
+		}
+		return err
+	}
+
+	// 事务中途出错时连接状态不确定，不再复用
+	if _, err := rc.do("MULTI"); err != nil {
+		rc.broken = true
+		return err
+	}
+	for _, cmd := range cmds {
+		if _, err := rc.do(cmd...); err != nil {
+			rc.broken = true
+			return err
+		}
+	}
+	reply, err := rc.do("EXEC")
+	if err != nil {
+		rc.broken = true
+		return err
+	}
+	if reply == nil {
+		return errRedisTxConflict
+	}
+	for _, item := range reply.([]interface{}) {
+		if replyErr, ok := item.(redisError); ok {
+			return replyErr
+		}
+	}
+	return nil
+}
+
+// exec 以MULTI/EXEC原子提交命令（不监视任何键）
+func (rs *RedisStorage) exec(cmds [][]string) error {
+	return rs.transaction(nil, func(*redisConn) ([][]string, error) {
+		return cmds, nil
+	})
+}
+
+// updateConfig 在乐观事务中读取活跃配置、交给modify修改后写回，同步令牌索引
+func (rs *RedisStorage) updateConfig(id string, modify func(config *ProxyConfig) error) error {
+	key := rs.configKey(id)
+	return rs.transaction([]string{key}, func(rc *redisConn) ([][]string, error) {
+		reply, err := rc.do("GET", key)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := redisString(reply)
+		if !ok {
+			return nil, ErrConfigNotFound
+		}
+		config, err := decodeConfig(data)
+		if err != nil {
+			return nil, err
+		}
+
+		cmds := rs.unindexCommands(config)
+		if err := modify(config); err != nil {
+			return nil, err
+		}
+		store, err := rs.storeCommands(config)
+		if err != nil {
+			return nil, err
+		}
+		return append(cmds, store...), nil
+	})
+}
+
+// capacityCommands 检查是否有空间容纳新配置，返回LRU模式下淘汰最久未访问配置的命令
+//
+// 需在监视configs集合的事务中调用：新增配置会修改该集合，并发新增时只有一个事务能提交。
+func (rs *RedisStorage) capacityCommands(rc *redisConn) ([][]string, error) {
+	reply, err := rc.do("SCARD", rs.idsKey())
+	if err != nil {
+		return nil, err
+	}
+	if count, _ := reply.(int64); count < int64(rs.maxEntries) {
+		return nil, nil
+	}
+
+	configs, err := rs.loadActive()
+	if err != nil {
+		return nil, err
+	}
+	var cmds [][]string
+	for rs.evictionMode == EvictionLRU && len(configs) > 0 && len(configs) >= rs.maxEntries {
+		var oldest *ProxyConfig
+		for _, config := range configs {
+			if oldest == nil || lastAccessTime(config).Before(lastAccessTime(oldest)) {
+				oldest = config
+			}
+		}
+		cmds = append(cmds, rs.removeCommands(oldest)...)
+		delete(configs, oldest.ID)
+	}
+
+	if len(configs) >= rs.maxEntries {
+		return nil, fmt.Errorf("maximum entries (%d) exceeded", rs.maxEntries)
+	}
+	return cmds, nil
+}
+
+// ==================== 配置管理 ====================
+
+// Add 添加配置
+func (rs *RedisStorage) Add(config *ProxyConfig) error {
+	// 生成ID和时间戳
+	config.ID = uuid.New().String()
+	config.Version = 1
+	config.CreatedAt = time.Now()
+	config.UpdatedAt = time.Now()
+
+	// 初始化令牌相关字段
+	if config.AccessTokens == nil {
+		config.AccessTokens = make([]AccessToken, 0)
+	}
+	if config.TokenStats == nil {
+		config.TokenStats = &TokenStats{}
+	}
+
+	store, err := rs.storeCommands(config)
+	if err != nil {
+		return err
+	}
+	return rs.transaction([]string{rs.idsKey()}, func(rc *redisConn) ([][]string, error) {
+		cmds, err := rs.capacityCommands(rc)
+		if err != nil {
+			return nil, err
+		}
+		return append(cmds, store...), nil
+	})
+}
+
+// Update 更新配置（版本号规则与MemoryStorage.Update相同）
+func (rs *RedisStorage) Update(id string, config *ProxyConfig) error {
+	return rs.updateConfig(id, func(existing *ProxyConfig) error {
+		if err := applyConfigUpdate(existing, config); err != nil {
+			return err
+		}
+		*existing = *config
+		return nil
+	})
+}
+
+// Delete 删除配置（软删除：移入回收站，令牌和统计随配置保留，可通过Restore恢复）
+func (rs *RedisStorage) Delete(id string) error {
+	key := rs.configKey(id)
+	return rs.transaction([]string{key}, func(rc *redisConn) ([][]string, error) {
+		reply, err := rc.do("GET", key)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := redisString(reply)
+		if !ok {
+			return nil, ErrConfigNotFound
+		}
+		config, err := decodeConfig(data)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		config.DeletedAt = &now
+		deleted, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal config: %w", err)
+		}
+		return append(rs.removeCommands(config), []string{"HSET", rs.deletedKey(), id, string(deleted)}), nil
+	})
+}
+
+// GetByID 根据ID获取配置
+func (rs *RedisStorage) GetByID(id string) (*ProxyConfig, error) {
+	return rs.getConfig(id)
+}
+
+// List 获取配置列表
+func (rs *RedisStorage) List(filter *ConfigFilter) (*ConfigResponse, error) {
+	snap, err := rs.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return snap.List(filter)
+}
+
+// Search 按关键字搜索配置和令牌，Redis不可用时返回空结果
+func (rs *RedisStorage) Search(query string) *SearchResult {
+	snap, err := rs.snapshot()
+	if err != nil {
+		rs.logger.Error("failed to load configs from redis", "error", err, "operation", "search")
+		return &SearchResult{Query: query, Results: []ConfigSearchMatch{}}
+	}
+	return snap.Search(query)
+}
+
+// Clear 清空所有配置、回收站和令牌索引
+func (rs *RedisStorage) Clear() {
+	reply, err := rs.client.do("SMEMBERS", rs.idsKey())
+	if err == nil {
+		keys := []string{"DEL", rs.idsKey(), rs.deletedKey(), rs.tokensKey()}
+		for _, id := range redisStrings(reply) {
+			keys = append(keys, rs.configKey(id))
+		}
+		err = rs.exec([][]string{keys})
+	}
+	if err != nil {
+		rs.logger.Error("failed to clear configs in redis", "error", err)
+	}
+}
+
+// GetStats 获取统计信息，Redis不可用时返回零值
+func (rs *RedisStorage) GetStats() *StorageStats {
+	snap, err := rs.snapshot()
+	if err != nil {
+		rs.logger.Error("failed to load configs from redis", "error", err, "operation", "stats")
+		return &StorageStats{}
+	}
+	return snap.GetStats()
+}
+
+// BatchOperation 批量操作，每个配置单独提交
+func (rs *RedisStorage) BatchOperation(operation string, configIDs []string) (*BatchOperationResult, error) {
+	result := &BatchOperationResult{
+		Success:    make([]string, 0),
+		Failed:     make([]string, 0),
+		TotalCount: len(configIDs),
+	}
+
+	for _, configID := range configIDs {
+		var err error
+		switch operation {
+		case "enable", "disable":
+			enabled := operation == "enable"
+			err = rs.updateConfig(configID, func(config *ProxyConfig) error {
+				config.Enabled = enabled
+				config.Version++
+				config.UpdatedAt = time.Now()
+				return nil
+			})
+		case "delete":
+			err = rs.Delete(configID)
+		default:
+			err = fmt.Errorf("unsupported batch operation: %s", operation)
+		}
+
+		if err != nil {
+			result.Failed = append(result.Failed, configID)
+		} else {
+			result.Success = append(result.Success, configID)
+		}
+	}
+
+	result.FailedCount = len(result.Failed)
+	return result, nil
+}
+
+// ==================== 导入导出 ====================
+
+// ExportAll 导出所有配置
+func (rs *RedisStorage) ExportAll() (*ExportData, error) {
+	snap, err := rs.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return snap.ExportAll()
+}
+
+// ImportConfigs 导入配置
+func (rs *RedisStorage) ImportConfigs(configs []ProxyConfig, mode string) (*ImportResult, error) {
+	return rs.ImportConfigsWithOptions(configs, ImportOptions{Mode: mode})
+}
+
+// ImportConfigsWithOptions 按导入选项导入配置
+//
+// 在快照上执行与MemoryStorage相同的导入逻辑，再将新建、覆盖和被LRU淘汰的配置一次性提交。
+func (rs *RedisStorage) ImportConfigsWithOptions(configs []ProxyConfig, options ImportOptions) (*ImportResult, error) {
+	snap, err := rs.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	before := make(map[string]*ProxyConfig, len(snap.configs))
+	for id, config := range snap.configs {
+		before[id] = config
+	}
+
+	result, err := snap.ImportConfigsWithOptions(configs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmds [][]string
+	for id, config := range snap.configs {
+		existing, exists := before[id]
+		if exists && existing == config {
+			continue // 未修改
+		}
+		if exists {
+			cmds = append(cmds, rs.unindexCommands(existing)...)
+		}
+		store, err := rs.storeCommands(config)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, store...)
+	}
+	for id, existing := range before {
+		if _, kept := snap.configs[id]; !kept {
+			cmds = append(cmds, rs.removeCommands(existing)...)
+		}
+	}
+
+	if err := rs.exec(cmds); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DiffImport 计算导入配置与当前配置的差异，Redis不可用时在Errors中报告
+func (rs *RedisStorage) DiffImport(configs []ProxyConfig, prune bool) *ImportDiff {
+	snap, err := rs.snapshot()
+	if err != nil {
+		rs.logger.Error("failed to load configs from redis", "error", err, "operation", "diff_import")
+		return &ImportDiff{Adds: []ConfigDiff{}, Updates: []ConfigDiff{}, Deletes: []ConfigDiff{}, Errors: []string{err.Error()}}
+	}
+	return snap.DiffImport(configs, prune)
+}
+
+// ==================== 统计 ====================
+
+// UpdateStats 更新配置统计信息
+func (rs *RedisStorage) UpdateStats(configID string, responseTime time.Duration, success bool, bytes int64) error {
+	return rs.updateConfig(configID, func(config *ProxyConfig) error {
+		recordRequestStats(config, responseTime, success, bytes)
+		return nil
+	})
+}
+
+// GetConfigStats 获取配置统计信息
+func (rs *RedisStorage) GetConfigStats(configID string) (*ConfigStats, error) {
+	config, err := rs.getConfig(configID)
+	if err != nil {
+		return nil, err
+	}
+	if config.Stats == nil {
+		return &ConfigStats{}, nil
+	}
+	return config.Stats, nil
+}
+
+// ResetConfigStats 将配置统计信息清零，配置本身及其令牌保持不变
+func (rs *RedisStorage) ResetConfigStats(configID string) error {
+	return rs.updateConfig(configID, func(config *ProxyConfig) error {
+		config.Stats = &ConfigStats{}
+		return nil
+	})
+}
+
+// ==================== 令牌管理 ====================
+
+// AddToken 添加令牌到指定配置
+func (rs *RedisStorage) AddToken(configID string, token *AccessToken) error {
+	return rs.updateConfig(configID, func(config *ProxyConfig) error {
+		return appendToken(config, token)
+	})
+}
+
+// UpdateToken 更新指定令牌
+func (rs *RedisStorage) UpdateToken(configID, tokenID string, token *AccessToken) error {
+	return rs.updateConfig(configID, func(config *ProxyConfig) error {
+		return replaceToken(config, tokenID, token)
+	})
+}
+
+// DeleteToken 删除指定令牌
+func (rs *RedisStorage) DeleteToken(configID, tokenID string) error {
+	return rs.updateConfig(configID, func(config *ProxyConfig) error {
+		return removeToken(config, tokenID)
+	})
+}
+
+// GetTokens 获取指定配置的所有令牌
+func (rs *RedisStorage) GetTokens(configID string) ([]AccessToken, error) {
+	config, err := rs.getConfig(configID)
+	if err != nil {
+		return nil, err
+	}
+	if config.AccessTokens == nil {
+		return []AccessToken{}, nil
+	}
+	return config.AccessTokens, nil
+}
+
+// GetTokenByID 根据ID获取指定令牌
+func (rs *RedisStorage) GetTokenByID(configID, tokenID string) (*AccessToken, error) {
+	config, err := rs.getConfig(configID)
+	if err != nil {
+		return nil, err
+	}
+	tokenIndex := findTokenByID(config, tokenID)
+	if tokenIndex == -1 {
+		return nil, ErrTokenNotFound
+	}
+	return &config.AccessTokens[tokenIndex], nil
+}
+
+// ValidateToken 验证令牌并返回验证结果
+func (rs *RedisStorage) ValidateToken(configID, tokenValue string) (*TokenValidationResult, error) {
+	config, err := rs.getConfig(configID)
+	if err == ErrConfigNotFound {
+		return &TokenValidationResult{
+			Valid:     false,
+			ErrorCode: "CONFIG_NOT_FOUND",
+			ErrorMsg:  "configuration not found",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tokenIndex := matchTokenInConfig(config, tokenValue)
+	if tokenIndex == -1 {
+		return &TokenValidationResult{
+			Valid:     false,
+			ErrorCode: "TOKEN_NOT_FOUND",
+			ErrorMsg:  "token not found",
+		}, nil
+	}
+
+	return tokenValidationResult(configID, config.AccessTokens[tokenIndex]), nil
+}
+
+// FindConfigByToken 通过令牌值查找对应的配置ID
+//
+// 通过tokens哈希按SHA-256哈希和查找标识定位候选配置，只在候选配置内验证令牌；
+// 索引项指向的配置不存在或令牌已变化时视为未找到。
+func (rs *RedisStorage) FindConfigByToken(tokenValue string) (string, error) {
+	tokenHash := HashToken(tokenValue)
+	reply, err := rs.client.do("HMGET", rs.tokensKey(), tokenHash, tokenHash[:lookupIDLength])
+	if err != nil {
+		return "", err
+	}
+
+	var matched *AccessToken
+	matchedConfigID := ""
+	candidates := redisStrings(reply)
+	for i, configID := range candidates {
+		if configID == "" || (i > 0 && configID == candidates[0]) {
+			continue
+		}
+		config, err := rs.getConfig(configID)
+		if err == ErrConfigNotFound {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if tokenIndex := matchTokenInConfig(config, tokenValue); tokenIndex != -1 && matched == nil {
+			matched, matchedConfigID = &config.AccessTokens[tokenIndex], configID
+		}
+	}
+
+	// 跳过无效令牌（禁用或过期）
+	if matched == nil || ValidateTokenAccess(matched) != nil {
+		return "", ErrTokenNotFound
+	}
+	return matchedConfigID, nil
+}
+
+// UpdateTokenUsage 更新令牌使用统计
+//
+// 检查使用次数上限和计数在同一个事务内完成，多个实例的并发请求不会超出MaxUsageCount；
+// 已达上限时返回ErrTokenQuotaExceeded且不计数。
+func (rs *RedisStorage) UpdateTokenUsage(configID, tokenValue string) error {
+	return rs.updateConfig(configID, func(config *ProxyConfig) error {
+		tokenIndex := matchTokenInConfig(config, tokenValue)
+		if tokenIndex == -1 {
+			return ErrTokenNotFound
+		}
+		return consumeTokenUsage(config, tokenIndex)
+	})
+}
+
+// GetTokenStats 获取令牌统计信息
+func (rs *RedisStorage) GetTokenStats(configID string) (*TokenStats, error) {
+	config, err := rs.getConfig(configID)
+	if err != nil {
+		return nil, err
+	}
+	if config.TokenStats == nil {
+		return &TokenStats{}, nil
+	}
+	return config.TokenStats, nil
+}
+
+// ResetTokenStats 将指定令牌的使用统计清零，并重新计算令牌统计
+func (rs *RedisStorage) ResetTokenStats(configID, tokenID string) error {
+	return rs.updateConfig(configID, func(config *ProxyConfig) error {
+		return resetTokenUsage(config, tokenID)
+	})
+}
+
+// ==================== 回收站 ====================
+
+// ListDeleted 获取回收站中的配置，按删除时间从新到旧排序
+func (rs *RedisStorage) ListDeleted() ([]ProxyConfig, error) {
+	snap, err := rs.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return snap.ListDeleted()
+}
+
+// Restore 从回收站恢复配置，令牌和统计信息保持删除前的状态
+func (rs *RedisStorage) Restore(id string) error {
+	return rs.transaction([]string{rs.deletedKey(), rs.idsKey()}, func(rc *redisConn) ([][]string, error) {
+		reply, err := rc.do("HGET", rs.deletedKey(), id)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := redisString(reply)
+		if !ok {
+			return nil, ErrConfigNotFound
+		}
+		config, err := decodeConfig(data)
+		if err != nil {
+			return nil, err
+		}
+
+		// 恢复同样受最大配置数限制
+		cmds, err := rs.capacityCommands(rc)
+		if err != nil {
+			return nil, err
+		}
+
+		config.DeletedAt = nil
+		config.Version++
+		config.UpdatedAt = time.Now()
+		store, err := rs.storeCommands(config)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, []string{"HDEL", rs.deletedKey(), id})
+		return append(cmds, store...), nil
+	})
+}
+
+// PurgeDeleted 彻底清除删除时间早于deletedBefore的配置，返回清除数量
+func (rs *RedisStorage) PurgeDeleted(deletedBefore time.Time) int {
+	deleted, err := rs.loadDeleted()
+	if err != nil {
+		rs.logger.Error("failed to load deleted configs from redis", "error", err)
+		return 0
+	}
+
+	cmd := []string{"HDEL", rs.deletedKey()}
+	for id, config := range deleted {
+		if config.DeletedAt != nil && config.DeletedAt.Before(deletedBefore) {
+			cmd = append(cmd, id)
+		}
+	}
+	if len(cmd) == 2 {
+		return 0
+	}
+
+	if _, err := rs.client.do(cmd...); err != nil {
+		rs.logger.Error("failed to purge deleted configs in redis", "error", err)
+		return 0
+	}
+	return len(cmd) - 2
+}
@@ -0,0 +1,282 @@
+package proxyconfig
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redis连接默认参数
+const (
+	redisDefaultPort    = "6379"
+	redisDialTimeout    = 5 * time.Second
+	redisCommandTimeout = 5 * time.Second
+	redisMaxIdleConns   = 16
+)
+
+// redisError Redis服务端返回的错误回复（连接本身仍然可用）
+type redisError string
+
+// Error 实现 error 接口
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisOptions 从连接URL解析出的连接参数
+type redisOptions struct {
+	addr      string
+	username  string
+	password  string
+	db        int
+	tlsConfig *tls.Config // rediss://时非空
+}
+
+// parseRedisURL 解析redis://[用户名:密码@]主机[:端口][/库号]，rediss://使用TLS
+func parseRedisURL(rawURL string) (*redisOptions, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("unsupported redis URL scheme: %s", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("redis URL requires a host")
+	}
+
+	opts := &redisOptions{addr: u.Host}
+	if u.Port() == "" {
+		opts.addr = net.JoinHostPort(u.Hostname(), redisDefaultPort)
+	}
+	if u.User != nil {
+		opts.username = u.User.Username()
+		opts.password, _ = u.User.Password()
+		// redis://:密码@主机 只设置密码（Redis 6之前没有用户名）
+		if _, hasPassword := u.User.Password(); !hasPassword {
+			opts.password, opts.username = opts.username, ""
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid redis database: %s", db)
+		}
+		opts.db = n
+	}
+	if u.Scheme == "rediss" {
+		opts.tlsConfig = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+	return opts, nil
+}
+
+// redisClient 最小的Redis客户端（RESP2协议），只实现存储需要的命令
+//
+// 连接按需建立并放回空闲池复用；WATCH/MULTI事务需要独占连接，通过conn获取。
+type redisClient struct {
+	opts *redisOptions
+	idle chan *redisConn
+}
+
+// redisConn 单个Redis连接
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	broken bool // 发生网络或协议错误，不能再放回连接池
+}
+
+// newRedisClient 根据连接URL创建客户端（不立即建立连接）
+func newRedisClient(rawURL string) (*redisClient, error) {
+	opts, err := parseRedisURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &redisClient{opts: opts, idle: make(chan *redisConn, redisMaxIdleConns)}, nil
+}
+
+// conn 从连接池取出连接，池为空时建立新连接并完成认证和选库
+func (c *redisClient) conn() (*redisConn, error) {
+	select {
+	case rc := <-c.idle:
+		return rc, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var conn net.Conn
+	var err error
+	if c.opts.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.opts.addr, c.opts.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", c.opts.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if c.opts.password != "" {
+		args := []string{"AUTH", c.opts.password}
+		if c.opts.username != "" {
+			args = []string{"AUTH", c.opts.username, c.opts.password}
+		}
+		if _, err := rc.do(args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.opts.db != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(c.opts.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// release 将连接放回连接池；连接已损坏或池已满时关闭
+func (c *redisClient) release(rc *redisConn) {
+	if rc.broken {
+		rc.conn.Close()
+		return
+	}
+	select {
+	case c.idle <- rc:
+	default:
+		rc.conn.Close()
+	}
+}
+
+// do 使用连接池中的连接执行单条命令
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	rc, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release(rc)
+	return rc.do(args...)
+}
+
+// Close 关闭连接池中的全部空闲连接
+func (c *redisClient) Close() error {
+	for {
+		select {
+		case rc := <-c.idle:
+			rc.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do 发送一条命令并读取回复
+//
+// 回复类型：简单字符串和批量字符串为string，整数为int64，数组为[]interface{}，
+// 空批量字符串和空数组为nil，错误回复为redisError。
+func (rc *redisConn) do(args ...string) (interface{}, error) {
+	rc.conn.SetDeadline(time.Now().Add(redisCommandTimeout))
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(rc.conn, buf.String()); err != nil {
+		rc.broken = true
+		return nil, fmt.Errorf("failed to send redis command: %w", err)
+	}
+
+	reply, err := rc.readReply()
+	if err != nil {
+		var replyErr redisError
+		if !errors.As(err, &replyErr) {
+			rc.broken = true
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// readReply 读取一个RESP回复
+func (rc *redisConn) readReply() (interface{}, error) {
+	line, err := rc.reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis reply: %w", err)
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed redis reply: %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis integer: %q", payload)
+		}
+		return n, nil
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis bulk length: %q", payload)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(rc.reader, data); err != nil {
+			return nil, fmt.Errorf("failed to read redis reply: %w", err)
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis array length: %q", payload)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			// 数组中的错误回复（如EXEC中失败的命令）作为元素返回，不中断读取
+			item, err := rc.readReply()
+			var replyErr redisError
+			if errors.As(err, &replyErr) {
+				item, err = replyErr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply type: %q", kind)
+	}
+}
+
+// redisString 将回复转换为字符串，空回复返回false
+func redisString(reply interface{}) (string, bool) {
+	s, ok := reply.(string)
+	return s, ok
+}
+
+// redisStrings 将数组回复转换为字符串列表，空元素为空字符串
+func redisStrings(reply interface{}) []string {
+	items, _ := reply.([]interface{})
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i], _ = item.(string)
+	}
+	return values
+}
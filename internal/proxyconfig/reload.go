@@ -0,0 +1,205 @@
+package proxyconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// ReloadResult 配置热加载结果（按配置名称列出变化）
+type ReloadResult struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Changed   []string `json:"changed"`
+	Unchanged int      `json:"unchanged"`
+	Invalid   []string `json:"invalid"` // 校验失败而被跳过的配置
+}
+
+// Reload 从磁盘重新加载配置并原子替换内存中的配置表
+//
+// 加载过程与启动时一致：旧版本数据先迁移，再逐个校验，校验失败的配置被跳过。
+// 替换在写锁内一次完成，正在处理的请求持有的是配置副本，不受影响。
+// 已存在配置的运行时统计和令牌使用情况保留内存中的值，因为它们比文件中的更新。
+// 文件不存在或无法解析时返回错误，内存中的配置保持不变。
+// 通过API所做的修改还在保存合并窗口内（或保存失败）未写入文件时返回ErrReloadPending，
+// 避免用文件内容覆盖这些修改；修改写入后再次热加载即可。
+func (ps *PersistentStorage) Reload() (*ReloadResult, error) {
+	if ps.hasPendingEdits() {
+		return nil, ErrReloadPending
+	}
+
+	modTime := ps.currentFileModTime()
+	data, err := os.ReadFile(ps.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var loaded map[string]*ProxyConfig
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config file: %w", err)
+	}
+
+	// 按全部配置判断版本：只要有一个配置带有令牌字段就视为当前版本，
+	// 手工新增的配置可能省略这些字段，不能只看其中一个
+	if len(loaded) > 0 && !isCurrentVersion(loaded) {
+		migration, migrated, err := MigrateConfigData(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate config file: %w", err)
+		}
+		ps.logger.Info("config file migrated during reload", "from", migration.FromVersion, "to", migration.ToVersion, "count", migration.MigratedCount)
+
+		loaded = nil
+		if err := json.Unmarshal(migrated, &loaded); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal migrated config file: %w", err)
+		}
+	}
+
+	cleared := clearSaltedTokenValues(loaded)
+
+	result := &ReloadResult{
+		Added:   make([]string, 0),
+		Removed: make([]string, 0),
+		Changed: make([]string, 0),
+		Invalid: make([]string, 0),
+	}
+
+	loaded, deleted := splitDeleted(loaded)
+	configs := make(map[string]*ProxyConfig, len(loaded))
+	for id, config := range loaded {
+		if config == nil {
+			continue
+		}
+		if config.ID == "" {
+			config.ID = id
+		}
+		if config.AccessTokens == nil {
+			config.AccessTokens = make([]AccessToken, 0)
+		}
+		if config.TokenStats == nil {
+			config.TokenStats = &TokenStats{}
+		}
+		if err := ValidateConfig(config); err != nil {
+			result.Invalid = append(result.Invalid, fmt.Sprintf("%s: %v", config.Name, err))
+			continue
+		}
+		configs[config.ID] = config
+	}
+	if len(configs) > ps.maxEntries {
+		return nil, fmt.Errorf("config file contains %d configs, exceeding the limit of %d", len(configs), ps.maxEntries)
+	}
+
+	ps.mutex.Lock()
+	// 读取文件期间可能有新的修改
+	if ps.hasPendingEdits() {
+		ps.mutex.Unlock()
+		return nil, ErrReloadPending
+	}
+	for id, config := range configs {
+		existing, ok := ps.configs[id]
+		if !ok {
+			result.Added = append(result.Added, config.Name)
+			continue
+		}
+		if existing.Stats != nil {
+			config.Stats = existing.Stats
+		}
+		carryOverTokenUsage(existing, config)
+		if configChanged(existing, config) {
+			// 文件被手工修改时版本号可能未变，递增以使之前读取的版本失效
+			if config.Version <= existing.Version {
+				config.Version = existing.Version + 1
+			}
+			result.Changed = append(result.Changed, config.Name)
+		} else {
+			config.Version = existing.Version
+			result.Unchanged++
+		}
+	}
+	for id, existing := range ps.configs {
+		if _, ok := configs[id]; !ok {
+			result.Removed = append(result.Removed, existing.Name)
+		}
+	}
+
+	for _, config := range configs {
+		ps.updateTokenStatsLocked(config)
+	}
+
+	ps.configs = configs
+	ps.deleted = deleted
+	ps.rebuildTokenIndexLocked()
+	ps.mutex.Unlock()
+
+	// 文件内容已加载，之后的自动保存不再视为覆盖外部修改
+	ps.saveMutex.Lock()
+	ps.fileModTime = modTime
+	ps.saveMutex.Unlock()
+
+	for _, config := range configs {
+		WarnInsecureTLS(ps.logger, config)
+	}
+
+	if cleared > 0 {
+		ps.logger.Info("cleared stored values of salted tokens", "count", cleared)
+		ps.markDirty()
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Changed)
+	return result, nil
+}
+
+// carryOverTokenUsage 将内存中较新的令牌使用情况复制到重新加载的配置
+func carryOverTokenUsage(existing, reloaded *ProxyConfig) {
+	usage := make(map[string]*AccessToken, len(existing.AccessTokens))
+	for i := range existing.AccessTokens {
+		usage[existing.AccessTokens[i].ID] = &existing.AccessTokens[i]
+	}
+	for i := range reloaded.AccessTokens {
+		token := &reloaded.AccessTokens[i]
+		if current, ok := usage[token.ID]; ok && current.UsageCount > token.UsageCount {
+			token.UsageCount = current.UsageCount
+			token.LastUsed = current.LastUsed
+			token.UsageHistory = current.UsageHistory
+		}
+	}
+}
+
+// configChanged 比较两个配置是否有实质变化（忽略运行时统计、令牌使用情况和更新时间）
+//
+// 通过JSON序列化比较，避免内存中时间值的单调时钟和时区信息造成误判。
+func configChanged(a, b *ProxyConfig) bool {
+	left, right := comparableConfig(*a), comparableConfig(*b)
+	leftData, err := json.Marshal(left)
+	if err != nil {
+		return true
+	}
+	rightData, err := json.Marshal(right)
+	if err != nil {
+		return true
+	}
+	return !bytes.Equal(leftData, rightData)
+}
+
+// comparableConfig 返回去掉运行时数据的配置副本，用于变化比较
+func comparableConfig(config ProxyConfig) ProxyConfig {
+	config.Stats = nil
+	config.TokenStats = nil
+	config.Version = 0
+	config.UpdatedAt = time.Time{}
+
+	tokens := make([]AccessToken, len(config.AccessTokens))
+	for i, token := range config.AccessTokens {
+		token.LastUsed = nil
+		token.UsageCount = 0
+		token.UsageHistory = nil
+		token.UpdatedAt = time.Time{}
+		tokens[i] = token
+	}
+	config.AccessTokens = tokens
+	return config
+}
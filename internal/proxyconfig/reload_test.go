@@ -0,0 +1,150 @@
+package proxyconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"privacygateway/internal/logger"
+)
+
+func TestPersistentStorage_Reload(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New()
+	log.SetOutput(&buf)
+
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, log)
+	defer storage.Shutdown()
+
+	api := &ProxyConfig{Name: "api", TargetURL: "https://api.example.com", Protocol: "https", Enabled: true}
+	old := &ProxyConfig{Name: "old", TargetURL: "https://old.example.com", Protocol: "https", Enabled: true}
+	same := &ProxyConfig{Name: "same", TargetURL: "https://same.example.com", Protocol: "https", Enabled: true}
+	for _, config := range []*ProxyConfig{api, old, same} {
+		if err := storage.Add(config); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	token, tokenValue, _ := CreateAccessToken(&TokenCreateRequest{Name: "client"}, "admin")
+	storage.AddToken(api.ID, token)
+	storage.SaveToFile()
+
+	// 保存后产生的令牌使用情况只存在于内存中
+	storage.UpdateTokenUsage(api.ID, tokenValue)
+
+	// 外部修改文件：修改api、删除old、新增new、写入一个无效配置
+	data, _ := os.ReadFile(filePath)
+	var configs map[string]*ProxyConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	configs[api.ID].TargetURL = "https://api-v2.example.com"
+	delete(configs, old.ID)
+	configs["new-id"] = &ProxyConfig{ID: "new-id", Name: "new", TargetURL: "https://new.example.com", Protocol: "https", Enabled: true}
+	configs["bad-id"] = &ProxyConfig{ID: "bad-id", Name: "bad", TargetURL: "not a url", Protocol: "https"}
+	data, _ = json.Marshal(configs)
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	result, err := storage.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if len(result.Added) != 1 || result.Added[0] != "new" {
+		t.Errorf("Expected [new] added, got %v", result.Added)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != "old" {
+		t.Errorf("Expected [old] removed, got %v", result.Removed)
+	}
+	if len(result.Changed) != 1 || result.Changed[0] != "api" {
+		t.Errorf("Expected [api] changed, got %v", result.Changed)
+	}
+	if result.Unchanged != 1 {
+		t.Errorf("Expected 1 unchanged config, got %d", result.Unchanged)
+	}
+	if len(result.Invalid) != 1 {
+		t.Errorf("Expected 1 invalid config, got %v", result.Invalid)
+	}
+
+	// 同一个存储实例立即反映文件内容
+	reloaded, err := storage.GetByID(api.ID)
+	if err != nil || reloaded.TargetURL != "https://api-v2.example.com" {
+		t.Errorf("Expected reloaded target URL, got %+v, %v", reloaded, err)
+	}
+	if _, err := storage.GetByID(old.ID); err != ErrConfigNotFound {
+		t.Errorf("Expected removed config to be gone, got %v", err)
+	}
+	if _, err := storage.GetByID("new-id"); err != nil {
+		t.Errorf("Expected new config to be loaded, got %v", err)
+	}
+
+	// 令牌索引已重建，内存中较新的使用次数被保留
+	validation, _ := storage.ValidateToken(api.ID, tokenValue)
+	if validation == nil || !validation.Valid {
+		t.Fatalf("Expected token to remain valid after reload, got %+v", validation)
+	}
+	if validation.Token.UsageCount != 1 {
+		t.Errorf("Expected in-memory usage count to be kept, got %d", validation.Token.UsageCount)
+	}
+}
+
+func TestPersistentStorage_ReloadKeepsConfigsOnError(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New()
+	log.SetOutput(&buf)
+
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, log)
+	defer storage.Shutdown()
+	storage.Add(&ProxyConfig{Name: "api", TargetURL: "https://api.example.com", Protocol: "https", Enabled: true})
+
+	if err := os.WriteFile(filePath, []byte("{broken"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := storage.Reload(); err == nil {
+		t.Error("Expected error for malformed config file")
+	}
+	if stats := storage.GetStats(); stats.TotalConfigs != 1 {
+		t.Errorf("Expected existing configs to be kept, got %d", stats.TotalConfigs)
+	}
+}
+
+func TestPersistentStorage_ReloadRefusedWithPendingEdits(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	defer storage.Shutdown()
+
+	saved := newEvictionTestConfig("saved")
+	storage.Add(saved)
+
+	// 合并窗口内的API修改尚未写入文件
+	storage.SetSaveDelay(time.Hour)
+	pending := newEvictionTestConfig("pending")
+	if err := storage.Add(pending); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if _, err := storage.Reload(); err != ErrReloadPending {
+		t.Fatalf("Expected ErrReloadPending, got %v", err)
+	}
+	if _, err := storage.GetByID(pending.ID); err != nil {
+		t.Errorf("Expected pending config to survive the refused reload, got %v", err)
+	}
+
+	// 令牌使用和请求统计不阻止热加载
+	if err := storage.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	storage.UpdateStats(saved.ID, time.Millisecond, true, 1)
+	result, err := storage.Reload()
+	if err != nil {
+		t.Fatalf("Expected reload to succeed after changes are saved, got %v", err)
+	}
+	if result.Unchanged != 2 || len(result.Removed) != 0 {
+		t.Errorf("Expected both configs to be kept, got %+v", result)
+	}
+}
@@ -0,0 +1,92 @@
+package proxyconfig
+
+import (
+	"sort"
+	"strings"
+)
+
+// 搜索命中的字段
+const (
+	SearchFieldName        = "name"
+	SearchFieldTargetURL   = "target_url"
+	SearchFieldDescription = "description"
+)
+
+// TokenSearchMatch 搜索命中的令牌（已清理敏感信息）
+type TokenSearchMatch struct {
+	Token         AccessToken `json:"token"`
+	MatchedFields []string    `json:"matched_fields"`
+}
+
+// ConfigSearchMatch 搜索命中的配置，配置本身或其令牌命中均会返回
+type ConfigSearchMatch struct {
+	Config        ProxyConfig        `json:"config"`         // 不含令牌列表，命中的令牌见Tokens
+	MatchedFields []string           `json:"matched_fields"` // 配置自身命中的字段，仅令牌命中时为空
+	Tokens        []TokenSearchMatch `json:"tokens,omitempty"`
+}
+
+// SearchResult 跨配置和令牌的搜索结果
+type SearchResult struct {
+	Query   string              `json:"query"`
+	Results []ConfigSearchMatch `json:"results"`
+	Total   int                 `json:"total"`
+}
+
+// containsFold 不区分大小写的子串匹配，term需已转为小写
+func containsFold(value, term string) bool {
+	return strings.Contains(strings.ToLower(value), term)
+}
+
+// matchedConfigFields 返回配置命中的字段（配置名称、目标URL）
+func matchedConfigFields(config *ProxyConfig, term string) []string {
+	var fields []string
+	if containsFold(config.Name, term) {
+		fields = append(fields, SearchFieldName)
+	}
+	if containsFold(config.TargetURL, term) {
+		fields = append(fields, SearchFieldTargetURL)
+	}
+	return fields
+}
+
+// Search 按关键字搜索配置名称、目标URL以及令牌名称和描述，结果按创建时间倒序
+func (s *MemoryStorage) Search(query string) *SearchResult {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	term := strings.ToLower(strings.TrimSpace(query))
+	results := []ConfigSearchMatch{}
+	for _, config := range s.configs {
+		match := ConfigSearchMatch{MatchedFields: matchedConfigFields(config, term)}
+
+		for i := range config.AccessTokens {
+			token := &config.AccessTokens[i]
+			var fields []string
+			if containsFold(token.Name, term) {
+				fields = append(fields, SearchFieldName)
+			}
+			if containsFold(token.Description, term) {
+				fields = append(fields, SearchFieldDescription)
+			}
+			if len(fields) > 0 {
+				match.Tokens = append(match.Tokens, TokenSearchMatch{
+					Token:         SanitizeTokenForResponse(token),
+					MatchedFields: fields,
+				})
+			}
+		}
+
+		if len(match.MatchedFields) == 0 && len(match.Tokens) == 0 {
+			continue
+		}
+		match.Config = *config
+		match.Config.AccessTokens = nil
+		results = append(results, match)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Config.CreatedAt.After(results[j].Config.CreatedAt)
+	})
+
+	return &SearchResult{Query: query, Results: results, Total: len(results)}
+}
@@ -0,0 +1,182 @@
+package proxyconfig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 请求签名默认值
+const (
+	DefaultSignatureHeader   = "X-Signature"
+	DefaultTimestampHeader   = "X-Signature-Timestamp"
+	DefaultNonceHeader       = "X-Signature-Nonce"
+	DefaultSignatureMaxSkew  = 300  // 秒
+	MaxSignatureMaxSkew      = 3600 // 秒
+	minSigningSecretLength   = 16
+	maxSignatureNonceLength  = 128
+	MaxSignedBodySize        = 10 << 20 // 签名校验需缓存请求体，超过该大小（字节）时拒绝
+	signatureAlgorithmSHA256 = "sha256"
+	signatureAlgorithmSHA512 = "sha512"
+)
+
+// 签名校验失败的原因
+var (
+	ErrSignatureMissing   = errors.New("missing request signature")
+	ErrSignatureExpired   = errors.New("request timestamp outside the allowed window")
+	ErrSignatureMismatch  = errors.New("request signature mismatch")
+	ErrSignatureMalformed = errors.New("malformed signature headers")
+	ErrSignatureReplayed  = errors.New("request nonce has already been used")
+	ErrSignedBodyTooLarge = errors.New("request body exceeds the signed body size limit")
+)
+
+// RequestSigning 配置级HMAC请求签名要求
+//
+// 客户端对规范字符串计算HMAC，以十六进制写入签名头（可带"sha256="前缀）：
+//
+//	METHOD\nTARGET\nTIMESTAMP\nNONCE\nhex(sha256(BODY))
+//
+// TARGET为target参数的原始值，TIMESTAMP为Unix秒。时间戳超出窗口或nonce重复的请求被拒绝。
+type RequestSigning struct {
+	Secret          string `json:"secret"`                     // HMAC密钥
+	Algorithm       string `json:"algorithm,omitempty"`        // sha256（默认）或sha512
+	Header          string `json:"header,omitempty"`           // 签名头（默认X-Signature）
+	TimestampHeader string `json:"timestamp_header,omitempty"` // 时间戳头（默认X-Signature-Timestamp）
+	NonceHeader     string `json:"nonce_header,omitempty"`     // nonce头（默认X-Signature-Nonce）
+	MaxSkewSeconds  int    `json:"max_skew_seconds,omitempty"` // 允许的时间偏差（秒，默认300）
+}
+
+// SignatureHeader 返回签名头名称
+func (s *RequestSigning) SignatureHeader() string {
+	if s.Header == "" {
+		return DefaultSignatureHeader
+	}
+	return s.Header
+}
+
+// TimestampHeaderName 返回时间戳头名称
+func (s *RequestSigning) TimestampHeaderName() string {
+	if s.TimestampHeader == "" {
+		return DefaultTimestampHeader
+	}
+	return s.TimestampHeader
+}
+
+// NonceHeaderName 返回nonce头名称
+func (s *RequestSigning) NonceHeaderName() string {
+	if s.NonceHeader == "" {
+		return DefaultNonceHeader
+	}
+	return s.NonceHeader
+}
+
+// MaxSkew 返回允许的时间偏差，nonce至少需要保留这么久
+func (s *RequestSigning) MaxSkew() time.Duration {
+	if s.MaxSkewSeconds <= 0 {
+		return DefaultSignatureMaxSkew * time.Second
+	}
+	return time.Duration(s.MaxSkewSeconds) * time.Second
+}
+
+// algorithm 返回规范化的算法名称
+func (s *RequestSigning) algorithm() string {
+	if s.Algorithm == "" {
+		return signatureAlgorithmSHA256
+	}
+	return strings.ToLower(s.Algorithm)
+}
+
+// newHash 返回算法对应的哈希构造函数
+func (s *RequestSigning) newHash() func() hash.Hash {
+	if s.algorithm() == signatureAlgorithmSHA512 {
+		return sha512.New
+	}
+	return sha256.New
+}
+
+// CanonicalString 构造参与签名的规范字符串
+func CanonicalString(method, target, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{strings.ToUpper(method), target, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+}
+
+// Sign 计算规范字符串的HMAC签名（十六进制）
+func (s *RequestSigning) Sign(method, target, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(s.newHash(), []byte(s.Secret))
+	mac.Write([]byte(CanonicalString(method, target, timestamp, nonce, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验请求的签名和时间戳，成功时返回nonce（重放检查由调用方负责）
+func (s *RequestSigning) Verify(method, target string, header http.Header, body []byte, now time.Time) (string, error) {
+	signature := header.Get(s.SignatureHeader())
+	timestamp := header.Get(s.TimestampHeaderName())
+	nonce := header.Get(s.NonceHeaderName())
+	if signature == "" || timestamp == "" || nonce == "" {
+		return "", ErrSignatureMissing
+	}
+	if len(nonce) > maxSignatureNonceLength {
+		return "", ErrSignatureMalformed
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrSignatureMalformed
+	}
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > s.MaxSkew() {
+		return "", ErrSignatureExpired
+	}
+
+	signature = strings.TrimPrefix(strings.ToLower(signature), s.algorithm()+"=")
+	provided, err := hex.DecodeString(signature)
+	if err != nil {
+		return "", ErrSignatureMalformed
+	}
+	expected, _ := hex.DecodeString(s.Sign(method, target, timestamp, nonce, body))
+	if !hmac.Equal(provided, expected) {
+		return "", ErrSignatureMismatch
+	}
+	return nonce, nil
+}
+
+// ValidateRequestSigning 验证请求签名设置
+func ValidateRequestSigning(s *RequestSigning) error {
+	if s == nil {
+		return nil
+	}
+	if len(s.Secret) < minSigningSecretLength {
+		return fmt.Errorf("request_signing.secret must be at least %d characters", minSigningSecretLength)
+	}
+	if alg := s.algorithm(); alg != signatureAlgorithmSHA256 && alg != signatureAlgorithmSHA512 {
+		return errors.New("request_signing.algorithm must be sha256 or sha512")
+	}
+	if s.MaxSkewSeconds < 0 || s.MaxSkewSeconds > MaxSignatureMaxSkew {
+		return fmt.Errorf("request_signing.max_skew_seconds must be between 0 and %d", MaxSignatureMaxSkew)
+	}
+	for _, name := range []string{s.Header, s.TimestampHeader, s.NonceHeader} {
+		if strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("request_signing header name %q is invalid", name)
+		}
+	}
+	names := map[string]bool{}
+	for _, name := range []string{s.SignatureHeader(), s.TimestampHeaderName(), s.NonceHeaderName()} {
+		key := http.CanonicalHeaderKey(name)
+		if names[key] {
+			return errors.New("request_signing header names must be distinct")
+		}
+		names[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package proxyconfig
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRequestSigning_Verify(t *testing.T) {
+	signing := &RequestSigning{Secret: "0123456789abcdef-secret"}
+	now := time.Unix(1700000000, 0)
+	target := "https://api.example.com/orders?id=1"
+	body := []byte(`{"amount":100}`)
+
+	// signedHeader 按给定参数生成签名头
+	signedHeader := func(s *RequestSigning, timestamp time.Time, body []byte) http.Header {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		header := http.Header{}
+		header.Set(s.SignatureHeader(), s.Sign("POST", target, ts, "nonce-1", body))
+		header.Set(s.TimestampHeaderName(), ts)
+		header.Set(s.NonceHeaderName(), "nonce-1")
+		return header
+	}
+
+	tests := []struct {
+		name    string
+		signing *RequestSigning
+		header  http.Header
+		body    []byte
+		wantErr error
+	}{
+		{"valid", signing, signedHeader(signing, now, body), body, nil},
+		{"valid sha512", &RequestSigning{Secret: signing.Secret, Algorithm: "sha512", Header: "X-Hub-Signature"}, signedHeader(&RequestSigning{Secret: signing.Secret, Algorithm: "sha512", Header: "X-Hub-Signature"}, now, body), body, nil},
+		{"tampered body", signing, signedHeader(signing, now, body), []byte(`{"amount":999}`), ErrSignatureMismatch},
+		{"expired timestamp", signing, signedHeader(signing, now.Add(-10*time.Minute), body), body, ErrSignatureExpired},
+		{"future timestamp", signing, signedHeader(signing, now.Add(10*time.Minute), body), body, ErrSignatureExpired},
+		{"wrong secret", &RequestSigning{Secret: "another-secret-value!"}, signedHeader(signing, now, body), body, ErrSignatureMismatch},
+		{"missing headers", signing, http.Header{}, body, ErrSignatureMissing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.signing.Verify("POST", target, tt.header, tt.body, now)
+			if err != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// 签名可带算法前缀
+	header := signedHeader(signing, now, body)
+	header.Set(signing.SignatureHeader(), "sha256="+header.Get(signing.SignatureHeader()))
+	if _, err := signing.Verify("POST", target, header, body, now); err != nil {
+		t.Errorf("Expected prefixed signature to be accepted, got %v", err)
+	}
+}
+
+func TestValidateRequestSigning(t *testing.T) {
+	tests := []struct {
+		name    string
+		signing *RequestSigning
+		wantErr bool
+	}{
+		{"not configured", nil, false},
+		{"defaults", &RequestSigning{Secret: "0123456789abcdef"}, false},
+		{"short secret", &RequestSigning{Secret: "short"}, true},
+		{"unknown algorithm", &RequestSigning{Secret: "0123456789abcdef", Algorithm: "md5"}, true},
+		{"skew out of range", &RequestSigning{Secret: "0123456789abcdef", MaxSkewSeconds: 7200}, true},
+		{"duplicate headers", &RequestSigning{Secret: "0123456789abcdef", Header: "x-signature-nonce"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateRequestSigning(tt.signing); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package proxyconfig
 
 import (
-	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -18,6 +17,7 @@ type Storage interface {
 	Delete(id string) error
 	GetByID(id string) (*ProxyConfig, error)
 	List(filter *ConfigFilter) (*ConfigResponse, error)
+	Search(query string) *SearchResult
 	Clear()
 	GetStats() *StorageStats
 
@@ -27,10 +27,13 @@ type Storage interface {
 	// 导入导出
 	ExportAll() (*ExportData, error)
 	ImportConfigs(configs []ProxyConfig, mode string) (*ImportResult, error)
+	ImportConfigsWithOptions(configs []ProxyConfig, options ImportOptions) (*ImportResult, error)
+	DiffImport(configs []ProxyConfig, prune bool) *ImportDiff
 
 	// 统计功能
 	UpdateStats(configID string, responseTime time.Duration, success bool, bytes int64) error
 	GetConfigStats(configID string) (*ConfigStats, error)
+	ResetConfigStats(configID string) error
 
 	// 令牌管理
 	AddToken(configID string, token *AccessToken) error
@@ -41,36 +44,83 @@ type Storage interface {
 	ValidateToken(configID, tokenValue string) (*TokenValidationResult, error)
 	UpdateTokenUsage(configID, tokenValue string) error
 	GetTokenStats(configID string) (*TokenStats, error)
+	ResetTokenStats(configID, tokenID string) error
 	FindConfigByToken(tokenValue string) (string, error)
+
+	// 回收站
+	ListDeleted() ([]ProxyConfig, error)
+	Restore(id string) error
+	PurgeDeleted(deletedBefore time.Time) int
+}
+
+// EvictionMode 达到最大条目数时的处理策略
+type EvictionMode string
+
+// 淘汰策略常量
+const (
+	EvictionReject EvictionMode = "reject" // 拒绝新增（默认）
+	EvictionLRU    EvictionMode = "lru"    // 淘汰最久未访问的配置
+)
+
+// ParseEvictionMode 解析淘汰策略字符串，无法识别时返回错误
+func ParseEvictionMode(mode string) (EvictionMode, error) {
+	switch EvictionMode(strings.ToLower(strings.TrimSpace(mode))) {
+	case "", EvictionReject:
+		return EvictionReject, nil
+	case EvictionLRU:
+		return EvictionLRU, nil
+	default:
+		return "", fmt.Errorf("unsupported eviction mode: %s", mode)
+	}
 }
 
 // MemoryStorage 内存存储实现
 type MemoryStorage struct {
-	configs    map[string]*ProxyConfig
-	mutex      sync.RWMutex
-	maxEntries int
+	configs      map[string]*ProxyConfig
+	mutex        sync.RWMutex
+	maxEntries   int
+	evictionMode EvictionMode
+	tokens       *tokenIndex             // 令牌反向索引
+	deleted      map[string]*ProxyConfig // 回收站：软删除的配置，不参与列表、查找和代理
 }
 
-// NewMemoryStorage 创建内存存储实例
+// NewMemoryStorage 创建内存存储实例（达到上限时拒绝新增）
 func NewMemoryStorage(maxEntries int) *MemoryStorage {
+	return NewMemoryStorageWithEviction(maxEntries, EvictionReject)
+}
+
+// NewMemoryStorageWithEviction 创建指定淘汰策略的内存存储实例
+func NewMemoryStorageWithEviction(maxEntries int, evictionMode EvictionMode) *MemoryStorage {
+	if evictionMode == "" {
+		evictionMode = EvictionReject
+	}
 	return &MemoryStorage{
-		configs:    make(map[string]*ProxyConfig),
-		maxEntries: maxEntries,
+		configs:      make(map[string]*ProxyConfig),
+		maxEntries:   maxEntries,
+		evictionMode: evictionMode,
+		tokens:       newTokenIndex(),
+		deleted:      make(map[string]*ProxyConfig),
 	}
 }
 
+// EvictionMode 获取当前淘汰策略
+func (s *MemoryStorage) EvictionMode() EvictionMode {
+	return s.evictionMode
+}
+
 // Add 添加配置
 func (s *MemoryStorage) Add(config *ProxyConfig) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// 检查是否超过最大条目数
-	if len(s.configs) >= s.maxEntries {
-		return fmt.Errorf("maximum entries (%d) exceeded", s.maxEntries)
+	// 检查是否超过最大条目数（LRU模式下淘汰最久未访问的配置）
+	if err := s.ensureCapacityLocked(); err != nil {
+		return err
 	}
 
 	// 生成ID和时间戳
 	config.ID = uuid.New().String()
+	config.Version = 1
 	config.CreatedAt = time.Now()
 	config.UpdatedAt = time.Now()
 
@@ -84,11 +134,15 @@ func (s *MemoryStorage) Add(config *ProxyConfig) error {
 
 	// 存储配置
 	s.configs[config.ID] = config
+	s.tokens.add(config)
 
 	return nil
 }
 
 // Update 更新配置
+//
+// config.Version必须等于当前版本，否则返回ErrVersionConflict（为0时返回ErrVersionRequired），
+// 避免两个管理员同时编辑时后提交的修改覆盖先提交的；成功后版本号加1。
 func (s *MemoryStorage) Update(id string, config *ProxyConfig) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -97,32 +151,25 @@ func (s *MemoryStorage) Update(id string, config *ProxyConfig) error {
 	if !exists {
 		return ErrConfigNotFound
 	}
-
-	// 更新配置，保留令牌数据
-	config.ID = id
-	config.CreatedAt = existing.CreatedAt
-	config.UpdatedAt = time.Now()
-
-	// 保留原有的令牌数据和统计信息
-	config.AccessTokens = existing.AccessTokens
-	config.TokenStats = existing.TokenStats
+	if err := applyConfigUpdate(existing, config); err != nil {
+		return err
+	}
 
 	s.configs[id] = config
 
 	return nil
 }
 
-// Delete 删除配置
+// Delete 删除配置（软删除：移入回收站，令牌和统计随配置保留，可通过Restore恢复）
 func (s *MemoryStorage) Delete(id string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	_, exists := s.configs[id]
-	if !exists {
+	if _, exists := s.configs[id]; !exists {
 		return ErrConfigNotFound
 	}
 
-	delete(s.configs, id)
+	s.softDeleteLocked(id, time.Now())
 
 	return nil
 }
@@ -151,9 +198,7 @@ func (s *MemoryStorage) List(filter *ConfigFilter) (*ConfigResponse, error) {
 	for _, config := range s.configs {
 		// 应用筛选条件
 		if filter.Search != "" {
-			searchTerm := strings.ToLower(filter.Search)
-			if !strings.Contains(strings.ToLower(config.Name), searchTerm) &&
-				!strings.Contains(strings.ToLower(config.TargetURL), searchTerm) {
+			if len(matchedConfigFields(config, strings.ToLower(filter.Search))) == 0 {
 				continue
 			}
 		}
@@ -162,6 +207,10 @@ func (s *MemoryStorage) List(filter *ConfigFilter) (*ConfigResponse, error) {
 			continue
 		}
 
+		if !MatchesTags(config.Tags, filter.Tags) {
+			continue
+		}
+
 		allConfigs = append(allConfigs, *config)
 	}
 
@@ -207,6 +256,8 @@ func (s *MemoryStorage) Clear() {
 	defer s.mutex.Unlock()
 
 	s.configs = make(map[string]*ProxyConfig)
+	s.tokens = newTokenIndex()
+	s.deleted = make(map[string]*ProxyConfig)
 }
 
 // GetStats 获取统计信息
@@ -249,14 +300,16 @@ func (s *MemoryStorage) BatchOperation(operation string, configIDs []string) (*B
 		switch operation {
 		case "enable":
 			config.Enabled = true
+			config.Version++
 			config.UpdatedAt = time.Now()
 			result.Success = append(result.Success, configID)
 		case "disable":
 			config.Enabled = false
+			config.Version++
 			config.UpdatedAt = time.Now()
 			result.Success = append(result.Success, configID)
 		case "delete":
-			delete(s.configs, configID)
+			s.softDeleteLocked(configID, time.Now())
 			result.Success = append(result.Success, configID)
 		default:
 			result.Failed = append(result.Failed, configID)
@@ -285,16 +338,68 @@ func (s *MemoryStorage) ExportAll() (*ExportData, error) {
 	}, nil
 }
 
+// ExportSingle 将单个配置包装为导出数据（与ExportAll格式相同，可直接导入），includeTokens为false时移除令牌及令牌统计
+func ExportSingle(config *ProxyConfig, includeTokens bool) *ExportData {
+	exported := *config
+	if !includeTokens {
+		exported.AccessTokens = nil
+		exported.TokenStats = nil
+	}
+
+	return &ExportData{
+		Version:    "1.0",
+		ExportAt:   time.Now(),
+		Configs:    []ProxyConfig{exported},
+		TotalCount: 1,
+	}
+}
+
 // ImportConfigs 导入配置
+//
+// 冲突按配置名称判断（配置没有子域名字段，名称是唯一可读标识）：
+//   - skip: 保留已有配置，跳过导入项
+//   - replace: 原地覆盖已有配置，保留其ID、创建时间和令牌
+//   - error: 存在任何冲突时整体中止，不导入任何配置
 func (s *MemoryStorage) ImportConfigs(configs []ProxyConfig, mode string) (*ImportResult, error) {
+	return s.ImportConfigsWithOptions(configs, ImportOptions{Mode: mode})
+}
+
+// ImportConfigsWithOptions 按导入选项导入配置
+//
+// 令牌只随新建的配置导入（replace模式保留已有配置的令牌）：默认丢弃不含哈希的令牌，
+// reissue模式为其重新签发令牌值，新值在结果的reissued_tokens中返回。
+func (s *MemoryStorage) ImportConfigsWithOptions(configs []ProxyConfig, options ImportOptions) (*ImportResult, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	mode := options.Mode
+	if mode == "" {
+		mode = ImportModeError
+	}
+	if mode != ImportModeSkip && mode != ImportModeReplace && mode != ImportModeError {
+		return nil, fmt.Errorf("invalid import mode: %s", mode)
+	}
+	if options.TokenMode != "" && options.TokenMode != TokenImportKeep && options.TokenMode != TokenImportReissue {
+		return nil, fmt.Errorf("invalid token mode: %s", options.TokenMode)
+	}
+
 	result := &ImportResult{
 		Errors: make([]string, 0),
 	}
 
-	for _, config := range configs {
+	// 建立名称索引
+	byName := importNameIndex(s.configs)
+
+	// error模式下先检查冲突，存在冲突时整体中止
+	if mode == ImportModeError {
+		if conflicts := findImportConflicts(configs, byName); len(conflicts) > 0 {
+			return nil, &ImportConflictError{Names: conflicts}
+		}
+	}
+
+	for i := range configs {
+		config := configs[i]
+
 		// 验证配置
 		if err := ValidateConfig(&config); err != nil {
 			result.ErrorCount++
@@ -302,8 +407,32 @@ func (s *MemoryStorage) ImportConfigs(configs []ProxyConfig, mode string) (*Impo
 			continue
 		}
 
+		if existing, conflict := byName[config.Name]; conflict {
+			if mode == ImportModeSkip {
+				result.SkippedCount++
+				continue
+			}
+
+			// replace模式：原地覆盖，保留ID、创建时间和令牌数据
+			config.ID = existing.ID
+			config.Version = existing.Version + 1
+			config.CreatedAt = existing.CreatedAt
+			config.UpdatedAt = time.Now()
+			config.AccessTokens = existing.AccessTokens
+			config.TokenStats = existing.TokenStats
+			if config.Stats == nil {
+				config.Stats = existing.Stats
+			}
+
+			replaced := config
+			s.configs[replaced.ID] = &replaced
+			byName[replaced.Name] = &replaced
+			result.ImportedCount++
+			continue
+		}
+
 		// 检查是否超过最大条目数
-		if len(s.configs) >= s.maxEntries {
+		if err := s.ensureCapacityLocked(); err != nil {
 			result.ErrorCount++
 			result.Errors = append(result.Errors, fmt.Sprintf("已达到最大配置数量限制 (%d)", s.maxEntries))
 			break
@@ -311,17 +440,68 @@ func (s *MemoryStorage) ImportConfigs(configs []ProxyConfig, mode string) (*Impo
 
 		// 生成新的ID和时间戳
 		config.ID = uuid.New().String()
+		config.Version = 1
 		config.CreatedAt = time.Now()
 		config.UpdatedAt = time.Now()
+		if config.AccessTokens == nil {
+			config.AccessTokens = make([]AccessToken, 0)
+		}
+		if config.TokenStats == nil {
+			config.TokenStats = &TokenStats{}
+		}
+		issued, dropped, err := prepareImportedTokens(&config, options.TokenMode)
+		if err != nil {
+			result.ErrorCount++
+			result.Errors = append(result.Errors, fmt.Sprintf("配置 %s 令牌签发失败: %v", config.Name, err))
+			continue
+		}
+		result.DroppedTokens += dropped
+		if len(issued) > 0 {
+			if result.ReissuedTokens == nil {
+				result.ReissuedTokens = make(map[string]map[string]string)
+			}
+			result.ReissuedTokens[config.Name] = issued
+		}
 
 		// 添加配置
-		s.configs[config.ID] = &config
+		added := config
+		s.configs[added.ID] = &added
+		byName[added.Name] = &added
 		result.ImportedCount++
 	}
 
+	// 导入可能携带令牌，重建令牌索引
+	s.rebuildTokenIndexLocked()
+
 	return result, nil
 }
 
+// importNameIndex 建立导入时匹配已有配置使用的名称索引
+//
+// 配置没有子域名字段，名称是唯一可读标识，导入冲突和导入差异都按名称匹配。
+func importNameIndex(configs map[string]*ProxyConfig) map[string]*ProxyConfig {
+	byName := make(map[string]*ProxyConfig, len(configs))
+	for _, existing := range configs {
+		byName[existing.Name] = existing
+	}
+	return byName
+}
+
+// findImportConflicts 查找与已有配置（或导入数据内部）名称冲突的配置
+func findImportConflicts(configs []ProxyConfig, byName map[string]*ProxyConfig) []string {
+	var conflicts []string
+	seen := make(map[string]bool, len(configs))
+
+	for _, config := range configs {
+		if _, exists := byName[config.Name]; exists || seen[config.Name] {
+			conflicts = append(conflicts, config.Name)
+		}
+		seen[config.Name] = true
+	}
+
+	return conflicts
+}
+
 // UpdateStats 更新配置统计信息
 func (s *MemoryStorage) UpdateStats(configID string, responseTime time.Duration, success bool, bytes int64) error {
 	s.mutex.Lock()
@@ -332,29 +512,7 @@ func (s *MemoryStorage) UpdateStats(configID string, responseTime time.Duration,
 		return ErrConfigNotFound
 	}
 
-	// 初始化统计信息
-	if config.Stats == nil {
-		config.Stats = &ConfigStats{}
-	}
-
-	// 更新统计数据
-	config.Stats.RequestCount++
-	if !success {
-		config.Stats.ErrorCount++
-	}
-
-	// 更新平均响应时间（使用移动平均）
-	responseTimeMs := float64(responseTime.Nanoseconds()) / 1e6
-	if config.Stats.RequestCount == 1 {
-		config.Stats.AvgResponseTime = responseTimeMs
-	} else {
-		// 使用指数移动平均，权重为0.1
-		config.Stats.AvgResponseTime = config.Stats.AvgResponseTime*0.9 + responseTimeMs*0.1
-	}
-
-	config.Stats.LastAccessed = time.Now()
-	config.Stats.TotalBytes += bytes
-
+	recordRequestStats(config, responseTime, success, bytes)
 	return nil
 }
 
@@ -377,10 +535,8 @@ func (s *MemoryStorage) GetConfigStats(configID string) (*ConfigStats, error) {
 	return &statsCopy, nil
 }
 
-// ==================== 令牌管理方法 ====================
-
-// AddToken 添加令牌到指定配置
-func (s *MemoryStorage) AddToken(configID string, token *AccessToken) error {
+// ResetConfigStats 将配置统计信息清零，配置本身及其令牌保持不变
+func (s *MemoryStorage) ResetConfigStats(configID string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -389,31 +545,28 @@ func (s *MemoryStorage) AddToken(configID string, token *AccessToken) error {
 		return ErrConfigNotFound
 	}
 
-	// 验证令牌数据
-	if err := token.Validate(); err != nil {
-		return err
-	}
+	config.Stats = &ConfigStats{}
+	return nil
+}
 
-	// 检查令牌数量限制
-	if err := ValidateTokenLimit(len(config.AccessTokens)); err != nil {
-		return err
-	}
+// ==================== 令牌管理方法 ====================
 
-	// 检查令牌名称是否重复
-	for _, existingToken := range config.AccessTokens {
-		if existingToken.Name == token.Name {
-			return errors.New("token name already exists")
-		}
-	}
+// AddToken 添加令牌到指定配置
+func (s *MemoryStorage) AddToken(configID string, token *AccessToken) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	// 添加令牌
-	config.AccessTokens = append(config.AccessTokens, *token)
-	config.UpdatedAt = time.Now()
+	config, exists := s.configs[configID]
+	if !exists {
+		return ErrConfigNotFound
+	}
 
-	// 更新令牌统计
-	s.updateTokenStatsLocked(config)
+	// 添加令牌（令牌列表变化前后同步索引）
+	s.tokens.remove(config)
+	err := appendToken(config, token)
+	s.tokens.add(config)
 
-	return nil
+	return err
 }
 
 // UpdateToken 更新指定令牌
@@ -426,39 +579,12 @@ func (s *MemoryStorage) UpdateToken(configID, tokenID string, token *AccessToken
 		return ErrConfigNotFound
 	}
 
-	// 查找令牌
-	tokenIndex := -1
-	for i, existingToken := range config.AccessTokens {
-		if existingToken.ID == tokenID {
-			tokenIndex = i
-			break
-		}
-	}
-
-	if tokenIndex == -1 {
-		return ErrTokenNotFound
-	}
-
-	// 验证令牌数据
-	if err := token.Validate(); err != nil {
-		return err
-	}
-
-	// 检查名称冲突（排除自己）
-	for i, existingToken := range config.AccessTokens {
-		if i != tokenIndex && existingToken.Name == token.Name {
-			return errors.New("token name already exists")
-		}
-	}
-
-	// 更新令牌
-	config.AccessTokens[tokenIndex] = *token
-	config.UpdatedAt = time.Now()
-
-	// 更新令牌统计
-	s.updateTokenStatsLocked(config)
+	// 更新令牌（令牌哈希可能变化，需同步索引）
+	s.tokens.remove(config)
+	err := replaceToken(config, tokenID, token)
+	s.tokens.add(config)
 
-	return nil
+	return err
 }
 
 // DeleteToken 删除指定令牌
@@ -471,27 +597,12 @@ func (s *MemoryStorage) DeleteToken(configID, tokenID string) error {
 		return ErrConfigNotFound
 	}
 
-	// 查找令牌
-	tokenIndex := -1
-	for i, token := range config.AccessTokens {
-		if token.ID == tokenID {
-			tokenIndex = i
-			break
-		}
-	}
+	// 删除令牌（后续令牌下标会变化，整体重建该配置的索引）
+	s.tokens.remove(config)
+	err := removeToken(config, tokenID)
+	s.tokens.add(config)
 
-	if tokenIndex == -1 {
-		return ErrTokenNotFound
-	}
-
-	// 删除令牌
-	config.AccessTokens = append(config.AccessTokens[:tokenIndex], config.AccessTokens[tokenIndex+1:]...)
-	config.UpdatedAt = time.Now()
-
-	// 更新令牌统计
-	s.updateTokenStatsLocked(config)
-
-	return nil
+	return err
 }
 
 // GetTokens 获取指定配置的所有令牌
@@ -547,67 +658,46 @@ func (s *MemoryStorage) ValidateToken(configID, tokenValue string) (*TokenValida
 		}, nil
 	}
 
-	// 计算令牌哈希
-	tokenHash := HashToken(tokenValue)
-
-	// 查找匹配的令牌
-	for _, token := range config.AccessTokens {
-		if token.TokenHash == tokenHash {
-			// 验证令牌访问权限
-			tokenCopy := token // 创建副本避免指针问题
-			if err := ValidateTokenAccess(&tokenCopy); err != nil {
-				return &TokenValidationResult{
-					Valid:     false,
-					Token:     &tokenCopy,
-					ConfigID:  configID,
-					ErrorCode: getErrorCode(err),
-					ErrorMsg:  err.Error(),
-				}, nil
-			}
-
-			// 令牌有效
-			return &TokenValidationResult{
-				Valid:    true,
-				Token:    &tokenCopy,
-				ConfigID: configID,
-			}, nil
-		}
+	// 通过令牌索引查找
+	ref, found := s.lookupTokenLocked(tokenValue, configID)
+	if !found {
+		// 令牌未找到
+		return &TokenValidationResult{
+			Valid:     false,
+			ErrorCode: "TOKEN_NOT_FOUND",
+			ErrorMsg:  "token not found",
+		}, nil
 	}
 
-	// 令牌未找到
-	return &TokenValidationResult{
-		Valid:     false,
-		ErrorCode: "TOKEN_NOT_FOUND",
-		ErrorMsg:  "token not found",
-	}, nil
+	// 验证令牌访问权限（副本避免指针问题）
+	return tokenValidationResult(configID, config.AccessTokens[ref.tokenIndex]), nil
 }
 
 // FindConfigByToken 通过令牌值查找对应的配置ID
+//
+// 通过令牌反向索引O(1)定位，有效与无效令牌的查找路径一致，
+// 避免通过响应时间推断令牌是否存在或属于哪个配置。
 func (s *MemoryStorage) FindConfigByToken(tokenValue string) (string, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	// 计算令牌哈希
-	tokenHash := HashToken(tokenValue)
-
-	// 遍历所有配置查找匹配的令牌
-	for configID, config := range s.configs {
-		for _, token := range config.AccessTokens {
-			if token.TokenHash == tokenHash {
-				// 验证令牌是否有效
-				tokenCopy := token
-				if err := ValidateTokenAccess(&tokenCopy); err != nil {
-					continue // 跳过无效令牌
-				}
-				return configID, nil
-			}
-		}
+	ref, found := s.lookupTokenLocked(tokenValue, "")
+	if !found {
+		return "", ErrTokenNotFound
+	}
+
+	// 跳过无效令牌（禁用或过期）
+	if err := ValidateTokenAccess(s.resolveTokenRefLocked(ref)); err != nil {
+		return "", ErrTokenNotFound
 	}
 
-	return "", ErrTokenNotFound
+	return ref.configID, nil
 }
 
 // UpdateTokenUsage 更新令牌使用统计
+//
+// 检查使用次数上限和计数在同一把锁内完成，并发请求不会超出MaxUsageCount；
+// 已达上限时返回ErrTokenQuotaExceeded且不计数。
 func (s *MemoryStorage) UpdateTokenUsage(configID, tokenValue string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -617,23 +707,13 @@ func (s *MemoryStorage) UpdateTokenUsage(configID, tokenValue string) error {
 		return ErrConfigNotFound
 	}
 
-	// 计算令牌哈希
-	tokenHash := HashToken(tokenValue)
-
 	// 查找并更新令牌
-	for i, token := range config.AccessTokens {
-		if token.TokenHash == tokenHash {
-			config.AccessTokens[i].UpdateUsage()
-			config.UpdatedAt = time.Now()
-
-			// 更新令牌统计
-			s.updateTokenStatsLocked(config)
-
-			return nil
-		}
+	ref, found := s.lookupTokenLocked(tokenValue, configID)
+	if !found {
+		return ErrTokenNotFound
 	}
 
-	return ErrTokenNotFound
+	return consumeTokenUsage(config, ref.tokenIndex)
 }
 
 // GetTokenStats 获取令牌统计信息
@@ -655,6 +735,66 @@ func (s *MemoryStorage) GetTokenStats(configID string) (*TokenStats, error) {
 	return &statsCopy, nil
 }
 
+// ResetTokenStats 将指定令牌的使用次数、最后使用时间和使用历史清零，并重新计算令牌统计
+func (s *MemoryStorage) ResetTokenStats(configID, tokenID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	config, exists := s.configs[configID]
+	if !exists {
+		return ErrConfigNotFound
+	}
+
+	return resetTokenUsage(config, tokenID)
+}
+
+// ensureCapacityLocked 确保有空间容纳新配置（需要持有锁）
+func (s *MemoryStorage) ensureCapacityLocked() error {
+	if len(s.configs) < s.maxEntries {
+		return nil
+	}
+
+	if s.evictionMode == EvictionLRU {
+		for len(s.configs) > 0 && len(s.configs) >= s.maxEntries {
+			s.evictLRULocked()
+		}
+	}
+
+	if len(s.configs) >= s.maxEntries {
+		return fmt.Errorf("maximum entries (%d) exceeded", s.maxEntries)
+	}
+	return nil
+}
+
+// evictLRULocked 淘汰最久未访问的配置（需要持有锁）
+//
+// 访问时间取Stats.LastAccessed，从未被访问过的配置使用CreatedAt。
+func (s *MemoryStorage) evictLRULocked() {
+	var oldestID string
+	var oldestTime time.Time
+
+	for id, config := range s.configs {
+		accessed := lastAccessTime(config)
+		if oldestID == "" || accessed.Before(oldestTime) {
+			oldestID = id
+			oldestTime = accessed
+		}
+	}
+
+	if oldestID != "" {
+		s.tokens.remove(s.configs[oldestID])
+		delete(s.configs, oldestID)
+	}
+}
+
+// lastAccessTime 获取配置的最后访问时间，从未访问时返回创建时间
+func lastAccessTime(config *ProxyConfig) time.Time {
+	if config.Stats != nil && !config.Stats.LastAccessed.IsZero() {
+		return config.Stats.LastAccessed
+	}
+	return config.CreatedAt
+}
+
 // updateTokenStatsLocked 更新令牌统计信息（需要持有锁）
 func (s *MemoryStorage) updateTokenStatsLocked(config *ProxyConfig) {
 	stats := CalculateTokenStats(config.AccessTokens)
@@ -670,6 +810,8 @@ func getErrorCode(err error) string {
 		return "TOKEN_EXPIRED"
 	case ErrTokenDisabled:
 		return "TOKEN_DISABLED"
+	case ErrTokenQuotaExceeded:
+		return "TOKEN_QUOTA_EXCEEDED"
 	case ErrTokenInvalid:
 		return "TOKEN_INVALID"
 	default:
@@ -0,0 +1,478 @@
+package proxyconfig
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"privacygateway/internal/logger"
+)
+
+// storageFactory 为每个契约测试创建一个空的存储实例（最大配置数为10）
+type storageFactory func(t *testing.T) Storage
+
+// storageContractTests Storage接口的契约测试，所有存储后端的行为必须一致
+var storageContractTests = []struct {
+	name string
+	run  func(t *testing.T, storage Storage)
+}{
+	{"AddGetUpdate", testContractAddGetUpdate},
+	{"DeleteRestore", testContractDeleteRestore},
+	{"ListAndSearch", testContractListAndSearch},
+	{"BatchOperation", testContractBatchOperation},
+	{"TokenLifecycle", testContractTokenLifecycle},
+	{"Stats", testContractStats},
+	{"ImportExport", testContractImportExport},
+	{"ConcurrentTokenUsage", testContractConcurrentTokenUsage},
+	{"ConcurrentWrites", testContractConcurrentWrites},
+}
+
+// runStorageContract 对factory创建的存储运行全部契约测试
+func runStorageContract(t *testing.T, factory storageFactory) {
+	for _, tt := range storageContractTests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, factory(t))
+		})
+	}
+}
+
+func TestStorageContract_MemoryStorage(t *testing.T) {
+	runStorageContract(t, func(t *testing.T) Storage {
+		return NewMemoryStorage(10)
+	})
+}
+
+func TestStorageContract_PersistentStorage(t *testing.T) {
+	runStorageContract(t, func(t *testing.T) Storage {
+		return NewPersistentStorage(filepath.Join(t.TempDir(), "configs.json"), 10, EvictionReject, false, logger.New())
+	})
+}
+
+func TestStorageContract_RedisStorage(t *testing.T) {
+	runStorageContract(t, func(t *testing.T) Storage {
+		storage, _ := newRedisTestStorage(t)
+		return storage
+	})
+}
+
+// addContractConfig 添加配置并返回其ID
+func addContractConfig(t *testing.T, storage Storage, name string) string {
+	t.Helper()
+	config := newEvictionTestConfig(name)
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Add(%s) error = %v", name, err)
+	}
+	return config.ID
+}
+
+// addContractToken 为配置添加令牌，返回令牌和令牌值
+func addContractToken(t *testing.T, storage Storage, configID string, req *TokenCreateRequest) (*AccessToken, string) {
+	t.Helper()
+	token, value, err := CreateAccessToken(req, "admin")
+	if err != nil {
+		t.Fatalf("CreateAccessToken() error = %v", err)
+	}
+	if err := storage.AddToken(configID, token); err != nil {
+		t.Fatalf("AddToken() error = %v", err)
+	}
+	return token, value
+}
+
+func testContractAddGetUpdate(t *testing.T, storage Storage) {
+	config := newEvictionTestConfig("contract")
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if config.ID == "" || config.Version != 1 || config.CreatedAt.IsZero() {
+		t.Fatalf("Expected Add to assign ID, version and timestamps, got %+v", config)
+	}
+
+	stored, err := storage.GetByID(config.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if stored.Name != "contract" || stored.TargetURL != "https://example.com" {
+		t.Errorf("Expected stored config to match, got %+v", stored)
+	}
+	if _, err := storage.GetByID("missing"); err != ErrConfigNotFound {
+		t.Errorf("Expected ErrConfigNotFound for missing config, got %v", err)
+	}
+
+	// 修改返回的配置不影响存储
+	stored.Name = "mutated"
+	if again, _ := storage.GetByID(config.ID); again.Name != "contract" {
+		t.Errorf("Expected GetByID to return a copy, got name %q", again.Name)
+	}
+
+	// 更新需要当前版本号，令牌随配置保留
+	_, tokenValue := addContractToken(t, storage, config.ID, &TokenCreateRequest{Name: "kept"})
+	update := newEvictionTestConfig("contract")
+	update.TargetURL = "https://api.example.com"
+	if err := storage.Update(config.ID, update); err != ErrVersionRequired {
+		t.Errorf("Expected ErrVersionRequired, got %v", err)
+	}
+	update.Version = 1
+	if err := storage.Update(config.ID, update); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if update.Version != 2 || update.ID != config.ID {
+		t.Errorf("Expected update to bump version to 2, got version %d id %s", update.Version, update.ID)
+	}
+	stale := newEvictionTestConfig("contract")
+	stale.Version = 1
+	if err := storage.Update(config.ID, stale); err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict for stale version, got %v", err)
+	}
+	if err := storage.Update("missing", stale); err != ErrConfigNotFound {
+		t.Errorf("Expected ErrConfigNotFound for missing config, got %v", err)
+	}
+
+	updated, _ := storage.GetByID(config.ID)
+	if updated.TargetURL != "https://api.example.com" || !updated.CreatedAt.Equal(config.CreatedAt) {
+		t.Errorf("Expected updated target with original creation time, got %+v", updated)
+	}
+	if configID, err := storage.FindConfigByToken(tokenValue); err != nil || configID != config.ID {
+		t.Errorf("Expected token to survive update, got %q (err %v)", configID, err)
+	}
+}
+
+func testContractDeleteRestore(t *testing.T, storage Storage) {
+	configID := addContractConfig(t, storage, "trash")
+	_, tokenValue := addContractToken(t, storage, configID, &TokenCreateRequest{Name: "ci"})
+
+	if err := storage.Delete(configID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := storage.Delete(configID); err != ErrConfigNotFound {
+		t.Errorf("Expected second delete to fail with ErrConfigNotFound, got %v", err)
+	}
+	if _, err := storage.GetByID(configID); err != ErrConfigNotFound {
+		t.Errorf("Expected deleted config to be hidden, got %v", err)
+	}
+	if _, err := storage.FindConfigByToken(tokenValue); err != ErrTokenNotFound {
+		t.Errorf("Expected token of deleted config not to resolve, got %v", err)
+	}
+
+	deleted, err := storage.ListDeleted()
+	if err != nil || len(deleted) != 1 || deleted[0].ID != configID || deleted[0].DeletedAt == nil {
+		t.Fatalf("Expected deleted config in trash, got %+v (err %v)", deleted, err)
+	}
+
+	// 恢复后令牌重新可用
+	if err := storage.Restore(configID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if err := storage.Restore(configID); err != ErrConfigNotFound {
+		t.Errorf("Expected restoring a live config to fail, got %v", err)
+	}
+	if restored, err := storage.GetByID(configID); err != nil || restored.DeletedAt != nil {
+		t.Errorf("Expected restored config, got %+v (err %v)", restored, err)
+	}
+	if found, err := storage.FindConfigByToken(tokenValue); err != nil || found != configID {
+		t.Errorf("Expected token to resolve after restore, got %q (err %v)", found, err)
+	}
+
+	// 只清除早于截止时间的回收站配置
+	storage.Delete(configID)
+	if purged := storage.PurgeDeleted(time.Now().Add(-time.Hour)); purged != 0 {
+		t.Errorf("Expected recent deletion to be kept, purged %d", purged)
+	}
+	if purged := storage.PurgeDeleted(time.Now().Add(time.Second)); purged != 1 {
+		t.Errorf("Expected 1 config to be purged, got %d", purged)
+	}
+	if deleted, _ := storage.ListDeleted(); len(deleted) != 0 {
+		t.Errorf("Expected empty trash after purge, got %d", len(deleted))
+	}
+}
+
+func testContractListAndSearch(t *testing.T, storage Storage) {
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		addContractConfig(t, storage, name)
+		time.Sleep(time.Millisecond) // 保证创建时间有先后
+	}
+	disabled := newEvictionTestConfig("disabled-delta")
+	disabled.Enabled = false
+	storage.Add(disabled)
+
+	list, err := storage.List(&ConfigFilter{Limit: 2, Page: 1})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if list.Total != 4 || len(list.Configs) != 2 || list.TotalPages != 2 {
+		t.Errorf("Expected 4 configs over 2 pages, got total=%d len=%d pages=%d", list.Total, len(list.Configs), list.TotalPages)
+	}
+	if list.Configs[0].Name != "disabled-delta" {
+		t.Errorf("Expected newest config first, got %s", list.Configs[0].Name)
+	}
+
+	enabled := true
+	if list, _ := storage.List(&ConfigFilter{Enabled: &enabled}); list.Total != 3 {
+		t.Errorf("Expected 3 enabled configs, got %d", list.Total)
+	}
+	if list, _ := storage.List(&ConfigFilter{Search: "GAM"}); list.Total != 1 || list.Configs[0].Name != "gamma" {
+		t.Errorf("Expected case-insensitive search to match gamma, got %+v", list.Configs)
+	}
+
+	if result := storage.Search("delta"); result.Total != 1 || result.Results[0].Config.Name != "disabled-delta" {
+		t.Errorf("Expected search to match disabled-delta, got %+v", result)
+	}
+
+	if stats := storage.GetStats(); stats.TotalConfigs != 4 || stats.EnabledConfigs != 3 {
+		t.Errorf("Expected 4 configs with 3 enabled, got %+v", stats)
+	}
+
+	storage.Clear()
+	if stats := storage.GetStats(); stats.TotalConfigs != 0 {
+		t.Errorf("Expected no configs after Clear, got %d", stats.TotalConfigs)
+	}
+}
+
+func testContractBatchOperation(t *testing.T, storage Storage) {
+	first := addContractConfig(t, storage, "first")
+	second := addContractConfig(t, storage, "second")
+
+	result, err := storage.BatchOperation("disable", []string{first, second, "missing"})
+	if err != nil {
+		t.Fatalf("BatchOperation() error = %v", err)
+	}
+	if len(result.Success) != 2 || result.FailedCount != 1 || result.TotalCount != 3 {
+		t.Errorf("Expected 2 successes and 1 failure, got %+v", result)
+	}
+	if config, _ := storage.GetByID(first); config.Enabled || config.Version != 2 {
+		t.Errorf("Expected disabled config with bumped version, got enabled=%v version=%d", config.Enabled, config.Version)
+	}
+
+	if result, _ := storage.BatchOperation("delete", []string{second}); len(result.Success) != 1 {
+		t.Errorf("Expected batch delete to succeed, got %+v", result)
+	}
+	if deleted, _ := storage.ListDeleted(); len(deleted) != 1 || deleted[0].ID != second {
+		t.Errorf("Expected batch-deleted config in trash, got %+v", deleted)
+	}
+
+	if result, _ := storage.BatchOperation("archive", []string{first}); result.FailedCount != 1 {
+		t.Errorf("Expected unknown operation to fail, got %+v", result)
+	}
+}
+
+func testContractTokenLifecycle(t *testing.T, storage Storage) {
+	configID := addContractConfig(t, storage, "tokens")
+	token, value := addContractToken(t, storage, configID, &TokenCreateRequest{Name: "ci"})
+
+	duplicate, _, _ := CreateAccessToken(&TokenCreateRequest{Name: "ci"}, "admin")
+	if err := storage.AddToken(configID, duplicate); err == nil {
+		t.Error("Expected duplicate token name to be rejected")
+	}
+	if err := storage.AddToken("missing", duplicate); err != ErrConfigNotFound {
+		t.Errorf("Expected ErrConfigNotFound for missing config, got %v", err)
+	}
+
+	if tokens, err := storage.GetTokens(configID); err != nil || len(tokens) != 1 {
+		t.Errorf("Expected 1 token, got %d (err %v)", len(tokens), err)
+	}
+	if stored, err := storage.GetTokenByID(configID, token.ID); err != nil || stored.Name != "ci" {
+		t.Errorf("Expected token by ID, got %+v (err %v)", stored, err)
+	}
+	if _, err := storage.GetTokenByID(configID, "missing"); err != ErrTokenNotFound {
+		t.Errorf("Expected ErrTokenNotFound, got %v", err)
+	}
+
+	if result, _ := storage.ValidateToken(configID, value); !result.Valid || result.ConfigID != configID {
+		t.Errorf("Expected token to be valid, got %+v", result)
+	}
+	if result, _ := storage.ValidateToken(configID, "wrong-token"); result.Valid || result.ErrorCode != "TOKEN_NOT_FOUND" {
+		t.Errorf("Expected TOKEN_NOT_FOUND, got %+v", result)
+	}
+	if result, _ := storage.ValidateToken("missing", value); result.Valid || result.ErrorCode != "CONFIG_NOT_FOUND" {
+		t.Errorf("Expected CONFIG_NOT_FOUND, got %+v", result)
+	}
+
+	// 使用统计
+	if err := storage.UpdateTokenUsage(configID, value); err != nil {
+		t.Fatalf("UpdateTokenUsage() error = %v", err)
+	}
+	if stats, _ := storage.GetTokenStats(configID); stats.TotalTokens != 1 || stats.TotalRequests != 1 {
+		t.Errorf("Expected token stats to count usage, got %+v", stats)
+	}
+	if err := storage.ResetTokenStats(configID, token.ID); err != nil {
+		t.Fatalf("ResetTokenStats() error = %v", err)
+	}
+	if stored, _ := storage.GetTokenByID(configID, token.ID); stored.UsageCount != 0 || stored.LastUsed != nil {
+		t.Errorf("Expected token usage to be reset, got %+v", stored)
+	}
+
+	// 禁用后验证失败，查找不再返回配置
+	disabled, _ := storage.GetTokenByID(configID, token.ID)
+	disabled.Enabled = false
+	if err := storage.UpdateToken(configID, token.ID, disabled); err != nil {
+		t.Fatalf("UpdateToken() error = %v", err)
+	}
+	if result, _ := storage.ValidateToken(configID, value); result.Valid || result.ErrorCode != "TOKEN_DISABLED" {
+		t.Errorf("Expected TOKEN_DISABLED, got %+v", result)
+	}
+	if _, err := storage.FindConfigByToken(value); err != ErrTokenNotFound {
+		t.Errorf("Expected disabled token not to resolve, got %v", err)
+	}
+
+	if err := storage.DeleteToken(configID, token.ID); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if err := storage.DeleteToken(configID, token.ID); err != ErrTokenNotFound {
+		t.Errorf("Expected second delete to fail with ErrTokenNotFound, got %v", err)
+	}
+	if result, _ := storage.ValidateToken(configID, value); result.Valid {
+		t.Error("Expected deleted token to be invalid")
+	}
+}
+
+func testContractStats(t *testing.T, storage Storage) {
+	configID := addContractConfig(t, storage, "stats")
+
+	storage.UpdateStats(configID, 10*time.Millisecond, true, 100)
+	storage.UpdateStats(configID, 20*time.Millisecond, false, 50)
+	if err := storage.UpdateStats("missing", time.Millisecond, true, 1); err != ErrConfigNotFound {
+		t.Errorf("Expected ErrConfigNotFound for missing config, got %v", err)
+	}
+
+	stats, err := storage.GetConfigStats(configID)
+	if err != nil {
+		t.Fatalf("GetConfigStats() error = %v", err)
+	}
+	if stats.RequestCount != 2 || stats.ErrorCount != 1 || stats.TotalBytes != 150 || stats.LastAccessed.IsZero() {
+		t.Errorf("Expected 2 requests, 1 error and 150 bytes, got %+v", stats)
+	}
+
+	if err := storage.ResetConfigStats(configID); err != nil {
+		t.Fatalf("ResetConfigStats() error = %v", err)
+	}
+	if stats, _ := storage.GetConfigStats(configID); stats.RequestCount != 0 || stats.TotalBytes != 0 {
+		t.Errorf("Expected stats to be reset, got %+v", stats)
+	}
+}
+
+func testContractImportExport(t *testing.T, storage Storage) {
+	configID := addContractConfig(t, storage, "exported")
+	_, tokenValue := addContractToken(t, storage, configID, &TokenCreateRequest{Name: "ci"})
+
+	exported, err := storage.ExportAll()
+	if err != nil || exported.TotalCount != 1 || len(exported.Configs[0].AccessTokens) != 1 {
+		t.Fatalf("Expected 1 exported config with its token, got %+v (err %v)", exported, err)
+	}
+
+	// error模式下存在同名配置时整体中止
+	incoming := []ProxyConfig{*newEvictionTestConfig("exported"), *newEvictionTestConfig("imported")}
+	if _, err := storage.ImportConfigs(incoming, ImportModeError); err == nil {
+		t.Error("Expected conflicting import to be rejected in error mode")
+	}
+	if stats := storage.GetStats(); stats.TotalConfigs != 1 {
+		t.Errorf("Expected aborted import not to add configs, got %d", stats.TotalConfigs)
+	}
+
+	diff := storage.DiffImport(incoming, false)
+	if len(diff.Adds) != 1 || diff.Unchanged != 1 {
+		t.Errorf("Expected 1 add and 1 unchanged in diff, got %+v", diff)
+	}
+
+	result, err := storage.ImportConfigs(incoming, ImportModeSkip)
+	if err != nil || result.ImportedCount != 1 || result.SkippedCount != 1 {
+		t.Errorf("Expected 1 imported and 1 skipped, got %+v (err %v)", result, err)
+	}
+
+	// replace模式原地覆盖，保留ID和令牌
+	replacement := newEvictionTestConfig("exported")
+	replacement.TargetURL = "https://replaced.example.com"
+	if _, err := storage.ImportConfigs([]ProxyConfig{*replacement}, ImportModeReplace); err != nil {
+		t.Fatalf("ImportConfigs(replace) error = %v", err)
+	}
+	replaced, err := storage.GetByID(configID)
+	if err != nil || replaced.TargetURL != "https://replaced.example.com" {
+		t.Errorf("Expected config to be replaced in place, got %+v (err %v)", replaced, err)
+	}
+	if found, err := storage.FindConfigByToken(tokenValue); err != nil || found != configID {
+		t.Errorf("Expected token to survive replace import, got %q (err %v)", found, err)
+	}
+
+	if _, err := storage.ImportConfigs(incoming, "merge"); err == nil {
+		t.Error("Expected invalid import mode to be rejected")
+	}
+}
+
+func testContractConcurrentTokenUsage(t *testing.T, storage Storage) {
+	configID := addContractConfig(t, storage, "quota")
+	_, value := addContractToken(t, storage, configID, &TokenCreateRequest{Name: "limited", MaxUsageCount: 5})
+
+	// 并发请求不会超出使用次数上限
+	var succeeded, exceeded int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch err := storage.UpdateTokenUsage(configID, value); err {
+			case nil:
+				atomic.AddInt32(&succeeded, 1)
+			case ErrTokenQuotaExceeded:
+				atomic.AddInt32(&exceeded, 1)
+			default:
+				t.Errorf("UpdateTokenUsage() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 5 || exceeded != 15 {
+		t.Errorf("Expected 5 successful uses and 15 rejections, got %d and %d", succeeded, exceeded)
+	}
+	if stats, _ := storage.GetTokenStats(configID); stats.TotalRequests != 5 {
+		t.Errorf("Expected usage count of 5, got %d", stats.TotalRequests)
+	}
+}
+
+func testContractConcurrentWrites(t *testing.T, storage Storage) {
+	configID := addContractConfig(t, storage, "busy")
+
+	// 并发更新统计和添加令牌不会丢失写入
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := storage.UpdateStats(configID, time.Millisecond, true, 1); err != nil {
+				t.Errorf("UpdateStats() error = %v", err)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			token, _, _ := CreateAccessToken(&TokenCreateRequest{Name: string(rune('a' + i))}, "admin")
+			if err := storage.AddToken(configID, token); err != nil {
+				t.Errorf("AddToken() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if stats, _ := storage.GetConfigStats(configID); stats.RequestCount != 10 {
+		t.Errorf("Expected 10 requests recorded, got %d", stats.RequestCount)
+	}
+	if tokens, _ := storage.GetTokens(configID); len(tokens) != 10 {
+		t.Errorf("Expected 10 tokens, got %d", len(tokens))
+	}
+
+	// 并发添加配置受最大配置数限制
+	var added int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if storage.Add(newEvictionTestConfig("parallel")) == nil {
+				atomic.AddInt32(&added, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if added != 9 {
+		t.Errorf("Expected 9 configs to fit alongside the existing one, got %d", added)
+	}
+}
@@ -0,0 +1,433 @@
+package proxyconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 辅助函数：创建最小可用配置
+func newEvictionTestConfig(name string) *ProxyConfig {
+	return &ProxyConfig{
+		Name:      name,
+		TargetURL: "https://example.com",
+		Protocol:  "https",
+		Enabled:   true,
+	}
+}
+
+func TestMemoryStorage_RejectModeAtBoundary(t *testing.T) {
+	storage := NewMemoryStorage(2)
+
+	if storage.EvictionMode() != EvictionReject {
+		t.Fatalf("Expected default eviction mode %q, got %q", EvictionReject, storage.EvictionMode())
+	}
+
+	for _, name := range []string{"first", "second"} {
+		if err := storage.Add(newEvictionTestConfig(name)); err != nil {
+			t.Fatalf("Failed to add config %s: %v", name, err)
+		}
+	}
+
+	if err := storage.Add(newEvictionTestConfig("third")); err == nil {
+		t.Error("Expected error when adding beyond max entries in reject mode")
+	}
+
+	if stats := storage.GetStats(); stats.TotalConfigs != 2 {
+		t.Errorf("Expected 2 configs, got %d", stats.TotalConfigs)
+	}
+}
+
+func TestMemoryStorage_LRUModeEvictsLeastRecentlyAccessed(t *testing.T) {
+	storage := NewMemoryStorageWithEviction(2, EvictionLRU)
+
+	first := newEvictionTestConfig("first")
+	second := newEvictionTestConfig("second")
+	if err := storage.Add(first); err != nil {
+		t.Fatalf("Failed to add first config: %v", err)
+	}
+	if err := storage.Add(second); err != nil {
+		t.Fatalf("Failed to add second config: %v", err)
+	}
+
+	// first较早创建，但最近被访问过；second从未访问，按创建时间参与淘汰
+	storage.configs[first.ID].CreatedAt = time.Now().Add(-2 * time.Hour)
+	storage.configs[second.ID].CreatedAt = time.Now().Add(-1 * time.Hour)
+	if err := storage.UpdateStats(first.ID, 10*time.Millisecond, true, 0); err != nil {
+		t.Fatalf("Failed to update stats: %v", err)
+	}
+
+	third := newEvictionTestConfig("third")
+	if err := storage.Add(third); err != nil {
+		t.Fatalf("Expected LRU mode to make room, got error: %v", err)
+	}
+
+	if _, err := storage.GetByID(second.ID); err != ErrConfigNotFound {
+		t.Errorf("Expected never-accessed config to be evicted, got err=%v", err)
+	}
+	if _, err := storage.GetByID(first.ID); err != nil {
+		t.Errorf("Expected recently accessed config to be kept: %v", err)
+	}
+	if _, err := storage.GetByID(third.ID); err != nil {
+		t.Errorf("Expected new config to be stored: %v", err)
+	}
+	if stats := storage.GetStats(); stats.TotalConfigs != 2 {
+		t.Errorf("Expected 2 configs after eviction, got %d", stats.TotalConfigs)
+	}
+}
+
+func TestMemoryStorage_LRUModeBelowBoundary(t *testing.T) {
+	storage := NewMemoryStorageWithEviction(2, EvictionLRU)
+
+	first := newEvictionTestConfig("first")
+	if err := storage.Add(first); err != nil {
+		t.Fatalf("Failed to add config: %v", err)
+	}
+	if err := storage.Add(newEvictionTestConfig("second")); err != nil {
+		t.Fatalf("Failed to add config: %v", err)
+	}
+
+	// 未达到上限前不应淘汰任何配置
+	if _, err := storage.GetByID(first.ID); err != nil {
+		t.Errorf("Expected no eviction below max entries: %v", err)
+	}
+}
+
+func TestParseEvictionMode(t *testing.T) {
+	testCases := []struct {
+		input       string
+		expected    EvictionMode
+		expectError bool
+	}{
+		{"", EvictionReject, false},
+		{"reject", EvictionReject, false},
+		{"LRU", EvictionLRU, false},
+		{"fifo", "", true},
+	}
+
+	for _, tc := range testCases {
+		mode, err := ParseEvictionMode(tc.input)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("Expected error for %q", tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.input, err)
+		}
+		if mode != tc.expected {
+			t.Errorf("Expected %q for %q, got %q", tc.expected, tc.input, mode)
+		}
+	}
+}
+
+// 辅助函数：创建预置了配置和令牌的存储
+func createPopulatedImportStorage(t *testing.T) (*MemoryStorage, *ProxyConfig) {
+	storage := NewMemoryStorage(10)
+
+	existing := newEvictionTestConfig("shared")
+	if err := storage.Add(existing); err != nil {
+		t.Fatalf("Failed to add config: %v", err)
+	}
+	token := &AccessToken{
+		ID:        "existing-token",
+		Name:      "Existing Token",
+		TokenHash: HashToken("existing"),
+		Enabled:   true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.AddToken(existing.ID, token); err != nil {
+		t.Fatalf("Failed to add token: %v", err)
+	}
+
+	return storage, existing
+}
+
+func importTestConfigs() []ProxyConfig {
+	return []ProxyConfig{
+		{Name: "shared", TargetURL: "https://replaced.example.com", Protocol: "https", Enabled: false},
+		{Name: "fresh", TargetURL: "https://fresh.example.com", Protocol: "https", Enabled: true},
+	}
+}
+
+func TestMemoryStorage_ImportSkipMode(t *testing.T) {
+	storage, existing := createPopulatedImportStorage(t)
+
+	result, err := storage.ImportConfigs(importTestConfigs(), ImportModeSkip)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.ImportedCount != 1 || result.SkippedCount != 1 {
+		t.Errorf("Expected 1 imported and 1 skipped, got %d/%d", result.ImportedCount, result.SkippedCount)
+	}
+
+	kept, err := storage.GetByID(existing.ID)
+	if err != nil {
+		t.Fatalf("Existing config missing: %v", err)
+	}
+	if kept.TargetURL != "https://example.com" {
+		t.Errorf("Expected existing config to be kept, got target %s", kept.TargetURL)
+	}
+	if stats := storage.GetStats(); stats.TotalConfigs != 2 {
+		t.Errorf("Expected 2 configs, got %d", stats.TotalConfigs)
+	}
+}
+
+func TestMemoryStorage_ImportReplaceMode(t *testing.T) {
+	storage, existing := createPopulatedImportStorage(t)
+
+	result, err := storage.ImportConfigs(importTestConfigs(), ImportModeReplace)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.ImportedCount != 2 || result.SkippedCount != 0 {
+		t.Errorf("Expected 2 imported and 0 skipped, got %d/%d", result.ImportedCount, result.SkippedCount)
+	}
+
+	replaced, err := storage.GetByID(existing.ID)
+	if err != nil {
+		t.Fatalf("Replaced config should keep its ID: %v", err)
+	}
+	if replaced.TargetURL != "https://replaced.example.com" || replaced.Enabled {
+		t.Errorf("Expected config to be overwritten, got %+v", replaced)
+	}
+	if len(replaced.AccessTokens) != 1 || replaced.AccessTokens[0].ID != "existing-token" {
+		t.Errorf("Expected tokens to be preserved, got %+v", replaced.AccessTokens)
+	}
+	if !replaced.CreatedAt.Equal(existing.CreatedAt) {
+		t.Error("Expected CreatedAt to be preserved")
+	}
+	if stats := storage.GetStats(); stats.TotalConfigs != 2 {
+		t.Errorf("Expected 2 configs, got %d", stats.TotalConfigs)
+	}
+}
+
+func TestMemoryStorage_ImportErrorMode(t *testing.T) {
+	storage, existing := createPopulatedImportStorage(t)
+
+	result, err := storage.ImportConfigs(importTestConfigs(), ImportModeError)
+	if err == nil {
+		t.Fatalf("Expected conflict error, got result %+v", result)
+	}
+
+	conflictErr, ok := err.(*ImportConflictError)
+	if !ok {
+		t.Fatalf("Expected *ImportConflictError, got %T", err)
+	}
+	if len(conflictErr.Names) != 1 || conflictErr.Names[0] != "shared" {
+		t.Errorf("Expected conflict on 'shared', got %v", conflictErr.Names)
+	}
+	if !strings.Contains(err.Error(), "shared") {
+		t.Errorf("Expected error message to list conflicts, got %q", err.Error())
+	}
+
+	// 中止后存储不应发生任何变化
+	if stats := storage.GetStats(); stats.TotalConfigs != 1 {
+		t.Errorf("Expected no configs imported on abort, got %d configs", stats.TotalConfigs)
+	}
+	kept, _ := storage.GetByID(existing.ID)
+	if kept.TargetURL != "https://example.com" {
+		t.Errorf("Expected existing config untouched, got target %s", kept.TargetURL)
+	}
+}
+
+func TestMemoryStorage_ImportInvalidMode(t *testing.T) {
+	storage, _ := createPopulatedImportStorage(t)
+
+	if _, err := storage.ImportConfigs(importTestConfigs(), "merge"); err == nil {
+		t.Error("Expected error for invalid import mode")
+	}
+}
+
+func TestValidateConfig_LogBodies(t *testing.T) {
+	for _, mode := range []LogBodiesMode{"", LogBodiesInherit, LogBodiesNever, LogBodiesAlways} {
+		config := newEvictionTestConfig("log-bodies")
+		config.LogBodies = mode
+		if err := ValidateConfig(config); err != nil {
+			t.Errorf("Expected log_bodies %q to be valid, got %v", mode, err)
+		}
+	}
+
+	config := newEvictionTestConfig("log-bodies")
+	config.LogBodies = "sometimes"
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "log_bodies") {
+		t.Errorf("Expected log_bodies validation error, got %v", err)
+	}
+}
+
+func TestMemoryStorage_TagsSurviveExportImport(t *testing.T) {
+	source := NewMemoryStorage(10)
+	config := newEvictionTestConfig("tagged")
+	config.Tags = map[string]string{"team": "payments", "env": "prod"}
+	if err := source.Add(config); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	exported, err := source.ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll() error = %v", err)
+	}
+
+	// 经过YAML格式往返，确保标签在两种格式中都能保留
+	data, err := MarshalFormat(exported, FormatYAML)
+	if err != nil {
+		t.Fatalf("MarshalFormat() error = %v", err)
+	}
+	var decoded ExportData
+	if err := UnmarshalFormat(data, FormatYAML, &decoded); err != nil {
+		t.Fatalf("UnmarshalFormat() error = %v", err)
+	}
+
+	target := NewMemoryStorage(10)
+	if _, err := target.ImportConfigs(decoded.Configs, ImportModeError); err != nil {
+		t.Fatalf("ImportConfigs() error = %v", err)
+	}
+
+	response, err := target.List(&ConfigFilter{})
+	if err != nil || len(response.Configs) != 1 {
+		t.Fatalf("Expected 1 imported config, got %v (err %v)", response, err)
+	}
+	if tags := response.Configs[0].Tags; tags["team"] != "payments" || tags["env"] != "prod" {
+		t.Errorf("Expected tags to be preserved, got %v", tags)
+	}
+}
+
+func TestMemoryStorage_ListFiltersByTag(t *testing.T) {
+	storage := NewMemoryStorage(10)
+	prod := newEvictionTestConfig("prod")
+	prod.Tags = map[string]string{"env": "prod", "team": "payments"}
+	dev := newEvictionTestConfig("dev")
+	dev.Tags = map[string]string{"env": "dev"}
+	for _, config := range []*ProxyConfig{prod, dev, newEvictionTestConfig("untagged")} {
+		if err := storage.Add(config); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		tags []string
+		want int
+	}{
+		{nil, 3},
+		{[]string{"env:prod"}, 1},
+		{[]string{"env"}, 2},
+		{[]string{"env:dev", "team"}, 0},
+		{[]string{"team:payments", "env:prod"}, 1},
+	}
+	for _, tt := range tests {
+		response, err := storage.List(&ConfigFilter{Tags: tt.tags})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if response.Total != tt.want {
+			t.Errorf("List(tags=%v) total = %d, want %d", tt.tags, response.Total, tt.want)
+		}
+	}
+}
+
+func TestValidateConfig_Tags(t *testing.T) {
+	config := newEvictionTestConfig("tags")
+	config.Tags = map[string]string{"env:prod": "x"}
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("Expected validation error for tag key containing ':'")
+	}
+	if !strings.Contains(err.Error(), "tags") {
+		t.Errorf("Expected error on tags field, got %v", err)
+	}
+}
+
+func TestMemoryStorage_UpdateVersionCheck(t *testing.T) {
+	storage := NewMemoryStorage(10)
+	config := newEvictionTestConfig("versioned")
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if config.Version != 1 {
+		t.Fatalf("Expected new config to start at version 1, got %d", config.Version)
+	}
+
+	// 两个管理员读取到同一版本
+	first := *config
+	second := *config
+
+	first.TargetURL = "https://first.example.com"
+	if err := storage.Update(config.ID, &first); err != nil {
+		t.Fatalf("Update() with current version error = %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("Expected version to be incremented to 2, got %d", first.Version)
+	}
+
+	// 基于旧版本的修改被拒绝，不覆盖先提交的修改
+	second.TargetURL = "https://second.example.com"
+	if err := storage.Update(config.ID, &second); err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict for stale update, got %v", err)
+	}
+	current, _ := storage.GetByID(config.ID)
+	if current.TargetURL != "https://first.example.com" || current.Version != 2 {
+		t.Errorf("Expected first update to be kept, got %s (version %d)", current.TargetURL, current.Version)
+	}
+
+	missing := *current
+	missing.Version = 0
+	if err := storage.Update(config.ID, &missing); err != ErrVersionRequired {
+		t.Errorf("Expected ErrVersionRequired without version, got %v", err)
+	}
+}
+
+func TestValidateConfig_UserAgentOverride(t *testing.T) {
+	config := newEvictionTestConfig("ua")
+	config.UserAgentOverride = "PrivacyGateway/1.0"
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected valid user agent, got %v", err)
+	}
+
+	for _, ua := range []string{"bad\r\nX-Injected: 1", strings.Repeat("a", MaxUserAgentLength+1)} {
+		config.UserAgentOverride = ua
+		if err := ValidateConfig(config); err == nil {
+			t.Errorf("Expected user agent %q to be rejected", ua)
+		}
+	}
+}
+
+func TestValidateConfig_ResponseBodyRewrites(t *testing.T) {
+	config := newEvictionTestConfig("rewrite")
+	config.ResponseBodyRewrites = []BodyRewrite{
+		{Find: "internal.example", Replace: "gateway.example"},
+		{Find: `cdn(\d+)\.example`, Replace: "static$1.example", Regex: true},
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected valid rewrites, got %v", err)
+	}
+
+	for _, rule := range []BodyRewrite{{Find: "", Replace: "x"}, {Find: "([a-z", Regex: true}} {
+		config.ResponseBodyRewrites = []BodyRewrite{rule}
+		if err := ValidateConfig(config); err == nil {
+			t.Errorf("Expected rewrite %+v to be rejected", rule)
+		}
+	}
+}
+
+func TestValidateConfig_ConnectionPool(t *testing.T) {
+	config := newEvictionTestConfig("pool")
+	config.MaxIdleConnsPerHost = 64
+	config.IdleConnTimeout = 120
+	config.DisableKeepAlives = true
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected valid pool settings, got %v", err)
+	}
+
+	config.MaxIdleConnsPerHost = MaxIdleConnsPerHostLimit + 1
+	if err := ValidateConfig(config); err == nil {
+		t.Error("Expected max_idle_conns_per_host above limit to be rejected")
+	}
+
+	config.MaxIdleConnsPerHost = 0
+	config.IdleConnTimeout = -1
+	if err := ValidateConfig(config); err == nil {
+		t.Error("Expected negative idle_conn_timeout to be rejected")
+	}
+}
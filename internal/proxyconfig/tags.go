@@ -0,0 +1,63 @@
+package proxyconfig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 标签限制（配置和令牌共用）
+const (
+	MaxTags           = 20  // 最多标签数
+	MaxTagKeyLength   = 64  // 标签键最大长度
+	MaxTagValueLength = 256 // 标签值最大长度
+)
+
+// ErrInvalidTags 标签不合法
+var ErrInvalidTags = errors.New("invalid tags")
+
+// ValidateTags 验证标签：数量和键值长度受限，键不能为空且不能包含冒号（筛选时用作键值分隔符）
+func ValidateTags(tags map[string]string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("%w: at most %d tags allowed", ErrInvalidTags, MaxTags)
+	}
+	for key, value := range tags {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("%w: tag key must not be empty", ErrInvalidTags)
+		}
+		if len(key) > MaxTagKeyLength {
+			return fmt.Errorf("%w: tag key %q exceeds %d characters", ErrInvalidTags, key, MaxTagKeyLength)
+		}
+		if strings.Contains(key, ":") {
+			return fmt.Errorf("%w: tag key %q must not contain ':'", ErrInvalidTags, key)
+		}
+		if len(value) > MaxTagValueLength {
+			return fmt.Errorf("%w: value of tag %q exceeds %d characters", ErrInvalidTags, key, MaxTagValueLength)
+		}
+	}
+	return nil
+}
+
+// MatchesTags 检查标签是否满足全部筛选条件，条件为 "key:value"（精确匹配）或 "key"（存在该标签即可）
+func MatchesTags(tags map[string]string, filters []string) bool {
+	for _, filter := range filters {
+		key, value, hasValue := strings.Cut(filter, ":")
+		actual, ok := tags[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// copyTags 复制标签，避免副本之间共享同一个map；空标签返回nil
+func copyTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,124 @@
+package proxyconfig
+
+import (
+	"fmt"
+	"time"
+)
+
+// 导出时令牌哈希的处理方式
+const (
+	TokenHashesExclude  = "exclude"  // 默认：只导出令牌元数据，不含哈希（导入后需重新签发）
+	TokenHashesPreserve = "preserve" // 保留哈希，用于同一服务器的备份恢复（令牌值导入后仍然可用）
+)
+
+// 导入时令牌的处理方式
+const (
+	TokenImportKeep    = "keep"    // 默认：保留带哈希的令牌，丢弃不含哈希的令牌
+	TokenImportReissue = "reissue" // 为导入的令牌重新签发令牌值，新值在导入结果中返回
+)
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Mode      string // 配置冲突处理方式：skip、replace、error（默认）
+	TokenMode string // 令牌处理方式：keep（默认）、reissue
+}
+
+// NormalizeTokenHashesMode 校验导出时的令牌哈希处理方式，空值视为exclude
+func NormalizeTokenHashesMode(mode string) (string, error) {
+	switch mode {
+	case "", TokenHashesExclude:
+		return TokenHashesExclude, nil
+	case TokenHashesPreserve:
+		return TokenHashesPreserve, nil
+	default:
+		return "", fmt.Errorf("invalid token_hashes: %s. Must be: exclude or preserve", mode)
+	}
+}
+
+// StripTokenHashes 移除导出数据中的令牌哈希和令牌值，令牌列表重新分配，不影响存储中的令牌
+func (e *ExportData) StripTokenHashes() {
+	for i := range e.Configs {
+		if len(e.Configs[i].AccessTokens) == 0 {
+			continue
+		}
+		tokens := make([]AccessToken, len(e.Configs[i].AccessTokens))
+		copy(tokens, e.Configs[i].AccessTokens)
+		for j := range tokens {
+			tokens[j].TokenHash = ""
+			tokens[j].TokenValue = ""
+			tokens[j].LookupID = ""
+		}
+		e.Configs[i].AccessTokens = tokens
+	}
+}
+
+// StripTokenValues 移除导出数据中的令牌值，保留令牌哈希和查找标识（preserve模式），令牌列表重新分配，不影响存储中的令牌
+//
+// SHA-256令牌在存储中保存了令牌值用于复制，备份导出中不能包含明文令牌。
+func (e *ExportData) StripTokenValues() {
+	for i := range e.Configs {
+		if len(e.Configs[i].AccessTokens) == 0 {
+			continue
+		}
+		tokens := make([]AccessToken, len(e.Configs[i].AccessTokens))
+		copy(tokens, e.Configs[i].AccessTokens)
+		for j := range tokens {
+			tokens[j].TokenValue = ""
+		}
+		e.Configs[i].AccessTokens = tokens
+	}
+}
+
+// prepareImportedTokens 处理新导入配置的令牌
+//
+// reissue模式下为每个令牌生成新的令牌值并返回令牌名称到新值的映射（名称重复时以令牌ID为键）；
+// 其余模式丢弃不含哈希的令牌（无法用于认证），返回丢弃的数量。
+func prepareImportedTokens(config *ProxyConfig, tokenMode string) (map[string]string, int, error) {
+	if len(config.AccessTokens) == 0 {
+		return nil, 0, nil
+	}
+
+	tokens := make([]AccessToken, 0, len(config.AccessTokens))
+	if tokenMode != TokenImportReissue {
+		for _, token := range config.AccessTokens {
+			if token.TokenHash != "" {
+				token.LookupID = token.lookupKey()
+				token.TokenValue = ""
+				tokens = append(tokens, token)
+			}
+		}
+		dropped := len(config.AccessTokens) - len(tokens)
+		config.AccessTokens = tokens
+		if dropped > 0 {
+			config.TokenStats = CalculateTokenStats(tokens)
+		}
+		return nil, dropped, nil
+	}
+
+	issued := make(map[string]string, len(config.AccessTokens))
+	now := time.Now()
+	for _, token := range config.AccessTokens {
+		value, err := GenerateToken()
+		if err != nil {
+			return nil, 0, err
+		}
+		hash, err := HashTokenWithScheme(value, DefaultHashScheme())
+		if err != nil {
+			return nil, 0, err
+		}
+		token.TokenHash = hash
+		token.TokenValue = ""
+		token.LookupID = tokenLookupIDFor(value, hash)
+		token.UpdatedAt = now
+		tokens = append(tokens, token)
+
+		key := token.Name
+		if _, exists := issued[key]; exists || key == "" {
+			key = token.ID
+		}
+		issued[key] = value
+	}
+	config.AccessTokens = tokens
+	config.TokenStats = CalculateTokenStats(tokens)
+	return issued, 0, nil
+}
@@ -0,0 +1,145 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newTokenExportSource 创建带一个令牌的存储，返回存储、配置和令牌值
+func newTokenExportSource(t *testing.T) (*MemoryStorage, *ProxyConfig, string) {
+	t.Helper()
+	storage := NewMemoryStorage(10)
+	config := newEvictionTestConfig("exported")
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	token, value, err := CreateAccessToken(&TokenCreateRequest{Name: "ci"}, "admin")
+	if err != nil {
+		t.Fatalf("CreateAccessToken() error = %v", err)
+	}
+	if err := storage.AddToken(config.ID, token); err != nil {
+		t.Fatalf("AddToken() error = %v", err)
+	}
+	return storage, config, value
+}
+
+// importedConfigID 返回目标存储中唯一配置的ID
+func importedConfigID(t *testing.T, storage *MemoryStorage) string {
+	t.Helper()
+	response, err := storage.List(&ConfigFilter{})
+	if err != nil || len(response.Configs) != 1 {
+		t.Fatalf("Expected 1 imported config, got %v (err %v)", response, err)
+	}
+	return response.Configs[0].ID
+}
+
+func TestExportData_StripTokenHashes(t *testing.T) {
+	source, config, _ := newTokenExportSource(t)
+
+	exported, err := source.ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll() error = %v", err)
+	}
+	exported.StripTokenHashes()
+
+	if token := exported.Configs[0].AccessTokens[0]; token.TokenHash != "" || token.Name != "ci" {
+		t.Errorf("Expected token metadata without hash, got %+v", token)
+	}
+	// 移除导出数据中的哈希不影响存储中的令牌
+	tokens, _ := source.GetTokens(config.ID)
+	if len(tokens) != 1 || tokens[0].TokenHash == "" {
+		t.Errorf("Expected stored token hash to be untouched, got %+v", tokens)
+	}
+}
+
+func TestExportData_StripTokenValues(t *testing.T) {
+	source, config, value := newTokenExportSource(t)
+
+	exported, err := source.ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll() error = %v", err)
+	}
+	exported.StripTokenValues()
+
+	// preserve导出保留哈希，但不包含明文令牌值
+	data, _ := json.Marshal(exported)
+	if strings.Contains(string(data), "token_value") || strings.Contains(string(data), value) {
+		t.Errorf("Expected preserve export not to contain token values, got %s", data)
+	}
+	if token := exported.Configs[0].AccessTokens[0]; token.TokenHash == "" {
+		t.Errorf("Expected token hash to be preserved, got %+v", token)
+	}
+	if tokens, _ := source.GetTokens(config.ID); len(tokens) != 1 || tokens[0].TokenValue != value {
+		t.Errorf("Expected stored token value to be untouched, got %+v", tokens)
+	}
+}
+
+func TestImportConfigsWithOptions_ReissueTokens(t *testing.T) {
+	source, config, oldValue := newTokenExportSource(t)
+	exported, _ := source.ExportAll()
+	exported.StripTokenHashes()
+
+	target := NewMemoryStorage(10)
+	result, err := target.ImportConfigsWithOptions(exported.Configs, ImportOptions{TokenMode: TokenImportReissue})
+	if err != nil {
+		t.Fatalf("ImportConfigsWithOptions() error = %v", err)
+	}
+
+	newValue := result.ReissuedTokens[config.Name]["ci"]
+	if newValue == "" || newValue == oldValue {
+		t.Fatalf("Expected a fresh token value for ci, got %v", result.ReissuedTokens)
+	}
+
+	configID := importedConfigID(t, target)
+	if validation, _ := target.ValidateToken(configID, newValue); !validation.Valid {
+		t.Errorf("Expected reissued token to be valid, got %+v", validation)
+	}
+	if validation, _ := target.ValidateToken(configID, oldValue); validation.Valid {
+		t.Error("Expected old token value to be rejected after reissue")
+	}
+	if tokens, _ := target.GetTokens(configID); len(tokens) != 1 || tokens[0].TokenValue != "" {
+		t.Errorf("Expected token value not to be stored, got %+v", tokens)
+	}
+}
+
+func TestImportConfigsWithOptions_PreserveHashes(t *testing.T) {
+	source, _, value := newTokenExportSource(t)
+	exported, _ := source.ExportAll()
+
+	target := NewMemoryStorage(10)
+	result, err := target.ImportConfigsWithOptions(exported.Configs, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportConfigsWithOptions() error = %v", err)
+	}
+	if len(result.ReissuedTokens) != 0 || result.DroppedTokens != 0 {
+		t.Errorf("Expected tokens to be kept as-is, got %+v", result)
+	}
+
+	// 保留哈希时原令牌值在恢复后仍然可用
+	if validation, _ := target.ValidateToken(importedConfigID(t, target), value); !validation.Valid {
+		t.Errorf("Expected preserved token to remain valid, got %+v", validation)
+	}
+}
+
+func TestImportConfigsWithOptions_DropsTokensWithoutHash(t *testing.T) {
+	source, _, _ := newTokenExportSource(t)
+	exported, _ := source.ExportAll()
+	exported.StripTokenHashes()
+
+	target := NewMemoryStorage(10)
+	result, err := target.ImportConfigsWithOptions(exported.Configs, ImportOptions{TokenMode: TokenImportKeep})
+	if err != nil {
+		t.Fatalf("ImportConfigsWithOptions() error = %v", err)
+	}
+	if result.ImportedCount != 1 || result.DroppedTokens != 1 {
+		t.Errorf("Expected config imported with its unhashed token dropped, got %+v", result)
+	}
+	if tokens, _ := target.GetTokens(importedConfigID(t, target)); len(tokens) != 0 {
+		t.Errorf("Expected no tokens after import, got %d", len(tokens))
+	}
+
+	if _, err := target.ImportConfigsWithOptions(exported.Configs, ImportOptions{TokenMode: "rotate"}); err == nil {
+		t.Error("Expected invalid token mode to be rejected")
+	}
+}
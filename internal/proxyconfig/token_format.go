@@ -0,0 +1,115 @@
+package proxyconfig
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// TokenCharset 新令牌使用的字符集
+type TokenCharset string
+
+// 令牌字符集常量
+const (
+	TokenCharsetBase64URL TokenCharset = "base64url" // A-Z a-z 0-9 - _（默认）
+	TokenCharsetBase62    TokenCharset = "base62"    // A-Z a-z 0-9
+	TokenCharsetHex       TokenCharset = "hex"       // 0-9 a-f
+)
+
+// 可配置的令牌长度范围
+const (
+	MinTokenLength = 32
+	MaxTokenLength = 256
+)
+
+// tokenAlphabets 各字符集的字符表
+var tokenAlphabets = map[TokenCharset]string{
+	TokenCharsetBase64URL: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_",
+	TokenCharsetBase62:    "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	TokenCharsetHex:       "0123456789abcdef",
+}
+
+var (
+	// tokenLength 新令牌长度，0表示沿用旧格式（32字节随机数的Base64 URL编码）
+	tokenLength      int
+	tokenCharset     = TokenCharsetBase64URL
+	tokenFormatMutex sync.RWMutex
+)
+
+// ParseTokenCharset 解析令牌字符集，为空时返回默认的base64url
+func ParseTokenCharset(charset string) (TokenCharset, error) {
+	value := TokenCharset(strings.ToLower(strings.TrimSpace(charset)))
+	if value == "" {
+		return TokenCharsetBase64URL, nil
+	}
+	if _, ok := tokenAlphabets[value]; !ok {
+		return "", fmt.Errorf("unsupported token charset: %s", charset)
+	}
+	return value, nil
+}
+
+// SetTokenFormat 设置新令牌的长度和字符集（已有令牌不受影响），length为0时使用旧格式
+func SetTokenFormat(length int, charset TokenCharset) error {
+	if length != 0 && (length < MinTokenLength || length > MaxTokenLength) {
+		return fmt.Errorf("token length must be between %d and %d", MinTokenLength, MaxTokenLength)
+	}
+	if _, ok := tokenAlphabets[charset]; !ok {
+		return fmt.Errorf("unsupported token charset: %s", charset)
+	}
+
+	tokenFormatMutex.Lock()
+	defer tokenFormatMutex.Unlock()
+	tokenLength = length
+	tokenCharset = charset
+	return nil
+}
+
+// TokenFormat 返回新令牌的长度和字符集
+func TokenFormat() (int, TokenCharset) {
+	tokenFormatMutex.RLock()
+	defer tokenFormatMutex.RUnlock()
+	return tokenLength, tokenCharset
+}
+
+// generateTokenWithFormat 从字符集中均匀随机选取字符生成指定长度的令牌
+func generateTokenWithFormat(length int, charset TokenCharset) (string, error) {
+	alphabet := tokenAlphabets[charset]
+	max := big.NewInt(int64(len(alphabet)))
+
+	var builder strings.Builder
+	builder.Grow(length)
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random token: %w", err)
+		}
+		builder.WriteByte(alphabet[n.Int64()])
+	}
+	return builder.String(), nil
+}
+
+// matchesTokenFormat 检查令牌是否符合指定的长度和字符集
+func matchesTokenFormat(token string, length int, charset TokenCharset) bool {
+	if len(token) != length {
+		return false
+	}
+	alphabet := tokenAlphabets[charset]
+	for i := 0; i < len(token); i++ {
+		if strings.IndexByte(alphabet, token[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isLegacyTokenFormat 检查令牌是否为旧格式（Base64 URL编码，长度32~TokenLength）
+func isLegacyTokenFormat(token string) bool {
+	if len(token) < 32 || len(token) > TokenLength {
+		return false
+	}
+	_, err := base64.URLEncoding.DecodeString(token)
+	return err == nil
+}
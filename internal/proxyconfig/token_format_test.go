@@ -0,0 +1,98 @@
+package proxyconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateToken_CustomFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		length   int
+		charset  TokenCharset
+		alphabet string
+	}{
+		{"base62", 48, TokenCharsetBase62, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"},
+		{"hex", 96, TokenCharsetHex, "0123456789abcdef"},
+		{"base64url", 128, TokenCharsetBase64URL, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetTokenFormat(tt.length, tt.charset); err != nil {
+				t.Fatalf("SetTokenFormat() error = %v", err)
+			}
+			defer SetTokenFormat(0, TokenCharsetBase64URL)
+
+			token, err := GenerateToken()
+			if err != nil {
+				t.Fatalf("GenerateToken() error = %v", err)
+			}
+			if len(token) != tt.length {
+				t.Errorf("Token length = %d, want %d", len(token), tt.length)
+			}
+			for _, c := range token {
+				if !strings.ContainsRune(tt.alphabet, c) {
+					t.Fatalf("Token %q contains character %q outside %s charset", token, c, tt.charset)
+				}
+			}
+			if !IsValidTokenFormat(token) {
+				t.Error("Generated token should have valid format")
+			}
+
+			// 长度不符的令牌无效
+			if IsValidTokenFormat(token + token[:1]) {
+				t.Error("Token with wrong length should be invalid")
+			}
+		})
+	}
+}
+
+func TestIsValidTokenFormat_CustomFormatKeepsLegacyTokens(t *testing.T) {
+	// 切换格式前发放的令牌
+	legacy, err := GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	if err := SetTokenFormat(40, TokenCharsetHex); err != nil {
+		t.Fatalf("SetTokenFormat() error = %v", err)
+	}
+	defer SetTokenFormat(0, TokenCharsetBase64URL)
+
+	if !IsValidTokenFormat(legacy) {
+		t.Error("Legacy token should remain valid after changing token format")
+	}
+	// 字符集之外的字符
+	if IsValidTokenFormat(strings.Repeat(".", 40)) {
+		t.Error("Token with characters outside the charset should be invalid")
+	}
+	if !IsValidTokenFormat(strings.Repeat("a", 40)) {
+		t.Error("Token matching configured length and charset should be valid")
+	}
+}
+
+func TestSetTokenFormat_Invalid(t *testing.T) {
+	if err := SetTokenFormat(MinTokenLength-1, TokenCharsetBase62); err == nil {
+		t.Error("Expected error for token length below minimum")
+	}
+	if err := SetTokenFormat(MaxTokenLength+1, TokenCharsetBase62); err == nil {
+		t.Error("Expected error for token length above maximum")
+	}
+	if err := SetTokenFormat(64, TokenCharset("base32")); err == nil {
+		t.Error("Expected error for unsupported charset")
+	}
+	if length, charset := TokenFormat(); length != 0 || charset != TokenCharsetBase64URL {
+		t.Errorf("Invalid settings should not change token format, got %d/%s", length, charset)
+	}
+
+	if charset, err := ParseTokenCharset(" HEX "); err != nil || charset != TokenCharsetHex {
+		t.Errorf("ParseTokenCharset(\" HEX \") = %s, %v", charset, err)
+	}
+	if charset, err := ParseTokenCharset(""); err != nil || charset != TokenCharsetBase64URL {
+		t.Errorf("ParseTokenCharset(\"\") = %s, %v", charset, err)
+	}
+	if _, err := ParseTokenCharset("base32"); err == nil {
+		t.Error("Expected error for unsupported charset")
+	}
+}
@@ -0,0 +1,170 @@
+package proxyconfig
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// HashScheme 令牌哈希方案
+type HashScheme string
+
+// 哈希方案常量
+const (
+	HashSchemeSHA256   HashScheme = "sha256"   // SHA-256（默认，存储为不带前缀的十六进制）
+	HashSchemeArgon2id HashScheme = "argon2id" // Argon2id（PHC格式，以 $argon2id$ 开头）
+)
+
+// Argon2id参数（参考OWASP推荐的最低配置）
+const (
+	argon2idMemory  uint32 = 19 * 1024 // KiB
+	argon2idTime    uint32 = 2
+	argon2idThreads uint8  = 1
+	argon2idKeyLen  uint32 = 32
+	argon2idSaltLen        = 16
+	argon2idPrefix         = "$argon2id$"
+)
+
+// lookupIDLength 加盐哈希令牌查找标识的长度（令牌SHA-256十六进制的前16位，即64位）
+const lookupIDLength = 16
+
+var (
+	defaultHashScheme      = HashSchemeSHA256
+	defaultHashSchemeMutex sync.RWMutex
+
+	// verifiedHashes 缓存已通过慢哈希验证的令牌：存储的哈希 -> 令牌的SHA-256
+	// 仅保存在内存中，避免每次代理请求都重新计算Argon2id
+	verifiedHashes sync.Map
+
+	// argon2idVerifications 已执行的Argon2id验证次数，用于观察令牌查找的开销
+	argon2idVerifications atomic.Int64
+)
+
+// ParseHashScheme 解析哈希方案字符串，无法识别时返回错误
+func ParseHashScheme(scheme string) (HashScheme, error) {
+	switch HashScheme(strings.ToLower(strings.TrimSpace(scheme))) {
+	case "", HashSchemeSHA256:
+		return HashSchemeSHA256, nil
+	case HashSchemeArgon2id:
+		return HashSchemeArgon2id, nil
+	default:
+		return "", fmt.Errorf("unsupported token hash scheme: %s", scheme)
+	}
+}
+
+// SetDefaultHashScheme 设置新令牌使用的哈希方案（已有令牌不受影响）
+func SetDefaultHashScheme(scheme HashScheme) {
+	defaultHashSchemeMutex.Lock()
+	defer defaultHashSchemeMutex.Unlock()
+	defaultHashScheme = scheme
+}
+
+// DefaultHashScheme 返回新令牌使用的哈希方案
+func DefaultHashScheme() HashScheme {
+	defaultHashSchemeMutex.RLock()
+	defer defaultHashSchemeMutex.RUnlock()
+	return defaultHashScheme
+}
+
+// DetectHashScheme 根据存储的哈希值识别哈希方案
+func DetectHashScheme(storedHash string) HashScheme {
+	if strings.HasPrefix(storedHash, argon2idPrefix) {
+		return HashSchemeArgon2id
+	}
+	return HashSchemeSHA256
+}
+
+// HashTokenWithScheme 使用指定方案计算令牌哈希
+func HashTokenWithScheme(token string, scheme HashScheme) (string, error) {
+	switch scheme {
+	case HashSchemeSHA256:
+		return HashToken(token), nil
+	case HashSchemeArgon2id:
+		return hashTokenArgon2id(token)
+	default:
+		return "", fmt.Errorf("unsupported token hash scheme: %s", scheme)
+	}
+}
+
+// TokenLookupID 计算令牌的查找标识
+//
+// 加盐哈希无法由明文直接得出存储值，存储中按该标识定位令牌，
+// 每次查找最多执行一次Argon2id验证，未知令牌不触发验证。
+// 标识只是SHA-256的前64位，令牌至少32位随机字符，公开标识不会降低令牌强度。
+func TokenLookupID(token string) string {
+	return HashToken(token)[:lookupIDLength]
+}
+
+// tokenLookupIDFor 返回存储哈希对应的查找标识，SHA-256哈希可直接索引，不需要标识
+func tokenLookupIDFor(token, storedHash string) string {
+	if DetectHashScheme(storedHash) == HashSchemeSHA256 {
+		return ""
+	}
+	return TokenLookupID(token)
+}
+
+// lookupKey 返回令牌用于加盐哈希索引的查找标识
+//
+// SHA-256令牌返回空字符串。早期创建的加盐哈希令牌没有LookupID，存有令牌值时由令牌值计算；
+// 两者都没有的无法通过令牌值查找，需要重新签发。
+func (t *AccessToken) lookupKey() string {
+	if DetectHashScheme(t.TokenHash) == HashSchemeSHA256 || t.LookupID != "" || t.TokenValue == "" {
+		return t.LookupID
+	}
+	return TokenLookupID(t.TokenValue)
+}
+
+// hashTokenArgon2id 使用随机盐计算Argon2id哈希，输出PHC格式字符串
+func hashTokenArgon2id(token string) (string, error) {
+	salt := make([]byte, argon2idSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(token), salt, argon2idTime, argon2idMemory, argon2idThreads, argon2idKeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, argon2idMemory, argon2idTime, argon2idThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyTokenArgon2id 按存储的参数重新计算Argon2id并比较
+func verifyTokenArgon2id(token, storedHash string) bool {
+	argon2idVerifications.Add(1)
+
+	// 格式: $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+	parts := strings.Split(storedHash, "$")
+	if len(parts) != 6 {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	computed := argon2.IDKey([]byte(token), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1
+}
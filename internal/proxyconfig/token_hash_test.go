@@ -0,0 +1,232 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"privacygateway/internal/logger"
+)
+
+func TestParseHashScheme(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    HashScheme
+		wantErr bool
+	}{
+		{"", HashSchemeSHA256, false},
+		{"sha256", HashSchemeSHA256, false},
+		{" Argon2id ", HashSchemeArgon2id, false},
+		{"bcrypt", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseHashScheme(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHashScheme(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseHashScheme(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHashTokenWithScheme_Argon2id(t *testing.T) {
+	token := "test-token-123"
+
+	hash1, err := HashTokenWithScheme(token, HashSchemeArgon2id)
+	if err != nil {
+		t.Fatalf("HashTokenWithScheme() error = %v", err)
+	}
+	hash2, _ := HashTokenWithScheme(token, HashSchemeArgon2id)
+
+	if !strings.HasPrefix(hash1, "$argon2id$") {
+		t.Errorf("Expected PHC argon2id prefix, got %s", hash1)
+	}
+	if DetectHashScheme(hash1) != HashSchemeArgon2id {
+		t.Errorf("Expected scheme to be detected as argon2id")
+	}
+
+	// 随机盐：相同令牌的两次哈希不同
+	if hash1 == hash2 {
+		t.Error("Argon2id hashes should be salted")
+	}
+
+	if !VerifyToken(token, hash1) || !VerifyToken(token, hash2) {
+		t.Error("Valid token should verify against argon2id hash")
+	}
+	if VerifyToken("wrong-token", hash1) {
+		t.Error("Invalid token should fail argon2id verification")
+	}
+
+	// 缓存命中后仍需拒绝错误令牌
+	if VerifyToken("wrong-token", hash1) {
+		t.Error("Invalid token should fail verification after cache warm-up")
+	}
+}
+
+func TestVerifyToken_MalformedArgon2id(t *testing.T) {
+	for _, hash := range []string{"$argon2id$", "$argon2id$v=19$m=1,t=1,p=1$!!$!!", "$argon2id$v=18$m=1,t=1,p=1$c2FsdA$a2V5"} {
+		if VerifyToken("token", hash) {
+			t.Errorf("Malformed hash %q should not verify", hash)
+		}
+	}
+}
+
+func TestMemoryStorage_MixedHashSchemes(t *testing.T) {
+	SetDefaultHashScheme(HashSchemeArgon2id)
+	defer SetDefaultHashScheme(HashSchemeSHA256)
+
+	storage := NewMemoryStorage(10)
+	config := &ProxyConfig{Name: "mixed", TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Failed to add config: %v", err)
+	}
+
+	// 新令牌使用默认方案（argon2id）
+	newToken, newValue, err := CreateAccessToken(&TokenCreateRequest{Name: "new"}, "admin")
+	if err != nil {
+		t.Fatalf("CreateAccessToken() error = %v", err)
+	}
+	if DetectHashScheme(newToken.TokenHash) != HashSchemeArgon2id {
+		t.Fatalf("Expected new token to use argon2id, got %s", newToken.TokenHash)
+	}
+
+	// 旧令牌仍为SHA-256
+	legacyValue := "legacy-token-value"
+	legacyToken := &AccessToken{ID: "legacy", Name: "legacy", TokenHash: HashToken(legacyValue), Enabled: true}
+
+	storage.AddToken(config.ID, newToken)
+	storage.AddToken(config.ID, legacyToken)
+
+	for name, value := range map[string]string{"argon2id": newValue, "sha256": legacyValue} {
+		result, err := storage.ValidateToken(config.ID, value)
+		if err != nil || !result.Valid {
+			t.Errorf("Expected %s token to validate, got %+v (err=%v)", name, result, err)
+		}
+
+		configID, err := storage.FindConfigByToken(value)
+		if err != nil || configID != config.ID {
+			t.Errorf("Expected %s token to resolve to config %s, got %s (err=%v)", name, config.ID, configID, err)
+		}
+	}
+
+	if result, _ := storage.ValidateToken(config.ID, "unknown"); result.Valid {
+		t.Error("Unknown token should not validate")
+	}
+}
+
+func TestMemoryStorage_SaltedTokenLookupCost(t *testing.T) {
+	SetDefaultHashScheme(HashSchemeArgon2id)
+	defer SetDefaultHashScheme(HashSchemeSHA256)
+
+	storage := NewMemoryStorage(10)
+	var values []string
+	for i := 0; i < 3; i++ {
+		config := &ProxyConfig{Name: fmt.Sprintf("salted-%d", i), TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+		storage.Add(config)
+		for j := 0; j < 3; j++ {
+			token, value, err := CreateAccessToken(&TokenCreateRequest{Name: fmt.Sprintf("t%d", j)}, "admin")
+			if err != nil {
+				t.Fatalf("CreateAccessToken() error = %v", err)
+			}
+			if token.LookupID != TokenLookupID(value) {
+				t.Fatalf("Expected lookup ID %s, got %s", TokenLookupID(value), token.LookupID)
+			}
+			storage.AddToken(config.ID, token)
+			values = append(values, value)
+		}
+	}
+
+	// 未知令牌不触发Argon2id验证
+	before := argon2idVerifications.Load()
+	for i := 0; i < 5; i++ {
+		if _, err := storage.FindConfigByToken(fmt.Sprintf("unknown-token-%d", i)); err != ErrTokenNotFound {
+			t.Errorf("Expected unknown token not to be found, got %v", err)
+		}
+	}
+	if count := argon2idVerifications.Load() - before; count != 0 {
+		t.Errorf("Expected no argon2id verifications for unknown tokens, got %d", count)
+	}
+
+	// 有效令牌只验证对应的一个令牌
+	before = argon2idVerifications.Load()
+	if _, err := storage.FindConfigByToken(values[len(values)-1]); err != nil {
+		t.Fatalf("Expected salted token to be found, got %v", err)
+	}
+	if count := argon2idVerifications.Load() - before; count != 1 {
+		t.Errorf("Expected exactly one argon2id verification, got %d", count)
+	}
+
+	// 早期创建的令牌没有查找标识时由保存的令牌值计算
+	legacyValue := "legacy-salted-token-value"
+	legacyHash, _ := HashTokenWithScheme(legacyValue, HashSchemeArgon2id)
+	legacy := &ProxyConfig{Name: "legacy", TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+	storage.Add(legacy)
+	storage.AddToken(legacy.ID, &AccessToken{ID: "legacy", Name: "legacy", TokenHash: legacyHash, TokenValue: legacyValue, Enabled: true})
+	if configID, err := storage.FindConfigByToken(legacyValue); err != nil || configID != legacy.ID {
+		t.Errorf("Expected legacy salted token to resolve to %s, got %q (err %v)", legacy.ID, configID, err)
+	}
+}
+
+func TestCreateAccessToken_SaltedTokenValueNotStored(t *testing.T) {
+	SetDefaultHashScheme(HashSchemeArgon2id)
+	defer SetDefaultHashScheme(HashSchemeSHA256)
+
+	token, value, err := CreateAccessToken(&TokenCreateRequest{Name: "salted"}, "admin")
+	if err != nil {
+		t.Fatalf("CreateAccessToken() error = %v", err)
+	}
+	if value == "" || token.TokenValue != "" {
+		t.Errorf("Expected value to be returned once and not stored, got stored %q", token.TokenValue)
+	}
+
+	// 早期保存的令牌值不出现在响应中
+	token.TokenValue = value
+	if sanitized := SanitizeTokenForResponse(token); sanitized.TokenValue != "" || sanitized.TokenHash != "" {
+		t.Errorf("Expected salted token value and hash to be removed, got %+v", sanitized)
+	}
+
+	// SHA-256令牌保留令牌值用于复制
+	SetDefaultHashScheme(HashSchemeSHA256)
+	shaToken, shaValue, _ := CreateAccessToken(&TokenCreateRequest{Name: "sha"}, "admin")
+	if sanitized := SanitizeTokenForResponse(shaToken); sanitized.TokenValue != shaValue {
+		t.Errorf("Expected sha256 token value to be kept, got %q", sanitized.TokenValue)
+	}
+}
+
+func TestPersistentStorage_ClearsStoredSaltedTokenValues(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+
+	// 早期版本保存的argon2id令牌带有令牌值，且没有查找标识
+	value := "legacy-salted-token-value"
+	hash, _ := HashTokenWithScheme(value, HashSchemeArgon2id)
+	config := newEvictionTestConfig("legacy-salted")
+	config.AccessTokens = []AccessToken{{ID: "legacy", Name: "legacy", TokenHash: hash, TokenValue: value, Enabled: true}}
+	data, _ := json.Marshal(map[string]*ProxyConfig{config.ID: config})
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, logger.New())
+	defer storage.Shutdown()
+
+	if configID, err := storage.FindConfigByToken(value); err != nil || configID != config.ID {
+		t.Errorf("Expected legacy token to keep working, got %q (err %v)", configID, err)
+	}
+	stored, _ := storage.GetTokenByID(config.ID, "legacy")
+	if stored == nil || stored.TokenValue != "" || stored.LookupID != TokenLookupID(value) {
+		t.Fatalf("Expected value to be replaced by lookup ID, got %+v", stored)
+	}
+
+	// 清除后的内容写回文件
+	if err := storage.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if saved, _ := os.ReadFile(filePath); strings.Contains(string(saved), value) {
+		t.Error("Expected saved file not to contain the token value")
+	}
+}
@@ -0,0 +1,113 @@
+package proxyconfig
+
+import "crypto/subtle"
+
+// tokenRef 令牌在存储中的位置
+type tokenRef struct {
+	configID   string
+	tokenIndex int
+}
+
+// tokenIndex 令牌反向索引
+//
+// SHA-256令牌的存储哈希可直接由明文计算，按哈希建立map实现O(1)查找；
+// Argon2id等加盐哈希无法由明文直接得出存储值，按查找标识（TokenLookupID）索引，
+// 查找时只验证标识相同的令牌，未知令牌不会触发慢哈希计算。
+// 索引的读写均由MemoryStorage.mutex保护。
+type tokenIndex struct {
+	byHash     map[string]tokenRef
+	byLookupID map[string][]tokenRef
+}
+
+// newTokenIndex 创建空的令牌索引
+func newTokenIndex() *tokenIndex {
+	return &tokenIndex{
+		byHash:     make(map[string]tokenRef),
+		byLookupID: make(map[string][]tokenRef),
+	}
+}
+
+// add 将配置的全部令牌加入索引（没有查找标识的加盐哈希令牌无法索引，跳过）
+func (idx *tokenIndex) add(config *ProxyConfig) {
+	for i := range config.AccessTokens {
+		token := &config.AccessTokens[i]
+		ref := tokenRef{configID: config.ID, tokenIndex: i}
+		if DetectHashScheme(token.TokenHash) == HashSchemeSHA256 {
+			idx.byHash[token.TokenHash] = ref
+		} else if lookupID := token.lookupKey(); lookupID != "" {
+			idx.byLookupID[lookupID] = append(idx.byLookupID[lookupID], ref)
+		}
+	}
+}
+
+// remove 从索引中移除配置的全部令牌（需在修改令牌列表之前调用）
+func (idx *tokenIndex) remove(config *ProxyConfig) {
+	for i := range config.AccessTokens {
+		token := &config.AccessTokens[i]
+		if ref, ok := idx.byHash[token.TokenHash]; ok && ref.configID == config.ID {
+			delete(idx.byHash, token.TokenHash)
+		}
+
+		lookupID := token.lookupKey()
+		refs, ok := idx.byLookupID[lookupID]
+		if !ok {
+			continue
+		}
+		kept := refs[:0]
+		for _, ref := range refs {
+			if ref.configID != config.ID {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.byLookupID, lookupID)
+		} else {
+			idx.byLookupID[lookupID] = kept
+		}
+	}
+}
+
+// rebuildTokenIndexLocked 根据全部配置重建令牌索引（需要持有写锁）
+func (s *MemoryStorage) rebuildTokenIndexLocked() {
+	s.tokens = newTokenIndex()
+	for _, config := range s.configs {
+		s.tokens.add(config)
+	}
+}
+
+// resolveTokenRefLocked 校验索引项并返回对应令牌（需要持有锁）
+func (s *MemoryStorage) resolveTokenRefLocked(ref tokenRef) *AccessToken {
+	config, exists := s.configs[ref.configID]
+	if !exists || ref.tokenIndex >= len(config.AccessTokens) {
+		return nil
+	}
+	return &config.AccessTokens[ref.tokenIndex]
+}
+
+// lookupTokenLocked 通过令牌值查找令牌位置（需要持有锁）
+//
+// configID非空时只匹配该配置下的令牌。SHA-256令牌通过哈希直接定位，
+// 加盐哈希的令牌先按查找标识定位，只验证标识相同的候选令牌（64位标识几乎不会冲突，
+// 通常最多一次Argon2id验证）。
+func (s *MemoryStorage) lookupTokenLocked(tokenValue, configID string) (tokenRef, bool) {
+	tokenHash := HashToken(tokenValue)
+
+	if ref, ok := s.tokens.byHash[tokenHash]; ok {
+		if token := s.resolveTokenRefLocked(ref); token != nil &&
+			subtle.ConstantTimeCompare([]byte(token.TokenHash), []byte(tokenHash)) == 1 &&
+			(configID == "" || ref.configID == configID) {
+			return ref, true
+		}
+	}
+
+	for _, ref := range s.tokens.byLookupID[tokenHash[:lookupIDLength]] {
+		if configID != "" && ref.configID != configID {
+			continue
+		}
+		if token := s.resolveTokenRefLocked(ref); token != nil && VerifyToken(tokenValue, token.TokenHash) {
+			return ref, true
+		}
+	}
+
+	return tokenRef{}, false
+}
@@ -0,0 +1,154 @@
+package proxyconfig
+
+import (
+	"fmt"
+	"testing"
+)
+
+// addTestToken 创建令牌并添加到配置，返回令牌与明文值
+func addTestToken(t testing.TB, storage *MemoryStorage, configID, name string) (*AccessToken, string) {
+	token, tokenValue, err := CreateAccessToken(&TokenCreateRequest{Name: name}, "admin")
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	if err := storage.AddToken(configID, token); err != nil {
+		t.Fatalf("Failed to add token: %v", err)
+	}
+	return token, tokenValue
+}
+
+// assertIndexConsistent 检查索引与实际令牌列表一致
+func assertIndexConsistent(t *testing.T, storage *MemoryStorage) {
+	t.Helper()
+
+	expected := 0
+	for configID, config := range storage.configs {
+		for i, token := range config.AccessTokens {
+			expected++
+			ref, ok := storage.tokens.byHash[token.TokenHash]
+			if !ok {
+				t.Errorf("Token %s of config %s missing from index", token.ID, configID)
+				continue
+			}
+			if ref.configID != configID || ref.tokenIndex != i {
+				t.Errorf("Token %s indexed at %+v, expected {%s %d}", token.ID, ref, configID, i)
+			}
+		}
+	}
+
+	if len(storage.tokens.byHash) != expected {
+		t.Errorf("Expected %d index entries, got %d", expected, len(storage.tokens.byHash))
+	}
+}
+
+func TestMemoryStorage_TokenIndexAfterMutations(t *testing.T) {
+	storage := NewMemoryStorage(10)
+
+	config1 := &ProxyConfig{Name: "c1", TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+	config2 := &ProxyConfig{Name: "c2", TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+	storage.Add(config1)
+	storage.Add(config2)
+
+	first, firstValue := addTestToken(t, storage, config1.ID, "first")
+	_, secondValue := addTestToken(t, storage, config1.ID, "second")
+	_, thirdValue := addTestToken(t, storage, config2.ID, "third")
+	assertIndexConsistent(t, storage)
+
+	// 删除第一个令牌后，后续令牌的下标应更新
+	if err := storage.DeleteToken(config1.ID, first.ID); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	assertIndexConsistent(t, storage)
+
+	if _, err := storage.FindConfigByToken(firstValue); err != ErrTokenNotFound {
+		t.Errorf("Expected deleted token to be gone, got %v", err)
+	}
+	if configID, _ := storage.FindConfigByToken(secondValue); configID != config1.ID {
+		t.Errorf("Expected second token to resolve to %s, got %s", config1.ID, configID)
+	}
+
+	// 更新令牌（替换哈希，模拟轮换）
+	second, _ := storage.GetTokenByID(config1.ID, storage.configs[config1.ID].AccessTokens[0].ID)
+	rotatedValue := "rotated-token-value"
+	second.TokenHash = HashToken(rotatedValue)
+	if err := storage.UpdateToken(config1.ID, second.ID, second); err != nil {
+		t.Fatalf("UpdateToken() error = %v", err)
+	}
+	assertIndexConsistent(t, storage)
+
+	if _, err := storage.FindConfigByToken(secondValue); err != ErrTokenNotFound {
+		t.Errorf("Expected old token value to stop resolving after rotation, got %v", err)
+	}
+	if configID, _ := storage.FindConfigByToken(rotatedValue); configID != config1.ID {
+		t.Errorf("Expected rotated token to resolve to %s, got %s", config1.ID, configID)
+	}
+
+	// 删除配置后其令牌应从索引移除
+	storage.Delete(config2.ID)
+	assertIndexConsistent(t, storage)
+	if _, err := storage.FindConfigByToken(thirdValue); err != ErrTokenNotFound {
+		t.Errorf("Expected token of deleted config to be gone, got %v", err)
+	}
+
+	// 导入携带令牌的配置后索引应重建
+	exported, _ := storage.ExportAll()
+	storage.Clear()
+	if len(storage.tokens.byHash) != 0 {
+		t.Errorf("Expected empty index after clear, got %d entries", len(storage.tokens.byHash))
+	}
+	if _, err := storage.ImportConfigs(exported.Configs, ImportModeError); err != nil {
+		t.Fatalf("ImportConfigs() error = %v", err)
+	}
+	assertIndexConsistent(t, storage)
+	if configID, _ := storage.FindConfigByToken(rotatedValue); configID == "" {
+		t.Error("Expected imported token to resolve")
+	}
+}
+
+func TestMemoryStorage_TokenIndexLRUEviction(t *testing.T) {
+	storage := NewMemoryStorageWithEviction(1, EvictionLRU)
+
+	config1 := &ProxyConfig{Name: "c1", TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+	storage.Add(config1)
+	_, tokenValue := addTestToken(t, storage, config1.ID, "token")
+
+	// 新增配置会淘汰旧配置，其令牌也应失效
+	storage.Add(&ProxyConfig{Name: "c2", TargetURL: "https://example.com", Protocol: "https", Enabled: true})
+	assertIndexConsistent(t, storage)
+
+	if _, err := storage.FindConfigByToken(tokenValue); err != ErrTokenNotFound {
+		t.Errorf("Expected evicted config's token to be gone, got %v", err)
+	}
+}
+
+// setupTokenLookupBenchmark 创建指定数量的配置（每个配置10个令牌）
+func setupTokenLookupBenchmark(b *testing.B, configCount int) (*MemoryStorage, string) {
+	storage := NewMemoryStorage(configCount + 1)
+
+	var lastValue string
+	for i := 0; i < configCount; i++ {
+		config := &ProxyConfig{Name: fmt.Sprintf("bench-%d", i), TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+		storage.Add(config)
+		for j := 0; j < 10; j++ {
+			_, lastValue = addTestToken(b, storage, config.ID, fmt.Sprintf("token-%d", j))
+		}
+	}
+
+	return storage, lastValue
+}
+
+// BenchmarkFindConfigByToken 令牌查找耗时应与令牌总数无关
+func BenchmarkFindConfigByToken(b *testing.B) {
+	for _, configCount := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("tokens=%d", configCount*10), func(b *testing.B) {
+			storage, tokenValue := setupTokenLookupBenchmark(b, configCount)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := storage.FindConfigByToken(tokenValue); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package proxyconfig
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -10,7 +11,6 @@ import (
 func createTestConfig(storage *MemoryStorage, subdomain string) *ProxyConfig {
 	config := &ProxyConfig{
 		Name:      "Test Config",
-		Subdomain: subdomain,
 		TargetURL: "https://example.com",
 		Enabled:   true,
 	}
@@ -219,6 +219,93 @@ func TestMemoryStorage_UpdateTokenUsage(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_UpdateTokenUsage_Quota(t *testing.T) {
+	storage := NewMemoryStorage(100)
+	config := createTestConfig(storage, "test")
+
+	tokenValue := "trial-token-value"
+	storage.AddToken(config.ID, &AccessToken{
+		ID:            "trial-token",
+		Name:          "Trial Token",
+		TokenHash:     HashToken(tokenValue),
+		Enabled:       true,
+		MaxUsageCount: 3,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	})
+
+	// 恰好用满配额
+	for i := 0; i < 3; i++ {
+		result, err := storage.ValidateToken(config.ID, tokenValue)
+		if err != nil || !result.Valid {
+			t.Fatalf("Expected token to be valid on use %d, got %+v (%v)", i+1, result, err)
+		}
+		if err := storage.UpdateTokenUsage(config.ID, tokenValue); err != nil {
+			t.Fatalf("Expected use %d to be counted, got %v", i+1, err)
+		}
+	}
+
+	// 之后的请求被拒绝且不再计数
+	result, err := storage.ValidateToken(config.ID, tokenValue)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if result.Valid || result.ErrorCode != "TOKEN_QUOTA_EXCEEDED" {
+		t.Errorf("Expected TOKEN_QUOTA_EXCEEDED, got %+v", result)
+	}
+	if err := storage.UpdateTokenUsage(config.ID, tokenValue); err != ErrTokenQuotaExceeded {
+		t.Errorf("Expected ErrTokenQuotaExceeded, got %v", err)
+	}
+
+	token, _ := storage.GetTokenByID(config.ID, "trial-token")
+	if token.UsageCount != 3 {
+		t.Errorf("Expected usage count to stop at 3, got %d", token.UsageCount)
+	}
+	if token.GetStatus() != TokenStatusExhausted {
+		t.Errorf("Expected status %s, got %s", TokenStatusExhausted, token.GetStatus())
+	}
+}
+
+func TestMemoryStorage_UpdateTokenUsage_ConcurrentQuota(t *testing.T) {
+	storage := NewMemoryStorage(100)
+	config := createTestConfig(storage, "test")
+
+	tokenValue := "concurrent-trial-token"
+	storage.AddToken(config.ID, &AccessToken{
+		ID:            "concurrent-token",
+		Name:          "Concurrent Token",
+		TokenHash:     HashToken(tokenValue),
+		Enabled:       true,
+		MaxUsageCount: 10,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	})
+
+	// 并发请求不会超出配额
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if storage.UpdateTokenUsage(config.ID, tokenValue) == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 10 {
+		t.Errorf("Expected exactly 10 accepted uses, got %d", accepted)
+	}
+	if token, _ := storage.GetTokenByID(config.ID, "concurrent-token"); token.UsageCount != 10 {
+		t.Errorf("Expected usage count 10, got %d", token.UsageCount)
+	}
+}
+
 func TestMemoryStorage_GetTokenStats(t *testing.T) {
 	storage := NewMemoryStorage(100)
 	config := createTestConfig(storage, "test")
@@ -276,6 +363,41 @@ func TestMemoryStorage_GetTokenStats(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_ResetStats(t *testing.T) {
+	storage := NewMemoryStorage(100)
+	config := createTestConfig(storage, "test")
+
+	tokenValue := "reset-token-value"
+	storage.AddToken(config.ID, &AccessToken{ID: "reset-token", Name: "Reset Token", TokenHash: HashToken(tokenValue), Enabled: true, CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	storage.UpdateTokenUsage(config.ID, tokenValue)
+	storage.UpdateStats(config.ID, 50*time.Millisecond, false, 100)
+
+	// 清零配置统计
+	if err := storage.ResetConfigStats(config.ID); err != nil {
+		t.Fatalf("Failed to reset config stats: %v", err)
+	}
+	stats, _ := storage.GetConfigStats(config.ID)
+	if *stats != (ConfigStats{}) {
+		t.Errorf("Expected config stats to be zeroed, got %+v", stats)
+	}
+
+	// 清零令牌统计后汇总统计重新计算
+	if err := storage.ResetTokenStats(config.ID, "reset-token"); err != nil {
+		t.Fatalf("Failed to reset token stats: %v", err)
+	}
+	tokenStats, _ := storage.GetTokenStats(config.ID)
+	if tokenStats.TotalTokens != 1 || tokenStats.TotalRequests != 0 || !tokenStats.LastTokenUsed.IsZero() {
+		t.Errorf("Expected token stats to be recomputed, got %+v", tokenStats)
+	}
+
+	if err := storage.ResetTokenStats(config.ID, "missing"); err != ErrTokenNotFound {
+		t.Errorf("Expected ErrTokenNotFound, got %v", err)
+	}
+	if err := storage.ResetConfigStats("missing"); err != ErrConfigNotFound {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+}
+
 // 并发安全性测试
 func TestMemoryStorage_ConcurrentTokenOperations(t *testing.T) {
 	storage := NewMemoryStorage(100)
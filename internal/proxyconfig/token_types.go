@@ -2,23 +2,52 @@ package proxyconfig
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // AccessToken 访问令牌结构
 type AccessToken struct {
-	ID          string     `json:"id"`                    // 令牌唯一标识
-	Name        string     `json:"name"`                  // 令牌名称
-	TokenHash   string     `json:"token_hash"`            // 令牌哈希值(不存储明文)
-	TokenValue  string     `json:"token_value,omitempty"` // 令牌值(用于复制)
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // 过期时间
-	CreatedAt   time.Time  `json:"created_at"`            // 创建时间
-	UpdatedAt   time.Time  `json:"updated_at"`            // 更新时间
-	LastUsed    *time.Time `json:"last_used,omitempty"`   // 最后使用时间
-	UsageCount  int64      `json:"usage_count"`           // 使用次数
-	Enabled     bool       `json:"enabled"`               // 是否启用
-	CreatedBy   string     `json:"created_by,omitempty"`  // 创建者
-	Description string     `json:"description,omitempty"` // 描述信息
+	ID            string     `json:"id"`                        // 令牌唯一标识
+	Name          string     `json:"name"`                      // 令牌名称
+	TokenHash     string     `json:"token_hash"`                // 令牌哈希值(不存储明文)
+	TokenValue    string     `json:"token_value,omitempty"`     // 令牌值(用于复制)
+	LookupID      string     `json:"lookup_id,omitempty"`       // 查找标识(加盐哈希令牌使用，非机密)
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`      // 过期时间
+	CreatedAt     time.Time  `json:"created_at"`                // 创建时间
+	UpdatedAt     time.Time  `json:"updated_at"`                // 更新时间
+	LastUsed      *time.Time `json:"last_used,omitempty"`       // 最后使用时间
+	UsageCount    int64      `json:"usage_count"`               // 使用次数
+	MaxUsageCount int64      `json:"max_usage_count,omitempty"` // 总使用次数上限（0表示不限制），达到后令牌不再可用
+	Enabled       bool       `json:"enabled"`                   // 是否启用
+	CreatedBy     string     `json:"created_by,omitempty"`      // 创建者
+	Description   string     `json:"description,omitempty"`     // 描述信息
+
+	Tags map[string]string `json:"tags,omitempty"` // 自定义标签（如 team、env），用于分类和筛选
+
+	UsageHistory []TokenUsageBucket `json:"usage_history,omitempty"` // 按天（UTC）统计的近期使用次数
+}
+
+// TokenUsageBucket 令牌单日使用计数
+type TokenUsageBucket struct {
+	Date  string `json:"date"`  // 日期（UTC，格式 2006-01-02）
+	Count int64  `json:"count"` // 当日使用次数
+}
+
+// TokenUsageHistory 令牌使用历史响应
+type TokenUsageHistory struct {
+	TokenID    string             `json:"token_id"`
+	UsageCount int64              `json:"usage_count"`
+	LastUsed   *time.Time         `json:"last_used,omitempty"`
+	Days       []TokenUsageBucket `json:"days"` // 按日期升序，无请求的日期计数为0
+}
+
+// IdleTokenReport 闲置令牌报告
+type IdleTokenReport struct {
+	Days   int           `json:"days"`   // 闲置天数阈值
+	Cutoff time.Time     `json:"cutoff"` // 最后使用时间早于该时间即视为闲置
+	Tokens []AccessToken `json:"tokens"` // 闲置令牌，按最后使用时间从早到晚排序
 }
 
 // TokenStats 令牌统计信息
@@ -31,17 +60,25 @@ type TokenStats struct {
 
 // TokenCreateRequest 创建令牌请求
 type TokenCreateRequest struct {
-	Name        string     `json:"name"`                  // 令牌名称
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // 过期时间
-	Description string     `json:"description,omitempty"` // 描述信息
+	Name          string     `json:"name"`                      // 令牌名称
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`      // 过期时间
+	ExpiresIn     string     `json:"expires_in,omitempty"`      // 相对有效期（如 "720h"、"30d"），与 ExpiresAt 互斥
+	Description   string     `json:"description,omitempty"`     // 描述信息
+	MaxUsageCount int64      `json:"max_usage_count,omitempty"` // 总使用次数上限（0表示不限制）
+
+	Tags map[string]string `json:"tags,omitempty"` // 自定义标签
 }
 
 // TokenUpdateRequest 更新令牌请求
 type TokenUpdateRequest struct {
-	Name        string     `json:"name,omitempty"`        // 令牌名称
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // 过期时间
-	Description string     `json:"description,omitempty"` // 描述信息
-	Enabled     *bool      `json:"enabled,omitempty"`     // 是否启用
+	Name          string     `json:"name,omitempty"`            // 令牌名称
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`      // 过期时间
+	ExpiresIn     string     `json:"expires_in,omitempty"`      // 相对有效期（如 "720h"、"30d"），与 ExpiresAt 互斥
+	Description   string     `json:"description,omitempty"`     // 描述信息
+	Enabled       *bool      `json:"enabled,omitempty"`         // 是否启用
+	MaxUsageCount *int64     `json:"max_usage_count,omitempty"` // 总使用次数上限（0表示取消限制）
+
+	Tags map[string]string `json:"tags,omitempty"` // 自定义标签，整体替换（传空对象清除全部标签）
 }
 
 // TokenResponse 令牌响应（包含明文令牌，仅在创建时返回）
@@ -78,21 +115,26 @@ type TokenValidationResult struct {
 
 // 令牌相关错误定义
 var (
-	ErrTokenNotFound     = errors.New("token not found")
-	ErrTokenExpired      = errors.New("token expired")
-	ErrTokenDisabled     = errors.New("token disabled")
-	ErrTokenInvalid      = errors.New("token invalid")
-	ErrTokenNameRequired = errors.New("token name is required")
-	ErrTokenNameTooLong  = errors.New("token name too long (max 100 characters)")
-	ErrDuplicateToken    = errors.New("token already exists")
-	ErrMaxTokensExceeded = errors.New("maximum tokens per config exceeded")
+	ErrTokenNotFound      = errors.New("token not found")
+	ErrTokenExpired       = errors.New("token expired")
+	ErrTokenDisabled      = errors.New("token disabled")
+	ErrTokenQuotaExceeded = errors.New("token usage quota exceeded")
+	ErrInvalidTokenQuota  = errors.New("max_usage_count must not be negative")
+	ErrTokenInvalid       = errors.New("token invalid")
+	ErrTokenNameRequired  = errors.New("token name is required")
+	ErrTokenNameTooLong   = errors.New("token name too long (max 100 characters)")
+	ErrDuplicateToken     = errors.New("token already exists")
+	ErrMaxTokensExceeded  = errors.New("maximum tokens per config exceeded")
+	ErrExpiresConflict    = errors.New("expires_at and expires_in cannot both be set")
+	ErrInvalidExpiresIn   = errors.New("expires_in must be a positive duration such as \"24h\" or \"30d\"")
 )
 
 // 令牌状态常量
 const (
-	TokenStatusActive   = "active"   // 活跃
-	TokenStatusDisabled = "disabled" // 已禁用
-	TokenStatusExpired  = "expired"  // 已过期
+	TokenStatusActive    = "active"    // 活跃
+	TokenStatusDisabled  = "disabled"  // 已禁用
+	TokenStatusExpired   = "expired"   // 已过期
+	TokenStatusExhausted = "exhausted" // 使用次数已达上限
 )
 
 // 令牌相关配置常量
@@ -101,6 +143,9 @@ const (
 	TokenLength        = 64                   // 令牌长度
 	DefaultTokenTTL    = 365 * 24 * time.Hour // 默认令牌有效期（1年）
 	MaxTokenNameLength = 100                  // 令牌名称最大长度
+	MaxTokenUsageDays  = 30                   // 每个令牌保留的使用历史天数
+	DefaultIdleDays    = 30                   // 闲置令牌报告默认天数阈值
+	tokenUsageDateFmt  = "2006-01-02"         // 使用历史日期格式
 )
 
 // IsExpired 检查令牌是否过期
@@ -111,9 +156,14 @@ func (t *AccessToken) IsExpired() bool {
 	return time.Now().After(*t.ExpiresAt)
 }
 
+// IsQuotaExceeded 检查令牌的使用次数是否已达上限
+func (t *AccessToken) IsQuotaExceeded() bool {
+	return t.MaxUsageCount > 0 && t.UsageCount >= t.MaxUsageCount
+}
+
 // IsActive 检查令牌是否活跃
 func (t *AccessToken) IsActive() bool {
-	return t.Enabled && !t.IsExpired()
+	return t.Enabled && !t.IsExpired() && !t.IsQuotaExceeded()
 }
 
 // GetStatus 获取令牌状态
@@ -124,15 +174,66 @@ func (t *AccessToken) GetStatus() string {
 	if t.IsExpired() {
 		return TokenStatusExpired
 	}
+	if t.IsQuotaExceeded() {
+		return TokenStatusExhausted
+	}
 	return TokenStatusActive
 }
 
 // UpdateUsage 更新令牌使用统计
 func (t *AccessToken) UpdateUsage() {
-	now := time.Now()
+	t.updateUsageAt(time.Now())
+}
+
+// updateUsageAt 按指定时间更新令牌使用统计和按天计数
+func (t *AccessToken) updateUsageAt(now time.Time) {
 	t.LastUsed = &now
 	t.UsageCount++
 	t.UpdatedAt = now
+	t.recordUsageAt(now)
+}
+
+// recordUsageAt 将一次使用计入对应日期的计数，最多保留 MaxTokenUsageDays 天
+func (t *AccessToken) recordUsageAt(now time.Time) {
+	date := now.UTC().Format(tokenUsageDateFmt)
+
+	// 写时复制，避免与已返回的令牌副本共享底层数组
+	history := make([]TokenUsageBucket, len(t.UsageHistory), len(t.UsageHistory)+1)
+	copy(history, t.UsageHistory)
+
+	if n := len(history); n > 0 && history[n-1].Date == date {
+		history[n-1].Count++
+	} else {
+		history = append(history, TokenUsageBucket{Date: date, Count: 1})
+	}
+
+	// 丢弃超出保留窗口的日期
+	cutoff := now.UTC().AddDate(0, 0, -(MaxTokenUsageDays - 1)).Format(tokenUsageDateFmt)
+	start := 0
+	for start < len(history) && history[start].Date < cutoff {
+		start++
+	}
+	t.UsageHistory = history[start:]
+}
+
+// UsageSeries 返回截至 now 的最近 days 天使用计数，缺失的日期补0
+func (t *AccessToken) UsageSeries(now time.Time, days int) []TokenUsageBucket {
+	if days <= 0 || days > MaxTokenUsageDays {
+		days = MaxTokenUsageDays
+	}
+
+	counts := make(map[string]int64, len(t.UsageHistory))
+	for _, bucket := range t.UsageHistory {
+		counts[bucket.Date] = bucket.Count
+	}
+
+	today := now.UTC()
+	series := make([]TokenUsageBucket, days)
+	for i := 0; i < days; i++ {
+		date := today.AddDate(0, 0, i-days+1).Format(tokenUsageDateFmt)
+		series[i] = TokenUsageBucket{Date: date, Count: counts[date]}
+	}
+	return series
 }
 
 // Validate 验证令牌数据
@@ -146,7 +247,7 @@ func (t *AccessToken) Validate() error {
 	if t.TokenHash == "" {
 		return ErrTokenInvalid
 	}
-	return nil
+	return ValidateTags(t.Tags)
 }
 
 // ValidateCreateRequest 验证创建令牌请求
@@ -161,7 +262,13 @@ func ValidateCreateRequest(req *TokenCreateRequest) error {
 	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
 		return errors.New("expiration time cannot be in the past")
 	}
-	return nil
+	if req.MaxUsageCount < 0 {
+		return ErrInvalidTokenQuota
+	}
+	if err := ValidateTags(req.Tags); err != nil {
+		return err
+	}
+	return validateExpiresIn(req.ExpiresAt, req.ExpiresIn)
 }
 
 // ValidateUpdateRequest 验证更新令牌请求
@@ -173,5 +280,63 @@ func ValidateUpdateRequest(req *TokenUpdateRequest) error {
 	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
 		return errors.New("expiration time cannot be in the past")
 	}
-	return nil
+	if req.MaxUsageCount != nil && *req.MaxUsageCount < 0 {
+		return ErrInvalidTokenQuota
+	}
+	if err := ValidateTags(req.Tags); err != nil {
+		return err
+	}
+	return validateExpiresIn(req.ExpiresAt, req.ExpiresIn)
+}
+
+// validateExpiresIn 校验相对有效期格式，并确保不与绝对过期时间同时设置
+func validateExpiresIn(expiresAt *time.Time, expiresIn string) error {
+	if expiresIn == "" {
+		return nil
+	}
+	if expiresAt != nil {
+		return ErrExpiresConflict
+	}
+	_, err := ParseExpiresIn(expiresIn)
+	return err
+}
+
+// maxExpiresInDays 以天数表示的相对有效期上限（time.Duration能表示的最大天数，约292年）
+const maxExpiresInDays = int64(1<<63-1) / int64(24*time.Hour)
+
+// ParseExpiresIn 解析相对有效期，除 time.ParseDuration 支持的格式外，还支持以 "d" 结尾的天数
+func ParseExpiresIn(value string) (time.Duration, error) {
+	var duration time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		// 先检查上限再换算，避免乘法溢出得到负数或过去的时间
+		if err != nil || n > maxExpiresInDays {
+			return 0, ErrInvalidExpiresIn
+		}
+		duration = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, ErrInvalidExpiresIn
+		}
+		duration = parsed
+	}
+
+	if duration <= 0 {
+		return 0, ErrInvalidExpiresIn
+	}
+	return duration, nil
+}
+
+// resolveExpiresAt 将相对有效期换算为绝对过期时间，未设置时返回原有的 ExpiresAt
+func resolveExpiresAt(expiresAt *time.Time, expiresIn string, now time.Time) *time.Time {
+	if expiresIn == "" {
+		return expiresAt
+	}
+	duration, err := ParseExpiresIn(expiresIn)
+	if err != nil {
+		return expiresAt
+	}
+	expiry := now.Add(duration)
+	return &expiry
 }
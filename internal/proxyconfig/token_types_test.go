@@ -152,6 +152,76 @@ func TestAccessToken_UpdateUsage(t *testing.T) {
 	}
 }
 
+func TestAccessToken_UsageHistoryBuckets(t *testing.T) {
+	token := &AccessToken{}
+	base := time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC)
+
+	// 同一天的两次请求计入同一桶，跨过零点后进入新桶
+	token.updateUsageAt(base)
+	token.updateUsageAt(base.Add(20 * time.Minute))
+	token.updateUsageAt(base.Add(40 * time.Minute))
+	// 中间空一天
+	token.updateUsageAt(base.Add(48 * time.Hour))
+
+	expected := []TokenUsageBucket{
+		{Date: "2024-03-10", Count: 2},
+		{Date: "2024-03-11", Count: 1},
+		{Date: "2024-03-12", Count: 1},
+	}
+	if len(token.UsageHistory) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %+v", len(expected), token.UsageHistory)
+	}
+	for i, bucket := range expected {
+		if token.UsageHistory[i] != bucket {
+			t.Errorf("Bucket %d: expected %+v, got %+v", i, bucket, token.UsageHistory[i])
+		}
+	}
+	if token.UsageCount != 4 {
+		t.Errorf("Expected usage count 4, got %d", token.UsageCount)
+	}
+
+	// 非UTC时间按UTC日期归档
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	token.updateUsageAt(time.Date(2024, 3, 13, 7, 0, 0, 0, shanghai))
+	if last := token.UsageHistory[len(token.UsageHistory)-1]; last.Date != "2024-03-12" || last.Count != 2 {
+		t.Errorf("Expected local time to be bucketed by UTC date, got %+v", last)
+	}
+
+	// 连续序列补齐没有请求的日期
+	series := token.UsageSeries(base.Add(72*time.Hour), 5)
+	expectedSeries := []int64{0, 2, 1, 2, 0}
+	for i, count := range expectedSeries {
+		if series[i].Count != count {
+			t.Errorf("Series day %s: expected %d, got %d", series[i].Date, count, series[i].Count)
+		}
+	}
+	if series[4].Date != "2024-03-13" {
+		t.Errorf("Expected series to end at 2024-03-13, got %s", series[4].Date)
+	}
+}
+
+func TestAccessToken_UsageHistoryCapped(t *testing.T) {
+	token := &AccessToken{}
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for day := 0; day < MaxTokenUsageDays+10; day++ {
+		token.updateUsageAt(base.AddDate(0, 0, day))
+	}
+
+	if len(token.UsageHistory) != MaxTokenUsageDays {
+		t.Fatalf("Expected history capped at %d days, got %d", MaxTokenUsageDays, len(token.UsageHistory))
+	}
+	if first := token.UsageHistory[0].Date; first != base.AddDate(0, 0, 10).Format("2006-01-02") {
+		t.Errorf("Expected oldest days to be dropped, first bucket is %s", first)
+	}
+
+	// 长时间未使用后，旧日期全部过期
+	token.updateUsageAt(base.AddDate(0, 0, 200))
+	if len(token.UsageHistory) != 1 {
+		t.Errorf("Expected stale buckets to be dropped, got %d buckets", len(token.UsageHistory))
+	}
+}
+
 func TestAccessToken_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -3,9 +3,11 @@ package proxyconfig
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,13 +15,18 @@ import (
 
 // GenerateToken 生成安全的随机令牌
 //
-// 该函数使用crypto/rand生成32字节的随机数据，然后使用Base64 URL编码。
-// 生成的令牌长度固定，避免包含特殊字符，适合在HTTP头部和URL中使用。
+// 默认使用crypto/rand生成32字节的随机数据，然后使用Base64 URL编码。
+// 通过SetTokenFormat设置长度后，改为从所选字符集中均匀随机选取字符，
+// 生成的令牌不包含特殊字符，适合在HTTP头部和URL中使用。
 //
 // 返回值:
 //   - string: 生成的令牌字符串
 //   - error: 如果随机数生成失败则返回错误
 func GenerateToken() (string, error) {
+	if length, charset := TokenFormat(); length > 0 {
+		return generateTokenWithFormat(length, charset)
+	}
+
 	// 生成32字节的随机数据
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
@@ -53,8 +60,26 @@ func HashToken(token string) string {
 }
 
 // VerifyToken 验证令牌是否匹配哈希值
+//
+// 根据存储值自动识别哈希方案：以 $argon2id$ 开头的按Argon2id验证，
+// 其余按SHA-256十六进制比较，以兼容旧版本生成的令牌。
 func VerifyToken(token, hash string) bool {
-	return HashToken(token) == hash
+	sha256Hash := HashToken(token)
+
+	if DetectHashScheme(hash) == HashSchemeSHA256 {
+		return subtle.ConstantTimeCompare([]byte(sha256Hash), []byte(hash)) == 1
+	}
+
+	// 慢哈希验证结果按存储值缓存，后续请求只需比较SHA-256
+	if cached, ok := verifiedHashes.Load(hash); ok {
+		return subtle.ConstantTimeCompare([]byte(cached.(string)), []byte(sha256Hash)) == 1
+	}
+
+	if !verifyTokenArgon2id(token, hash) {
+		return false
+	}
+	verifiedHashes.Store(hash, sha256Hash)
+	return true
 }
 
 // CreateAccessToken 创建新的访问令牌
@@ -70,21 +95,34 @@ func CreateAccessToken(req *TokenCreateRequest, createdBy string) (*AccessToken,
 		return nil, "", err
 	}
 
+	// 使用当前默认方案计算哈希
+	tokenHash, err := HashTokenWithScheme(tokenValue, DefaultHashScheme())
+	if err != nil {
+		return nil, "", err
+	}
+
 	// 创建令牌对象
 	now := time.Now()
 	token := &AccessToken{
-		ID:          uuid.New().String(),
-		Name:        req.Name,
-		TokenHash:   HashToken(tokenValue),
-		TokenValue:  tokenValue, // 保存令牌值用于复制
-		ExpiresAt:   req.ExpiresAt,
-		CreatedAt:   now,
-		UpdatedAt:   now,
-		LastUsed:    nil,
-		UsageCount:  0,
-		Enabled:     true,
-		CreatedBy:   createdBy,
-		Description: req.Description,
+		ID:            uuid.New().String(),
+		Name:          req.Name,
+		TokenHash:     tokenHash,
+		LookupID:      tokenLookupIDFor(tokenValue, tokenHash),
+		ExpiresAt:     resolveExpiresAt(req.ExpiresAt, req.ExpiresIn, now),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		LastUsed:      nil,
+		UsageCount:    0,
+		MaxUsageCount: req.MaxUsageCount,
+		Enabled:       true,
+		CreatedBy:     createdBy,
+		Description:   req.Description,
+		Tags:          copyTags(req.Tags),
+	}
+
+	// SHA-256令牌保存令牌值用于复制；加盐哈希令牌的值只在创建时返回一次，不保存
+	if token.LookupID == "" {
+		token.TokenValue = tokenValue
 	}
 
 	return token, tokenValue, nil
@@ -101,8 +139,9 @@ func UpdateAccessToken(token *AccessToken, req *TokenUpdateRequest) error {
 	if req.Name != "" {
 		token.Name = req.Name
 	}
-	if req.ExpiresAt != nil {
-		token.ExpiresAt = req.ExpiresAt
+	now := time.Now()
+	if req.ExpiresAt != nil || req.ExpiresIn != "" {
+		token.ExpiresAt = resolveExpiresAt(req.ExpiresAt, req.ExpiresIn, now)
 	}
 	if req.Description != "" {
 		token.Description = req.Description
@@ -110,9 +149,15 @@ func UpdateAccessToken(token *AccessToken, req *TokenUpdateRequest) error {
 	if req.Enabled != nil {
 		token.Enabled = *req.Enabled
 	}
+	if req.MaxUsageCount != nil {
+		token.MaxUsageCount = *req.MaxUsageCount
+	}
+	if req.Tags != nil {
+		token.Tags = copyTags(req.Tags)
+	}
 
 	// 更新时间戳
-	token.UpdatedAt = time.Now()
+	token.UpdatedAt = now
 
 	return nil
 }
@@ -131,6 +176,10 @@ func ValidateTokenAccess(token *AccessToken) error {
 		return ErrTokenExpired
 	}
 
+	if token.IsQuotaExceeded() {
+		return ErrTokenQuotaExceeded
+	}
+
 	return nil
 }
 
@@ -186,6 +235,46 @@ func FilterTokensByStatus(tokens []AccessToken, status string) []AccessToken {
 	return filteredTokens
 }
 
+// FilterTokensByTags 按标签过滤令牌，filters 中每一项为 "key:value"（精确匹配）或 "key"（存在该标签即可），需全部满足
+func FilterTokensByTags(tokens []AccessToken, filters []string) []AccessToken {
+	if len(filters) == 0 {
+		return tokens
+	}
+	var filtered []AccessToken
+	for _, token := range tokens {
+		if MatchesTags(token.Tags, filters) {
+			filtered = append(filtered, token)
+		}
+	}
+	return filtered
+}
+
+// FilterIdleTokens 返回在 cutoff 之后未被使用过的令牌（含从未使用的令牌），
+// 从未使用的排在最前，其余按最后使用时间从早到晚排序
+func FilterIdleTokens(tokens []AccessToken, cutoff time.Time) []AccessToken {
+	idleTokens := make([]AccessToken, 0)
+	for _, token := range tokens {
+		if token.LastUsed == nil || token.LastUsed.Before(cutoff) {
+			idleTokens = append(idleTokens, token)
+		}
+	}
+
+	sort.SliceStable(idleTokens, func(i, j int) bool {
+		a, b := idleTokens[i].LastUsed, idleTokens[j].LastUsed
+		switch {
+		case a == nil && b == nil:
+			return idleTokens[i].CreatedAt.Before(idleTokens[j].CreatedAt)
+		case a == nil:
+			return true
+		case b == nil:
+			return false
+		default:
+			return a.Before(*b)
+		}
+	})
+	return idleTokens
+}
+
 // FindTokenByID 根据ID查找令牌
 func FindTokenByID(tokens []AccessToken, tokenID string) (*AccessToken, int) {
 	for i, token := range tokens {
@@ -228,11 +317,36 @@ func ValidateTokenLimit(currentCount int) error {
 // SanitizeTokenForResponse 清理令牌数据用于响应（移除敏感信息）
 func SanitizeTokenForResponse(token *AccessToken) AccessToken {
 	sanitized := *token
-	// 不返回令牌哈希值，但保留令牌值用于复制
+	// 不返回令牌哈希值；SHA-256令牌保留令牌值用于复制，加盐哈希令牌从不返回令牌值
+	if DetectHashScheme(token.TokenHash) != HashSchemeSHA256 {
+		sanitized.TokenValue = ""
+	}
 	sanitized.TokenHash = ""
 	return sanitized
 }
 
+// clearSaltedTokenValues 清除加盐哈希令牌保存的令牌值（先由令牌值得出查找标识），返回清除的数量
+//
+// 早期版本创建的argon2id令牌同时保存了令牌值，加载时清除，避免明文与慢哈希并存。
+func clearSaltedTokenValues(configs map[string]*ProxyConfig) int {
+	cleared := 0
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		for i := range config.AccessTokens {
+			token := &config.AccessTokens[i]
+			if token.TokenValue == "" || DetectHashScheme(token.TokenHash) == HashSchemeSHA256 {
+				continue
+			}
+			token.LookupID = token.lookupKey()
+			token.TokenValue = ""
+			cleared++
+		}
+	}
+	return cleared
+}
+
 // SanitizeTokensForResponse 批量清理令牌数据
 func SanitizeTokensForResponse(tokens []AccessToken) []AccessToken {
 	sanitized := make([]AccessToken, len(tokens))
@@ -256,13 +370,12 @@ func CreateTokenUsageRecord(tokenID, configID string, success bool, errorMsg, us
 }
 
 // IsValidTokenFormat 检查令牌格式是否有效
+//
+// 符合当前配置的长度和字符集，或为旧格式（Base64 URL编码）的令牌均视为有效，
+// 修改令牌格式后已发放的令牌仍可使用。
 func IsValidTokenFormat(token string) bool {
-	// 检查长度
-	if len(token) < 32 || len(token) > TokenLength {
-		return false
+	if length, charset := TokenFormat(); length > 0 && matchesTokenFormat(token, length, charset) {
+		return true
 	}
-
-	// 检查是否为有效的Base64 URL编码
-	_, err := base64.URLEncoding.DecodeString(token)
-	return err == nil
+	return isLegacyTokenFormat(token)
 }
@@ -1,6 +1,7 @@
 package proxyconfig
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -481,7 +482,117 @@ func TestValidateUpdateRequest_EdgeCases(t *testing.T) {
 	}
 }
 
+func TestCreateAccessToken_ExpiresIn(t *testing.T) {
+	before := time.Now()
+	token, _, err := CreateAccessToken(&TokenCreateRequest{Name: "relative", ExpiresIn: "24h"}, "admin")
+	if err != nil {
+		t.Fatalf("CreateAccessToken() error = %v", err)
+	}
+	after := time.Now()
+
+	if token.ExpiresAt == nil {
+		t.Fatal("Expected ExpiresAt to be derived from ExpiresIn")
+	}
+	if token.ExpiresAt.Before(before.Add(24*time.Hour)) || token.ExpiresAt.After(after.Add(24*time.Hour)) {
+		t.Errorf("Expected expiry ~24h out, got %v", token.ExpiresAt.Sub(before))
+	}
+
+	// 天数格式
+	token, _, err = CreateAccessToken(&TokenCreateRequest{Name: "days", ExpiresIn: "30d"}, "admin")
+	if err != nil {
+		t.Fatalf("CreateAccessToken() error = %v", err)
+	}
+	if d := token.ExpiresAt.Sub(token.CreatedAt); d != 30*24*time.Hour {
+		t.Errorf("Expected 30 day expiry, got %v", d)
+	}
+
+	// 更新请求同样支持相对有效期
+	if err := UpdateAccessToken(token, &TokenUpdateRequest{ExpiresIn: "1h"}); err != nil {
+		t.Fatalf("UpdateAccessToken() error = %v", err)
+	}
+	if d := token.ExpiresAt.Sub(token.UpdatedAt); d != time.Hour {
+		t.Errorf("Expected 1h expiry after update, got %v", d)
+	}
+}
+
+func TestValidateExpiresIn(t *testing.T) {
+	future := timePtr(time.Now().Add(time.Hour))
+
+	// 同时设置两种过期方式
+	if err := ValidateCreateRequest(&TokenCreateRequest{Name: "test", ExpiresAt: future, ExpiresIn: "24h"}); err != ErrExpiresConflict {
+		t.Errorf("Expected ErrExpiresConflict on create, got %v", err)
+	}
+	if err := ValidateUpdateRequest(&TokenUpdateRequest{ExpiresAt: future, ExpiresIn: "24h"}); err != ErrExpiresConflict {
+		t.Errorf("Expected ErrExpiresConflict on update, got %v", err)
+	}
+
+	for _, value := range []string{"abc", "-1h", "0s", "0d", "-3d", "1.5d", "106752d", "9999999999999d"} {
+		if err := ValidateCreateRequest(&TokenCreateRequest{Name: "test", ExpiresIn: value}); err != ErrInvalidExpiresIn {
+			t.Errorf("Expected ErrInvalidExpiresIn for %q, got %v", value, err)
+		}
+	}
+
+	// 上限以内的天数仍然有效
+	if duration, err := ParseExpiresIn("106751d"); err != nil || duration <= 0 {
+		t.Errorf("Expected largest representable day count to be valid, got %v (err %v)", duration, err)
+	}
+}
+
 // 辅助函数
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func TestFilterTokensByTags(t *testing.T) {
+	tokens := []AccessToken{
+		{ID: "token1", Tags: map[string]string{"env": "prod", "team": "a"}},
+		{ID: "token2", Tags: map[string]string{"env": "dev"}},
+		{ID: "token3"},
+	}
+
+	if got := FilterTokensByTags(tokens, nil); len(got) != 3 {
+		t.Errorf("Expected all tokens without filters, got %d", len(got))
+	}
+	if got := FilterTokensByTags(tokens, []string{"env:prod"}); len(got) != 1 || got[0].ID != "token1" {
+		t.Errorf("Expected token1 for env:prod, got %v", got)
+	}
+	if got := FilterTokensByTags(tokens, []string{"env"}); len(got) != 2 {
+		t.Errorf("Expected 2 tokens with env tag, got %d", len(got))
+	}
+	if got := FilterTokensByTags(tokens, []string{"env:dev", "team"}); len(got) != 0 {
+		t.Errorf("Expected no tokens matching all filters, got %d", len(got))
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxTags; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr bool
+	}{
+		{"nil tags", nil, false},
+		{"valid tags", map[string]string{"env": "prod", "team": ""}, false},
+		{"empty key", map[string]string{" ": "v"}, true},
+		{"key with colon", map[string]string{"env:x": "v"}, true},
+		{"key too long", map[string]string{strings.Repeat("k", MaxTagKeyLength+1): "v"}, true},
+		{"value too long", map[string]string{"env": strings.Repeat("v", MaxTagValueLength+1)}, true},
+		{"too many tags", tooMany, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTags(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTags) {
+				t.Errorf("Expected ErrInvalidTags, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package proxyconfig
+
+import (
+	"sort"
+	"time"
+
+	"privacygateway/internal/logger"
+)
+
+// softDeleteLocked 将配置移入回收站并移除其令牌索引（调用方需持有写锁）
+func (s *MemoryStorage) softDeleteLocked(id string, now time.Time) {
+	config := s.configs[id]
+	s.tokens.remove(config)
+	delete(s.configs, id)
+
+	config.DeletedAt = &now
+	s.deleted[id] = config
+}
+
+// ListDeleted 获取回收站中的配置，按删除时间从新到旧排序
+func (s *MemoryStorage) ListDeleted() ([]ProxyConfig, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	configs := make([]ProxyConfig, 0, len(s.deleted))
+	for _, config := range s.deleted {
+		configs = append(configs, *config)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].DeletedAt.After(*configs[j].DeletedAt)
+	})
+
+	return configs, nil
+}
+
+// Restore 从回收站恢复配置，令牌和统计信息保持删除前的状态
+func (s *MemoryStorage) Restore(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	config, exists := s.deleted[id]
+	if !exists {
+		return ErrConfigNotFound
+	}
+
+	// 恢复同样受最大配置数限制
+	if err := s.ensureCapacityLocked(); err != nil {
+		return err
+	}
+
+	delete(s.deleted, id)
+	config.DeletedAt = nil
+	config.Version++
+	config.UpdatedAt = time.Now()
+	s.configs[id] = config
+	s.tokens.add(config)
+
+	return nil
+}
+
+// PurgeDeleted 彻底清除删除时间早于deletedBefore的配置，返回清除数量
+func (s *MemoryStorage) PurgeDeleted(deletedBefore time.Time) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	purged := 0
+	for id, config := range s.deleted {
+		if config.DeletedAt.Before(deletedBefore) {
+			delete(s.deleted, id)
+			purged++
+		}
+	}
+	return purged
+}
+
+// splitDeleted 将从文件加载的配置按是否软删除拆分为正常配置和回收站
+//
+// 旧版本文件中的配置没有版本号，加载时从1开始计数。
+func splitDeleted(configs map[string]*ProxyConfig) (active, deleted map[string]*ProxyConfig) {
+	active = make(map[string]*ProxyConfig, len(configs))
+	deleted = make(map[string]*ProxyConfig)
+	for id, config := range configs {
+		if config != nil && config.Version == 0 {
+			config.Version = 1
+		}
+		if config != nil && config.DeletedAt != nil {
+			deleted[id] = config
+		} else {
+			active[id] = config
+		}
+	}
+	return active, deleted
+}
+
+// DeletedPurger 定期清除回收站中超过保留时长的配置
+type DeletedPurger struct {
+	storage   Storage
+	retention time.Duration
+	interval  time.Duration
+	log       *logger.Logger
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewDeletedPurger 创建回收站清理器，检查间隔为保留时长的1/24（最长1小时）
+func NewDeletedPurger(storage Storage, retention time.Duration, log *logger.Logger) *DeletedPurger {
+	interval := retention / 24
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	return &DeletedPurger{
+		storage:   storage,
+		retention: retention,
+		interval:  interval,
+		log:       log,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start 启动定期清理
+func (p *DeletedPurger) Start() {
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.purge(time.Now())
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定期清理
+func (p *DeletedPurger) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+// purge 清除在now之前已超过保留时长的配置
+func (p *DeletedPurger) purge(now time.Time) int {
+	purged := p.storage.PurgeDeleted(now.Add(-p.retention))
+	if purged > 0 {
+		p.log.Info("deleted configs purged", "count", purged, "retention", p.retention)
+	}
+	return purged
+}
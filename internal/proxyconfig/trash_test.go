@@ -0,0 +1,137 @@
+package proxyconfig
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"privacygateway/internal/logger"
+)
+
+// 辅助函数：创建带一个令牌的配置，返回配置和令牌明文
+func addConfigWithToken(t *testing.T, storage Storage, name string) (*ProxyConfig, string) {
+	t.Helper()
+	config := newEvictionTestConfig(name)
+	if err := storage.Add(config); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	token, tokenValue, err := CreateAccessToken(&TokenCreateRequest{Name: "token"}, "admin")
+	if err != nil {
+		t.Fatalf("CreateAccessToken() error = %v", err)
+	}
+	if err := storage.AddToken(config.ID, token); err != nil {
+		t.Fatalf("AddToken() error = %v", err)
+	}
+	return config, tokenValue
+}
+
+func TestMemoryStorage_SoftDeleteHidesConfig(t *testing.T) {
+	storage := NewMemoryStorage(10)
+	config, tokenValue := addConfigWithToken(t, storage, "deleted")
+	storage.Add(newEvictionTestConfig("kept"))
+
+	if err := storage.Delete(config.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	response, _ := storage.List(&ConfigFilter{})
+	if response.Total != 1 || response.Configs[0].Name != "kept" {
+		t.Errorf("Expected deleted config to be hidden from List, got %+v", response.Configs)
+	}
+	if _, err := storage.GetByID(config.ID); err != ErrConfigNotFound {
+		t.Errorf("Expected ErrConfigNotFound for deleted config, got %v", err)
+	}
+	if _, err := storage.FindConfigByToken(tokenValue); err != ErrTokenNotFound {
+		t.Errorf("Expected tokens of deleted config to stop working, got %v", err)
+	}
+	if err := storage.Delete(config.ID); err != ErrConfigNotFound {
+		t.Errorf("Expected deleting twice to return ErrConfigNotFound, got %v", err)
+	}
+
+	deleted, _ := storage.ListDeleted()
+	if len(deleted) != 1 || deleted[0].ID != config.ID || deleted[0].DeletedAt == nil {
+		t.Fatalf("Expected config in recycle bin with deleted_at, got %+v", deleted)
+	}
+}
+
+func TestMemoryStorage_RestoreKeepsTokens(t *testing.T) {
+	storage := NewMemoryStorage(10)
+	config, tokenValue := addConfigWithToken(t, storage, "restored")
+	if err := storage.UpdateTokenUsage(config.ID, tokenValue); err != nil {
+		t.Fatalf("UpdateTokenUsage() error = %v", err)
+	}
+	storage.Delete(config.ID)
+
+	if err := storage.Restore(config.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	restored, err := storage.GetByID(config.ID)
+	if err != nil {
+		t.Fatalf("Expected restored config to be visible: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Error("Expected deleted_at to be cleared after restore")
+	}
+	if len(restored.AccessTokens) != 1 || restored.AccessTokens[0].UsageCount != 1 {
+		t.Errorf("Expected token and usage to be intact, got %+v", restored.AccessTokens)
+	}
+	if configID, err := storage.FindConfigByToken(tokenValue); err != nil || configID != config.ID {
+		t.Errorf("Expected restored token to resolve to config, got %q (err %v)", configID, err)
+	}
+	if deleted, _ := storage.ListDeleted(); len(deleted) != 0 {
+		t.Errorf("Expected recycle bin to be empty, got %d", len(deleted))
+	}
+	if err := storage.Restore(config.ID); err != ErrConfigNotFound {
+		t.Errorf("Expected restoring an active config to fail, got %v", err)
+	}
+}
+
+func TestDeletedPurger_PurgesAfterRetention(t *testing.T) {
+	storage := NewMemoryStorage(10)
+	config, _ := addConfigWithToken(t, storage, "purged")
+	storage.Delete(config.ID)
+
+	purger := NewDeletedPurger(storage, time.Hour, logger.New())
+
+	// 保留期内不清除
+	if purged := purger.purge(time.Now().Add(30 * time.Minute)); purged != 0 {
+		t.Errorf("Expected nothing purged within retention, got %d", purged)
+	}
+	if deleted, _ := storage.ListDeleted(); len(deleted) != 1 {
+		t.Fatalf("Expected config to stay in recycle bin, got %d", len(deleted))
+	}
+
+	// 超过保留期后彻底清除，无法再恢复
+	if purged := purger.purge(time.Now().Add(2 * time.Hour)); purged != 1 {
+		t.Errorf("Expected 1 config purged, got %d", purged)
+	}
+	if err := storage.Restore(config.ID); err != ErrConfigNotFound {
+		t.Errorf("Expected purged config to be unrecoverable, got %v", err)
+	}
+}
+
+func TestPersistentStorage_RecycleBinSurvivesRestart(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "configs.json")
+	log := logger.New()
+
+	storage := NewPersistentStorage(filePath, 10, EvictionReject, false, log)
+	config, tokenValue := addConfigWithToken(t, storage, "persisted")
+	if err := storage.Delete(config.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	storage.Shutdown()
+
+	reloaded := NewPersistentStorage(filePath, 10, EvictionReject, false, log)
+	defer reloaded.Shutdown()
+
+	if response, _ := reloaded.List(&ConfigFilter{}); response.Total != 0 {
+		t.Errorf("Expected deleted config to stay hidden after restart, got %d", response.Total)
+	}
+	if err := reloaded.Restore(config.ID); err != nil {
+		t.Fatalf("Restore() after restart error = %v", err)
+	}
+	if configID, err := reloaded.FindConfigByToken(tokenValue); err != nil || configID != config.ID {
+		t.Errorf("Expected token to work after restore, got %q (err %v)", configID, err)
+	}
+}
@@ -1,22 +1,117 @@
 package proxyconfig
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // ProxyConfig 代理配置结构
 type ProxyConfig struct {
-	ID           string        `json:"id"`
-	Name         string        `json:"name"`
-	TargetURL    string        `json:"target_url"`
-	Protocol     string        `json:"protocol"`
-	Enabled      bool          `json:"enabled"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	Stats        *ConfigStats  `json:"stats,omitempty"`
-	AccessTokens []AccessToken `json:"access_tokens,omitempty"` // 访问令牌列表
-	TokenStats   *TokenStats   `json:"token_stats,omitempty"`   // 令牌统计信息
+	ID                    string            `json:"id"`
+	Name                  string            `json:"name"`
+	TargetURL             string            `json:"target_url"`
+	Protocol              string            `json:"protocol"`
+	Enabled               bool              `json:"enabled"`
+	CacheTTLSeconds       int               `json:"cache_ttl_seconds,omitempty"`        // GET响应缓存时间（秒），0表示不缓存
+	AllowedHosts          []string          `json:"allowed_hosts,omitempty"`            // 允许代理的目标主机（为空时不限制，支持*.example.com）
+	DeniedHosts           []string          `json:"denied_hosts,omitempty"`             // 禁止代理的目标主机
+	RetryCount            int               `json:"retry_count,omitempty"`              // 上游连接失败或返回502/503/504时的重试次数，0表示不重试
+	RetryBackoffMs        int               `json:"retry_backoff_ms,omitempty"`         // 首次重试前的等待时间（毫秒），之后每次翻倍
+	RetryNonIdempotent    bool              `json:"retry_non_idempotent,omitempty"`     // 是否允许重试POST等非幂等请求
+	UpstreamProxy         string            `json:"upstream_proxy,omitempty"`           // 出站代理（http://、socks5://），覆盖全局UPSTREAM_PROXY；"direct"表示直连
+	MaxIdleConnsPerHost   int               `json:"max_idle_conns_per_host,omitempty"`  // 每个上游主机的最大空闲连接数，覆盖全局UPSTREAM_MAX_IDLE_CONNS_PER_HOST（0表示沿用）
+	IdleConnTimeout       int               `json:"idle_conn_timeout,omitempty"`        // 上游空闲连接保留时间（秒），覆盖全局UPSTREAM_IDLE_CONN_TIMEOUT（0表示沿用）
+	DisableKeepAlives     bool              `json:"disable_keep_alives,omitempty"`      // 禁用上游连接复用，每个请求使用新连接
+	ResponseHeaderTimeout int               `json:"response_header_timeout,omitempty"`  // 发送请求后等待上游响应头的最长时间（秒），超时返回504，0表示只受整体请求超时约束
+	ReadIdleTimeout       int               `json:"read_idle_timeout,omitempty"`        // 上游连续多久（秒）没有发送任何数据时中止请求，0表示不限制
+	ClientCert            string            `json:"client_cert,omitempty"`              // 上游mTLS客户端证书（PEM内容或文件路径）
+	ClientKey             string            `json:"client_key,omitempty"`               // 上游mTLS客户端私钥（PEM内容或文件路径）
+	CACert                string            `json:"ca_cert,omitempty"`                  // 校验上游证书的自定义CA（PEM内容或文件路径）
+	InsecureSkipVerify    bool              `json:"insecure_skip_verify,omitempty"`     // 跳过上游证书校验（仅用于自签名证书的测试环境）
+	LogBodies             LogBodiesMode     `json:"log_bodies,omitempty"`               // 访问日志是否记录请求/响应体：inherit（默认，沿用全局设置）、never、always
+	LogWebSocketFrames    bool              `json:"log_websocket_frames,omitempty"`     // 记录WebSocket帧（文本帧内容截断记录，二进制帧只记录类型和大小）
+	PathRules             []PathRule        `json:"path_rules,omitempty"`               // 路径前缀路由规则（按顺序匹配，首个匹配生效）
+	BasePath              string            `json:"base_path,omitempty"`                // 拼接在目标路径前的固定基础路径（如/v1），路径规则匹配时不生效
+	AllowedMethods        []string          `json:"allowed_methods,omitempty"`          // 允许转发的HTTP方法（如["GET","HEAD"]），为空时不限制；OPTIONS预检始终放行
+	AllowedQueryParams    []string          `json:"allowed_query_params,omitempty"`     // 允许转发的查询参数（为空时不限制）
+	DeniedQueryParams     []string          `json:"denied_query_params,omitempty"`      // 转发前移除的查询参数
+	RedirectPolicy        RedirectPolicy    `json:"redirect_policy,omitempty"`          // 上游重定向处理：no-follow（默认，3xx原样返回）、follow、limited:N
+	CompressResponses     *bool             `json:"compress_responses,omitempty"`       // 网关是否gzip压缩响应，覆盖全局RESPONSE_COMPRESSION（未设置时沿用全局设置）
+	MaxConcurrency        int               `json:"max_concurrency,omitempty"`          // 该配置同时进行中的代理请求上限，超出时返回503，0表示不限制
+	BasicAuthUser         string            `json:"basic_auth_user,omitempty"`          // 要求客户端提供HTTP Basic认证的用户名（为空时不要求）
+	BasicAuthPasswordHash string            `json:"basic_auth_password_hash,omitempty"` // Basic认证密码的哈希（SHA-256十六进制或Argon2id，不保存明文）
+	OwnerSecretHash       string            `json:"owner_secret_hash,omitempty"`        // 租户管理密钥的哈希（SHA-256十六进制或Argon2id），持有者只能管理本配置的令牌
+	RequestSigning        *RequestSigning   `json:"request_signing,omitempty"`          // 要求客户端对请求做HMAC签名（防篡改、防重放）
+	MirrorURL             string            `json:"mirror_url,omitempty"`               // 镜像上游地址：请求副本异步发送到该地址，响应被丢弃（用于验证新后端）
+	MaintenanceMode       bool              `json:"maintenance_mode,omitempty"`         // 维护模式：代理请求直接返回503，不转发给上游
+	MaintenanceMessage    string            `json:"maintenance_message,omitempty"`      // 维护模式下返回给客户端的提示信息
+	ForwardClientHeaders  bool              `json:"forward_client_headers,omitempty"`   // 向上游发送X-Forwarded-For/Proto/Host和X-Real-IP（默认不发送，隐藏客户端信息）
+	UserAgentOverride     string            `json:"user_agent_override,omitempty"`      // 替换转发给上游的User-Agent（为空时透传客户端的User-Agent）
+	BodyTransform         json.RawMessage   `json:"body_transform,omitempty"`           // 合并到JSON请求体的模板对象（JSON Merge Patch），非JSON请求体不受影响
+	ResponseBodyRewrites  []BodyRewrite     `json:"response_body_rewrites,omitempty"`   // 文本响应体的查找替换规则（按顺序应用），如将上游域名改写为网关地址
+	Tags                  map[string]string `json:"tags,omitempty"`                     // 自定义标签（如 team、env），用于分组和筛选
+	Version               int64             `json:"version"`                            // 版本号，每次修改加1，更新时需携带（乐观并发控制）
+	CreatedAt             time.Time         `json:"created_at"`
+	UpdatedAt             time.Time         `json:"updated_at"`
+	DeletedAt             *time.Time        `json:"deleted_at,omitempty"` // 软删除时间，非空表示配置在回收站中
+	Stats                 *ConfigStats      `json:"stats,omitempty"`
+	AccessTokens          []AccessToken     `json:"access_tokens,omitempty"` // 访问令牌列表
+	TokenStats            *TokenStats       `json:"token_stats,omitempty"`   // 令牌统计信息
+}
+
+// LogBodiesMode 配置级请求/响应体日志记录策略
+type LogBodiesMode string
+
+// 请求/响应体日志记录策略常量
+const (
+	LogBodiesInherit LogBodiesMode = "inherit" // 沿用全局LOG_RECORD_200设置（默认）
+	LogBodiesNever   LogBodiesMode = "never"   // 从不记录请求体和响应体（敏感数据）
+	LogBodiesAlways  LogBodiesMode = "always"  // 记录所有状态码的请求体和响应体
+)
+
+// IsValid 检查记录策略是否合法（空值视为inherit）
+func (m LogBodiesMode) IsValid() bool {
+	switch m {
+	case "", LogBodiesInherit, LogBodiesNever, LogBodiesAlways:
+		return true
+	}
+	return false
+}
+
+// RedirectPolicy 配置级上游重定向处理策略
+type RedirectPolicy string
+
+// 重定向处理策略常量（另可使用 limited:N 最多跟随N次）
+const (
+	RedirectNoFollow RedirectPolicy = "no-follow" // 不跟随重定向，3xx响应原样返回给客户端（默认）
+	RedirectFollow   RedirectPolicy = "follow"    // 跟随重定向，最多MaxRedirects次
+)
+
+// MaxRedirects 跟随重定向的最大次数（follow及limited:N的上限）
+const MaxRedirects = 10
+
+// redirectLimitedPrefix limited:N 策略的前缀
+const redirectLimitedPrefix = "limited:"
+
+// MaxRedirects 返回策略允许跟随的重定向次数，0表示不跟随；策略不合法时返回错误
+func (p RedirectPolicy) MaxRedirects() (int, error) {
+	switch p {
+	case "", RedirectNoFollow:
+		return 0, nil
+	case RedirectFollow:
+		return MaxRedirects, nil
+	}
+
+	if rest, ok := strings.CutPrefix(string(p), redirectLimitedPrefix); ok {
+		if n, err := strconv.Atoi(rest); err == nil && n >= 1 && n <= MaxRedirects {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("redirect_policy must be no-follow, follow or limited:N (N between 1 and %d)", MaxRedirects)
 }
 
 // ConfigStats 配置访问统计
@@ -26,14 +121,17 @@ type ConfigStats struct {
 	AvgResponseTime float64   `json:"avg_response_time"` // 平均响应时间(毫秒)
 	LastAccessed    time.Time `json:"last_accessed"`     // 最后访问时间
 	TotalBytes      int64     `json:"total_bytes"`       // 总传输字节数
+
+	CurrentConcurrency int64 `json:"current_concurrency"` // 当前进行中的代理请求数（查询时实时填充，不持久化）
 }
 
 // ConfigFilter 配置筛选条件
 type ConfigFilter struct {
-	Search  string `json:"search"`
-	Enabled *bool  `json:"enabled"`
-	Page    int    `json:"page"`
-	Limit   int    `json:"limit"`
+	Search  string   `json:"search"`
+	Enabled *bool    `json:"enabled"`
+	Tags    []string `json:"tags"` // 标签筛选条件（"key:value" 或 "key"），需全部满足
+	Page    int      `json:"page"`
+	Limit   int      `json:"limit"`
 }
 
 // ConfigResponse 配置列表响应
@@ -50,6 +148,17 @@ type StorageStats struct {
 	TotalConfigs   int `json:"total_configs"`
 	EnabledConfigs int `json:"enabled_configs"`
 	MemoryUsage    int `json:"memory_usage"`
+
+	Persistence *PersistenceStatus `json:"persistence,omitempty"` // 文件持久化状态（仅持久化存储）
+}
+
+// PersistenceStatus 持久化存储的保存状态
+type PersistenceStatus struct {
+	LastSaveAt          *time.Time `json:"last_save_at,omitempty"`  // 最近一次成功保存的时间
+	LastError           string     `json:"last_error,omitempty"`    // 最近一次保存失败的错误
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"` // 最近一次保存失败的时间
+	ConsecutiveFailures int        `json:"consecutive_failures"`    // 连续失败次数，保存成功后清零
+	Degraded            bool       `json:"degraded"`                // 连续失败达到阈值，配置没有被持久化
 }
 
 // BatchOperationRequest 批量操作请求
@@ -80,6 +189,26 @@ type ImportResult struct {
 	SkippedCount  int      `json:"skipped_count"`  // 跳过数量
 	ErrorCount    int      `json:"error_count"`    // 错误数量
 	Errors        []string `json:"errors"`         // 错误信息列表
+
+	ReissuedTokens map[string]map[string]string `json:"reissued_tokens,omitempty"` // 重新签发的令牌值：配置名称 -> 令牌名称 -> 令牌值（仅在本次结果中返回）
+	DroppedTokens  int                          `json:"dropped_tokens,omitempty"`  // 因不含哈希而丢弃的令牌数量
+}
+
+// 导入模式常量
+const (
+	ImportModeSkip    = "skip"    // 跳过冲突配置
+	ImportModeReplace = "replace" // 覆盖冲突配置
+	ImportModeError   = "error"   // 存在冲突时中止导入
+)
+
+// ImportConflictError 导入冲突错误（error模式）
+type ImportConflictError struct {
+	Names []string // 冲突的配置名称
+}
+
+// Error 实现error接口
+func (e *ImportConflictError) Error() string {
+	return fmt.Sprintf("import aborted: %d conflicting config(s): %s", len(e.Names), strings.Join(e.Names, ", "))
 }
 
 // 错误定义
@@ -88,4 +217,7 @@ var (
 	ErrInvalidConfigID    = errors.New("invalid config id")
 	ErrInvalidTargetURL   = errors.New("invalid target url")
 	ErrMaxEntriesExceeded = errors.New("maximum entries exceeded")
+	ErrVersionRequired    = errors.New("config version is required for update")
+	ErrVersionConflict    = errors.New("config has been modified by another request")
+	ErrReloadPending      = errors.New("config changes are waiting to be saved, retry reload after they are written")
 )
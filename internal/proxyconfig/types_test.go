@@ -0,0 +1,31 @@
+package proxyconfig
+
+import "testing"
+
+func TestRedirectPolicy_MaxRedirects(t *testing.T) {
+	tests := []struct {
+		policy  RedirectPolicy
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{RedirectNoFollow, 0, false},
+		{RedirectFollow, MaxRedirects, false},
+		{"limited:3", 3, false},
+		{"limited:0", 0, true},
+		{"limited:99", 0, true},
+		{"limited:x", 0, true},
+		{"always", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.policy.MaxRedirects()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("MaxRedirects(%q) error = %v, wantErr %v", tt.policy, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("MaxRedirects(%q) = %d, want %d", tt.policy, got, tt.want)
+		}
+	}
+}
@@ -2,25 +2,223 @@ package proxyconfig
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"strings"
 )
 
-// ValidateConfig 验证配置
+// 上游重试配置的上限
+const (
+	MaxRetryCount     = 5
+	MaxRetryBackoffMs = 10000
+)
+
+// 配置级上游连接池和超时设置的上限
+const (
+	MaxIdleConnsPerHostLimit = 1000
+	MaxIdleConnTimeout       = 3600 // 秒
+	MaxUpstreamTimeout       = 3600 // 秒，response_header_timeout和read_idle_timeout的上限
+)
+
+// MaxUserAgentLength user_agent_override的最大长度
+const MaxUserAgentLength = 512
+
+// MaxMaintenanceMessageLength maintenance_message的最大长度
+const MaxMaintenanceMessageLength = 1024
+
+// 字段校验错误代码
+const (
+	FieldErrorRequired   = "REQUIRED"
+	FieldErrorTooLong    = "TOO_LONG"
+	FieldErrorInvalid    = "INVALID_VALUE"
+	FieldErrorOutOfRange = "OUT_OF_RANGE"
+)
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field     string `json:"field"`
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// ValidationError 配置校验失败，包含所有字段错误
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// Error 实现 error 接口，多个字段错误以分号连接
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add 添加字段错误
+func (e *ValidationError) add(field, code, message string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, ErrorCode: code, Message: message})
+}
+
+// ValidateConfig 验证配置，失败时返回包含所有字段错误的*ValidationError
 func ValidateConfig(config *ProxyConfig) error {
+	verr := &ValidationError{}
+
 	if config.Name == "" {
-		return errors.New("name is required")
+		verr.add("name", FieldErrorRequired, "name is required")
+	} else if len(config.Name) > 100 {
+		verr.add("name", FieldErrorTooLong, "name too long (max 100 characters)")
 	}
 
-	if len(config.Name) > 100 {
-		return errors.New("name too long (max 100 characters)")
+	if config.TargetURL == "" {
+		verr.add("target_url", FieldErrorRequired, "target_url is required")
+	} else if err := ValidateTargetURL(config.TargetURL); err != nil {
+		verr.add("target_url", FieldErrorInvalid, err.Error())
 	}
 
-	if err := ValidateTargetURL(config.TargetURL); err != nil {
-		return err
+	if config.Protocol != "http" && config.Protocol != "https" {
+		verr.add("protocol", FieldErrorInvalid, "protocol must be http or https")
 	}
 
-	if config.Protocol != "http" && config.Protocol != "https" {
-		return errors.New("protocol must be http or https")
+	if config.CacheTTLSeconds < 0 {
+		verr.add("cache_ttl_seconds", FieldErrorOutOfRange, "cache_ttl_seconds must not be negative")
+	}
+
+	if config.MaxConcurrency < 0 {
+		verr.add("max_concurrency", FieldErrorOutOfRange, "max_concurrency must not be negative")
+	}
+
+	if config.RetryCount < 0 || config.RetryCount > MaxRetryCount {
+		verr.add("retry_count", FieldErrorOutOfRange, fmt.Sprintf("retry_count must be between 0 and %d", MaxRetryCount))
+	}
+
+	if config.RetryBackoffMs < 0 || config.RetryBackoffMs > MaxRetryBackoffMs {
+		verr.add("retry_backoff_ms", FieldErrorOutOfRange, fmt.Sprintf("retry_backoff_ms must be between 0 and %d", MaxRetryBackoffMs))
+	}
+
+	if config.MaxIdleConnsPerHost < 0 || config.MaxIdleConnsPerHost > MaxIdleConnsPerHostLimit {
+		verr.add("max_idle_conns_per_host", FieldErrorOutOfRange, fmt.Sprintf("max_idle_conns_per_host must be between 0 and %d", MaxIdleConnsPerHostLimit))
+	}
+
+	if config.IdleConnTimeout < 0 || config.IdleConnTimeout > MaxIdleConnTimeout {
+		verr.add("idle_conn_timeout", FieldErrorOutOfRange, fmt.Sprintf("idle_conn_timeout must be between 0 and %d", MaxIdleConnTimeout))
+	}
+
+	if config.ResponseHeaderTimeout < 0 || config.ResponseHeaderTimeout > MaxUpstreamTimeout {
+		verr.add("response_header_timeout", FieldErrorOutOfRange, fmt.Sprintf("response_header_timeout must be between 0 and %d", MaxUpstreamTimeout))
+	}
+
+	if config.ReadIdleTimeout < 0 || config.ReadIdleTimeout > MaxUpstreamTimeout {
+		verr.add("read_idle_timeout", FieldErrorOutOfRange, fmt.Sprintf("read_idle_timeout must be between 0 and %d", MaxUpstreamTimeout))
+	}
+
+	if len(config.MaintenanceMessage) > MaxMaintenanceMessageLength {
+		verr.add("maintenance_message", FieldErrorTooLong, fmt.Sprintf("maintenance_message must be at most %d characters", MaxMaintenanceMessageLength))
+	}
+
+	if len(config.UserAgentOverride) > MaxUserAgentLength {
+		verr.add("user_agent_override", FieldErrorOutOfRange, fmt.Sprintf("user_agent_override must be at most %d characters", MaxUserAgentLength))
+	} else if strings.ContainsAny(config.UserAgentOverride, "\r\n\x00") {
+		verr.add("user_agent_override", FieldErrorInvalid, "user_agent_override must not contain control characters")
+	}
+
+	if err := ValidateMirrorURL(config.MirrorURL); err != nil {
+		verr.add("mirror_url", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateUpstreamProxy(config.UpstreamProxy); err != nil {
+		verr.add("upstream_proxy", FieldErrorInvalid, err.Error())
+	}
+
+	if !config.LogBodies.IsValid() {
+		verr.add("log_bodies", FieldErrorInvalid, "log_bodies must be inherit, never or always")
+	}
+
+	if err := ValidatePathRules(config.PathRules); err != nil {
+		verr.add("path_rules", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateBasePath(config.BasePath); err != nil {
+		verr.add("base_path", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateAllowedMethods(config.AllowedMethods); err != nil {
+		verr.add("allowed_methods", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateQueryParams("allowed_query_params", config.AllowedQueryParams); err != nil {
+		verr.add("allowed_query_params", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateQueryParams("denied_query_params", config.DeniedQueryParams); err != nil {
+		verr.add("denied_query_params", FieldErrorInvalid, err.Error())
+	}
+
+	if _, err := config.RedirectPolicy.MaxRedirects(); err != nil {
+		verr.add("redirect_policy", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateBasicAuth(config.BasicAuthUser, config.BasicAuthPasswordHash); err != nil {
+		field := "basic_auth_password_hash"
+		if strings.HasPrefix(err.Error(), "basic_auth_user") {
+			field = "basic_auth_user"
+		}
+		verr.add(field, FieldErrorInvalid, err.Error())
+	}
+
+	if config.OwnerSecretHash != "" {
+		if err := validateSecretHash("owner_secret_hash", config.OwnerSecretHash); err != nil {
+			verr.add("owner_secret_hash", FieldErrorInvalid, err.Error())
+		}
+	}
+
+	if err := ValidateRequestSigning(config.RequestSigning); err != nil {
+		verr.add("request_signing", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateBodyRewrites(config.ResponseBodyRewrites); err != nil {
+		verr.add("response_body_rewrites", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateBodyTransform(config.BodyTransform); err != nil {
+		verr.add("body_transform", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateTags(config.Tags); err != nil {
+		verr.add("tags", FieldErrorInvalid, err.Error())
+	}
+
+	if err := ValidateClientTLS(config); err != nil {
+		field := "client_cert"
+		if strings.Contains(err.Error(), "ca_cert") {
+			field = "ca_cert"
+		}
+		verr.add(field, FieldErrorInvalid, err.Error())
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// ValidateUpstreamProxy 验证配置级出站代理（为空或"direct"时不校验）
+func ValidateUpstreamProxy(upstreamProxy string) error {
+	if upstreamProxy == "" || upstreamProxy == "direct" {
+		return nil
+	}
+
+	u, err := url.Parse(upstreamProxy)
+	if err != nil {
+		return errors.New("invalid upstream_proxy format")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return errors.New("upstream_proxy must use http, https or socks5")
+	}
+
+	if u.Host == "" {
+		return errors.New("upstream_proxy must have a host")
 	}
 
 	return nil
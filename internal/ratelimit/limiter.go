@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket 单个客户端的令牌桶
+type bucket struct {
+	tokens   float64   // 当前可用令牌数
+	lastSeen time.Time // 最后一次补充令牌的时间
+}
+
+// maxBuckets 跟踪的客户端数量上限，达到上限时先清理已补满的桶，仍不够时随机淘汰一部分
+const maxBuckets = 100000
+
+// IPLimiter 基于令牌桶的按客户端IP限流器
+type IPLimiter struct {
+	rate  float64 // 每秒补充的令牌数
+	burst float64 // 桶容量（允许的突发请求数）
+
+	buckets     map[string]*bucket
+	maxBuckets  int
+	mutex       sync.Mutex
+	idleTimeout time.Duration // 空闲桶的清理时间
+	lastCleanup time.Time
+
+	now func() time.Time // 时间源（便于测试）
+}
+
+// NewIPLimiter 创建限流器，perMinute为每分钟允许的请求数，burst为突发容量
+func NewIPLimiter(perMinute int, burst int) *IPLimiter {
+	if burst <= 0 {
+		burst = perMinute
+	}
+
+	return &IPLimiter{
+		rate:        float64(perMinute) / 60,
+		burst:       float64(burst),
+		buckets:     make(map[string]*bucket),
+		maxBuckets:  maxBuckets,
+		idleTimeout: 10 * time.Minute,
+		lastCleanup: time.Now(),
+		now:         time.Now,
+	}
+}
+
+// Allow 判断该IP的请求是否放行，被拒绝时返回建议的重试等待时间
+func (l *IPLimiter) Allow(ip string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.cleanupLocked(now)
+
+	b, exists := l.buckets[ip]
+	if !exists {
+		if len(l.buckets) >= l.maxBuckets {
+			l.evictLocked(now)
+		}
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[ip] = b
+	} else {
+		// 按流逝时间补充令牌
+		elapsed := now.Sub(b.lastSeen).Seconds()
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.lastSeen = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	// 计算获得下一个令牌所需的时间
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanupLocked 清理长时间未访问的桶，避免内存无限增长（调用方需持有锁）
+func (l *IPLimiter) cleanupLocked(now time.Time) {
+	if now.Sub(l.lastCleanup) < l.idleTimeout {
+		return
+	}
+
+	for ip, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idleTimeout {
+			delete(l.buckets, ip)
+		}
+	}
+	l.lastCleanup = now
+}
+
+// evictLocked 桶数量达到上限时腾出空间（调用方需持有锁）
+//
+// 已补满的桶与新建的桶等价，删除不影响限流结果；全部都未补满时随机淘汰十分之一。
+func (l *IPLimiter) evictLocked(now time.Time) {
+	refillTime := time.Duration(l.burst / l.rate * float64(time.Second))
+	for ip, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= refillTime {
+			delete(l.buckets, ip)
+		}
+	}
+	if len(l.buckets) < l.maxBuckets {
+		return
+	}
+
+	evict := l.maxBuckets / 10
+	if evict < 1 {
+		evict = 1
+	}
+	for ip := range l.buckets {
+		if evict == 0 {
+			break
+		}
+		delete(l.buckets, ip)
+		evict--
+	}
+}
+
+// Size 返回当前跟踪的客户端数量
+func (l *IPLimiter) Size() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.buckets)
+}
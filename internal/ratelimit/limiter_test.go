@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIPLimiter_ThrottlesAfterBurst(t *testing.T) {
+	limiter := NewIPLimiter(60, 3)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow("10.0.0.1"); !allowed {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+
+	allowed, retryAfter := limiter.Allow("10.0.0.1")
+	if allowed {
+		t.Fatal("Expected request beyond burst to be throttled")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Expected retry after within 1s at 60/min, got %v", retryAfter)
+	}
+
+	// 其他IP不受影响
+	if allowed, _ := limiter.Allow("10.0.0.2"); !allowed {
+		t.Error("Expected a different IP to be unaffected")
+	}
+}
+
+func TestIPLimiter_RefillsOverTime(t *testing.T) {
+	limiter := NewIPLimiter(60, 1)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("10.0.0.1")
+	if allowed, _ := limiter.Allow("10.0.0.1"); allowed {
+		t.Fatal("Expected second request to be throttled")
+	}
+
+	now = now.Add(time.Second)
+	if allowed, _ := limiter.Allow("10.0.0.1"); !allowed {
+		t.Error("Expected a token to be refilled after one second")
+	}
+}
+
+func TestIPLimiter_CleansUpIdleBuckets(t *testing.T) {
+	limiter := NewIPLimiter(60, 1)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("10.0.0.1")
+	now = now.Add(limiter.idleTimeout + time.Second)
+	limiter.Allow("10.0.0.2")
+
+	if limiter.Size() != 1 {
+		t.Errorf("Expected idle bucket to be removed, got %d buckets", limiter.Size())
+	}
+}
+
+func TestIPLimiter_BoundsBucketCount(t *testing.T) {
+	limiter := NewIPLimiter(60, 1)
+	limiter.maxBuckets = 10
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	// 大量不同客户端不会让桶数量超过上限
+	for i := 0; i < 100; i++ {
+		limiter.Allow(fmt.Sprintf("10.0.0.%d", i))
+		if size := limiter.Size(); size > 10 {
+			t.Fatalf("Expected at most 10 buckets, got %d", size)
+		}
+	}
+
+	// 优先清理已补满的桶，正在限流的客户端保留
+	limiter = NewIPLimiter(60, 1)
+	limiter.maxBuckets = 3
+	limiter.now = func() time.Time { return now }
+	limiter.Allow("10.0.1.1")
+	limiter.Allow("10.0.1.2")
+	now = now.Add(2 * time.Second)
+	limiter.Allow("10.0.1.3")
+	limiter.Allow("10.0.1.4")
+	if limiter.Size() != 2 {
+		t.Errorf("Expected refilled buckets to be evicted first, got %d buckets", limiter.Size())
+	}
+	if allowed, _ := limiter.Allow("10.0.1.3"); allowed {
+		t.Error("Expected throttled client to keep its bucket")
+	}
+}
@@ -0,0 +1,115 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// storageLoadChecker 可报告启动加载错误的配置存储（如持久化存储）
+type storageLoadChecker interface {
+	LoadError() error
+}
+
+// storageSaveChecker 可报告持续写入失败的配置存储（如磁盘已满或只读时的持久化存储）
+type storageSaveChecker interface {
+	SaveError() error
+}
+
+// componentStatus 组件健康状态
+type componentStatus struct {
+	Status string `json:"status"`          // ok / degraded / error / disabled
+	Error  string `json:"error,omitempty"` // 错误信息
+}
+
+// setupHealthRoutes 设置健康检查路由（无需认证）
+func (r *Router) setupHealthRoutes() {
+	http.HandleFunc("/healthz", r.HandleHealthz)
+	http.HandleFunc("/readyz", r.HandleReadyz)
+}
+
+// HandleHealthz 存活检查：进程能够响应即返回200
+func (r *Router) HandleHealthz(w http.ResponseWriter, req *http.Request) {
+	writeHealthResponse(w, http.StatusOK, map[string]interface{}{
+		"status":    "ok",
+		"timestamp": time.Now(),
+	})
+}
+
+// HandleReadyz 就绪检查：配置存储加载成功且日志记录器（如已配置）可写时返回200
+//
+// 配置存储连续保存失败时仍返回200（代理继续使用内存中的配置），但状态为degraded。
+func (r *Router) HandleReadyz(w http.ResponseWriter, req *http.Request) {
+	components := map[string]componentStatus{
+		"config_storage": r.checkConfigStorage(),
+		"access_log":     r.checkRecorder(),
+	}
+
+	ready := true
+	degraded := false
+	for _, component := range components {
+		switch component.Status {
+		case "error":
+			ready = false
+		case "degraded":
+			degraded = true
+		}
+	}
+
+	status := "ok"
+	statusCode := http.StatusOK
+	if !ready {
+		status = "unavailable"
+		statusCode = http.StatusServiceUnavailable
+	} else if degraded {
+		status = "degraded"
+	}
+
+	writeHealthResponse(w, statusCode, map[string]interface{}{
+		"status":     status,
+		"components": components,
+		"timestamp":  time.Now(),
+	})
+}
+
+// checkConfigStorage 检查配置存储状态
+func (r *Router) checkConfigStorage() componentStatus {
+	if r.configStorage == nil {
+		return componentStatus{Status: "error", Error: "config storage not initialized"}
+	}
+
+	if checker, ok := r.configStorage.(storageLoadChecker); ok {
+		if err := checker.LoadError(); err != nil {
+			return componentStatus{Status: "error", Error: err.Error()}
+		}
+	}
+
+	if checker, ok := r.configStorage.(storageSaveChecker); ok {
+		if err := checker.SaveError(); err != nil {
+			return componentStatus{Status: "degraded", Error: err.Error()}
+		}
+	}
+
+	return componentStatus{Status: "ok"}
+}
+
+// checkRecorder 检查访问日志记录器状态（未配置时视为disabled）
+func (r *Router) checkRecorder() componentStatus {
+	if r.recorder == nil {
+		return componentStatus{Status: "disabled"}
+	}
+
+	if err := r.recorder.Writable(); err != nil {
+		return componentStatus{Status: "error", Error: err.Error()}
+	}
+
+	return componentStatus{Status: "ok"}
+}
+
+// writeHealthResponse 写入健康检查JSON响应
+func writeHealthResponse(w http.ResponseWriter, statusCode int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"privacygateway/internal/handler"
+)
+
+// handleFunc 注册路由并应用全局中间件
+func (r *Router) handleFunc(pattern string, handlerFunc http.HandlerFunc) {
+	http.HandleFunc(pattern, r.withErrorPages(r.withRateLimit(r.withHeaderLimits(handlerFunc))))
+}
+
+// withHeaderLimits 请求头数量和总大小限制中间件，超出时返回431
+func (r *Router) withHeaderLimits(next http.HandlerFunc) http.HandlerFunc {
+	return handler.WithRequestHeaderLimits(next, r.cfg, r.log)
+}
+
+// withErrorPages 浏览器请求的网关错误以HTML错误页返回（未配置ERROR_PAGES_DIR时不包装）
+func (r *Router) withErrorPages(next http.HandlerFunc) http.HandlerFunc {
+	if r.errorPages == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		next(r.errorPages.Wrap(w, req), req)
+	}
+}
+
+// withRateLimit 单IP限流中间件，超出限制时返回429和Retry-After
+func (r *Router) withRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	if r.ipLimiter == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		// 按对端地址限流，转发头只在来自TRUSTED_PROXIES时采用，防止伪造X-Forwarded-For绕过限流
+		clientIP := handler.ClientIP(req, r.cfg.TrustedProxies)
+
+		allowed, retryAfter := r.ipLimiter.Allow(clientIP)
+		if allowed {
+			next(w, req)
+			return
+		}
+
+		retrySeconds := int(math.Ceil(retryAfter.Seconds()))
+		if retrySeconds < 1 {
+			retrySeconds = 1
+		}
+
+		r.log.Warn("request rate limited",
+			"client_ip", clientIP,
+			"path", req.URL.Path,
+			"retry_after", retrySeconds)
+
+		r.addCORSHeaders(w, req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(retrySeconds))
+		w.WriteHeader(http.StatusTooManyRequests)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":       "Too Many Requests",
+			"message":     "Rate limit exceeded, please retry later",
+			"error_code":  "RATE_LIMITED",
+			"retry_after": retrySeconds,
+			"status":      http.StatusTooManyRequests,
+			"success":     false,
+		})
+	}
+}
+
+// withMetrics 记录代理请求的耗时和结果（5xx视为失败）
+func (r *Router) withMetrics(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		sw := &metricsStatusWriter{ResponseWriter: w, status: http.StatusOK}
+		next(sw, req)
+		r.metrics.RecordRequest(time.Since(start), sw.status < http.StatusInternalServerError)
+	}
+}
+
+// metricsStatusWriter 记录响应状态码的ResponseWriter
+type metricsStatusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader 记录状态码
+func (sw *metricsStatusWriter) WriteHeader(statusCode int) {
+	if !sw.wroteHeader {
+		sw.status = statusCode
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush 支持流式响应
+func (sw *metricsStatusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 返回被包装的ResponseWriter（供http.ResponseController使用）
+func (sw *metricsStatusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -3,13 +3,18 @@ package router
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"privacygateway/internal/accesslog"
+	"privacygateway/internal/audit"
+	"privacygateway/internal/cache"
 	"privacygateway/internal/config"
 	"privacygateway/internal/handler"
 	"privacygateway/internal/logger"
 	"privacygateway/internal/logviewer"
+	"privacygateway/internal/metrics"
 	"privacygateway/internal/proxyconfig"
+	"privacygateway/internal/ratelimit"
 )
 
 // Router 路由器结构
@@ -19,11 +24,34 @@ type Router struct {
 	recorder      *accesslog.Recorder
 	configStorage proxyconfig.Storage
 	tokenHandler  *handler.TokenAPIHandler
+	responseCache *cache.ResponseCache
+	ipLimiter     *ratelimit.IPLimiter // 单IP限流器（未启用时为nil）
+	auditRecorder *audit.Recorder      // 审计日志记录器（未启用时为nil）
+	metrics       *metrics.Metrics     // 代理请求指标收集器
+	errorPages    *handler.ErrorPages  // 浏览器请求的HTML错误页（未配置时为nil）
 }
 
 // NewRouter 创建新的路由器
 func NewRouter(cfg *config.Config, log *logger.Logger, recorder *accesslog.Recorder, configStorage proxyconfig.Storage) *Router {
-	tokenHandler := handler.NewTokenAPIHandler(configStorage, cfg.AdminSecret, log)
+	tokenHandler := handler.NewTokenAPIHandler(configStorage, cfg.AdminSecret, log, cfg.AdminSecretPrevious)
+
+	var ipLimiter *ratelimit.IPLimiter
+	if cfg.IPRateLimit > 0 {
+		ipLimiter = ratelimit.NewIPLimiter(cfg.IPRateLimit, cfg.IPRateLimitBurst)
+	}
+
+	var errorPages *handler.ErrorPages
+	if cfg.ErrorPagesDir != "" {
+		pages, err := handler.LoadErrorPages(cfg.ErrorPagesDir)
+		if err != nil {
+			log.Error("failed to load error pages, falling back to JSON errors", "dir", cfg.ErrorPagesDir, "error", err)
+		} else {
+			errorPages = pages
+		}
+	}
+
+	routerMetrics := metrics.NewMetricsWithHistory(cfg.MetricsHistoryLength, time.Duration(cfg.MetricsHistoryIntervalSeconds)*time.Second)
+	handler.SetMetrics(routerMetrics)
 
 	return &Router{
 		cfg:           cfg,
@@ -31,9 +59,24 @@ func NewRouter(cfg *config.Config, log *logger.Logger, recorder *accesslog.Recor
 		recorder:      recorder,
 		configStorage: configStorage,
 		tokenHandler:  tokenHandler,
+		responseCache: cache.NewResponseCache(int64(cfg.ResponseCacheMaxMB * 1024 * 1024)),
+		ipLimiter:     ipLimiter,
+		metrics:       routerMetrics,
+		errorPages:    errorPages,
 	}
 }
 
+// SetAuditRecorder 设置审计日志记录器（需在SetupRoutes之前调用）
+func (r *Router) SetAuditRecorder(recorder *audit.Recorder) {
+	r.auditRecorder = recorder
+	r.tokenHandler.SetAuditRecorder(recorder)
+}
+
+// Metrics 获取代理请求指标收集器
+func (r *Router) Metrics() *metrics.Metrics {
+	return r.metrics
+}
+
 // SetupRoutes 设置所有路由
 func (r *Router) SetupRoutes() {
 	// 设置中间件
@@ -47,55 +90,83 @@ func (r *Router) SetupRoutes() {
 
 	// 设置日志查看路由
 	r.setupLogRoutes()
+
+	// 设置健康检查路由
+	r.setupHealthRoutes()
 }
 
 // setupMiddleware 设置全局中间件
 func (r *Router) setupMiddleware() {
-	// 这里可以添加全局中间件，如日志记录、CORS等
-	// 由于使用的是标准库的http包，中间件需要在每个处理器中单独处理
+	// 由于使用的是标准库的http包，中间件通过 r.handleFunc 在注册路由时统一包装
+	if r.ipLimiter != nil {
+		r.log.Info("ip rate limit enabled", "per_minute", r.cfg.IPRateLimit, "burst", r.cfg.IPRateLimitBurst)
+	}
 }
 
 // setupMainRoutes 设置主要路由
 func (r *Router) setupMainRoutes() {
 	// 根路径处理器 - 支持静态文件和子域名代理
-	http.HandleFunc("/", r.HandleRoot)
+	r.handleFunc("/", r.HandleRoot)
 
 	// HTTP代理路由
-	http.HandleFunc("/proxy", r.HandleHTTPProxy)
+	r.handleFunc("/proxy", r.withMetrics(r.HandleHTTPProxy))
 
 	// WebSocket路由
-	http.HandleFunc("/ws", r.HandleWebSocket)
+	r.handleFunc("/ws", r.HandleWebSocket)
 }
 
 // setupAPIRoutes 设置API路由
 func (r *Router) setupAPIRoutes() {
 	// 代理配置管理API
-	http.HandleFunc("/config/proxy", r.HandleProxyConfigAPI)
+	r.handleFunc("/config/proxy", r.HandleProxyConfigAPI)
 
 	// 配置导入导出API
-	http.HandleFunc("/config/proxy/export", r.HandleProxyConfigExportAPI)
-	http.HandleFunc("/config/proxy/import", r.HandleProxyConfigImportAPI)
+	r.handleFunc("/config/proxy/export", r.HandleProxyConfigExportAPI)
+	r.handleFunc("/config/proxy/import", r.HandleProxyConfigImportAPI)
 
 	// 批量操作API
-	http.HandleFunc("/config/proxy/batch", r.HandleProxyConfigBatchAPI)
+	r.handleFunc("/config/proxy/batch", r.HandleProxyConfigBatchAPI)
 
 	// 令牌管理API（通用路由）
-	http.HandleFunc("/config/proxy/", r.HandleProxyConfigOrTokenAPI)
+	r.handleFunc("/config/proxy/", r.HandleProxyConfigOrTokenAPI)
+
+	// 审计日志API（只读）
+	r.handleFunc("/audit", r.HandleAuditAPI)
+	r.handleFunc("/audit/verify", r.HandleAuditVerifyAPI)
+
+	// 指标清零API
+	r.handleFunc("/metrics/reset", r.HandleMetricsResetAPI)
 }
 
 // setupLogRoutes 设置日志查看路由
 func (r *Router) setupLogRoutes() {
 	if r.recorder != nil {
 		// 验证日志查看器配置
-		if _, err := logviewer.CreateAuthenticator(r.cfg.AdminSecret); err != nil {
+		if _, err := logviewer.CreateAuthenticator(r.cfg.AdminSecret, r.cfg.AdminSecretPrevious); err != nil {
 			r.log.Error("log viewer configuration error", "error", err.Error())
 			r.log.Info("log viewer disabled", "reason", "invalid secret configuration")
 		}
 
 		// 注册日志查看路由
-		logHandler := logviewer.CreateLogViewHandler(r.recorder, r.cfg.AdminSecret, r.log)
-		http.HandleFunc("/logs", logHandler)
-		http.HandleFunc("/logs/", logHandler)
+		logHandler := logviewer.CreateLogViewHandler(r.recorder, r.cfg.AdminSecret, r.log, r.statusData, r.cfg.AdminSecretPrevious)
+		r.handleFunc("/logs", logHandler)
+		r.handleFunc("/logs/", logHandler)
+
+		// 状态页（/logs/status 的别名）
+		r.handleFunc("/status", func(w http.ResponseWriter, req *http.Request) {
+			statusReq := req.Clone(req.Context())
+			statusReq.URL.Path = "/logs/status"
+			logHandler(w, statusReq)
+		})
+	}
+}
+
+// statusData 状态页中的指标、健康状态与配置统计
+func (r *Router) statusData() *logviewer.StatusData {
+	return &logviewer.StatusData{
+		Health:  r.metrics.GetHealthStatus(),
+		Metrics: r.metrics.GetSnapshot(),
+		Configs: r.configStorage.GetStats(),
 	}
 }
 
@@ -113,7 +184,7 @@ func (r *Router) HandleRoot(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// 处理静态文件
-	handler.Static(w, req, r.log)
+	handler.Static(w, req, r.cfg, r.log)
 }
 
 // HandleHTTPProxy 处理HTTP代理请求
@@ -128,12 +199,12 @@ func (r *Router) HandleHTTPProxy(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// 使用支持令牌认证的HTTP代理处理器
-	handler.HTTPProxyWithTokenAuth(w, req, r.cfg, r.log, r.recorder, r.configStorage)
+	handler.HTTPProxyWithTokenAuth(w, req, r.cfg, r.log, r.recorder, r.configStorage, r.responseCache)
 }
 
 // HandleWebSocket 处理WebSocket请求
 func (r *Router) HandleWebSocket(w http.ResponseWriter, req *http.Request) {
-	handler.WebSocket(w, req, r.cfg, r.log, r.recorder)
+	handler.WebSocket(w, req, r.cfg, r.log, r.recorder, r.configStorage)
 }
 
 // HandleProxyConfigAPI 处理代理配置API请求
@@ -147,7 +218,7 @@ func (r *Router) HandleProxyConfigAPI(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage)
+	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage, r.auditRecorder)
 }
 
 // HandleProxyConfigExportAPI 处理配置导出API请求
@@ -161,7 +232,7 @@ func (r *Router) HandleProxyConfigExportAPI(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage)
+	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage, r.auditRecorder)
 }
 
 // HandleProxyConfigImportAPI 处理配置导入API请求
@@ -175,7 +246,7 @@ func (r *Router) HandleProxyConfigImportAPI(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage)
+	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage, r.auditRecorder)
 }
 
 // HandleProxyConfigBatchAPI 处理批量操作API请求
@@ -189,7 +260,7 @@ func (r *Router) HandleProxyConfigBatchAPI(w http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage)
+	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage, r.auditRecorder)
 }
 
 // HandleProxyConfigOrTokenAPI 处理代理配置或令牌API请求
@@ -209,39 +280,161 @@ func (r *Router) HandleProxyConfigOrTokenAPI(w http.ResponseWriter, req *http.Re
 		return
 	}
 
+	// 检查是否是配置统计API请求
+	if strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "/stats") {
+		handler.HandleConfigStatsAPI(w, req, r.cfg, r.log, r.configStorage, r.auditRecorder)
+		return
+	}
+
+	// 检查是否是配置测试API请求
+	if strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "/test") {
+		handler.HandleConfigTestAPI(w, req, r.cfg, r.log, r.configStorage)
+		return
+	}
+
 	// 否则交给配置管理API处理
-	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage)
+	handler.HandleProxyConfigAPI(w, req, r.cfg, r.log, r.configStorage, r.auditRecorder)
+}
+
+// HandleAuditAPI 处理审计日志查询请求
+func (r *Router) HandleAuditAPI(w http.ResponseWriter, req *http.Request) {
+	// 添加CORS支持
+	r.addCORSHeaders(w, req)
+
+	// 处理预检请求
+	if req.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	handler.HandleAuditAPI(w, req, r.cfg, r.log, r.auditRecorder)
+}
+
+// HandleAuditVerifyAPI 处理审计日志哈希链校验请求
+func (r *Router) HandleAuditVerifyAPI(w http.ResponseWriter, req *http.Request) {
+	// 添加CORS支持
+	r.addCORSHeaders(w, req)
+
+	// 处理预检请求
+	if req.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	handler.HandleAuditVerifyAPI(w, req, r.cfg, r.log, r.auditRecorder)
+}
+
+// HandleMetricsResetAPI 处理指标清零请求
+func (r *Router) HandleMetricsResetAPI(w http.ResponseWriter, req *http.Request) {
+	// 添加CORS支持
+	r.addCORSHeaders(w, req)
+
+	// 处理预检请求
+	if req.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	handler.HandleMetricsResetAPI(w, req, r.cfg, r.log, r.metrics, r.auditRecorder)
 }
 
 // addCORSHeaders 添加CORS头
+//
+// 来源为通配符时返回*；否则仅当请求的Origin在允许列表中时回显该来源，
+// 并设置 Vary: Origin 以免缓存混用不同来源的响应。
 func (r *Router) addCORSHeaders(w http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	allowAll := r.corsAllowsAllOrigins()
+
+	if !allowAll || r.cfg.CORSAllowCredentials {
+		w.Header().Add("Vary", "Origin")
+	}
+
+	switch {
+	case allowAll && !r.cfg.CORSAllowCredentials:
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	case origin != "" && (allowAll || r.isCORSOriginAllowed(origin)):
+		// 携带凭据时浏览器不接受*，需回显具体来源
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if r.cfg.CORSAllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
 	// 设置CORS头
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Log-Secret, X-Proxy-Token, X-Config-ID")
-	w.Header().Set("Access-Control-Expose-Headers", "Content-Type, Content-Length")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	requestIDHeader := handler.RequestIDHeader(r.cfg)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Log-Secret, X-Proxy-Token, X-Config-ID, "+requestIDHeader)
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Type, Content-Length, "+requestIDHeader)
 	w.Header().Set("Access-Control-Max-Age", "86400") // 24小时
 }
 
+// corsAllowsAllOrigins 是否允许所有来源（未配置时默认允许）
+func (r *Router) corsAllowsAllOrigins() bool {
+	if len(r.cfg.CORSAllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range r.cfg.CORSAllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// isCORSOriginAllowed 检查来源是否在允许列表中（不区分大小写）
+func (r *Router) isCORSOriginAllowed(origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, allowed := range r.cfg.CORSAllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsOriginsDescription 返回CORS来源配置的描述
+func (r *Router) corsOriginsDescription() string {
+	if r.corsAllowsAllOrigins() {
+		return "*"
+	}
+	return strings.Join(r.cfg.CORSAllowedOrigins, ", ")
+}
+
 // GetRouteInfo 获取路由信息
 func (r *Router) GetRouteInfo() map[string]interface{} {
 	return map[string]interface{}{
 		"routes": map[string]interface{}{
 			"main": map[string]string{
-				"/":      "静态文件服务 / 子域名代理",
-				"/proxy": "HTTP代理服务",
+				"/":        "静态文件服务 / 子域名代理",
+				"/proxy":   "HTTP代理服务",
+				"/healthz": "存活检查",
+				"/readyz":  "就绪检查",
 			},
 			"api": map[string]string{
-				"/config/proxy":                             "代理配置管理API",
-				"/config/proxy/export":                      "配置导出API",
-				"/config/proxy/import":                      "配置导入API",
-				"/config/proxy/batch":                       "批量操作API",
-				"/config/proxy/{configID}/tokens":           "令牌管理API - 列表/创建",
-				"/config/proxy/{configID}/tokens/{tokenID}": "令牌管理API - 获取/更新/删除",
+				"/config/proxy":                                   "代理配置管理API",
+				"/config/proxy/export":                            "配置导出API",
+				"/config/proxy/import":                            "配置导入API",
+				"/config/proxy/import/diff":                       "导入差异预览API",
+				"/config/proxy/batch":                             "批量操作API",
+				"/config/proxy/search":                            "搜索API - 跨配置和令牌搜索",
+				"/config/proxy/deleted":                           "回收站API - 已删除配置列表",
+				"/config/proxy/{configID}/restore":                "回收站API - 恢复配置",
+				"/config/proxy/{configID}/tokens":                 "令牌管理API - 列表/创建",
+				"/config/proxy/{configID}/tokens/{tokenID}":       "令牌管理API - 获取/更新/删除",
+				"/config/proxy/{configID}/tokens/{tokenID}/stats": "令牌统计清零API",
+				"/config/proxy/{configID}/tokens/{tokenID}/usage": "令牌使用历史API",
+				"/config/proxy/{configID}/tokens/idle":            "闲置令牌报告API",
+				"/config/proxy/{configID}/stats":                  "配置统计信息API - 查询/清零",
+				"/config/proxy/{configID}/test":                   "配置测试API - 服务端发送示例请求",
+				"/config/proxy/{configID}/export":                 "单个配置导出API",
+				"/audit":                                          "审计日志查询API",
+				"/metrics/reset":                                  "指标清零API",
 			},
 			"logs": map[string]string{
-				"/logs":  "访问日志查看",
-				"/logs/": "访问日志详情",
+				"/logs":   "访问日志查看",
+				"/logs/":  "访问日志详情",
+				"/status": "系统状态页",
 			},
 		},
 		"authentication": map[string]interface{}{
@@ -255,9 +448,10 @@ func (r *Router) GetRouteInfo() map[string]interface{} {
 			},
 		},
 		"cors": map[string]interface{}{
-			"enabled": true,
-			"origins": "*",
-			"methods": []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			"enabled":     true,
+			"origins":     r.corsOriginsDescription(),
+			"credentials": r.cfg.CORSAllowCredentials,
+			"methods":     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 			"headers": []string{
 				"Content-Type",
 				"Authorization",
@@ -277,25 +471,36 @@ func (r *Router) PrintRoutes() {
 	r.log.Info("主要服务:")
 	r.log.Info("  /           - 静态文件服务 / 子域名代理")
 	r.log.Info("  /proxy      - HTTP代理服务")
+	r.log.Info("  /healthz    - 存活检查")
+	r.log.Info("  /readyz     - 就绪检查")
 
 	r.log.Info("API端点:")
 	r.log.Info("  /config/proxy                              - 代理配置管理")
 	r.log.Info("  /config/proxy/export                       - 配置导出")
 	r.log.Info("  /config/proxy/import                       - 配置导入")
 	r.log.Info("  /config/proxy/batch                        - 批量操作")
+	r.log.Info("  /config/proxy/search                       - 跨配置和令牌搜索")
+	r.log.Info("  /config/proxy/deleted                      - 回收站（已删除配置）")
+	r.log.Info("  /config/proxy/{configID}/restore          - 恢复已删除配置")
 	r.log.Info("  /config/proxy/{configID}/tokens           - 令牌列表/创建")
 	r.log.Info("  /config/proxy/{configID}/tokens/{tokenID} - 令牌操作")
+	r.log.Info("  /config/proxy/{configID}/test             - 配置测试（服务端示例请求）")
+	r.log.Info("  /audit                                     - 审计日志查询")
+	r.log.Info("  /metrics/reset                             - 指标清零")
 
 	if r.recorder != nil {
 		r.log.Info("日志服务:")
 		r.log.Info("  /logs       - 访问日志查看")
 		r.log.Info("  /logs/      - 访问日志详情")
+		r.log.Info("  /status     - 系统状态页")
 	}
 
 	r.log.Info("认证方式:")
 	r.log.Info("  管理员密钥: X-Log-Secret 请求头 或 ?secret= 查询参数")
 	r.log.Info("  令牌认证:   X-Proxy-Token 请求头 或 ?token= 查询参数")
 
-	r.log.Info("CORS支持: 已启用，允许所有来源")
+	r.log.Info("CORS支持: 已启用",
+		"origins", r.corsOriginsDescription(),
+		"credentials", r.cfg.CORSAllowCredentials)
 	r.log.Info("================================")
 }
@@ -2,12 +2,18 @@ package router
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"privacygateway/internal/audit"
 	"privacygateway/internal/config"
+	"privacygateway/internal/handler"
 	"privacygateway/internal/logger"
 	"privacygateway/internal/proxyconfig"
 )
@@ -66,7 +72,7 @@ func TestRouter_SetupRoutes(t *testing.T) {
 	}
 
 	tokenAuth := auth["token"].(map[string]string)
-	if tokenAuth["header_primary"] != "X-Proxy-Token" {
+	if tokenAuth["header"] != "X-Proxy-Token" || tokenAuth["query_parameter"] != "token" {
 		t.Error("Token auth header not properly configured")
 	}
 
@@ -78,6 +84,11 @@ func TestRouter_SetupRoutes(t *testing.T) {
 	if cors["origins"] != "*" {
 		t.Error("CORS origins not properly configured")
 	}
+	// 与Access-Control-Allow-Methods响应头保持一致
+	methods := strings.Join(cors["methods"].([]string), ", ")
+	if methods != "GET, POST, PUT, PATCH, DELETE, OPTIONS" {
+		t.Errorf("Expected CORS methods to match Access-Control-Allow-Methods, got %q", methods)
+	}
 }
 
 func TestRouter_CORSHeaders(t *testing.T) {
@@ -95,7 +106,7 @@ func TestRouter_CORSHeaders(t *testing.T) {
 			method: "OPTIONS",
 			expected: map[string]string{
 				"Access-Control-Allow-Origin":  "*",
-				"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE, OPTIONS",
+				"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 				"Access-Control-Max-Age":       "86400",
 			},
 		},
@@ -105,7 +116,7 @@ func TestRouter_CORSHeaders(t *testing.T) {
 			method: "OPTIONS",
 			expected: map[string]string{
 				"Access-Control-Allow-Origin":  "*",
-				"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE, OPTIONS",
+				"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 				"Access-Control-Max-Age":       "86400",
 			},
 		},
@@ -119,9 +130,9 @@ func TestRouter_CORSHeaders(t *testing.T) {
 			// 根据路径调用相应的处理器
 			switch {
 			case tt.path == "/proxy":
-				router.handleHTTPProxy(w, req)
+				router.HandleHTTPProxy(w, req)
 			case strings.HasPrefix(tt.path, "/config/proxy"):
-				router.handleProxyConfigAPI(w, req)
+				router.HandleProxyConfigAPI(w, req)
 			}
 
 			// 验证CORS头
@@ -146,7 +157,6 @@ func TestRouter_TokenAPIRouting(t *testing.T) {
 	// 创建测试配置
 	config := &proxyconfig.ProxyConfig{
 		Name:      "Test Config",
-		Subdomain: "test",
 		TargetURL: "https://example.com",
 		Enabled:   true,
 	}
@@ -195,7 +205,7 @@ func TestRouter_TokenAPIRouting(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			// 调用通用的配置/令牌API处理器
-			router.handleProxyConfigOrTokenAPI(w, req)
+			router.HandleProxyConfigOrTokenAPI(w, req)
 
 			// 验证状态码
 			if w.Code != tt.expectedStatus {
@@ -261,7 +271,7 @@ func TestRouter_AuthenticationHeaders(t *testing.T) {
 			}
 
 			w := httptest.NewRecorder()
-			router.handleProxyConfigAPI(w, req)
+			router.HandleProxyConfigAPI(w, req)
 
 			if w.Code != tt.expectedStatus {
 				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
@@ -328,7 +338,7 @@ func TestRouter_MiddlewareIntegration(t *testing.T) {
 	req.Header.Set("Origin", "https://example.com")
 	w := httptest.NewRecorder()
 
-	router.handleHTTPProxy(w, req)
+	router.HandleHTTPProxy(w, req)
 
 	// 验证CORS头是否被添加
 	corsHeaders := []string{
@@ -345,3 +355,564 @@ func TestRouter_MiddlewareIntegration(t *testing.T) {
 		}
 	}
 }
+
+func TestRouter_Healthz(t *testing.T) {
+	router := setupRouterTest()
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	router.HandleHealthz(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestRouter_ReadyzStorageLoaded(t *testing.T) {
+	router := setupRouterTest()
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.HandleReadyz(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	components := response["components"].(map[string]interface{})
+	if components["access_log"].(map[string]interface{})["status"] != "disabled" {
+		t.Errorf("Expected access_log to be disabled without recorder, got %v", components["access_log"])
+	}
+}
+
+func TestRouter_ReadyzStorageLoadFailed(t *testing.T) {
+	// 写入损坏的配置文件，模拟存储初始化失败
+	configFile := filepath.Join(t.TempDir(), "configs.json")
+	if err := os.WriteFile(configFile, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := &config.Config{AdminSecret: "test-secret", Port: "10805"}
+	log := logger.New()
+	storage := proxyconfig.NewPersistentStorage(configFile, 100, proxyconfig.EvictionReject, false, log)
+	router := NewRouter(cfg, log, nil, storage)
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.HandleReadyz(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	storageStatus := response["components"].(map[string]interface{})["config_storage"].(map[string]interface{})
+	if storageStatus["status"] != "error" {
+		t.Errorf("Expected config_storage status error, got %v", storageStatus["status"])
+	}
+}
+
+func TestRouter_ReadyzStorageSaveFailing(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "configs.json")
+	cfg := &config.Config{AdminSecret: "test-secret", Port: "10805"}
+	log := logger.New()
+	storage := proxyconfig.NewPersistentStorage(configFile, 100, proxyconfig.EvictionReject, false, log)
+	router := NewRouter(cfg, log, nil, storage)
+
+	// 临时文件路径被目录占用，模拟磁盘不可写，连续保存失败
+	if err := os.Mkdir(configFile+".tmp", 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		storage.SaveToFile()
+	}
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.HandleReadyz(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected degraded storage to stay ready with 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response["status"] != "degraded" {
+		t.Errorf("Expected overall status degraded, got %v", response["status"])
+	}
+	storageStatus := response["components"].(map[string]interface{})["config_storage"].(map[string]interface{})
+	if storageStatus["status"] != "degraded" || storageStatus["error"] == nil {
+		t.Errorf("Expected config_storage to be degraded with an error, got %v", storageStatus)
+	}
+}
+
+func TestRouter_CORSAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowedOrigins []string
+		credentials    bool
+		origin         string
+		expectedOrigin string
+		expectVary     bool
+	}{
+		{
+			name:           "wildcard origin",
+			allowedOrigins: []string{"*"},
+			origin:         "https://any.example.com",
+			expectedOrigin: "*",
+		},
+		{
+			name:           "allowed origin is echoed",
+			allowedOrigins: []string{"https://app.example.com", "https://admin.example.com"},
+			origin:         "https://admin.example.com",
+			expectedOrigin: "https://admin.example.com",
+			expectVary:     true,
+		},
+		{
+			name:           "disallowed origin",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "https://evil.example.com",
+			expectedOrigin: "",
+			expectVary:     true,
+		},
+		{
+			name:           "wildcard with credentials echoes origin",
+			allowedOrigins: []string{"*"},
+			credentials:    true,
+			origin:         "https://any.example.com",
+			expectedOrigin: "https://any.example.com",
+			expectVary:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupRouterTest()
+			router.cfg.CORSAllowedOrigins = tt.allowedOrigins
+			router.cfg.CORSAllowCredentials = tt.credentials
+
+			req := httptest.NewRequest("OPTIONS", "/config/proxy", nil)
+			req.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+			router.HandleProxyConfigAPI(w, req)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.expectedOrigin, got)
+			}
+			if got := w.Header().Get("Vary") == "Origin"; got != tt.expectVary {
+				t.Errorf("Expected Vary: Origin to be %v, got header %q", tt.expectVary, w.Header().Get("Vary"))
+			}
+
+			expectCredentials := tt.credentials && tt.expectedOrigin != ""
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != expectCredentials {
+				t.Errorf("Expected credentials header to be %v", expectCredentials)
+			}
+		})
+	}
+}
+
+func TestRouter_IPRateLimit(t *testing.T) {
+	cfg := &config.Config{
+		AdminSecret:      "test-secret",
+		Port:             "10805",
+		IPRateLimit:      60,
+		IPRateLimitBurst: 5,
+	}
+	router := NewRouter(cfg, logger.New(), nil, proxyconfig.NewMemoryStorage(100))
+
+	handlerFunc := router.withRateLimit(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	doRequest := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/config/proxy", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handlerFunc(w, req)
+		return w
+	}
+
+	// 同一IP的大量请求应在突发容量耗尽后被限流
+	throttled := 0
+	for i := 0; i < 20; i++ {
+		w := doRequest("203.0.113.10:5000")
+		if w.Code == http.StatusTooManyRequests {
+			throttled++
+			if w.Header().Get("Retry-After") == "" {
+				t.Error("Expected Retry-After header on throttled response")
+			}
+		}
+	}
+	if throttled != 15 {
+		t.Errorf("Expected 15 throttled requests, got %d", throttled)
+	}
+
+	// 其他IP不受影响
+	if w := doRequest("203.0.113.20:5000"); w.Code != http.StatusOK {
+		t.Errorf("Expected different IP to be unaffected, got %d", w.Code)
+	}
+}
+
+func TestRouter_IPRateLimitIgnoresForgedForwardedFor(t *testing.T) {
+	cfg := &config.Config{
+		AdminSecret:      "test-secret",
+		Port:             "10805",
+		IPRateLimit:      60,
+		IPRateLimitBurst: 2,
+		TrustedProxies:   []string{"10.0.0.1"},
+	}
+	router := NewRouter(cfg, logger.New(), nil, proxyconfig.NewMemoryStorage(100))
+
+	handlerFunc := router.withRateLimit(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	doRequest := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/config/proxy", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		handlerFunc(w, req)
+		return w.Code
+	}
+
+	// 直连客户端每次伪造不同的X-Forwarded-For，仍按对端地址限流
+	throttled := 0
+	for i := 0; i < 10; i++ {
+		if doRequest("203.0.113.10:5000", fmt.Sprintf("198.51.100.%d", i)) == http.StatusTooManyRequests {
+			throttled++
+		}
+	}
+	if throttled != 8 {
+		t.Errorf("Expected forged X-Forwarded-For not to bypass the limit, got %d throttled", throttled)
+	}
+
+	// 来自受信任代理的请求按转发的客户端地址限流
+	if code := doRequest("10.0.0.1:5000", "198.51.100.1"); code != http.StatusOK {
+		t.Errorf("Expected forwarded client behind trusted proxy to have its own bucket, got %d", code)
+	}
+}
+
+func TestRouter_IPRateLimitDisabled(t *testing.T) {
+	router := setupRouterTest()
+
+	if router.ipLimiter != nil {
+		t.Fatal("Expected rate limiter to be disabled by default")
+	}
+
+	handlerFunc := router.withRateLimit(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for i := 0; i < 50; i++ {
+		w := httptest.NewRecorder()
+		handlerFunc(w, httptest.NewRequest("GET", "/proxy", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected no throttling when disabled, got %d", w.Code)
+		}
+	}
+}
+
+func TestRouter_AuditAPI(t *testing.T) {
+	router := setupRouterTest()
+
+	auditRecorder, _ := audit.NewRecorder(100, "")
+	router.SetAuditRecorder(auditRecorder)
+
+	// 通过配置API创建配置，应产生审计记录
+	body := `{"name":"Audit Config","target_url":"https://example.com","protocol":"https","enabled":true}`
+	req := httptest.NewRequest("POST", "/config/proxy", strings.NewReader(body))
+	req.Header.Set("X-Log-Secret", "test-secret")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.HandleProxyConfigAPI(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	// 未认证请求被拒绝
+	req = httptest.NewRequest("GET", "/audit", nil)
+	w = httptest.NewRecorder()
+	router.HandleAuditAPI(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without secret, got %d", w.Code)
+	}
+
+	// 只读：不允许写操作
+	req = httptest.NewRequest("DELETE", "/audit", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	router.HandleAuditAPI(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for DELETE, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/audit?action=config.create&limit=10", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	router.HandleAuditAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response audit.Response
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Total != 1 || response.Limit != 10 {
+		t.Errorf("Expected 1 entry with limit 10, got total=%d limit=%d", response.Total, response.Limit)
+	}
+	if len(response.Entries) == 1 && response.Entries[0].Actor != audit.ActorAdmin {
+		t.Errorf("Expected admin actor, got %s", response.Entries[0].Actor)
+	}
+}
+
+func TestRouter_ConfigStatsAPI(t *testing.T) {
+	router := setupRouterTest()
+
+	config := &proxyconfig.ProxyConfig{Name: "Stats Config", TargetURL: "https://example.com", Protocol: "https", Enabled: true}
+	if err := router.configStorage.Add(config); err != nil {
+		t.Fatalf("Failed to add config: %v", err)
+	}
+
+	// 模拟代理请求产生的统计
+	router.configStorage.UpdateStats(config.ID, 100*time.Millisecond, true, 1024)
+	router.configStorage.UpdateStats(config.ID, 300*time.Millisecond, false, 512)
+
+	doRequest := func(method, path, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if secret != "" {
+			req.Header.Set("X-Log-Secret", secret)
+		}
+		w := httptest.NewRecorder()
+		router.HandleProxyConfigOrTokenAPI(w, req)
+		return w
+	}
+
+	// 未认证请求被拒绝
+	if w := doRequest("GET", "/config/proxy/"+config.ID+"/stats", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without secret, got %d", w.Code)
+	}
+
+	if w := doRequest("PUT", "/config/proxy/"+config.ID+"/stats", "test-secret"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for PUT, got %d", w.Code)
+	}
+
+	// 未知配置返回404
+	if w := doRequest("GET", "/config/proxy/unknown/stats", "test-secret"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown config, got %d", w.Code)
+	}
+
+	w := doRequest("GET", "/config/proxy/"+config.ID+"/stats", "test-secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stats proxyconfig.ConfigStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if stats.RequestCount != 2 || stats.ErrorCount != 1 || stats.TotalBytes != 1536 {
+		t.Errorf("Expected counters to reflect updates, got %+v", stats)
+	}
+	if stats.LastAccessed.IsZero() {
+		t.Error("Expected last_accessed to be set")
+	}
+
+	// 清零后计数器归零
+	if w := doRequest("DELETE", "/config/proxy/"+config.ID+"/stats", "test-secret"); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 for reset, got %d", w.Code)
+	}
+	w = doRequest("GET", "/config/proxy/"+config.ID+"/stats", "test-secret")
+	stats = proxyconfig.ConfigStats{}
+	json.NewDecoder(w.Body).Decode(&stats)
+	if stats.RequestCount != 0 || stats.ErrorCount != 0 || stats.TotalBytes != 0 {
+		t.Errorf("Expected counters to be reset, got %+v", stats)
+	}
+	if _, err := router.configStorage.GetByID(config.ID); err != nil {
+		t.Errorf("Expected config to be kept after reset, got %v", err)
+	}
+}
+
+func TestRouter_ConfigValidationErrors(t *testing.T) {
+	router := setupRouterTest()
+
+	doCreate := func(body, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/config/proxy", strings.NewReader(body))
+		req.Header.Set("X-Log-Secret", "test-secret")
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.HandleProxyConfigAPI(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		wantField string
+		wantCode  string
+	}{
+		{"missing name", `{"target_url":"https://example.com","protocol":"https"}`, "name", proxyconfig.FieldErrorRequired},
+		{"bad target url", `{"name":"bad","target_url":"ftp://example.com","protocol":"https"}`, "target_url", proxyconfig.FieldErrorInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doCreate(tt.body, "application/json")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", w.Code)
+			}
+
+			var response struct {
+				ErrorCode string                   `json:"error_code"`
+				Errors    []proxyconfig.FieldError `json:"errors"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Expected JSON error body: %v", err)
+			}
+			if response.ErrorCode != "VALIDATION_ERROR" || len(response.Errors) != 1 {
+				t.Fatalf("Expected a single field error, got %+v", response)
+			}
+			if response.Errors[0].Field != tt.wantField || response.Errors[0].ErrorCode != tt.wantCode {
+				t.Errorf("Expected %s error on %s, got %+v", tt.wantCode, tt.wantField, response.Errors[0])
+			}
+		})
+	}
+
+	// 多个字段错误一并返回
+	w := doCreate(`{"protocol":"ftp"}`, "application/json")
+	var response struct {
+		Errors []proxyconfig.FieldError `json:"errors"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response.Errors) != 3 {
+		t.Errorf("Expected errors for name, target_url and protocol, got %+v", response.Errors)
+	}
+
+	// 未声明接受JSON时同样返回统一的JSON错误格式
+	w = doCreate(`{"target_url":"https://example.com","protocol":"https"}`, "")
+	var plain struct {
+		ErrorCode string `json:"error_code"`
+		Message   string `json:"message"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&plain); err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected JSON error body, got %d: %v", w.Code, err)
+	}
+	if plain.ErrorCode != "VALIDATION_ERROR" || plain.Message != "name is required" {
+		t.Errorf("Expected validation error without Accept header, got %+v", plain)
+	}
+}
+
+func TestRouter_MetricsResetAPI(t *testing.T) {
+	router := setupRouterTest()
+
+	proxied := router.withMetrics(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("fail") == "true" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, query := range []string{"", "", "fail=true"} {
+		proxied(httptest.NewRecorder(), httptest.NewRequest("GET", "/proxy?"+query, nil))
+	}
+
+	// 未认证和非POST请求被拒绝
+	req := httptest.NewRequest("POST", "/metrics/reset", nil)
+	w := httptest.NewRecorder()
+	router.HandleMetricsResetAPI(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without secret, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/metrics/reset", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	router.HandleMetricsResetAPI(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET, got %d", w.Code)
+	}
+
+	// 返回清零前的快照
+	req = httptest.NewRequest("POST", "/metrics/reset", nil)
+	req.Header.Set("X-Log-Secret", "test-secret")
+	w = httptest.NewRecorder()
+	router.HandleMetricsResetAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var snapshot struct {
+		TotalRequests int64 `json:"total_requests"`
+		ErrorRequests int64 `json:"error_requests"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&snapshot); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if snapshot.TotalRequests != 3 || snapshot.ErrorRequests != 1 {
+		t.Errorf("Expected pre-reset snapshot with 3 requests and 1 error, got %+v", snapshot)
+	}
+
+	// 清零后计数从零开始
+	if current := router.Metrics().GetSnapshot(); current.TotalRequests != 0 || current.ErrorRequests != 0 {
+		t.Errorf("Expected counters to be zero after reset, got total=%d errors=%d", current.TotalRequests, current.ErrorRequests)
+	}
+	proxied(httptest.NewRecorder(), httptest.NewRequest("GET", "/proxy", nil))
+	if current := router.Metrics().GetSnapshot(); current.TotalRequests != 1 || current.SuccessRequests != 1 {
+		t.Errorf("Expected counters to increment from zero, got total=%d success=%d", current.TotalRequests, current.SuccessRequests)
+	}
+}
+
+func TestRouter_AuditVerifyAPI(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "audit.log")
+	auditRecorder, _ := audit.NewRecorder(100, filePath)
+	auditRecorder.Record(audit.Entry{Actor: audit.ActorAdmin, Action: audit.ActionConfigCreate, ConfigID: "c1"})
+	auditRecorder.Record(audit.Entry{Actor: audit.ActorAdmin, Action: audit.ActionConfigDelete, ConfigID: "c1"})
+
+	verify := func(recorder *audit.Recorder, secret string) (*httptest.ResponseRecorder, handler.AuditVerifyResponse) {
+		router := setupRouterTest()
+		router.SetAuditRecorder(recorder)
+		req := httptest.NewRequest("GET", "/audit/verify", nil)
+		if secret != "" {
+			req.Header.Set("X-Log-Secret", secret)
+		}
+		w := httptest.NewRecorder()
+		router.HandleAuditVerifyAPI(w, req)
+		var response handler.AuditVerifyResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		return w, response
+	}
+
+	if w, _ := verify(auditRecorder, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without secret, got %d", w.Code)
+	}
+	if w, response := verify(auditRecorder, "test-secret"); w.Code != http.StatusOK || !response.Valid || response.Entries != 2 {
+		t.Errorf("Expected intact chain with 2 entries, got %d %+v", w.Code, response)
+	}
+	auditRecorder.Close()
+
+	// 篡改文件中的第一条记录后重新加载
+	data, _ := os.ReadFile(filePath)
+	os.WriteFile(filePath, []byte(strings.Replace(string(data), `"config_id":"c1"`, `"config_id":"c2"`, 1)), 0600)
+	tampered, err := audit.NewRecorder(100, filePath)
+	if err != nil {
+		t.Fatalf("Failed to reopen recorder: %v", err)
+	}
+	defer tampered.Close()
+
+	w, response := verify(tampered, "test-secret")
+	if w.Code != http.StatusConflict || response.Valid || response.Error == "" {
+		t.Errorf("Expected tampered chain to be reported, got %d %+v", w.Code, response)
+	}
+}
@@ -0,0 +1,59 @@
+package tlsserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"privacygateway/internal/config"
+)
+
+// NewACMEManager 创建自动申请和续期证书的ACME管理器，未启用ACME时返回nil
+//
+// 证书及账户密钥保存在ACMECacheDir中，重启后复用。TLS-ALPN-01验证由TLSConfig处理，
+// HTTP-01验证需要通过HTTPHandler在80端口提供。
+func NewACMEManager(cfg *config.Config) *autocert.Manager {
+	if !cfg.ACMEEnabled {
+		return nil
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: HostPolicy(cfg.ACMEDomains),
+		Cache:      autocert.DirCache(cfg.ACMECacheDir),
+		Email:      cfg.ACMEEmail,
+	}
+}
+
+// HostPolicy 只允许为配置的域名申请证书
+//
+// 普通条目精确匹配；*.example.com匹配example.com的一级子域名（不含example.com本身和更深的子域名），
+// 防止任意Host触发证书申请而耗尽签发配额。
+func HostPolicy(domains []string) autocert.HostPolicy {
+	exact := make(map[string]bool)
+	var parents []string
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if parent, ok := strings.CutPrefix(domain, "*."); ok {
+			parents = append(parents, parent)
+		} else if domain != "" {
+			exact[domain] = true
+		}
+	}
+
+	return func(_ context.Context, host string) error {
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		if exact[host] {
+			return nil
+		}
+		if label, parent, found := strings.Cut(host, "."); found && label != "" {
+			for _, allowed := range parents {
+				if parent == allowed {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("acme: host %q is not in ACME_DOMAINS", host)
+	}
+}
@@ -0,0 +1,51 @@
+package tlsserver
+
+import (
+	"context"
+	"testing"
+
+	"privacygateway/internal/config"
+)
+
+func TestHostPolicy(t *testing.T) {
+	policy := HostPolicy([]string{"gateway.example.com", "*.proxy.example.com"})
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"gateway.example.com", true},
+		{"GATEWAY.example.com.", true},
+		{"api.proxy.example.com", true},
+		{"proxy.example.com", false},
+		{"a.b.proxy.example.com", false},
+		{"evil.example.com", false},
+		{"gateway.example.com.evil.net", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		err := policy(context.Background(), tt.host)
+		if (err == nil) != tt.allowed {
+			t.Errorf("Host %q: expected allowed=%v, got error %v", tt.host, tt.allowed, err)
+		}
+	}
+}
+
+func TestNewACMEManager(t *testing.T) {
+	if manager := NewACMEManager(&config.Config{}); manager != nil {
+		t.Error("Expected no ACME manager when ACME is disabled")
+	}
+
+	manager := NewACMEManager(&config.Config{
+		ACMEEnabled:  true,
+		ACMEDomains:  []string{"gateway.example.com"},
+		ACMECacheDir: t.TempDir(),
+	})
+	if manager == nil || manager.Cache == nil {
+		t.Fatal("Expected ACME manager with certificate cache")
+	}
+	if err := manager.HostPolicy(context.Background(), "other.example.com"); err == nil {
+		t.Error("Expected manager to reject hosts outside ACME_DOMAINS")
+	}
+}
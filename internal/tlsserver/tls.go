@@ -0,0 +1,79 @@
+package tlsserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"privacygateway/internal/config"
+)
+
+// LoadConfig 按配置加载静态TLS证书，未配置证书时返回nil
+//
+// 默认证书用于没有SNI或SNI主机名没有匹配的连接；TLSSNICerts中的证书按主机名精确匹配，
+// 其次匹配*.example.com形式的通配符条目。
+func LoadConfig(cfg *config.Config) (*tls.Config, error) {
+	if !cfg.HasStaticCert() {
+		return nil, nil
+	}
+
+	defaultCert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default certificate: %w", err)
+	}
+
+	sniCerts := make(map[string]*tls.Certificate, len(cfg.TLSSNICerts))
+	for host, pair := range cfg.TLSSNICerts {
+		cert, err := tls.LoadX509KeyPair(pair.CertFile, pair.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate for %s: %w", host, err)
+		}
+		sniCerts[host] = &cert
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{defaultCert},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if cert := matchSNICert(sniCerts, hello.ServerName); cert != nil {
+				return cert, nil
+			}
+			return &defaultCert, nil
+		},
+	}, nil
+}
+
+// matchSNICert 按SNI主机名选择证书：先精确匹配，再匹配上一级域名的通配符证书
+func matchSNICert(certs map[string]*tls.Certificate, serverName string) *tls.Certificate {
+	host := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if host == "" {
+		return nil
+	}
+	if cert, ok := certs[host]; ok {
+		return cert
+	}
+	if _, parent, found := strings.Cut(host, "."); found {
+		return certs["*."+parent]
+	}
+	return nil
+}
+
+// RedirectHandler 将HTTP请求永久重定向到HTTPS监听端口的相同路径
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = host + ":" + httpsPort
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,154 @@
+package tlsserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"privacygateway/internal/config"
+)
+
+// writeSelfSignedCert 生成自签名证书并写入临时目录，返回证书、私钥路径和证书PEM
+func writeSelfSignedCert(t *testing.T, name string, dnsNames ...string) (string, string, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:              dnsNames,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, certPEM, 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile, certPEM
+}
+
+func TestLoadConfig_ServesHTTPS(t *testing.T) {
+	certFile, keyFile, certPEM := writeSelfSignedCert(t, "default", "localhost")
+	sniCert, sniKey, sniPEM := writeSelfSignedCert(t, "wildcard", "*.example.test")
+
+	tlsConfig, err := LoadConfig(&config.Config{
+		TLSCertFile: certFile,
+		TLSKeyFile:  keyFile,
+		TLSSNICerts: map[string]config.TLSCertPair{
+			"*.example.test": {CertFile: sniCert, KeyFile: sniKey},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load TLS config: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil {
+			t.Error("Expected request to arrive over TLS")
+		}
+		w.Write([]byte("secure"))
+	}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	roots.AppendCertsFromPEM(sniPEM)
+
+	// 默认证书：HTTPS请求成功
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected HTTPS request to succeed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "secure" {
+		t.Errorf("Expected 200 secure, got %d %q", resp.StatusCode, body)
+	}
+
+	// SNI主机名匹配通配符证书，未匹配时使用默认证书
+	for serverName, wantName := range map[string]string{
+		"api.example.test": "*.example.test",
+		"localhost":        "localhost",
+	} {
+		conn, err := tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{RootCAs: roots, ServerName: serverName})
+		if err != nil {
+			t.Fatalf("Expected TLS handshake for %s to succeed: %v", serverName, err)
+		}
+		if got := conn.ConnectionState().PeerCertificates[0].DNSNames[0]; got != wantName {
+			t.Errorf("Expected certificate %s for %s, got %s", wantName, serverName, got)
+		}
+		conn.Close()
+	}
+}
+
+func TestLoadConfig_Disabled(t *testing.T) {
+	tlsConfig, err := LoadConfig(&config.Config{})
+	if err != nil || tlsConfig != nil {
+		t.Errorf("Expected nil config without certificates, got %v, %v", tlsConfig, err)
+	}
+
+	if _, err := LoadConfig(&config.Config{TLSCertFile: "missing.crt", TLSKeyFile: "missing.key"}); err == nil {
+		t.Error("Expected error for missing certificate files")
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		httpsPort string
+		host      string
+		want      string
+	}{
+		{"8443", "example.com:8080", "https://example.com:8443/logs?page=2"},
+		{"443", "example.com", "https://example.com/logs?page=2"},
+		{"8443", "[::1]:8080", "https://[::1]:8443/logs?page=2"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/logs?page=2", nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		RedirectHandler(tt.httpsPort).ServeHTTP(w, req)
+
+		if w.Code != http.StatusMovedPermanently {
+			t.Errorf("Expected 301, got %d", w.Code)
+		}
+		if got := w.Header().Get("Location"); got != tt.want {
+			t.Errorf("Expected redirect to %s, got %s", tt.want, got)
+		}
+	}
+}
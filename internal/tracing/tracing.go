@@ -0,0 +1,198 @@
+// Package tracing 提供代理请求的链路追踪（OpenTelemetry兼容的span与W3C Trace Context传播）
+//
+// 未设置全局Tracer时所有操作均为空操作，Span的方法对nil接收者安全。
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader W3C Trace Context传播头
+const TraceparentHeader = "traceparent"
+
+// Span 一次代理请求的追踪记录
+type Span struct {
+	TraceID      string                 // 32位十六进制trace ID
+	SpanID       string                 // 16位十六进制span ID
+	ParentSpanID string                 // 上游调用方的span ID（请求携带traceparent时）
+	Name         string                 // span名称
+	StartTime    time.Time              // 开始时间
+	EndTime      time.Time              // 结束时间
+	Attributes   map[string]interface{} // 属性（string、int、int64、float64、bool）
+	ErrorMessage string                 // 错误信息（为空表示成功）
+	Failed       bool                   // 是否失败
+
+	tracer *Tracer
+	mutex  sync.Mutex
+	ended  bool
+}
+
+// Exporter span导出器
+type Exporter interface {
+	ExportSpans(spans []*Span) error
+	Shutdown() error
+}
+
+// Tracer 创建span并在结束时交给导出器
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer 创建Tracer
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Shutdown 关闭导出器，刷新尚未导出的span
+func (t *Tracer) Shutdown() error {
+	return t.exporter.Shutdown()
+}
+
+var (
+	globalMutex  sync.RWMutex
+	globalTracer *Tracer
+)
+
+// SetTracer 设置全局Tracer（nil表示关闭追踪）
+func SetTracer(tracer *Tracer) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+	globalTracer = tracer
+}
+
+// currentTracer 获取全局Tracer
+func currentTracer() *Tracer {
+	globalMutex.RLock()
+	defer globalMutex.RUnlock()
+	return globalTracer
+}
+
+// StartSpan 为请求开始一个span，未启用追踪时返回nil
+//
+// 请求携带合法的traceparent头时延续调用方的trace，否则开始新的trace。
+func StartSpan(r *http.Request, name string) *Span {
+	tracer := currentTracer()
+	if tracer == nil {
+		return nil
+	}
+
+	span := &Span{
+		SpanID:     randomHex(8),
+		Name:       name,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]interface{}),
+		tracer:     tracer,
+	}
+
+	if traceID, parentID, ok := parseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+		span.TraceID = traceID
+		span.ParentSpanID = parentID
+	} else {
+		span.TraceID = randomHex(16)
+	}
+
+	return span
+}
+
+// SetAttribute 设置span属性
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Attributes[key] = value
+}
+
+// RecordError 将span标记为失败并记录错误信息
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Failed = true
+	s.ErrorMessage = err.Error()
+}
+
+// SetFailed 将span标记为失败（无具体错误时使用）
+func (s *Span) SetFailed(message string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Failed = true
+	if s.ErrorMessage == "" {
+		s.ErrorMessage = message
+	}
+}
+
+// Inject 将当前span作为父span写入traceparent头，传播给上游
+func (s *Span) Inject(header http.Header) {
+	if s == nil {
+		return
+	}
+	header.Set(TraceparentHeader, "00-"+s.TraceID+"-"+s.SpanID+"-01")
+}
+
+// End 结束span并导出，重复调用无效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mutex.Unlock()
+
+	s.tracer.exporter.ExportSpans([]*Span{s})
+}
+
+// Duration 获取span持续时间
+func (s *Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// parseTraceparent 解析W3C traceparent头，返回trace ID和父span ID
+func parseTraceparent(value string) (string, string, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", "", false
+	}
+	// 版本00必须恰好包含4个字段
+	if parts[0] == "00" && len(parts) != 4 {
+		return "", "", false
+	}
+
+	traceID, spanID := strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	if !isHex(parts[0]) || len(traceID) != 32 || !isHex(traceID) || len(spanID) != 16 || !isHex(spanID) || len(parts[3]) != 2 || !isHex(parts[3]) {
+		return "", "", false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// isHex 检查字符串是否全部为小写或大写十六进制字符
+func isHex(value string) bool {
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// randomHex 生成指定字节数的随机十六进制字符串
+func randomHex(n int) string {
+	bytes := make([]byte, n)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"privacygateway/internal/logger"
+)
+
+func TestStartSpan_Disabled(t *testing.T) {
+	SetTracer(nil)
+
+	req := httptest.NewRequest("GET", "/proxy", nil)
+	span := StartSpan(req, "proxy example.com")
+	if span != nil {
+		t.Fatalf("Expected nil span when tracing is disabled, got %+v", span)
+	}
+
+	// nil span的方法均为空操作
+	header := make(http.Header)
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("boom"))
+	span.Inject(header)
+	span.End()
+	if header.Get(TraceparentHeader) != "" {
+		t.Error("Expected no traceparent to be injected when tracing is disabled")
+	}
+}
+
+func TestStartSpan_Traceparent(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	SetTracer(NewTracer(exporter))
+	defer SetTracer(nil)
+
+	tests := []struct {
+		name        string
+		traceparent string
+		wantTraceID string
+		wantParent  string
+	}{
+		{"valid", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{"missing", "", "", ""},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", ""},
+		{"bad length", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", "", ""},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/proxy", nil)
+			if tt.traceparent != "" {
+				req.Header.Set(TraceparentHeader, tt.traceparent)
+			}
+
+			span := StartSpan(req, "proxy example.com")
+			if tt.wantTraceID != "" {
+				if span.TraceID != tt.wantTraceID || span.ParentSpanID != tt.wantParent {
+					t.Errorf("Expected trace %s/%s, got %s/%s", tt.wantTraceID, tt.wantParent, span.TraceID, span.ParentSpanID)
+				}
+			} else if len(span.TraceID) != 32 || span.ParentSpanID != "" {
+				t.Errorf("Expected new root trace, got trace=%q parent=%q", span.TraceID, span.ParentSpanID)
+			}
+
+			// 传播给上游的traceparent使用当前span作为父span
+			header := make(http.Header)
+			span.Inject(header)
+			if want := "00-" + span.TraceID + "-" + span.SpanID + "-01"; header.Get(TraceparentHeader) != want {
+				t.Errorf("Expected traceparent %q, got %q", want, header.Get(TraceparentHeader))
+			}
+		})
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body []byte
+	var authHeader, path string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		authHeader = r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL, "gateway-test", map[string]string{"Authorization": "Bearer abc"}, logger.New())
+	tracer := NewTracer(exporter)
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	span := StartSpan(httptest.NewRequest("GET", "/proxy", nil), "proxy api.example.com")
+	span.SetAttribute("http.response.status_code", 502)
+	span.RecordError(errors.New("connection refused"))
+	span.End()
+
+	// 关闭时发送剩余的span
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if path != "/v1/traces" || authHeader != "Bearer abc" {
+		t.Errorf("Expected POST to /v1/traces with headers, got path=%q auth=%q", path, authHeader)
+	}
+
+	var request otlpRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("Failed to decode OTLP request: %v (%s)", err, body)
+	}
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 exported span, got %d", len(spans))
+	}
+	if spans[0].TraceID != span.TraceID || spans[0].Status.Code != otlpStatusCodeError {
+		t.Errorf("Unexpected exported span: %+v", spans[0])
+	}
+	if !strings.Contains(string(body), `"service.name"`) || !strings.Contains(string(body), `"intValue":"502"`) {
+		t.Errorf("Expected service name and status code attribute, got %s", body)
+	}
+}
@@ -2,17 +2,26 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"privacygateway/internal/accesslog"
+	"privacygateway/internal/audit"
 	"privacygateway/internal/config"
 	"privacygateway/internal/logger"
+	"privacygateway/internal/metrics"
+	"privacygateway/internal/proxy"
 	"privacygateway/internal/proxyconfig"
 	"privacygateway/internal/router"
+	"privacygateway/internal/tlsserver"
+	"privacygateway/internal/tracing"
 )
 
 func main() {
@@ -22,6 +31,21 @@ func main() {
 	// 加载配置
 	cfg := config.Load()
 
+	// 检查关键配置（STRICT_CONFIG=true时有问题直接退出，否则记录警告后继续启动）
+	if issues := cfg.Validate(); len(issues) > 0 {
+		for _, issue := range issues {
+			if cfg.StrictConfig {
+				log.Error("invalid configuration", "env", issue.Env, "error", issue.Message)
+			} else {
+				log.Warn("configuration problem detected, set STRICT_CONFIG=true to refuse starting", "env", issue.Env, "error", issue.Message)
+			}
+		}
+		if cfg.StrictConfig {
+			log.Error("refusing to start with invalid configuration", "issues", len(issues))
+			os.Exit(1)
+		}
+	}
+
 	// 创建访问日志记录器
 	var recorder *accesslog.Recorder
 	if cfg.AdminSecret != "" {
@@ -34,50 +58,237 @@ func main() {
 		}
 	}
 
+	// 上游传输层连接池（代理配置可单独覆盖）
+	proxy.SetTransportPool(&proxy.TransportPool{
+		MaxIdleConns:        cfg.UpstreamMaxIdleConns,
+		MaxIdleConnsPerHost: cfg.UpstreamMaxIdleConnsPerHost,
+		IdleConnTimeout:     time.Duration(cfg.UpstreamIdleConnTimeoutSeconds) * time.Second,
+		DisableKeepAlives:   cfg.UpstreamDisableKeepAlives,
+	})
+
+	// 上游主机DNS缓存（直连拨号与目标地址检查共用，连接前仍逐个检查IP）
+	if cfg.DNSCacheEnabled {
+		proxy.SetDNSCache(proxy.NewDNSCache(nil,
+			time.Duration(cfg.DNSCacheTTLSeconds)*time.Second,
+			time.Duration(cfg.DNSCacheNegativeTTLSeconds)*time.Second))
+		log.Info("dns cache enabled", "ttl_seconds", cfg.DNSCacheTTLSeconds, "negative_ttl_seconds", cfg.DNSCacheNegativeTTLSeconds)
+	}
+
+	// 链路追踪（仅在配置了OTLP Collector地址时启用）
+	var tracer *tracing.Tracer
+	if cfg.OTelEndpoint != "" {
+		tracer = tracing.NewTracer(tracing.NewOTLPExporter(cfg.OTelEndpoint, cfg.OTelServiceName, cfg.OTelHeaders, log))
+		tracing.SetTracer(tracer)
+		log.Info("tracing enabled", "endpoint", cfg.OTelEndpoint, "service_name", cfg.OTelServiceName)
+	}
+
+	// 创建审计日志记录器（记录配置与令牌的变更操作）
+	auditRecorder, err := audit.NewRecorder(cfg.AuditMaxEntries, cfg.AuditLogFile)
+	if err != nil {
+		log.Error("failed to create audit recorder", "error", err, "file", cfg.AuditLogFile)
+	} else {
+		log.Info("audit recorder initialized", "file", cfg.AuditLogFile, "max_entries", cfg.AuditMaxEntries, "entries", auditRecorder.Count())
+		// 校验从文件恢复的记录，发现篡改时告警但继续追加（可通过 /audit/verify 复查）
+		if err := auditRecorder.Verify(); err != nil {
+			log.Error("audit log integrity check failed", "error", err, "file", cfg.AuditLogFile)
+		}
+	}
+
 	// 创建代理配置存储
 	var configStorage proxyconfig.Storage
 
-	// 检查是否禁用持久化存储（默认启用）
-	persistDisabled := os.Getenv("PROXY_CONFIG_PERSIST") == "false"
-	if persistDisabled {
-		configStorage = proxyconfig.NewMemoryStorage(1000)
-		log.Info("memory config storage initialized", "max_entries", 1000)
+	// 达到最大配置数时的淘汰策略（reject|lru，默认reject）
+	evictionMode, err := proxyconfig.ParseEvictionMode(os.Getenv("PROXY_CONFIG_EVICTION"))
+	if err != nil {
+		log.Error("invalid config eviction mode, falling back to reject", "error", err)
+		evictionMode = proxyconfig.EvictionReject
+	}
+
+	// 新令牌的哈希方案（sha256|argon2id，默认sha256；已有令牌按存储格式自动识别）
+	hashScheme, err := proxyconfig.ParseHashScheme(os.Getenv("TOKEN_HASH_SCHEME"))
+	if err != nil {
+		log.Error("invalid token hash scheme, falling back to sha256", "error", err)
+		hashScheme = proxyconfig.HashSchemeSHA256
+	}
+	proxyconfig.SetDefaultHashScheme(hashScheme)
+	log.Info("token hash scheme configured", "scheme", hashScheme)
+
+	// 新令牌的长度和字符集（TOKEN_LENGTH为0时沿用旧格式；已有令牌不受影响）
+	tokenCharset, err := proxyconfig.ParseTokenCharset(os.Getenv("TOKEN_CHARSET"))
+	if err != nil {
+		log.Error("invalid token charset, falling back to base64url", "error", err)
+		tokenCharset = proxyconfig.TokenCharsetBase64URL
+	}
+	tokenLength, _ := strconv.Atoi(os.Getenv("TOKEN_LENGTH"))
+	if err := proxyconfig.SetTokenFormat(tokenLength, tokenCharset); err != nil {
+		log.Error("invalid token format, falling back to default", "error", err)
+		proxyconfig.SetTokenFormat(0, proxyconfig.TokenCharsetBase64URL)
 	} else {
-		configFile := os.Getenv("PROXY_CONFIG_FILE")
-		if configFile == "" {
-			configFile = "data/proxy-configs.json"
+		log.Info("token format configured", "length", tokenLength, "charset", tokenCharset)
+	}
+
+	// 存储后端：redis供多实例共享配置和令牌；否则使用内存存储，按需持久化到文件（默认启用）
+	if cfg.StorageBackend == config.StorageBackendRedis {
+		redisStorage, err := proxyconfig.NewRedisStorage(cfg.RedisURL, cfg.RedisKeyPrefix, 1000, evictionMode, log)
+		if err != nil {
+			log.Error("failed to connect to redis config storage", "error", err)
+			os.Exit(1)
 		}
+		configStorage = redisStorage
+		log.Info("redis config storage initialized", "key_prefix", cfg.RedisKeyPrefix, "max_entries", 1000, "eviction", evictionMode)
+	} else if cfg.ProxyConfigFile == "" {
+		configStorage = proxyconfig.NewMemoryStorageWithEviction(1000, evictionMode)
+		log.Info("memory config storage initialized", "max_entries", 1000, "eviction", evictionMode)
+	} else {
+		configFile := cfg.ProxyConfigFile
 		autoSave := os.Getenv("PROXY_CONFIG_AUTO_SAVE") != "false"
-		configStorage = proxyconfig.NewPersistentStorage(configFile, 1000, autoSave, log)
-		log.Info("persistent config storage initialized", "file", configFile, "auto_save", autoSave)
+		persistent := proxyconfig.NewPersistentStorage(configFile, 1000, evictionMode, autoSave, log)
+
+		// 保存合并窗口（毫秒，默认500，0表示每次修改立即保存），突发的令牌修改只写一次文件
+		saveDelayMs := 500
+		if val := os.Getenv("PROXY_CONFIG_SAVE_DELAY_MS"); val != "" {
+			if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+				saveDelayMs = parsed
+			}
+		}
+		persistent.SetSaveDelay(time.Duration(saveDelayMs) * time.Millisecond)
+
+		configStorage = persistent
+		log.Info("persistent config storage initialized", "file", configFile, "auto_save", autoSave, "save_delay_ms", saveDelayMs, "eviction", evictionMode)
+	}
+
+	// 预置配置（容器等不可变部署通过文件或环境变量导入初始配置，已存在的同名配置跳过）
+	bootstrapFile := os.Getenv("BOOTSTRAP_CONFIGS_FILE")
+	bootstrapInline := os.Getenv("BOOTSTRAP_CONFIGS")
+	if bootstrapFile != "" || bootstrapInline != "" {
+		result, err := proxyconfig.Bootstrap(configStorage, bootstrapFile, bootstrapInline)
+		if err != nil {
+			log.Error("failed to bootstrap proxy configs", "error", err, "file", bootstrapFile)
+		} else {
+			log.Info("proxy configs bootstrapped", "seeded", result.ImportedCount, "skipped", result.SkippedCount, "errors", result.ErrorCount)
+			for _, msg := range result.Errors {
+				log.Warn("bootstrap config rejected", "error", msg)
+			}
+		}
 	}
 
+	// 定期清除回收站中超过保留时长的配置
+	retention := time.Duration(cfg.DeletedConfigRetentionHours) * time.Hour
+	deletedPurger := proxyconfig.NewDeletedPurger(configStorage, retention, log)
+	deletedPurger.Start()
+
 	// 创建并设置路由
 	appRouter := router.NewRouter(cfg, log, recorder, configStorage)
+	appRouter.SetAuditRecorder(auditRecorder)
+
+	// 阈值告警（仅在配置了Webhook地址时定期检查代理请求指标）
+	var alerter *metrics.Alerter
+	if cfg.AlertWebhookURL != "" {
+		alerter = metrics.NewAlerter(appRouter.Metrics(), metrics.AlertConfig{
+			WebhookURL:       cfg.AlertWebhookURL,
+			MinSuccessRate:   cfg.AlertMinSuccessRate,
+			MinRequests:      cfg.AlertMinRequests,
+			MaxAvgResponseMs: cfg.AlertMaxAvgResponseMs,
+			MaxMemoryMB:      cfg.AlertMaxMemoryMB,
+			Window:           time.Duration(cfg.AlertWindowSeconds) * time.Second,
+			Cooldown:         time.Duration(cfg.AlertCooldownSeconds) * time.Second,
+		}, log)
+		alerter.Start()
+		log.Info("alerting enabled", "min_success_rate", cfg.AlertMinSuccessRate, "max_avg_response_ms", cfg.AlertMaxAvgResponseMs, "window_seconds", cfg.AlertWindowSeconds)
+	}
 	appRouter.SetupRoutes()
 
 	// 打印路由信息
 	appRouter.PrintRoutes()
 
+	// 加载TLS证书：启用ACME时自动申请证书，否则使用TLS_CERT_FILE/TLS_KEY_FILE的静态证书
+	var tlsConfig *tls.Config
+	acmeManager := tlsserver.NewACMEManager(cfg)
+	if acmeManager != nil {
+		tlsConfig = acmeManager.TLSConfig()
+		log.Info("acme certificate provisioning enabled", "domains", cfg.ACMEDomains, "cache_dir", cfg.ACMECacheDir)
+	} else if tlsConfig, err = tlsserver.LoadConfig(cfg); err != nil {
+		log.Error("failed to load TLS certificates", "error", err)
+		os.Exit(1)
+	}
+
 	// 创建HTTP服务器
 	server := &http.Server{
-		Addr:         ":" + cfg.Port,
+		Addr:         cfg.ListenAddr(),
 		Handler:      nil,
+		TLSConfig:    tlsConfig,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
-	log.Info("starting Privacy Gateway", "port", cfg.Port)
+	log.Info("starting Privacy Gateway", "addr", cfg.ListenAddr(), "tls", tlsConfig != nil, "sni_certs", len(cfg.TLSSNICerts))
 
 	// 在goroutine中启动服务器
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsConfig != nil {
+			// 证书由TLSConfig提供（静态证书或ACME）
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error("server failed to start", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	// HTTP→HTTPS重定向（仅在启用TLS且设置了重定向端口时）
+	var redirectServer *http.Server
+	if tlsConfig != nil && cfg.TLSHTTPRedirectPort != "" {
+		// ACME的HTTP-01验证请求由管理器处理，其余请求重定向
+		redirectHandler := tlsserver.RedirectHandler(cfg.Port)
+		if acmeManager != nil {
+			redirectHandler = acmeManager.HTTPHandler(redirectHandler)
+		}
+		redirectServer = &http.Server{
+			Addr:              net.JoinHostPort(cfg.BindAddress, cfg.TLSHTTPRedirectPort),
+			Handler:           redirectHandler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		log.Info("starting HTTPS redirect listener", "addr", redirectServer.Addr)
+
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("redirect listener failed to start", "error", err)
+			}
+		}()
+	}
+
+	// 收到SIGHUP时从磁盘重新加载代理配置（仅持久化存储）
+	if persistent, ok := configStorage.(*proxyconfig.PersistentStorage); ok {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		go func() {
+			for range reload {
+				result, err := persistent.Reload()
+				if errors.Is(err, proxyconfig.ErrReloadPending) {
+					// API修改尚在保存合并窗口内，稍后写入文件时会覆盖手工修改
+					log.Warn("proxy config reload refused: API changes are pending and will be written to the config file, reapply manual edits after they are saved and send SIGHUP again")
+					continue
+				}
+				if err != nil {
+					log.Error("failed to reload proxy configs", "error", err)
+					continue
+				}
+				log.Info("proxy configs reloaded",
+					"added", result.Added,
+					"removed", result.Removed,
+					"changed", result.Changed,
+					"unchanged", result.Unchanged)
+				for _, msg := range result.Invalid {
+					log.Warn("reloaded config rejected", "error", msg)
+				}
+			}
+		}()
+	}
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -93,14 +304,34 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Error("server forced to shutdown", "error", err)
 	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(ctx)
+	}
 
 	// 清理资源
+	if alerter != nil {
+		alerter.Stop()
+	}
+	deletedPurger.Stop()
+
 	if recorder != nil {
 		if err := recorder.Close(); err != nil {
 			log.Error("failed to close access log recorder", "error", err)
 		}
 	}
 
+	if tracer != nil {
+		if err := tracer.Shutdown(); err != nil {
+			log.Error("failed to flush trace spans", "error", err)
+		}
+	}
+
+	if auditRecorder != nil {
+		if err := auditRecorder.Close(); err != nil {
+			log.Error("failed to close audit recorder", "error", err)
+		}
+	}
+
 	// 如果配置存储实现了Closer接口，也要关闭它
 	if closer, ok := configStorage.(interface{ Close() error }); ok {
 		if err := closer.Close(); err != nil {
@@ -64,6 +64,13 @@ echo -e "${RED}🔑 管理员密钥: ${ADMIN_SECRET}${NC}"
 # 是否记录200状态码的请求 (默认: false)
 export LOG_RECORD_200=true
 
+# 请求ID头名称，用于关联客户端、网关日志与上游服务 (默认: X-Request-ID)
+# export REQUEST_ID_HEADER=X-Request-ID
+
+# OpenTelemetry链路追踪，设置Collector地址后启用 (默认: 不启用)
+# export OTEL_EXPORTER_OTLP_ENDPOINT=http://otel-collector:4318
+# export OTEL_SERVICE_NAME=privacy-gateway
+
 # 日志级别 (debug, info, warn, error) (默认: info)
 # export LOG_LEVEL=info
 
@@ -73,6 +80,12 @@ export LOG_RECORD_200=true
 # 日志文件路径 (默认: 不写入文件，仅控制台输出)
 # export LOG_FILE=logs/privacy-gateway.log
 
+# 审计日志文件路径 (记录配置和令牌的变更操作) (默认: 仅保存在内存中)
+# export AUDIT_LOG_FILE=data/audit.log
+
+# 内存中保留的审计记录数 (默认: 10000)
+# export AUDIT_MAX_ENTRIES=10000
+
 # 日志文件最大大小 (MB) (默认: 100)
 # export LOG_MAX_SIZE=100
 
@@ -89,6 +102,18 @@ export LOG_RECORD_200=true
 # 是否允许代理私有IP地址 (默认: false)
 export ALLOW_PRIVATE_PROXY=true
 
+# 新建访问令牌的哈希方案 (sha256, argon2id) (默认: sha256)
+# 已有令牌根据存储格式自动识别，切换方案不影响旧令牌
+# export TOKEN_HASH_SCHEME=argon2id
+
+# 代理目标访问策略（SSRF防护），主机列表逗号分隔，支持 *.example.com
+# export TARGET_ALLOWED_SCHEMES=http,https
+# export TARGET_ALLOWED_HOSTS=api.example.com,*.example.org
+# export TARGET_DENIED_HOSTS=
+# 是否允许代理到私有/链路本地地址、回环地址 (默认: false)
+# export TARGET_ALLOW_PRIVATE=false
+# export TARGET_ALLOW_LOOPBACK=false
+
 # 是否允许代理本地回环地址 (默认: false)
 # export ALLOW_LOOPBACK_PROXY=false
 
@@ -98,6 +123,9 @@ export ALLOW_PRIVATE_PROXY=true
 # 单IP速率限制 (请求/分钟) (默认: 0 = 无限制)
 # export IP_RATE_LIMIT=100
 
+# 单IP突发请求数 (默认: 等于 IP_RATE_LIMIT)
+# export IP_RATE_LIMIT_BURST=20
+
 # 请求超时时间 (秒) (默认: 30)
 # export REQUEST_TIMEOUT=30
 
@@ -168,6 +196,21 @@ export ALLOW_PRIVATE_PROXY=true
 # 是否验证SSL证书 (默认: true)
 # export HTTP_CLIENT_VERIFY_SSL=true
 
+# 是否缓存上游主机的DNS解析结果 (默认: true)
+# export DNS_CACHE_ENABLED=true
+
+# DNS解析结果缓存时间 (秒) (默认: 30)
+# export DNS_CACHE_TTL=30
+
+# 域名不存在(NXDOMAIN)的缓存时间 (秒，0为不缓存) (默认: 5)
+# export DNS_CACHE_NEGATIVE_TTL=5
+
+# 出站代理，所有上游请求经该代理转发 (http://、socks5://) (默认: 无)
+# export UPSTREAM_PROXY=http://proxy.corp.example.com:3128
+
+# 不经过出站代理直连的目标 (域名后缀、IP、CIDR，逗号分隔) (默认: 读取NO_PROXY)
+# export UPSTREAM_NO_PROXY=internal.example.com,10.0.0.0/8
+
 # =============================================================================
 # 🌍 CORS配置
 # =============================================================================